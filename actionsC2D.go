@@ -2,53 +2,75 @@ package parrotbebop
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/eiannone/keyboard"
 )
 
-type inputAction int
+// InputAction is an action to do with the drone, like a takeoff or a
+// PCMD movement, given by the keyboard, the ground station, or with
+// SendAction.
+type InputAction int
 
 const (
 	// Standard actions.
 	//
-	ActionPcmdFlag                       inputAction = iota
-	ActionPcmdRollLeft                   inputAction = iota
-	ActionPcmdRollRight                  inputAction = iota
-	ActionPcmdPitchForward               inputAction = iota
-	ActionPcmdPitchBackward              inputAction = iota
-	ActionPcmdYawClockwise               inputAction = iota
-	ActionPcmdYawCounterClockwise        inputAction = iota
-	ActionPcmdHover                      inputAction = iota
-	ActionPcmdGazInc                     inputAction = iota
-	ActionPcmdGazDec                     inputAction = iota
-	ActionPcmdRepeatLastCmd              inputAction = iota
-	ActionTakeoff                        inputAction = iota
-	ActionLanding                        inputAction = iota
-	ActionEmergency                      inputAction = iota
-	ActionNavigateHomeStart              inputAction = iota // Check how to implement it in xml line 153
-	ActionNavigateHomeStop               inputAction = iota // Check how to implement it in xml line 153
-	ActionMoveBy                         inputAction = iota // Check how to implement it in xml line 181
-	ActionUserTakeoff                    inputAction = iota
-	ActionMoveTo                         inputAction = iota // Check how to implement it in xml line 259
-	ActionCancelMoveTo                   inputAction = iota
-	ActionStartPilotedPOI                inputAction = iota
-	ActionStopPilotedPOI                 inputAction = iota
-	ActionCancelMoveBy                   inputAction = iota
-	ActionMoveToSetLatInc                inputAction = iota // Direction North
-	ActionMoveToSetLatDec                inputAction = iota // Direction South
-	ActionMoveToSetLonInc                inputAction = iota // Direction East
-	ActionMoveToSetLonDec                inputAction = iota // Direction West
-	ActionMoveToExecute                  inputAction = iota // Execute moveTo next waypoint
-	ActionMoveToCancel                   inputAction = iota // Cancel all moveTo operation
-	ActionMoveToSetBufferCurrentPosition inputAction = iota // Set buffer to current position
+	ActionPcmdFlag                       InputAction = iota
+	ActionPcmdRollLeft                   InputAction = iota
+	ActionPcmdRollRight                  InputAction = iota
+	ActionPcmdPitchForward               InputAction = iota
+	ActionPcmdPitchBackward              InputAction = iota
+	ActionPcmdYawClockwise               InputAction = iota
+	ActionPcmdYawCounterClockwise        InputAction = iota
+	ActionPcmdHover                      InputAction = iota
+	ActionPcmdGazInc                     InputAction = iota
+	ActionPcmdGazDec                     InputAction = iota
+	ActionPcmdRepeatLastCmd              InputAction = iota
+	ActionTakeoff                        InputAction = iota
+	ActionLanding                        InputAction = iota
+	ActionEmergency                      InputAction = iota
+	ActionNavigateHomeStart              InputAction = iota // Check how to implement it in xml line 153
+	ActionNavigateHomeStop               InputAction = iota // Check how to implement it in xml line 153
+	ActionMoveBy                         InputAction = iota // Check how to implement it in xml line 181
+	ActionUserTakeoff                    InputAction = iota
+	ActionMoveTo                         InputAction = iota // Check how to implement it in xml line 259
+	ActionCancelMoveTo                   InputAction = iota
+	ActionStartPilotedPOI                InputAction = iota
+	ActionStopPilotedPOI                 InputAction = iota
+	ActionCancelMoveBy                   InputAction = iota
+	ActionMoveToSetLatInc                InputAction = iota // Direction North
+	ActionMoveToSetLatDec                InputAction = iota // Direction South
+	ActionMoveToSetLonInc                InputAction = iota // Direction East
+	ActionMoveToSetLonDec                InputAction = iota // Direction West
+	ActionMoveToExecute                  InputAction = iota // Execute moveTo next waypoint
+	ActionMoveToCancel                   InputAction = iota // Cancel all moveTo operation
+	ActionMoveToSetBufferCurrentPosition InputAction = iota // Set buffer to current position
 
 	// Custom actions.
 	//
-	ActionHow inputAction = iota
+	ActionHow InputAction = iota
 	// Flattrim should be performed before a takeoff
 	// to calibrate the drone.
-	ActionFlatTrim inputAction = iota
+	ActionFlatTrim InputAction = iota
+	// Nudge actions will move the drone a fixed distance with a moveBy
+	// command, which is more predictable than the raw PCMD values.
+	ActionNudgeForward  InputAction = iota
+	ActionNudgeBackward InputAction = iota
+	ActionNudgeLeft     InputAction = iota
+	ActionNudgeRight    InputAction = iota
+	ActionNudgeUp       InputAction = iota
+	ActionNudgeDown     InputAction = iota
+	// Flip will do a front flip.
+	ActionFlip InputAction = iota
+	// ResumeMission will give the control back to the mission after
+	// the pilot took over, as with ResumeMission.
+	ActionResumeMission InputAction = iota
+	// AbsoluteControlToggle will turn the absolute control on or off,
+	// as with SetAbsoluteControl.
+	ActionAbsoluteControlToggle InputAction = iota
 	// TODO: Also check out the <class name="PilotingSettings" id="2">"
 	// starting at line 1400 in the ardrone3.xml document, for more
 	// commands to eventually implement.
@@ -64,7 +86,8 @@ func (d *Drone) readKeyBoardEvent() {
 
 	keysEvents, err := keyboard.GetKeys(10)
 	if err != nil {
-		panic(err)
+		log.Printf("error: failed to open keyboard, no keyboard control available, use SetHeadless to not use the keyboard: %v\n", err)
+		return
 	}
 	defer func() {
 		err := keyboard.Close()
@@ -85,7 +108,7 @@ func (d *Drone) readKeyBoardEvent() {
 	// implies that we have mechanism's in place to handle continous
 	// flight of the drone incase there is a drop, or the connection have
 	// to be re-established for some reason
-	checkChOpen := func(ch chan inputAction, ia inputAction) {
+	checkChOpen := func(ch chan InputAction, ia InputAction) {
 		select {
 		case ch <- ia:
 		default:
@@ -109,53 +132,12 @@ func (d *Drone) readKeyBoardEvent() {
 				case d.chNetworkConnect <- struct{}{}:
 				default:
 				}
-			case event.Rune == 't':
-				checkChOpen(d.chInputActions, ActionTakeoff)
-			case event.Rune == 'l':
-				checkChOpen(d.chInputActions, ActionLanding)
-			case event.Rune == 'r':
-				checkChOpen(d.chInputActions, ActionNavigateHomeStart)
-			case event.Rune == 'R':
-				checkChOpen(d.chInputActions, ActionNavigateHomeStop)
-
-			case event.Rune == 'w':
-				checkChOpen(d.chInputActions, ActionPcmdGazInc)
-			case event.Rune == 's':
-				checkChOpen(d.chInputActions, ActionPcmdGazDec)
-			case event.Rune == 'a':
-				checkChOpen(d.chInputActions, ActionPcmdYawCounterClockwise)
-			case event.Rune == 'd':
-				checkChOpen(d.chInputActions, ActionPcmdYawClockwise)
-
-			case event.Key == keyboard.KeyArrowUp:
-				checkChOpen(d.chInputActions, ActionPcmdPitchForward)
-			case event.Key == keyboard.KeyArrowDown:
-				checkChOpen(d.chInputActions, ActionPcmdPitchBackward)
-			case event.Key == keyboard.KeyArrowLeft:
-				checkChOpen(d.chInputActions, ActionPcmdRollLeft)
-			case event.Key == keyboard.KeyArrowRight:
-				checkChOpen(d.chInputActions, ActionPcmdRollRight)
-			case event.Key == keyboard.KeySpace:
-				checkChOpen(d.chInputActions, ActionPcmdRepeatLastCmd)
-
-			case event.Key == keyboard.KeyCtrlW:
-				checkChOpen(d.chInputActions, ActionMoveToSetLatInc)
-			case event.Key == keyboard.KeyCtrlS:
-				checkChOpen(d.chInputActions, ActionMoveToSetLatDec)
-			case event.Key == keyboard.KeyCtrlA:
-				checkChOpen(d.chInputActions, ActionMoveToSetLonDec)
-			case event.Key == keyboard.KeyCtrlD:
-				checkChOpen(d.chInputActions, ActionMoveToSetLonInc)
-			case event.Key == keyboard.KeyCtrlX:
-				checkChOpen(d.chInputActions, ActionMoveToSetBufferCurrentPosition)
-			case event.Key == keyboard.KeyCtrlSpace:
-				checkChOpen(d.chInputActions, ActionMoveToExecute)
-			case event.Key == keyboard.KeyCtrlQ:
-				checkChOpen(d.chInputActions, ActionMoveToCancel)
-
-			case event.Rune == 'h':
-				checkChOpen(d.chInputActions, ActionPcmdHover)
-
+			default:
+				// The other keys are given the action bound with
+				// SetKeyBindings.
+				if action, ok := d.keyBindings.action(event); ok {
+					checkChOpen(d.chInputActions, action)
+				}
 			}
 		}
 
@@ -170,7 +152,7 @@ func (d *Drone) readKeyBoardEvent() {
 // in readKeyBoardEvent, is that we might want to have other input methods
 // then the keyboard to control the drone.
 // This function will execute the commands that arrives on the d.chInputActions.
-func (d *Drone) handleInputAction(packetCreator udpPacketCreator, ctx context.Context) {
+func (d *Drone) handleInputAction(packetCreator *udpPacketCreator, ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
@@ -178,18 +160,57 @@ func (d *Drone) handleInputAction(packetCreator udpPacketCreator, ctx context.Co
 			return
 
 		case action := <-d.chInputActions:
+			// Reject the actions not allowed in the current flying
+			// state before they are sent to the drone.
+			if err := d.checkFlyingStateFor(action); err != nil {
+				log.Printf("info: action %v refused: %v\n", action, err)
+				continue
+			}
+			if err := d.checkPreflightFor(action); err != nil {
+				log.Printf("info: action %v refused: %v\n", action, err)
+				continue
+			}
+			if !d.confirmAction(action) {
+				continue
+			}
+			d.recordAction(action)
+
 			// --------------Standard actions
 			switch action {
 			case ActionTakeoff:
+				d.warnGPSGuard("takeoff")
 				p := packetCreator.encodeCmd(Command(PilotingTakeOff), &Ardrone3PilotingTakeOffArguments{})
 				d.chSendingUDPPacket <- p
+			case ActionUserTakeoff:
+				d.warnGPSGuard("user takeoff")
+				p := packetCreator.encodeCmd(Command(PilotingUserTakeOff), &Ardrone3PilotingUserTakeOffArguments{State: 1})
+				d.chSendingUDPPacket <- p
 			case ActionLanding:
+				// A positive Gaz from the altitude controller cancels
+				// the landing.
+				d.ClearTargetAltitude()
+				// A hand launch not thrown yet is cancelled instead,
+				// which stops the motors.
+				if state, _ := d.FlyingState(); state.userTakeoffPending() {
+					p := packetCreator.encodeCmd(Command(PilotingUserTakeOff), &Ardrone3PilotingUserTakeOffArguments{State: 0})
+					d.chSendingUDPPacket <- p
+					continue
+				}
 				p := packetCreator.encodeCmd(Command(PilotingLanding), &Ardrone3PilotingLandingArguments{})
 				d.chSendingUDPPacket <- p
+			case ActionEmergency:
+				d.ClearTargetAltitude()
+				p := packetCreator.encodeCmd(Command(PilotingEmergency), &Ardrone3PilotingEmergencyArguments{})
+				d.chSendingUDPPacket <- p
 			case ActionNavigateHomeStart:
+				if err := d.returnHomeAvailable(); err != nil {
+					d.startRTHFallback(err)
+					continue
+				}
 				p := packetCreator.encodeCmd(Command(PilotingNavigateHome), &Ardrone3PilotingNavigateHomeArguments{Start: 1})
 				d.chSendingUDPPacket <- p
 			case ActionNavigateHomeStop:
+				d.StopRTHFallback()
 				p := packetCreator.encodeCmd(Command(PilotingNavigateHome), &Ardrone3PilotingNavigateHomeArguments{Start: 0})
 				d.chSendingUDPPacket <- p
 
@@ -202,11 +223,11 @@ func (d *Drone) handleInputAction(packetCreator udpPacketCreator, ctx context.Co
 				d.pcmd.Flag = 1
 				d.pcmd.Gaz++
 				d.pcmd.Gaz = d.CheckLimitPcmdField(d.pcmd.Gaz)
-				arg := &Ardrone3PilotingPCMDArguments{
+				arg := Ardrone3PilotingPCMDArguments{
 					Flag: 1,
 					Gaz:  d.pcmd.Gaz,
 				}
-				d.chPcmdPacketScheduler <- packetCreator.encodeCmd(Command(PilotingPCMD), arg)
+				d.setShapedPcmd(arg, pcmdGaz)
 			case ActionPcmdGazDec:
 				if d.pcmd.Gaz > 0 {
 					d.pcmd.Gaz = 0
@@ -214,11 +235,11 @@ func (d *Drone) handleInputAction(packetCreator udpPacketCreator, ctx context.Co
 				d.pcmd.Flag = 1
 				d.pcmd.Gaz--
 				d.pcmd.Gaz = d.CheckLimitPcmdField(d.pcmd.Gaz)
-				arg := &Ardrone3PilotingPCMDArguments{
+				arg := Ardrone3PilotingPCMDArguments{
 					Flag: 1,
 					Gaz:  d.pcmd.Gaz,
 				}
-				d.chPcmdPacketScheduler <- packetCreator.encodeCmd(Command(PilotingPCMD), arg)
+				d.setShapedPcmd(arg, pcmdGaz)
 
 			case ActionPcmdYawCounterClockwise:
 				if d.pcmd.Yaw > 0 {
@@ -227,11 +248,11 @@ func (d *Drone) handleInputAction(packetCreator udpPacketCreator, ctx context.Co
 				d.pcmd.Flag = 1
 				d.pcmd.Yaw--
 				d.pcmd.Yaw = d.CheckLimitPcmdField(d.pcmd.Yaw)
-				arg := &Ardrone3PilotingPCMDArguments{
+				arg := Ardrone3PilotingPCMDArguments{
 					Flag: 1,
 					Yaw:  d.pcmd.Yaw,
 				}
-				d.chPcmdPacketScheduler <- packetCreator.encodeCmd(Command(PilotingPCMD), arg)
+				d.setShapedPcmd(arg, pcmdYaw)
 			case ActionPcmdYawClockwise:
 				if d.pcmd.Yaw < 0 {
 					d.pcmd.Yaw = 0
@@ -239,11 +260,11 @@ func (d *Drone) handleInputAction(packetCreator udpPacketCreator, ctx context.Co
 				d.pcmd.Flag = 1
 				d.pcmd.Yaw++
 				d.pcmd.Yaw = d.CheckLimitPcmdField(d.pcmd.Yaw)
-				arg := &Ardrone3PilotingPCMDArguments{
+				arg := Ardrone3PilotingPCMDArguments{
 					Flag: 1,
 					Yaw:  d.pcmd.Yaw,
 				}
-				d.chPcmdPacketScheduler <- packetCreator.encodeCmd(Command(PilotingPCMD), arg)
+				d.setShapedPcmd(arg, pcmdYaw)
 
 			case ActionPcmdHover:
 				d.pcmd = Ardrone3PilotingPCMDArguments{
@@ -256,7 +277,7 @@ func (d *Drone) handleInputAction(packetCreator udpPacketCreator, ctx context.Co
 				}
 
 				arg := d.pcmd
-				d.chPcmdPacketScheduler <- packetCreator.encodeCmd(Command(PilotingPCMD), arg)
+				d.setShapedPcmd(arg, pcmdAllAxes)
 
 			case ActionPcmdPitchForward:
 				if d.pcmd.Pitch < 0 {
@@ -265,11 +286,11 @@ func (d *Drone) handleInputAction(packetCreator udpPacketCreator, ctx context.Co
 				d.pcmd.Flag = 1
 				d.pcmd.Pitch++
 				d.pcmd.Pitch = d.CheckLimitPcmdField(d.pcmd.Pitch)
-				arg := &Ardrone3PilotingPCMDArguments{
+				arg := Ardrone3PilotingPCMDArguments{
 					Flag:  1,
 					Pitch: d.pcmd.Pitch,
 				}
-				d.chPcmdPacketScheduler <- packetCreator.encodeCmd(Command(PilotingPCMD), arg)
+				d.setShapedPcmd(arg, pcmdPitch)
 			case ActionPcmdPitchBackward:
 				if d.pcmd.Pitch > 0 {
 					d.pcmd.Pitch = 0
@@ -277,11 +298,11 @@ func (d *Drone) handleInputAction(packetCreator udpPacketCreator, ctx context.Co
 				d.pcmd.Flag = 1
 				d.pcmd.Pitch--
 				d.pcmd.Pitch = d.CheckLimitPcmdField(d.pcmd.Pitch)
-				arg := &Ardrone3PilotingPCMDArguments{
+				arg := Ardrone3PilotingPCMDArguments{
 					Flag:  1,
 					Pitch: d.pcmd.Pitch,
 				}
-				d.chPcmdPacketScheduler <- packetCreator.encodeCmd(Command(PilotingPCMD), arg)
+				d.setShapedPcmd(arg, pcmdPitch)
 
 			case ActionPcmdRollLeft:
 				if d.pcmd.Roll > 0 {
@@ -290,11 +311,11 @@ func (d *Drone) handleInputAction(packetCreator udpPacketCreator, ctx context.Co
 				d.pcmd.Flag = 1
 				d.pcmd.Roll--
 				d.pcmd.Roll = d.CheckLimitPcmdField(d.pcmd.Roll)
-				arg := &Ardrone3PilotingPCMDArguments{
+				arg := Ardrone3PilotingPCMDArguments{
 					Flag: 1,
 					Roll: d.pcmd.Roll,
 				}
-				d.chPcmdPacketScheduler <- packetCreator.encodeCmd(Command(PilotingPCMD), arg)
+				d.setShapedPcmd(arg, pcmdRoll)
 			case ActionPcmdRollRight:
 				if d.pcmd.Roll < 0 {
 					d.pcmd.Roll = 0
@@ -302,13 +323,13 @@ func (d *Drone) handleInputAction(packetCreator udpPacketCreator, ctx context.Co
 				d.pcmd.Flag = 1
 				d.pcmd.Roll--
 				d.pcmd.Roll = d.CheckLimitPcmdField(d.pcmd.Roll)
-				arg := &Ardrone3PilotingPCMDArguments{
+				arg := Ardrone3PilotingPCMDArguments{
 					Flag: 1,
 					Roll: d.pcmd.Roll,
 				}
-				d.chPcmdPacketScheduler <- packetCreator.encodeCmd(Command(PilotingPCMD), arg)
+				d.setShapedPcmd(arg, pcmdRoll)
 			case ActionPcmdRepeatLastCmd:
-				d.chPcmdPacketScheduler <- packetCreator.encodeCmd(Command(PilotingPCMD), d.pcmd)
+				d.setShapedPcmd(d.pcmd, pcmdAllAxes)
 
 			// --------------moveTo
 			// The commands below is a bit overly complicated to use, but they
@@ -353,18 +374,164 @@ func (d *Drone) handleInputAction(packetCreator udpPacketCreator, ctx context.Co
 				// The idea here is to use this action with a moveTo command to the drone,
 				// and giving the current moveTo variables as arguments to the moveTo
 				// command.
-
-				d.gps.doingMoveTo = true
-				d.gps.chMoveToExecute <- struct{}{}
-				// TODO: send the moveTo command here!!!
-				log.Printf("*************************************************************\n")
-				log.Printf("ActionMoveToExecute: current value of buffer: %#v\n", d.gps)
-				log.Printf("*************************************************************\n")
+				if err := d.executeMoveTo(); err != nil {
+					log.Printf("ActionMoveToExecute: refused: %v\n", err)
+					continue
+				}
+				log.Printf("ActionMoveToExecute: waypoints in buffer: %v\n", len(d.moveToBuffer.list()))
 			case ActionMoveToCancel:
-				d.gps.doingMoveTo = false
-				d.gps.chMoveToCancel <- struct{}{}
+				signalMoveTo(d.gps.chMoveToCancel)
+
+			case ActionCancelMoveBy:
+				d.chSendingUDPPacket <- packetCreator.encodeCmd(Command(PilotingCancelMoveBy), &Ardrone3PilotingCancelMoveByArguments{})
+				d.events.publish(EventMoveByCancelled, nil)
+
+			// --------------nudge
+			// Move the drone a fixed distance in meters with moveBy.
+			// The axis of moveBy are X forward, Y right, and Z down.
+			case ActionNudgeForward:
+				d.sendMoveBy(packetCreator, MoveBy{DX: d.nudgeConfig.get()})
+			case ActionNudgeBackward:
+				d.sendMoveBy(packetCreator, MoveBy{DX: -d.nudgeConfig.get()})
+			case ActionNudgeLeft:
+				d.sendMoveBy(packetCreator, MoveBy{DY: -d.nudgeConfig.get()})
+			case ActionNudgeRight:
+				d.sendMoveBy(packetCreator, MoveBy{DY: d.nudgeConfig.get()})
+			case ActionNudgeUp:
+				d.sendMoveBy(packetCreator, MoveBy{DZ: -d.nudgeConfig.get()})
+			case ActionNudgeDown:
+				d.sendMoveBy(packetCreator, MoveBy{DZ: d.nudgeConfig.get()})
+
+			// --------------animations
+			case ActionFlip:
+				d.chSendingUDPPacket <- packetCreator.encodeCmd(Command(AnimationsFlip), &Ardrone3AnimationsFlipArguments{Direction: 0})
+
+			// --------------flat trim
+			case ActionFlatTrim:
+				c := Command{Project: ProjectArdrone3, Class: Ardrone3PilotingClassPiloting, Cmd: flatTrimCmd}
+				d.chSendingUDPPacket <- packetCreator.encodeCmd(c, flatTrimArguments{})
+
+			// --------------piloting settings
+			case ActionAbsoluteControlToggle:
+				on := !d.PilotingSettings().AbsoluteControl
+				log.Printf("info: setting absolute control to %v\n", on)
+				d.chSendingUDPPacket <- packetCreator.encodeCmd(Command(PilotingSettingsAbsolutControl), &Ardrone3PilotingSettingsAbsolutControlArguments{On: boolToUint8(on)})
+
+			// --------------control authority
+			case ActionResumeMission:
+				d.ResumeMission()
 			}
 		}
 
 	}
 }
+
+// sendCmd will encode the command with the given arguments, and put
+// the resulting UDP packet on the channel for sending to the drone.
+// An error is returned if the packet could not be handed over to the
+// sender in time, like when there is no active connection with the
+// drone.
+func (d *Drone) sendCmd(c Command, arg Encoder) error {
+	p := d.packetCreator.encodeCmd(c, arg)
+
+	select {
+	case d.chSendingUDPPacket <- p:
+		d.publishMoveTo(arg)
+		d.publishMoveBy(arg)
+		return nil
+	case <-time.After(time.Second * 2):
+		return fmt.Errorf("sendCmd: timed out waiting for the UDP sender, command %#v", c)
+	}
+}
+
+// publishMoveTo will publish an event if the command sent was a moveTo
+// or a cancel of the moveTo.
+func (d *Drone) publishMoveTo(arg Encoder) {
+	d.trackMoveTo(arg)
+
+	switch arg := arg.(type) {
+	case *Ardrone3PilotingmoveToArguments:
+		d.events.publish(EventMoveToSent, Position{
+			Latitude:  arg.Latitude,
+			Longitude: arg.Longitude,
+			Altitude:  arg.Altitude,
+		})
+	case *Ardrone3PilotingCancelMoveToArguments:
+		d.events.publish(EventMoveToCancelled, nil)
+	}
+}
+
+// SetHeadless will disable the keyboard control when set to true, so
+// the driver can run on machines without a terminal, like a companion
+// computer or a container. All the control must then be done with the
+// API, like SendAction and SendPcmd. Must be called before Start.
+func (d *Drone) SetHeadless(headless bool) {
+	d.headless = headless
+}
+
+// SendAction will give the action to the driver the same way as when
+// the key for the action is pressed on the keyboard, like ActionTakeoff
+// or ActionLanding.
+func (d *Drone) SendAction(action InputAction) error {
+	select {
+	case d.chInputActions <- action:
+		return nil
+	case <-time.After(time.Second * 2):
+		return fmt.Errorf("SendAction: timed out, no connection with drone")
+	}
+}
+
+// nudgeConfig holds the distance of the nudge actions, which can be
+// changed while the input actions are handled.
+type nudgeConfig struct {
+	mu       sync.Mutex
+	distance float32
+}
+
+// get will return the distance in meters.
+func (n *nudgeConfig) get() float32 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.distance
+}
+
+// SetNudgeDistance will set the distance in meters the drone will
+// move for each of the nudge actions.
+func (d *Drone) SetNudgeDistance(meters float32) error {
+	if meters <= 0 || meters > 10 {
+		return fmt.Errorf("SetNudgeDistance: distance must be within (0, 10] meters, got %v", meters)
+	}
+
+	d.nudgeConfig.mu.Lock()
+	defer d.nudgeConfig.mu.Unlock()
+
+	d.nudgeConfig.distance = meters
+
+	return nil
+}
+
+// SendPcmd will send a PCMD with the values given via the PCMD
+// scheduler, which for example can be used by computer vision code
+// to correct the position of the drone based on the video frames.
+// All the values are percentages in the range [-100, 100]. While the
+// altitude hold is enabled the Gaz is left to the altitude controller.
+func (d *Drone) SendPcmd(roll int8, pitch int8, yaw int8, gaz int8) error {
+	arg := Ardrone3PilotingPCMDArguments{
+		Roll:  d.CheckLimitPcmdField(roll),
+		Pitch: d.CheckLimitPcmdField(pitch),
+		Yaw:   d.CheckLimitPcmdField(yaw),
+		Gaz:   d.CheckLimitPcmdField(gaz),
+	}
+	axes := pcmdAllAxes
+	d.altitudeHold.mu.Lock()
+	if d.altitudeHold.enabled {
+		axes &^= pcmdGaz
+	}
+	d.altitudeHold.mu.Unlock()
+
+	arg = d.updatePcmd(arg, axes)
+	d.recordPcmd(arg)
+
+	return nil
+}
@@ -1,33 +1,332 @@
 package parrotbebop
 
 import (
-	"fmt"
+	"time"
 )
 
 // Try to figure out what kind of command that where received.
 // Based on the type of cmdArgs we can execute som action.
 func (d *Drone) checkCmdFromDrone(cmd protocolARCommands, cmdArgs interface{}) {
-	fmt.Printf("----------COMMAND-------------------------------------------\r\n")
-	fmt.Printf("-- cmd = %+v\r\n", cmd)
-	fmt.Printf("-- Value of cmdArgs = %+v\r\n", cmdArgs)
-	fmt.Printf("-- Type of cmdArgs = %+T\r\n", cmdArgs)
+	// All the settings are kept in the settings store, in addition to
+	// the handling of each of them below.
+	d.handleSetting(cmdArgs)
+	d.cmdWaiters.notify(commandOf(cmd), cmdArgs)
+
 	switch cmdArgs := cmdArgs.(type) {
 	case Ardrone3CameraStateOrientationArguments:
 		//log.Printf("** EXECUTING ACTION FOR TYPE, Ardrone3CameraStateOrientationArguments ...........\r\n")
+	case Ardrone3CameraStateOrientationV2Arguments:
+		d.handleCameraOrientation(cmdArgs.Tilt, cmdArgs.Pan)
+	case Ardrone3CameraStatedefaultCameraOrientationV2Arguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Gimbal.DefaultTilt = cmdArgs.Tilt
+			t.Gimbal.DefaultPan = cmdArgs.Pan
+		})
+	case Ardrone3CameraStateVelocityRangeArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Gimbal.MaxTiltSpeed = cmdArgs.Maxtilt
+			t.Gimbal.MaxPanSpeed = cmdArgs.Maxpan
+		})
+	case CommonCameraSettingsStateCameraSettingsChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Gimbal.TiltMin = cmdArgs.TiltMin
+			t.Gimbal.TiltMax = cmdArgs.TiltMax
+			t.Gimbal.PanMin = cmdArgs.PanMin
+			t.Gimbal.PanMax = cmdArgs.PanMax
+		})
 	case Ardrone3PilotingStateAttitudeChangedArguments:
-		//log.Printf("** EXECUTING ACTION FOR TYPE, Ardrone3PilotingStateAttitudeChangedArguments\r\n")
+		d.telemetry.update(func(t *Telemetry) {
+			t.Roll = cmdArgs.Roll
+			t.Pitch = cmdArgs.Pitch
+			t.Yaw = cmdArgs.Yaw
+		})
+		a := Attitude{
+			Roll:  cmdArgs.Roll,
+			Pitch: cmdArgs.Pitch,
+			Yaw:   cmdArgs.Yaw,
+		}
+		d.events.publishThrottled(EventAttitudeChanged, attitudeEventInterval, a)
+		d.telemetryStreams.publishAttitude(a)
+	case Ardrone3PilotingStateFlyingStateChangedArguments:
+		d.setFlyingState(FlyingState(cmdArgs.State))
+	case Ardrone3PilotingStateAlertStateChangedArguments:
+		d.handleAlertState(AlertState(cmdArgs.State))
+	case CommonCommonStateSensorsStatesListChangedArguments:
+		d.handleSensorState(SensorState{
+			Sensor: Sensor(cmdArgs.SensorName),
+			OK:     cmdArgs.SensorState == 1,
+		})
+	case Ardrone3SettingsStateMotorErrorStateChangedArguments:
+		d.handleMotorError(cmdArgs.MotorIds, MotorError(cmdArgs.MotorError))
+	case Ardrone3SettingsStateMotorErrorLastErrorChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Motor.LastError = MotorError(cmdArgs.MotorError)
+		})
+	case Ardrone3SettingsStateMotorSoftwareVersionChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Motor.SoftwareVersion = cmdArgs.Version
+		})
+	case Ardrone3SettingsStateMotorFlightsStatusChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Motor.Flights = cmdArgs.NbFlights
+			t.Motor.LastFlightDuration = cmdArgs.LastFlightDuration
+			t.Motor.TotalFlightDuration = cmdArgs.TotalFlightDuration
+		})
+	case CommonCommonStateAllStatesChangedArguments:
+		d.events.publish(EventAllStatesReceived, nil)
+	case CommonSettingsStateAllSettingsChangedArguments:
+		d.events.publish(EventAllSettingsReceived, nil)
+	case CommonSettingsStateProductNameChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Info.Name = cmdArgs.Name
+		})
+	case CommonSettingsStateProductVersionChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Info.SoftwareVersion = cmdArgs.Software
+			t.Info.HardwareVersion = cmdArgs.Hardware
+		})
+	case CommonSettingsStateProductSerialHighChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Info.SerialHigh = cmdArgs.High
+		})
+	case CommonSettingsStateProductSerialLowChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Info.SerialLow = cmdArgs.Low
+		})
+	case Ardrone3NetworkSettingsStateWifiSelectionChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Wifi.Selection = WifiSelection(cmdArgs.TypeX)
+			t.Wifi.Band = WifiBand(cmdArgs.Band)
+			t.Wifi.Channel = cmdArgs.Channel
+		})
+	case CommonSettingsStateCountryChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Wifi.Country = cmdArgs.Code
+		})
+	case CommonSettingsStateAutoCountryChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Wifi.AutoCountry = cmdArgs.Automatic == 1
+		})
+	case Ardrone3NetworkStateWifiAuthChannelListChangedArguments:
+		d.handleWifiAuthChannel(WifiChannel{
+			Band:    WifiBand(cmdArgs.Band),
+			Channel: cmdArgs.Channel,
+			Outdoor: cmdArgs.Inorout == 1,
+		})
+	case Ardrone3NetworkStateAllWifiAuthChannelChangedArguments:
+		d.handleWifiAuthChannelsDone()
+	case Ardrone3NetworkStateWifiScanListChangedArguments:
+		d.handleWifiNetwork(WifiNetwork{
+			SSID:    cmdArgs.Ssid,
+			RSSI:    cmdArgs.Rssi,
+			Band:    WifiBand(cmdArgs.Band),
+			Channel: cmdArgs.Channel,
+		})
+	case Ardrone3NetworkStateAllWifiScanChangedArguments:
+		d.handleWifiScanDone()
+	case CommonCommonStateBatteryStateChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Battery = cmdArgs.Percent
+		})
+		d.recordBattery(cmdArgs.Percent)
+		d.telemetryStreams.publishBattery(int(cmdArgs.Percent))
+	case CommonCalibrationStateMagnetoCalibrationRequiredStateArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.MagnetoCalibrationRequired = cmdArgs.Required == 1
+		})
+	case flatTrimArguments:
+		d.handleFlatTrimChanged()
+	case Ardrone3MediaStreamingStateVideoEnableChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.VideoStream = VideoEnableState(cmdArgs.Enabled)
+		})
+	case Ardrone3MediaStreamingStateVideoStreamModeChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.VideoStreamMode = VideoStreamMode(cmdArgs.Mode)
+		})
+	case Ardrone3MediaRecordStateVideoStateChangedV2Arguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Media.VideoState = VideoRecordState(cmdArgs.State)
+			t.Media.VideoError = MediaError(cmdArgs.Error)
+		})
+	case Ardrone3MediaRecordStatePictureStateChangedV2Arguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Media.PictureState = PictureState(cmdArgs.State)
+			t.Media.PictureError = MediaError(cmdArgs.Error)
+		})
+	case Ardrone3MediaRecordEventPictureEventChangedArguments:
+		// Event 0 is the picture taken, and 1 is failed.
+		if cmdArgs.Event == 0 {
+			d.flightPhotoTaken()
+		}
+	case CommonCommonStateMassStorageStateListChangedArguments:
+		d.updateMassStorage(cmdArgs.Massstorageid, func(m *MassStorage) {
+			m.Name = cmdArgs.Name
+		})
+	case CommonCommonStateMassStorageInfoStateListChangedArguments:
+		d.updateMassStorage(cmdArgs.Massstorageid, func(m *MassStorage) {
+			m.Size = cmdArgs.Size
+			m.UsedSize = cmdArgs.Usedsize
+			m.Plugged = cmdArgs.Plugged == 1
+			m.Full = cmdArgs.Full == 1
+			m.Internal = cmdArgs.Internal == 1
+		})
+	case CommonCommonStateMassStorageContentArguments:
+		d.updateMassStorage(cmdArgs.Massstorageid, func(m *MassStorage) {
+			m.Photos = cmdArgs.NbPhotos
+			m.Videos = cmdArgs.NbVideos
+		})
+	case CommonCommonStateMassStorageInfoRemainingListChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Storage.FreeSpace = cmdArgs.Freespace
+			t.Storage.RecordingTime = cmdArgs.Rectime
+			t.Storage.PhotosRemaining = cmdArgs.Photoremaining
+		})
+	case Ardrone3PictureSettingsStatePictureFormatChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Camera.PictureFormat = PictureFormat(cmdArgs.TypeX)
+		})
+	case Ardrone3PictureSettingsStateAutoWhiteBalanceChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Camera.WhiteBalance = WhiteBalance(cmdArgs.TypeX)
+		})
+	case Ardrone3PictureSettingsStateExpositionChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Camera.Exposure = cmdArgs.Value
+			t.Camera.ExposureMin = cmdArgs.Min
+			t.Camera.ExposureMax = cmdArgs.Max
+		})
+	case Ardrone3PictureSettingsStateSaturationChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Camera.Saturation = cmdArgs.Value
+			t.Camera.SaturationMin = cmdArgs.Min
+			t.Camera.SaturationMax = cmdArgs.Max
+		})
+	case Ardrone3PictureSettingsStateTimelapseChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Camera.Timelapse = cmdArgs.Enabled == 1
+			t.Camera.TimelapseInterval = cmdArgs.Interval
+		})
+	case Ardrone3PictureSettingsStateVideoAutorecordChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Camera.VideoAutorecord = cmdArgs.Enabled == 1
+			t.Camera.VideoAutorecordStorage = cmdArgs.Massstorageid
+		})
+	case Ardrone3PictureSettingsStateVideoStabilizationModeChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Camera.VideoStabilization = VideoStabilization(cmdArgs.Mode)
+		})
+	case Ardrone3PictureSettingsStateVideoRecordingModeChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Camera.VideoRecordingMode = VideoRecordingMode(cmdArgs.Mode)
+		})
+	case Ardrone3PictureSettingsStateVideoFramerateChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Camera.VideoFramerate = VideoFramerate(cmdArgs.Framerate)
+		})
+	case Ardrone3PictureSettingsStateVideoResolutionsChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Camera.VideoResolution = VideoResolution(cmdArgs.TypeX)
+		})
+	case Ardrone3AntiflickeringStateelectricFrequencyChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Camera.ElectricFrequency = ElectricFrequency(cmdArgs.Frequency)
+		})
+	case Ardrone3AntiflickeringStatemodeChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Camera.AntiflickeringMode = AntiflickeringMode(cmdArgs.Mode)
+		})
+	case Ardrone3PilotingSettingsStateBankedTurnChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Piloting.BankedTurn = cmdArgs.State == 1
+		})
+	case Ardrone3PilotingSettingsStateAbsolutControlChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Piloting.AbsoluteControl = cmdArgs.On == 1
+		})
+	case autonomousFlightStateChangedArguments:
+		d.handleAutonomousFlightState(cmdArgs)
+	case Ardrone3PilotingStateWindStateChangedArguments:
+		d.handleWindState(WarningLevel(cmdArgs.State))
+	case Ardrone3PilotingStateVibrationLevelChangedArguments:
+		d.handleVibrationLevel(WarningLevel(cmdArgs.State))
+	case Ardrone3PilotingStateAltitudeChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Altitude = cmdArgs.Altitude
+		})
+	case Ardrone3PilotingStatePositionChangedArguments:
+		p := Position{
+			Latitude:  cmdArgs.Latitude,
+			Longitude: cmdArgs.Longitude,
+			Altitude:  cmdArgs.Altitude,
+		}
+		d.telemetry.update(func(t *Telemetry) {
+			t.Position = p
+		})
+		d.handleGPSPosition(p, time.Now())
+		if validGPSPosition(p) {
+			d.telemetryStreams.publishPosition(p)
+		}
+		d.logFlightSample(p, time.Now())
+		d.flightPosition(p, time.Now())
+		d.updateWaypointDistance(p)
+		d.publishMissionStatus(time.Now())
+	case Ardrone3PilotingStateSpeedChangedArguments:
+		v := Velocity{
+			North: float64(cmdArgs.SpeedX),
+			East:  float64(cmdArgs.SpeedY),
+			Down:  float64(cmdArgs.SpeedZ),
+		}
+		d.telemetry.update(func(t *Telemetry) {
+			t.Velocity = v
+			t.GroundSpeed = v.GroundSpeed()
+			t.ClimbRate = v.ClimbRate()
+		})
+		d.handleSpeed(cmdArgs.SpeedX, cmdArgs.SpeedY, cmdArgs.SpeedZ, time.Now())
 	case Ardrone3PilotingStateGpsLocationChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.Position = Position{
+				Latitude:  cmdArgs.Latitude,
+				Longitude: cmdArgs.Longitude,
+				Altitude:  cmdArgs.Altitude,
+			}
+		})
 		d.gps.chCurrentLocation <- gpsLatLonAlt{
 			latitude:  cmdArgs.Latitude,
 			longitude: cmdArgs.Longitude,
 			altitude:  cmdArgs.Altitude,
 		}
-	case Ardrone3PilotingStatemoveToChanged:
+	case Ardrone3PilotingEventmoveByEndArguments:
+		d.handleMoveByEnd(cmdArgs)
+	case Ardrone3PilotingStatemoveToChangedArguments:
 		// Indicated that the drone have moved to the asked position.
 		// We send a signal to the moveTo handling here to indicate
 		// that it can pick the next available position in the buffer.
-		d.gps.chMoveToPositionDone <- struct{}{}
+		d.handleMoveToChanged(cmdArgs.Status)
+	case Ardrone3GPSSettingsStateHomeChangedArguments:
+		d.home.setPosition(cmdArgs.Latitude, cmdArgs.Longitude, cmdArgs.Altitude)
+	case Ardrone3GPSSettingsStateResetHomeChangedArguments:
+		d.home.setPosition(cmdArgs.Latitude, cmdArgs.Longitude, cmdArgs.Altitude)
+	case Ardrone3GPSSettingsStateHomeTypeChangedArguments:
+		d.home.setType(HomeType(cmdArgs.TypeX))
+	case Ardrone3GPSSettingsStateGPSFixStateChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.GPSFixed = cmdArgs.Fixed == 1
+		})
+		d.handleGPSFix(cmdArgs.Fixed == 1, time.Now())
+	case Ardrone3GPSStateNumberOfSatelliteChangedArguments:
+		d.telemetry.update(func(t *Telemetry) {
+			t.NumberOfSatellites = cmdArgs.NumberOfSatellite
+		})
 	}
-	fmt.Printf("-----------------------------------------------------------\r\n")
+}
 
+// debugCmd will print the command received from the drone to the raw
+// debug output.
+func (d *Drone) debugCmd(cmd protocolARCommands, cmdArgs interface{}) {
+	d.debugf("----------COMMAND-------------------------------------------\r\n")
+	d.debugf("-- cmd = %+v\r\n", cmd)
+	d.debugf("-- Value of cmdArgs = %+v\r\n", cmdArgs)
+	d.debugf("-- Type of cmdArgs = %+T\r\n", cmdArgs)
+	d.debugf("-----------------------------------------------------------\r\n")
 }
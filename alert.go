@@ -0,0 +1,150 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// AlertState is the alert state reported by the drone.
+type AlertState uint32
+
+const (
+	AlertNone            AlertState = 0
+	AlertUser            AlertState = 1
+	AlertCutOut          AlertState = 2
+	AlertCriticalBattery AlertState = 3
+	AlertLowBattery      AlertState = 4
+	AlertTooMuchAngle    AlertState = 5
+)
+
+// String will return the name of the alert state.
+func (a AlertState) String() string {
+	switch a {
+	case AlertNone:
+		return "none"
+	case AlertUser:
+		return "user"
+	case AlertCutOut:
+		return "cut_out"
+	case AlertCriticalBattery:
+		return "critical_battery"
+	case AlertLowBattery:
+		return "low_battery"
+	case AlertTooMuchAngle:
+		return "too_much_angle"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(a))
+}
+
+// Sensor is the name of a sensor on the drone.
+type Sensor uint32
+
+const (
+	SensorIMU            Sensor = 0
+	SensorBarometer      Sensor = 1
+	SensorUltrasound     Sensor = 2
+	SensorGPS            Sensor = 3
+	SensorMagnetometer   Sensor = 4
+	SensorVerticalCamera Sensor = 5
+)
+
+// String will return the name of the sensor.
+func (s Sensor) String() string {
+	switch s {
+	case SensorIMU:
+		return "IMU"
+	case SensorBarometer:
+		return "barometer"
+	case SensorUltrasound:
+		return "ultrasound"
+	case SensorGPS:
+		return "GPS"
+	case SensorMagnetometer:
+		return "magnetometer"
+	case SensorVerticalCamera:
+		return "vertical_camera"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(s))
+}
+
+// SensorState is the state of a single sensor on the drone.
+type SensorState struct {
+	Sensor Sensor
+	OK     bool
+}
+
+// alertPolicy holds the alerts that should make the driver land the
+// drone automatically.
+type alertPolicy struct {
+	mu       sync.Mutex
+	autoLand map[AlertState]bool
+}
+
+// SetAutoLandOnAlert will make the driver send a landing command to
+// the drone when any of the alerts given are reported while the drone
+// is in the air. Calling it with no alerts will disable auto landing.
+func (d *Drone) SetAutoLandOnAlert(alerts ...AlertState) {
+	d.alertPolicy.mu.Lock()
+	defer d.alertPolicy.mu.Unlock()
+
+	d.alertPolicy.autoLand = make(map[AlertState]bool)
+	for _, a := range alerts {
+		d.alertPolicy.autoLand[a] = true
+	}
+}
+
+// handleAlertState will update the alert state telemetry, publish a
+// high priority event, and land the drone if the auto land policy
+// says so.
+func (d *Drone) handleAlertState(a AlertState) {
+	d.telemetry.update(func(t *Telemetry) {
+		t.AlertState = a
+	})
+
+	if a == AlertNone {
+		d.events.publish(EventAlertStateChanged, a)
+		return
+	}
+
+	log.Printf("warning: alert state from drone: %v\n", a)
+	d.events.publishPriority(EventAlertStateChanged, PriorityHigh, a)
+
+	d.alertPolicy.mu.Lock()
+	land := d.alertPolicy.autoLand[a]
+	d.alertPolicy.mu.Unlock()
+
+	if state, _ := d.FlyingState(); !land || !state.Airborne() {
+		return
+	}
+
+	log.Printf("warning: landing drone because of alert: %v\n", a)
+	// Sending is done in it's own go routine so we don't block the
+	// decoding of packets from the drone.
+	go func() {
+		if err := d.sendCmd(Command(PilotingLanding), &Ardrone3PilotingLandingArguments{}); err != nil {
+			log.Printf("error: failed to send landing on alert: %v\n", err)
+		}
+	}()
+}
+
+// handleSensorState will update the sensor state telemetry, and
+// publish a high priority event if a sensor is not ok.
+func (d *Drone) handleSensorState(s SensorState) {
+	d.telemetry.update(func(t *Telemetry) {
+		if t.Sensors == nil {
+			t.Sensors = make(map[Sensor]bool)
+		}
+		t.Sensors[s.Sensor] = s.OK
+	})
+
+	if s.OK {
+		d.events.publish(EventSensorStateChanged, s)
+		return
+	}
+
+	log.Printf("warning: sensor not ok: %v\n", s.Sensor)
+	d.events.publishPriority(EventSensorStateChanged, PriorityHigh, s)
+}
@@ -0,0 +1,137 @@
+package parrotbebop
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// altitudeHold holds the values for the altitude controller.
+// The target are set by the API methods, and read by the controller
+// go routine, so all access should be done while holding the mutex.
+type altitudeHold struct {
+	mu sync.Mutex
+	// enabled is true when the controller should try to reach and
+	// hold the target altitude.
+	enabled bool
+	// target altitude in meters.
+	target float64
+	// reached is true when the altitude is within the tolerance of
+	// the target altitude.
+	reached bool
+}
+
+const (
+	// altitudeHoldInterval is how often the altitude controller will
+	// calculate and send a new Gaz value.
+	altitudeHoldInterval = time.Millisecond * 100
+	// altitudeHoldTolerance in meters, for when the target altitude
+	// is considered reached.
+	altitudeHoldTolerance = 0.3
+	// altitudeHoldGain is the Gaz percentage to use per meter of
+	// altitude error.
+	altitudeHoldGain = 20
+	// altitudeHoldMaxGaz is the max Gaz percentage the controller
+	// will use.
+	altitudeHoldMaxGaz = 50
+)
+
+// SetTargetAltitude will make the altitude controller adjust the Gaz
+// of the drone to reach and hold the altitude given in meters.
+func (d *Drone) SetTargetAltitude(meters float64) error {
+	if meters < 0 {
+		return fmt.Errorf("SetTargetAltitude: altitude can not be negative, got %v", meters)
+	}
+
+	d.altitudeHold.mu.Lock()
+	defer d.altitudeHold.mu.Unlock()
+
+	d.altitudeHold.enabled = true
+	d.altitudeHold.target = meters
+	d.altitudeHold.reached = false
+
+	return nil
+}
+
+// ClearTargetAltitude will stop the altitude controller, and give
+// the control of the Gaz back to the operator.
+func (d *Drone) ClearTargetAltitude() {
+	d.altitudeHold.mu.Lock()
+	defer d.altitudeHold.mu.Unlock()
+
+	d.altitudeHold.enabled = false
+	d.altitudeHold.reached = false
+}
+
+// TargetAltitudeReached will return true if the altitude controller is
+// enabled, and the altitude is within the tolerance of the target.
+func (d *Drone) TargetAltitudeReached() bool {
+	d.altitudeHold.mu.Lock()
+	defer d.altitudeHold.mu.Unlock()
+
+	return d.altitudeHold.enabled && d.altitudeHold.reached
+}
+
+// startAltitudeController will run the altitude controller until the
+// context is done. When a target altitude is set the controller will
+// for each interval calculate the Gaz needed based on the difference
+// between the current altitude and the target, and send it to the
+// drone via the PCMD scheduler. Only the Gaz of the PCMD state is set,
+// so the other axes are left to the pilot.
+func (d *Drone) startAltitudeController(ctx context.Context) {
+	ticker := time.NewTicker(altitudeHoldInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("info: exiting startAltitudeController")
+			return
+		case <-ticker.C:
+			d.altitudeHold.mu.Lock()
+			enabled := d.altitudeHold.enabled
+			target := d.altitudeHold.target
+			d.altitudeHold.mu.Unlock()
+
+			if !enabled {
+				continue
+			}
+
+			altitude := d.telemetry.snapshot().Altitude
+			gaz, reached := altitudeGaz(target, altitude)
+
+			d.altitudeHold.mu.Lock()
+			d.altitudeHold.reached = reached
+			d.altitudeHold.mu.Unlock()
+
+			arg := Ardrone3PilotingPCMDArguments{
+				Gaz: gaz,
+			}
+
+			d.updatePcmd(arg, pcmdGaz)
+		}
+	}
+}
+
+// altitudeGaz will calculate the Gaz value to use for going from the
+// current altitude to the target altitude, and if the target is
+// considered reached.
+func altitudeGaz(target float64, altitude float64) (int8, bool) {
+	diff := target - altitude
+	if math.Abs(diff) <= altitudeHoldTolerance {
+		return 0, true
+	}
+
+	gaz := diff * altitudeHoldGain
+	switch {
+	case gaz > altitudeHoldMaxGaz:
+		gaz = altitudeHoldMaxGaz
+	case gaz < -altitudeHoldMaxGaz:
+		gaz = -altitudeHoldMaxGaz
+	}
+
+	return int8(gaz), false
+}
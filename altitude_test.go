@@ -0,0 +1,47 @@
+package parrotbebop
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAltitudeGaz(t *testing.T) {
+	tests := []struct {
+		target   float64
+		altitude float64
+		gaz      int8
+		reached  bool
+	}{
+		{target: 10, altitude: 10.1, gaz: 0, reached: true},
+		{target: 10, altitude: 9, gaz: 20, reached: false},
+		{target: 10, altitude: 0, gaz: 50, reached: false},
+		{target: 0, altitude: 10, gaz: -50, reached: false},
+	}
+
+	for _, tt := range tests {
+		gaz, reached := altitudeGaz(tt.target, tt.altitude)
+		if gaz != tt.gaz || reached != tt.reached {
+			t.Fatalf("altitudeGaz(%v, %v) = %v, %v, want %v, %v", tt.target, tt.altitude, gaz, reached, tt.gaz, tt.reached)
+		}
+	}
+}
+
+func TestAltitudeControllerOnlyGaz(t *testing.T) {
+	d := NewDrone()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	d.updatePcmd(Ardrone3PilotingPCMDArguments{Roll: 20, Yaw: -10}, pcmdAllAxes)
+	if err := d.SetTargetAltitude(5); err != nil {
+		t.Fatalf("SetTargetAltitude: %v", err)
+	}
+	go d.startAltitudeController(ctx)
+
+	if err := d.scriptWaitFor(ctx, time.Second, func() bool { return d.currentPcmd().Gaz != 0 }); err != nil {
+		t.Fatalf("altitude controller never set the Gaz: %v", err)
+	}
+	if got := d.currentPcmd(); got.Roll != 20 || got.Yaw != -10 {
+		t.Fatalf("altitude controller changed the other axes: %+v", got)
+	}
+}
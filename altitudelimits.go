@@ -0,0 +1,183 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+const (
+	// altitudeLimitSlowZone is how many meters from the floor or the
+	// ceiling the Gaz towards it is reduced, so the drone don't
+	// overshoot the limit.
+	altitudeLimitSlowZone = 1
+	// altitudeLimitSlowGaz is the max Gaz percentage towards the floor
+	// or the ceiling when within the slow zone.
+	altitudeLimitSlowGaz = 20
+)
+
+// AltitudeLimits are an altitude floor and ceiling in meters above the
+// takeoff point enforced by the driver on the commands sent, using the
+// altitude reported by the drone. Unlike the MaxAltitude setting of the
+// drone they also protect against flying too low, and can be used for
+// the ceiling of a room.
+type AltitudeLimits struct {
+	Enabled bool
+	// Floor is the lowest altitude the drone can be flown down to
+	// while flying. Takeoff and landing are not limited.
+	Floor float64
+	// Ceiling is the highest altitude the drone can be flown up to.
+	Ceiling float64
+}
+
+// DefaultAltitudeLimits are the altitude limits used if not set with
+// SetAltitudeLimits, which are disabled.
+var DefaultAltitudeLimits = AltitudeLimits{
+	Enabled: false,
+	Floor:   1,
+	Ceiling: 30,
+}
+
+// altitudeLimitsConfig holds the altitude limits, where the zero value
+// uses DefaultAltitudeLimits.
+type altitudeLimitsConfig struct {
+	mu     sync.Mutex
+	limits *AltitudeLimits
+	// limited is true while the Gaz is being limited, so it is only
+	// logged when starting.
+	limited bool
+}
+
+// get will return the current altitude limits.
+func (c *altitudeLimitsConfig) get() AltitudeLimits {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.limits == nil {
+		return DefaultAltitudeLimits
+	}
+
+	return *c.limits
+}
+
+// SetAltitudeLimits will set the altitude floor and ceiling enforced on
+// the Gaz of the PCMD, and on the altitude of the moveTo and moveBy
+// commands, to protect against flying into the ground or the ceiling
+// by mistake.
+func (d *Drone) SetAltitudeLimits(l AltitudeLimits) error {
+	switch {
+	case l.Floor < 0:
+		return fmt.Errorf("SetAltitudeLimits: floor can not be negative, got %v", l.Floor)
+	case l.Ceiling <= l.Floor:
+		return fmt.Errorf("SetAltitudeLimits: ceiling %v must be above the floor %v", l.Ceiling, l.Floor)
+	}
+
+	d.altitudeLimits.mu.Lock()
+	defer d.altitudeLimits.mu.Unlock()
+
+	d.altitudeLimits.limits = &l
+
+	return nil
+}
+
+// limitGaz will return the Gaz allowed at the altitude given, where
+// the Gaz towards the floor or the ceiling is reduced within the slow
+// zone, and stopped at the limit.
+func (l AltitudeLimits) limitGaz(gaz int8, altitude float64) int8 {
+	if !l.Enabled {
+		return gaz
+	}
+
+	switch {
+	case gaz > 0 && altitude >= l.Ceiling:
+		return 0
+	case gaz > 0 && altitude >= l.Ceiling-altitudeLimitSlowZone && gaz > altitudeLimitSlowGaz:
+		return altitudeLimitSlowGaz
+	case gaz < 0 && altitude <= l.Floor:
+		return 0
+	case gaz < 0 && altitude <= l.Floor+altitudeLimitSlowZone && gaz < -altitudeLimitSlowGaz:
+		return -altitudeLimitSlowGaz
+	}
+
+	return gaz
+}
+
+// limitAltitude will return the altitude within the limits.
+func (l AltitudeLimits) limitAltitude(altitude float64) float64 {
+	if !l.Enabled {
+		return altitude
+	}
+
+	switch {
+	case altitude > l.Ceiling:
+		return l.Ceiling
+	case altitude < l.Floor:
+		return l.Floor
+	}
+
+	return altitude
+}
+
+// limitPcmd will limit the Gaz of the PCMD to be sent with the
+// altitude limits, and the current altitude of the drone. The Gaz is
+// not limited while taking off or landing.
+func (d *Drone) limitPcmd(arg Ardrone3PilotingPCMDArguments) Ardrone3PilotingPCMDArguments {
+	l := d.altitudeLimits.get()
+	t := d.telemetry.snapshot()
+	if !l.Enabled || (t.FlyingState != FlyingStateHovering && t.FlyingState != FlyingStateFlying) {
+		return arg
+	}
+
+	gaz := l.limitGaz(arg.Gaz, t.Altitude)
+
+	d.altitudeLimits.mu.Lock()
+	if gaz != arg.Gaz && !d.altitudeLimits.limited {
+		log.Printf("warning: altitude limits: limiting gaz %v to %v at altitude %.1fm, the limits are [%v, %v]\n", arg.Gaz, gaz, t.Altitude, l.Floor, l.Ceiling)
+	}
+	d.altitudeLimits.limited = gaz != arg.Gaz
+	d.altitudeLimits.mu.Unlock()
+
+	arg.Gaz = gaz
+	return arg
+}
+
+// limitMoveTo will limit the altitude of the moveTo command with the
+// altitude limits.
+func (d *Drone) limitMoveTo(arg *Ardrone3PilotingmoveToArguments) {
+	l := d.altitudeLimits.get()
+	if a := l.limitAltitude(arg.Altitude); a != arg.Altitude {
+		log.Printf("warning: altitude limits: moveTo altitude %vm changed to %vm\n", arg.Altitude, a)
+		arg.Altitude = a
+	}
+}
+
+// limitMoveBy will limit the vertical move of the moveBy, so the drone
+// ends within the altitude limits from the current altitude.
+func (d *Drone) limitMoveBy(m MoveBy) MoveBy {
+	l := d.altitudeLimits.get()
+	if !l.Enabled {
+		return m
+	}
+
+	// DZ is down, so the target altitude is the current minus DZ.
+	altitude := d.Telemetry().Altitude
+	target := altitude - float64(m.DZ)
+	a := l.limitAltitude(target)
+
+	// Only the moves towards the floor or the ceiling are limited.
+	up := m.DZ < 0 && a < target
+	down := m.DZ > 0 && a > target
+	if !up && !down {
+		return m
+	}
+
+	dz := float32(altitude - a)
+	if dz*m.DZ < 0 {
+		// Already beyond the limit, so don't move further.
+		dz = 0
+	}
+	log.Printf("warning: altitude limits: moveBy down %vm changed to %vm at altitude %.1fm\n", m.DZ, dz, altitude)
+	m.DZ = dz
+
+	return m
+}
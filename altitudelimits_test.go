@@ -0,0 +1,79 @@
+package parrotbebop
+
+import "testing"
+
+func TestAltitudeLimitsGaz(t *testing.T) {
+	l := AltitudeLimits{Enabled: true, Floor: 1, Ceiling: 3}
+
+	tests := []struct {
+		gaz      int8
+		altitude float64
+		want     int8
+	}{
+		{50, 1.5, 50},
+		{50, 2.5, altitudeLimitSlowGaz},
+		{50, 3, 0},
+		{-50, 3.5, -50},
+		{-50, 1.5, -altitudeLimitSlowGaz},
+		{-10, 1.5, -10},
+		{-50, 1, 0},
+		{50, 0.5, 50},
+	}
+	for _, tt := range tests {
+		if got := l.limitGaz(tt.gaz, tt.altitude); got != tt.want {
+			t.Errorf("gaz %v at %vm: got %v, want %v", tt.gaz, tt.altitude, got, tt.want)
+		}
+	}
+
+	l.Enabled = false
+	if got := l.limitGaz(50, 10); got != 50 {
+		t.Errorf("got %v when disabled, want 50", got)
+	}
+}
+
+func TestAltitudeLimits(t *testing.T) {
+	d := NewDrone()
+	if err := d.SetAltitudeLimits(AltitudeLimits{Enabled: true, Floor: 2, Ceiling: 1}); err == nil {
+		t.Fatalf("expected error for ceiling below floor")
+	}
+	if err := d.SetAltitudeLimits(AltitudeLimits{Enabled: true, Floor: 1, Ceiling: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The Gaz is not limited while taking off.
+	d.telemetry.update(func(t *Telemetry) { t.Altitude = 3 })
+	d.setFlyingState(FlyingStateTakingOff)
+	if got := d.limitPcmd(Ardrone3PilotingPCMDArguments{Gaz: 50}).Gaz; got != 50 {
+		t.Fatalf("got gaz %v while taking off, want 50", got)
+	}
+	d.setFlyingState(FlyingStateHovering)
+	if got := d.limitPcmd(Ardrone3PilotingPCMDArguments{Gaz: 50}).Gaz; got != 0 {
+		t.Fatalf("got gaz %v at the ceiling, want 0", got)
+	}
+
+	arg := &Ardrone3PilotingmoveToArguments{Altitude: 10}
+	d.limitMoveTo(arg)
+	if arg.Altitude != 3 {
+		t.Fatalf("got moveTo altitude %v, want 3", arg.Altitude)
+	}
+
+	d.telemetry.update(func(t *Telemetry) { t.Altitude = 2 })
+	if got := d.limitMoveBy(MoveBy{DZ: -5}); got.DZ != -1 {
+		t.Fatalf("got moveBy up %v, want 1", -got.DZ)
+	}
+	if got := d.limitMoveBy(MoveBy{DZ: 5}); got.DZ != 1 {
+		t.Fatalf("got moveBy down %v, want 1", got.DZ)
+	}
+	if got := d.limitMoveBy(MoveBy{DX: 2, DZ: 0.5}); got.DZ != 0.5 {
+		t.Fatalf("got moveBy down %v, want 0.5 unchanged", got.DZ)
+	}
+
+	// Above the ceiling a move up is stopped, and a move down is kept.
+	d.telemetry.update(func(t *Telemetry) { t.Altitude = 4 })
+	if got := d.limitMoveBy(MoveBy{DZ: -1}); got.DZ != 0 {
+		t.Fatalf("got moveBy up %v above the ceiling, want 0", -got.DZ)
+	}
+	if got := d.limitMoveBy(MoveBy{DZ: 0.5}); got.DZ != 0.5 {
+		t.Fatalf("got moveBy down %v above the ceiling, want 0.5", got.DZ)
+	}
+}
@@ -2954,7 +2954,7 @@ func (a Ardrone3NetworkStateWifiScanListChanged) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Ssid = string(b[offset : offset+stringEnd])
+	arg.Ssid = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 	ConvLittleEndianSliceToNumeric(b[offset:offset+2], &arg.Rssi)
 	offset += 2
@@ -5032,7 +5032,7 @@ func (a Ardrone3NetworkSettingswifiSecurity) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Key = string(b[offset : offset+stringEnd])
+	arg.Key = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 	ConvLittleEndianSliceToNumeric(b[offset:offset+4], &arg.KeyType)
 	offset += 4
@@ -5206,7 +5206,7 @@ func (a Ardrone3NetworkSettingsStatewifiSecurity) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Key = string(b[offset : offset+stringEnd])
+	arg.Key = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 	ConvLittleEndianSliceToNumeric(b[offset:offset+4], &arg.KeyType)
 	offset += 4
@@ -5273,21 +5273,21 @@ func (a Ardrone3SettingsStateProductMotorVersionListChanged) Decode(b []byte) in
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.TypeX = string(b[offset : offset+stringEnd])
+	arg.TypeX = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	stringEnd, err = getLengthOfStringData(b[offset:])
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Software = string(b[offset : offset+stringEnd])
+	arg.Software = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	stringEnd, err = getLengthOfStringData(b[offset:])
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Hardware = string(b[offset : offset+stringEnd])
+	arg.Hardware = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -5346,14 +5346,14 @@ func (a Ardrone3SettingsStateProductGPSVersionChanged) Decode(b []byte) interfac
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Software = string(b[offset : offset+stringEnd])
+	arg.Software = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	stringEnd, err = getLengthOfStringData(b[offset:])
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Hardware = string(b[offset : offset+stringEnd])
+	arg.Hardware = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -5464,7 +5464,7 @@ func (a Ardrone3SettingsStateMotorSoftwareVersionChanged) Decode(b []byte) inter
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Version = string(b[offset : offset+stringEnd])
+	arg.Version = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -5628,7 +5628,7 @@ func (a Ardrone3SettingsStateP7ID) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.SerialID = string(b[offset : offset+stringEnd])
+	arg.SerialID = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -5682,7 +5682,7 @@ func (a Ardrone3SettingsStateCPUID) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Id = string(b[offset : offset+stringEnd])
+	arg.Id = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -8472,14 +8472,14 @@ func (a Ardrone3AccessoryStateConnectedAccessories) Decode(b []byte) interface{}
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Uid = string(b[offset : offset+stringEnd])
+	arg.Uid = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	stringEnd, err = getLengthOfStringData(b[offset:])
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.SwVersion = string(b[offset : offset+stringEnd])
+	arg.SwVersion = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 	ConvLittleEndianSliceToNumeric(b[offset:offset+1], &arg.Listflags)
 	offset++
@@ -8960,7 +8960,7 @@ func (a CommonSettingsProductName) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Name = string(b[offset : offset+stringEnd])
+	arg.Name = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -9018,7 +9018,7 @@ func (a CommonSettingsCountry) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Code = string(b[offset : offset+stringEnd])
+	arg.Code = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -9227,7 +9227,7 @@ func (a CommonSettingsStateProductNameChanged) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Name = string(b[offset : offset+stringEnd])
+	arg.Name = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -9286,14 +9286,14 @@ func (a CommonSettingsStateProductVersionChanged) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Software = string(b[offset : offset+stringEnd])
+	arg.Software = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	stringEnd, err = getLengthOfStringData(b[offset:])
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Hardware = string(b[offset : offset+stringEnd])
+	arg.Hardware = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -9351,7 +9351,7 @@ func (a CommonSettingsStateProductSerialHighChanged) Decode(b []byte) interface{
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.High = string(b[offset : offset+stringEnd])
+	arg.High = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -9409,7 +9409,7 @@ func (a CommonSettingsStateProductSerialLowChanged) Decode(b []byte) interface{}
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Low = string(b[offset : offset+stringEnd])
+	arg.Low = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -9467,7 +9467,7 @@ func (a CommonSettingsStateCountryChanged) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Code = string(b[offset : offset+stringEnd])
+	arg.Code = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -9576,7 +9576,7 @@ func (a CommonSettingsStateBoardIdChanged) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Id = string(b[offset : offset+stringEnd])
+	arg.Id = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -9686,7 +9686,7 @@ func (a CommonCommonCurrentDate) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Date = string(b[offset : offset+stringEnd])
+	arg.Date = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -9744,7 +9744,7 @@ func (a CommonCommonCurrentTime) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Time = string(b[offset : offset+stringEnd])
+	arg.Time = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -9850,7 +9850,7 @@ func (a CommonCommonCurrentDateTime) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Datetime = string(b[offset : offset+stringEnd])
+	arg.Datetime = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -10014,7 +10014,7 @@ func (a CommonCommonStateMassStorageStateListChanged) Decode(b []byte) interface
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Name = string(b[offset : offset+stringEnd])
+	arg.Name = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -10138,7 +10138,7 @@ func (a CommonCommonStateCurrentDateChanged) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Date = string(b[offset : offset+stringEnd])
+	arg.Date = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -10196,7 +10196,7 @@ func (a CommonCommonStateCurrentTimeChanged) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Time = string(b[offset : offset+stringEnd])
+	arg.Time = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -10466,7 +10466,7 @@ func (a CommonCommonStateCountryListKnown) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.CountryCodes = string(b[offset : offset+stringEnd])
+	arg.CountryCodes = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -10764,7 +10764,7 @@ func (a CommonCommonStateCurrentDateTimeChanged) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Datetime = string(b[offset : offset+stringEnd])
+	arg.Datetime = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -10873,7 +10873,7 @@ func (a CommonCommonStateBootId) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.BootId = string(b[offset : offset+stringEnd])
+	arg.BootId = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -11193,21 +11193,21 @@ func (a CommonControllerPeerStateChanged) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.PeerName = string(b[offset : offset+stringEnd])
+	arg.PeerName = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	stringEnd, err = getLengthOfStringData(b[offset:])
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.PeerId = string(b[offset : offset+stringEnd])
+	arg.PeerId = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	stringEnd, err = getLengthOfStringData(b[offset:])
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.PeerType = string(b[offset : offset+stringEnd])
+	arg.PeerType = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -11380,7 +11380,7 @@ func (a CommonMavlinkStart) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Filepath = string(b[offset : offset+stringEnd])
+	arg.Filepath = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 	ConvLittleEndianSliceToNumeric(b[offset:offset+4], &arg.TypeX)
 	offset += 4
@@ -11544,7 +11544,7 @@ func (a CommonMavlinkStateMavlinkFilePlayingStateChanged) Decode(b []byte) inter
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Filepath = string(b[offset : offset+stringEnd])
+	arg.Filepath = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 	ConvLittleEndianSliceToNumeric(b[offset:offset+4], &arg.TypeX)
 	offset += 4
@@ -12575,7 +12575,7 @@ func (a CommonARLibsVersionsStateControllerLibARCommandsVersion) Decode(b []byte
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Version = string(b[offset : offset+stringEnd])
+	arg.Version = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -12629,7 +12629,7 @@ func (a CommonARLibsVersionsStateSkyControllerLibARCommandsVersion) Decode(b []b
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Version = string(b[offset : offset+stringEnd])
+	arg.Version = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -12683,7 +12683,7 @@ func (a CommonARLibsVersionsStateDeviceLibARCommandsVersion) Decode(b []byte) in
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.Version = string(b[offset : offset+stringEnd])
+	arg.Version = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -13664,7 +13664,7 @@ func (a CommonRunStateRunIdChanged) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.RunId = string(b[offset : offset+stringEnd])
+	arg.RunId = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	return arg
@@ -13780,14 +13780,14 @@ func (a CommonUpdateStateUpdateStateChanged) Decode(b []byte) interface{} {
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.SourceVersion = string(b[offset : offset+stringEnd])
+	arg.SourceVersion = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 
 	stringEnd, err = getLengthOfStringData(b[offset:])
 	if err != nil {
 		log.Println("error: ", err)
 	}
-	arg.TargetVersion = string(b[offset : offset+stringEnd])
+	arg.TargetVersion = stringArg(b[offset : offset+stringEnd])
 	offset += stringEnd
 	ConvLittleEndianSliceToNumeric(b[offset:offset+4], &arg.Status)
 	offset += 4
@@ -14087,46 +14087,31 @@ var CommandMap = map[Command]Decoder{
 	Command(UpdateStateUpdateStateChanged):                            UpdateStateUpdateStateChanged,
 }
 
-// lenStringData takes a []byte which is the data for the arguments, and returns
-// the position of the 0 terminator for the string.
+// getLengthOfStringData takes a []byte which is the data for the arguments,
+// and returns the length of the string including the 0 terminator.
 // The []byte given as input will start looking from the beginning of the slice,
 // so the input slice should be sliced to start from the offset of the string.
-func lenStringData(b []byte) (int, error) {
+func getLengthOfStringData(b []byte) (int, error) {
 	// Figure out the length of the string
-	for i := 0; i < cap(b); i++ {
-		//fmt.Printf("%+v, of type %T\n", b[i], b[i])
-
-		//fmt.Println("i = ", i)
+	for i := 0; i < len(b); i++ {
 		if b[i] == 0 {
-			//fmt.Println("lengthString = ", i)
-
 			// add 1 to jump to the 0
 			return i + 1, nil
 		}
-
 	}
 
-	err := fmt.Errorf("no string bytes found, returning 0")
+	err := fmt.Errorf("no string terminator found, returning 0")
 	return 0, err
 }
 
-func getLengthOfStringData(b []byte) (int, error) {
-	// Figure out the length of the string
-	for i := 0; i < cap(b); i++ {
-		//fmt.Printf("%+v, of type %T\n", b[i], b[i])
-
-		//fmt.Println("i = ", i)
-		if b[i] == 0 {
-			//fmt.Println("lengthString = ", i)
-
-			// add 1 to jump to the 0
-			return i + 1, nil
-		}
-
+// stringArg takes the []byte of a string argument including the 0
+// terminator, and returns the string without the terminator.
+func stringArg(b []byte) string {
+	if len(b) > 0 && b[len(b)-1] == 0 {
+		b = b[:len(b)-1]
 	}
 
-	err := fmt.Errorf("no string bytes found, returning 0")
-	return 0, err
+	return string(b)
 }
 
 // ConvLittleEndianSliceToNumeric takes a []byte, and an *out variable of type
@@ -14157,13 +14142,13 @@ func ConvLittleEndianSliceToNumeric(in []byte, out interface{}) {
 		bits := binary.LittleEndian.Uint64(in)
 		*out = math.Float64frombits(bits)
 	case *string:
-		*out = string(in)
+		*out = stringArg(in)
 	}
 }
 
 // ConvLittleEndianNumericToSlice takes a a value of any of the standard types
-// uint8/int8/uint16/int16/uint32/int32/uint64/int64/float32/float64
-// and convert to a []byte.
+// uint8/int8/uint16/int16/uint32/int32/uint64/int64/float32/float64/string
+// and convert to a []byte. Strings will be null terminated.
 func ConvLittleEndianNumericToSlice(value interface{}) []byte {
 	var b []byte
 
@@ -14197,7 +14182,8 @@ func ConvLittleEndianNumericToSlice(value interface{}) []byte {
 		b = make([]byte, 8)
 		binary.LittleEndian.PutUint64(b, math.Float64bits(v))
 	case string:
-		b = []byte(v)
+		// Strings are sent as null terminated strings.
+		b = append([]byte(v), 0)
 
 	}
 
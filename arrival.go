@@ -0,0 +1,124 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// Default values for the arrival detection.
+	defaultArrivalRadius            = 2.0
+	defaultArrivalAltitude          = 1.0
+	defaultArrivalHold              = time.Second * 3
+	defaultArrivalLegTimeout        = time.Minute * 5
+	arrivalCheckInterval            = time.Millisecond * 250
+	moveToStatusDone         uint32 = 1
+)
+
+// arrivalSettings holds when the moveTo executor consider a position
+// reached.
+type arrivalSettings struct {
+	// radius is the horizontal distance, and altitude the vertical
+	// distance in meters, the drone must be within.
+	radius   float64
+	altitude float64
+	// hold is how long the drone must stay within the distances.
+	hold time.Duration
+	// legTimeout is how long to wait for a position to be reached
+	// before giving up and continuing with the next.
+	legTimeout time.Duration
+}
+
+// arrivalConfig holds the arrival settings, which can be changed while
+// the executor is running.
+type arrivalConfig struct {
+	mu       sync.Mutex
+	settings arrivalSettings
+}
+
+// get will return a copy of the settings.
+func (a *arrivalConfig) get() arrivalSettings {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.settings
+}
+
+// SetArrivalDetection will set when the moveTo executor consider a
+// waypoint reached, which is when the drone have been within radius
+// meters horizontally and altitude meters vertically of the waypoint
+// for the duration hold, or the drone reports the moveTo as done. If a
+// waypoint is not reached within legTimeout the executor stops the
+// mission, keeps the waypoint in the buffer, and publishes
+// EventMoveToFailed. The defaults are 2m, 1m, 3s and 5 minutes.
+func (d *Drone) SetArrivalDetection(radius float64, altitude float64, hold time.Duration, legTimeout time.Duration) error {
+	if radius <= 0 || altitude <= 0 || hold < 0 || legTimeout <= 0 {
+		return fmt.Errorf("SetArrivalDetection: radius, altitude and leg timeout must be above 0, and hold can not be negative")
+	}
+
+	d.arrivalConfig.mu.Lock()
+	defer d.arrivalConfig.mu.Unlock()
+
+	d.arrivalConfig.settings = arrivalSettings{
+		radius:     radius,
+		altitude:   altitude,
+		hold:       hold,
+		legTimeout: legTimeout,
+	}
+
+	return nil
+}
+
+// arrivalDetector will tell when a position is reached, by checking
+// that the drone have stayed close to the position long enough.
+type arrivalDetector struct {
+	conf   arrivalSettings
+	target Position
+	// since is when the drone came within the distances, or zero if
+	// not within.
+	since time.Time
+}
+
+// reached will check the position of the drone, and return true when
+// it have been within the distances of the target for the hold time.
+// Positions from a drone without gps fix resets the detection.
+func (a *arrivalDetector) reached(t Telemetry, now time.Time) bool {
+	if !withinDistance(t, a.target, a.conf.radius, a.conf.altitude) {
+		a.since = time.Time{}
+		return false
+	}
+	if a.since.IsZero() {
+		a.since = now
+	}
+
+	return now.Sub(a.since) >= a.conf.hold
+}
+
+// withinDistance will return true if the drone have a gps position
+// within radius meters horizontally, and altitude meters vertically, of
+// the target. The altitude of a moveTo target is above the take off
+// point, so it is compared with the altitude of the drone, and not with
+// the gps altitude.
+func withinDistance(t Telemetry, target Position, radius float64, altitude float64) bool {
+	return validGPSPosition(t.Position) &&
+		t.Position.DistanceTo(target) <= radius &&
+		math.Abs(t.Altitude-target.Altitude) <= altitude
+}
+
+// handleMoveToChanged will signal the moveTo executor when the drone
+// reports that the moveTo is done.
+func (d *Drone) handleMoveToChanged(status uint32) {
+	if status != moveToStatusDone {
+		return
+	}
+	d.setMoveToPending(false)
+
+	// Don't block the decoding of the packets from the drone if the
+	// executor is not waiting for it.
+	select {
+	case d.gps.chMoveToPositionDone <- struct{}{}:
+	default:
+	}
+}
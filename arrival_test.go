@@ -0,0 +1,57 @@
+package parrotbebop
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArrivalDetector(t *testing.T) {
+	target := Position{Latitude: 60, Longitude: 10, Altitude: 20}
+	a := arrivalDetector{
+		conf:   arrivalSettings{radius: 2, altitude: 1, hold: time.Second * 3},
+		target: target,
+	}
+	now := time.Now()
+
+	// The gps altitude is above sea level, and the target altitude is
+	// above the take off point.
+	at := func(p Position, altitude float64) Telemetry {
+		p.Altitude = 300
+		return Telemetry{Position: p, Altitude: altitude}
+	}
+
+	// Passing close to the target should not count as reached.
+	if a.reached(at(target.Offset(1, 0), 20), now) {
+		t.Fatal("reached before the hold time")
+	}
+	if a.reached(at(target.Offset(5, 0), 20), now.Add(time.Second*2)) {
+		t.Fatal("reached when too far away")
+	}
+	if a.reached(at(target.Offset(1, 0), 20), now.Add(time.Second*4)) {
+		t.Fatal("the hold time was not reset when leaving")
+	}
+
+	if a.reached(at(target, 22), now.Add(time.Second*8)) {
+		t.Fatal("reached when too high")
+	}
+
+	if a.reached(at(target, 20), now.Add(time.Second*9)) {
+		t.Fatal("reached before the hold time")
+	}
+	if !a.reached(at(target.Offset(1.5, 90), 20.5), now.Add(time.Second*12)) {
+		t.Fatal("expected reached after the hold time")
+	}
+}
+
+func TestSetArrivalDetection(t *testing.T) {
+	d := NewDrone()
+	if err := d.SetArrivalDetection(0, 1, time.Second, time.Minute); err == nil {
+		t.Fatal("expected error for radius 0")
+	}
+	if err := d.SetArrivalDetection(3, 1, time.Second, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if c := d.arrivalConfig.get(); c.radius != 3 || c.hold != time.Second {
+		t.Fatalf("config not set: %+v", c)
+	}
+}
@@ -0,0 +1,447 @@
+package parrotbebop
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// The video from the drone is sent with the ARStream2 protocol, which
+// is RTP carrying H264 on the stream port, and RTCP on the control port.
+// The drone have a resender which will resend lost packets if it gets
+// to know about them, so the controller needs to send RTCP receiver
+// reports, and NACK's for the packets lost, on the control port.
+
+const (
+	// rtpHeaderSize is the size of a RTP header without CSRC's.
+	rtpHeaderSize = 12
+	// rtcpTypeSenderReport is the RTCP packet type for sender reports.
+	rtcpTypeSenderReport = 200
+	// rtcpTypeReceiverReport is the RTCP packet type for receiver reports.
+	rtcpTypeReceiverReport = 201
+	// rtcpTypeRTPFB is the RTCP packet type for transport layer feedback,
+	// where the generic NACK is format 1.
+	rtcpTypeRTPFB = 205
+	// rtcpReportInterval is how often a receiver report is sent.
+	rtcpReportInterval = time.Second
+	// rtpClockRate is the clock rate of the RTP timestamps for video.
+	rtpClockRate = 90000
+)
+
+// rtpPacket is a decoded RTP packet.
+type rtpPacket struct {
+	marker      bool
+	payloadType uint8
+	seq         uint16
+	timestamp   uint32
+	ssrc        uint32
+	payload     []byte
+}
+
+// parseRTPPacket will decode the RTP packet in b. The payload of the
+// returned packet is referencing the input slice.
+func parseRTPPacket(b []byte) (rtpPacket, error) {
+	if len(b) < rtpHeaderSize {
+		return rtpPacket{}, fmt.Errorf("rtp packet too short: %v bytes", len(b))
+	}
+	if b[0]>>6 != 2 {
+		return rtpPacket{}, fmt.Errorf("rtp packet with wrong version: %v", b[0]>>6)
+	}
+
+	p := rtpPacket{
+		marker:      b[1]&0x80 != 0,
+		payloadType: b[1] & 0x7f,
+		seq:         binary.BigEndian.Uint16(b[2:4]),
+		timestamp:   binary.BigEndian.Uint32(b[4:8]),
+		ssrc:        binary.BigEndian.Uint32(b[8:12]),
+	}
+
+	// Skip the CSRC's and the extension header if present.
+	offset := rtpHeaderSize + int(b[0]&0x0f)*4
+	if b[0]&0x10 != 0 {
+		if len(b) < offset+4 {
+			return rtpPacket{}, fmt.Errorf("rtp packet too short for extension header")
+		}
+		offset += 4 + int(binary.BigEndian.Uint16(b[offset+2:offset+4]))*4
+	}
+
+	end := len(b)
+	// Remove the padding if present.
+	if b[0]&0x20 != 0 && end > 0 {
+		end -= int(b[end-1])
+	}
+	if offset > end {
+		return rtpPacket{}, fmt.Errorf("rtp packet header larger than packet")
+	}
+
+	p.payload = b[offset:end]
+
+	return p, nil
+}
+
+// rtpReceiverStats keeps the statistics of the received RTP packets
+// as described in RFC3550, needed for creating receiver reports, and
+// for finding the packets lost.
+type rtpReceiverStats struct {
+	mu          sync.Mutex
+	initialized bool
+	ssrc        uint32
+	// baseSeq is the first sequence number received.
+	baseSeq uint16
+	// maxSeq is the highest sequence number received.
+	maxSeq uint16
+	// cycles is the number of times the sequence number have wrapped.
+	cycles uint32
+	// received is the total number of packets received.
+	received uint32
+	// expectedPrior and receivedPrior are the values at the time of
+	// the last receiver report.
+	expectedPrior uint32
+	receivedPrior uint32
+	// jitter is the interarrival jitter in timestamp units.
+	jitter float64
+	// transit is the relative transit time of the last packet.
+	transit int64
+	// lastSR is the middle 32 bits of the ntp timestamp of the last
+	// sender report, and lastSRTime when it was received.
+	lastSR     uint32
+	lastSRTime time.Time
+}
+
+// update will update the statistics with the received packet, and
+// return the sequence numbers found to be missing between the last
+// highest sequence number and the one received.
+func (s *rtpReceiverStats) update(p rtpPacket, arrival time.Time) []uint16 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.received++
+
+	// The transit time in timestamp units.
+	transit := arrival.UnixNano()*rtpClockRate/int64(time.Second) - int64(p.timestamp)
+
+	if !s.initialized {
+		s.initialized = true
+		s.ssrc = p.ssrc
+		s.baseSeq = p.seq
+		s.maxSeq = p.seq
+		s.transit = transit
+		return nil
+	}
+
+	diff := transit - s.transit
+	s.transit = transit
+	if diff < 0 {
+		diff = -diff
+	}
+	s.jitter += (float64(diff) - s.jitter) / 16
+
+	delta := p.seq - s.maxSeq
+	// A delta of 0 is a duplicate, and a delta above half the
+	// sequence space is an old or resent packet.
+	if delta == 0 || delta > 0x8000 {
+		return nil
+	}
+
+	var missing []uint16
+	for seq := s.maxSeq + 1; seq != p.seq; seq++ {
+		missing = append(missing, seq)
+	}
+
+	if p.seq < s.maxSeq {
+		s.cycles++
+	}
+	s.maxSeq = p.seq
+
+	return missing
+}
+
+// setSenderReport will record the time of the sender report received,
+// which is needed for the LSR and DLSR fields of the receiver report.
+func (s *rtpReceiverStats) setSenderReport(ntpMiddle uint32, arrival time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastSR = ntpMiddle
+	s.lastSRTime = arrival
+}
+
+// receiverReport will create a RTCP receiver report with the current
+// statistics, and false if no packets have been received yet.
+func (s *rtpReceiverStats) receiverReport(senderSSRC uint32, now time.Time) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.initialized {
+		return nil, false
+	}
+
+	extMax := s.cycles<<16 | uint32(s.maxSeq)
+	expected := extMax - uint32(s.baseSeq) + 1
+	lost := int32(expected) - int32(s.received)
+	// The cumulative lost field is a signed 24 bit value.
+	if lost > 0x7fffff {
+		lost = 0x7fffff
+	}
+	if lost < -0x800000 {
+		lost = -0x800000
+	}
+
+	expectedInterval := expected - s.expectedPrior
+	receivedInterval := s.received - s.receivedPrior
+	s.expectedPrior = expected
+	s.receivedPrior = s.received
+
+	var fraction uint8
+	if expectedInterval > 0 && expectedInterval > receivedInterval {
+		fraction = uint8(((expectedInterval - receivedInterval) << 8) / expectedInterval)
+	}
+
+	var dlsr uint32
+	if !s.lastSRTime.IsZero() {
+		// The delay is given in units of 1/65536 seconds.
+		dlsr = uint32(now.Sub(s.lastSRTime).Seconds() * 65536)
+	}
+
+	b := make([]byte, 32)
+	// Version 2, one report block.
+	b[0] = 2<<6 | 1
+	b[1] = rtcpTypeReceiverReport
+	binary.BigEndian.PutUint16(b[2:4], uint16(len(b)/4-1))
+	binary.BigEndian.PutUint32(b[4:8], senderSSRC)
+	binary.BigEndian.PutUint32(b[8:12], s.ssrc)
+	binary.BigEndian.PutUint32(b[12:16], uint32(fraction)<<24|uint32(lost)&0xffffff)
+	binary.BigEndian.PutUint32(b[16:20], extMax)
+	binary.BigEndian.PutUint32(b[20:24], uint32(s.jitter))
+	binary.BigEndian.PutUint32(b[24:28], s.lastSR)
+	binary.BigEndian.PutUint32(b[28:32], dlsr)
+
+	return b, true
+}
+
+// buildRTCPNack will create a RTCP generic NACK (RFC4585) asking for
+// the lost sequence numbers given to be resent.
+func buildRTCPNack(senderSSRC uint32, mediaSSRC uint32, lost []uint16) []byte {
+	// Each FCI entry holds a packet id, and a bitmask of the 16
+	// following packets also lost.
+	var fci []uint32
+	for i := 0; i < len(lost); {
+		pid := lost[i]
+		var blp uint16
+		i++
+		for i < len(lost) {
+			d := lost[i] - pid
+			if d == 0 || d > 16 {
+				break
+			}
+			blp |= 1 << (d - 1)
+			i++
+		}
+		fci = append(fci, uint32(pid)<<16|uint32(blp))
+	}
+
+	b := make([]byte, 12+len(fci)*4)
+	// Version 2, format 1 is generic NACK.
+	b[0] = 2<<6 | 1
+	b[1] = rtcpTypeRTPFB
+	binary.BigEndian.PutUint16(b[2:4], uint16(len(b)/4-1))
+	binary.BigEndian.PutUint32(b[4:8], senderSSRC)
+	binary.BigEndian.PutUint32(b[8:12], mediaSSRC)
+	for i, v := range fci {
+		binary.BigEndian.PutUint32(b[12+i*4:16+i*4], v)
+	}
+
+	return b
+}
+
+// arstream holds the connections and statistics for the video stream.
+type arstream struct {
+	stats rtpReceiverStats
+	// ssrc is our own ssrc used in the RTCP packets.
+	ssrc uint32
+	// connControl is the connection for the RTCP control channel.
+	connControl net.PacketConn
+	// addrControl is the address of the drone's control port.
+	addrControl net.Addr
+	// chLost is where the sequence numbers of lost packets are put
+	// to be nack'ed.
+	chLost chan []uint16
+	// capture and localControl are used for capturing the RTCP
+	// packets, where localControl is our address of the control channel.
+	capture      *capture
+	localControl net.Addr
+}
+
+// startARStream will start the go routines for receiving the RTP
+// video stream, and for handling the RTCP control channel with the
+// drone. All the go routines will stop when the context is done.
+func (d *Drone) startARStream(ctx context.Context) error {
+	connStream, err := net.ListenPacket("udp", ":"+d.portRTPStream)
+	if err != nil {
+		return fmt.Errorf("startARStream: failed to listen on stream port: %v", err)
+	}
+
+	connControl, err := net.ListenPacket("udp", ":"+d.portRTPControl)
+	if err != nil {
+		connStream.Close()
+		return fmt.Errorf("startARStream: failed to listen on control port: %v", err)
+	}
+
+	if c, ok := connControl.(*net.UDPConn); ok && d.qosMode == 1 {
+		if err := setClassSelector(c, classSelectorVideo); err != nil {
+			log.Printf("error: startARStream: %v\n", err)
+		}
+	}
+
+	addrControl, err := net.ResolveUDPAddr("udp", d.addressDrone+":"+d.portRTPServerControl)
+	if err != nil {
+		connStream.Close()
+		connControl.Close()
+		return fmt.Errorf("startARStream: failed to resolve drone control address: %v", err)
+	}
+
+	s := &arstream{
+		ssrc:        rand.Uint32(),
+		connControl: connControl,
+		addrControl: addrControl,
+		chLost:      make(chan []uint16, 100),
+
+		capture:      &d.capture,
+		localControl: d.capture.local(d.portRTPControl),
+	}
+
+	// Close the connections when done, which will also stop the
+	// go routines reading from them.
+	go func() {
+		<-ctx.Done()
+		connStream.Close()
+		connControl.Close()
+		log.Printf("...closed arstream connections\n")
+	}()
+
+	go d.readRTPStream(ctx, connStream, s)
+	go s.readRTCP(ctx)
+	go s.sendRTCP(ctx)
+
+	return nil
+}
+
+// readRTPStream will read the RTP packets of the video stream, update
+// the receiver statistics, and put together the H264 frames which are
+// then published to the video frame subscribers.
+func (d *Drone) readRTPStream(ctx context.Context, conn net.PacketConn, s *arstream) {
+	b := make([]byte, 65536)
+	var depacketizer h264Depacketizer
+	localStream := d.capture.local(d.portRTPStream)
+	for {
+		n, addr, err := conn.ReadFrom(b)
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				log.Printf("info: exiting readRTPStream\n")
+				return
+			default:
+			}
+			log.Printf("error: readRTPStream: %v\n", err)
+			continue
+		}
+		d.capture.udp(addr, localStream, b[:n])
+
+		p, err := parseRTPPacket(b[:n])
+		if err != nil {
+			log.Printf("error: readRTPStream: %v\n", err)
+			continue
+		}
+
+		missing := s.stats.update(p, time.Now())
+		if len(missing) > 0 {
+			depacketizer.lost()
+			select {
+			case s.chLost <- missing:
+			default:
+			}
+		}
+
+		frame, ok, err := depacketizer.push(p)
+		if err != nil {
+			log.Printf("info: readRTPStream: %v\n", err)
+			continue
+		}
+		if ok {
+			d.frames.publish(frame)
+		}
+	}
+}
+
+// readRTCP will read the RTCP packets sent by the drone on the control
+// channel, and record the sender reports.
+func (s *arstream) readRTCP(ctx context.Context) {
+	b := make([]byte, 1500)
+	for {
+		n, addr, err := s.connControl.ReadFrom(b)
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				log.Printf("info: exiting readRTCP\n")
+				return
+			default:
+			}
+			log.Printf("error: readRTCP: %v\n", err)
+			continue
+		}
+		s.capture.udp(addr, s.localControl, b[:n])
+
+		// A RTCP packet can be a compound of several packets.
+		for p := b[:n]; len(p) >= 4; {
+			size := (int(binary.BigEndian.Uint16(p[2:4])) + 1) * 4
+			if size > len(p) {
+				break
+			}
+			// The sender report holds the 64 bit ntp timestamp at
+			// position 8, and we keep the middle 32 bits.
+			if p[1] == rtcpTypeSenderReport && size >= 20 {
+				s.stats.setSenderReport(binary.BigEndian.Uint32(p[10:14]), time.Now())
+			}
+			p = p[size:]
+		}
+	}
+}
+
+// sendRTCP will send receiver reports on a regular interval, and the
+// NACK's for lost packets as soon as they are found.
+func (s *arstream) sendRTCP(ctx context.Context) {
+	ticker := time.NewTicker(rtcpReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("info: exiting sendRTCP\n")
+			return
+		case lost := <-s.chLost:
+			s.stats.mu.Lock()
+			mediaSSRC := s.stats.ssrc
+			s.stats.mu.Unlock()
+
+			nack := buildRTCPNack(s.ssrc, mediaSSRC, lost)
+			if _, err := s.connControl.WriteTo(nack, s.addrControl); err != nil {
+				log.Printf("error: sendRTCP: failed to send nack: %v\n", err)
+			}
+			s.capture.udp(s.localControl, s.addrControl, nack)
+		case now := <-ticker.C:
+			rr, ok := s.stats.receiverReport(s.ssrc, now)
+			if !ok {
+				continue
+			}
+			if _, err := s.connControl.WriteTo(rr, s.addrControl); err != nil {
+				log.Printf("error: sendRTCP: failed to send receiver report: %v\n", err)
+			}
+			s.capture.udp(s.localControl, s.addrControl, rr)
+		}
+	}
+}
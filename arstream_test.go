@@ -0,0 +1,85 @@
+package parrotbebop
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestRTPPacket will create a RTP packet with the values given.
+func newTestRTPPacket(seq uint16, ts uint32, marker bool, payload []byte) []byte {
+	b := make([]byte, rtpHeaderSize)
+	b[0] = 2 << 6
+	b[1] = 96
+	if marker {
+		b[1] |= 0x80
+	}
+	binary.BigEndian.PutUint16(b[2:4], seq)
+	binary.BigEndian.PutUint32(b[4:8], ts)
+	binary.BigEndian.PutUint32(b[8:12], 0x1234)
+
+	return append(b, payload...)
+}
+
+func TestParseRTPPacket(t *testing.T) {
+	p, err := parseRTPPacket(newTestRTPPacket(10, 900, true, []byte{1, 2, 3}))
+	if err != nil {
+		t.Fatalf("parseRTPPacket failed: %v", err)
+	}
+
+	if p.seq != 10 || p.timestamp != 900 || !p.marker || p.ssrc != 0x1234 || !reflect.DeepEqual(p.payload, []byte{1, 2, 3}) {
+		t.Fatalf("wrong packet: %#v", p)
+	}
+
+	if _, err := parseRTPPacket([]byte{0x80, 96}); err == nil {
+		t.Fatalf("expected error for short packet")
+	}
+}
+
+func TestRTPReceiverStatsMissing(t *testing.T) {
+	var s rtpReceiverStats
+	now := time.Now()
+
+	for _, seq := range []uint16{65534, 65535} {
+		p, _ := parseRTPPacket(newTestRTPPacket(seq, 0, false, nil))
+		if missing := s.update(p, now); missing != nil {
+			t.Fatalf("unexpected missing packets: %v", missing)
+		}
+	}
+
+	// Wrapping the sequence number, and losing 0 and 1.
+	p, _ := parseRTPPacket(newTestRTPPacket(2, 0, false, nil))
+	missing := s.update(p, now)
+	if !reflect.DeepEqual(missing, []uint16{0, 1}) {
+		t.Fatalf("wrong missing packets: %v", missing)
+	}
+
+	rr, ok := s.receiverReport(1, now)
+	if !ok {
+		t.Fatalf("expected a receiver report")
+	}
+	// Cumulative lost should be 2, and the extended highest sequence
+	// number should have one cycle.
+	if lost := binary.BigEndian.Uint32(rr[12:16]) & 0xffffff; lost != 2 {
+		t.Fatalf("wrong cumulative lost: %v", lost)
+	}
+	if ext := binary.BigEndian.Uint32(rr[16:20]); ext != 1<<16|2 {
+		t.Fatalf("wrong extended highest sequence: %v", ext)
+	}
+}
+
+func TestBuildRTCPNack(t *testing.T) {
+	b := buildRTCPNack(1, 2, []uint16{100, 101, 103, 200})
+
+	// Header + 2 ssrc's + 2 FCI entries.
+	if len(b) != 20 {
+		t.Fatalf("wrong nack length: %v", len(b))
+	}
+	if fci := binary.BigEndian.Uint32(b[12:16]); fci != 100<<16|0x5 {
+		t.Fatalf("wrong first fci: %x", fci)
+	}
+	if fci := binary.BigEndian.Uint32(b[16:20]); fci != 200<<16 {
+		t.Fatalf("wrong second fci: %x", fci)
+	}
+}
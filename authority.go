@@ -0,0 +1,188 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// ControlAuthority is who is in control of the drone.
+type ControlAuthority int
+
+const (
+	// AuthorityManual is the pilot flying the drone with the PCMD.
+	AuthorityManual ControlAuthority = iota
+	// AuthorityMission is the drone flying a mission with moveTo, like
+	// the moveTo executor or FollowMe, or a relative mission with
+	// moveBy.
+	AuthorityMission
+)
+
+// String will return the name of the authority.
+func (c ControlAuthority) String() string {
+	switch c {
+	case AuthorityManual:
+		return "manual"
+	case AuthorityMission:
+		return "mission"
+	}
+
+	return fmt.Sprintf("ControlAuthority(%d)", int(c))
+}
+
+// controlAuthority keeps track of the moveTo in progress, and if the
+// pilot have taken over the control from the mission.
+type controlAuthority struct {
+	mu sync.Mutex
+	// overrideDisabled will disable the taking over by manual input.
+	overrideDisabled bool
+	// moveToPending is true when a moveTo have been sent, and is not yet
+	// done or cancelled.
+	moveToPending bool
+	// takenOver is true from the pilot taking over, until the mission
+	// is resumed.
+	takenOver bool
+	// resumeExecutor is true if the moveTo executor was flying when
+	// taken over, so it is started again when resumed.
+	resumeExecutor bool
+	// relativeMission is true while a mission flown with moveBy is
+	// running, which the moveTo tracking don't know about.
+	relativeMission bool
+}
+
+// SetManualOverride will set if manual PCMD input from the keyboard,
+// SendSticks or the Gobot driver should take over the control from an
+// active mission, which is enabled by default. When taken over the
+// moveTo in progress is cancelled, and the mission is paused until
+// ResumeMission is called, or the ActionResumeMission is given.
+func (d *Drone) SetManualOverride(enabled bool) {
+	d.controlAuthority.mu.Lock()
+	defer d.controlAuthority.mu.Unlock()
+
+	d.controlAuthority.overrideDisabled = !enabled
+}
+
+// ControlAuthority will return who is in control of the drone.
+func (d *Drone) ControlAuthority() ControlAuthority {
+	d.controlAuthority.mu.Lock()
+	defer d.controlAuthority.mu.Unlock()
+
+	return d.controlAuthorityLocked()
+}
+
+// controlAuthorityLocked will return who is in control of the drone.
+// Must be called while holding the lock.
+func (d *Drone) controlAuthorityLocked() ControlAuthority {
+	if d.controlAuthority.takenOver {
+		return AuthorityManual
+	}
+	if d.controlAuthority.moveToPending || d.controlAuthority.relativeMission || d.MoveToActive() {
+		return AuthorityMission
+	}
+
+	return AuthorityManual
+}
+
+// trackMoveTo will keep track of if a moveTo is in progress, from the
+// commands sent to the drone.
+func (d *Drone) trackMoveTo(arg Encoder) {
+	switch arg.(type) {
+	case *Ardrone3PilotingmoveToArguments:
+		d.setMoveToPending(true)
+	case *Ardrone3PilotingCancelMoveToArguments:
+		d.setMoveToPending(false)
+	}
+}
+
+// setRelativeMission will set if a mission flown with moveBy is
+// running.
+func (d *Drone) setRelativeMission(running bool) {
+	d.controlAuthority.mu.Lock()
+	defer d.controlAuthority.mu.Unlock()
+
+	d.controlAuthority.relativeMission = running
+}
+
+// setMoveToPending will set if a moveTo is in progress.
+func (d *Drone) setMoveToPending(pending bool) {
+	d.controlAuthority.mu.Lock()
+	defer d.controlAuthority.mu.Unlock()
+
+	d.controlAuthority.moveToPending = pending
+}
+
+// manualInput will let the pilot take over the control from an active
+// mission when any of the stick inputs in the range [-1, 1] is moved
+// outside the deadzone of it's axis. The moveTo in progress is
+// cancelled, and the mission is paused. The sticks are checked before
+// they are shaped, since the trim moves the PCMD also with the sticks
+// centered.
+func (d *Drone) manualInput(roll float64, pitch float64, yaw float64, gaz float64) {
+	s := d.inputShaping.get()
+	if !s.Roll.moved(roll) && !s.Pitch.moved(pitch) && !s.Yaw.moved(yaw) && !s.Gaz.moved(gaz) {
+		return
+	}
+
+	d.controlAuthority.mu.Lock()
+	if d.controlAuthority.overrideDisabled || d.controlAuthorityLocked() != AuthorityMission {
+		d.controlAuthority.mu.Unlock()
+		return
+	}
+	executor := d.MoveToActive()
+	relative := d.controlAuthority.relativeMission
+	d.controlAuthority.takenOver = true
+	d.controlAuthority.resumeExecutor = executor
+	d.controlAuthority.mu.Unlock()
+
+	d.missionPause.mu.Lock()
+	d.missionPause.paused = true
+	d.missionPause.mu.Unlock()
+
+	log.Printf("warning: manual input, pilot taking over the control from the mission\n")
+	d.events.publishPriority(EventControlAuthority, PriorityHigh, AuthorityManual)
+
+	// The executor will cancel the moveTo, and put the waypoint being
+	// flown back in the buffer. Otherwise the moveTo, or the moveBy of a
+	// relative mission, is cancelled here, in it's own go routine so the
+	// input is not blocked.
+	if executor {
+		signalMoveTo(d.gps.chMoveToCancel)
+		return
+	}
+	go func() {
+		if relative {
+			if err := d.CancelMoveBy(); err != nil {
+				log.Printf("error: failed to cancel moveBy when taking over: %v\n", err)
+			}
+			return
+		}
+		if err := d.sendCmd(Command(PilotingCancelMoveTo), &Ardrone3PilotingCancelMoveToArguments{}); err != nil {
+			log.Printf("error: failed to cancel moveTo when taking over: %v\n", err)
+		}
+	}()
+}
+
+// resumeAuthority will give the control back to the mission if taken
+// over by the pilot, and start the moveTo executor again if it was
+// flying.
+func (d *Drone) resumeAuthority() {
+	d.controlAuthority.mu.Lock()
+	takenOver := d.controlAuthority.takenOver
+	executor := d.controlAuthority.resumeExecutor
+	d.controlAuthority.takenOver = false
+	d.controlAuthority.resumeExecutor = false
+	d.controlAuthority.mu.Unlock()
+
+	if !takenOver {
+		return
+	}
+
+	log.Printf("info: mission resumed, giving the control back to the mission\n")
+	d.events.publish(EventControlAuthority, AuthorityMission)
+
+	if executor {
+		if err := d.executeMoveTo(); err != nil {
+			log.Printf("warning: resume: moveTo executor not started: %v\n", err)
+		}
+	}
+}
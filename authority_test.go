@@ -0,0 +1,116 @@
+package parrotbebop
+
+import (
+	"testing"
+	"time"
+)
+
+func TestManualInputTakesOver(t *testing.T) {
+	d := NewDrone()
+	chEvents, unsubscribe := d.events.subscribe()
+	defer unsubscribe()
+
+	nextAuthority := func() ControlAuthority {
+		for {
+			select {
+			case ev := <-chEvents:
+				if ev.Type == EventControlAuthority {
+					return ev.Value.(ControlAuthority)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("no control authority event")
+			}
+		}
+	}
+
+	// Without a mission the sticks are just flying the drone.
+	if err := d.SendSticks(0, 0.5, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if d.ControlAuthority() != AuthorityManual || d.MissionPaused() {
+		t.Fatalf("manual input without mission should not pause")
+	}
+
+	d.publishMoveTo(&Ardrone3PilotingmoveToArguments{Latitude: 60, Longitude: 10})
+	if got := d.ControlAuthority(); got != AuthorityMission {
+		t.Fatalf("got %v, want %v", got, AuthorityMission)
+	}
+
+	// A neutral stick should not take over, also when trimmed.
+	s := DefaultInputShaping
+	s.Roll.Trim = 5
+	s.Pitch.Trim = -5
+	if err := d.SetInputShaping(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SendSticks(0, 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.ControlAuthority(); got != AuthorityMission || d.MissionPaused() {
+		t.Fatalf("neutral sticks took over, got %v", got)
+	}
+
+	if err := d.SendSticks(0.5, 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := nextAuthority(); got != AuthorityManual {
+		t.Fatalf("got %v, want %v", got, AuthorityManual)
+	}
+	if !d.MissionPaused() {
+		t.Fatalf("mission not paused when taken over")
+	}
+
+	// The moveTo should be cancelled.
+	select {
+	case <-d.chSendingUDPPacket:
+	case <-time.After(time.Second * 3):
+		t.Fatalf("no cancel of the moveTo sent")
+	}
+
+	d.ResumeMission()
+	if got := nextAuthority(); got != AuthorityMission {
+		t.Fatalf("got %v, want %v", got, AuthorityMission)
+	}
+	if d.MissionPaused() {
+		t.Fatalf("mission still paused after resume")
+	}
+}
+
+func TestManualOverrideDisabled(t *testing.T) {
+	d := NewDrone()
+	d.SetManualOverride(false)
+
+	d.publishMoveTo(&Ardrone3PilotingmoveToArguments{Latitude: 60, Longitude: 10})
+	if err := d.SendSticks(0, 0.5, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.ControlAuthority(); got != AuthorityMission || d.MissionPaused() {
+		t.Fatalf("override disabled, got %v", got)
+	}
+}
+
+func TestManualInputTakesOverRelativeMission(t *testing.T) {
+	d := NewDrone()
+	d.setRelativeMission(true)
+	if got := d.ControlAuthority(); got != AuthorityMission {
+		t.Fatalf("got %v, want %v for a relative mission", got, AuthorityMission)
+	}
+
+	if err := d.SendSticks(0, 0.5, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !d.MissionPaused() {
+		t.Fatalf("relative mission not paused when taken over")
+	}
+
+	// The moveBy should be cancelled.
+	select {
+	case p := <-d.chSendingUDPPacket:
+		f, _, _ := DecodeFrame(p.data)
+		if c, _, _ := DecodeCommand(f.Data); c != Command(PilotingCancelMoveBy) {
+			t.Fatalf("got %+v sent, want the cancel of the moveBy", c)
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatalf("no cancel of the moveBy sent")
+	}
+}
@@ -0,0 +1,333 @@
+package parrotbebop
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrMissionInfeasible is returned when a mission is refused by the
+// battery guard, since the estimated duration of the mission and the
+// return leg is longer than the flight time remaining.
+var ErrMissionInfeasible = errors.New("not enough battery for the mission")
+
+const (
+	// batteryEstimateWindow is how far back the battery levels are used
+	// for estimating the discharge rate.
+	batteryEstimateWindow = time.Minute * 5
+	// batteryEstimateMinSpan is the time the battery levels must span
+	// before the discharge rate is estimated.
+	batteryEstimateMinSpan = time.Second * 30
+)
+
+// batterySample is a battery level reported while airborne.
+type batterySample struct {
+	time    time.Time
+	percent float64
+}
+
+// batteryEstimator holds the battery levels reported while airborne,
+// used for estimating the discharge rate.
+type batteryEstimator struct {
+	mu      sync.Mutex
+	samples []batterySample
+}
+
+// add will add the battery level, and drop the levels older than the
+// estimate window.
+func (b *batteryEstimator) add(percent uint8, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.samples = append(b.samples, batterySample{time: now, percent: float64(percent)})
+
+	i := 0
+	for i < len(b.samples) && now.Sub(b.samples[i].time) > batteryEstimateWindow {
+		i++
+	}
+	b.samples = b.samples[i:]
+}
+
+// rate will return the discharge rate in percent per second, found by
+// a least squares fit of the battery levels.
+func (b *batteryEstimator) rate() (float64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := len(b.samples)
+	if n < 2 || b.samples[n-1].time.Sub(b.samples[0].time) < batteryEstimateMinSpan {
+		return 0, fmt.Errorf("not enough battery levels reported while airborne")
+	}
+
+	var sumT, sumP, sumTT, sumTP float64
+	for _, s := range b.samples {
+		t := s.time.Sub(b.samples[0].time).Seconds()
+		sumT += t
+		sumP += s.percent
+		sumTT += t * t
+		sumTP += t * s.percent
+	}
+	slope := (float64(n)*sumTP - sumT*sumP) / (float64(n)*sumTT - sumT*sumT)
+	if math.IsNaN(slope) || slope >= 0 {
+		return 0, fmt.Errorf("battery is not discharging")
+	}
+
+	return -slope, nil
+}
+
+// recordBattery will add the battery level to the estimator if the
+// drone is airborne, since the discharge on the ground says nothing
+// about the flight time.
+func (d *Drone) recordBattery(percent uint8) {
+	if state, _ := d.FlyingState(); !state.Airborne() {
+		return
+	}
+	d.batteryEstimator.add(percent, time.Now())
+}
+
+// BatteryGuardMode is what the battery guard does with a mission that
+// is estimated to need more than the flight time remaining.
+type BatteryGuardMode int
+
+const (
+	// BatteryGuardOff will not check the missions.
+	BatteryGuardOff BatteryGuardMode = iota
+	// BatteryGuardWarn will log a warning, and start the mission.
+	BatteryGuardWarn
+	// BatteryGuardRefuse will refuse to start the mission.
+	BatteryGuardRefuse
+)
+
+// String will return the name of the mode.
+func (m BatteryGuardMode) String() string {
+	switch m {
+	case BatteryGuardOff:
+		return "off"
+	case BatteryGuardWarn:
+		return "warn"
+	case BatteryGuardRefuse:
+		return "refuse"
+	}
+
+	return fmt.Sprintf("BatteryGuardMode(%d)", int(m))
+}
+
+// BatteryGuard is the check of the missions against the flight time
+// remaining, done when the moveTo executor or a mission plan is
+// started.
+type BatteryGuard struct {
+	Mode BatteryGuardMode
+	// Reserve is the battery level in percent that should be left when
+	// the drone is back home.
+	Reserve uint8
+	// Speed is the speed in m/s used for estimating the duration of the
+	// mission and the return leg.
+	Speed float64
+}
+
+// DefaultBatteryGuard is the battery guard used if not set with
+// SetBatteryGuard.
+var DefaultBatteryGuard = BatteryGuard{
+	Mode:    BatteryGuardWarn,
+	Reserve: 15,
+	Speed:   5,
+}
+
+// batteryGuardConfig holds the battery guard, where the zero value uses
+// DefaultBatteryGuard.
+type batteryGuardConfig struct {
+	mu    sync.Mutex
+	guard *BatteryGuard
+}
+
+// get will return the current battery guard.
+func (c *batteryGuardConfig) get() BatteryGuard {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.guard == nil {
+		return DefaultBatteryGuard
+	}
+
+	return *c.guard
+}
+
+// SetBatteryGuard will set what to do with missions that are estimated
+// to need more than the flight time remaining.
+func (d *Drone) SetBatteryGuard(g BatteryGuard) error {
+	switch {
+	case g.Mode < BatteryGuardOff || g.Mode > BatteryGuardRefuse:
+		return fmt.Errorf("SetBatteryGuard: unknown mode: %v", g.Mode)
+	case g.Reserve > 100:
+		return fmt.Errorf("SetBatteryGuard: reserve must be within [0, 100], got %v", g.Reserve)
+	case g.Speed <= 0:
+		return fmt.Errorf("SetBatteryGuard: speed must be above 0, got %v", g.Speed)
+	}
+
+	d.batteryGuard.mu.Lock()
+	defer d.batteryGuard.mu.Unlock()
+
+	d.batteryGuard.guard = &g
+
+	return nil
+}
+
+// BatteryTimeRemaining will return the estimated flight time until the
+// battery reaches the reserve of the battery guard, based on the
+// discharge rate observed while airborne. An error is returned if the
+// drone have not been flying long enough for an estimate.
+func (d *Drone) BatteryTimeRemaining() (time.Duration, error) {
+	rate, err := d.batteryEstimator.rate()
+	if err != nil {
+		return 0, fmt.Errorf("BatteryTimeRemaining: %v", err)
+	}
+
+	left := float64(d.Telemetry().Battery) - float64(d.batteryGuard.get().Reserve)
+	if left <= 0 {
+		return 0, nil
+	}
+
+	return time.Duration(left / rate * float64(time.Second)), nil
+}
+
+// MissionFeasibility is the estimated duration of a mission compared
+// with the flight time remaining.
+type MissionFeasibility struct {
+	// Duration is the estimated time to fly the mission.
+	Duration time.Duration
+	// ReturnLeg is the estimated time to fly home from the end of the
+	// mission.
+	ReturnLeg time.Duration
+	// Remaining is the estimated flight time remaining.
+	Remaining time.Duration
+	// Feasible is true when the mission and the return leg are
+	// estimated to be done within the flight time remaining.
+	Feasible bool
+}
+
+// flightTime will return the time for flying the path at the speed,
+// where the altitude changes are included in the distance.
+func flightTime(path []Position, speed float64) time.Duration {
+	var dist float64
+	for i := 1; i < len(path); i++ {
+		h := path[i-1].DistanceTo(path[i])
+		v := path[i].Altitude - path[i-1].Altitude
+		dist += math.Sqrt(h*h + v*v)
+	}
+
+	return time.Duration(dist / speed * float64(time.Second))
+}
+
+// feasibility will compare the mission flown along the path, with the
+// extra time given, and the return leg from the end of the path to
+// home, against the flight time remaining. If no home is set the
+// return leg is back to the start of the path.
+func (d *Drone) feasibility(path []Position, extra time.Duration) (MissionFeasibility, error) {
+	remaining, err := d.BatteryTimeRemaining()
+	if err != nil {
+		return MissionFeasibility{}, err
+	}
+	if len(path) == 0 {
+		return MissionFeasibility{Remaining: remaining, Feasible: true}, nil
+	}
+
+	speed := d.batteryGuard.get().Speed
+	home := path[0]
+	if h := d.Home(); h.Set {
+		home = Position{Latitude: h.Latitude, Longitude: h.Longitude, Altitude: h.Altitude}
+	}
+
+	f := MissionFeasibility{
+		Duration:  flightTime(path, speed) + extra,
+		ReturnLeg: flightTime([]Position{path[len(path)-1], home}, speed),
+		Remaining: remaining,
+	}
+	f.Feasible = f.Duration+f.ReturnLeg <= f.Remaining
+
+	return f, nil
+}
+
+// MoveToFeasibility will estimate if there is flight time enough for
+// flying from the current position through the waypoints in the moveTo
+// buffer, and back home.
+func (d *Drone) MoveToFeasibility() (MissionFeasibility, error) {
+	path := []Position{d.Telemetry().Position}
+	path = append(path, d.Waypoints()...)
+
+	f, err := d.feasibility(path, 0)
+	if err != nil {
+		return f, fmt.Errorf("MoveToFeasibility: %v", err)
+	}
+
+	return f, nil
+}
+
+// PlanFeasibility will estimate if there is flight time enough for the
+// mission plan and the flight back home, where the moveto and orbit
+// steps are flown from the current position, and the waits with a
+// duration are added. The time of the other steps are not known, and
+// not included.
+func (d *Drone) PlanFeasibility(plan *MissionPlan) (MissionFeasibility, error) {
+	path := []Position{d.Telemetry().Position}
+	var extra time.Duration
+
+	for _, s := range plan.Steps {
+		switch s.Type {
+		case "moveto":
+			path = append(path, Position{Latitude: s.float("latitude", 0), Longitude: s.float("longitude", 0), Altitude: s.float("altitude", 0)})
+		case "orbit":
+			center := Position{Latitude: s.float("latitude", 0), Longitude: s.float("longitude", 0), Altitude: s.float("altitude", 0)}
+			path = append(path, center.Offset(s.float("radius", 0), 0))
+			speed := s.float("speed", defaultOrbitSpeed)
+			if speed > 0 {
+				extra += time.Duration(s.float("turns", 1) * 360 / speed * float64(time.Second))
+			}
+		case "moveby":
+			// The relative moves are not on the path, since the
+			// position is not known without GPS.
+			dist := math.Sqrt(math.Pow(s.float("forward", 0), 2) + math.Pow(s.float("right", 0), 2) + math.Pow(s.float("up", 0), 2))
+			if speed := d.batteryGuard.get().Speed; speed > 0 {
+				extra += time.Duration(dist / speed * float64(time.Second))
+			}
+		case "wait":
+			extra += s.duration("value", 0)
+		}
+	}
+
+	f, err := d.feasibility(path, extra)
+	if err != nil {
+		return f, fmt.Errorf("PlanFeasibility: %v", err)
+	}
+
+	return f, nil
+}
+
+// checkBatteryGuard will check the feasibility given against the
+// battery guard, where an error wrapping ErrMissionInfeasible is
+// returned if the mission should be refused. When no estimate can be
+// made the mission is allowed.
+func (d *Drone) checkBatteryGuard(mission string, f MissionFeasibility, err error) error {
+	mode := d.batteryGuard.get().Mode
+	if mode == BatteryGuardOff {
+		return nil
+	}
+	if err != nil {
+		log.Printf("info: %v: no battery estimate: %v\n", mission, err)
+		return nil
+	}
+	if f.Feasible {
+		return nil
+	}
+
+	err = fmt.Errorf("%w: %v estimated to take %v with %v return, and %v flight time is remaining",
+		ErrMissionInfeasible, mission, f.Duration.Round(time.Second), f.ReturnLeg.Round(time.Second), f.Remaining.Round(time.Second))
+	if mode == BatteryGuardWarn {
+		log.Printf("warning: %v\n", err)
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,105 @@
+package parrotbebop
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestBatteryEstimator(t *testing.T) {
+	var b batteryEstimator
+	start := time.Now()
+
+	// An old level outside of the window should be dropped.
+	b.add(100, start.Add(-batteryEstimateWindow*2))
+	b.add(90, start)
+	if _, err := b.rate(); err == nil {
+		t.Fatalf("expected error with a single level")
+	}
+
+	// 1% every 10 seconds.
+	for i := 1; i <= 6; i++ {
+		b.add(uint8(90-i), start.Add(time.Duration(i)*time.Second*10))
+	}
+	rate, err := b.rate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(rate-0.1) > 1e-9 {
+		t.Fatalf("got rate %v, want 0.1", rate)
+	}
+}
+
+func TestMissionFeasibility(t *testing.T) {
+	d := NewDrone()
+
+	bad := []BatteryGuard{
+		{Mode: BatteryGuardRefuse + 1, Speed: 5},
+		{Reserve: 101, Speed: 5},
+		{Speed: 0},
+	}
+	for _, g := range bad {
+		if err := d.SetBatteryGuard(g); err == nil {
+			t.Fatalf("expected error for %+v", g)
+		}
+	}
+	if err := d.SetBatteryGuard(BatteryGuard{Mode: BatteryGuardRefuse, Reserve: 10, Speed: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	// No estimate, so the mission is allowed.
+	f, err := d.MoveToFeasibility()
+	if err == nil {
+		t.Fatalf("expected error without battery levels")
+	}
+	if err := d.checkBatteryGuard("test", f, err); err != nil {
+		t.Fatal(err)
+	}
+
+	// Discharging 0.1% per second, with 40% left to the reserve, gives
+	// 400 seconds remaining.
+	now := time.Now()
+	d.batteryEstimator.add(54, now.Add(-time.Second*100))
+	d.batteryEstimator.add(50, now.Add(-time.Second*60))
+	d.telemetry.update(func(t *Telemetry) { t.Battery = 50 })
+	remaining, err := d.BatteryTimeRemaining()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remaining != time.Second*400 {
+		t.Fatalf("got %v remaining, want 400s", remaining)
+	}
+
+	start := Position{Latitude: 60, Longitude: 10}
+	d.telemetry.update(func(t *Telemetry) { t.Position = start })
+
+	// 1000 m out, and 1000 m back, at 10 m/s is 200 seconds.
+	near := start.Offset(1000, 90)
+	plan := &MissionPlan{Steps: []MissionStep{
+		{Type: "moveto", Params: map[string]string{"latitude": strconv.FormatFloat(near.Latitude, 'f', -1, 64), "longitude": strconv.FormatFloat(near.Longitude, 'f', -1, 64), "altitude": "0"}},
+		{Type: "wait", Params: map[string]string{"value": "1m"}},
+	}}
+	f, err = d.PlanFeasibility(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.Feasible || f.Duration.Round(time.Second) != time.Second*160 || f.ReturnLeg.Round(time.Second) != time.Second*100 {
+		t.Fatalf("got %+v", f)
+	}
+
+	// 2500 m out is 500 seconds in total, which is more than remaining.
+	far := start.Offset(2500, 90)
+	d.moveToBuffer.pushWayPointNew(fromPosition(far))
+	f, err = d.MoveToFeasibility()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Feasible {
+		t.Fatalf("expected mission to be infeasible: %+v", f)
+	}
+	if err := d.checkBatteryGuard("test", f, nil); !errors.Is(err, ErrMissionInfeasible) {
+		t.Fatalf("expected refused mission, got %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package parrotbebop
+
+import (
+	"fmt"
+)
+
+// The ARNetworkAL data types of the frames.
+const (
+	// dataTypeAck is an acknowledgment of previously received data.
+	dataTypeAck = 1
+	// dataTypeData is normal data, where no ack is requested.
+	dataTypeData = 2
+	// dataTypeLowLatency is treated as normal data on the network, but
+	// is given higher priority internally.
+	dataTypeLowLatency = 3
+	// dataTypeDataWithAck is data requesting an ack, and the receiver
+	// must send an ack for it.
+	dataTypeDataWithAck = 4
+)
+
+// ackBufferOffset is added to the buffer ID of the data being acked
+// to get the buffer ID of the ack.
+const ackBufferOffset = 128
+
+// The ARNetwork buffer IDs used by the Bebop, as given in the
+// documentation of the ARSDK.
+const (
+	// bufferPing and bufferPong are the internal buffers of ARNetwork
+	// used to check that the connection is alive. A ping received is
+	// answered with a pong holding the payload of the ping.
+	bufferPing = 0
+	bufferPong = 1
+
+	// bufferC2DNonAck is for periodic commands like piloting and
+	// camera orientation, where a lost frame is replaced by the next.
+	bufferC2DNonAck = 10
+	// bufferC2DAck is for events and settings, which must arrive.
+	bufferC2DAck = 11
+	// bufferC2DEmergency is for the emergency command only.
+	bufferC2DEmergency = 12
+	// bufferC2DVideoAck is for the ARStream video acks.
+	bufferC2DVideoAck = 13
+
+	// bufferD2CVideoData is for the ARStream video data.
+	bufferD2CVideoData = 125
+	// bufferD2CEvents is for the events and settings from the drone.
+	bufferD2CEvents = 126
+	// bufferD2CNavdata is for the periodic state values from the drone,
+	// like the attitude and position.
+	bufferD2CNavdata = 127
+)
+
+// bufferDirection is which way the frames of a buffer are sent.
+type bufferDirection int
+
+const (
+	// controllerToDrone is for the buffers the controller sends on.
+	controllerToDrone bufferDirection = iota
+	// droneToController is for the buffers the drone sends on.
+	droneToController
+	// bothDirections is for the internal ping and pong buffers.
+	bothDirections
+)
+
+// networkBuffer is the definition of an ARNetwork buffer.
+type networkBuffer struct {
+	id        int
+	name      string
+	direction bufferDirection
+	// dataType is the data type of the frames sent on the buffer.
+	dataType int
+}
+
+// networkBuffers are all the buffers used with the Bebop, where the
+// ack buffers are the buffers of the data type with ack, offset with
+// ackBufferOffset, and sent the opposite direction.
+var networkBuffers = func() map[int]networkBuffer {
+	bufs := []networkBuffer{
+		{id: bufferPing, name: "ping", direction: bothDirections, dataType: dataTypeData},
+		{id: bufferPong, name: "pong", direction: bothDirections, dataType: dataTypeData},
+		{id: bufferC2DNonAck, name: "c2d non ack", direction: controllerToDrone, dataType: dataTypeData},
+		{id: bufferC2DAck, name: "c2d ack", direction: controllerToDrone, dataType: dataTypeDataWithAck},
+		{id: bufferC2DEmergency, name: "c2d emergency", direction: controllerToDrone, dataType: dataTypeDataWithAck},
+		{id: bufferC2DVideoAck, name: "c2d video ack", direction: controllerToDrone, dataType: dataTypeLowLatency},
+		{id: bufferD2CVideoData, name: "d2c video data", direction: droneToController, dataType: dataTypeLowLatency},
+		{id: bufferD2CEvents, name: "d2c events", direction: droneToController, dataType: dataTypeDataWithAck},
+		{id: bufferD2CNavdata, name: "d2c navdata", direction: droneToController, dataType: dataTypeData},
+	}
+
+	m := make(map[int]networkBuffer)
+	for _, b := range bufs {
+		m[b.id] = b
+		if b.dataType != dataTypeDataWithAck {
+			continue
+		}
+
+		ack := networkBuffer{
+			id:        ackID(b.id),
+			name:      b.name + " acks",
+			direction: controllerToDrone,
+			dataType:  dataTypeAck,
+		}
+		if b.direction == controllerToDrone {
+			ack.direction = droneToController
+		}
+		m[ack.id] = ack
+	}
+
+	return m
+}()
+
+// ackID will return the ID of the buffer used for the acks of the
+// frames sent on the buffer given.
+func ackID(bufferID int) int {
+	return bufferID + ackBufferOffset
+}
+
+// lookupBuffer will return the definition of the buffer, or an error
+// if the buffer is not known.
+func lookupBuffer(id int) (networkBuffer, error) {
+	b, ok := networkBuffers[id]
+	if !ok {
+		return networkBuffer{}, fmt.Errorf("unknown ARNetwork buffer: %v", id)
+	}
+
+	return b, nil
+}
+
+// bufferForCmd will return the buffer a command should be sent on,
+// where the emergency command have it's own buffer, the periodic
+// piloting and camera commands are sent without ack, and all the other
+// commands are sent with ack.
+func bufferForCmd(c Command) networkBuffer {
+	switch c {
+	case Command(PilotingEmergency):
+		return networkBuffers[bufferC2DEmergency]
+	case Command(PilotingPCMD), Command(CameraOrientation), Command(CameraOrientationV2), Command(CameraVelocity):
+		return networkBuffers[bufferC2DNonAck]
+	}
+
+	return networkBuffers[bufferC2DAck]
+}
@@ -0,0 +1,69 @@
+package parrotbebop
+
+import (
+	"testing"
+)
+
+func TestNetworkBuffersAckCounterparts(t *testing.T) {
+	tests := []struct {
+		id        int
+		direction bufferDirection
+	}{
+		{id: bufferC2DAck + ackBufferOffset, direction: droneToController},
+		{id: bufferC2DEmergency + ackBufferOffset, direction: droneToController},
+		{id: bufferD2CEvents + ackBufferOffset, direction: controllerToDrone},
+	}
+
+	for _, tt := range tests {
+		b, err := lookupBuffer(tt.id)
+		if err != nil {
+			t.Fatalf("missing ack buffer %v: %v", tt.id, err)
+		}
+		if b.dataType != dataTypeAck || b.direction != tt.direction {
+			t.Fatalf("wrong ack buffer %v: %#v", tt.id, b)
+		}
+	}
+
+	// Buffers without ack should not have an ack buffer.
+	if _, err := lookupBuffer(bufferD2CNavdata + ackBufferOffset); err == nil {
+		t.Fatalf("unexpected ack buffer for the navdata buffer")
+	}
+}
+
+func TestEncodeCmdBuffers(t *testing.T) {
+	u := newUdpPacketCreator()
+
+	tests := []struct {
+		name     string
+		p        networkUDPPacket
+		dataType byte
+		buffer   byte
+	}{
+		{"pcmd", u.encodeCmd(Command(PilotingPCMD), &Ardrone3PilotingPCMDArguments{}), dataTypeData, bufferC2DNonAck},
+		{"takeoff", u.encodeCmd(Command(PilotingTakeOff), &Ardrone3PilotingTakeOffArguments{}), dataTypeDataWithAck, bufferC2DAck},
+		{"emergency", u.encodeCmd(Command(PilotingEmergency), &Ardrone3PilotingEmergencyArguments{}), dataTypeDataWithAck, bufferC2DEmergency},
+	}
+
+	for _, tt := range tests {
+		if tt.p.data[0] != tt.dataType || tt.p.data[1] != tt.buffer {
+			t.Fatalf("%v: got data type %v on buffer %v, want %v on %v", tt.name, tt.p.data[0], tt.p.data[1], tt.dataType, tt.buffer)
+		}
+	}
+}
+
+func TestEncodePong(t *testing.T) {
+	u := newUdpPacketCreator()
+	ping := protocolARNetworkAL{
+		dataType:       dataTypeData,
+		targetBufferID: bufferPing,
+		dataARNetwork:  []byte{1, 2, 3, 4, 5, 6, 7, 8},
+	}
+
+	p := u.encodePong(ping)
+	if p.data[1] != bufferPong {
+		t.Fatalf("pong sent on buffer %v, want %v", p.data[1], bufferPong)
+	}
+	if size := int(p.data[3]); size != len(p.data) || size != 7+len(ping.dataARNetwork) {
+		t.Fatalf("wrong size in pong header: %v, frame length %v", size, len(p.data))
+	}
+}
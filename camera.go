@@ -0,0 +1,156 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// CameraGimbal is the orientation of the camera, and the limits of
+// it, as reported by the drone. All the angles are in degrees, and the
+// speeds in degrees per second.
+type CameraGimbal struct {
+	Tilt float32
+	Pan  float32
+	// DefaultTilt and DefaultPan is the centered orientation.
+	DefaultTilt float32
+	DefaultPan  float32
+	// The limits of the orientation.
+	TiltMin float32
+	TiltMax float32
+	PanMin  float32
+	PanMax  float32
+	// MaxTiltSpeed and MaxPanSpeed are the limits of the velocity.
+	MaxTiltSpeed float32
+	MaxPanSpeed  float32
+}
+
+// limitsKnown will return true if the drone have reported the limits
+// of the orientation.
+func (g CameraGimbal) limitsKnown() bool {
+	return g.TiltMin != g.TiltMax || g.PanMin != g.PanMax
+}
+
+// atLimit will return true if the angle have reached the limit in the
+// direction of the speed.
+func atLimit(angle float32, speed float32, min float32, max float32) bool {
+	return (speed > 0 && angle >= max) || (speed < 0 && angle <= min)
+}
+
+// clamp32 will return v limited to [min, max].
+func clamp32(v float32, min float32, max float32) float32 {
+	return float32(math.Max(float64(min), math.Min(float64(max), float64(v))))
+}
+
+// cameraVelocity holds the velocity of the camera last sent to the
+// drone, so it can be stopped when the limits are reached.
+type cameraVelocity struct {
+	mu   sync.Mutex
+	tilt float32
+	pan  float32
+}
+
+// CameraGimbal will return the orientation of the camera, and the
+// limits of it, as reported by the drone.
+func (d *Drone) CameraGimbal() CameraGimbal {
+	return d.telemetry.snapshot().Gimbal
+}
+
+// SetCameraOrientation will move the camera to the absolute tilt and
+// pan given in degrees, limited to the range reported by the drone.
+func (d *Drone) SetCameraOrientation(tilt float32, pan float32) error {
+	g := d.CameraGimbal()
+	if g.limitsKnown() {
+		tilt = clamp32(tilt, g.TiltMin, g.TiltMax)
+		pan = clamp32(pan, g.PanMin, g.PanMax)
+	}
+
+	return d.sendCmd(Command(CameraOrientationV2), &Ardrone3CameraOrientationV2Arguments{Tilt: tilt, Pan: pan})
+}
+
+// SetCameraVelocity will move the camera continuously with the tilt and
+// pan speeds given in degrees per second, limited to the max speeds
+// reported by the drone. The movement stops when the camera reaches
+// the limits of the orientation, or when the speeds are set to 0.
+func (d *Drone) SetCameraVelocity(tilt float32, pan float32) error {
+	g := d.CameraGimbal()
+	if g.MaxTiltSpeed > 0 {
+		tilt = clamp32(tilt, -g.MaxTiltSpeed, g.MaxTiltSpeed)
+	}
+	if g.MaxPanSpeed > 0 {
+		pan = clamp32(pan, -g.MaxPanSpeed, g.MaxPanSpeed)
+	}
+
+	// Don't move further out if already at the limit.
+	if g.limitsKnown() {
+		if atLimit(g.Tilt, tilt, g.TiltMin, g.TiltMax) {
+			tilt = 0
+		}
+		if atLimit(g.Pan, pan, g.PanMin, g.PanMax) {
+			pan = 0
+		}
+	}
+
+	d.cameraVelocity.mu.Lock()
+	defer d.cameraVelocity.mu.Unlock()
+
+	if err := d.sendCmd(Command(CameraVelocity), &Ardrone3CameraVelocityArguments{Tilt: tilt, Pan: pan}); err != nil {
+		return err
+	}
+	d.cameraVelocity.tilt = tilt
+	d.cameraVelocity.pan = pan
+
+	return nil
+}
+
+// SetCameraStick will move the camera with the position of a stick,
+// like the second stick of a gamepad, where x is the pan and y is the
+// tilt in the range [-1, 1], and the speed is the fraction of the max
+// speeds reported by the drone. Centering the stick stops the camera.
+func (d *Drone) SetCameraStick(x float64, y float64) error {
+	if x < -1 || x > 1 || y < -1 || y > 1 {
+		return fmt.Errorf("SetCameraStick: x and y must be within [-1, 1], got %v, %v", x, y)
+	}
+
+	g := d.CameraGimbal()
+	if g.MaxTiltSpeed == 0 || g.MaxPanSpeed == 0 {
+		return fmt.Errorf("SetCameraStick: the camera velocity range is not reported by the drone yet")
+	}
+
+	return d.SetCameraVelocity(float32(y)*g.MaxTiltSpeed, float32(x)*g.MaxPanSpeed)
+}
+
+// handleCameraOrientation will store the orientation reported by the
+// drone, and stop the velocity of an axis that have reached it's limit.
+func (d *Drone) handleCameraOrientation(tilt float32, pan float32) {
+	var g CameraGimbal
+	d.telemetry.update(func(t *Telemetry) {
+		t.Gimbal.Tilt = tilt
+		t.Gimbal.Pan = pan
+		g = t.Gimbal
+	})
+
+	if !g.limitsKnown() {
+		return
+	}
+
+	d.cameraVelocity.mu.Lock()
+	vTilt, vPan := d.cameraVelocity.tilt, d.cameraVelocity.pan
+	d.cameraVelocity.mu.Unlock()
+
+	stopTilt := atLimit(tilt, vTilt, g.TiltMin, g.TiltMax)
+	stopPan := atLimit(pan, vPan, g.PanMin, g.PanMax)
+	if !stopTilt && !stopPan {
+		return
+	}
+
+	if stopTilt {
+		vTilt = 0
+	}
+	if stopPan {
+		vPan = 0
+	}
+	if err := d.SetCameraVelocity(vTilt, vPan); err != nil {
+		d.debugf("error: failed to stop the camera at it's limit: %v\r\n", err)
+	}
+}
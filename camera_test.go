@@ -0,0 +1,109 @@
+package parrotbebop
+
+import (
+	"testing"
+	"time"
+)
+
+// cameraTestDrone will return a drone with the camera limits set, and a
+// channel with the arguments of the commands sent.
+func cameraTestDrone() (*Drone, chan interface{}) {
+	d := NewDrone()
+	d.telemetry.update(func(t *Telemetry) {
+		t.Gimbal = CameraGimbal{
+			TiltMin:      -80,
+			TiltMax:      20,
+			PanMin:       -40,
+			PanMax:       40,
+			MaxTiltSpeed: 10,
+			MaxPanSpeed:  20,
+		}
+	})
+
+	sent := make(chan interface{}, 10)
+	go func() {
+		for p := range d.chSendingUDPPacket {
+			_, args, err := DecodeCommand(p.data[frameHeaderSize:])
+			if err != nil {
+				panic(err)
+			}
+			sent <- args
+		}
+	}()
+
+	return d, sent
+}
+
+func nextSent(t *testing.T, sent chan interface{}) interface{} {
+	t.Helper()
+
+	select {
+	case args := <-sent:
+		return args
+	case <-time.After(time.Second):
+		t.Fatalf("no command sent")
+	}
+
+	return nil
+}
+
+func TestSetCameraOrientationClamped(t *testing.T) {
+	d, sent := cameraTestDrone()
+
+	if err := d.SetCameraOrientation(-100, 50); err != nil {
+		t.Fatal(err)
+	}
+	want := Ardrone3CameraOrientationV2Arguments{Tilt: -80, Pan: 40}
+	if got := nextSent(t, sent); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestSetCameraStick(t *testing.T) {
+	d, sent := cameraTestDrone()
+
+	if err := d.SetCameraStick(2, 0); err == nil {
+		t.Fatalf("expected error for stick outside [-1, 1]")
+	}
+
+	if err := d.SetCameraStick(-0.5, 1); err != nil {
+		t.Fatal(err)
+	}
+	want := Ardrone3CameraVelocityArguments{Tilt: 10, Pan: -10}
+	if got := nextSent(t, sent); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestCameraVelocityStopsAtLimit(t *testing.T) {
+	d, sent := cameraTestDrone()
+
+	if err := d.SetCameraVelocity(10, -20); err != nil {
+		t.Fatal(err)
+	}
+	nextSent(t, sent)
+
+	// Within the limits the velocity should be kept.
+	d.handleCameraOrientation(0, 0)
+	select {
+	case args := <-sent:
+		t.Fatalf("command sent within the limits: %+v", args)
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	// Tilt reaching the max should stop only the tilt.
+	d.handleCameraOrientation(20, -10)
+	want := Ardrone3CameraVelocityArguments{Tilt: 0, Pan: -20}
+	if got := nextSent(t, sent); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	// Moving further out at the limit should not be sent.
+	if err := d.SetCameraVelocity(5, 0); err != nil {
+		t.Fatal(err)
+	}
+	want = Ardrone3CameraVelocityArguments{}
+	if got := nextSent(t, sent); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,216 @@
+package parrotbebop
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// pcapLinkTypeRaw is the pcap link type for raw IP packets with no
+	// link layer header.
+	pcapLinkTypeRaw = 101
+	// pcapSnapLen is the max size of the packets captured.
+	pcapSnapLen = 65535
+
+	ipProtoTCP = 6
+	ipProtoUDP = 17
+)
+
+// capture will write the traffic between the controller and the drone
+// into a pcap file, which can be analyzed with for example Wireshark.
+// Since the packets are captured at the application level, the IP, UDP
+// and TCP headers are made up from the addresses of the connections.
+type capture struct {
+	mu sync.Mutex
+	w  io.Writer
+	// localIP is the IP address of the controller used in the headers
+	// for the connections with no known local address.
+	localIP net.IP
+	// tcpSeq holds the next TCP sequence number for each direction of
+	// a TCP connection.
+	tcpSeq map[string]uint32
+}
+
+// StartCapture will start writing every UDP datagram sent to and received
+// from the drone, and the TCP discovery exchange, into w in the pcap
+// format. The headers of the packets are made up by the driver, so TCP
+// checksums are not set, and should be ignored by the analyzer.
+func (d *Drone) StartCapture(w io.Writer) error {
+	d.capture.mu.Lock()
+	defer d.capture.mu.Unlock()
+
+	hdr := make([]byte, 24)
+	binary.LittleEndian.PutUint32(hdr[0:4], 0xa1b2c3d4)
+	binary.LittleEndian.PutUint16(hdr[4:6], 2)
+	binary.LittleEndian.PutUint16(hdr[6:8], 4)
+	binary.LittleEndian.PutUint32(hdr[16:20], pcapSnapLen)
+	binary.LittleEndian.PutUint32(hdr[20:24], pcapLinkTypeRaw)
+
+	if _, err := w.Write(hdr); err != nil {
+		return fmt.Errorf("StartCapture: failed to write pcap header: %v", err)
+	}
+
+	d.capture.w = w
+	d.capture.tcpSeq = make(map[string]uint32)
+
+	return nil
+}
+
+// StopCapture will stop writing the traffic to the capture writer. The
+// writer is not closed.
+func (d *Drone) StopCapture() {
+	d.capture.mu.Lock()
+	defer d.capture.mu.Unlock()
+
+	d.capture.w = nil
+}
+
+// setLocalIP will set the IP address of the controller used for the
+// connections with no known local address.
+func (c *capture) setLocalIP(ip net.IP) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.localIP = ip
+}
+
+// local will return the address of the controller with the port given.
+func (c *capture) local(port string) net.Addr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, _ := strconv.Atoi(port)
+	ip := c.localIP
+	if ip == nil {
+		ip = net.IPv4zero
+	}
+
+	return &net.UDPAddr{IP: ip, Port: p}
+}
+
+// udp will write an UDP datagram sent from src to dst to the capture.
+func (c *capture) udp(src net.Addr, dst net.Addr, payload []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.w == nil {
+		return
+	}
+
+	srcIP, srcPort := splitAddr(src)
+	dstIP, dstPort := splitAddr(dst)
+
+	udp := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint16(udp[0:2], srcPort)
+	binary.BigEndian.PutUint16(udp[2:4], dstPort)
+	binary.BigEndian.PutUint16(udp[4:6], uint16(8+len(payload)))
+	// A zero checksum means no checksum for UDP over IPv4.
+	udp = append(udp, payload...)
+
+	c.writePacket(ipv4Packet(ipProtoUDP, srcIP, dstIP, udp))
+}
+
+// tcp will write a TCP segment with the payload sent from src to dst to
+// the capture, keeping track of the sequence numbers of the connection.
+func (c *capture) tcp(src net.Addr, dst net.Addr, payload []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.w == nil {
+		return
+	}
+
+	srcIP, srcPort := splitAddr(src)
+	dstIP, dstPort := splitAddr(dst)
+
+	seq := c.tcpSeq[src.String()+"-"+dst.String()]
+	ack := c.tcpSeq[dst.String()+"-"+src.String()]
+	c.tcpSeq[src.String()+"-"+dst.String()] = seq + uint32(len(payload))
+
+	tcp := make([]byte, 20, 20+len(payload))
+	binary.BigEndian.PutUint16(tcp[0:2], srcPort)
+	binary.BigEndian.PutUint16(tcp[2:4], dstPort)
+	binary.BigEndian.PutUint32(tcp[4:8], seq)
+	binary.BigEndian.PutUint32(tcp[8:12], ack)
+	// Header length of 5 words, and the PSH and ACK flags.
+	tcp[12] = 5 << 4
+	tcp[13] = 0x18
+	binary.BigEndian.PutUint16(tcp[14:16], 65535)
+	tcp = append(tcp, payload...)
+
+	c.writePacket(ipv4Packet(ipProtoTCP, srcIP, dstIP, tcp))
+}
+
+// writePacket will write the packet as a pcap record. Must be called
+// while holding the lock.
+func (c *capture) writePacket(p []byte) {
+	now := time.Now()
+	size := len(p)
+	if size > pcapSnapLen {
+		p = p[:pcapSnapLen]
+	}
+
+	rec := make([]byte, 16, 16+len(p))
+	binary.LittleEndian.PutUint32(rec[0:4], uint32(now.Unix()))
+	binary.LittleEndian.PutUint32(rec[4:8], uint32(now.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(rec[8:12], uint32(len(p)))
+	binary.LittleEndian.PutUint32(rec[12:16], uint32(size))
+	rec = append(rec, p...)
+
+	if _, err := c.w.Write(rec); err != nil {
+		log.Printf("error: capture: failed to write packet, stopping capture: %v\n", err)
+		c.w = nil
+	}
+}
+
+// ipv4Packet will create an IPv4 packet with the payload given.
+func ipv4Packet(proto uint8, src net.IP, dst net.IP, payload []byte) []byte {
+	p := make([]byte, 20, 20+len(payload))
+	p[0] = 0x45
+	binary.BigEndian.PutUint16(p[2:4], uint16(20+len(payload)))
+	// Don't fragment flag.
+	p[6] = 0x40
+	p[8] = 64
+	p[9] = proto
+	copy(p[12:16], src.To4())
+	copy(p[16:20], dst.To4())
+
+	var sum uint32
+	for i := 0; i < 20; i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(p[i : i+2]))
+	}
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	binary.BigEndian.PutUint16(p[10:12], ^uint16(sum))
+
+	return append(p, payload...)
+}
+
+// splitAddr will return the IPv4 address and port of a network address.
+func splitAddr(a net.Addr) (net.IP, uint16) {
+	switch a := a.(type) {
+	case *net.UDPAddr:
+		return ipv4OrZero(a.IP), uint16(a.Port)
+	case *net.TCPAddr:
+		return ipv4OrZero(a.IP), uint16(a.Port)
+	}
+
+	return net.IPv4zero, 0
+}
+
+// ipv4OrZero will return the IPv4 address, or 0.0.0.0 if not an IPv4
+// address.
+func ipv4OrZero(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+
+	return net.IPv4zero.To4()
+}
@@ -0,0 +1,56 @@
+package parrotbebop
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+func TestCaptureUDP(t *testing.T) {
+	var d Drone
+	var buf bytes.Buffer
+	if err := d.StartCapture(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	src := &net.UDPAddr{IP: net.ParseIP("192.168.42.1"), Port: 54321}
+	dst := &net.UDPAddr{IP: net.ParseIP("192.168.42.23"), Port: 43210}
+	payload := []byte{2, 127, 1, 8, 0, 0, 0, 42}
+	d.capture.udp(src, dst, payload)
+
+	b := buf.Bytes()
+	if want := 24 + 16 + 20 + 8 + len(payload); len(b) != want {
+		t.Fatalf("wrong capture size: %v, want %v", len(b), want)
+	}
+	if binary.LittleEndian.Uint32(b[20:24]) != pcapLinkTypeRaw {
+		t.Fatalf("wrong link type")
+	}
+
+	ip := b[40:60]
+	var sum uint32
+	for i := 0; i < 20; i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(ip[i : i+2]))
+	}
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	if sum != 0xffff {
+		t.Fatalf("wrong IP header checksum")
+	}
+	if !net.IP(ip[12:16]).Equal(src.IP) || !net.IP(ip[16:20]).Equal(dst.IP) {
+		t.Fatalf("wrong IP addresses in header")
+	}
+	if port := binary.BigEndian.Uint16(b[60:62]); port != 54321 {
+		t.Fatalf("wrong UDP source port: %v", port)
+	}
+	if !bytes.Equal(b[68:], payload) {
+		t.Fatalf("wrong payload: %v", b[68:])
+	}
+
+	d.StopCapture()
+	d.capture.udp(src, dst, payload)
+	if len(buf.Bytes()) != len(b) {
+		t.Fatalf("packet captured after StopCapture")
+	}
+}
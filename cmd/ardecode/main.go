@@ -0,0 +1,181 @@
+// ardecode is a tool for reading the ARNetwork traffic of the drone,
+// where the frames of a pcap capture, or of a hex dump, are written to
+// stdout with the name of the command and the decoded arguments, using
+// the same command tables as the driver.
+//
+// The pcap can be captured with wireshark or tcpdump, or with the
+// driver itself, and both raw IP and ethernet captures are read. The
+// direction of each packet is found by the UDP ports. The hex dump is
+// given as a packet per line, where spaces, colons and 0x prefixes are
+// ignored, and the lines starting with # are comments.
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/postmannen/parrotbebop"
+)
+
+const (
+	linkTypeEthernet = 1
+	linkTypeRaw      = 101
+	ethernetHeader   = 14
+	udpHeader        = 8
+)
+
+func main() {
+	pcapFile := flag.String("pcap", "", "pcap file to decode")
+	hexFile := flag.String("hex", "", "hex dump to decode, where - is stdin")
+	direction := flag.String("direction", "C2D", "direction of the packets in the hex dump, C2D or D2C")
+	portC2D := flag.Int("c2d-port", 54321, "UDP port of the drone, for finding the direction of the packets in the pcap")
+	portD2C := flag.Int("d2c-port", 43210, "UDP port of the controller, for finding the direction of the packets in the pcap")
+	flag.Parse()
+
+	var err error
+	switch {
+	case *pcapFile != "":
+		err = decodePcap(os.Stdout, *pcapFile, uint16(*portC2D), uint16(*portD2C))
+	case *hexFile != "":
+		err = decodeHex(os.Stdout, *hexFile, *direction)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("error: %v\n", err)
+	}
+}
+
+// decodeHex will write the frames of each packet in the hex dump.
+func decodeHex(w io.Writer, name string, direction string) error {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	replacer := strings.NewReplacer("0x", "", "0X", "", " ", "", ":", "", "\t", "")
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		b, err := hex.DecodeString(replacer.Replace(text))
+		if err != nil {
+			return fmt.Errorf("line %v: %v", line, err)
+		}
+		if err := parrotbebop.WriteFrames(w, direction, b); err != nil {
+			log.Printf("warning: line %v: %v\n", line, err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// decodePcap will write the frames of each UDP packet to or from the
+// drone in the pcap, with the time captured.
+func decodePcap(w io.Writer, name string, portC2D uint16, portD2C uint16) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return fmt.Errorf("reading pcap header: %v", err)
+	}
+
+	var order binary.ByteOrder
+	switch {
+	case binary.LittleEndian.Uint32(header) == 0xa1b2c3d4:
+		order = binary.LittleEndian
+	case binary.BigEndian.Uint32(header) == 0xa1b2c3d4:
+		order = binary.BigEndian
+	default:
+		return fmt.Errorf("not a pcap file, or a nanosecond pcap")
+	}
+	linkType := order.Uint32(header[20:])
+	if linkType != linkTypeRaw && linkType != linkTypeEthernet {
+		return fmt.Errorf("unsupported link type %v", linkType)
+	}
+
+	record := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(r, record); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("reading pcap record: %v", err)
+		}
+		ts := time.Unix(int64(order.Uint32(record)), int64(order.Uint32(record[4:]))*1000)
+
+		packet := make([]byte, order.Uint32(record[8:]))
+		if _, err := io.ReadFull(r, packet); err != nil {
+			return fmt.Errorf("reading pcap packet: %v", err)
+		}
+		if linkType == linkTypeEthernet {
+			if len(packet) < ethernetHeader || binary.BigEndian.Uint16(packet[12:]) != 0x0800 {
+				continue
+			}
+			packet = packet[ethernetHeader:]
+		}
+
+		srcPort, dstPort, payload, ok := udpPayload(packet)
+		if !ok {
+			continue
+		}
+		var direction string
+		switch {
+		case dstPort == portC2D:
+			direction = "C2D"
+		case dstPort == portD2C:
+			direction = "D2C"
+		default:
+			continue
+		}
+
+		fmt.Fprintf(w, "%v %v:%v > %v\n", ts.Format("15:04:05.000000"), direction, srcPort, dstPort)
+		if err := parrotbebop.WriteFrames(w, direction, payload); err != nil {
+			log.Printf("warning: packet at %v: %v\n", ts.Format("15:04:05.000000"), err)
+		}
+	}
+}
+
+// udpPayload will return the ports and the payload of an IPv4 UDP
+// packet, and false if the packet is not one.
+func udpPayload(packet []byte) (uint16, uint16, []byte, bool) {
+	if len(packet) < 20 || packet[0]>>4 != 4 || packet[9] != 17 {
+		return 0, 0, nil, false
+	}
+	ihl := int(packet[0]&0x0f) * 4
+	if len(packet) < ihl+udpHeader {
+		return 0, 0, nil, false
+	}
+
+	udp := packet[ihl:]
+	length := int(binary.BigEndian.Uint16(udp[4:]))
+	if length < udpHeader || length > len(udp) {
+		length = len(udp)
+	}
+
+	return binary.BigEndian.Uint16(udp), binary.BigEndian.Uint16(udp[2:]), udp[udpHeader:length], true
+}
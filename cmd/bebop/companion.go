@@ -0,0 +1,12 @@
+// +build companion
+
+package main
+
+// The companion build runs in companion mode by default, so no flags
+// are needed on the companion computer. Build it for a Raspberry Pi
+// with:
+//
+//   GOOS=linux GOARCH=arm GOARM=7 go build -tags companion ./cmd/bebop
+func init() {
+	companionDefault = true
+}
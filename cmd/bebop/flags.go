@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// envPrefix is put before the name of a flag to get the environment
+// variable giving it's default, like BEBOP_ADDRESS for -address.
+const envPrefix = "BEBOP_"
+
+// envName will return the name of the environment variable of the flag.
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+// setFlagsFromEnv will set the flags from the environment variables,
+// so the flags given on the command line are used before the
+// environment when parsed after.
+func setFlagsFromEnv(fs *flag.FlagSet) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		v, ok := os.LookupEnv(envName(f.Name))
+		if !ok || err != nil {
+			return
+		}
+		if e := f.Value.Set(v); e != nil {
+			err = fmt.Errorf("invalid value %q for %v: %v", v, envName(f.Name), e)
+		}
+	})
+
+	return err
+}
+
+// usage will write the flags with their environment variables.
+func usage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(fs.Output(), "Usage of %v:\n", os.Args[0])
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\nEach flag can also be given with an environment variable, like %v for -address.\n", envName("address"))
+	}
+}
+
+// logLevels are the log levels in order, where debug also turns on the
+// raw printing of the packets and commands.
+var logLevels = []string{"debug", "info", "warning", "error"}
+
+// levelWriter will only pass on the log lines at the level given or
+// above, where the level is given after the time as "info:" and the
+// like. The lines without a level are debug.
+type levelWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	min     int
+	partial []byte
+}
+
+// newLevelWriter will return a writer passing on the lines at level or
+// above to w.
+func newLevelWriter(w io.Writer, level string) (*levelWriter, error) {
+	for i, l := range logLevels {
+		if l == level {
+			return &levelWriter{w: w, min: i}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown log level %q, known levels are %v", level, logLevels)
+}
+
+// Write will pass on the complete lines at the level or above.
+func (lw *levelWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	lw.partial = append(lw.partial, p...)
+	for {
+		i := bytes.IndexByte(lw.partial, '\n')
+		if i == -1 {
+			break
+		}
+		line := lw.partial[:i+1]
+		if lw.level(string(line)) >= lw.min {
+			if _, err := lw.w.Write(line); err != nil {
+				return 0, err
+			}
+		}
+		lw.partial = lw.partial[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// level will return the level of the log line.
+func (lw *levelWriter) level(line string) int {
+	for i, l := range logLevels {
+		if strings.Contains(line, " "+l+":") || strings.HasPrefix(line, l+":") {
+			return i
+		}
+	}
+
+	return 0
+}
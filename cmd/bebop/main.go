@@ -1,9 +1,207 @@
 package main
 
-import "github.com/postmannen/parrotbebop"
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/postmannen/parrotbebop"
+)
+
+// companionDefault is the default of the -companion flag, which is
+// true when built with the companion build tag.
+var companionDefault bool
 
 func main() {
+	headless := flag.Bool("headless", false, "run without keyboard control")
+	dashboard := flag.Bool("dashboard", false, "show a dashboard with the live telemetry instead of the raw debug output")
+	script := flag.String("script", "", "mission script to run when connected to the drone")
+	mission := flag.String("mission", "", "YAML mission plan to run when connected to the drone")
+	notify := flag.String("notify", "", "notify about critical events, with bell for the terminal bell or desktop for desktop notifications")
+	summary := flag.String("summary", "", "directory to write a summary of each flight to")
+	configFile := flag.String("config", "", "YAML config file with the key bindings, failsafe thresholds, scheduler rates and geofence, applied again when changed")
+	eventJSON := flag.String("json", "", "file to write every command received from the drone to as JSON lines, where - is stdout")
+	profile := flag.String("profile", "bebop", "connection profile to use, bebop for a real drone or sphinx for the Parrot Sphinx simulator")
+	address := flag.String("address", "", "IP address of the drone, instead of the one of the profile")
+	discoveryPort := flag.String("discovery-port", "", "TCP port of the discovery on the drone, instead of the one of the profile")
+	d2cPort := flag.String("d2c-port", "", "UDP port to listen on for the frames from the drone, defaults to 43210")
+	logLevel := flag.String("log-level", "debug", "lowest level to log, debug, info, warning or error, where debug also prints the raw packets")
+	record := flag.Bool("record", false, "record video on the drone when connected")
+	autorecord := flag.Bool("autorecord", false, "make the drone record video to the internal storage by itself on each takeoff")
+	daemon := flag.Bool("daemon", false, "run as a service without keyboard, controlled with the REST API of the ground station, and landing the drone when stopped with SIGTERM")
+	listen := flag.String("listen", "127.0.0.1:8080", "address of the ground station with the REST API and the /healthz and /readyz endpoints in daemon mode, which is only reachable from this machine by default")
+	token := flag.String("token", "", "token other clients than the ground station page must give in the X-Bebop-Token header of the ground station API requests changing the state of the drone, where only the ground station page is allowed if not given")
+	shutdown := flag.String("shutdown", "land", "what to do with a flying drone when the daemon is stopped, land or home")
+	companion := flag.Bool("companion", companionDefault, "run on a companion computer like a Raspberry Pi, as a daemon with low memory use and reconnecting forever")
+	mqttBroker := flag.String("mqtt", "", "host:port of an MQTT broker to publish the telemetry and events to, and take actions from when -mqtt-token is given")
+	mqttPrefix := flag.String("mqtt-prefix", "bebop", "prefix of the MQTT topics")
+	mqttToken := flag.String("mqtt-token", "", "token to give before the action in the messages on the MQTT action topic, like \"<token> land\", where the actions are not taken from MQTT if not given")
+	shutdownTimeout := flag.Duration("shutdown-timeout", time.Minute*5, "how long to wait for the drone to land when the daemon is stopped")
+	flag.CommandLine.Usage = usage(flag.CommandLine)
+	if err := setFlagsFromEnv(flag.CommandLine); err != nil {
+		log.Fatalf("error: %v\n", err)
+	}
+	flag.Parse()
+
+	lw, err := newLevelWriter(os.Stderr, *logLevel)
+	if err != nil {
+		log.Fatalf("error: %v\n", err)
+	}
+	log.SetOutput(lw)
+
 	drone := parrotbebop.NewDrone()
+	drone.SetVerbose(*logLevel == "debug")
+	p, err := parrotbebop.ProfileByName(*profile)
+	if err != nil {
+		log.Fatalf("error: %v\n", err)
+	}
+	if *address != "" {
+		p.Address = *address
+	}
+	if *discoveryPort != "" {
+		p.DiscoveryPort = *discoveryPort
+	}
+	p.D2CPort = *d2cPort
+	if err := drone.SetProfile(p); err != nil {
+		log.Fatalf("error: %v\n", err)
+	}
+	drone.SetHeadless(*headless || *daemon)
+
+	if *companion {
+		*daemon = true
+		if err := drone.SetCompanionMode(); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+	}
+
+	if *mqttBroker != "" {
+		go func() {
+			if err := drone.RunMQTT(context.Background(), parrotbebop.MQTTConfig{Broker: *mqttBroker, Prefix: *mqttPrefix, ActionToken: *mqttToken}); err != nil {
+				log.Fatalf("error: %v\n", err)
+			}
+		}()
+	}
+
+	if *daemon {
+		action, err := shutdownAction(*shutdown)
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+		drone.SetGroundStationToken(*token)
+		if err := drone.StartGroundStation(context.Background(), *listen, nil); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+		go handleSignals(drone, action, *shutdownTimeout)
+	}
+
+	if *record || *autorecord {
+		go func() {
+			for !drone.Ready() {
+				time.Sleep(time.Millisecond * 500)
+			}
+			if *autorecord {
+				if err := drone.SetVideoAutorecord(true); err != nil {
+					log.Printf("error: failed to enable video autorecord: %v\n", err)
+				}
+			}
+			if *record {
+				if err := drone.SetVideoRecording(true); err != nil {
+					log.Printf("error: failed to start video recording: %v\n", err)
+				}
+			}
+		}()
+	}
+
+	if *dashboard {
+		go drone.RunDashboard(context.Background(), os.Stdout)
+	}
+
+	if *summary != "" {
+		if err := drone.SetFlightSummaryDir(*summary); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+	}
+
+	if *configFile != "" {
+		go func() {
+			if err := drone.WatchConfig(context.Background(), *configFile); err != nil {
+				log.Fatalf("error: %v\n", err)
+			}
+		}()
+	}
+
+	switch *eventJSON {
+	case "":
+	case "-":
+		// The raw debug output is also written to stdout.
+		drone.SetVerbose(false)
+		drone.SetEventJSON(os.Stdout)
+	default:
+		f, err := os.Create(*eventJSON)
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+		defer f.Close()
+		drone.SetEventJSON(f)
+	}
+
+	switch *notify {
+	case "":
+	case "bell":
+		go drone.RunNotifier(context.Background(), parrotbebop.BellNotifier{})
+	case "desktop":
+		go drone.RunNotifier(context.Background(), parrotbebop.DesktopNotifier{})
+	default:
+		log.Fatalf("error: unknown notifier: %v\n", *notify)
+	}
+
+	if *script != "" {
+		f, err := os.Open(*script)
+		if err != nil {
+			log.Fatalf("error: failed to open script: %v\n", err)
+		}
+		s, err := parrotbebop.ParseScript(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+
+		go func() {
+			for !drone.Ready() {
+				time.Sleep(time.Millisecond * 500)
+			}
+			if err := drone.RunScript(context.Background(), s); err != nil {
+				log.Printf("error: script failed: %v\n", err)
+				return
+			}
+			log.Printf("info: script done\n")
+		}()
+	}
+
+	if *mission != "" {
+		f, err := os.Open(*mission)
+		if err != nil {
+			log.Fatalf("error: failed to open mission: %v\n", err)
+		}
+		plan, err := parrotbebop.LoadMissionPlan(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+
+		go func() {
+			for !drone.Ready() {
+				time.Sleep(time.Millisecond * 500)
+			}
+			if err := drone.RunMissionPlan(context.Background(), plan); err != nil {
+				log.Printf("error: mission failed: %v\n", err)
+			}
+		}()
+	}
 
-	drone.Start()
+	if err := drone.Start(); err != nil {
+		log.Fatalf("error: %v\n", err)
+	}
 }
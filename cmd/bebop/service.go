@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/postmannen/parrotbebop"
+)
+
+// shutdownAction will return the shutdown action with the name given.
+func shutdownAction(name string) (parrotbebop.ShutdownAction, error) {
+	for _, a := range []parrotbebop.ShutdownAction{parrotbebop.ShutdownLand, parrotbebop.ShutdownReturnHome} {
+		if a.String() == name {
+			return a, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown shutdown action %q, use land or home", name)
+}
+
+// handleSignals will bring the drone to the ground with the action
+// given when asked to stop with SIGTERM or an interrupt, and then exit.
+func handleSignals(drone *parrotbebop.Drone, action parrotbebop.ShutdownAction, timeout time.Duration) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGTERM, os.Interrupt)
+
+	sig := <-ch
+	log.Printf("info: got %v, shutting down with %v\n", sig, action)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := drone.Shutdown(ctx, action); err != nil {
+		log.Printf("error: %v\n", err)
+		os.Exit(1)
+	}
+
+	os.Exit(0)
+}
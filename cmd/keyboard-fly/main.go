@@ -0,0 +1,88 @@
+// keyboard-fly is an example of flying the drone with the keyboard,
+// where the keys are read by the program itself, and given to the
+// drone with SendAction.
+//
+// The keys are :
+//
+//	t takeoff, l land, h hover, E emergency, T flat trim
+//	arrows for pitch and roll, w/s for up and down, a/d for yaw
+//	Esc to land and quit
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/eiannone/keyboard"
+	"github.com/postmannen/parrotbebop"
+)
+
+// runeActions and keyActions are the actions for the keys.
+var (
+	runeActions = map[rune]parrotbebop.InputAction{
+		't': parrotbebop.ActionTakeoff,
+		'l': parrotbebop.ActionLanding,
+		'h': parrotbebop.ActionPcmdHover,
+		'E': parrotbebop.ActionEmergency,
+		'T': parrotbebop.ActionFlatTrim,
+		'w': parrotbebop.ActionPcmdGazInc,
+		's': parrotbebop.ActionPcmdGazDec,
+		'a': parrotbebop.ActionPcmdYawCounterClockwise,
+		'd': parrotbebop.ActionPcmdYawClockwise,
+	}
+	keyActions = map[keyboard.Key]parrotbebop.InputAction{
+		keyboard.KeyArrowUp:    parrotbebop.ActionPcmdPitchForward,
+		keyboard.KeyArrowDown:  parrotbebop.ActionPcmdPitchBackward,
+		keyboard.KeyArrowLeft:  parrotbebop.ActionPcmdRollLeft,
+		keyboard.KeyArrowRight: parrotbebop.ActionPcmdRollRight,
+	}
+)
+
+func main() {
+	address := flag.String("address", "192.168.42.1", "IP address of the drone")
+	flag.Parse()
+
+	drone := parrotbebop.NewDrone()
+	if err := drone.SetAddress(*address); err != nil {
+		log.Fatalf("error: %v\n", err)
+	}
+	// The keys are read here, and not by the driver.
+	drone.SetHeadless(true)
+
+	go func() {
+		if err := drone.Start(); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+	}()
+
+	keys, err := keyboard.GetKeys(10)
+	if err != nil {
+		log.Fatalf("error: failed to open keyboard: %v\n", err)
+	}
+	defer keyboard.Close()
+
+	for ev := range keys {
+		if ev.Err != nil {
+			log.Fatalf("error: keyboard: %v\n", ev.Err)
+		}
+
+		if ev.Key == keyboard.KeyEsc {
+			if err := drone.SendAction(parrotbebop.ActionLanding); err != nil {
+				log.Printf("error: %v\n", err)
+			}
+			return
+		}
+
+		action, ok := runeActions[ev.Rune]
+		if !ok {
+			action, ok = keyActions[ev.Key]
+		}
+		if !ok {
+			continue
+		}
+
+		if err := drone.SendAction(action); err != nil {
+			log.Printf("error: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// telemetry-dump is an example of reading the telemetry and the events
+// from the drone, where the telemetry is written to stdout as a JSON
+// object per line at the interval given, together with the events.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/postmannen/parrotbebop"
+)
+
+// record is a line written to stdout.
+type record struct {
+	Time      time.Time              `json:"time"`
+	Telemetry *parrotbebop.Telemetry `json:"telemetry,omitempty"`
+	Event     string                 `json:"event,omitempty"`
+	Value     interface{}            `json:"value,omitempty"`
+}
+
+func main() {
+	address := flag.String("address", "192.168.42.1", "IP address of the drone")
+	interval := flag.Duration("interval", time.Second, "how often to write the telemetry")
+	flag.Parse()
+
+	drone := parrotbebop.NewDrone()
+	if err := drone.SetAddress(*address); err != nil {
+		log.Fatalf("error: %v\n", err)
+	}
+	drone.SetHeadless(true)
+
+	go func() {
+		if err := drone.Start(); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+	}()
+
+	// The high rate events are limited to the same interval as the
+	// telemetry.
+	events, unsubscribe := drone.SubscribeThrottled(*interval)
+	defer unsubscribe()
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	enc := json.NewEncoder(os.Stdout)
+	for {
+		var r record
+		select {
+		case now := <-ticker.C:
+			t := drone.Telemetry()
+			r = record{Time: now, Telemetry: &t}
+		case ev := <-events:
+			r = record{Time: ev.Time, Event: ev.Type.String(), Value: ev.Value}
+		}
+
+		if err := enc.Encode(r); err != nil {
+			log.Printf("error: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// video-record is an example of recording the video stream from the
+// drone to a raw H264 file, which can be played with for example
+// ffplay, or put into a container with ffmpeg -i video.h264 -c copy
+// video.mp4.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/postmannen/parrotbebop"
+)
+
+func main() {
+	address := flag.String("address", "192.168.42.1", "IP address of the drone")
+	out := flag.String("out", "video.h264", "file to write the H264 stream to")
+	duration := flag.Duration("duration", 0, "how long to record, or until interrupted if 0")
+	flag.Parse()
+
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("error: %v\n", err)
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	defer w.Flush()
+
+	drone := parrotbebop.NewDrone()
+	if err := drone.SetAddress(*address); err != nil {
+		log.Fatalf("error: %v\n", err)
+	}
+	drone.SetHeadless(true)
+
+	// The frames are written from the go routine receiving the video,
+	// and the first frame written is a key frame, so the file can be
+	// decoded from the start.
+	var (
+		mu      sync.Mutex
+		started bool
+		frames  int
+	)
+	unregister := drone.OnVideoFrame(func(frame parrotbebop.H264Frame) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !started && !frame.KeyFrame {
+			return
+		}
+		started = true
+		frames++
+
+		if _, err := w.Write(frame.Data); err != nil {
+			log.Printf("error: failed to write frame: %v\n", err)
+		}
+	})
+
+	if err := drone.EnableVideoStream(true); err != nil {
+		log.Fatalf("error: %v\n", err)
+	}
+
+	go func() {
+		if err := drone.Start(); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	if *duration > 0 {
+		select {
+		case <-stop:
+		case <-time.After(*duration):
+		}
+	} else {
+		<-stop
+	}
+
+	unregister()
+
+	mu.Lock()
+	defer mu.Unlock()
+	log.Printf("info: wrote %v frames to %v\n", frames, *out)
+}
@@ -0,0 +1,102 @@
+// waypoint-mission is an example of flying a list of waypoints, where
+// the drone takes off, flies the waypoints with the moveTo executor,
+// and lands when the mission is done.
+//
+// The waypoints are given as lat,lon,alt separated by spaces, like :
+//
+//	waypoint-mission 59.9131,10.7519,10 59.9135,10.7525,15
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/postmannen/parrotbebop"
+)
+
+func main() {
+	address := flag.String("address", "192.168.42.1", "IP address of the drone")
+	flag.Parse()
+
+	var waypoints []parrotbebop.Position
+	for _, arg := range flag.Args() {
+		var p parrotbebop.Position
+		if _, err := fmt.Sscanf(arg, "%f,%f,%f", &p.Latitude, &p.Longitude, &p.Altitude); err != nil {
+			log.Fatalf("error: waypoint %q is not lat,lon,alt: %v\n", arg, err)
+		}
+		waypoints = append(waypoints, p)
+	}
+	if len(waypoints) == 0 {
+		log.Fatalf("error: no waypoints given\n")
+	}
+
+	drone := parrotbebop.NewDrone()
+	if err := drone.SetAddress(*address); err != nil {
+		log.Fatalf("error: %v\n", err)
+	}
+	drone.SetHeadless(true)
+
+	go func() {
+		if err := drone.Start(); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+	}()
+
+	for !drone.Ready() {
+		time.Sleep(time.Millisecond * 500)
+	}
+
+	report := drone.Preflight()
+	if !report.Passed {
+		log.Fatalf("error: preflight failed: %+v\n", report.Failed())
+	}
+
+	events, unsubscribe := drone.Subscribe()
+	defer unsubscribe()
+
+	drone.ClearWaypoints()
+	for _, p := range waypoints {
+		if err := drone.InsertWaypoint(-1, p); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+	}
+
+	if err := drone.SendAction(parrotbebop.ActionTakeoff); err != nil {
+		log.Fatalf("error: %v\n", err)
+	}
+	waitFlyingState(events, parrotbebop.FlyingStateHovering)
+
+	if err := drone.SendAction(parrotbebop.ActionMoveToExecute); err != nil {
+		log.Fatalf("error: %v\n", err)
+	}
+
+	// Follow the mission until it stops, which is when all the
+	// waypoints have been flown, or it was cancelled.
+	for ev := range events {
+		if ev.Type != parrotbebop.EventMissionStatus {
+			continue
+		}
+		s := ev.Value.(parrotbebop.MissionStatus)
+		log.Printf("info: mission %.0f%% done, next waypoint %v of %v\n", s.Percent, s.NextWaypoint, s.Waypoints)
+		if !s.Active {
+			break
+		}
+	}
+
+	if err := drone.SendAction(parrotbebop.ActionLanding); err != nil {
+		log.Fatalf("error: %v\n", err)
+	}
+	waitFlyingState(events, parrotbebop.FlyingStateLanded)
+	log.Printf("info: mission done\n")
+}
+
+// waitFlyingState will wait until the drone reports the flying state.
+func waitFlyingState(events <-chan parrotbebop.Event, state parrotbebop.FlyingState) {
+	for ev := range events {
+		if ev.Type == parrotbebop.EventFlyingStateChanged && ev.Value.(parrotbebop.FlyingState) == state {
+			return
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package parrotbebop
+
+import (
+	"testing"
+)
+
+func TestStringArgumentRoundTrip(t *testing.T) {
+	arg := Ardrone3NetworkStateWifiScanListChangedArguments{
+		Ssid:    "BebopDrone-123",
+		Rssi:    -42,
+		Band:    1,
+		Channel: 36,
+	}
+
+	b := arg.Encode()
+	// The string is null terminated, and followed by 2+4+1 bytes.
+	if want := len(arg.Ssid) + 1 + 7; len(b) != want {
+		t.Fatalf("wrong length of encoded arguments: %v, want %v", len(b), want)
+	}
+
+	got := NetworkStateWifiScanListChanged.Decode(b).(Ardrone3NetworkStateWifiScanListChangedArguments)
+	if got != arg {
+		t.Fatalf("decoded arguments differ: %#v, want %#v", got, arg)
+	}
+}
+
+func TestGetLengthOfStringDataNoTerminator(t *testing.T) {
+	// A slice with capacity beyond the length should not be read
+	// outside of it's length.
+	b := make([]byte, 3, 10)
+	b[0], b[1], b[2] = 'a', 'b', 'c'
+
+	if _, err := getLengthOfStringData(b); err == nil {
+		t.Fatalf("expected error for string without terminator")
+	}
+}
+
+func TestAutonomousFlightStateDecode(t *testing.T) {
+	c := Command{
+		Project: ProjectArdrone3,
+		Class:   Ardrone3PilotingSettingsStateClassPilotingSettingsState,
+		Cmd:     autonomousFlightCmdMaxVerticalSpeed,
+	}
+
+	dec, ok := CommandMap[c]
+	if !ok {
+		t.Fatalf("autonomous flight state not registered in CommandMap")
+	}
+
+	b := autonomousFlightArguments{Value: 1.5}.Encode()
+	got := dec.Decode(b).(autonomousFlightStateChangedArguments)
+	if got.cmd != autonomousFlightCmdMaxVerticalSpeed || got.Value != 1.5 {
+		t.Fatalf("wrong decoded state: %#v", got)
+	}
+}
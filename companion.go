@@ -0,0 +1,32 @@
+package parrotbebop
+
+import "fmt"
+
+// companionFlightLogSamples is the max number of samples kept in the
+// flight log in companion mode, which is about 1 hour.
+const companionFlightLogSamples = 20000
+
+// SetCompanionMode will set up the driver for running unattended on a
+// small computer, like a Raspberry Pi carried by the operator, and
+// controlled from a remote ground station with the ground station API
+// or RunMQTT. The keyboard and the raw debug printing are turned off,
+// the driver will try to reconnect with the drone forever, and the
+// flight log keeps fewer samples to save memory. Must be called before
+// Start.
+func (d *Drone) SetCompanionMode() error {
+	d.SetHeadless(true)
+	d.SetVerbose(false)
+
+	p := DefaultReconnectPolicy
+	p.MaxAttempts = 0
+	p.OnFailure = ReconnectForever
+	if err := d.SetReconnectPolicy(p); err != nil {
+		return fmt.Errorf("SetCompanionMode: %v", err)
+	}
+
+	d.flightLog.mu.Lock()
+	defer d.flightLog.mu.Unlock()
+	d.flightLog.max = companionFlightLogSamples
+
+	return nil
+}
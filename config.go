@@ -0,0 +1,405 @@
+package parrotbebop
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// configPollInterval is how often the config file is checked for
+// changes by WatchConfig.
+const configPollInterval = time.Second
+
+// configSettings are the settings that can be given in the config file,
+// named by the section and the key, with the function applying the
+// value.
+var configSettings = map[string]func(d *Drone, value string) error{
+	"failsafe.battery_guard": func(d *Drone, value string) error {
+		g := d.batteryGuard.get()
+		for m := BatteryGuardOff; m <= BatteryGuardRefuse; m++ {
+			if m.String() == value {
+				g.Mode = m
+				return d.SetBatteryGuard(g)
+			}
+		}
+		return fmt.Errorf("unknown battery guard mode: %v", value)
+	},
+	"failsafe.battery_reserve": func(d *Drone, value string) error {
+		v, err := strconv.ParseUint(value, 10, 8)
+		if err != nil {
+			return err
+		}
+		g := d.batteryGuard.get()
+		g.Reserve = uint8(v)
+		return d.SetBatteryGuard(g)
+	},
+	"failsafe.battery_speed": func(d *Drone, value string) error {
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		g := d.batteryGuard.get()
+		g.Speed = v
+		return d.SetBatteryGuard(g)
+	},
+	"failsafe.min_satellites": func(d *Drone, value string) error {
+		v, err := strconv.ParseUint(value, 10, 8)
+		if err != nil {
+			return err
+		}
+		d.SetGPSGuard(v > 0, uint8(v))
+		return nil
+	},
+	"failsafe.pause_on_critical": func(d *Drone, value string) error {
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		d.SetPauseOnCritical(v)
+		return nil
+	},
+	"scheduler.pcmd_interval": func(d *Drone, value string) error {
+		v, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		return d.SetPcmdInterval(v)
+	},
+	"scheduler.keepalive_interval": func(d *Drone, value string) error {
+		v, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		return d.SetKeepAliveInterval(v)
+	},
+	"scheduler.pcmd_rate": func(d *Drone, value string) error {
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		s := d.trafficShaping.get()
+		s.PCMDRate = v
+		return d.SetTrafficShaping(s)
+	},
+	"scheduler.camera_rate": func(d *Drone, value string) error {
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		s := d.trafficShaping.get()
+		s.CameraRate = v
+		return d.SetTrafficShaping(s)
+	},
+	"scheduler.event_burst": func(d *Drone, value string) error {
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		s := d.trafficShaping.get()
+		s.EventBurst = v
+		return d.SetTrafficShaping(s)
+	},
+	"scheduler.event_burst_gap": func(d *Drone, value string) error {
+		v, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		s := d.trafficShaping.get()
+		s.EventBurstGap = v
+		return d.SetTrafficShaping(s)
+	},
+	"scheduler.pack_frames": func(d *Drone, value string) error {
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		s := d.trafficShaping.get()
+		s.PackFrames = v
+		return d.SetTrafficShaping(s)
+	},
+	"scheduler.pack_delay": func(d *Drone, value string) error {
+		v, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		s := d.trafficShaping.get()
+		s.PackDelay = v
+		return d.SetTrafficShaping(s)
+	},
+	"geofence.max_altitude": func(d *Drone, value string) error {
+		v, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return err
+		}
+		return d.SetMaxAltitude(float32(v))
+	},
+	"geofence.altitude_floor": func(d *Drone, value string) error {
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		l := d.altitudeLimits.get()
+		l.Enabled = true
+		l.Floor = v
+		return d.SetAltitudeLimits(l)
+	},
+	"geofence.altitude_ceiling": func(d *Drone, value string) error {
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		l := d.altitudeLimits.get()
+		l.Enabled = true
+		l.Ceiling = v
+		return d.SetAltitudeLimits(l)
+	},
+}
+
+// config is the content of a config file.
+type config struct {
+	// settings are the values of the settings given, named as in
+	// configSettings.
+	settings map[string]string
+	// keys are the key bindings, or nil if not given.
+	keys map[string]string
+}
+
+// parseConfig will parse the config file, and check the names of the
+// settings and the key bindings. The config file is YAML with the
+// sections keys, failsafe, scheduler and geofence, like:
+//
+//   keys:
+//     t: takeoff
+//     up: forward
+//   failsafe:
+//     battery_guard: refuse
+//     battery_reserve: 20
+//   scheduler:
+//     pcmd_rate: 20
+func parseConfig(data string) (config, error) {
+	c := config{settings: map[string]string{}}
+	if strings.TrimSpace(data) == "" {
+		return c, nil
+	}
+
+	v, err := parseYAML(data)
+	if err != nil {
+		return c, err
+	}
+	doc, ok := v.(map[string]interface{})
+	if !ok {
+		return c, fmt.Errorf("the config must be a mapping of sections")
+	}
+
+	for section, v := range doc {
+		values, ok := v.(map[string]interface{})
+		if !ok {
+			return c, fmt.Errorf("section %v must be a mapping", section)
+		}
+
+		if section == "keys" {
+			c.keys = map[string]string{}
+			for key, action := range values {
+				s, ok := action.(string)
+				if !ok {
+					return c, fmt.Errorf("the action of key %v must be a name", key)
+				}
+				c.keys[key] = s
+			}
+			if _, err := parseKeyBindings(c.keys); err != nil {
+				return c, err
+			}
+			continue
+		}
+
+		for key, value := range values {
+			name := section + "." + key
+			if _, ok := configSettings[name]; !ok {
+				return c, fmt.Errorf("unknown setting: %v", name)
+			}
+			s, ok := value.(string)
+			if !ok {
+				return c, fmt.Errorf("setting %v must be a single value", name)
+			}
+			c.settings[name] = s
+		}
+	}
+
+	return c, nil
+}
+
+// ConfigChange is what changed when the config file was applied.
+type ConfigChange struct {
+	Path string
+	// Changed are the settings applied, like "scheduler.pcmd_rate=20",
+	// where the key bindings are given as "keys".
+	Changed []string
+	// Err is the error if the config file could not be read, in which
+	// case the settings before are kept, or the settings that could
+	// not be applied.
+	Err error
+}
+
+// configWatch holds the settings applied from the config file.
+type configWatch struct {
+	mu      sync.Mutex
+	applied map[string]string
+	keys    map[string]string
+}
+
+// applyConfig will apply the settings that have changed since the
+// config was last applied, and return the settings changed. A setting
+// that could not be applied is kept pending, and is tried again the
+// next time it is applied.
+// A setting removed from the config keeps it's value.
+func (d *Drone) applyConfig(c config) ([]string, error) {
+	w := &d.configWatch
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.applied == nil {
+		w.applied = map[string]string{}
+	}
+
+	var changed []string
+	var failed []string
+
+	if !reflect.DeepEqual(c.keys, w.keys) {
+		if err := d.SetKeyBindings(c.keys); err != nil {
+			failed = append(failed, err.Error())
+		} else {
+			w.keys = c.keys
+			changed = append(changed, "keys")
+		}
+	}
+
+	names := make([]string, 0, len(c.settings))
+	for name := range c.settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := c.settings[name]
+		if old, ok := w.applied[name]; ok && old == value {
+			continue
+		}
+		if err := configSettings[name](d, value); err != nil {
+			failed = append(failed, fmt.Sprintf("%v: %v", name, err))
+			continue
+		}
+		w.applied[name] = value
+		changed = append(changed, name+"="+value)
+	}
+
+	if len(failed) > 0 {
+		return changed, fmt.Errorf("failed to apply %v", strings.Join(failed, ", "))
+	}
+
+	return changed, nil
+}
+
+// WatchConfig will apply the config file given, with the key bindings,
+// the failsafe thresholds, the rates of the schedulers and the
+// geofence, and apply it again each time it is changed until the
+// context is done, so the controller don't have to be restarted. Each
+// change is published with EventConfigChanged. A config file that
+// can't be read when changed is ignored, and the settings before are
+// kept, but an error is returned if the first read fails. Settings
+// that failed to apply, like the max altitude sent to the drone when
+// disconnected, are tried again when the connection is ready.
+func (d *Drone) WatchConfig(ctx context.Context, path string) error {
+	read := func() (config, os.FileInfo, error) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return config{}, nil, err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return config{}, nil, err
+		}
+		c, err := parseConfig(string(data))
+		return c, info, err
+	}
+
+	// pending is true when some of the settings failed to apply.
+	var pending bool
+
+	apply := func(c config, always bool) {
+		changed, err := d.applyConfig(c)
+		pending = err != nil
+		if len(changed) == 0 && (err == nil || !always) {
+			return
+		}
+		if len(changed) > 0 {
+			log.Printf("info: config %v applied: %v\n", path, strings.Join(changed, ", "))
+		}
+		if err != nil {
+			log.Printf("warning: config %v: %v\n", path, err)
+		}
+		d.events.publish(EventConfigChanged, ConfigChange{Path: path, Changed: changed, Err: err})
+	}
+
+	c, info, err := read()
+	if err != nil {
+		return fmt.Errorf("WatchConfig: %v", err)
+	}
+
+	// Subscribe before applying, so we don't miss the connection
+	// getting ready.
+	events, unsubscribe := d.events.subscribe()
+	defer unsubscribe()
+
+	apply(c, true)
+
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	// lastErr is the last error reading the config, so it is only
+	// reported once.
+	var lastErr string
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev := <-events:
+			// Try again the settings that failed when the
+			// connection is ready.
+			if ready, ok := ev.Value.(bool); ev.Type == EventConnectionReady && ok && ready && pending {
+				apply(c, false)
+			}
+			continue
+		case <-ticker.C:
+		}
+
+		i, err := os.Stat(path)
+		if err == nil && i.ModTime().Equal(info.ModTime()) && i.Size() == info.Size() {
+			continue
+		}
+
+		next, nextInfo, err := read()
+		if nextInfo != nil {
+			info = nextInfo
+		}
+		if err != nil {
+			if err.Error() != lastErr {
+				log.Printf("warning: config %v not applied, keeping the settings before: %v\n", path, err)
+				d.events.publish(EventConfigChanged, ConfigChange{Path: path, Err: err})
+			}
+			lastErr = err.Error()
+			continue
+		}
+		lastErr = ""
+		c = next
+		apply(c, true)
+	}
+}
@@ -0,0 +1,195 @@
+package parrotbebop
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eiannone/keyboard"
+)
+
+func TestParseConfig(t *testing.T) {
+	bad := []string{
+		"failsafe:\n  unknown: 1\n",
+		"keys:\n  t: unknown\n",
+		"keys:\n  q: takeoff\n",
+		"keys:\n  ctrl+z: takeoff\n",
+		"scheduler: 1\n",
+	}
+	for _, b := range bad {
+		if _, err := parseConfig(b); err == nil {
+			t.Fatalf("expected error for %q", b)
+		}
+	}
+
+	c, err := parseConfig("keys:\n  x: takeoff\n  up: land\nfailsafe:\n  battery_reserve: 20\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.keys["x"] != "takeoff" || c.keys["up"] != "land" || c.settings["failsafe.battery_reserve"] != "20" {
+		t.Fatalf("got %+v", c)
+	}
+}
+
+func TestKeyBindings(t *testing.T) {
+	d := NewDrone()
+
+	if a, ok := d.keyBindings.action(keyboard.KeyEvent{Rune: 't'}); !ok || a != ActionTakeoff {
+		t.Fatalf("got default action %v, %v, want takeoff", a, ok)
+	}
+	if _, ok := d.keyBindings.action(keyboard.KeyEvent{Rune: 'x'}); ok {
+		t.Fatalf("expected no action for an unbound key")
+	}
+
+	if err := d.SetKeyBindings(map[string]string{"x": "takeoff", "up": "land"}); err != nil {
+		t.Fatal(err)
+	}
+	if a, ok := d.keyBindings.action(keyboard.KeyEvent{Key: keyboard.KeyArrowUp}); !ok || a != ActionLanding {
+		t.Fatalf("got %v, %v, want land", a, ok)
+	}
+	if _, ok := d.keyBindings.action(keyboard.KeyEvent{Rune: 't'}); ok {
+		t.Fatalf("expected no action for a key not bound")
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "bebop.yaml")
+
+	d := NewDrone()
+	if err := d.WatchConfig(context.Background(), path); err == nil {
+		t.Fatalf("expected error for a missing config")
+	}
+
+	write := func(s string, mod time.Time) {
+		if err := ioutil.WriteFile(path, []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mod := time.Now().Add(-time.Minute)
+	write("failsafe:\n  battery_guard: refuse\n  battery_reserve: 20\n", mod)
+
+	events, unsubscribe := d.Subscribe()
+	defer unsubscribe()
+	next := func() ConfigChange {
+		for {
+			select {
+			case ev := <-events:
+				if ev.Type == EventConfigChanged {
+					return ev.Value.(ConfigChange)
+				}
+			case <-time.After(configPollInterval * 5):
+				t.Fatalf("timed out waiting for the config change")
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.WatchConfig(ctx, path)
+
+	change := next()
+	if change.Err != nil || len(change.Changed) != 2 {
+		t.Fatalf("got %+v", change)
+	}
+	if g := d.batteryGuard.get(); g.Mode != BatteryGuardRefuse || g.Reserve != 20 {
+		t.Fatalf("got battery guard %+v", g)
+	}
+
+	// A bad config is not applied.
+	write("failsafe:\n  battery_reserve: 200\n  unknown: 1\n", mod.Add(time.Second))
+	if change := next(); change.Err == nil {
+		t.Fatalf("expected error, got %+v", change)
+	}
+
+	// Only the changed settings are applied.
+	write("failsafe:\n  battery_guard: refuse\n  battery_reserve: 30\nscheduler:\n  pcmd_rate: 20\n", mod.Add(time.Second*2))
+	change = next()
+	if change.Err != nil || len(change.Changed) != 2 || change.Changed[0] != "failsafe.battery_reserve=30" || change.Changed[1] != "scheduler.pcmd_rate=20" {
+		t.Fatalf("got %+v", change)
+	}
+	if s := d.trafficShaping.get(); s.PCMDRate != 20 {
+		t.Fatalf("got traffic shaping %+v", s)
+	}
+}
+
+func TestWatchConfigRetryWhenReady(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "bebop.yaml")
+	if err := ioutil.WriteFile(path, []byte("test:\n  retry: 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A setting failing until the drone is ready, counting the tries.
+	var mu sync.Mutex
+	var tries int
+	configSettings["test.retry"] = func(d *Drone, value string) error {
+		mu.Lock()
+		tries++
+		mu.Unlock()
+		if !d.Ready() {
+			return fmt.Errorf("not connected")
+		}
+		return nil
+	}
+	defer delete(configSettings, "test.retry")
+	triesNow := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return tries
+	}
+
+	d := NewDrone()
+	events, unsubscribe := d.Subscribe()
+	defer unsubscribe()
+	next := func() ConfigChange {
+		for {
+			select {
+			case ev := <-events:
+				if ev.Type == EventConfigChanged {
+					return ev.Value.(ConfigChange)
+				}
+			case <-time.After(configPollInterval * 5):
+				t.Fatalf("timed out waiting for the config change")
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.WatchConfig(ctx, path)
+
+	if change := next(); change.Err == nil {
+		t.Fatalf("expected error, got %+v", change)
+	}
+
+	// The failed setting is not tried again on each poll.
+	time.Sleep(configPollInterval * 2)
+	if n := triesNow(); n != 1 {
+		t.Fatalf("got %v tries before ready, want 1", n)
+	}
+
+	d.setReady(true)
+	if change := next(); change.Err != nil || len(change.Changed) != 1 || change.Changed[0] != "test.retry=1" {
+		t.Fatalf("got %+v", change)
+	}
+	if n := triesNow(); n != 2 {
+		t.Fatalf("got %v tries, want 2", n)
+	}
+}
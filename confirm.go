@@ -0,0 +1,134 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultConfirmTimeout is the time given to confirm an action if
+	// not set with SetConfirmMode.
+	defaultConfirmTimeout = time.Second * 3
+	// maxConfirmTimeout is the longest confirm timeout allowed.
+	maxConfirmTimeout = time.Second * 30
+)
+
+// confirmGuard holds the action waiting for a confirmation when the
+// confirm mode is enabled. The confirm mode is disabled by default.
+type confirmGuard struct {
+	mu      sync.Mutex
+	enabled bool
+	timeout time.Duration
+	// pending is the action waiting to be confirmed, and is only valid
+	// until the deadline.
+	pending  InputAction
+	deadline time.Time
+	// flip is the direction of the pending flip.
+	flip FlipDirection
+}
+
+// needsConfirm will return true for the actions that can't be undone,
+// and should be confirmed when the confirm mode is enabled.
+func needsConfirm(action InputAction) bool {
+	switch action {
+	case ActionTakeoff, ActionUserTakeoff, ActionEmergency, ActionFlip:
+		return true
+	}
+
+	return false
+}
+
+// SetConfirmMode will enable or disable the confirm mode. When enabled
+// the takeoff, emergency and flip actions will not be executed before
+// the same action is given a second time, or confirmed with Confirm,
+// within the timeout. This protects against keys pressed by accident
+// while flying with the keyboard. If timeout is 0 the default timeout
+// of 3 seconds is used.
+func (d *Drone) SetConfirmMode(enabled bool, timeout time.Duration) error {
+	if timeout < 0 || timeout > maxConfirmTimeout {
+		return fmt.Errorf("SetConfirmMode: timeout must be within [0, %v], got %v", maxConfirmTimeout, timeout)
+	}
+	if timeout == 0 {
+		timeout = defaultConfirmTimeout
+	}
+
+	d.confirmGuard.mu.Lock()
+	defer d.confirmGuard.mu.Unlock()
+
+	d.confirmGuard.enabled = enabled
+	d.confirmGuard.timeout = timeout
+	d.confirmGuard.deadline = time.Time{}
+
+	return nil
+}
+
+// Confirm will confirm the action waiting for a confirmation, so it is
+// executed. An error is returned if there is no action waiting, or the
+// timeout have passed.
+func (d *Drone) Confirm() error {
+	d.confirmGuard.mu.Lock()
+	action := d.confirmGuard.pending
+	flip := d.confirmGuard.flip
+	waiting := time.Now().Before(d.confirmGuard.deadline)
+	d.confirmGuard.mu.Unlock()
+
+	if !waiting {
+		return fmt.Errorf("Confirm: no action waiting for confirmation")
+	}
+
+	// A flip is given again with it's direction, since the flip action
+	// is always a front flip.
+	if action == ActionFlip {
+		if err := d.Flip(flip); err != nil {
+			return fmt.Errorf("Confirm: %v", err)
+		}
+		return nil
+	}
+
+	// The action is given again, which will be the confirmation.
+	select {
+	case d.chInputActions <- action:
+	default:
+		return fmt.Errorf("Confirm: not connected to drone")
+	}
+
+	return nil
+}
+
+// confirmAction will check if the action can be executed. With the
+// confirm mode enabled, an action needing a confirmation is only
+// allowed when it is the second time it is given within the timeout.
+func (d *Drone) confirmAction(action InputAction) bool {
+	return d.confirmFlip(action, FlipFront)
+}
+
+// confirmFlip will check if the action can be executed like
+// confirmAction, where a flip is only confirmed by a flip in the same
+// direction.
+func (d *Drone) confirmFlip(action InputAction, flip FlipDirection) bool {
+	if !needsConfirm(action) {
+		return true
+	}
+
+	d.confirmGuard.mu.Lock()
+	defer d.confirmGuard.mu.Unlock()
+
+	if !d.confirmGuard.enabled {
+		return true
+	}
+
+	now := time.Now()
+	if d.confirmGuard.pending == action && d.confirmGuard.flip == flip && now.Before(d.confirmGuard.deadline) {
+		d.confirmGuard.deadline = time.Time{}
+		return true
+	}
+
+	d.confirmGuard.pending = action
+	d.confirmGuard.flip = flip
+	d.confirmGuard.deadline = now.Add(d.confirmGuard.timeout)
+	log.Printf("info: action %v needs confirmation, give it again within %v to execute\n", action, d.confirmGuard.timeout)
+
+	return false
+}
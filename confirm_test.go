@@ -0,0 +1,66 @@
+package parrotbebop
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfirmAction(t *testing.T) {
+	d := NewDrone()
+
+	if !d.confirmAction(ActionTakeoff) {
+		t.Fatalf("takeoff should be allowed with confirm mode disabled")
+	}
+
+	if err := d.SetConfirmMode(true, time.Millisecond*50); err != nil {
+		t.Fatal(err)
+	}
+	if !d.confirmAction(ActionLanding) {
+		t.Fatalf("landing should not need confirmation")
+	}
+	if d.confirmAction(ActionTakeoff) {
+		t.Fatalf("first takeoff should need confirmation")
+	}
+	if d.confirmAction(ActionEmergency) {
+		t.Fatalf("another action should not confirm the takeoff")
+	}
+	if !d.confirmAction(ActionEmergency) {
+		t.Fatalf("second emergency should be confirmed")
+	}
+
+	if d.confirmAction(ActionTakeoff) {
+		t.Fatalf("first takeoff should need confirmation")
+	}
+	time.Sleep(time.Millisecond * 60)
+	if d.confirmAction(ActionTakeoff) {
+		t.Fatalf("takeoff should need a new confirmation after the timeout")
+	}
+}
+
+func TestConfirmFlip(t *testing.T) {
+	d := NewDrone()
+	go func() {
+		for range d.chSendingUDPPacket {
+		}
+	}()
+	if err := d.SetConfirmMode(true, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Flip(FlipBack); err == nil {
+		t.Fatalf("first flip should need confirmation")
+	}
+	if err := d.Flip(FlipLeft); err == nil {
+		t.Fatalf("a flip in another direction should not confirm the flip")
+	}
+	if err := d.Flip(FlipLeft); err != nil {
+		t.Fatalf("second left flip should be confirmed: %v", err)
+	}
+
+	if err := d.Flip(FlipRight); err == nil {
+		t.Fatalf("first flip should need confirmation")
+	}
+	if err := d.Confirm(); err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package parrotbebop
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// dashboardInterval is how often the dashboard is redrawn.
+	dashboardInterval = time.Millisecond * 250
+	// dashboardLogLines is the number of log lines shown.
+	dashboardLogLines = 6
+)
+
+// dashboardKeys is the key map shown in the dashboard.
+var dashboardKeys = []string{
+	"t takeoff   l land      r/R home start/stop   h hover   E emergency   F flip   T flat trim",
+	"w/s up/down a/d yaw     arrows pitch/roll     space repeat   I/K/J/L/U/N nudge   A absolute",
+	"ctrl+w/s/a/d move wp    ctrl+x wp here        ctrl+space execute   ctrl+q cancel",
+	"u hand launch, l to cancel   q reconnect esc quit",
+}
+
+// dashboard holds the state of the terminal dashboard. While the
+// dashboard is running the raw debug printing is turned off, and the
+// log output is shown at the bottom of the dashboard.
+type dashboard struct {
+	mu      sync.Mutex
+	running bool
+	// quiet is true when the raw debug printing is turned off with
+	// SetVerbose.
+	quiet bool
+	// logLines holds the latest log lines.
+	logLines []string
+	partial  []byte
+}
+
+// SetVerbose will turn on or off the raw printing of every packet and
+// command sent to and received from the drone. It is on by default.
+func (d *Drone) SetVerbose(verbose bool) {
+	d.dashboard.mu.Lock()
+	defer d.dashboard.mu.Unlock()
+
+	d.dashboard.quiet = !verbose
+}
+
+// debugf will print the raw debug output, unless turned off with
+// SetVerbose, or the dashboard is running.
+func (d *Drone) debugf(format string, a ...interface{}) {
+	d.dashboard.mu.Lock()
+	quiet := d.dashboard.quiet || d.dashboard.running
+	d.dashboard.mu.Unlock()
+
+	if quiet {
+		return
+	}
+
+	fmt.Printf(format, a...)
+}
+
+// Write will collect the log output while the dashboard is running.
+func (db *dashboard) Write(p []byte) (int, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.partial = append(db.partial, p...)
+	for {
+		i := bytes.IndexByte(db.partial, '\n')
+		if i == -1 {
+			break
+		}
+		line := strings.TrimRight(string(db.partial[:i]), "\r")
+		db.partial = db.partial[i+1:]
+
+		db.logLines = append(db.logLines, line)
+		if len(db.logLines) > dashboardLogLines {
+			db.logLines = db.logLines[len(db.logLines)-dashboardLogLines:]
+		}
+	}
+
+	return len(p), nil
+}
+
+// RunDashboard will draw a dashboard with the live telemetry of the
+// drone to w, which should be a terminal, updating it in place until
+// the context is done. The raw debug printing is turned off, and the
+// log output is shown in the dashboard while it is running.
+func (d *Drone) RunDashboard(ctx context.Context, w io.Writer) error {
+	d.dashboard.mu.Lock()
+	if d.dashboard.running {
+		d.dashboard.mu.Unlock()
+		return fmt.Errorf("RunDashboard: dashboard already running")
+	}
+	d.dashboard.running = true
+	d.dashboard.mu.Unlock()
+
+	log.SetOutput(&d.dashboard)
+
+	defer func() {
+		log.SetOutput(os.Stderr)
+		d.dashboard.mu.Lock()
+		d.dashboard.running = false
+		d.dashboard.mu.Unlock()
+	}()
+
+	ticker := time.NewTicker(dashboardInterval)
+	defer ticker.Stop()
+
+	for {
+		// Move the cursor to the top left, and clear the screen.
+		if _, err := io.WriteString(w, "\x1b[H\x1b[2J"+d.renderDashboard()); err != nil {
+			return fmt.Errorf("RunDashboard: failed to write: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// renderDashboard will create the text of the dashboard.
+func (d *Drone) renderDashboard() string {
+	t := d.Telemetry()
+	state, known := d.FlyingState()
+
+	var b strings.Builder
+	// Lines must end with \r\n since the keyboard puts the terminal
+	// into raw mode.
+	line := func(format string, a ...interface{}) {
+		fmt.Fprintf(&b, format+"\r\n", a...)
+	}
+
+	stateText := "unknown"
+	if known {
+		stateText = state.String()
+	}
+
+	line("BEBOP %v   state: %v   ready: %v   alert: %v", t.Info.Name, stateText, d.Ready(), t.AlertState)
+	line("")
+	line("battery   %3d%%", t.Battery)
+	line("altitude  %6.1f m", t.Altitude)
+	line("speed     ground %5.1f m/s  climb %5.1f m/s  course %5.1f°", t.GroundSpeed, t.ClimbRate, t.Velocity.Course())
+	line("attitude  roll %6.1f°  pitch %6.1f°  heading %5.1f°", radToDeg(t.Roll), radToDeg(t.Pitch), t.HeadingDegrees())
+	line("gps       fix: %v  satellites: %v  lat %.6f  lon %.6f  alt %.1f", t.GPSFixed, t.NumberOfSatellites, t.Position.Latitude, t.Position.Longitude, t.Position.Altitude)
+	if t.PositionConfidence == PositionConfidenceEstimated {
+		line("estimate  lat %.6f  lon %.6f  alt %.1f  (gps lost %v ago)", t.EstimatedPosition.Latitude, t.EstimatedPosition.Longitude, t.EstimatedPosition.Altitude, time.Since(t.LastGPSFix).Round(time.Second))
+	}
+	if m := d.MissionStatus(); m.Active {
+		eta := "unknown"
+		if m.ETAKnown {
+			eta = m.ETA.Round(time.Second).String()
+		}
+		line("mission   waypoint %v/%v  %.0f%%  next %.1f m  eta %v", m.NextWaypoint+1, m.Waypoints, m.Percent, t.WaypointDistance, eta)
+	}
+	line("wind      %v   vibration: %v", t.Wind, t.Vibration)
+
+	var sent, received, lost, dropped uint64
+	for _, s := range d.Stats() {
+		sent += s.FramesSent
+		received += s.FramesReceived
+		lost += s.Lost
+		dropped += s.Dropped
+	}
+	line("link      sent: %v  received: %v  lost: %v  dropped: %v", sent, received, lost, dropped)
+
+	line("")
+	for _, k := range dashboardKeys {
+		line("%v", k)
+	}
+
+	line("")
+	d.dashboard.mu.Lock()
+	for _, l := range d.dashboard.logLines {
+		line("%v", l)
+	}
+	d.dashboard.mu.Unlock()
+
+	return b.String()
+}
+
+// radToDeg will convert radians to degrees.
+func radToDeg(r float32) float64 {
+	return float64(r) * 180 / math.Pi
+}
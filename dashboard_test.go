@@ -0,0 +1,32 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDashboardLogLines(t *testing.T) {
+	var db dashboard
+	for i := 0; i < dashboardLogLines+2; i++ {
+		fmt.Fprintf(&db, "line %v\n", i)
+	}
+	fmt.Fprintf(&db, "partial")
+
+	if len(db.logLines) != dashboardLogLines {
+		t.Fatalf("got %v log lines, want %v", len(db.logLines), dashboardLogLines)
+	}
+	if db.logLines[0] != "line 2" {
+		t.Fatalf("oldest line kept is %q, want %q", db.logLines[0], "line 2")
+	}
+}
+
+func TestRenderDashboard(t *testing.T) {
+	d := NewDrone()
+	d.checkCmdFromDrone(protocolARCommands{}, CommonCommonStateBatteryStateChangedArguments{Percent: 77})
+
+	s := d.renderDashboard()
+	if !strings.Contains(s, "battery    77%") {
+		t.Fatalf("battery not shown in dashboard:\n%s", s)
+	}
+}
@@ -0,0 +1,29 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// dateFormat is the ISO-8601 date format used by the drone.
+	dateFormat = "2006-01-02"
+	// timeFormat is the ISO-8601 time format used by the drone.
+	timeFormat = "T150405-0700"
+)
+
+// syncDateTime will send the current date and time of the controller
+// to the drone, so the drone will timestamp media and logs correctly.
+func (d *Drone) syncDateTime(now time.Time) error {
+	err := d.sendCmd(Command(CommonCurrentDate), &CommonCommonCurrentDateArguments{Date: now.Format(dateFormat)})
+	if err != nil {
+		return fmt.Errorf("syncDateTime: failed to send date: %v", err)
+	}
+
+	err = d.sendCmd(Command(CommonCurrentTime), &CommonCommonCurrentTimeArguments{Time: now.Format(timeFormat)})
+	if err != nil {
+		return fmt.Errorf("syncDateTime: failed to send time: %v", err)
+	}
+
+	return nil
+}
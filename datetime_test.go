@@ -0,0 +1,23 @@
+package parrotbebop
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestDateTimeEncode(t *testing.T) {
+	now := time.Date(2020, 10, 17, 14, 5, 9, 0, time.FixedZone("", 2*3600))
+
+	got := CommonCommonCurrentDateArguments{Date: now.Format(dateFormat)}.Encode()
+	want := append([]byte("2020-10-17"), 0)
+	if !bytes.Equal(got, want) {
+		t.Fatalf("wrong date encoding: %q, want %q", got, want)
+	}
+
+	got = CommonCommonCurrentTimeArguments{Time: now.Format(timeFormat)}.Encode()
+	want = append([]byte("T140509+0200"), 0)
+	if !bytes.Equal(got, want) {
+		t.Fatalf("wrong time encoding: %q, want %q", got, want)
+	}
+}
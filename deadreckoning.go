@@ -0,0 +1,160 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// deadReckoningMaxAge is how long after the gps is lost the
+	// estimated position is trusted, since the error grows with time.
+	deadReckoningMaxAge = time.Minute * 2
+	// deadReckoningMaxStep is the longest time a single speed sample
+	// is used for, so a gap in the speed events don't move the
+	// estimate too far.
+	deadReckoningMaxStep = time.Second
+)
+
+// PositionConfidence tells how the EstimatedPosition in the telemetry
+// was found.
+type PositionConfidence int
+
+const (
+	// PositionConfidenceNone means there is no known position.
+	PositionConfidenceNone PositionConfidence = iota
+	// PositionConfidenceEstimated means the gps is lost, and the
+	// position is estimated from the speed of the drone since the last
+	// gps position.
+	PositionConfidenceEstimated
+	// PositionConfidenceGPS means the position is the gps position.
+	PositionConfidenceGPS
+)
+
+// String will return the name of the confidence.
+func (p PositionConfidence) String() string {
+	switch p {
+	case PositionConfidenceNone:
+		return "none"
+	case PositionConfidenceEstimated:
+		return "estimated"
+	case PositionConfidenceGPS:
+		return "gps"
+	}
+
+	return fmt.Sprintf("unknown(%d)", int(p))
+}
+
+// deadReckoning holds the position estimated from the last gps
+// position and the speed of the drone.
+type deadReckoning struct {
+	mu sync.Mutex
+	// estimate is the estimated position, valid if known is true.
+	estimate Position
+	known    bool
+	// gpsOK is true while the drone reports valid gps positions.
+	gpsOK bool
+	// lastFix is when the last valid gps position was received, and
+	// fixAltitude the altitude above the take off point at that time,
+	// used to find the altitude of the estimate.
+	lastFix     time.Time
+	fixAltitude float64
+	fixPosition Position
+	// lastSpeed is when the last speed was received.
+	lastSpeed time.Time
+}
+
+// validGPSPosition will return false for the position reported by the
+// drone when it have no gps fix.
+func validGPSPosition(p Position) bool {
+	return p.Latitude != 500 && p.Longitude != 500 && !(p.Latitude == 0 && p.Longitude == 0)
+}
+
+// handleGPSPosition will use a valid gps position as the estimate, or
+// start the dead reckoning from the last valid position if the gps is
+// lost.
+func (d *Drone) handleGPSPosition(p Position, now time.Time) {
+	d.deadReckoning.mu.Lock()
+	defer d.deadReckoning.mu.Unlock()
+
+	dr := &d.deadReckoning
+	if !validGPSPosition(p) {
+		dr.gpsOK = false
+		d.publishEstimateLocked(now)
+		return
+	}
+
+	dr.gpsOK = true
+	dr.known = true
+	dr.estimate = p
+	dr.fixPosition = p
+	dr.lastFix = now
+	dr.fixAltitude = d.telemetry.snapshot().Altitude
+	d.publishEstimateLocked(now)
+}
+
+// handleGPSFix will stop using the gps positions for the estimate when
+// the drone reports that the gps fix is lost.
+func (d *Drone) handleGPSFix(fixed bool, now time.Time) {
+	d.deadReckoning.mu.Lock()
+	defer d.deadReckoning.mu.Unlock()
+
+	if !fixed {
+		d.deadReckoning.gpsOK = false
+		d.publishEstimateLocked(now)
+	}
+}
+
+// handleSpeed will move the estimated position with the speed reported
+// by the drone while the gps is lost. The speed is given in m/s in the
+// north, east and down directions, so the attitude of the drone is
+// already accounted for by the drone.
+func (d *Drone) handleSpeed(north float32, east float32, down float32, now time.Time) {
+	d.deadReckoning.mu.Lock()
+	defer d.deadReckoning.mu.Unlock()
+
+	dr := &d.deadReckoning
+	last := dr.lastSpeed
+	dr.lastSpeed = now
+	if dr.gpsOK || !dr.known || last.IsZero() {
+		return
+	}
+
+	dt := now.Sub(last)
+	if dt > deadReckoningMaxStep {
+		dt = deadReckoningMaxStep
+	}
+
+	dist := math.Hypot(float64(north), float64(east)) * dt.Seconds()
+	if dist > 0 {
+		bearing := math.Atan2(float64(east), float64(north)) * 180 / math.Pi
+		dr.estimate.Latitude, dr.estimate.Longitude = offsetPosition(dr.estimate.Latitude, dr.estimate.Longitude, dist, bearing)
+	}
+
+	// The altitude above the take off point comes from the barometer,
+	// which is better than integrating the vertical speed.
+	dr.estimate.Altitude = dr.fixPosition.Altitude + d.telemetry.snapshot().Altitude - dr.fixAltitude
+
+	d.publishEstimateLocked(now)
+}
+
+// publishEstimateLocked will put the estimated position and confidence
+// in the telemetry. Must be called while holding the lock.
+func (d *Drone) publishEstimateLocked(now time.Time) {
+	dr := &d.deadReckoning
+
+	conf := PositionConfidenceNone
+	switch {
+	case dr.known && dr.gpsOK:
+		conf = PositionConfidenceGPS
+	case dr.known && now.Sub(dr.lastFix) <= deadReckoningMaxAge:
+		conf = PositionConfidenceEstimated
+	}
+
+	d.telemetry.update(func(t *Telemetry) {
+		t.EstimatedPosition = dr.estimate
+		t.PositionConfidence = conf
+		t.LastGPSFix = dr.lastFix
+	})
+}
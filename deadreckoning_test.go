@@ -0,0 +1,58 @@
+package parrotbebop
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDeadReckoning(t *testing.T) {
+	d := NewDrone()
+	now := time.Now()
+
+	if c := d.Telemetry().PositionConfidence; c != PositionConfidenceNone {
+		t.Fatalf("expected no position, got %v", c)
+	}
+
+	fix := Position{Latitude: 60, Longitude: 10, Altitude: 100}
+	d.handleGPSPosition(fix, now)
+	if tm := d.Telemetry(); tm.PositionConfidence != PositionConfidenceGPS || tm.EstimatedPosition != fix {
+		t.Fatalf("expected gps position, got %v %#v", tm.PositionConfidence, tm.EstimatedPosition)
+	}
+
+	// The speed is not used while the gps is ok.
+	d.handleSpeed(10, 0, 0, now)
+	d.handleSpeed(10, 0, 0, now.Add(time.Second))
+	if d.Telemetry().EstimatedPosition != fix {
+		t.Fatal("estimate moved while the gps is ok")
+	}
+
+	// Lose the gps, and fly east at 5 m/s for 10 seconds while
+	// climbing 3 meters.
+	d.handleGPSPosition(Position{Latitude: 500, Longitude: 500, Altitude: 500}, now.Add(time.Second))
+	d.telemetry.update(func(t *Telemetry) { t.Altitude = 3 })
+	for i := 2; i <= 11; i++ {
+		d.handleSpeed(0, 5, 0, now.Add(time.Second*time.Duration(i)))
+	}
+
+	tm := d.Telemetry()
+	if tm.PositionConfidence != PositionConfidenceEstimated {
+		t.Fatalf("expected estimated position, got %v", tm.PositionConfidence)
+	}
+	e := tm.EstimatedPosition
+	if dist := distanceTo(fix.Latitude, fix.Longitude, e.Latitude, e.Longitude); math.Abs(dist-50) > 0.1 {
+		t.Fatalf("expected to have moved 50m, moved %v", dist)
+	}
+	if b := bearingTo(fix.Latitude, fix.Longitude, e.Latitude, e.Longitude); math.Abs(b-90) > 0.1 {
+		t.Fatalf("expected to have moved east, bearing %v", b)
+	}
+	if e.Altitude != 103 {
+		t.Fatalf("expected altitude 103, got %v", e.Altitude)
+	}
+
+	// The estimate is not trusted after a while.
+	d.handleSpeed(0, 0, 0, now.Add(deadReckoningMaxAge+time.Second*2))
+	if c := d.Telemetry().PositionConfidence; c != PositionConfidenceNone {
+		t.Fatalf("expected no position after max age, got %v", c)
+	}
+}
@@ -0,0 +1,199 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// projectNames holds the names of the ARSDK projects.
+var projectNames = map[ProjectDef]string{
+	ProjectCommon:   "common",
+	ProjectArdrone3: "ardrone3",
+}
+
+// classNames holds the names of the ARSDK classes of each project.
+var classNames = map[ProjectDef]map[ClassDef]string{
+	ProjectArdrone3: {
+		0:  "Piloting",
+		1:  "Camera",
+		2:  "PilotingSettings",
+		3:  "MediaRecordEvent",
+		4:  "PilotingState",
+		5:  "Animations",
+		6:  "PilotingSettingsState",
+		7:  "MediaRecord",
+		8:  "MediaRecordState",
+		9:  "NetworkSettings",
+		10: "NetworkSettingsState",
+		11: "SpeedSettings",
+		12: "SpeedSettingsState",
+		13: "Network",
+		14: "NetworkState",
+		16: "SettingsState",
+		19: "PictureSettings",
+		20: "PictureSettingsState",
+		21: "MediaStreaming",
+		22: "MediaStreamingState",
+		23: "GPSSettings",
+		24: "GPSSettingsState",
+		25: "CameraState",
+		29: "Antiflickering",
+		30: "AntiflickeringState",
+		31: "GPSState",
+		32: "PROState",
+		33: "AccessoryState",
+		34: "PilotingEvent",
+		35: "Sound",
+		36: "SoundState",
+	},
+	ProjectCommon: {
+		0:  "Network",
+		1:  "NetworkEvent",
+		2:  "Settings",
+		3:  "SettingsState",
+		4:  "Common",
+		5:  "CommonState",
+		6:  "OverHeat",
+		7:  "OverHeatState",
+		8:  "Controller",
+		9:  "WifiSettings",
+		10: "WifiSettingsState",
+		11: "Mavlink",
+		12: "MavlinkState",
+		13: "Calibration",
+		14: "CalibrationState",
+		15: "CameraSettingsState",
+		16: "GPS",
+		17: "FlightPlanState",
+		18: "ARLibsVersionsState",
+		19: "FlightPlanEvent",
+		20: "Audio",
+		21: "AudioState",
+		22: "Headlights",
+		23: "HeadlightsState",
+		24: "Animations",
+		25: "AnimationsState",
+		26: "Accessory",
+		27: "AccessoryState",
+		28: "Charger",
+		29: "ChargerState",
+		30: "RunState",
+		31: "Factory",
+		32: "FlightPlanSettings",
+		33: "FlightPlanSettingsState",
+		34: "UpdateState",
+	},
+}
+
+// commandName will return the name of the command with the project,
+// class and command names separated by dots, like
+// "ardrone3.PilotingState.AttitudeChanged". The names not known are
+// given as numbers.
+func commandName(c Command) string {
+	project, ok := projectNames[c.Project]
+	if !ok {
+		project = fmt.Sprint(c.Project)
+	}
+	class, ok := classNames[c.Project][c.Class]
+	if !ok {
+		class = fmt.Sprint(c.Class)
+	}
+	cmd := fmt.Sprint(c.Cmd)
+
+	// The name of the command type in the command map is the project,
+	// class and command names put together.
+	if v, ok := CommandMap[c]; ok {
+		prefix := strings.Title(project) + class
+		if name := reflect.TypeOf(v).Name(); strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+			cmd = name[len(prefix):]
+		}
+	}
+
+	return project + "." + class + "." + cmd
+}
+
+// frameDebug will write every ARNetworkAL frame sent and received
+// annotated with the command names when a writer is set.
+type frameDebug struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// SetFrameDebug will make the driver write every ARNetworkAL frame sent
+// to and received from the drone to w, annotated with the name of the
+// command, the decoded arguments, and a hex dump of the frame. Setting
+// w to nil will disable it.
+func (d *Drone) SetFrameDebug(w io.Writer) {
+	d.frameDebug.mu.Lock()
+	defer d.frameDebug.mu.Unlock()
+
+	d.frameDebug.w = w
+}
+
+// packet will write all the frames of the UDP packet, where direction
+// tells if the packet was sent (C2D) or received (D2C).
+func (f *frameDebug) packet(direction string, b []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.w == nil {
+		return
+	}
+
+	WriteFrames(f.w, direction, b)
+}
+
+// WriteFrames will write every ARNetworkAL frame of the UDP packet to w
+// as done by SetFrameDebug, annotated with the name of the command, the
+// decoded arguments, and a hex dump of the frame. The direction is put
+// first on each frame, like C2D for sent to the drone, and D2C for
+// received from the drone. The error of a malformed frame is written,
+// and returned.
+func WriteFrames(w io.Writer, direction string, b []byte) error {
+	for len(b) >= frameHeaderSize {
+		fr, rest, err := DecodeFrame(b)
+		if err != nil {
+			fmt.Fprintf(w, "%v malformed frame: %v\n  % x\n", direction, err, b)
+			return err
+		}
+
+		if _, err := fmt.Fprintln(w, annotateFrame(direction, b[:fr.Size()])); err != nil {
+			return err
+		}
+		b = rest
+	}
+
+	return nil
+}
+
+// annotateFrame will create the debug text for a single frame.
+func annotateFrame(direction string, frame []byte) string {
+	dataType, bufferID, seq := frame[0], frame[1], frame[2]
+	data := frame[7:]
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%v type=%v buf=%v seq=%v size=%v", direction, dataType, bufferID, seq, len(frame))
+
+	switch {
+	case dataType == dataTypeAck:
+		fmt.Fprintf(&sb, " ack for buf=%v", int(bufferID)-ackBufferOffset)
+	case bufferID == 0 || bufferID == 1:
+		sb.WriteString(" ping/pong")
+	case len(data) >= cmdHeaderSize:
+		c, args, err := DecodeCommand(data)
+		sb.WriteString(" " + commandName(c))
+		switch {
+		case err != nil:
+			fmt.Fprintf(&sb, " <failed to decode: %v>", err)
+		case args != nil && len(data) > cmdHeaderSize:
+			fmt.Fprintf(&sb, " %+v", args)
+		}
+	}
+
+	fmt.Fprintf(&sb, "\n  % x", frame)
+
+	return sb.String()
+}
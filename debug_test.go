@@ -0,0 +1,57 @@
+package parrotbebop
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCommandName(t *testing.T) {
+	tests := []struct {
+		c    Command
+		want string
+	}{
+		{Command(PilotingStateAttitudeChanged), "ardrone3.PilotingState.AttitudeChanged"},
+		{Command(CommonAllStates), "common.Common.AllStates"},
+		{Command{Project: 9, Class: 1, Cmd: 2}, "9.1.2"},
+	}
+
+	for _, tt := range tests {
+		if got := commandName(tt.c); got != tt.want {
+			t.Errorf("commandName(%+v) = %v, want %v", tt.c, got, tt.want)
+		}
+	}
+}
+
+func TestAnnotateFrame(t *testing.T) {
+	frame := []byte{2, 127, 3, 0, 0, 0, 0}
+	frame = append(frame, convertCMDToBytes(Command(PilotingStateFlyingStateChanged))...)
+	frame = append(frame, 2, 0, 0, 0)
+	frame[3] = byte(len(frame))
+
+	got := annotateFrame("D2C", frame)
+	if !strings.Contains(got, "ardrone3.PilotingState.FlyingStateChanged {State:2}") {
+		t.Fatalf("wrong annotation: %v", got)
+	}
+}
+
+func TestWriteFrames(t *testing.T) {
+	frame := []byte{2, 127, 3, 0, 0, 0, 0}
+	frame = append(frame, convertCMDToBytes(Command(PilotingStateFlyingStateChanged))...)
+	frame = append(frame, 2, 0, 0, 0)
+	frame[3] = byte(len(frame))
+
+	// Two frames in the same packet, followed by a truncated frame.
+	packet := append(append(append([]byte{}, frame...), frame...), 2, 127, 4, 200, 0, 0, 0)
+
+	var buf bytes.Buffer
+	if err := WriteFrames(&buf, "D2C", packet); err == nil {
+		t.Fatalf("expected error for the truncated frame")
+	}
+	if n := strings.Count(buf.String(), "FlyingStateChanged"); n != 2 {
+		t.Fatalf("got %v frames, want 2: %v", n, buf.String())
+	}
+	if !strings.Contains(buf.String(), "malformed frame") {
+		t.Fatalf("expected the malformed frame written: %v", buf.String())
+	}
+}
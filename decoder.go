@@ -0,0 +1,71 @@
+package parrotbebop
+
+import (
+	"github.com/postmannen/parrotbebop/decoder"
+)
+
+// The ARNetworkAL frames and the ARCommands are decoded by the decoder
+// package, which have no state of the connection. The functions here
+// decodes the commands with the CommandMap of the Bebop.
+
+// frameHeaderSize is the size of the ARNetworkAL header, 1 byte data
+// type, 1 byte buffer ID, 1 byte sequence number, and 4 bytes size.
+const frameHeaderSize = decoder.FrameHeaderSize
+
+// cmdHeaderSize is the size of the project, class and command ID's of
+// an ARCommand, which are 1+1+2 bytes.
+const cmdHeaderSize = decoder.CommandHeaderSize
+
+// The errors returned when decoding, which are the same as the errors
+// of the decoder package.
+var (
+	ErrShortFrame         = decoder.ErrShortFrame
+	ErrFrameSize          = decoder.ErrFrameSize
+	ErrShortCommand       = decoder.ErrShortCommand
+	ErrMalformedArguments = decoder.ErrMalformedArguments
+)
+
+// Frame is a decoded ARNetworkAL frame.
+type Frame = decoder.Frame
+
+// DecodeFrame will decode the first ARNetworkAL frame found in b, and
+// return the frame, and the bytes following the frame. The data of the
+// frame refers to b, and is not copied.
+// ErrShortFrame or ErrFrameSize is returned wrapped if the frame is
+// malformed.
+func DecodeFrame(b []byte) (f Frame, rest []byte, err error) {
+	return decoder.DecodeFrame(b)
+}
+
+// DecodeFrames will decode all the ARNetworkAL frames of an UDP packet.
+// If a frame is malformed the frames decoded before it are returned
+// together with the error.
+func DecodeFrames(b []byte) ([]Frame, error) {
+	return decoder.DecodeFrames(b)
+}
+
+// DecodeCommand will decode the ARCommand found in the data of a frame,
+// and return the command, and it's decoded arguments. The arguments are
+// nil if the command is not known.
+// ErrShortCommand or ErrMalformedArguments is returned wrapped if the
+// command is malformed.
+func DecodeCommand(data []byte) (Command, interface{}, error) {
+	id, args, err := decoder.DecodeCommand(data, commandTable)
+	return commandOfID(id), args, err
+}
+
+// commandTable will return the decoder of the command from the
+// CommandMap.
+func commandTable(id decoder.CommandID) (decoder.ArgumentDecoder, bool) {
+	dec, ok := CommandMap[commandOfID(id)]
+	return dec, ok
+}
+
+// commandOfID will return the Command with the ID's given.
+func commandOfID(id decoder.CommandID) Command {
+	return Command{
+		Project: ProjectDef(id.Project),
+		Class:   ClassDef(id.Class),
+		Cmd:     CmdDef(id.Cmd),
+	}
+}
@@ -0,0 +1,167 @@
+// Package decoder decodes the ARNetworkAL frames and the ARCommands they
+// carry from raw bytes only, without any state of the connection, so it
+// can be used to fuzz the decoder, and to analyze captured traffic
+// offline without the rest of the driver.
+//
+// The arguments of the commands are decoded with the command table
+// given to DecodeCommand, which for the Bebop is the CommandMap of the
+// parrotbebop package, as used by parrotbebop.DecodeCommand.
+package decoder
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// FrameHeaderSize is the size of the ARNetworkAL header, 1 byte data
+// type, 1 byte buffer ID, 1 byte sequence number, and 4 bytes size.
+const FrameHeaderSize = 7
+
+// CommandHeaderSize is the size of the project, class and command ID's
+// of an ARCommand, which are 1+1+2 bytes.
+const CommandHeaderSize = 4
+
+var (
+	// ErrShortFrame is returned when decoding a frame where the packet
+	// is shorter than the header of the frame.
+	ErrShortFrame = errors.New("frame shorter than header")
+	// ErrFrameSize is returned when decoding a frame where the size in
+	// the header is smaller than the header, or larger than the data
+	// left in the packet.
+	ErrFrameSize = errors.New("invalid frame size")
+	// ErrShortCommand is returned when decoding a command from a frame
+	// with less data than the project, class and command ID's.
+	ErrShortCommand = errors.New("frame data shorter than command header")
+	// ErrMalformedArguments is returned when the arguments of a command
+	// could not be decoded, like when there are too few bytes.
+	ErrMalformedArguments = errors.New("malformed command arguments")
+)
+
+// Frame is a decoded ARNetworkAL frame.
+type Frame struct {
+	// DataType is the ARNetworkAL data type, where 1 is an ack, 2 is
+	// data, 3 is low latency data, and 4 is data with ack.
+	DataType int
+	// BufferID is the ID of the ARNetwork buffer the frame was sent on.
+	BufferID int
+	// Sequence is the sequence number of the frame on the buffer.
+	Sequence int
+	// Data is the payload of the frame, without the header.
+	Data []byte
+}
+
+// Size will return the size of the frame including the header, as
+// given in the header of the frame.
+func (f Frame) Size() int {
+	return FrameHeaderSize + len(f.Data)
+}
+
+// DecodeFrame will decode the first ARNetworkAL frame found in b, and
+// return the frame, and the bytes following the frame. The data of the
+// frame refers to b, and is not copied.
+// ErrShortFrame or ErrFrameSize is returned wrapped if the frame is
+// malformed.
+func DecodeFrame(b []byte) (f Frame, rest []byte, err error) {
+	if len(b) < FrameHeaderSize {
+		return Frame{}, b, fmt.Errorf("DecodeFrame: %w, got %v bytes", ErrShortFrame, len(b))
+	}
+
+	// The size includes the header of 7 bytes.
+	size := binary.LittleEndian.Uint32(b[3:7])
+
+	if size < FrameHeaderSize || uint64(size) > uint64(len(b)) {
+		return Frame{}, b, fmt.Errorf("DecodeFrame: %w %v, got %v bytes", ErrFrameSize, size, len(b))
+	}
+
+	f = Frame{
+		DataType: int(b[0]),
+		BufferID: int(b[1]),
+		Sequence: int(b[2]),
+		// The capacity is limited so the decoding of the frame can't
+		// read into the next frame.
+		Data: b[FrameHeaderSize:size:size],
+	}
+
+	return f, b[size:], nil
+}
+
+// DecodeFrames will decode all the ARNetworkAL frames of an UDP packet.
+// If a frame is malformed the frames decoded before it are returned
+// together with the error.
+func DecodeFrames(b []byte) ([]Frame, error) {
+	var frames []Frame
+
+	for len(b) > 0 {
+		f, rest, err := DecodeFrame(b)
+		if err != nil {
+			return frames, err
+		}
+
+		frames = append(frames, f)
+		b = rest
+	}
+
+	return frames, nil
+}
+
+// CommandID is the project, class and command ID's of an ARCommand.
+type CommandID struct {
+	Project uint8
+	Class   uint8
+	Cmd     uint16
+}
+
+// ArgumentDecoder decodes the arguments of a command.
+type ArgumentDecoder interface {
+	Decode([]byte) interface{}
+}
+
+// CommandTable will return the decoder of the arguments for the command
+// given, and false if the command is not known.
+type CommandTable func(id CommandID) (ArgumentDecoder, bool)
+
+// DecodeCommand will decode the ARCommand found in the data of a frame,
+// and return the ID of the command, and it's arguments decoded with the
+// decoder found in the table. The arguments are nil if the command is
+// not known.
+// ErrShortCommand or ErrMalformedArguments is returned wrapped if the
+// command is malformed.
+func DecodeCommand(data []byte, table CommandTable) (CommandID, interface{}, error) {
+	if len(data) < CommandHeaderSize {
+		return CommandID{}, nil, fmt.Errorf("DecodeCommand: %w, got %v bytes", ErrShortCommand, len(data))
+	}
+
+	// The command ID is 2 bytes little endian.
+	id := CommandID{
+		Project: data[0],
+		Class:   data[1],
+		Cmd:     binary.LittleEndian.Uint16(data[2:4]),
+	}
+
+	dec, ok := table(id)
+	if !ok {
+		return id, nil, nil
+	}
+
+	args, err := decodeArguments(dec, data[CommandHeaderSize:len(data):len(data)])
+	if err != nil {
+		return id, nil, fmt.Errorf("DecodeCommand: %v/%v/%v: %w", id.Project, id.Class, id.Cmd, err)
+	}
+
+	return id, args, nil
+}
+
+// decodeArguments will decode the arguments with the decoder given. A
+// decoder reading the fields without checking the length will panic on
+// too few bytes, which is returned as ErrMalformedArguments.
+func decodeArguments(dec ArgumentDecoder, b []byte) (args interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			args = nil
+			err = fmt.Errorf("%w: %v", ErrMalformedArguments, r)
+		}
+	}()
+
+	return dec.Decode(b), nil
+}
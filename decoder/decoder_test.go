@@ -0,0 +1,106 @@
+package decoder
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+// uint32Decoder decodes a single uint32 argument, without checking the
+// length like the generated decoders.
+type uint32Decoder struct{}
+
+func (uint32Decoder) Decode(b []byte) interface{} {
+	return binary.LittleEndian.Uint32(b[0:4])
+}
+
+// testTable knows the command 1/2/3 with an uint32 argument.
+func testTable(id CommandID) (ArgumentDecoder, bool) {
+	if id == (CommandID{Project: 1, Class: 2, Cmd: 3}) {
+		return uint32Decoder{}, true
+	}
+	return nil, false
+}
+
+// testFrame will return a data frame with the command 1/2/3 and the
+// argument given.
+func testFrame(arg uint32) []byte {
+	b := []byte{2, 127, 1, 15, 0, 0, 0, 1, 2, 3, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(b[11:], arg)
+	return b
+}
+
+func TestDecodeFrames(t *testing.T) {
+	ack := []byte{1, 139, 5, 8, 0, 0, 0, 42}
+	b := append(testFrame(12), ack...)
+
+	frames, err := DecodeFrames(b)
+	if err != nil {
+		t.Fatalf("DecodeFrames: %v", err)
+	}
+	if len(frames) != 2 {
+		t.Fatalf("got %v frames, want 2", len(frames))
+	}
+
+	got := frames[1]
+	if got.DataType != 1 || got.BufferID != 139 || got.Sequence != 5 || got.Size() != len(ack) {
+		t.Fatalf("wrong ack frame: %+v", got)
+	}
+
+	id, args, err := DecodeCommand(frames[0].Data, testTable)
+	if err != nil {
+		t.Fatalf("DecodeCommand: %v", err)
+	}
+	if id != (CommandID{Project: 1, Class: 2, Cmd: 3}) || args != uint32(12) {
+		t.Fatalf("got command %+v with %v, want 1/2/3 with 12", id, args)
+	}
+
+	// A malformed frame after a good one returns the good one.
+	frames, err = DecodeFrames(append(testFrame(12), 2, 127, 1, 99, 0, 0, 0))
+	if !errors.Is(err, ErrFrameSize) || len(frames) != 1 {
+		t.Fatalf("got %v frames and error %v, want 1 and %v", len(frames), err, ErrFrameSize)
+	}
+}
+
+func TestDecodeCommandErrors(t *testing.T) {
+	if _, _, err := DecodeCommand([]byte{1, 2, 3}, testTable); !errors.Is(err, ErrShortCommand) {
+		t.Fatalf("got error %v, want %v", err, ErrShortCommand)
+	}
+	if _, _, err := DecodeCommand([]byte{1, 2, 3, 0, 1}, testTable); !errors.Is(err, ErrMalformedArguments) {
+		t.Fatalf("got error %v, want %v", err, ErrMalformedArguments)
+	}
+
+	id, args, err := DecodeCommand([]byte{0xfe, 0xfe, 0xfe, 0xfe, 1, 2}, testTable)
+	if err != nil || args != nil {
+		t.Fatalf("got arguments %v and error %v, want none", args, err)
+	}
+	if id.Project != 0xfe || id.Cmd != 0xfefe {
+		t.Fatalf("wrong command: %+v", id)
+	}
+}
+
+// TestDecodeRandom will feed the decoder random and mutated data, and
+// fail if it panics.
+func TestDecodeRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20000; i++ {
+		var b []byte
+		if i%2 == 0 {
+			b = testFrame(rnd.Uint32())
+			for n := rnd.Intn(4); n > 0; n-- {
+				b[rnd.Intn(len(b))] = byte(rnd.Intn(256))
+			}
+			b = b[:rnd.Intn(len(b)+1)]
+		} else {
+			b = make([]byte, rnd.Intn(64))
+			rnd.Read(b)
+		}
+
+		frames, _ := DecodeFrames(b)
+		for _, f := range frames {
+			DecodeCommand(f.Data, testTable)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package parrotbebop
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestDecodeFrames(t *testing.T) {
+	ack := []byte{dataTypeAck, bufferC2DAck + ackBufferOffset, 5, 8, 0, 0, 0, 42}
+	b := append(attitudeFrame(12), ack...)
+
+	frames, err := DecodeFrames(b)
+	if err != nil {
+		t.Fatalf("DecodeFrames: %v", err)
+	}
+	if len(frames) != 2 {
+		t.Fatalf("got %v frames, want 2", len(frames))
+	}
+
+	got := frames[1]
+	if got.DataType != dataTypeAck || got.BufferID != ackID(bufferC2DAck) || got.Sequence != 5 || got.Size() != len(ack) {
+		t.Fatalf("wrong ack frame: %+v", got)
+	}
+
+	c, args, err := DecodeCommand(frames[0].Data)
+	if err != nil {
+		t.Fatalf("DecodeCommand: %v", err)
+	}
+	if c != Command(PilotingStateAttitudeChanged) {
+		t.Fatalf("wrong command: %+v", c)
+	}
+	if _, ok := args.(Ardrone3PilotingStateAttitudeChangedArguments); !ok {
+		t.Fatalf("wrong type of arguments: %T", args)
+	}
+
+	// A malformed frame after a good one returns the good one.
+	frames, err = DecodeFrames(append(attitudeFrame(12), 2, 127, 1, 99, 0, 0, 0))
+	if !errors.Is(err, ErrFrameSize) || len(frames) != 1 {
+		t.Fatalf("got %v frames and error %v, want 1 and %v", len(frames), err, ErrFrameSize)
+	}
+}
+
+func TestDecodeCommandUnknown(t *testing.T) {
+	c, args, err := DecodeCommand([]byte{0xfe, 0xfe, 0xfe, 0xfe, 1, 2})
+	if err != nil || args != nil {
+		t.Fatalf("got arguments %v and error %v, want none", args, err)
+	}
+	if c.Project != 0xfe || c.Cmd != 0xfefe {
+		t.Fatalf("wrong command: %+v", c)
+	}
+}
+
+// TestDecodeRandom will feed the decoder random and mutated data, and
+// fail if it panics.
+func TestDecodeRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	// Start with valid frames of all the known commands, so the random
+	// mutations reaches the argument decoders.
+	var seeds [][]byte
+	for c := range CommandMap {
+		f := []byte{dataTypeData, bufferD2CNavdata, 1, 0, 0, 0, 0}
+		f = append(f, convertCMDToBytes(c)...)
+		f = append(f, make([]byte, rnd.Intn(16))...)
+		f[3] = byte(len(f))
+		seeds = append(seeds, f)
+	}
+
+	for i := 0; i < 20000; i++ {
+		var b []byte
+		if i%2 == 0 {
+			b = append(b, seeds[rnd.Intn(len(seeds))]...)
+			for n := rnd.Intn(4); n > 0; n-- {
+				b[rnd.Intn(len(b))] = byte(rnd.Intn(256))
+			}
+			b = b[:rnd.Intn(len(b)+1)]
+		} else {
+			b = make([]byte, rnd.Intn(64))
+			rnd.Read(b)
+		}
+
+		frames, _ := DecodeFrames(b)
+		for _, f := range frames {
+			DecodeCommand(f.Data)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package parrotbebop
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrDiscoveryRefused is returned by Discover when the drone
+	// refuses the connection.
+	ErrDiscoveryRefused = errors.New("discovery refused by drone")
+	// ErrDroneBusy is returned by Discover when the drone is busy,
+	// like when another controller is connected.
+	ErrDroneBusy = errors.New("drone is busy")
+)
+
+const (
+	// discoveryTimeout is how long each discovery attempt can take.
+	discoveryTimeout = time.Second * 3
+	// discoveryMinBackoff and discoveryMaxBackoff are the limits of the
+	// exponential backoff between discovery attempts.
+	discoveryMinBackoff = time.Millisecond * 500
+	discoveryMaxBackoff = time.Second * 10
+
+	// discoveryStatusBusy is the status given by the drone when busy,
+	// which is the ARDISCOVERY_ERROR_BUSY error code of the ARSDK.
+	discoveryStatusBusy = -6
+)
+
+// discoveryRequest is the JSON data sent to the drone to start the
+// discovery, where the ports are given as numbers.
+type discoveryRequest struct {
+	ControllerType             string `json:"controller_type"`
+	ControllerName             string `json:"controller_name"`
+	D2cPort                    int    `json:"d2c_port"`
+	Arstream2ClientStreamPort  int    `json:"arstream2_client_stream_port"`
+	Arstream2ClientControlPort int    `json:"arstream2_client_control_port"`
+	Arstream2MaxPacketSize     int    `json:"arstream2_max_packet_size,omitempty"`
+	Arstream2MaxLatency        int    `json:"arstream2_max_latency,omitempty"`
+	Arstream2MaxNetworkLatency int    `json:"arstream2_max_network_latency,omitempty"`
+	Arstream2MaxBitrate        int    `json:"arstream2_max_bitrate,omitempty"`
+}
+
+// discoveryResponse is the JSON data sent by the drone as the response
+// to the discovery request, like :
+//
+// { "status": 0, "c2d_port": 54321, "c2d_update_port": 51, "c2d_user_port": 21, "qos_mode": 0, "arstream2_server_stream_port": 5004, "arstream2_server_control_port": 5005 }
+type discoveryResponse struct {
+	// Status and C2dPort are pointers so we know if they were given.
+	Status                     *int `json:"status"`
+	C2dPort                    *int `json:"c2d_port"`
+	C2dUpdatePort              int  `json:"c2d_update_port"`
+	C2dUserPort                int  `json:"c2d_user_port"`
+	QosMode                    int  `json:"qos_mode"`
+	Arstream2ServerStreamPort  int  `json:"arstream2_server_stream_port"`
+	Arstream2ServerControlPort int  `json:"arstream2_server_control_port"`
+}
+
+// validate will check that the required fields are given, the ports
+// are valid, and that the drone accepted the connection.
+func (r discoveryResponse) validate() error {
+	if r.Status == nil {
+		return fmt.Errorf("missing status in discovery response")
+	}
+
+	switch *r.Status {
+	case 0:
+	case discoveryStatusBusy:
+		return ErrDroneBusy
+	default:
+		return fmt.Errorf("%w: status %v", ErrDiscoveryRefused, *r.Status)
+	}
+
+	if r.C2dPort == nil {
+		return fmt.Errorf("missing c2d_port in discovery response")
+	}
+
+	ports := map[string]int{
+		"c2d_port":                      *r.C2dPort,
+		"c2d_update_port":               r.C2dUpdatePort,
+		"c2d_user_port":                 r.C2dUserPort,
+		"arstream2_server_stream_port":  r.Arstream2ServerStreamPort,
+		"arstream2_server_control_port": r.Arstream2ServerControlPort,
+	}
+	for name, p := range ports {
+		if p < 0 || p > 65535 || (name == "c2d_port" && p == 0) {
+			return fmt.Errorf("invalid %v in discovery response: %v", name, p)
+		}
+	}
+
+	return nil
+}
+
+// Discover will initalize the connection with the drone. Failed
+// attempts are retried with an exponential backoff until the discovery
+// succeeds, the context is done, the drone refuses the connection
+// with ErrDiscoveryRefused, or the max attempts of the ReconnectPolicy
+// are reached with ErrMaxAttempts. An EventConnection is published for
+// each attempt.
+func (d *Drone) Discover(ctx context.Context) error {
+	policy := d.reconnectConfig.get()
+	backoff := policy.MinBackoff
+
+	for attempt := 1; ; attempt++ {
+		d.publishConnection(ConnectionEvent{State: ConnectionAttempt, Attempt: attempt})
+
+		err := d.discoverOnce(ctx)
+		if err == nil {
+			d.publishConnection(ConnectionEvent{State: ConnectionEstablished, Attempt: attempt})
+			return nil
+		}
+		if errors.Is(err, ErrDiscoveryRefused) {
+			d.publishConnection(ConnectionEvent{State: ConnectionAttemptFailed, Attempt: attempt, Err: err})
+			return fmt.Errorf("Discover: %w", err)
+		}
+		if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+			d.publishConnection(ConnectionEvent{State: ConnectionAttemptFailed, Attempt: attempt, Err: err})
+			return fmt.Errorf("Discover: %w after %v attempts, last error: %v", ErrMaxAttempts, attempt, err)
+		}
+
+		d.publishConnection(ConnectionEvent{State: ConnectionAttemptFailed, Attempt: attempt, Err: err, Backoff: backoff})
+		log.Printf("error: discovery attempt %v failed, retrying in %v: %v\n", attempt, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("Discover: %v, last error: %w", ctx.Err(), err)
+		case <-d.chQuit:
+			return fmt.Errorf("Discover: quit while discovering, last error: %w", err)
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+// discoverOnce will do a single discovery attempt.
+func (d *Drone) discoverOnce(ctx context.Context) error {
+	timeout := d.discoveryQuirks.timeout
+	if timeout == 0 {
+		timeout = discoveryTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	nd := net.Dialer{Cancel: d.chQuit}
+	discoverConn, err := nd.DialContext(ctx, "tcp", d.addressDrone+":"+d.portDiscover)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err := discoverConn.Close()
+		if err != nil {
+			log.Printf("error: failed to close discoverConn: %v\r\n", err)
+		}
+		log.Printf("...closed discoverConn\r\n")
+	}()
+
+	// Both the write and the read must be done before the deadline
+	// of the context.
+	deadline, _ := ctx.Deadline()
+	if err := discoverConn.SetDeadline(deadline); err != nil {
+		return fmt.Errorf("failed to set deadline: %v", err)
+	}
+
+	request, err := d.discoveryRequest()
+	if err != nil {
+		return err
+	}
+	if _, err := discoverConn.Write(request); err != nil {
+		return fmt.Errorf("failed to write discovery request: %v", err)
+	}
+	d.capture.tcp(discoverConn.LocalAddr(), discoverConn.RemoteAddr(), request)
+
+	// Read the returned response of the discovery from the drone. The
+	// JSON decoder will read until a complete JSON object is received,
+	// even if it spans several reads.
+	var raw bytes.Buffer
+	var resp discoveryResponse
+	err = json.NewDecoder(io.TeeReader(discoverConn, &raw)).Decode(&resp)
+	d.capture.tcp(discoverConn.RemoteAddr(), discoverConn.LocalAddr(), raw.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to decode discovery response %q: %v", raw.Bytes(), err)
+	}
+	log.Printf("info: discovery response: %s\n", bytes.Trim(raw.Bytes(), "\x00"))
+
+	if resp.Status == nil && resp.C2dPort != nil && d.discoveryQuirks.allowMissingStatus {
+		log.Printf("info: no status in discovery response, accepting it since the c2d_port is given\n")
+		ok := 0
+		resp.Status = &ok
+	}
+	if err := resp.validate(); err != nil {
+		return err
+	}
+
+	// Set the received Controller to Drone port to use based on discovery
+	// data. The optional ports keep their defaults if not given.
+	d.portC2D = strconv.Itoa(*resp.C2dPort)
+	setPortIfGiven(&d.portC2DUpdate, resp.C2dUpdatePort)
+	setPortIfGiven(&d.portC2DUser, resp.C2dUserPort)
+	setPortIfGiven(&d.portRTPServerStream, resp.Arstream2ServerStreamPort)
+	setPortIfGiven(&d.portRTPServerControl, resp.Arstream2ServerControlPort)
+	d.qosMode = resp.QosMode
+	if d.discoveryQuirks.ignoreQoS {
+		d.qosMode = 0
+	}
+
+	return nil
+}
+
+// setPortIfGiven will set the port string to the port given, if not 0.
+func setPortIfGiven(port *string, p int) {
+	if p != 0 {
+		*port = strconv.Itoa(p)
+	}
+}
+
+// DiscoveryInfo holds the values given by the drone in the discovery.
+type DiscoveryInfo struct {
+	// C2DPort is the port the drone receives the commands on.
+	C2DPort string
+	// C2DUpdatePort is the FTP port used for firmware updates.
+	C2DUpdatePort string
+	// C2DUserPort is the FTP port used for accessing the media stored
+	// on the drone.
+	C2DUserPort string
+	// QoSMode is 1 if the drone wants the packets marked for QoS.
+	QoSMode int
+	// RTPServerStreamPort and RTPServerControlPort are the drone's
+	// ports for the video stream.
+	RTPServerStreamPort  string
+	RTPServerControlPort string
+}
+
+// DiscoveryInfo will return the values given by the drone in the last
+// discovery.
+func (d *Drone) DiscoveryInfo() DiscoveryInfo {
+	return DiscoveryInfo{
+		C2DPort:              d.portC2D,
+		C2DUpdatePort:        d.portC2DUpdate,
+		C2DUserPort:          d.portC2DUser,
+		QoSMode:              d.qosMode,
+		RTPServerStreamPort:  d.portRTPServerStream,
+		RTPServerControlPort: d.portRTPServerControl,
+	}
+}
+
+// ARStream2Params are the optional parameters for the video stream the
+// controller can give the drone in the discovery. Zero values are not
+// sent, and the drone will use it's defaults for them.
+type ARStream2Params struct {
+	// MaxPacketSize is the max size in bytes of the RTP packets.
+	MaxPacketSize int
+	// MaxLatency is the max total latency in ms of the stream.
+	MaxLatency int
+	// MaxNetworkLatency is the max network latency in ms.
+	MaxNetworkLatency int
+	// MaxBitrate is the max bitrate in bits/s of the stream.
+	MaxBitrate int
+}
+
+// controllerConfig holds what the controller tells the drone about
+// itself in the discovery.
+type controllerConfig struct {
+	controllerType string
+	controllerName string
+	arstream2      ARStream2Params
+}
+
+// SetControllerIdentity will set the controller type and name given to
+// the drone in the discovery. Must be called before Start.
+func (d *Drone) SetControllerIdentity(controllerType string, controllerName string) error {
+	if controllerType == "" || controllerName == "" {
+		return fmt.Errorf("SetControllerIdentity: type and name can not be empty")
+	}
+
+	d.controllerConfig.controllerType = controllerType
+	d.controllerConfig.controllerName = controllerName
+
+	return nil
+}
+
+// SetARStream2Params will set the video stream parameters given to the
+// drone in the discovery. Must be called before Start.
+func (d *Drone) SetARStream2Params(p ARStream2Params) error {
+	if p.MaxPacketSize < 0 || p.MaxLatency < 0 || p.MaxNetworkLatency < 0 || p.MaxBitrate < 0 {
+		return fmt.Errorf("SetARStream2Params: negative values are not allowed: %+v", p)
+	}
+
+	d.controllerConfig.arstream2 = p
+
+	return nil
+}
+
+// discoveryRequest will create the JSON request for the discovery.
+func (d *Drone) discoveryRequest() ([]byte, error) {
+	ports := map[string]int{}
+	for name, p := range map[string]string{"d2c": d.portD2C, "stream": d.portRTPStream, "control": d.portRTPControl} {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %v port: %v", name, p)
+		}
+		ports[name] = v
+	}
+
+	c := d.controllerConfig
+	req := discoveryRequest{
+		ControllerType:             c.controllerType,
+		ControllerName:             c.controllerName,
+		D2cPort:                    ports["d2c"],
+		Arstream2ClientStreamPort:  ports["stream"],
+		Arstream2ClientControlPort: ports["control"],
+		Arstream2MaxPacketSize:     c.arstream2.MaxPacketSize,
+		Arstream2MaxLatency:        c.arstream2.MaxLatency,
+		Arstream2MaxNetworkLatency: c.arstream2.MaxNetworkLatency,
+		Arstream2MaxBitrate:        c.arstream2.MaxBitrate,
+	}
+
+	return json.Marshal(req)
+}
@@ -0,0 +1,121 @@
+package parrotbebop
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeDiscoveryServer will answer one discovery request with resp, and
+// return the port it is listening on.
+func fakeDiscoveryServer(t *testing.T, resp string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		b := make([]byte, 1024)
+		conn.Read(b)
+		conn.Write([]byte(resp))
+	}()
+
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	return port
+}
+
+func TestDiscover(t *testing.T) {
+	d := NewDrone()
+	d.addressDrone = "127.0.0.1"
+	d.portDiscover = fakeDiscoveryServer(t, `{ "status": 0, "c2d_port": 54321, "c2d_update_port": 51, "c2d_user_port": 21, "qos_mode": 0, "arstream2_server_stream_port": 5004, "arstream2_server_control_port": 5006 }`+"\x00")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+
+	if err := d.Discover(ctx); err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	if d.portC2D != "54321" || d.portRTPServerControl != "5006" {
+		t.Fatalf("wrong ports from discovery: %v, %v", d.portC2D, d.portRTPServerControl)
+	}
+
+	want := DiscoveryInfo{
+		C2DPort:              "54321",
+		C2DUpdatePort:        "51",
+		C2DUserPort:          "21",
+		RTPServerStreamPort:  "5004",
+		RTPServerControlPort: "5006",
+	}
+	if got := d.DiscoveryInfo(); got != want {
+		t.Fatalf("wrong discovery info: %+v, want %+v", got, want)
+	}
+}
+
+func TestDiscoverRefused(t *testing.T) {
+	d := NewDrone()
+	d.addressDrone = "127.0.0.1"
+	d.portDiscover = fakeDiscoveryServer(t, `{ "status": -1 }`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+
+	if err := d.Discover(ctx); !errors.Is(err, ErrDiscoveryRefused) {
+		t.Fatalf("expected ErrDiscoveryRefused, got %v", err)
+	}
+}
+
+func TestDiscoveryResponseValidate(t *testing.T) {
+	status := func(v int) *int { return &v }
+
+	tests := []struct {
+		name string
+		resp discoveryResponse
+		want error
+	}{
+		{"busy", discoveryResponse{Status: status(discoveryStatusBusy)}, ErrDroneBusy},
+		{"refused", discoveryResponse{Status: status(-1)}, ErrDiscoveryRefused},
+		{"missing status", discoveryResponse{C2dPort: status(54321)}, nil},
+		{"missing port", discoveryResponse{Status: status(0)}, nil},
+		{"invalid port", discoveryResponse{Status: status(0), C2dPort: status(70000)}, nil},
+	}
+
+	for _, tt := range tests {
+		err := tt.resp.validate()
+		if err == nil {
+			t.Errorf("%v: expected error", tt.name)
+			continue
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%v: expected %v, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestDiscoveryRequest(t *testing.T) {
+	d := NewDrone()
+	if err := d.SetControllerIdentity("phone", "my-controller"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetARStream2Params(ARStream2Params{MaxBitrate: 1500000}); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := d.discoveryRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"controller_type":"phone","controller_name":"my-controller","d2c_port":43210,"arstream2_client_stream_port":55004,"arstream2_client_control_port":55005,"arstream2_max_bitrate":1500000}`
+	if string(b) != want {
+		t.Fatalf("wrong request:\n%s\nwant\n%s", b, want)
+	}
+}
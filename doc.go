@@ -1,3 +1,14 @@
+// Package parrotbebop is a driver for the Parrot Bebop drone, where
+// Drone is the connection with a single drone, created with NewDrone
+// and connected with Start. The command line controller using the
+// package is in cmd/bebop, and there are examples only using the
+// exported API in cmd/keyboard-fly, cmd/waypoint-mission,
+// cmd/video-record and cmd/telemetry-dump. The frames of captured
+// traffic are decoded with cmd/ardecode.
+//
+// The decoding of the ARNetworkAL frames and the ARCommands, without
+// any state of the connection, is in the decoder package, so it can be
+// used and fuzzed without the rest of the driver.
 package parrotbebop
 
 /*
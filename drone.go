@@ -5,6 +5,7 @@ package parrotbebop
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log"
 	"net"
@@ -25,24 +26,36 @@ type Drone struct {
 	portD2C        string
 	portRTPStream  string
 	portRTPControl string
+	// The drone's RTCP control port for the video stream, assigned
+	// via discovery.
+	portRTPServerControl string
+	// The drone's RTP port for the video stream, assigned via discovery.
+	portRTPServerStream string
+	// The drone's ports for firmware updates, and for the user storage
+	// over FTP, assigned via discovery.
+	portC2DUpdate string
+	portC2DUser   string
+	// qosMode is the QoS mode given by the drone via discovery, where
+	// 1 means the packets sent should be marked with a class selector.
+	qosMode int
+	// controllerConfig holds what the controller tells the drone about
+	// itself in the discovery.
+	controllerConfig controllerConfig
+	// discoveryQuirks holds the discovery settings given by the
+	// connection profile.
+	discoveryQuirks discoveryQuirks
 	// Channel to put the raw UDP packages from the drone.
 	chReceivedUDPPacket chan networkUDPPacket
 	// Channel to put the raw UDP packages to be sent to the drone.
 	chSendingUDPPacket chan networkUDPPacket
-	// Channel to put the inputAction type send to the drone when
+	// Channel to put the InputAction type send to the drone when
 	// for example a key is pressed on the keyboard.
-	chInputActions chan inputAction
+	chInputActions chan InputAction
 	// Sending to this channel will quit the controller program.
 	chQuit chan struct{}
 	// Sending to this channel will disconnect all network related
 	// go routines, and then reconnect to the drone.
 	chNetworkConnect chan struct{}
-	// chPcmdPacketScheduler is used to set the frequency of PcmdPacket's
-	// that will be sent from the controller to the drone.
-	// All Pcmd packets from the controller should go through here to not
-	// overwhelm the drone with to many commands which can interupt
-	// other commands.
-	chPcmdPacketScheduler chan networkUDPPacket
 	// The conn object for the UDP network listener
 	connUDPRead net.PacketConn
 	// The conn object for the UDP connection to send commands to
@@ -55,6 +68,147 @@ type Drone struct {
 	// moveToBuffer is a FIFO buffer for storing the gps positions
 	// of the route to fly.
 	moveToBuffer *moveToBuffer
+	// packetCreator is the udpPacketCreator shared by everything sending
+	// to the drone, and it's sequence numbers are reset every time the
+	// network connection is re-initialized. It is created by NewDrone,
+	// and never replaced, so it can be used by the exported API methods
+	// without locking.
+	packetCreator *udpPacketCreator
+	// home holds the home position reported by the drone, and the
+	// preferred home type.
+	home home
+	// telemetry holds the latest state values received from the drone.
+	telemetry telemetry
+	// deadReckoning holds the position estimated from the speed of
+	// the drone when the gps is lost.
+	deadReckoning deadReckoning
+	// gpsGuard holds the gps requirements for starting moveTo missions.
+	gpsGuard gpsGuard
+	// nudgeConfig holds the distance in meters to move for each of the
+	// nudge input actions.
+	nudgeConfig nudgeConfig
+	// legConfig holds the max length in meters of the legs flown by
+	// the moveTo executor, where longer legs are split.
+	legConfig legConfig
+	// arrivalConfig holds when the moveTo executor consider a position
+	// reached.
+	arrivalConfig arrivalConfig
+	// moveToState holds the state of the moveTo executor.
+	moveToState moveToStateHolder
+	// missionStatusPublisher holds when the mission status was last
+	// published.
+	missionStatusPublisher missionStatusPublisher
+	// altitudeHold holds the target altitude for the altitude controller.
+	altitudeHold altitudeHold
+	// events will deliver the events published by the driver to
+	// the subscribers.
+	events eventBus
+	// alertPolicy holds the alerts that should make the drone land.
+	alertPolicy alertPolicy
+	// connectionState holds if the connection with the drone is ready.
+	connectionState connectionState
+	// videoConfig holds the settings for the video stream.
+	videoConfig videoConfig
+	// frames will deliver the H264 video frames from the drone to
+	// the subscribers.
+	frames frameBus
+	// snapshotConfig holds the frame decoder used for snapshots.
+	snapshotConfig snapshotConfig
+	// missionPause holds if the autonomous missions are paused.
+	missionPause missionPause
+	// pcmdConfig holds the interval used by the PCMD scheduler, and
+	// the latest pcmd state to send to the drone.
+	// All Pcmd's from the controller should be given to the scheduler
+	// with setPcmd to not overwhelm the drone with to many commands
+	// which can interupt other commands.
+	pcmdConfig pcmdConfig
+	// netStats holds the ARNetwork statistics for each buffer.
+	netStats netStats
+	// capture will write the traffic with the drone to a pcap file
+	// when started with StartCapture.
+	capture capture
+	// frameDebug will write the annotated frames when enabled with
+	// SetFrameDebug.
+	frameDebug frameDebug
+	// eventJSON will write the commands received as JSON when enabled
+	// with SetEventJSON.
+	eventJSON eventJSON
+	// wifiScan holds the wifi scan results while being received.
+	wifiScan wifiScan
+	// preflight holds the preflight checks to run.
+	preflight preflight
+	// confirmGuard holds the action waiting to be confirmed when the
+	// confirm mode is enabled.
+	confirmGuard confirmGuard
+	// headless is true when the keyboard should not be used.
+	headless bool
+	// dashboard holds the state of the terminal dashboard, and if the
+	// raw debug output should be printed.
+	dashboard dashboard
+	// logThrottle limits how often the commands received from the
+	// drone are logged.
+	logThrottle logThrottle
+	// settings holds the last value of each setting reported by the
+	// drone.
+	settings settingsStore
+	// cmdWaiters holds the callers of SendAndWait waiting for a command
+	// from the drone.
+	cmdWaiters cmdWaiters
+	// supervisor owns the go routines of the current connection with
+	// the drone, and restarts them if they fail.
+	supervisor supervisor
+	// reconnectConfig holds how to retry connecting with the drone.
+	reconnectConfig reconnectConfig
+	// linkWatchdog keeps track of the pings from the drone, to detect
+	// when the link is lost.
+	linkWatchdog linkWatchdog
+	// keepAlive holds when a packet was last sent to the drone, so a
+	// keep alive can be sent when idle.
+	keepAlive keepAlive
+	// cameraVelocity holds the velocity of the camera last sent.
+	cameraVelocity cameraVelocity
+	// flightLog holds the telemetry recorded for each position while
+	// recording a flight log.
+	flightLog flightLog
+	// inputShaping holds the shaping of the stick inputs.
+	inputShaping inputShapingConfig
+	// operators holds which operator owns each of the scopes.
+	operators operatorRegistry
+	// macroRecorder holds the macro of the inputs while recording.
+	macroRecorder macroRecorder
+	// controlAuthority holds if the pilot have taken over the control
+	// from the mission.
+	controlAuthority controlAuthority
+	// directMoveTo holds if FollowMe or Orbit is sending the moveTo
+	// commands to the drone.
+	directMoveTo directMoveTo
+	// rthFallback holds what to do when the drone can't do a return
+	// home, and the fallback running.
+	rthFallback rthFallbackConfig
+	// batteryEstimator holds the battery levels used for estimating the
+	// flight time remaining.
+	batteryEstimator batteryEstimator
+	// batteryGuard holds the check of the missions against the flight
+	// time remaining.
+	batteryGuard batteryGuardConfig
+	// flightSession holds the values of the flight in progress, used
+	// for the flight summary.
+	flightSession flightSession
+	// trafficShaping holds the rate limits of the frames sent to the
+	// drone.
+	trafficShaping trafficShapingConfig
+	// telemetryStreams holds the typed telemetry streams.
+	telemetryStreams telemetryStreams
+	// keyBindings holds the actions of the keys for flying with the
+	// keyboard.
+	keyBindings keyBindings
+	// configWatch holds the settings applied from the config file.
+	configWatch configWatch
+	// altitudeLimits holds the altitude floor and ceiling enforced
+	// on the commands sent.
+	altitudeLimits altitudeLimitsConfig
+	// groundStation holds the token of the ground station API.
+	groundStation groundStationConfig
 }
 
 // TODO:
@@ -79,12 +233,28 @@ func NewDrone() *Drone {
 		portRTPStream:  "55004",
 		portRTPControl: "55005",
 
-		chReceivedUDPPacket:   make(chan networkUDPPacket),
-		chSendingUDPPacket:    make(chan networkUDPPacket),
-		chInputActions:        make(chan inputAction),
-		chQuit:                make(chan struct{}),
-		chNetworkConnect:      make(chan struct{}),
-		chPcmdPacketScheduler: make(chan networkUDPPacket),
+		portRTPServerControl: "5005",
+		portRTPServerStream:  "5004",
+		portC2DUpdate:        "51",
+		portC2DUser:          "21",
+
+		controllerConfig: controllerConfig{
+			controllerType: "computer",
+			controllerName: "go-bebop",
+		},
+
+		pcmdConfig: pcmdConfig{
+			interval: defaultPcmdInterval,
+		},
+		preflight: preflight{
+			config: DefaultPreflightConfig,
+		},
+
+		chReceivedUDPPacket: make(chan networkUDPPacket),
+		chSendingUDPPacket:  make(chan networkUDPPacket),
+		chInputActions:      make(chan InputAction),
+		chQuit:              make(chan struct{}),
+		chNetworkConnect:    make(chan struct{}),
 
 		pcmd: Ardrone3PilotingPCMDArguments{
 			Flag:               0,
@@ -109,9 +279,43 @@ func NewDrone() *Drone {
 			latitudeMoveTo:    500,
 			longitudeMoveTo:   500,
 			altitudeMoveto:    500,
+
+			chMoveToExecute:      make(chan struct{}, 1),
+			chMoveToCancel:       make(chan struct{}, 1),
+			chMoveToPositionDone: make(chan struct{}, 1),
 		},
 
 		moveToBuffer: newMoveToHandler(),
+
+		// Since we need to use individual sequence number counters for each
+		// buffer a udpPacketCreator will keep track of them, and increment
+		// the currect buffer sequence number when a new package are created.
+		// All UDP packet encoding methods are tied to this type.
+		packetCreator: newUdpPacketCreator(),
+
+		// The drone will not send any video unless asked to, so we
+		// enable it by default.
+		videoConfig: videoConfig{
+			enable: true,
+			mode:   VideoStreamLowLatency,
+		},
+
+		nudgeConfig: nudgeConfig{
+			distance: 1,
+		},
+
+		logThrottle: logThrottle{
+			interval: defaultLogThrottle,
+		},
+
+		arrivalConfig: arrivalConfig{
+			settings: arrivalSettings{
+				radius:     defaultArrivalRadius,
+				altitude:   defaultArrivalAltitude,
+				hold:       defaultArrivalHold,
+				legTimeout: defaultArrivalLegTimeout,
+			},
+		},
 	}
 
 	go func() {
@@ -155,11 +359,6 @@ type GPS struct {
 	longitudeMoveTo float64
 	// Altitude height in meters above sea level
 	altitudeMoveto float64
-	// Are the drone currently in a moveTo action ?
-	// This value should be set to true when a moveTo are started,
-	// and it should be set to false when a message from the drone
-	// of type Ardrone3PilotingStatemoveToChanged are received.
-	doingMoveTo bool
 	// Initiate an execution of a moveTo to the next position in buffer.
 	chMoveToExecute chan struct{}
 	// Cancel the execution of a moveTo command
@@ -186,106 +385,277 @@ func (g *GPS) StartReadingPosition() {
 	}
 }
 
-// startMoveToExecutor
-// The plan here is to receive a signal for when to execute a
-// moveTo command to the drone, or to cancel it.
+// moveToState is the state of the moveTo executor.
+type moveToState int
+
+const (
+	// moveToIdle is waiting for a moveTo mission to be started.
+	moveToIdle moveToState = iota
+	// moveToExecuting will send the moveTo for the next leg, pulling
+	// the next waypoint from the buffer when needed.
+	moveToExecuting
+	// moveToWaitingConfirm is waiting for the current leg to be reached.
+	moveToWaitingConfirm
+	// moveToCancelling will cancel the moveTo in progress.
+	moveToCancelling
+)
+
+// String will return the name of the state.
+func (m moveToState) String() string {
+	switch m {
+	case moveToIdle:
+		return "idle"
+	case moveToExecuting:
+		return "executing"
+	case moveToWaitingConfirm:
+		return "waitingConfirm"
+	case moveToCancelling:
+		return "cancelling"
+	}
+
+	return fmt.Sprintf("unknown(%d)", int(m))
+}
+
+// startMoveToExecutor will fly the waypoints in the moveTo buffer when
+// signaled on chMoveToExecute, and stop when signaled on
+// chMoveToCancel, until the context is done.
 //
-// When a moveto signal is reveived we will pull one waypoint
-// at a time from the moveTo buffer, but before pulling a new
-// waypoint we will wait for a positiosChanged command from
-// the drone, since that will indicate that the last moveTo
-// command was executed and done by the drone, and we can pull
-// a new value and send another  moveTo package to the drone.
+// The executor is a single go routine running a state machine, going
+// from idle to executing when started. When executing, the next
+// waypoint is pulled from the buffer one at a time, split into legs,
+// and the moveTo for each leg is sent to the drone before waiting for
+// the leg to be reached. A leg is reached when the drone reports the
+// moveTo as done, or the drone have stayed close to the position. If
+// the leg timeout occurs first the mission is cancelled, and the
+// failure is published. When there are no more waypoints it goes back
+// to idle.
 //
-// When a cancel signal is received we should immediately send
-// a moveTo cancel package to the drone, and also stop any moveTo
-// processes.
+// When cancelled the cancel is sent to the drone, and the waypoint
+// being flown is put back in the buffer, so the mission can be resumed.
+// The executor will not start, or continue with the next leg, while the
+// missions are paused.
 func (d *Drone) startMoveToExecutor(packetCreator *udpPacketCreator, ctx context.Context) {
+	state := moveToIdle
+	// legs are the positions left to fly for the current waypoint.
+	var legs []Position
+	var arrival arrivalDetector
+
+	ticker := time.NewTicker(arrivalCheckInterval)
+	defer ticker.Stop()
+	legTimer := time.NewTimer(time.Hour)
+	legTimer.Stop()
+	defer legTimer.Stop()
+	// stopLegTimer will stop the leg timer, and drop a timeout that
+	// already fired, so it is not taken as the timeout of the next leg.
+	stopLegTimer := func() {
+		if !legTimer.Stop() {
+			select {
+			case <-legTimer.C:
+			default:
+			}
+		}
+	}
+
+	send := func(c Command, arg Encoder) bool {
+		select {
+		case d.chSendingUDPPacket <- packetCreator.encodeCmd(c, arg):
+			d.publishMoveTo(arg)
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	setState := func(s moveToState) {
+		if s != state {
+			log.Printf("info: moveTo executor: %v -> %v\n", state, s)
+		}
+		state = s
+		d.moveToState.set(s)
+		if s == moveToIdle {
+			d.publishMissionStatus(time.Now())
+		}
+	}
+
 	for {
-		<-d.gps.chMoveToExecute
-		ctx, cancel := context.WithCancel(ctx)
-		var wg sync.WaitGroup
-		wg.Add(1)
-
-		go func(ctx context.Context) {
-			for {
-
-				ticker := time.NewTicker(time.Second * 5)
-
-				select {
-				case <-ctx.Done():
-					return
-				case <-d.gps.chMoveToCancel:
-					p := packetCreator.encodeCmd(Command(PilotingCancelMoveTo), &Ardrone3PilotingCancelMoveToArguments{})
-					d.chSendingUDPPacket <- p
-					wg.Done()
-				case wp := <-d.moveToBuffer.chNewWayPointOut:
-					// Get a new wp, create the argument, and send the udp packet.
-					arg := &Ardrone3PilotingmoveToArguments{
-						Latitude:  wp.latitude,
-						Longitude: wp.longitude,
-						Altitude:  wp.altitude,
-					}
+		switch state {
+		case moveToExecuting:
+			// A pause that happened while waiting for the leg to be
+			// reached stops the mission before the next leg is sent.
+			if d.MissionPaused() {
+				log.Printf("warning: moveTo executor: missions paused, stopping\n")
+				setState(moveToCancelling)
+				continue
+			}
 
-					p := packetCreator.encodeCmd(Command(PilotingmoveTo), arg)
-					d.chSendingUDPPacket <- p
-
-					// Check if the waypoint was reached, and we got a confirmation
-					// from the drone. If a waypoint is not received we break out,
-					// loop and pick a new waypoint.
-					select {
-					case <-d.gps.chMoveToPositionDone:
-						log.Printf("moveToPositionDone received, breaking out and looping")
-						break
-					case <-ticker.C:
-						log.Printf("moveToPositionDone not received, ticker occured, looping")
-						break
-					}
+			if len(legs) == 0 {
+				// The waypoints are pulled from the buffer one at a
+				// time when they are flown, so the waypoints not yet
+				// flown can still be edited.
+				wp, err := d.moveToBuffer.pullWayPointNext()
+				if err != nil {
+					log.Printf("info: no more waypoints in moveTo buffer\n")
+					setState(moveToIdle)
+					continue
+				}
 
+				// Long legs are split into shorter legs if a max leg
+				// length is set, which is only possible when the
+				// current position is known.
+				legs = []Position{wp.toPosition()}
+				if from := d.Telemetry().Position; validGPSPosition(from) {
+					legs = SplitLeg(from, wp.toPosition(), d.legConfig.get())
 				}
 			}
-		}(ctx)
 
-		wg.Wait()
-		cancel()
+			// Drop a done reported by the drone for an earlier moveTo.
+			select {
+			case <-d.gps.chMoveToPositionDone:
+			default:
+			}
+
+			leg := legs[0]
+			arg := &Ardrone3PilotingmoveToArguments{
+				Latitude:  leg.Latitude,
+				Longitude: leg.Longitude,
+				Altitude:  leg.Altitude,
+			}
+			d.limitMoveTo(arg)
+			if !send(Command(PilotingmoveTo), arg) {
+				continue
+			}
+
+			arrival = arrivalDetector{conf: d.arrivalConfig.get(), target: leg}
+			legTimer.Reset(arrival.conf.legTimeout)
+			setState(moveToWaitingConfirm)
+			continue
+		case moveToCancelling:
+			stopLegTimer()
+			legs = nil
+			if !send(Command(PilotingCancelMoveTo), &Ardrone3PilotingCancelMoveToArguments{}) {
+				continue
+			}
+			d.moveToBuffer.requeueCurrent()
+			setState(moveToIdle)
+			continue
+		}
 
+		// legReached will continue with the next leg, or the next
+		// waypoint if all the legs of the waypoint are flown.
+		legReached := func() {
+			stopLegTimer()
+			legs = legs[1:]
+			if len(legs) == 0 {
+				d.moveToBuffer.confirmCurrent()
+			}
+			setState(moveToExecuting)
+		}
+
+		select {
+		case <-ctx.Done():
+			// The connection is gone, so the waypoint being flown is
+			// put back in the buffer to be flown again when resumed.
+			if state != moveToIdle {
+				d.moveToBuffer.requeueCurrent()
+			}
+			d.moveToState.set(moveToIdle)
+			return
+		case <-d.gps.chMoveToExecute:
+			if state == moveToIdle {
+				if d.MissionPaused() {
+					log.Printf("warning: moveTo executor: refused to start, missions paused\n")
+					continue
+				}
+				// Start while holding the lock of FollowMe and Orbit,
+				// so they can't claim the drone at the same time.
+				d.directMoveTo.mu.Lock()
+				owner := d.directMoveTo.owner
+				if owner == "" {
+					setState(moveToExecuting)
+				}
+				d.directMoveTo.mu.Unlock()
+				if owner != "" {
+					log.Printf("warning: moveTo executor: refused to start, %v is flying the drone\n", owner)
+				}
+			}
+		case <-d.gps.chMoveToCancel:
+			if state != moveToIdle {
+				setState(moveToCancelling)
+			}
+		case <-d.gps.chMoveToPositionDone:
+			if state == moveToWaitingConfirm {
+				log.Printf("info: moveTo done reported by the drone\n")
+				legReached()
+			}
+		case now := <-ticker.C:
+			if state == moveToWaitingConfirm && arrival.reached(d.Telemetry(), now) {
+				log.Printf("info: moveTo position reached\n")
+				legReached()
+			}
+		case <-legTimer.C:
+			if state == moveToWaitingConfirm {
+				log.Printf("error: moveTo position not reached within %v, stopping the mission\n", arrival.conf.legTimeout)
+				d.events.publishPriority(EventMoveToFailed, PriorityHigh, arrival.target)
+				setState(moveToCancelling)
+			}
+		}
+	}
+}
+
+// moveToStateHolder holds the state of the moveTo executor, which is
+// read by the API.
+type moveToStateHolder struct {
+	mu    sync.Mutex
+	state moveToState
+}
+
+// set will set the state.
+func (m *moveToStateHolder) set(s moveToState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.state = s
+}
+
+// MoveToActive will return true while the moveTo executor is flying the
+// waypoints in the moveTo buffer.
+func (d *Drone) MoveToActive() bool {
+	d.moveToState.mu.Lock()
+	defer d.moveToState.mu.Unlock()
+
+	return d.moveToState.state != moveToIdle
+}
+
+// executeMoveTo will start the moveTo executor flying the waypoints in
+// the moveTo buffer, and return an error instead if the missions are
+// paused, FollowMe or Orbit is running, or the gps or battery guard
+// refuses the mission.
+func (d *Drone) executeMoveTo() error {
+	if d.MissionPaused() {
+		return fmt.Errorf("missions paused, resume with ResumeMission")
+	}
+	if owner := d.directMoveTo.get(); owner != "" {
+		return fmt.Errorf("%v is flying the drone", owner)
+	}
+	if err := d.checkGPSGuard(); err != nil {
+		return err
+	}
+	f, err := d.MoveToFeasibility()
+	if err := d.checkBatteryGuard("moveTo mission", f, err); err != nil {
+		return err
 	}
 
-	// for {
-	// 	wp, err := d.moveToBuffer.pullWayPointNext()
-	// 	if err != nil {
-	// 		log.Printf("info: moveToBufferEmpty, breaking out\n")
-	// 		break
-	// 	}
-	//
-	// 	arg := &Ardrone3PilotingmoveToArguments{
-	// 		Latitude:  wp.latitude,
-	// 		Longitude: wp.longitude,
-	// 		Altitude:  wp.altitude,
-	// 	}
-	//
-	// 	p := packetCreator.encodeCmd(Command(PilotingmoveTo), arg)
-	// 	d.chSendingUDPPacket <- p
-	// }
-
-	//------------------------------------------
-
-	// for {
-	// 	select {
-	// 	case <-d.gps.chMoveToCancel:
-	//
-	// 		p := packetCreator.encodeCmd(Command(PilotingCancelMoveTo), &// Ardrone3PilotingCancelMoveToArguments{})
-	// 		d.chSendingUDPPacket <- p
-	// 		log.Printf("*************************************************************\n")
-	// 		log.Printf("startMoveToExecutor: chMoveToCancel received\n")
-	// 		log.Printf("*************************************************************\n")
-	// 	case <-d.gps.chMoveToExecute:
-	// 		// TODO:
-	// 		log.Printf("*************************************************************\n")
-	// 		log.Printf("startMoveToExecutor: chMoveToExecute received\n")
-	// 		log.Printf("*************************************************************\n")
-	// 	}
-	// }
+	signalMoveTo(d.gps.chMoveToExecute)
+	return nil
+}
+
+// signalMoveTo will signal the moveTo executor on the channel given
+// without blocking, where a signal already waiting to be handled is
+// not repeated.
+func signalMoveTo(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
 }
 
 // --------------------------------------------------------------------
@@ -293,10 +663,19 @@ func (d *Drone) startMoveToExecutor(packetCreator *udpPacketCreator, ctx context
 // moveToBuffer holds the buffer of all the waypoints
 // and the logic to receive, push and pull waypoints.
 type moveToBuffer struct {
+	// mu protects the waypoints, which are accessed both by the
+	// buffer go routines and the API.
+	mu sync.Mutex
 	// all the waypoints registered
-	waypoints        []gpsLatLonAlt
-	chNewWayPointIn  chan gpsLatLonAlt
-	chNewWayPointOut chan gpsLatLonAlt
+	waypoints       []gpsLatLonAlt
+	chNewWayPointIn chan gpsLatLonAlt
+	// current is the waypoint being flown, and completed is the number
+	// of waypoints flown.
+	current   *gpsLatLonAlt
+	completed int
+	updated   time.Time
+	// missionFile is the file the progress is persisted to if set.
+	missionFile string
 }
 
 // newmoveToBuffer is a push/pop storage for values.
@@ -310,23 +689,6 @@ func newMoveToHandler() *moveToBuffer {
 	// to the moveTo buffer
 	go b.startWayPointReceiver()
 
-	go func() {
-		for {
-			wp, err := b.pullWayPointNext()
-			if err != nil {
-				log.Printf("info: no way point in buffer, waiting 1 sec, and continue\n")
-				time.Sleep(time.Second * 1)
-				continue
-			}
-
-			// TODO: Might need to add a select with default here
-			// incase the channel is not listening
-			// or..maybe not since that would cause the wp to be dropped.
-			// Need to check this out.
-			b.chNewWayPointOut <- wp
-		}
-	}()
-
 	return &b
 }
 
@@ -340,12 +702,8 @@ func (s *moveToBuffer) startWayPointReceiver() {
 		// Check if the values are to big, which means no GPS connection
 		// where available for calculation, and drop the data if it is
 		// an not allowed value
-		switch {
-		case wp.latitude > 91 || wp.latitude < -91:
-			log.Printf("moveToBuffer: not allowed value received: %v\n", wp)
-			continue
-		case wp.longitude > 181 || wp.longitude < -181:
-			log.Printf("moveToBuffer: not allowed value received: %v\n", wp)
+		if err := validWayPoint(wp); err != nil {
+			log.Printf("moveToBuffer: not allowed value received: %v: %v\n", wp, err)
 			continue
 		}
 		s.pushWayPointNew(wp)
@@ -354,61 +712,119 @@ func (s *moveToBuffer) startWayPointReceiver() {
 
 // push will add another item to the end of the buffer with a normal append
 func (s *moveToBuffer) pushWayPointNew(d gpsLatLonAlt) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	s.waypoints = append(s.waypoints, d)
+	s.changedLocked()
+}
+
+// list will return a copy of the waypoints in the buffer.
+func (s *moveToBuffer) list() []gpsLatLonAlt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]gpsLatLonAlt(nil), s.waypoints...)
+}
+
+// Waypoints will return the waypoints currently in the moveTo buffer,
+// in the order they will be flown.
+func (d *Drone) Waypoints() []Position {
+	var wps []Position
+	for _, wp := range d.moveToBuffer.list() {
+		wps = append(wps, wp.toPosition())
+	}
+
+	return wps
 }
 
 // pop will remove and return the first element of the buffer,
 // and will return io.EOF if buffer is empty.
 func (s *moveToBuffer) pullWayPointNext() (gpsLatLonAlt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if len(s.waypoints) == 0 {
 		return gpsLatLonAlt{}, io.EOF
 	}
 
 	v := s.waypoints[0]
 	s.waypoints = append(s.waypoints[0:0], s.waypoints[1:]...)
+	s.current = &v
+	s.changedLocked()
 
 	return v, nil
 }
 
-func (d *Drone) Start() {
+// Start will connect with the drone, and reconnect when the connection
+// is lost. It only returns if all the attempts to connect failed, and
+// the ReconnectPolicy says to give up.
+func (d *Drone) Start() error {
 	// Check for keyboard press, and generate appropriate inputActions's.
-	go d.readKeyBoardEvent()
+	if !d.headless {
+		go d.readKeyBoardEvent()
+	}
 
 	// Start handling incomming gps packages, and fill the registers with
 	// the current location values.
 	go d.gps.StartReadingPosition()
 
+	packetCreator := d.packetCreator
+
+	// returnHomeSent is true when the ReconnectReturnHome action have
+	// been done for the current loss of connection.
+	var returnHomeSent bool
+
 	for {
 		var err error
 
-		// Since we need to use individual sequence number counters for each
-		// buffer a udpPacketCreator will keep track of them, and increment
-		// the currect buffer sequence number when a new package are created.
-		// All UDP packet encoding methods are tied to this type.
-		packetCreator := newUdpPacketCreator()
-
 		ctxBg := context.Background()
 		ctx, cancel := context.WithCancel(ctxBg)
 
-		// Will handle all the events generated by input actions from keyboard etc.
-		go d.handleInputAction(*packetCreator, ctx)
+		// All the go routines of the connection are started by the
+		// supervisor, which will restart them if they fail, and report
+		// their health with Drone.Health.
+		d.supervisor.reset()
+		d.supervisor.reconnect = d.requestReconnect
 
-		// Initialize the network connection to the drone.
-		// If the connection fails retry 20 times before giving up.
-		//
-		// TODO:
-		// Make it call return-home if unable to initialize.
+		// Will handle all the events generated by input actions from keyboard etc.
+		d.supervisor.start(ctx, "input actions", RestartOnFailure, func(ctx context.Context) error {
+			d.handleInputAction(packetCreator, ctx)
+			return nil
+		})
+
+		// Initialize the network connection to the drone, where the
+		// ReconnectPolicy decides how many times to retry, and what to do
+		// if all the attempts fail.
 		log.Println("Initializing the traffic with the drone, and starting controller UDP listener.")
-		for i := 0; i < 20; i++ {
-			err := d.Discover()
-			if err != nil {
-				log.Printf("error: client Discover failed: %v\n", err)
-				time.Sleep(time.Second * 2)
-				continue
+		if err := d.Discover(ctx); err != nil {
+			log.Printf("error: client Discover failed: %v\n", err)
+			cancel()
+
+			policy := d.reconnectConfig.get()
+			switch policy.OnFailure {
+			case ReconnectGiveUp:
+				d.publishConnection(ConnectionEvent{State: ConnectionGaveUp, Err: err})
+				return fmt.Errorf("Start: %w", err)
+			case ReconnectReturnHome:
+				if !returnHomeSent {
+					if err := d.returnHomeDirect(); err != nil {
+						log.Printf("error: %v\n", err)
+					}
+					returnHomeSent = true
+				}
 			}
 
-			break
+			time.Sleep(policy.MaxBackoff)
+			continue
 		}
+		returnHomeSent = false
+
+		// The drone starts on new sequence numbers for each connection,
+		// and so do we.
+		d.netStats.resetSequences()
+		packetCreator.reset()
+		d.linkWatchdog.reset(time.Now())
 
 		// create an 'empty' UDP listener.
 		d.connUDPRead, err = net.ListenPacket("udp", ":"+d.portD2C)
@@ -418,7 +834,10 @@ func (d *Drone) Start() {
 
 		// Start the reading of whole UDP packets from the network,
 		// and put them on the Drone.chReceivedUDPPacket channel.
-		go d.readNetworkUDPPacketsD2C(ctx)
+		d.supervisor.start(ctx, "udp reader", RestartConnection, func(ctx context.Context) error {
+			d.readNetworkUDPPacketsD2C(ctx)
+			return nil
+		})
 
 		// Prepare and dial the UDP connection from controller to drone.
 		udpAddr, err := net.ResolveUDPAddr("udp", d.addressDrone+":"+d.portC2D)
@@ -428,25 +847,78 @@ func (d *Drone) Start() {
 		d.connUDPWrite, err = net.DialUDP("udp", nil, udpAddr)
 		if err != nil {
 			log.Printf("error: failed to DialUDP: %v", err)
+		} else {
+			d.capture.setLocalIP(d.connUDPWrite.LocalAddr().(*net.UDPAddr).IP)
+		}
+
+		// Start receiving the video stream, and the control channel
+		// for reporting lost video packets to the drone.
+		if err := d.startARStream(ctx); err != nil {
+			log.Printf("error: %v\n", err)
 		}
 
-		// Start the scheduler which will make sure that if there are
-		// Pcmd packets to be sent, they are only sent at a fixed 50
-		// milli second interval.
-		go d.PcmdPacketScheduler(ctx)
+		// Start the scheduler which will send the current Pcmd state at
+		// the interval set with SetPcmdInterval.
+		d.supervisor.start(ctx, "pcmd scheduler", RestartOnFailure, func(ctx context.Context) error {
+			d.PcmdPacketScheduler(ctx)
+			return nil
+		})
 
 		// Start the sender of UDP packets,
 		// will send UDP packets received at the Drone.chSendingUDPPacket
 		// channel.
-		go d.writeNetworkUDPPacketsC2D(ctx)
-
-		go d.handleReadPackages(packetCreator, ctx)
-
-		go d.startMoveToExecutor(packetCreator, ctx)
+		d.supervisor.start(ctx, "udp writer", RestartConnection, func(ctx context.Context) error {
+			d.writeNetworkUDPPacketsC2D(ctx)
+			return nil
+		})
+
+		d.supervisor.start(ctx, "packet handler", RestartOnFailure, func(ctx context.Context) error {
+			return d.handleReadPackages(packetCreator, ctx)
+		})
+
+		// Keep the path to the drone open when nothing else is sent.
+		d.supervisor.start(ctx, "keep alive", RestartOnFailure, func(ctx context.Context) error {
+			d.startKeepAlive(ctx)
+			return nil
+		})
+
+		// Re-initialize the connection if the drone stops sending pings.
+		d.supervisor.start(ctx, "link watchdog", RestartOnFailure, func(ctx context.Context) error {
+			d.startLinkWatchdog(ctx)
+			return nil
+		})
+
+		d.supervisor.start(ctx, "moveTo executor", RestartOnFailure, func(ctx context.Context) error {
+			d.startMoveToExecutor(packetCreator, ctx)
+			return nil
+		})
+
+		// Start the altitude controller which will adjust the Gaz if
+		// a target altitude is set with SetTargetAltitude.
+		d.supervisor.start(ctx, "altitude controller", RestartOnFailure, func(ctx context.Context) error {
+			d.startAltitudeController(ctx)
+			return nil
+		})
+
+		// Give the drone the current date and time, and then ask the
+		// drone for a full snapshot of all it's states and settings,
+		// and set the connection to ready when received.
+		d.supervisor.start(ctx, "state sync", RestartNever, func(ctx context.Context) error {
+			if err := d.syncDateTime(time.Now()); err != nil {
+				log.Printf("error: %v\n", err)
+			}
+			if err := d.startVideoStream(); err != nil {
+				log.Printf("error: %v\n", err)
+			}
+			return d.syncAllStates(ctx)
+		})
 
 		// Wait here until receiving on quit channel. Trigger by pressing
 		// 'q' on the keyboard.
 		<-d.chNetworkConnect
+		d.setReady(false)
+		d.publishConnection(ConnectionEvent{State: ConnectionLost})
+		d.endFlightSession(time.Now(), "connection lost")
 		cancel()
 		time.Sleep(time.Second * 3)
 		continue
@@ -0,0 +1,106 @@
+package parrotbebop
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// The functions in this file encodes the ARNetworkAL frames and the
+// ARCommands they carry to raw bytes, and are the opposite of the ones
+// in decoder.go. They can be used to build the frames of commands not
+// yet wrapped by the API, or to create traffic for testing.
+
+// The ARNetwork buffers the commands can be sent on with SendRawCommand.
+const (
+	// BufferNonAck is for periodic commands like piloting and camera
+	// orientation, where a lost frame is replaced by the next.
+	BufferNonAck = bufferC2DNonAck
+	// BufferAck is for events and settings, which are acked by the
+	// drone.
+	BufferAck = bufferC2DAck
+	// BufferEmergency is for the emergency command only.
+	BufferEmergency = bufferC2DEmergency
+)
+
+// RawArguments are the already encoded arguments of a command, which
+// can be used with EncodeCommand, SendCommand or SendAndWait for the
+// commands without generated arguments, or to send malformed arguments
+// when testing. To also choose the buffer use SendRawCommand.
+type RawArguments []byte
+
+// Encode will return the arguments as they are.
+func (r RawArguments) Encode() []byte {
+	return r
+}
+
+// EncodeFrame will encode the ARNetworkAL frame with it's header, where
+// the size in the header is calculated from the data. The data type,
+// buffer ID and sequence number are truncated to a byte.
+func EncodeFrame(f Frame) []byte {
+	b := make([]byte, frameHeaderSize, f.Size())
+	b[0] = uint8(f.DataType)
+	b[1] = uint8(f.BufferID)
+	b[2] = uint8(f.Sequence)
+	binary.LittleEndian.PutUint32(b[3:7], uint32(f.Size()))
+
+	return append(b, f.Data...)
+}
+
+// EncodeCommand will encode the ARCommand with it's arguments, to be
+// used as the data of a frame. The arguments can be nil for commands
+// without arguments.
+func EncodeCommand(c Command, args Encoder) []byte {
+	b := make([]byte, cmdHeaderSize)
+	b[0] = uint8(c.Project)
+	b[1] = uint8(c.Class)
+	// The command ID is 2 bytes little endian.
+	binary.LittleEndian.PutUint16(b[2:4], uint16(c.Cmd))
+
+	if args == nil {
+		return b
+	}
+
+	return append(b, args.Encode()...)
+}
+
+// SendCommand will send the command with the arguments to the drone on
+// the buffer used for the kind of command, the same way as the commands
+// sent by the driver. Any command can be sent, also the ones not wrapped
+// by the API, where the arguments can be given as RawArguments.
+func (d *Drone) SendCommand(c Command, args Encoder) error {
+	if args == nil {
+		args = RawArguments(nil)
+	}
+	if err := d.sendCmd(c, args); err != nil {
+		return fmt.Errorf("SendCommand: %v", err)
+	}
+
+	return nil
+}
+
+// SendRawCommand will send the command with the project, class and
+// command ID's given, and the arguments already encoded, on the buffer
+// given, which must be one of BufferNonAck, BufferAck or
+// BufferEmergency. Nothing is checked about the command, so it can be
+// used for the commands missing from the generated ones, where the
+// ID's and the encoding of the arguments are found in the XML files of
+// the ARSDK. The frame is sent as it is, and is never coalesced with
+// other frames of the same command by the traffic shaping.
+func (d *Drone) SendRawCommand(bufferID int, project ProjectDef, class ClassDef, cmd CmdDef, args []byte) error {
+	switch bufferID {
+	case BufferNonAck, BufferAck, BufferEmergency:
+	default:
+		return fmt.Errorf("SendRawCommand: buffer %v is not a buffer for commands", bufferID)
+	}
+	c := Command{Project: project, Class: class, Cmd: cmd}
+	p := d.packetCreator.encodeFrame(networkBuffers[bufferID], EncodeCommand(c, RawArguments(args)))
+	p.raw = true
+
+	select {
+	case d.chSendingUDPPacket <- p:
+		return nil
+	case <-time.After(time.Second * 2):
+		return fmt.Errorf("SendRawCommand: timed out waiting for the UDP sender, command %#v", c)
+	}
+}
@@ -0,0 +1,97 @@
+package parrotbebop
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeFrameRoundTrip(t *testing.T) {
+	args := &Ardrone3PilotingPCMDArguments{Flag: 1, Roll: -10, Pitch: 20, Gaz: 5}
+	f := Frame{
+		DataType: dataTypeData,
+		BufferID: bufferC2DNonAck,
+		Sequence: 3,
+		Data:     EncodeCommand(Command(PilotingPCMD), args),
+	}
+	b := EncodeFrame(f)
+
+	// Must be the same as the frames sent by the driver.
+	u := newUdpPacketCreator()
+	u.sequenceNR[bufferC2DNonAck] = 3
+	if want := u.encodeCmd(Command(PilotingPCMD), args).data; !bytes.Equal(b, want) {
+		t.Fatalf("got frame %v, want %v", b, want)
+	}
+
+	got, rest, err := DecodeFrame(b)
+	if err != nil || len(rest) != 0 {
+		t.Fatalf("DecodeFrame: %v, %v bytes left", err, len(rest))
+	}
+	if got.DataType != f.DataType || got.BufferID != f.BufferID || got.Sequence != f.Sequence || !bytes.Equal(got.Data, f.Data) {
+		t.Fatalf("got frame %+v, want %+v", got, f)
+	}
+
+	c, v, err := DecodeCommand(got.Data)
+	if err != nil {
+		t.Fatalf("DecodeCommand: %v", err)
+	}
+	if c != Command(PilotingPCMD) {
+		t.Fatalf("wrong command: %+v", c)
+	}
+	if a, ok := v.(Ardrone3PilotingPCMDArguments); !ok || a != *args {
+		t.Fatalf("got arguments %+v, want %+v", v, *args)
+	}
+}
+
+func TestEncodeCommandRaw(t *testing.T) {
+	c := Command{Project: 0xfe, Class: 1, Cmd: 0x0102}
+	got := EncodeCommand(c, RawArguments{9, 8})
+	if want := []byte{0xfe, 1, 2, 1, 9, 8}; !bytes.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got := EncodeCommand(c, nil); len(got) != cmdHeaderSize {
+		t.Fatalf("got %v bytes without arguments, want %v", len(got), cmdHeaderSize)
+	}
+}
+
+func TestSendCommand(t *testing.T) {
+	d := NewDrone()
+	go func() {
+		if err := d.SendCommand(Command{Project: 0xfe, Class: 1, Cmd: 2}, RawArguments{7}); err != nil {
+			t.Errorf("SendCommand: %v", err)
+		}
+	}()
+
+	p := <-d.chSendingUDPPacket
+	f, _, err := DecodeFrame(p.data)
+	if err != nil {
+		t.Fatalf("DecodeFrame: %v", err)
+	}
+	if want := []byte{0xfe, 1, 2, 0, 7}; !bytes.Equal(f.Data, want) {
+		t.Fatalf("got data %v, want %v", f.Data, want)
+	}
+}
+
+func TestSendRawCommand(t *testing.T) {
+	d := NewDrone()
+	if err := d.SendRawCommand(bufferD2CEvents, 1, 2, 3, nil); err == nil {
+		t.Fatalf("expected error for a buffer not for commands")
+	}
+
+	go func() {
+		if err := d.SendRawCommand(BufferNonAck, 0xfe, 1, 0x0203, []byte{7, 8}); err != nil {
+			t.Errorf("SendRawCommand: %v", err)
+		}
+	}()
+
+	p := <-d.chSendingUDPPacket
+	f, _, err := DecodeFrame(p.data)
+	if err != nil {
+		t.Fatalf("DecodeFrame: %v", err)
+	}
+	if f.BufferID != BufferNonAck || f.DataType != dataTypeData {
+		t.Fatalf("sent on buffer %v with data type %v, want %v and %v", f.BufferID, f.DataType, BufferNonAck, dataTypeData)
+	}
+	if want := []byte{0xfe, 1, 3, 2, 7, 8}; !bytes.Equal(f.Data, want) {
+		t.Fatalf("got data %v, want %v", f.Data, want)
+	}
+}
@@ -0,0 +1,170 @@
+package parrotbebop
+
+import (
+	"fmt"
+)
+
+// This file holds the enum types for the arguments of the ARSDK
+// commands. The generated argument structs are using the raw integer
+// types from the protocol, so the values should be converted to the
+// enum types below when used, like FlipDirection(arg.Direction).
+// The enums for flying state, alert state and home type can be found
+// in their own files together with the logic using them.
+
+// FlipDirection is the direction of a flip animation.
+type FlipDirection uint32
+
+const (
+	FlipFront FlipDirection = 0
+	FlipBack  FlipDirection = 1
+	FlipRight FlipDirection = 2
+	FlipLeft  FlipDirection = 3
+)
+
+// String will return the name of the flip direction.
+func (f FlipDirection) String() string {
+	switch f {
+	case FlipFront:
+		return "front"
+	case FlipBack:
+		return "back"
+	case FlipRight:
+		return "right"
+	case FlipLeft:
+		return "left"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(f))
+}
+
+// MoveByError is why a relative move ended, as reported by the drone.
+type MoveByError uint32
+
+const (
+	MoveByOK           MoveByError = 0
+	MoveByUnknown      MoveByError = 1
+	MoveByBusy         MoveByError = 2
+	MoveByNotAvailable MoveByError = 3
+	MoveByInterrupted  MoveByError = 4
+)
+
+// String will return the name of the move by error.
+func (m MoveByError) String() string {
+	switch m {
+	case MoveByOK:
+		return "ok"
+	case MoveByUnknown:
+		return "unknown"
+	case MoveByBusy:
+		return "busy"
+	case MoveByNotAvailable:
+		return "not_available"
+	case MoveByInterrupted:
+		return "interrupted"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(m))
+}
+
+// MoveToOrientationMode is how the drone should orientate itself
+// while doing a moveTo.
+type MoveToOrientationMode uint32
+
+const (
+	// MoveToOrientationNone will not change the heading of the drone.
+	MoveToOrientationNone MoveToOrientationMode = 0
+	// MoveToOrientationToTarget will make the drone face the target
+	// position while moving.
+	MoveToOrientationToTarget MoveToOrientationMode = 1
+	// MoveToOrientationHeadingStart will make the drone turn to the
+	// given heading before moving.
+	MoveToOrientationHeadingStart MoveToOrientationMode = 2
+	// MoveToOrientationHeadingDuring will make the drone turn to the
+	// given heading while moving.
+	MoveToOrientationHeadingDuring MoveToOrientationMode = 3
+)
+
+// String will return the name of the orientation mode.
+func (m MoveToOrientationMode) String() string {
+	switch m {
+	case MoveToOrientationNone:
+		return "none"
+	case MoveToOrientationToTarget:
+		return "to_target"
+	case MoveToOrientationHeadingStart:
+		return "heading_start"
+	case MoveToOrientationHeadingDuring:
+		return "heading_during"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(m))
+}
+
+// MoveToStatus is the status of a moveTo reported by the drone.
+type MoveToStatus uint32
+
+const (
+	MoveToRunning  MoveToStatus = 0
+	MoveToDone     MoveToStatus = 1
+	MoveToCanceled MoveToStatus = 2
+	MoveToError    MoveToStatus = 3
+)
+
+// String will return the name of the moveTo status.
+func (m MoveToStatus) String() string {
+	switch m {
+	case MoveToRunning:
+		return "running"
+	case MoveToDone:
+		return "done"
+	case MoveToCanceled:
+		return "canceled"
+	case MoveToError:
+		return "error"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(m))
+}
+
+// NavigateHomeState is the state of a return home reported by the drone.
+type NavigateHomeState uint32
+
+const (
+	NavigateHomeAvailable   NavigateHomeState = 0
+	NavigateHomeInProgress  NavigateHomeState = 1
+	NavigateHomeUnavailable NavigateHomeState = 2
+	NavigateHomePending     NavigateHomeState = 3
+)
+
+// String will return the name of the return home state.
+func (n NavigateHomeState) String() string {
+	switch n {
+	case NavigateHomeAvailable:
+		return "available"
+	case NavigateHomeInProgress:
+		return "inProgress"
+	case NavigateHomeUnavailable:
+		return "unavailable"
+	case NavigateHomePending:
+		return "pending"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(n))
+}
+
+// Flip will make the drone do a flip in the direction given. With the
+// confirm mode enabled the flip is only done when confirmed, and an
+// error is returned until then.
+func (d *Drone) Flip(dir FlipDirection) error {
+	if dir > FlipLeft {
+		return fmt.Errorf("Flip: unknown direction: %v", dir)
+	}
+	if err := d.checkFlyingStateFor(ActionFlip); err != nil {
+		return fmt.Errorf("Flip: %v", err)
+	}
+	if !d.confirmFlip(ActionFlip, dir) {
+		return fmt.Errorf("Flip: %v flip needs confirmation, call Flip again or Confirm", dir)
+	}
+
+	return d.sendCmd(Command(AnimationsFlip), &Ardrone3AnimationsFlipArguments{Direction: uint32(dir)})
+}
@@ -0,0 +1,66 @@
+package parrotbebop
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// CommandRecord is a command received from the drone, as written by
+// SetEventJSON.
+type CommandRecord struct {
+	Time time.Time `json:"time"`
+	// Command is the name of the command, like
+	// "ardrone3.PilotingState.AttitudeChanged".
+	Command string `json:"command"`
+	// Arguments are the decoded arguments of the command, with the
+	// names from the generated command tables.
+	Arguments interface{} `json:"arguments,omitempty"`
+}
+
+// eventJSON will write the commands received as JSON when a writer is
+// set.
+type eventJSON struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	// failed is true when the last write failed, so the error is only
+	// logged once until the writing works again.
+	failed bool
+}
+
+// SetEventJSON will make the driver write every command received from
+// the drone to w as a JSON object per line, with the time received,
+// the name of the command and the decoded arguments, so the telemetry
+// can be piped into other tools like jq. Setting w to nil will disable
+// it.
+func (d *Drone) SetEventJSON(w io.Writer) {
+	d.eventJSON.mu.Lock()
+	defer d.eventJSON.mu.Unlock()
+
+	d.eventJSON.enc = nil
+	d.eventJSON.failed = false
+	if w != nil {
+		d.eventJSON.enc = json.NewEncoder(w)
+	}
+}
+
+// record will write the command received if a writer is set.
+func (e *eventJSON) record(c Command, args interface{}, now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.enc == nil {
+		return
+	}
+
+	err := e.enc.Encode(CommandRecord{Time: now, Command: commandName(c), Arguments: args})
+	switch {
+	case err != nil && !e.failed:
+		log.Printf("error: writing event JSON: %v\n", err)
+		e.failed = true
+	case err == nil:
+		e.failed = false
+	}
+}
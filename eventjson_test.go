@@ -0,0 +1,56 @@
+package parrotbebop
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEventJSON(t *testing.T) {
+	d := NewDrone()
+
+	// Nothing is written before a writer is set.
+	d.eventJSON.record(Command(PilotingStateFlyingStateChanged), nil, time.Now())
+
+	var buf bytes.Buffer
+	d.SetEventJSON(&buf)
+
+	data := convertCMDToBytes(Command(PilotingStateFlyingStateChanged))
+	data = append(data, 2, 0, 0, 0)
+	c, args, err := DecodeCommand(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	d.eventJSON.record(c, args, now)
+	d.eventJSON.record(c, args, now)
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("got %v lines, want 2: %s", len(lines), buf.Bytes())
+	}
+
+	var r struct {
+		Time      time.Time
+		Command   string
+		Arguments map[string]interface{}
+	}
+	if err := json.Unmarshal(lines[0], &r); err != nil {
+		t.Fatal(err)
+	}
+	if !r.Time.Equal(now) || r.Command != "ardrone3.PilotingState.FlyingStateChanged" || r.Arguments["State"] != float64(2) {
+		t.Fatalf("got %+v", r)
+	}
+
+	// A failing writer should not stop the reading.
+	d.SetEventJSON(failingWriter{})
+	d.eventJSON.record(c, args, now)
+	d.SetEventJSON(nil)
+	d.eventJSON.record(c, args, now)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("closed") }
@@ -0,0 +1,367 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// EventType is the type of an event published by the driver.
+type EventType int
+
+const (
+	// EventFlyingStateChanged is published when the flying state of
+	// the drone changes. The value is of type FlyingState.
+	EventFlyingStateChanged EventType = iota
+	// EventAlertStateChanged is published when the drone reports
+	// an alert. The value is of type AlertState.
+	EventAlertStateChanged
+	// EventSensorStateChanged is published when the drone reports
+	// the state of a sensor. The value is of type SensorState.
+	EventSensorStateChanged
+	// EventMotorError is published when the drone reports a motor
+	// error. The value is of type MotorError.
+	EventMotorError
+	// EventAllStatesReceived is published when the drone have sent
+	// all it's states after being asked with AllStates.
+	EventAllStatesReceived
+	// EventAllSettingsReceived is published when the drone have sent
+	// all it's settings after being asked with AllSettings.
+	EventAllSettingsReceived
+	// EventConnectionReady is published when the ready state of the
+	// connection changes. The value is of type bool.
+	EventConnectionReady
+	// EventWindStateChanged is published when the drone reports the
+	// wind state. The value is of type WarningLevel.
+	EventWindStateChanged
+	// EventVibrationLevelChanged is published when the drone reports
+	// the vibration level. The value is of type WarningLevel.
+	EventVibrationLevelChanged
+	// EventWifiAuthChannelsReceived is published when the drone have
+	// sent all the wifi channels it is allowed to use. The value is of
+	// type []WifiChannel.
+	EventWifiAuthChannelsReceived
+	// EventWifiScanReceived is published when the drone have sent all
+	// the wifi networks found in a scan. The value is of type
+	// []WifiNetwork.
+	EventWifiScanReceived
+	// EventMoveToSent is published when a moveTo command have been
+	// sent to the drone. The value is of type Position.
+	EventMoveToSent
+	// EventMoveToCancelled is published when a cancel of the moveTo
+	// have been sent to the drone.
+	EventMoveToCancelled
+	// EventMissionStatus is published while the moveTo executor is
+	// flying a mission, and when it stops. The value is of type
+	// MissionStatus.
+	EventMissionStatus
+	// EventAttitudeChanged is published when the drone reports the
+	// attitude, at most every 100ms. The value is of type Attitude.
+	EventAttitudeChanged
+	// EventSettingChanged is published when the drone reports the value
+	// of a setting. The value is of type SettingChange.
+	EventSettingChanged
+	// EventConnection is published for each step in the life cycle of
+	// the connection with the drone, like each attempt to connect. The
+	// value is of type ConnectionEvent.
+	EventConnection
+	// EventControlAuthority is published when the pilot takes over the
+	// control from a mission, and when the mission is resumed. The value
+	// is of type ControlAuthority.
+	EventControlAuthority
+	// EventRTHFallback is published for each stage of the RTH fallback,
+	// started when the drone can't do a return home. The value is of
+	// type RTHFallbackEvent.
+	EventRTHFallback
+	// EventFlightSummary is published when a flight ends, by the drone
+	// landing or the connection being lost. The value is of type
+	// FlightSummary.
+	EventFlightSummary
+	// EventConfigChanged is published when the config file watched with
+	// WatchConfig is changed. The value is of type ConfigChange.
+	EventConfigChanged
+	// EventMoveBySent is published when a relative move have been sent
+	// to the drone. The value is of type MoveBy.
+	EventMoveBySent
+	// EventMoveByCancelled is published when a cancel of the relative
+	// move have been sent to the drone.
+	EventMoveByCancelled
+	// EventMoveByEnd is published when the drone reports that a
+	// relative move ended. The value is of type MoveByEnd.
+	EventMoveByEnd
+	// EventMoveToFailed is published when the moveTo executor stops the
+	// mission, because a leg was not reached within the leg timeout.
+	// The value is of type Position, and is the leg not reached.
+	EventMoveToFailed
+)
+
+// String will return the name of the event type.
+func (e EventType) String() string {
+	switch e {
+	case EventFlyingStateChanged:
+		return "FlyingStateChanged"
+	case EventAlertStateChanged:
+		return "AlertStateChanged"
+	case EventSensorStateChanged:
+		return "SensorStateChanged"
+	case EventMotorError:
+		return "MotorError"
+	case EventAllStatesReceived:
+		return "AllStatesReceived"
+	case EventAllSettingsReceived:
+		return "AllSettingsReceived"
+	case EventConnectionReady:
+		return "ConnectionReady"
+	case EventWindStateChanged:
+		return "WindStateChanged"
+	case EventVibrationLevelChanged:
+		return "VibrationLevelChanged"
+	case EventWifiAuthChannelsReceived:
+		return "WifiAuthChannelsReceived"
+	case EventWifiScanReceived:
+		return "WifiScanReceived"
+	case EventMoveToSent:
+		return "MoveToSent"
+	case EventMoveToCancelled:
+		return "MoveToCancelled"
+	case EventMissionStatus:
+		return "MissionStatus"
+	case EventAttitudeChanged:
+		return "AttitudeChanged"
+	case EventSettingChanged:
+		return "SettingChanged"
+	case EventConnection:
+		return "Connection"
+	case EventControlAuthority:
+		return "ControlAuthority"
+	case EventRTHFallback:
+		return "RTHFallback"
+	case EventFlightSummary:
+		return "FlightSummary"
+	case EventConfigChanged:
+		return "ConfigChanged"
+	case EventMoveBySent:
+		return "MoveBySent"
+	case EventMoveByCancelled:
+		return "MoveByCancelled"
+	case EventMoveByEnd:
+		return "MoveByEnd"
+	case EventMoveToFailed:
+		return "MoveToFailed"
+	}
+
+	return fmt.Sprintf("EventType(%d)", int(e))
+}
+
+// Priority is the priority of an event.
+type Priority int
+
+const (
+	// PriorityNormal is used for normal state changes.
+	PriorityNormal Priority = iota
+	// PriorityHigh is used for events that the operator should be
+	// notified about, like alerts and failing sensors.
+	PriorityHigh
+)
+
+// Event is an event published by the driver, like a change of state
+// reported by the drone.
+type Event struct {
+	Type     EventType
+	Time     time.Time
+	Priority Priority
+	// Value holds the data of the event, and the type depends on the
+	// type of the event.
+	Value interface{}
+}
+
+// eventSubscriberBuffer is the size of the buffered channel of each
+// subscriber.
+const eventSubscriberBuffer = 100
+
+// eventSubscriber is the queue of a single subscriber.
+type eventSubscriber struct {
+	ch chan Event
+	// dropped is the number of events dropped since the subscriber was
+	// not keeping up.
+	dropped uint64
+}
+
+// eventBus will deliver all the events published to all the current
+// subscribers. Publishing will never block, since it is done while
+// reading the packets from the drone, so if a subscriber is not able to
+// keep up the oldest event queued is dropped for that subscriber, and
+// counted.
+type eventBus struct {
+	mu          sync.Mutex
+	subscribers map[int]*eventSubscriber
+	nextID      int
+	// published is when each type of event was last published, used
+	// by publishThrottled.
+	published map[EventType]time.Time
+}
+
+// publish will deliver the event with normal priority to all the
+// subscribers.
+func (e *eventBus) publish(typ EventType, value interface{}) {
+	e.publishPriority(typ, PriorityNormal, value)
+}
+
+// publishThrottled will deliver the event with normal priority to all
+// the subscribers, unless an event of the same type was published
+// within the interval, so high frequency state changes don't flood the
+// subscribers.
+func (e *eventBus) publishThrottled(typ EventType, interval time.Duration, value interface{}) {
+	now := time.Now()
+
+	e.mu.Lock()
+	if e.published == nil {
+		e.published = make(map[EventType]time.Time)
+	}
+	if now.Sub(e.published[typ]) < interval {
+		e.mu.Unlock()
+		return
+	}
+	e.published[typ] = now
+	e.mu.Unlock()
+
+	e.publish(typ, value)
+}
+
+// publishPriority will deliver the event with the given priority
+// to all the subscribers.
+func (e *eventBus) publishPriority(typ EventType, priority Priority, value interface{}) {
+	ev := Event{
+		Type:     typ,
+		Time:     time.Now(),
+		Priority: priority,
+		Value:    value,
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for id, s := range e.subscribers {
+		// The queue is only written while holding the lock, so there
+		// is a single sender.
+		if sendDropOldest(s.ch, ev) {
+			s.countDropped(id)
+		}
+	}
+}
+
+// sendDropOldest will send the event on the channel, where the oldest
+// event queued is dropped to make room if the channel is full, and
+// return true if an event was dropped. There must only be a single
+// sender on the channel, so the send after making room will not block.
+func sendDropOldest(ch chan Event, ev Event) bool {
+	select {
+	case ch <- ev:
+		return false
+	default:
+	}
+
+	dropped := false
+	select {
+	case <-ch:
+		dropped = true
+	default:
+	}
+	ch <- ev
+
+	return dropped
+}
+
+// countDropped will count an event dropped for the subscriber, and log
+// the first one. Must be called while holding the lock of the bus.
+func (s *eventSubscriber) countDropped(id int) {
+	if s.dropped == 0 {
+		log.Printf("warning: event subscriber %v is not keeping up, dropping the oldest events\n", id)
+	}
+	s.dropped++
+}
+
+// subscribe will register a new subscriber, and return the channel
+// to receive the events on, and a function to unsubscribe.
+func (e *eventBus) subscribe() (<-chan Event, func()) {
+	_, ch, unsubscribe := e.subscribeID()
+	return ch, unsubscribe
+}
+
+// countDropped will count an event dropped after being delivered to
+// the subscriber with the ID given, like by SubscribeThrottled, so it
+// is shown in the stats of the subscriber.
+func (e *eventBus) countDropped(id int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if s, ok := e.subscribers[id]; ok {
+		s.countDropped(id)
+	}
+}
+
+// subscribeID will register a new subscriber like subscribe, and also
+// return the ID of the subscriber.
+func (e *eventBus) subscribeID() (int, <-chan Event, func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.subscribers == nil {
+		e.subscribers = make(map[int]*eventSubscriber)
+	}
+
+	id := e.nextID
+	e.nextID++
+	ch := make(chan Event, eventSubscriberBuffer)
+	e.subscribers[id] = &eventSubscriber{ch: ch}
+
+	unsubscribe := func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+
+		if _, ok := e.subscribers[id]; ok {
+			delete(e.subscribers, id)
+			close(ch)
+		}
+	}
+
+	return id, ch, unsubscribe
+}
+
+// Subscribe will return a channel where all the events published by
+// the driver are delivered, and a function to call when the caller
+// no longer wants to receive events, which also closes the channel.
+// The channel holds the latest 100 events, and the oldest are dropped
+// for a subscriber that is not reading fast enough.
+func (d *Drone) Subscribe() (<-chan Event, func()) {
+	return d.events.subscribe()
+}
+
+// EventSubscriberStats is the state of the queue of a subscriber.
+type EventSubscriberStats struct {
+	// ID is the number of the subscriber, as used in the log.
+	ID int
+	// Queued is the number of events waiting to be read.
+	Queued int
+	// Dropped is the number of events dropped since the subscriber was
+	// not reading fast enough.
+	Dropped uint64
+}
+
+// EventSubscriberStats will return the state of the queue of each
+// current subscriber, including the ones used within the driver, so a
+// slow consumer of the events can be found.
+func (d *Drone) EventSubscriberStats() []EventSubscriberStats {
+	d.events.mu.Lock()
+	defer d.events.mu.Unlock()
+
+	stats := make([]EventSubscriberStats, 0, len(d.events.subscribers))
+	for id, s := range d.events.subscribers {
+		stats = append(stats, EventSubscriberStats{ID: id, Queued: len(s.ch), Dropped: s.dropped})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].ID < stats[j].ID })
+
+	return stats
+}
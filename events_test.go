@@ -0,0 +1,35 @@
+package parrotbebop
+
+import "testing"
+
+func TestEventBusDropOldest(t *testing.T) {
+	d := NewDrone()
+	slow, unsubscribeSlow := d.Subscribe()
+	defer unsubscribeSlow()
+	fast, unsubscribeFast := d.Subscribe()
+	defer unsubscribeFast()
+
+	// The slow subscriber never reads, which should not block the
+	// publishing, or the subscriber reading.
+	for i := 0; i < eventSubscriberBuffer+10; i++ {
+		d.events.publish(EventMoveToSent, i)
+		if ev := <-fast; ev.Value != i {
+			t.Fatalf("got %v, want %v", ev.Value, i)
+		}
+	}
+
+	if ev := <-slow; ev.Value != 10 {
+		t.Fatalf("got oldest event %v, want 10", ev.Value)
+	}
+
+	stats := d.EventSubscriberStats()
+	if len(stats) != 2 {
+		t.Fatalf("got %v subscribers, want 2", len(stats))
+	}
+	if stats[0].Dropped != 10 || stats[0].Queued != eventSubscriberBuffer-1 {
+		t.Fatalf("got slow subscriber %+v", stats[0])
+	}
+	if stats[1].Dropped != 0 || stats[1].Queued != 0 {
+		t.Fatalf("got fast subscriber %+v", stats[1])
+	}
+}
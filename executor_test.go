@@ -0,0 +1,311 @@
+package parrotbebop
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+// newExecutorTestDrone will return a drone with the moveTo executor
+// running against a fast simulator, and a channel closed when the
+// executor returns.
+func newExecutorTestDrone(t *testing.T, ctx context.Context, speed float64) (*Drone, <-chan struct{}) {
+	d := NewDrone()
+	go func() {
+		for range d.chSendingUDPPacket {
+		}
+	}()
+	if err := d.SetArrivalDetection(1, 1, 0, time.Second*5); err != nil {
+		t.Fatal(err)
+	}
+
+	sim := NewSimulatedPositionSource(Position{Latitude: 60, Longitude: 10, Altitude: 10})
+	sim.Speed = speed
+	sim.ClimbSpeed = speed
+	sim.Interval = time.Millisecond * 10
+	if err := d.Simulate(ctx, sim); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		d.startMoveToExecutor(d.packetCreator, ctx)
+		close(done)
+	}()
+
+	return d, done
+}
+
+func TestMoveToExecutor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d, done := newExecutorTestDrone(t, ctx, 2000)
+
+	start := Position{Latitude: 60, Longitude: 10, Altitude: 10}
+	for _, p := range []Position{start.Offset(50, 0), start.Offset(50, 90)} {
+		if err := d.InsertWaypoint(-1, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	signalMoveTo(d.gps.chMoveToExecute)
+	err := d.scriptWaitFor(ctx, time.Second*5, func() bool {
+		return d.MissionProgress().Completed == 2 && !d.MoveToActive()
+	})
+	if err != nil {
+		t.Fatalf("waypoints not flown: %v, progress %+v", err, d.MissionProgress())
+	}
+
+	// The executor must stop when the context is done.
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("executor did not stop when the context was done")
+	}
+}
+
+func TestMoveToExecutorCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d, done := newExecutorTestDrone(t, ctx, 1)
+
+	events, unsubscribe := d.Subscribe()
+	defer unsubscribe()
+
+	start := Position{Latitude: 60, Longitude: 10, Altitude: 10}
+	if err := d.InsertWaypoint(-1, start.Offset(500, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	signalMoveTo(d.gps.chMoveToExecute)
+	if err := d.scriptWaitFor(ctx, time.Second, d.MoveToActive); err != nil {
+		t.Fatal(err)
+	}
+	signalMoveTo(d.gps.chMoveToCancel)
+
+	timeout := time.After(time.Second * 2)
+	for cancelled := false; !cancelled; {
+		select {
+		case ev := <-events:
+			cancelled = ev.Type == EventMoveToCancelled
+		case <-timeout:
+			t.Fatal("cancel not sent")
+		}
+	}
+
+	err := d.scriptWaitFor(ctx, time.Second, func() bool {
+		return !d.MoveToActive() && len(d.Waypoints()) == 1
+	})
+	if err != nil {
+		t.Fatalf("waypoint not put back in the buffer: %v", err)
+	}
+
+	cancel()
+	<-done
+}
+
+func TestMoveToExecutorPausedOnCritical(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d, done := newExecutorTestDrone(t, ctx, 1)
+	d.SetPauseOnCritical(true)
+
+	start := Position{Latitude: 60, Longitude: 10, Altitude: 10}
+	if err := d.InsertWaypoint(-1, start.Offset(500, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	signalMoveTo(d.gps.chMoveToExecute)
+	if err := d.scriptWaitFor(ctx, time.Second, d.MoveToActive); err != nil {
+		t.Fatal(err)
+	}
+
+	// A critical wind must stop the executor, and keep the waypoint.
+	d.handleWindState(LevelCritical)
+	err := d.scriptWaitFor(ctx, time.Second, func() bool {
+		return !d.MoveToActive() && len(d.Waypoints()) == 1
+	})
+	if err != nil {
+		t.Fatalf("executor not stopped by the pause: %v", err)
+	}
+
+	// No new mission can be started while paused.
+	signalMoveTo(d.gps.chMoveToExecute)
+	time.Sleep(time.Millisecond * 50)
+	if d.MoveToActive() {
+		t.Fatalf("executor started while paused")
+	}
+
+	// Resuming starts the executor again.
+	d.ResumeMission()
+	if err := d.scriptWaitFor(ctx, time.Second, d.MoveToActive); err != nil {
+		t.Fatalf("executor not started when resumed: %v", err)
+	}
+
+	// The gps guard is checked when resuming.
+	d.handleWindState(LevelCritical)
+	err = d.scriptWaitFor(ctx, time.Second, func() bool { return !d.MoveToActive() })
+	if err != nil {
+		t.Fatalf("executor not stopped by the pause: %v", err)
+	}
+	d.SetGPSGuard(true, 200)
+	d.ResumeMission()
+	time.Sleep(time.Millisecond * 50)
+	if d.MoveToActive() {
+		t.Fatalf("executor resumed without enough satellites")
+	}
+
+	cancel()
+	<-done
+}
+
+func TestMoveToExecutorLegTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d, done := newExecutorTestDrone(t, ctx, 1)
+	if err := d.SetArrivalDetection(1, 1, 0, time.Millisecond*100); err != nil {
+		t.Fatal(err)
+	}
+
+	events, unsubscribe := d.Subscribe()
+	defer unsubscribe()
+
+	start := Position{Latitude: 60, Longitude: 10, Altitude: 10}
+	if err := d.InsertWaypoint(-1, start.Offset(500, 0)); err != nil {
+		t.Fatal(err)
+	}
+	signalMoveTo(d.gps.chMoveToExecute)
+
+	timeout := time.After(time.Second * 2)
+	for failed := false; !failed; {
+		select {
+		case ev := <-events:
+			failed = ev.Type == EventMoveToFailed
+		case <-timeout:
+			t.Fatal("failure not published")
+		}
+	}
+
+	// The waypoint not reached must be kept, and not counted as flown.
+	err := d.scriptWaitFor(ctx, time.Second, func() bool {
+		return !d.MoveToActive() && len(d.Waypoints()) == 1
+	})
+	if err != nil {
+		t.Fatalf("waypoint not kept after the timeout: %v", err)
+	}
+	if c := d.MissionProgress().Completed; c != 0 {
+		t.Fatalf("got %v waypoints completed, want 0", c)
+	}
+
+	cancel()
+	<-done
+}
+
+func TestFollowMeRefusedWhileMissionActive(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d, done := newExecutorTestDrone(t, ctx, 1)
+
+	start := Position{Latitude: 60, Longitude: 10, Altitude: 10}
+	if err := d.InsertWaypoint(-1, start.Offset(500, 0)); err != nil {
+		t.Fatal(err)
+	}
+	signalMoveTo(d.gps.chMoveToExecute)
+	if err := d.scriptWaitFor(ctx, time.Second, d.MoveToActive); err != nil {
+		t.Fatal(err)
+	}
+
+	src := NewSimulatedPositionSource(start)
+	if err := d.FollowMe(ctx, src, FollowMeConfig{Distance: 10}); err == nil {
+		t.Fatalf("FollowMe started while a mission is active")
+	}
+
+	cancel()
+	<-done
+}
+
+func TestMoveToExecutorMaxLegLengthChanged(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d, done := newExecutorTestDrone(t, ctx, 2000)
+
+	start := Position{Latitude: 60, Longitude: 10, Altitude: 10}
+	for _, p := range []Position{start.Offset(50, 0), start.Offset(50, 90)} {
+		if err := d.InsertWaypoint(-1, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The max leg length can be changed while the executor splits the
+	// legs.
+	signalMoveTo(d.gps.chMoveToExecute)
+	for i := 0; i < 10; i++ {
+		if err := d.SetMaxLegLength(float64(10 + i)); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	err := d.scriptWaitFor(ctx, time.Second*5, func() bool {
+		return !d.MoveToActive() && len(d.Waypoints()) == 0
+	})
+	if err != nil {
+		t.Fatalf("waypoints not flown: %v", err)
+	}
+
+	cancel()
+	<-done
+}
+
+func TestMoveToExecutorRefusedWhileFollowMe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d, done := newExecutorTestDrone(t, ctx, 1)
+
+	release, err := d.claimDirectMoveTo("FollowMe")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := Position{Latitude: 60, Longitude: 10, Altitude: 10}
+	if err := d.InsertWaypoint(-1, start.Offset(500, 0)); err != nil {
+		t.Fatal(err)
+	}
+	// Signal the executor directly, as executeMoveTo would refuse.
+	signalMoveTo(d.gps.chMoveToExecute)
+	if err := d.scriptWaitFor(ctx, time.Millisecond*200, d.MoveToActive); err == nil {
+		t.Fatalf("moveTo executor started while FollowMe is flying the drone")
+	}
+
+	release()
+	signalMoveTo(d.gps.chMoveToExecute)
+	if err := d.scriptWaitFor(ctx, time.Second, d.MoveToActive); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.claimDirectMoveTo("Orbit"); err == nil {
+		t.Fatalf("Orbit claimed the drone while a mission is active")
+	}
+
+	cancel()
+	<-done
+}
+
+func TestNextOrbitAngle(t *testing.T) {
+	tests := []struct {
+		angle, step, want float64
+	}{
+		{350, 20, 10},
+		{10, -20, 350},
+		{10, -400, 330},
+		{10, -760, 330},
+		{10, 720, 10},
+	}
+
+	for _, tt := range tests {
+		if got := nextOrbitAngle(tt.angle, tt.step); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("nextOrbitAngle(%v, %v) = %v, want %v", tt.angle, tt.step, got, tt.want)
+		}
+	}
+}
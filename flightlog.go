@@ -0,0 +1,105 @@
+package parrotbebop
+
+import (
+	"sync"
+	"time"
+)
+
+// maxFlightLogSamples is the max number of samples kept in the flight
+// log, where the oldest are dropped. With the drone reporting the
+// position 5 times a second it is about 5.5 hours.
+const maxFlightLogSamples = 100000
+
+// FlightSample is the telemetry of the drone at the time the position
+// was reported.
+type FlightSample struct {
+	Time     time.Time
+	Position Position
+	// Altitude is the altitude in meters above the take off point.
+	Altitude float64
+	// Heading is the heading of the drone in degrees, where 0 is north.
+	Heading float64
+	// Roll and Pitch is the attitude of the drone in radians.
+	Roll  float32
+	Pitch float32
+	// Tilt and Pan is the orientation of the camera in degrees.
+	Tilt float32
+	Pan  float32
+}
+
+// flightLog holds the samples of the flight log while recording.
+type flightLog struct {
+	mu        sync.Mutex
+	recording bool
+	samples   []FlightSample
+	// max is the max number of samples kept, where 0 is
+	// maxFlightLogSamples.
+	max int
+}
+
+// add will add the sample to the log if recording.
+func (f *flightLog) add(s FlightSample) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.recording {
+		return
+	}
+	max := f.max
+	if max == 0 {
+		max = maxFlightLogSamples
+	}
+	if len(f.samples) >= max {
+		f.samples = f.samples[1:]
+	}
+	f.samples = append(f.samples, s)
+}
+
+// StartFlightLog will start recording a flight log with the telemetry
+// each time the drone reports it's position. A previously recorded log
+// is cleared.
+func (d *Drone) StartFlightLog() {
+	d.flightLog.mu.Lock()
+	defer d.flightLog.mu.Unlock()
+
+	d.flightLog.recording = true
+	d.flightLog.samples = nil
+}
+
+// StopFlightLog will stop recording the flight log, keeping the samples
+// recorded.
+func (d *Drone) StopFlightLog() {
+	d.flightLog.mu.Lock()
+	defer d.flightLog.mu.Unlock()
+
+	d.flightLog.recording = false
+}
+
+// FlightLog will return a copy of the samples recorded in the flight
+// log, ordered by time.
+func (d *Drone) FlightLog() []FlightSample {
+	d.flightLog.mu.Lock()
+	defer d.flightLog.mu.Unlock()
+
+	s := make([]FlightSample, len(d.flightLog.samples))
+	copy(s, d.flightLog.samples)
+
+	return s
+}
+
+// logFlightSample will add the current telemetry to the flight log,
+// with the position reported by the drone.
+func (d *Drone) logFlightSample(p Position, now time.Time) {
+	t := d.telemetry.snapshot()
+
+	d.flightLog.add(FlightSample{
+		Time:     now,
+		Position: p,
+		Altitude: t.Altitude,
+		Heading:  t.HeadingDegrees(),
+		Roll:     t.Roll,
+		Pitch:    t.Pitch,
+		Tilt:     t.Gimbal.Tilt,
+		Pan:      t.Gimbal.Pan,
+	})
+}
@@ -0,0 +1,310 @@
+package parrotbebop
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FlightSummary is the summary of a single flight, from the take off
+// until landed or the connection was lost. The altitude and distances
+// are found from the positions reported by the drone during the flight.
+type FlightSummary struct {
+	Start    time.Time
+	End      time.Time
+	Duration time.Duration
+	// EndReason is why the flight ended, like landed or connection lost.
+	EndReason string
+	// MaxAltitude is the max altitude in meters above the take off point.
+	MaxAltitude float64
+	// MaxDistanceFromHome is the max distance in meters from the home
+	// position, or from the take off position if no home is set.
+	MaxDistanceFromHome float64
+	// DistanceFlown is the distance flown in meters, including the
+	// altitude changes.
+	DistanceFlown float64
+	// BatteryStart and BatteryEnd are the battery levels in percent.
+	BatteryStart uint8
+	BatteryEnd   uint8
+	BatteryUsed  int
+	PhotosTaken  int
+	// Warnings are the critical events during the flight, as given to
+	// the notifiers.
+	Warnings []string
+}
+
+// flightStats are the altitude and distances of a flight, updated with
+// each position reported by the drone.
+type flightStats struct {
+	maxAltitude         float64
+	maxDistanceFromHome float64
+	distanceFlown       float64
+	// first and last are the first and the latest valid positions, with
+	// the altitude above the take off point.
+	first, last *FlightSample
+}
+
+// add will update the stats with the sample, where the distance from
+// home is from the first valid position if home is nil. Only the
+// altitude is used from the samples without a valid position.
+func (f *flightStats) add(s FlightSample, home *Position) {
+	f.maxAltitude = math.Max(f.maxAltitude, s.Altitude)
+	if !validGPSPosition(s.Position) {
+		return
+	}
+
+	if f.first == nil {
+		f.first = &s
+	}
+	if home == nil {
+		home = &f.first.Position
+	}
+	f.maxDistanceFromHome = math.Max(f.maxDistanceFromHome, home.DistanceTo(s.Position))
+	if f.last != nil {
+		h := f.last.Position.DistanceTo(s.Position)
+		v := s.Altitude - f.last.Altitude
+		f.distanceFlown += math.Sqrt(h*h + v*v)
+	}
+	f.last = &s
+}
+
+// WriteFlightSummaryJSON will write the summary to w as JSON.
+func WriteFlightSummaryJSON(w io.Writer, s FlightSummary) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		return fmt.Errorf("WriteFlightSummaryJSON: %v", err)
+	}
+
+	return nil
+}
+
+// WriteFlightSummaryText will write the summary to w as text for
+// reading.
+func WriteFlightSummaryText(w io.Writer, s FlightSummary) error {
+	lines := []string{
+		fmt.Sprintf("Flight %v\n", s.Start.Format(time.RFC1123)),
+		fmt.Sprintf("  Duration:          %v (%v)\n", s.Duration.Round(time.Second), s.EndReason),
+		fmt.Sprintf("  Max altitude:      %.1f m\n", s.MaxAltitude),
+		fmt.Sprintf("  Max distance home: %.1f m\n", s.MaxDistanceFromHome),
+		fmt.Sprintf("  Distance flown:    %.1f m\n", s.DistanceFlown),
+		fmt.Sprintf("  Battery used:      %v%% (%v%% to %v%%)\n", s.BatteryUsed, s.BatteryStart, s.BatteryEnd),
+		fmt.Sprintf("  Photos taken:      %v\n", s.PhotosTaken),
+		fmt.Sprintf("  Warnings:          %v\n", len(s.Warnings)),
+	}
+	for _, warning := range s.Warnings {
+		lines = append(lines, fmt.Sprintf("    %v\n", warning))
+	}
+
+	for _, l := range lines {
+		if _, err := io.WriteString(w, l); err != nil {
+			return fmt.Errorf("WriteFlightSummaryText: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// flightSession holds the values of the flight in progress, and the
+// summary of the last flight.
+type flightSession struct {
+	mu           sync.Mutex
+	active       bool
+	start        time.Time
+	batteryStart uint8
+	photos       int
+	// unsubscribe will stop the collecting of warnings, and done is
+	// closed when the collecting is stopped.
+	unsubscribe func()
+	done        chan struct{}
+	warnings    []string
+	stats       flightStats
+	// dir is where the summaries are written.
+	dir  string
+	last *FlightSummary
+}
+
+// SetFlightSummaryDir will make the driver write a summary of each
+// flight to the directory given, when the drone have landed or the
+// connection is lost, both as JSON and as text for reading. An empty
+// dir will stop the writing. A summary is always published with
+// EventFlightSummary.
+func (d *Drone) SetFlightSummaryDir(dir string) error {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("SetFlightSummaryDir: %v", err)
+		}
+	}
+
+	d.flightSession.mu.Lock()
+	defer d.flightSession.mu.Unlock()
+
+	d.flightSession.dir = dir
+
+	return nil
+}
+
+// LastFlightSummary will return the summary of the last flight, and
+// false if no flight have ended yet.
+func (d *Drone) LastFlightSummary() (FlightSummary, bool) {
+	d.flightSession.mu.Lock()
+	defer d.flightSession.mu.Unlock()
+
+	if d.flightSession.last == nil {
+		return FlightSummary{}, false
+	}
+
+	return *d.flightSession.last, true
+}
+
+// startFlightSession will start a new flight, and collect the critical
+// events as warnings until the flight ends.
+func (d *Drone) startFlightSession(now time.Time) {
+	s := &d.flightSession
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active {
+		return
+	}
+	s.active = true
+	s.start = now
+	s.batteryStart = d.telemetry.snapshot().Battery
+	s.photos = 0
+	s.warnings = nil
+	s.stats = flightStats{}
+
+	events, unsubscribe := d.events.subscribe()
+	done := make(chan struct{})
+	s.unsubscribe = unsubscribe
+	s.done = done
+
+	go func() {
+		defer close(done)
+
+		var throttle notifyThrottle
+		for ev := range events {
+			n, ok := notificationFor(ev)
+			if !ok || !throttle.allow(n.Title, ev.Time) {
+				continue
+			}
+			s.mu.Lock()
+			s.warnings = append(s.warnings, fmt.Sprintf("%v %v: %v", ev.Time.Format("15:04:05"), n.Title, n.Message))
+			s.mu.Unlock()
+		}
+	}()
+}
+
+// flightPhotoTaken will count a picture taken during the flight.
+func (d *Drone) flightPhotoTaken() {
+	d.flightSession.mu.Lock()
+	defer d.flightSession.mu.Unlock()
+
+	if d.flightSession.active {
+		d.flightSession.photos++
+	}
+}
+
+// flightPosition will update the stats of the flight in progress with
+// the position reported by the drone.
+func (d *Drone) flightPosition(p Position, now time.Time) {
+	var home *Position
+	if h := d.Home(); h.Set {
+		home = &Position{Latitude: h.Latitude, Longitude: h.Longitude, Altitude: h.Altitude}
+	}
+	sample := FlightSample{Time: now, Position: p, Altitude: d.telemetry.snapshot().Altitude}
+
+	d.flightSession.mu.Lock()
+	defer d.flightSession.mu.Unlock()
+
+	if d.flightSession.active {
+		d.flightSession.stats.add(sample, home)
+	}
+}
+
+// endFlightSession will end the flight in progress, and publish the
+// summary of it. The summary files are written in the background, so
+// the decoding of the frames from the drone is not held up by the disk.
+func (d *Drone) endFlightSession(now time.Time, reason string) {
+	s := &d.flightSession
+
+	s.mu.Lock()
+	if !s.active {
+		s.mu.Unlock()
+		return
+	}
+	s.active = false
+	unsubscribe, done := s.unsubscribe, s.done
+	s.mu.Unlock()
+
+	// Wait for the collecting of the warnings to stop before using them.
+	unsubscribe()
+	<-done
+
+	t := d.telemetry.snapshot()
+
+	s.mu.Lock()
+	sum := FlightSummary{
+		Start:        s.start,
+		End:          now,
+		Duration:     now.Sub(s.start),
+		EndReason:    reason,
+		BatteryStart: s.batteryStart,
+		BatteryEnd:   t.Battery,
+		BatteryUsed:  int(s.batteryStart) - int(t.Battery),
+		PhotosTaken:  s.photos,
+		Warnings:     s.warnings,
+
+		MaxAltitude:         s.stats.maxAltitude,
+		MaxDistanceFromHome: s.stats.maxDistanceFromHome,
+		DistanceFlown:       s.stats.distanceFlown,
+	}
+	dir := s.dir
+	s.last = &sum
+	s.mu.Unlock()
+
+	log.Printf("info: flight ended, %v: %.0f m flown in %v\n", reason, sum.DistanceFlown, sum.Duration.Round(time.Second))
+	d.events.publish(EventFlightSummary, sum)
+
+	if dir != "" {
+		go func() {
+			if err := writeFlightSummaryFiles(dir, sum); err != nil {
+				log.Printf("error: %v\n", err)
+			}
+		}()
+	}
+}
+
+// writeFlightSummaryFiles will write the summary as JSON and text to
+// the directory, named by the start time of the flight.
+func writeFlightSummaryFiles(dir string, s FlightSummary) error {
+	name := filepath.Join(dir, "flight-"+s.Start.Format(dateFormat+timeFormat))
+
+	write := func(path string, fn func(io.Writer, FlightSummary) error) error {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		if err := fn(f, s); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+
+	if err := write(name+".json", WriteFlightSummaryJSON); err != nil {
+		return fmt.Errorf("writeFlightSummaryFiles: %v", err)
+	}
+	if err := write(name+".txt", WriteFlightSummaryText); err != nil {
+		return fmt.Errorf("writeFlightSummaryFiles: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package parrotbebop
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFlightSummary(t *testing.T) {
+	d := NewDrone()
+	dir, err := ioutil.TempDir("", "flightsummary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := d.SetFlightSummaryDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	d.telemetry.update(func(t *Telemetry) { t.Battery = 80 })
+	d.setFlyingState(FlyingStateLanded)
+	d.setFlyingState(FlyingStateTakingOff)
+
+	// 100 m east at 10 m altitude, and back, without the flight log
+	// recording. The position not known is only used for the altitude.
+	start := Position{Latitude: 60, Longitude: 10}
+	now := time.Now()
+	for i, s := range []FlightSample{
+		{Position: start},
+		{Position: start, Altitude: 10},
+		{Position: Position{Latitude: 500, Longitude: 500, Altitude: 500}, Altitude: 10},
+		{Position: start.Offset(100, 90), Altitude: 10},
+		{Position: start, Altitude: 10},
+	} {
+		d.telemetry.update(func(t *Telemetry) { t.Altitude = s.Altitude })
+		d.flightPosition(s.Position, now.Add(time.Duration(i+1)*time.Millisecond))
+	}
+	d.flightPhotoTaken()
+	d.flightPhotoTaken()
+	d.handleAlertState(AlertLowBattery)
+	d.telemetry.update(func(t *Telemetry) { t.Battery = 65 })
+	time.Sleep(time.Millisecond * 10)
+	d.setFlyingState(FlyingStateLanded)
+
+	s, ok := d.LastFlightSummary()
+	if !ok {
+		t.Fatalf("no flight summary")
+	}
+	if s.EndReason != "landed" || s.PhotosTaken != 2 || s.BatteryUsed != 15 || len(s.Warnings) != 1 {
+		t.Fatalf("got %+v", s)
+	}
+	if s.MaxAltitude != 10 || s.MaxDistanceFromHome < 99.9 || s.MaxDistanceFromHome > 100.1 || s.DistanceFlown < 209.9 || s.DistanceFlown > 210.1 {
+		t.Fatalf("got %+v", s)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteFlightSummaryText(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Photos taken:      2") {
+		t.Fatalf("bad text summary:\n%v", buf.String())
+	}
+
+	// The files are written in the background.
+	for _, ext := range []string{"*.json", "*.txt"} {
+		var files []string
+		for i := 0; i < 100 && len(files) != 1; i++ {
+			time.Sleep(time.Millisecond * 10)
+			files, _ = filepath.Glob(filepath.Join(dir, ext))
+		}
+		if len(files) != 1 {
+			t.Fatalf("got %v %v files, want 1", len(files), ext)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"time"
+)
+
+// FlyingState is the flying state reported by the drone.
+type FlyingState uint32
+
+const (
+	FlyingStateLanded           FlyingState = 0
+	FlyingStateTakingOff        FlyingState = 1
+	FlyingStateHovering         FlyingState = 2
+	FlyingStateFlying           FlyingState = 3
+	FlyingStateLanding          FlyingState = 4
+	FlyingStateEmergency        FlyingState = 5
+	FlyingStateUserTakeoff      FlyingState = 6
+	FlyingStateMotorRamping     FlyingState = 7
+	FlyingStateEmergencyLanding FlyingState = 8
+)
+
+// String will return the name of the flying state.
+func (f FlyingState) String() string {
+	switch f {
+	case FlyingStateLanded:
+		return "landed"
+	case FlyingStateTakingOff:
+		return "takingoff"
+	case FlyingStateHovering:
+		return "hovering"
+	case FlyingStateFlying:
+		return "flying"
+	case FlyingStateLanding:
+		return "landing"
+	case FlyingStateEmergency:
+		return "emergency"
+	case FlyingStateUserTakeoff:
+		return "usertakeoff"
+	case FlyingStateMotorRamping:
+		return "motor_ramping"
+	case FlyingStateEmergencyLanding:
+		return "emergency_landing"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(f))
+}
+
+// Airborne will return true if the drone is in the air.
+func (f FlyingState) Airborne() bool {
+	switch f {
+	case FlyingStateTakingOff, FlyingStateHovering, FlyingStateFlying, FlyingStateLanding, FlyingStateEmergencyLanding:
+		return true
+	}
+
+	return false
+}
+
+// userTakeoffPending will return true if a user takeoff have been
+// started, and the drone is waiting to be thrown.
+func (f FlyingState) userTakeoffPending() bool {
+	return f == FlyingStateMotorRamping || f == FlyingStateUserTakeoff
+}
+
+// FlyingState will return the last flying state reported by the drone,
+// and false if no flying state have been received yet.
+func (d *Drone) FlyingState() (FlyingState, bool) {
+	t := d.telemetry.snapshot()
+	return t.FlyingState, t.flyingStateKnown
+}
+
+// setFlyingState will update the flying state with the value received
+// from the drone, and publish an event if the state changed.
+func (d *Drone) setFlyingState(f FlyingState) {
+	var changed bool
+	d.telemetry.update(func(t *Telemetry) {
+		changed = !t.flyingStateKnown || t.FlyingState != f
+		t.FlyingState = f
+		t.flyingStateKnown = true
+	})
+
+	if !changed {
+		return
+	}
+	d.events.publish(EventFlyingStateChanged, f)
+
+	// The flight summary is made from take off until landed.
+	switch {
+	case f.Airborne():
+		d.startFlightSession(time.Now())
+	case f == FlyingStateLanded:
+		d.endFlightSession(time.Now(), "landed")
+	}
+}
+
+// checkFlyingStateFor will check if the action is allowed in the
+// current flying state of the drone, and return an error if not.
+// If no flying state have been received yet from the drone all the
+// actions are allowed.
+func (d *Drone) checkFlyingStateFor(action InputAction) error {
+	state, known := d.FlyingState()
+	if !known {
+		return nil
+	}
+
+	switch action {
+	case ActionTakeoff, ActionUserTakeoff:
+		if state != FlyingStateLanded {
+			return fmt.Errorf("takeoff not allowed while %v", state)
+		}
+	case ActionLanding:
+		if state.userTakeoffPending() {
+			return nil
+		}
+		if !state.Airborne() || state == FlyingStateLanding {
+			return fmt.Errorf("landing not allowed while %v", state)
+		}
+	case ActionMoveToExecute, ActionMoveBy, ActionNudgeForward, ActionNudgeBackward,
+		ActionNudgeLeft, ActionNudgeRight, ActionNudgeUp, ActionNudgeDown, ActionFlip:
+		if state != FlyingStateHovering && state != FlyingStateFlying {
+			return fmt.Errorf("moving not allowed while %v", state)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package parrotbebop
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// directMoveTo holds the name of the FollowMe or Orbit running, which
+// sends the moveTo commands directly instead of using the moveTo
+// executor, so only one of them flies the drone at a time.
+type directMoveTo struct {
+	mu    sync.Mutex
+	owner string
+}
+
+// get will return the name of the one sending the moveTo commands, or
+// an empty string if none.
+func (m *directMoveTo) get() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.owner
+}
+
+// claimDirectMoveTo will make the owner given the only one sending
+// moveTo commands directly to the drone, and return a function to
+// release it when done. An error is returned if a moveTo mission, or
+// another FollowMe or Orbit, is already flying the drone.
+// The moveTo executor starts while holding the same lock, so a moveTo
+// mission can't start between the check and the claim.
+func (d *Drone) claimDirectMoveTo(owner string) (release func(), err error) {
+	d.directMoveTo.mu.Lock()
+	defer d.directMoveTo.mu.Unlock()
+
+	if d.MoveToActive() {
+		return nil, fmt.Errorf("moveTo mission active, cancel it first")
+	}
+	if d.directMoveTo.owner != "" {
+		return nil, fmt.Errorf("%v already running", d.directMoveTo.owner)
+	}
+	d.directMoveTo.owner = owner
+
+	release = func() {
+		d.directMoveTo.mu.Lock()
+		d.directMoveTo.owner = ""
+		d.directMoveTo.mu.Unlock()
+	}
+
+	return release, nil
+}
+
+// FollowMeConfig holds the values used for positioning the drone
+// relative to the operator while in follow-me mode.
+type FollowMeConfig struct {
+	// Distance in meters the drone should keep from the operator.
+	Distance float64
+	// Bearing in degrees from the operator to the drone, where 0 is
+	// north and 90 is east.
+	Bearing float64
+	// Altitude in meters the drone should fly at.
+	Altitude float64
+	// Interval is how often a new moveTo are sent to the drone.
+	Interval time.Duration
+}
+
+// FollowMe will make the drone follow the positions received from the
+// PositionSource, keeping the distance and bearing to the operator
+// given in the config. For each interval the latest operator position
+// is used to calculate a new waypoint which is given to the drone with
+// a moveTo command, and the drone is turned to face the operator.
+// FollowMe will block until the context is cancelled or the source is
+// closed, and a CancelMoveTo will then be sent to the drone. An error
+// is returned if a moveTo mission or Orbit is already flying the drone.
+func (d *Drone) FollowMe(ctx context.Context, src PositionSource, conf FollowMeConfig) error {
+	if conf.Interval <= 0 {
+		conf.Interval = time.Second
+	}
+
+	if err := d.checkGPSGuard(); err != nil {
+		return fmt.Errorf("FollowMe: %v", err)
+	}
+
+	release, err := d.claimDirectMoveTo("FollowMe")
+	if err != nil {
+		return fmt.Errorf("FollowMe: %v", err)
+	}
+	defer release()
+
+	chPos, err := src.Positions(ctx)
+	if err != nil {
+		return fmt.Errorf("FollowMe: failed to start position source: %v", err)
+	}
+
+	defer func() {
+		err := d.sendCmd(Command(PilotingCancelMoveTo), &Ardrone3PilotingCancelMoveToArguments{})
+		if err != nil {
+			log.Printf("error: FollowMe: failed to cancel moveTo: %v\n", err)
+		}
+	}()
+
+	ticker := time.NewTicker(conf.Interval)
+	defer ticker.Stop()
+
+	var operator Position
+	var haveOperator bool
+	var lastSent Position
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("info: exiting FollowMe\n")
+			return nil
+		case pos, ok := <-chPos:
+			if !ok {
+				log.Printf("info: FollowMe: position source closed\n")
+				return nil
+			}
+			operator = pos
+			haveOperator = true
+		case <-ticker.C:
+			if !haveOperator {
+				continue
+			}
+
+			lat, lon := offsetPosition(operator.Latitude, operator.Longitude, conf.Distance, conf.Bearing)
+			wp := Position{Latitude: lat, Longitude: lon, Altitude: conf.Altitude}
+			// No need to bother the drone if the operator have not moved.
+			if wp == lastSent {
+				continue
+			}
+
+			// Face back towards the operator.
+			heading := math.Mod(conf.Bearing+180, 360)
+			if err := d.moveToHeading(wp, heading); err != nil {
+				log.Printf("error: FollowMe: %v\n", err)
+				continue
+			}
+			lastSent = wp
+		}
+	}
+}
+
+// moveToHeading will send a moveTo command to the drone for the
+// position given, and make the drone turn to the heading given in
+// degrees while moving.
+func (d *Drone) moveToHeading(p Position, heading float64) error {
+	if d.MissionPaused() {
+		return fmt.Errorf("moveToHeading: mission is paused")
+	}
+	if err := d.checkFlyingStateFor(ActionMoveToExecute); err != nil {
+		return fmt.Errorf("moveToHeading: %v", err)
+	}
+
+	arg := &Ardrone3PilotingmoveToArguments{
+		Latitude:        p.Latitude,
+		Longitude:       p.Longitude,
+		Altitude:        p.Altitude,
+		Orientationmode: uint32(MoveToOrientationHeadingDuring),
+		Heading:         float32(heading),
+	}
+	d.limitMoveTo(arg)
+
+	return d.sendCmd(Command(PilotingmoveTo), arg)
+}
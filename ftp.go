@@ -0,0 +1,248 @@
+package parrotbebop
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ftpTimeout is the timeout for each operation on the FTP
+	// connections with the drone.
+	ftpTimeout = time.Second * 10
+	// ftpStorageDir is the directory on the drone holding the storage of
+	// each product, like Bebop_Drone and Bebop_2, where the media is in
+	// the media directory of the product.
+	ftpStorageDir = "internal_000"
+)
+
+// ftpConn is a minimal FTP client with the commands needed for listing
+// the media on the drone, where the drone allows anonymous login.
+type ftpConn struct {
+	conn net.Conn
+	text *textproto.Conn
+	host string
+}
+
+// dialFTP will connect and log in to the FTP server at the address.
+func dialFTP(addr string) (*ftpConn, error) {
+	conn, err := net.DialTimeout("tcp", addr, ftpTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("dialFTP: %v", err)
+	}
+
+	host, _, _ := net.SplitHostPort(addr)
+	c := &ftpConn{
+		conn: conn,
+		text: textproto.NewConn(conn),
+		host: host,
+	}
+
+	conn.SetDeadline(time.Now().Add(ftpTimeout))
+	if _, _, err := c.text.ReadResponse(220); err != nil {
+		c.close()
+		return nil, fmt.Errorf("dialFTP: %v", err)
+	}
+
+	code, _, err := c.cmd(0, "USER anonymous")
+	if err != nil {
+		c.close()
+		return nil, fmt.Errorf("dialFTP: %v", err)
+	}
+	if code == 331 {
+		if _, _, err := c.cmd(230, "PASS anonymous"); err != nil {
+			c.close()
+			return nil, fmt.Errorf("dialFTP: %v", err)
+		}
+	} else if code != 230 {
+		c.close()
+		return nil, fmt.Errorf("dialFTP: login failed with code %v", code)
+	}
+
+	return c, nil
+}
+
+// cmd will send the command, and read the response which must have the
+// code given, or any code if 0.
+func (c *ftpConn) cmd(expectCode int, format string, args ...interface{}) (int, string, error) {
+	c.conn.SetDeadline(time.Now().Add(ftpTimeout))
+
+	if err := c.text.PrintfLine(format, args...); err != nil {
+		return 0, "", err
+	}
+
+	return c.text.ReadResponse(expectCode)
+}
+
+// pasvAddrRe matches the address in the response to PASV.
+var pasvAddrRe = regexp.MustCompile(`(\d+),(\d+),(\d+),(\d+),(\d+),(\d+)`)
+
+// passive will open a data connection with the PASV command. Only the
+// port of the response is used, with the host of the control
+// connection, so it also works through NAT.
+func (c *ftpConn) passive() (net.Conn, error) {
+	_, msg, err := c.cmd(227, "PASV")
+	if err != nil {
+		return nil, fmt.Errorf("passive: %v", err)
+	}
+
+	m := pasvAddrRe.FindStringSubmatch(msg)
+	if m == nil {
+		return nil, fmt.Errorf("passive: malformed PASV response: %v", msg)
+	}
+	p1, _ := strconv.Atoi(m[5])
+	p2, _ := strconv.Atoi(m[6])
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(c.host, strconv.Itoa(p1*256+p2)), ftpTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("passive: %v", err)
+	}
+
+	return conn, nil
+}
+
+// nameList will return the names in the directory with the NLST
+// command, where a directory not found gives an empty list.
+func (c *ftpConn) nameList(dir string) ([]string, error) {
+	data, err := c.passive()
+	if err != nil {
+		return nil, fmt.Errorf("nameList: %v", err)
+	}
+	defer data.Close()
+
+	code, msg, err := c.cmd(0, "NLST %v", dir)
+	if err != nil {
+		return nil, fmt.Errorf("nameList: %v", err)
+	}
+	switch {
+	case code == 550:
+		return nil, nil
+	case code != 125 && code != 150:
+		return nil, fmt.Errorf("nameList: NLST failed with %v %v", code, msg)
+	}
+
+	data.SetDeadline(time.Now().Add(ftpTimeout))
+	var names []string
+	scanner := bufio.NewScanner(data)
+	for scanner.Scan() {
+		if n := strings.TrimSpace(scanner.Text()); n != "" {
+			names = append(names, path.Base(n))
+		}
+	}
+	if err := scanner.Err(); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("nameList: %v", err)
+	}
+
+	c.conn.SetDeadline(time.Now().Add(ftpTimeout))
+	if _, _, err := c.text.ReadResponse(226); err != nil {
+		return nil, fmt.Errorf("nameList: %v", err)
+	}
+
+	return names, nil
+}
+
+// close will end the FTP session.
+func (c *ftpConn) close() error {
+	c.conn.SetDeadline(time.Now().Add(ftpTimeout))
+	c.text.PrintfLine("QUIT")
+
+	return c.text.Close()
+}
+
+// MediaFile is a media file stored on the drone.
+type MediaFile struct {
+	// Name is the file name, and Path the full path on the FTP server.
+	Name string
+	Path string
+	// Time is when the media was recorded, from the time stamp in the
+	// file name, which is only right if the date and time of the drone
+	// was set by the controller.
+	Time time.Time
+	// Photo is true for pictures, and false for videos.
+	Photo bool
+}
+
+// mediaTimeRe matches the time stamp in the media file names, like
+// Bebop_2_2016-06-08T151036+0200_5D6C85.jpg.
+var mediaTimeRe = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{6}[+-]\d{4}`)
+
+// parseMediaFile will return the media file for the file name, or false
+// if not a picture or video with a time stamp.
+func parseMediaFile(dir string, name string) (MediaFile, bool) {
+	var photo bool
+	switch strings.ToLower(path.Ext(name)) {
+	case ".jpg", ".dng":
+		photo = true
+	case ".mp4":
+	default:
+		return MediaFile{}, false
+	}
+
+	ts := mediaTimeRe.FindString(name)
+	if ts == "" {
+		return MediaFile{}, false
+	}
+	t, err := time.Parse(dateFormat+timeFormat, ts)
+	if err != nil {
+		return MediaFile{}, false
+	}
+
+	return MediaFile{
+		Name:  name,
+		Path:  path.Join(dir, name),
+		Time:  t,
+		Photo: photo,
+	}, true
+}
+
+// listMedia will list the media files in the media directory of each
+// product in the storage of the FTP server, ordered by time.
+func listMedia(addr string) ([]MediaFile, error) {
+	c, err := dialFTP(addr)
+	if err != nil {
+		return nil, fmt.Errorf("listMedia: %v", err)
+	}
+	defer c.close()
+
+	products, err := c.nameList(ftpStorageDir)
+	if err != nil {
+		return nil, fmt.Errorf("listMedia: %v", err)
+	}
+
+	var files []MediaFile
+	for _, p := range products {
+		dir := path.Join(ftpStorageDir, p, "media")
+		names, err := c.nameList(dir)
+		if err != nil {
+			return nil, fmt.Errorf("listMedia: %v", err)
+		}
+
+		for _, n := range names {
+			if f, ok := parseMediaFile(dir, n); ok {
+				files = append(files, f)
+			}
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Time.Before(files[j].Time) })
+
+	return files, nil
+}
+
+// ListMedia will list the pictures and videos stored on the drone over
+// FTP, on the user port given by the drone in the discovery.
+func (d *Drone) ListMedia() ([]MediaFile, error) {
+	if d.portC2DUser == "" {
+		return nil, fmt.Errorf("ListMedia: FTP port not known, the drone is not discovered")
+	}
+
+	return listMedia(net.JoinHostPort(d.addressDrone, d.portC2DUser))
+}
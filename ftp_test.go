@@ -0,0 +1,118 @@
+package parrotbebop
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeFTPServer will serve NLST of the directories given, with anonymous
+// login, and return the address of the server.
+func fakeFTPServer(t *testing.T, dirs map[string][]string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var data net.Listener
+		r := bufio.NewReader(conn)
+		fmt.Fprintf(conn, "220 ready\r\n")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch fields[0] {
+			case "USER":
+				fmt.Fprintf(conn, "331 password please\r\n")
+			case "PASS":
+				fmt.Fprintf(conn, "230 logged in\r\n")
+			case "PASV":
+				data, err = net.Listen("tcp", "127.0.0.1:0")
+				if err != nil {
+					return
+				}
+				port := data.Addr().(*net.TCPAddr).Port
+				fmt.Fprintf(conn, "227 Entering Passive Mode (10,0,0,1,%d,%d)\r\n", port/256, port%256)
+			case "NLST":
+				dc, err := data.Accept()
+				data.Close()
+				if err != nil {
+					return
+				}
+				names, ok := dirs[fields[1]]
+				if !ok {
+					dc.Close()
+					fmt.Fprintf(conn, "550 not found\r\n")
+					continue
+				}
+				fmt.Fprintf(conn, "150 listing\r\n")
+				for _, n := range names {
+					fmt.Fprintf(dc, "%v\r\n", n)
+				}
+				dc.Close()
+				fmt.Fprintf(conn, "226 done\r\n")
+			case "QUIT":
+				fmt.Fprintf(conn, "221 bye\r\n")
+				return
+			default:
+				fmt.Fprintf(conn, "502 not implemented\r\n")
+			}
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func TestListMedia(t *testing.T) {
+	addr := fakeFTPServer(t, map[string][]string{
+		"internal_000": {"Bebop_2", "Debug"},
+		"internal_000/Bebop_2/media": {
+			"Bebop_2_2016-06-08T151040+0200_5D6C85.mp4",
+			"Bebop_2_2016-06-08T151036+0200_5D6C85.jpg",
+			"Bebop_2_2016-06-08T151038+0200_5D6C85.dng",
+			"notes.txt",
+		},
+	})
+
+	files, err := listMedia(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("got %v files, want 3: %+v", len(files), files)
+	}
+
+	first := files[0]
+	if first.Path != "internal_000/Bebop_2/media/Bebop_2_2016-06-08T151036+0200_5D6C85.jpg" || !first.Photo {
+		t.Fatalf("wrong first file: %+v", first)
+	}
+	want := time.Date(2016, 6, 8, 13, 10, 36, 0, time.UTC)
+	if !first.Time.Equal(want) {
+		t.Fatalf("got time %v, want %v", first.Time, want)
+	}
+	if files[2].Photo {
+		t.Fatalf("video listed as photo: %+v", files[2])
+	}
+}
+
+func TestListMediaNotDiscovered(t *testing.T) {
+	d := NewDrone()
+	d.portC2DUser = ""
+
+	if _, err := d.ListMedia(); err == nil {
+		t.Fatalf("expected error when the FTP port is not known")
+	}
+}
@@ -0,0 +1,114 @@
+package parrotbebop
+
+import (
+	"math"
+)
+
+// earthRadius is the mean radius of the earth in meters.
+const earthRadius = 6371000.0
+
+// offsetPosition will return the position found by moving distance
+// meters from lat/lon in the direction of bearing degrees.
+func offsetPosition(lat float64, lon float64, distance float64, bearing float64) (float64, float64) {
+	toRad := math.Pi / 180
+	lat1 := lat * toRad
+	lon1 := lon * toRad
+	brng := bearing * toRad
+	dr := distance / earthRadius
+
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(dr) + math.Cos(lat1)*math.Sin(dr)*math.Cos(brng))
+	lon2 := lon1 + math.Atan2(math.Sin(brng)*math.Sin(dr)*math.Cos(lat1), math.Cos(dr)-math.Sin(lat1)*math.Sin(lat2))
+
+	return lat2 / toRad, math.Mod(lon2/toRad+540, 360) - 180
+}
+
+// bearingTo will return the initial bearing in degrees [0, 360) for
+// going from lat1/lon1 to lat2/lon2.
+func bearingTo(lat1 float64, lon1 float64, lat2 float64, lon2 float64) float64 {
+	toRad := math.Pi / 180
+	rLat1 := lat1 * toRad
+	rLat2 := lat2 * toRad
+	dLon := (lon2 - lon1) * toRad
+
+	y := math.Sin(dLon) * math.Cos(rLat2)
+	x := math.Cos(rLat1)*math.Sin(rLat2) - math.Sin(rLat1)*math.Cos(rLat2)*math.Cos(dLon)
+
+	return math.Mod(math.Atan2(y, x)/toRad+360, 360)
+}
+
+// distanceTo will return the distance in meters along the surface of
+// the earth between lat1/lon1 and lat2/lon2.
+func distanceTo(lat1 float64, lon1 float64, lat2 float64, lon2 float64) float64 {
+	toRad := math.Pi / 180
+	dLat := (lat2 - lat1) * toRad
+	dLon := (lon2 - lon1) * toRad
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadius * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// DistanceTo will return the distance in meters along the surface of
+// the earth from p to q. The altitude is not taken into account.
+func (p Position) DistanceTo(q Position) float64 {
+	return distanceTo(p.Latitude, p.Longitude, q.Latitude, q.Longitude)
+}
+
+// BearingTo will return the initial bearing in degrees [0, 360) for
+// going from p to q.
+func (p Position) BearingTo(q Position) float64 {
+	return bearingTo(p.Latitude, p.Longitude, q.Latitude, q.Longitude)
+}
+
+// Offset will return the position found by moving distance meters from
+// p in the direction of bearing degrees, keeping the altitude.
+func (p Position) Offset(distance float64, bearing float64) Position {
+	lat, lon := offsetPosition(p.Latitude, p.Longitude, distance, bearing)
+
+	return Position{Latitude: lat, Longitude: lon, Altitude: p.Altitude}
+}
+
+// Interpolate will return the position at the fraction f [0, 1] of the
+// way from a to b along the great circle, where the altitude changes
+// linearly.
+func Interpolate(a Position, b Position, f float64) Position {
+	toRad := math.Pi / 180
+	lat1, lon1 := a.Latitude*toRad, a.Longitude*toRad
+	lat2, lon2 := b.Latitude*toRad, b.Longitude*toRad
+
+	// The angular distance between the positions.
+	delta := a.DistanceTo(b) / earthRadius
+	if delta == 0 {
+		return Position{Latitude: a.Latitude, Longitude: a.Longitude, Altitude: a.Altitude + (b.Altitude-a.Altitude)*f}
+	}
+
+	fa := math.Sin((1-f)*delta) / math.Sin(delta)
+	fb := math.Sin(f*delta) / math.Sin(delta)
+	x := fa*math.Cos(lat1)*math.Cos(lon1) + fb*math.Cos(lat2)*math.Cos(lon2)
+	y := fa*math.Cos(lat1)*math.Sin(lon1) + fb*math.Cos(lat2)*math.Sin(lon2)
+	z := fa*math.Sin(lat1) + fb*math.Sin(lat2)
+
+	return Position{
+		Latitude:  math.Atan2(z, math.Hypot(x, y)) / toRad,
+		Longitude: math.Atan2(y, x) / toRad,
+		Altitude:  a.Altitude + (b.Altitude-a.Altitude)*f,
+	}
+}
+
+// SplitLeg will split the leg from a to b into legs no longer than
+// maxLength meters, and return the positions to fly to in order, where
+// the last one is b. If maxLength is 0 or less only b is returned.
+func SplitLeg(a Position, b Position, maxLength float64) []Position {
+	dist := a.DistanceTo(b)
+	if maxLength <= 0 || dist <= maxLength {
+		return []Position{b}
+	}
+
+	n := int(math.Ceil(dist / maxLength))
+	legs := make([]Position, 0, n)
+	for i := 1; i < n; i++ {
+		legs = append(legs, Interpolate(a, b, float64(i)/float64(n)))
+	}
+
+	return append(legs, b)
+}
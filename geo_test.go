@@ -0,0 +1,54 @@
+package parrotbebop
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPositionGeo(t *testing.T) {
+	a := Position{Latitude: 60, Longitude: 10, Altitude: 10}
+	b := a.Offset(1000, 45)
+
+	if d := a.DistanceTo(b); math.Abs(d-1000) > 0.01 {
+		t.Fatalf("wrong distance: %v", d)
+	}
+	if brng := a.BearingTo(b); math.Abs(brng-45) > 0.01 {
+		t.Fatalf("wrong bearing: %v", brng)
+	}
+	if b.Altitude != a.Altitude {
+		t.Fatalf("altitude changed by offset: %v", b.Altitude)
+	}
+
+	b.Altitude = 30
+	mid := Interpolate(a, b, 0.5)
+	if d := a.DistanceTo(mid); math.Abs(d-500) > 0.01 {
+		t.Fatalf("wrong distance to the middle: %v", d)
+	}
+	if d := mid.DistanceTo(b); math.Abs(d-500) > 0.01 {
+		t.Fatalf("wrong distance from the middle: %v", d)
+	}
+	if mid.Altitude != 20 {
+		t.Fatalf("wrong altitude in the middle: %v", mid.Altitude)
+	}
+}
+
+func TestSplitLeg(t *testing.T) {
+	a := Position{Latitude: 60, Longitude: 10, Altitude: 10}
+	b := a.Offset(1000, 90)
+
+	legs := SplitLeg(a, b, 300)
+	if len(legs) != 4 || legs[3] != b {
+		t.Fatalf("expected 4 legs ending at b, got %v", legs)
+	}
+	prev := a
+	for _, l := range legs {
+		if d := prev.DistanceTo(l); d > 300 {
+			t.Fatalf("leg too long: %v", d)
+		}
+		prev = l
+	}
+
+	if legs := SplitLeg(a, b, 0); len(legs) != 1 || legs[0] != b {
+		t.Fatalf("expected a single leg without limit, got %v", legs)
+	}
+}
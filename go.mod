@@ -4,5 +4,6 @@ go 1.15
 
 require (
 	github.com/eiannone/keyboard v0.0.0-20200508000154-caf4b762e807
+	gobot.io/x/gobot v1.16.0
 	golang.org/x/crypto v0.0.0-20201016220609-9e8e0b390897
 )
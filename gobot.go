@@ -0,0 +1,262 @@
+// +build gobot
+
+package parrotbebop
+
+// The Gobot adaptor and driver are only built with the gobot build tag,
+// so the package does not depend on Gobot for everyone else. Build with
+//
+//	go build -tags gobot
+//
+// and use them as any other Gobot robot :
+//
+//	adaptor := parrotbebop.NewGobotAdaptor(parrotbebop.NewDrone())
+//	driver := parrotbebop.NewGobotDriver(adaptor)
+//	robot := gobot.NewRobot("bebop", []gobot.Connection{adaptor}, []gobot.Device{driver}, work)
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gobot.io/x/gobot"
+)
+
+// The adaptor and driver must implement the Gobot interfaces.
+var (
+	_ gobot.Adaptor = (*GobotAdaptor)(nil)
+	_ gobot.Driver  = (*GobotDriver)(nil)
+)
+
+// gobotConnectTimeout is how long Connect will wait for the drone to
+// be ready.
+const gobotConnectTimeout = time.Minute
+
+// The Gobot events published by the GobotDriver.
+const (
+	// GobotFlyingEvent is published when the flying state changes,
+	// with the name of the FlyingState as the data.
+	GobotFlyingEvent = "flying"
+	// GobotAlertEvent is published when the drone reports an alert,
+	// with the AlertState as the data.
+	GobotAlertEvent = "alert"
+	// GobotBatteryEvent is published when the battery level changes,
+	// with the percentage as the data.
+	GobotBatteryEvent = "battery"
+	// GobotConnectionEvent is published for each step of the connection
+	// with the drone, with the ConnectionEvent as the data.
+	GobotConnectionEvent = "connection"
+)
+
+// GobotAdaptor is a gobot.Adaptor for the connection with the drone.
+type GobotAdaptor struct {
+	name  string
+	drone *Drone
+}
+
+// NewGobotAdaptor will return a Gobot adaptor for the drone, which is
+// started by Connect.
+func NewGobotAdaptor(d *Drone) *GobotAdaptor {
+	d.SetHeadless(true)
+
+	return &GobotAdaptor{
+		name:  gobot.DefaultName("Bebop"),
+		drone: d,
+	}
+}
+
+// Name will return the name of the adaptor.
+func (a *GobotAdaptor) Name() string { return a.name }
+
+// SetName will set the name of the adaptor.
+func (a *GobotAdaptor) SetName(n string) { a.name = n }
+
+// Drone will return the drone of the adaptor, for the functionality
+// not covered by the GobotDriver.
+func (a *GobotAdaptor) Drone() *Drone { return a.drone }
+
+// Connect will start the connection with the drone, and wait until
+// the drone is ready.
+func (a *GobotAdaptor) Connect() error {
+	events, unsubscribe := a.drone.Subscribe()
+	defer unsubscribe()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- a.drone.Start()
+	}()
+
+	timeout := time.After(gobotConnectTimeout)
+	for !a.drone.Ready() {
+		select {
+		case <-events:
+		case err := <-errCh:
+			return fmt.Errorf("Connect: %v", err)
+		case <-timeout:
+			return fmt.Errorf("Connect: drone not ready within %v", gobotConnectTimeout)
+		}
+	}
+
+	return nil
+}
+
+// Finalize will land the drone if flying. The connection can not be
+// stopped, and is kept until the program exits.
+func (a *GobotAdaptor) Finalize() error {
+	if s, ok := a.drone.FlyingState(); ok && s != FlyingStateLanded {
+		return a.drone.SendAction(ActionLanding)
+	}
+
+	return nil
+}
+
+// GobotDriver is a gobot.Driver for piloting the drone, with the same
+// methods as the Bebop driver of Gobot, so it can be used in it's
+// place.
+type GobotDriver struct {
+	name    string
+	adaptor *GobotAdaptor
+	gobot.Eventer
+
+	mu sync.Mutex
+	// The current PCMD values, where each of the movement methods
+	// changes one of them.
+	roll, pitch, yaw, gaz int8
+
+	unsubscribe func()
+}
+
+// NewGobotDriver will return a Gobot driver for the drone of the
+// adaptor.
+func NewGobotDriver(a *GobotAdaptor) *GobotDriver {
+	d := &GobotDriver{
+		name:    gobot.DefaultName("Bebop"),
+		adaptor: a,
+		Eventer: gobot.NewEventer(),
+	}
+	for _, e := range []string{GobotFlyingEvent, GobotAlertEvent, GobotBatteryEvent, GobotConnectionEvent} {
+		d.AddEvent(e)
+	}
+
+	return d
+}
+
+// Name will return the name of the driver.
+func (d *GobotDriver) Name() string { return d.name }
+
+// SetName will set the name of the driver.
+func (d *GobotDriver) SetName(n string) { d.name = n }
+
+// Connection will return the adaptor of the driver.
+func (d *GobotDriver) Connection() gobot.Connection { return d.adaptor }
+
+// Start will start publishing the events of the drone as Gobot events.
+func (d *GobotDriver) Start() error {
+	events, unsubscribe := d.adaptor.drone.Subscribe()
+
+	d.mu.Lock()
+	d.unsubscribe = unsubscribe
+	d.mu.Unlock()
+
+	go func() {
+		var battery uint8
+		for ev := range events {
+			switch ev.Type {
+			case EventFlyingStateChanged:
+				d.Publish(GobotFlyingEvent, ev.Value.(FlyingState).String())
+			case EventAlertStateChanged:
+				d.Publish(GobotAlertEvent, ev.Value)
+			case EventConnection:
+				d.Publish(GobotConnectionEvent, ev.Value)
+			}
+
+			// The battery level is not an event, so check it when
+			// something else happens.
+			if b := d.adaptor.drone.Telemetry().Battery; b != battery {
+				battery = b
+				d.Publish(GobotBatteryEvent, b)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Halt will stop publishing the events, and hover the drone.
+func (d *GobotDriver) Halt() error {
+	d.mu.Lock()
+	if d.unsubscribe != nil {
+		d.unsubscribe()
+		d.unsubscribe = nil
+	}
+	d.mu.Unlock()
+
+	return d.Stop()
+}
+
+// TakeOff will make the drone take off.
+func (d *GobotDriver) TakeOff() error {
+	return d.adaptor.drone.SendAction(ActionTakeoff)
+}
+
+// Land will make the drone land.
+func (d *GobotDriver) Land() error {
+	return d.adaptor.drone.SendAction(ActionLanding)
+}
+
+// Emergency will cut the motors of the drone.
+func (d *GobotDriver) Emergency() error {
+	return d.adaptor.drone.SendAction(ActionEmergency)
+}
+
+// FlatTrim will calibrate the drone, which should be done on a flat
+// surface before taking off.
+func (d *GobotDriver) FlatTrim() error {
+	return d.adaptor.drone.FlatTrim()
+}
+
+// StartRecording will start recording video on the drone.
+func (d *GobotDriver) StartRecording() error {
+	return d.adaptor.drone.SetVideoRecording(true)
+}
+
+// StopRecording will stop recording video on the drone.
+func (d *GobotDriver) StopRecording() error {
+	return d.adaptor.drone.SetVideoRecording(false)
+}
+
+// Up, Down, Left, Right, Forward, Backward, Clockwise and
+// CounterClockwise will set the speed in percent [0, 100] of the
+// movement, which continues until changed or Stop is called.
+func (d *GobotDriver) Up(val int) error               { return d.move(&d.gaz, val, 1) }
+func (d *GobotDriver) Down(val int) error             { return d.move(&d.gaz, val, -1) }
+func (d *GobotDriver) Left(val int) error             { return d.move(&d.roll, val, -1) }
+func (d *GobotDriver) Right(val int) error            { return d.move(&d.roll, val, 1) }
+func (d *GobotDriver) Forward(val int) error          { return d.move(&d.pitch, val, 1) }
+func (d *GobotDriver) Backward(val int) error         { return d.move(&d.pitch, val, -1) }
+func (d *GobotDriver) Clockwise(val int) error        { return d.move(&d.yaw, val, 1) }
+func (d *GobotDriver) CounterClockwise(val int) error { return d.move(&d.yaw, val, -1) }
+
+// Stop will stop all the movement, and make the drone hover.
+func (d *GobotDriver) Stop() error {
+	d.mu.Lock()
+	d.roll, d.pitch, d.yaw, d.gaz = 0, 0, 0, 0
+	d.mu.Unlock()
+
+	return d.adaptor.drone.SendPcmd(0, 0, 0, 0)
+}
+
+// move will set the PCMD field to the value in the direction given by
+// sign, and send the PCMD as stick inputs, shaped as set with
+// SetInputShaping.
+func (d *GobotDriver) move(field *int8, val int, sign int) error {
+	if val < 0 || val > 100 {
+		return fmt.Errorf("move: value must be within [0, 100], got %v", val)
+	}
+
+	d.mu.Lock()
+	*field = int8(val * sign)
+	roll, pitch, yaw, gaz := d.roll, d.pitch, d.yaw, d.gaz
+	d.mu.Unlock()
+
+	return d.adaptor.drone.SendSticks(float64(roll)/100, float64(pitch)/100, float64(yaw)/100, float64(gaz)/100)
+}
@@ -0,0 +1,63 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// gpsGuard holds the requirements for the gps state that must be
+// met before a moveTo mission are allowed to start. The guard is
+// disabled by default.
+type gpsGuard struct {
+	mu sync.Mutex
+	// enabled is true when the guard should be checked.
+	enabled bool
+	// minSatellites is the minimum number of satellites needed.
+	minSatellites uint8
+}
+
+// SetGPSGuard will enable or disable the gps guard. When enabled,
+// moveTo missions will be refused, and a warning will be given on
+// takeoff, if the drone have no gps fix, or sees fewer satellites
+// than minSatellites.
+func (d *Drone) SetGPSGuard(enabled bool, minSatellites uint8) {
+	d.gpsGuard.mu.Lock()
+	defer d.gpsGuard.mu.Unlock()
+
+	d.gpsGuard.enabled = enabled
+	d.gpsGuard.minSatellites = minSatellites
+}
+
+// checkGPSGuard will check the current gps telemetry against the
+// requirements of the gps guard. nil is returned if the guard is
+// disabled or the requirements are met.
+func (d *Drone) checkGPSGuard() error {
+	d.gpsGuard.mu.Lock()
+	enabled := d.gpsGuard.enabled
+	minSatellites := d.gpsGuard.minSatellites
+	d.gpsGuard.mu.Unlock()
+
+	if !enabled {
+		return nil
+	}
+
+	t := d.telemetry.snapshot()
+
+	switch {
+	case !t.GPSFixed:
+		return fmt.Errorf("gps guard: drone have no gps fix")
+	case t.NumberOfSatellites < minSatellites:
+		return fmt.Errorf("gps guard: %v satellites, need at least %v", t.NumberOfSatellites, minSatellites)
+	}
+
+	return nil
+}
+
+// warnGPSGuard will check the gps guard, and only log a warning if
+// the requirements are not met.
+func (d *Drone) warnGPSGuard(action string) {
+	if err := d.checkGPSGuard(); err != nil {
+		log.Printf("warning: %v: %v\n", action, err)
+	}
+}
@@ -0,0 +1,632 @@
+package parrotbebop
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// groundStationTokenHeader is the header holding the token of the
+// requests changing the state of the drone.
+const groundStationTokenHeader = "X-Bebop-Token"
+
+// groundStationConfig holds the token of the ground station API set by
+// the operator, where an empty token only allows the ground station
+// page.
+type groundStationConfig struct {
+	mu    sync.Mutex
+	token string
+}
+
+// SetGroundStationToken will set the token other clients than the
+// ground station page must give in the X-Bebop-Token header of the
+// requests to the ground station API that changes the state of the
+// drone, like the actions and the waypoints. The token is never written
+// into the ground station page, which uses it's own random token
+// created when the ground station is started. Must be called before
+// StartGroundStation.
+func (d *Drone) SetGroundStationToken(token string) {
+	d.groundStation.mu.Lock()
+	defer d.groundStation.mu.Unlock()
+
+	d.groundStation.token = token
+}
+
+// groundStationOperatorToken will return the token set with
+// SetGroundStationToken, or an empty string if none.
+func (d *Drone) groundStationOperatorToken() string {
+	d.groundStation.mu.Lock()
+	defer d.groundStation.mu.Unlock()
+
+	return d.groundStation.token
+}
+
+// newSessionToken will return a new random token for the ground station
+// page.
+func newSessionToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// requireToken will only let the requests changing the state through
+// when they have one of the tokens, and are not sent from another site,
+// so a web page open in the browser of the operator can't fly the
+// drone. The custom header also makes the browser ask before sending
+// the request cross site, which is never allowed. Empty tokens are
+// never accepted.
+func requireToken(tokens []string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			h(w, r)
+			return
+		}
+
+		if origin := r.Header.Get("Origin"); origin != "" {
+			u, err := url.Parse(origin)
+			if err != nil || u.Host != r.Host {
+				http.Error(w, "cross origin request not allowed", http.StatusForbidden)
+				return
+			}
+		}
+
+		got := r.Header.Get(groundStationTokenHeader)
+		for _, token := range tokens {
+			if token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1 {
+				h(w, r)
+				return
+			}
+		}
+
+		http.Error(w, "missing or wrong "+groundStationTokenHeader, http.StatusForbidden)
+	}
+}
+
+// requireHost will refuse the requests where the Host header is not the
+// address listened on, so a site resolving it's own name to the address
+// with DNS rebinding can't read the page or use the API. When listening
+// on a loopback address localhost and all the loopback addresses are
+// allowed, and when listening on all interfaces localhost and all the
+// IP addresses are allowed, but never other names.
+func requireHost(listenHost string, port string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowedHost(listenHost, port, r.Host) {
+			http.Error(w, "host not allowed", http.StatusForbidden)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost will return true if the host of a request is allowed for
+// the host and port listened on.
+func allowedHost(listenHost string, port string, host string) bool {
+	h, p, err := net.SplitHostPort(host)
+	if err != nil {
+		h, p = strings.Trim(host, "[]"), "80"
+	}
+	if p != port {
+		return false
+	}
+	if strings.EqualFold(h, listenHost) {
+		return true
+	}
+
+	isLoopback := func(name string) bool {
+		ip := net.ParseIP(name)
+		return strings.EqualFold(name, "localhost") || (ip != nil && ip.IsLoopback())
+	}
+	listenIP := net.ParseIP(listenHost)
+
+	switch {
+	case listenHost == "" || (listenIP != nil && listenIP.IsUnspecified()):
+		return strings.EqualFold(h, "localhost") || net.ParseIP(h) != nil
+	case isLoopback(listenHost):
+		return isLoopback(h)
+	}
+
+	return false
+}
+
+// groundStationActions are the actions that can be given with the
+// buttons of the ground station.
+var groundStationActions = map[string]InputAction{
+	"takeoff":     ActionTakeoff,
+	"usertakeoff": ActionUserTakeoff,
+	"land":        ActionLanding,
+	"home":        ActionNavigateHomeStart,
+	"stophome":    ActionNavigateHomeStop,
+	"hover":       ActionPcmdHover,
+	"emergency":   ActionEmergency,
+	"flattrim":    ActionFlatTrim,
+}
+
+// groundStationState is the state served to the ground station page.
+type groundStationState struct {
+	Telemetry   Telemetry
+	FlyingState string
+	Ready       bool
+	Home        HomePosition
+	Waypoints   []Position
+	Mission     MissionStatus
+}
+
+// StartGroundStation will start a http server on the address given,
+// serving a small web based ground station with a map of the position
+// of the drone, the home and the waypoints, the telemetry, the video
+// and buttons for takeoff, landing and return home.
+//
+//  /               : the ground station page.
+//  /api/state      : the telemetry, home and waypoints as JSON.
+//  /api/stats      : the network statistics as JSON.
+//  /api/action     : POST with ?name=takeoff|land|home|stophome|hover|emergency|flattrim.
+//  /api/waypoints  : GET to list the waypoints, POST with a JSON
+//                    Position and ?index=n to insert, where no index
+//                    appends, and DELETE with ?index=n to remove, or
+//                    no index to remove all.
+//  /api/waypoints/move : POST with ?from=n&to=m to reorder.
+//  /api/track.geojson : the flight log and the waypoints as GeoJSON,
+//                       as with WriteTrackGeoJSON.
+//  /api/track.kml  : the same as KML, as with WriteTrackKML.
+//  /video.h264     : the raw H264 stream, as with StartVideoPreview.
+//  /video.mjpeg    : the MJPEG stream shown on the page, only available
+//                    if a transcoder is given.
+//  /healthz, /readyz : the health of the driver, as with HealthHandler.
+//
+// The POST and DELETE requests must have the token of the page, or the
+// token set with SetGroundStationToken, in the X-Bebop-Token header,
+// and are refused from other sites. The token of the page is created
+// when started, and anyone able to load the page can use the API, so
+// only listen on addresses reachable by the operators. The requests
+// where the Host header is not the address listened on are refused.
+// An error is returned if the address can't be listened on. The server
+// is stopped when the context is done.
+func (d *Drone) StartGroundStation(ctx context.Context, addr string, transcoder VideoTranscoder) error {
+	session, err := newSessionToken()
+	if err != nil {
+		return fmt.Errorf("StartGroundStation: creating token: %v", err)
+	}
+	listenHost, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("StartGroundStation: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	d.registerVideoHandlers(mux, transcoder)
+	d.registerGroundStationHandlers(mux, transcoder != nil, session, d.groundStationOperatorToken())
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("StartGroundStation: %v", err)
+	}
+	// The port is taken from the listener, since it is chosen when
+	// given as 0.
+	_, port, err := net.SplitHostPort(l.Addr().String())
+	if err != nil {
+		l.Close()
+		return fmt.Errorf("StartGroundStation: %v", err)
+	}
+
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: requireHost(listenHost, port, mux),
+	}
+
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+
+	go func() {
+		if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+			log.Printf("error: ground station server failed: %v\n", err)
+		}
+	}()
+
+	log.Printf("info: ground station started on http://%v/\n", addr)
+
+	return nil
+}
+
+// registerGroundStationHandlers will register the page and the API of
+// the ground station on the mux, where the requests changing the state
+// need the session token written into the page, or the operator token
+// if not empty.
+func (d *Drone) registerGroundStationHandlers(mux *http.ServeMux, video bool, session string, operator string) {
+	tokens := []string{session, operator}
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		videoSrc := ""
+		if video {
+			videoSrc = "/video.mjpeg"
+		}
+		fmt.Fprintf(w, groundStationPage, videoSrc, session)
+	})
+
+	mux.HandleFunc("/api/state", func(w http.ResponseWriter, r *http.Request) {
+		state, known := d.FlyingState()
+		s := groundStationState{
+			Telemetry:   d.Telemetry(),
+			FlyingState: "unknown",
+			Ready:       d.Ready(),
+			Home:        d.Home(),
+			Waypoints:   d.Waypoints(),
+			Mission:     d.MissionStatus(),
+		}
+		if known {
+			s.FlyingState = state.String()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(s); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
+	mux.Handle("/api/stats", d.StatsHandler())
+	health := d.HealthHandler()
+	mux.Handle("/healthz", health)
+	mux.Handle("/readyz", health)
+
+	mux.HandleFunc("/api/action", requireToken(tokens, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		name := r.URL.Query().Get("name")
+		action, ok := groundStationActions[name]
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown action: %q", name), http.StatusBadRequest)
+			return
+		}
+
+		if err := d.SendAction(action); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		log.Printf("info: ground station: action %v given\n", name)
+	}))
+
+	mux.HandleFunc("/api/waypoints", requireToken(tokens, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			index, err := queryInt(r, "index", -1)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			var p Position
+			if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+				http.Error(w, fmt.Sprintf("bad waypoint: %v", err), http.StatusBadRequest)
+				return
+			}
+			if err := d.InsertWaypoint(index, p); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		case http.MethodDelete:
+			if r.URL.Query().Get("index") == "" {
+				d.ClearWaypoints()
+				break
+			}
+			index, err := queryInt(r, "index", 0)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := d.RemoveWaypoint(index); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(d.Waypoints()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}))
+
+	trackHandler := func(contentType string, write func(io.Writer, []FlightSample, []Position) error) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			var buf bytes.Buffer
+			if err := write(&buf, d.FlightLog(), d.Waypoints()); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", contentType)
+			w.Write(buf.Bytes())
+		}
+	}
+	mux.HandleFunc("/api/track.geojson", trackHandler("application/geo+json", WriteTrackGeoJSON))
+	mux.HandleFunc("/api/track.kml", trackHandler("application/vnd.google-earth.kml+xml", WriteTrackKML))
+
+	mux.HandleFunc("/api/waypoints/move", requireToken(tokens, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		from, err := queryInt(r, "from", -1)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		to, err := queryInt(r, "to", -1)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := d.MoveWaypoint(from, to); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(d.Waypoints()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}))
+}
+
+// queryInt will return the integer value of the query parameter, or
+// def if not given.
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("bad %v: %q", name, v)
+	}
+
+	return n, nil
+}
+
+// groundStationPage is the single page ground station, where the first
+// %s is the source of the video, or empty if no video is available, and
+// the second the session token of the API. The map
+// is drawn on a canvas relative to the drone, so no map tiles from the
+// internet are needed in the field.
+const groundStationPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Bebop ground station</title>
+<style>
+body { font-family: sans-serif; background: #222; color: #eee; margin: 0; display: flex; flex-wrap: wrap; }
+.panel { margin: 8px; padding: 8px; background: #333; border-radius: 4px; }
+table td { padding: 2px 8px; }
+button { margin: 4px; padding: 8px 16px; font-size: 16px; }
+#emergency { background: #c00; color: #fff; }
+img { max-width: 640px; display: block; }
+</style>
+</head>
+<body>
+<div class="panel">
+<canvas id="map" width="480" height="480"></canvas>
+<div>export the flight log and waypoints as <a href="/api/track.geojson" download="track.geojson">GeoJSON</a> or <a href="/api/track.kml" download="track.kml">KML</a></div>
+<div>scale: <span id="scale"></span> m across, click the map to add a waypoint at <input id="wpalt" type="number" value="10" size="4"> m</div>
+<table id="waypoints"></table>
+<button onclick="clearWaypoints()">Clear waypoints</button>
+</div>
+<div class="panel">
+<table>
+<tr><td>state</td><td id="state"></td></tr>
+<tr><td>ready</td><td id="ready"></td></tr>
+<tr><td>battery</td><td id="battery"></td></tr>
+<tr><td>altitude</td><td id="altitude"></td></tr>
+<tr><td>heading</td><td id="heading"></td></tr>
+<tr><td>speed</td><td id="speed"></td></tr>
+<tr><td>gps</td><td id="gps"></td></tr>
+<tr><td>position</td><td id="position"></td></tr>
+<tr><td>wind</td><td id="wind"></td></tr>
+<tr><td>mission</td><td id="mission"></td></tr>
+</table>
+<div>
+<button onclick="action('takeoff')">Takeoff</button>
+<button onclick="action('usertakeoff')">Hand launch</button>
+<button onclick="action('land')">Land</button>
+<button onclick="action('hover')">Hover</button>
+<button onclick="action('home')">Return home</button>
+<button onclick="action('stophome')">Stop return</button>
+<button onclick="action('flattrim')">Flat trim</button>
+<button id="emergency" onclick="if (confirm('Cut the motors?')) action('emergency')">Emergency</button>
+</div>
+<div id="error"></div>
+</div>
+<div class="panel" id="video"></div>
+<script>
+var videoSrc = "%s";
+var apiToken = "%s";
+if (videoSrc) {
+	var img = document.createElement("img");
+	img.src = videoSrc;
+	document.getElementById("video").appendChild(img);
+} else {
+	document.getElementById("video").textContent = "no video transcoder given";
+}
+
+var track = [];
+// view holds the center and span of the map last drawn, used to find
+// the position clicked on the map.
+var view = null;
+var lastWaypoints = "";
+
+function waypointRequest(method, url, body) {
+	fetch(url, {method: method, body: body, headers: {"X-Bebop-Token": apiToken}}).then(function(r) {
+		return r.text().then(function(t) {
+			document.getElementById("error").textContent = r.ok ? "" : t;
+		});
+	}).then(update);
+}
+
+function clearWaypoints() {
+	waypointRequest("DELETE", "/api/waypoints");
+}
+
+document.getElementById("map").addEventListener("click", function(e) {
+	if (!view) return;
+	var c = e.target, rect = c.getBoundingClientRect();
+	var x = e.clientX - rect.left, y = e.clientY - rect.top;
+	var p = {
+		Latitude: view.center.Latitude - (y - c.height / 2) * view.span / c.height / view.mLat,
+		Longitude: view.center.Longitude + (x - c.width / 2) * view.span / c.width / view.mLon,
+		Altitude: parseFloat(document.getElementById("wpalt").value)
+	};
+	waypointRequest("POST", "/api/waypoints", JSON.stringify(p));
+});
+
+function listWaypoints(wps) {
+	var key = JSON.stringify(wps);
+	if (key == lastWaypoints) return;
+	lastWaypoints = key;
+
+	var t = document.getElementById("waypoints");
+	t.innerHTML = "";
+	wps.forEach(function(w, i) {
+		var row = t.insertRow();
+		row.insertCell().textContent = (i + 1) + ": " + w.Latitude.toFixed(6) + ", " + w.Longitude.toFixed(6) + ", " + w.Altitude.toFixed(1) + " m";
+		[["up", i - 1], ["down", i + 1]].forEach(function(m) {
+			var b = document.createElement("button");
+			b.textContent = m[0];
+			b.disabled = m[1] < 0 || m[1] >= wps.length;
+			b.onclick = function() { waypointRequest("POST", "/api/waypoints/move?from=" + i + "&to=" + m[1]); };
+			row.insertCell().appendChild(b);
+		});
+		var del = document.createElement("button");
+		del.textContent = "delete";
+		del.onclick = function() { waypointRequest("DELETE", "/api/waypoints?index=" + i); };
+		row.insertCell().appendChild(del);
+	});
+}
+
+function action(name) {
+	fetch("/api/action?name=" + name, {method: "POST", headers: {"X-Bebop-Token": apiToken}}).then(function(r) {
+		return r.text().then(function(t) {
+			document.getElementById("error").textContent = r.ok ? "" : t;
+		});
+	});
+}
+
+function valid(p) {
+	return p && p.Latitude != 0 && p.Latitude != 500 && p.Longitude != 500;
+}
+
+function draw(s) {
+	var c = document.getElementById("map");
+	var ctx = c.getContext("2d");
+	ctx.fillStyle = "#111";
+	ctx.fillRect(0, 0, c.width, c.height);
+
+	var pos = s.Telemetry.Position;
+	var points = track.slice();
+	if (s.Home.Set) points.push(s.Home);
+	(s.Waypoints || []).forEach(function(w) { points.push(w); });
+	if (!valid(pos) && points.length == 0) return;
+	var center = valid(pos) ? pos : points[0];
+
+	// Meters per degree, and the scale fitting all the points.
+	var mLat = 111320, mLon = 111320 * Math.cos(center.Latitude * Math.PI / 180);
+	var span = 50;
+	points.forEach(function(p) {
+		span = Math.max(span, 2.2 * Math.abs((p.Latitude - center.Latitude) * mLat), 2.2 * Math.abs((p.Longitude - center.Longitude) * mLon));
+	});
+	document.getElementById("scale").textContent = span.toFixed(0);
+	view = {center: center, span: span, mLat: mLat, mLon: mLon};
+	function xy(p) {
+		return [c.width / 2 + (p.Longitude - center.Longitude) * mLon * c.width / span,
+			c.height / 2 - (p.Latitude - center.Latitude) * mLat * c.height / span];
+	}
+	function dot(p, color, r) {
+		var q = xy(p);
+		ctx.fillStyle = color;
+		ctx.beginPath();
+		ctx.arc(q[0], q[1], r, 0, 2 * Math.PI);
+		ctx.fill();
+	}
+
+	ctx.strokeStyle = "#555";
+	ctx.beginPath();
+	track.forEach(function(p, i) { var q = xy(p); if (i == 0) ctx.moveTo(q[0], q[1]); else ctx.lineTo(q[0], q[1]); });
+	ctx.stroke();
+
+	ctx.strokeStyle = "#fc0";
+	ctx.beginPath();
+	(s.Waypoints || []).forEach(function(w, i) {
+		var q = xy(w);
+		if (i == 0) ctx.moveTo(q[0], q[1]); else ctx.lineTo(q[0], q[1]);
+	});
+	ctx.stroke();
+	(s.Waypoints || []).forEach(function(w, i) {
+		dot(w, "#fc0", 5);
+		var q = xy(w);
+		ctx.fillText(String(i + 1), q[0] + 7, q[1] - 7);
+	});
+	if (s.Home.Set) dot(s.Home, "#0c0", 6);
+	if (valid(pos)) dot(pos, "#09f", 7);
+}
+
+function update() {
+	fetch("/api/state").then(function(r) { return r.json(); }).then(function(s) {
+		var t = s.Telemetry;
+		document.getElementById("state").textContent = s.FlyingState;
+		document.getElementById("ready").textContent = s.Ready;
+		document.getElementById("battery").textContent = t.Battery + " %%";
+		document.getElementById("altitude").textContent = t.Altitude.toFixed(1) + " m";
+		document.getElementById("heading").textContent = ((t.Yaw * 180 / Math.PI + 360) %% 360).toFixed(0) + "°";
+		document.getElementById("speed").textContent = t.GroundSpeed.toFixed(1) + " m/s, climb " + t.ClimbRate.toFixed(1) + " m/s";
+		document.getElementById("gps").textContent = (t.GPSFixed ? "fix" : "no fix") + ", " + t.NumberOfSatellites + " satellites";
+		document.getElementById("position").textContent = t.Position.Latitude.toFixed(6) + ", " + t.Position.Longitude.toFixed(6);
+		document.getElementById("wind").textContent = ["ok", "warning", "critical"][t.Wind];
+		var m = s.Mission;
+		document.getElementById("mission").textContent = !m.Active ? "-" :
+			"waypoint " + (m.NextWaypoint + 1) + "/" + m.Waypoints + ", " + m.Percent.toFixed(0) + " %%" +
+			(m.ETAKnown ? ", eta " + Math.round(m.ETA / 1e9) + " s" : "");
+		if (valid(t.Position)) {
+			var last = track[track.length - 1];
+			if (!last || last.Latitude != t.Position.Latitude || last.Longitude != t.Position.Longitude) {
+				track.push(t.Position);
+				if (track.length > 1000) track.shift();
+			}
+		}
+		listWaypoints(s.Waypoints || []);
+		draw(s);
+	}).catch(function(e) {
+		document.getElementById("error").textContent = "lost connection with driver";
+	});
+}
+
+setInterval(update, 500);
+update();
+</script>
+</body>
+</html>
+`
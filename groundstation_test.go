@@ -0,0 +1,203 @@
+package parrotbebop
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGroundStationHandlers(t *testing.T) {
+	d := NewDrone()
+	d.checkCmdFromDrone(protocolARCommands{}, CommonCommonStateBatteryStateChangedArguments{Percent: 64})
+
+	mux := http.NewServeMux()
+	d.registerGroundStationHandlers(mux, false, "session", "secret")
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `var videoSrc = "";`) || !strings.Contains(rec.Body.String(), `var apiToken = "session";`) {
+		t.Fatalf("bad page, code %v", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "secret") {
+		t.Fatalf("the operator token is written into the page")
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/state", nil))
+	var s groundStationState
+	if err := json.NewDecoder(rec.Body).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Telemetry.Battery != 64 || s.FlyingState != "unknown" {
+		t.Fatalf("wrong state: %+v", s)
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/action?name=barrelroll", nil)
+	req.Header.Set(groundStationTokenHeader, "secret")
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected bad request for unknown action, got %v", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/action?name=takeoff", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected method not allowed, got %v", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/track.kml", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<kml") {
+		t.Fatalf("bad track KML, code %v", rec.Code)
+	}
+}
+
+func TestGroundStationWaypoints(t *testing.T) {
+	d := NewDrone()
+	mux := http.NewServeMux()
+	d.registerGroundStationHandlers(mux, false, "session", "secret")
+
+	do := func(method string, url string, body string) []Position {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set(groundStationTokenHeader, "secret")
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%v %v: code %v: %s", method, url, rec.Code, rec.Body)
+		}
+		var wps []Position
+		if err := json.NewDecoder(rec.Body).Decode(&wps); err != nil {
+			t.Fatal(err)
+		}
+		return wps
+	}
+
+	do("POST", "/api/waypoints", `{"Latitude":1,"Longitude":1,"Altitude":10}`)
+	do("POST", "/api/waypoints", `{"Latitude":2,"Longitude":2,"Altitude":10}`)
+	wps := do("POST", "/api/waypoints?index=0", `{"Latitude":3,"Longitude":3,"Altitude":10}`)
+	if len(wps) != 3 || wps[0].Latitude != 3 {
+		t.Fatalf("wrong waypoints after insert: %+v", wps)
+	}
+
+	wps = do("POST", "/api/waypoints/move?from=0&to=2", "")
+	if wps[0].Latitude != 1 || wps[2].Latitude != 3 {
+		t.Fatalf("wrong waypoints after move: %+v", wps)
+	}
+
+	wps = do("DELETE", "/api/waypoints?index=1", "")
+	if len(wps) != 2 || wps[0].Latitude != 1 || wps[1].Latitude != 3 {
+		t.Fatalf("wrong waypoints after remove: %+v", wps)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/waypoints", strings.NewReader(`{"Latitude":500,"Longitude":500}`))
+	req.Header.Set(groundStationTokenHeader, "secret")
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected invalid waypoint to be refused, got %v", rec.Code)
+	}
+
+	if wps := do("DELETE", "/api/waypoints", ""); len(wps) != 0 {
+		t.Fatalf("expected no waypoints after clear, got %+v", wps)
+	}
+}
+
+func TestGroundStationToken(t *testing.T) {
+	d := NewDrone()
+	mux := http.NewServeMux()
+	d.registerGroundStationHandlers(mux, false, "session", "secret")
+
+	post := func(token string, origin string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "http://localhost:8080/api/action?name=hover", nil)
+		if token != "" {
+			req.Header.Set(groundStationTokenHeader, token)
+		}
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := post("", ""); code != http.StatusForbidden {
+		t.Fatalf("expected request without token to be refused, got %v", code)
+	}
+	if code := post("wrong", ""); code != http.StatusForbidden {
+		t.Fatalf("expected request with wrong token to be refused, got %v", code)
+	}
+	if code := post("secret", "http://evil.example.com"); code != http.StatusForbidden {
+		t.Fatalf("expected cross origin request to be refused, got %v", code)
+	}
+
+	for _, token := range []string{"secret", "session"} {
+		go func() { <-d.chInputActions }()
+		if code := post(token, "http://localhost:8080"); code != http.StatusOK {
+			t.Fatalf("expected request with token %v to be allowed, got %v", token, code)
+		}
+	}
+
+	// Without an operator token only the page token is allowed.
+	mux = http.NewServeMux()
+	d.registerGroundStationHandlers(mux, false, "session", "")
+	if code := post("", ""); code != http.StatusForbidden {
+		t.Fatalf("expected request without token to be refused, got %v", code)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/waypoints", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected waypoints change without token to be refused, got %v", rec.Code)
+	}
+}
+
+func TestGroundStationHost(t *testing.T) {
+	tests := []struct {
+		listen string
+		host   string
+		want   bool
+	}{
+		{"127.0.0.1", "127.0.0.1:8080", true},
+		{"127.0.0.1", "localhost:8080", true},
+		{"127.0.0.1", "[::1]:8080", true},
+		{"127.0.0.1", "127.0.0.1:9090", false},
+		{"127.0.0.1", "evil.example.com:8080", false},
+		{"localhost", "LOCALHOST:8080", true},
+		{"", "192.168.42.10:8080", true},
+		{"0.0.0.0", "evil.example.com:8080", false},
+		{"bebop.local", "bebop.local:8080", true},
+		{"bebop.local", "10.0.0.1:8080", false},
+	}
+
+	for _, tt := range tests {
+		if got := allowedHost(tt.listen, "8080", tt.host); got != tt.want {
+			t.Errorf("listen %q, host %q: got %v, want %v", tt.listen, tt.host, got, tt.want)
+		}
+	}
+
+	h := requireHost("127.0.0.1", "8080", http.NotFoundHandler())
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "http://rebind.example.com:8080/", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected DNS rebound request to be refused, got %v", rec.Code)
+	}
+}
+
+func TestStartGroundStationListenError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	d := NewDrone()
+	if err := d.StartGroundStation(context.Background(), l.Addr().String(), nil); err == nil {
+		t.Fatalf("expected error when the address is in use")
+	}
+}
@@ -0,0 +1,249 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"sync"
+)
+
+// H264 NAL unit types used when depacketizing.
+const (
+	nalTypeIDR   = 5
+	nalTypeSPS   = 7
+	nalTypePPS   = 8
+	nalTypeSTAPA = 24
+	nalTypeFUA   = 28
+)
+
+// annexBStartCode is put in front of each NAL unit in a H264Frame.
+var annexBStartCode = []byte{0, 0, 0, 1}
+
+// H264Frame is a complete H264 access unit received from the drone,
+// with each NAL unit prefixed by an Annex-B start code.
+type H264Frame struct {
+	// Data is the NAL units of the frame in Annex-B format.
+	Data []byte
+	// Timestamp is the RTP timestamp of the frame, in 90kHz units.
+	Timestamp uint32
+	// KeyFrame is true if the frame holds an IDR slice, and can be
+	// decoded without any of the previous frames.
+	KeyFrame bool
+}
+
+// h264Depacketizer will put together the H264 payloads of RTP packets
+// into complete frames, as described in RFC6184.
+type h264Depacketizer struct {
+	frame     []byte
+	timestamp uint32
+	keyFrame  bool
+	// fuStarted is true while in the middle of a fragmented unit.
+	fuStarted bool
+	// broken is true if a packet of the current frame was lost.
+	broken bool
+}
+
+// lost will tell the depacketizer that packets were lost, so the
+// frame currently being put together is dropped.
+func (h *h264Depacketizer) lost() {
+	h.broken = true
+	h.fuStarted = false
+}
+
+// push will add the RTP packet to the current frame, and return the
+// frame when it is complete, which is when the marker bit is set.
+func (h *h264Depacketizer) push(p rtpPacket) (H264Frame, bool, error) {
+	// A new timestamp means a new frame, even if we never got the
+	// marker bit of the previous one.
+	if len(h.frame) > 0 && p.timestamp != h.timestamp {
+		h.reset()
+	}
+	h.timestamp = p.timestamp
+
+	if err := h.addPayload(p.payload); err != nil {
+		h.broken = true
+	}
+
+	if !p.marker {
+		return H264Frame{}, false, nil
+	}
+
+	defer h.reset()
+
+	if h.broken || len(h.frame) == 0 {
+		return H264Frame{}, false, fmt.Errorf("dropping incomplete frame with timestamp %v", p.timestamp)
+	}
+
+	f := H264Frame{
+		Data:      h.frame,
+		Timestamp: h.timestamp,
+		KeyFrame:  h.keyFrame,
+	}
+
+	return f, true, nil
+}
+
+// reset will start on a new frame.
+func (h *h264Depacketizer) reset() {
+	h.frame = nil
+	h.keyFrame = false
+	h.fuStarted = false
+	h.broken = false
+}
+
+// addPayload will add the NAL units of the payload to the frame.
+func (h *h264Depacketizer) addPayload(b []byte) error {
+	if len(b) < 1 {
+		return fmt.Errorf("empty h264 payload")
+	}
+
+	switch typ := b[0] & 0x1f; typ {
+	case nalTypeSTAPA:
+		// Aggregation packet with several NAL units, each prefixed
+		// with a 16 bit size.
+		for b = b[1:]; len(b) > 2; {
+			size := int(b[0])<<8 | int(b[1])
+			if size == 0 || len(b) < 2+size {
+				return fmt.Errorf("malformed STAP-A packet")
+			}
+			h.addNAL(b[2 : 2+size])
+			b = b[2+size:]
+		}
+	case nalTypeFUA:
+		// Fragmentation unit, where the first fragment holds the
+		// start bit, and the NAL header is rebuilt from the FU
+		// indicator and the FU header.
+		if len(b) < 2 {
+			return fmt.Errorf("malformed FU-A packet")
+		}
+		start := b[1]&0x80 != 0
+		if start {
+			nalHeader := b[0]&0xe0 | b[1]&0x1f
+			h.addNAL([]byte{nalHeader})
+			h.fuStarted = true
+		}
+		if !h.fuStarted {
+			return fmt.Errorf("FU-A packet without start fragment")
+		}
+		h.frame = append(h.frame, b[2:]...)
+		if b[1]&0x40 != 0 {
+			h.fuStarted = false
+		}
+	default:
+		if typ == 0 || typ > nalTypeSTAPA {
+			return fmt.Errorf("unsupported NAL unit type: %v", typ)
+		}
+		h.addNAL(b)
+	}
+
+	return nil
+}
+
+// addNAL will add a complete NAL unit to the frame.
+func (h *h264Depacketizer) addNAL(nal []byte) {
+	if nal[0]&0x1f == nalTypeIDR {
+		h.keyFrame = true
+	}
+	h.frame = append(h.frame, annexBStartCode...)
+	h.frame = append(h.frame, nal...)
+}
+
+// frameSubscriberBuffer is the size of the buffered channel of each
+// video frame subscriber.
+const frameSubscriberBuffer = 30
+
+// frameBus will deliver the video frames to all the subscribers.
+// Frames are dropped for subscribers not able to keep up, since
+// a live video stream is only interesting when it is live.
+type frameBus struct {
+	mu          sync.Mutex
+	subscribers map[int]chan H264Frame
+	callbacks   map[int]func(H264Frame)
+	nextID      int
+}
+
+// publish will call all the registered callbacks with the frame, and
+// deliver the frame to all the subscribers.
+func (f *frameBus) publish(frame H264Frame) {
+	f.mu.Lock()
+	// The callbacks are called without holding the lock, so they are
+	// able to unregister themselves.
+	callbacks := make([]func(H264Frame), 0, len(f.callbacks))
+	for _, fn := range f.callbacks {
+		callbacks = append(callbacks, fn)
+	}
+	f.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn(frame)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, ch := range f.subscribers {
+		select {
+		case ch <- frame:
+		default:
+		}
+	}
+}
+
+// subscribe will register a new subscriber, and return the channel
+// to receive the frames on, and a function to unsubscribe.
+func (f *frameBus) subscribe() (<-chan H264Frame, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.subscribers == nil {
+		f.subscribers = make(map[int]chan H264Frame)
+	}
+
+	id := f.nextID
+	f.nextID++
+	ch := make(chan H264Frame, frameSubscriberBuffer)
+	f.subscribers[id] = ch
+
+	unsubscribe := func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		if _, ok := f.subscribers[id]; ok {
+			delete(f.subscribers, id)
+			close(ch)
+		}
+	}
+
+	return ch, unsubscribe
+}
+
+// onFrame will register a callback to be called for each frame, and
+// return a function to unregister it.
+func (f *frameBus) onFrame(fn func(H264Frame)) func() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.callbacks == nil {
+		f.callbacks = make(map[int]func(H264Frame))
+	}
+
+	id := f.nextID
+	f.nextID++
+	f.callbacks[id] = fn
+
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		delete(f.callbacks, id)
+	}
+}
+
+// OnVideoFrame will register a function to be called for each H264
+// frame received from the drone, which can be used for feeding
+// computer vision code. The function is called directly from the go
+// routine receiving the video with no copy of the frame done, so the
+// function should return quickly, and must not modify the frame data
+// or keep a reference to it after returning. Copy the data if it is
+// needed later. The returned function will unregister the callback.
+func (d *Drone) OnVideoFrame(fn func(frame H264Frame)) func() {
+	return d.frames.onFrame(fn)
+}
@@ -0,0 +1,50 @@
+package parrotbebop
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestH264DepacketizerFUA(t *testing.T) {
+	var h h264Depacketizer
+
+	// A single NAL SPS, and an IDR slice split into two FU-A packets.
+	packets := []rtpPacket{
+		{timestamp: 1, payload: []byte{0x67, 1, 2}},
+		{timestamp: 1, payload: []byte{0x7c, 0x85, 3, 4}},
+		{timestamp: 1, marker: true, payload: []byte{0x7c, 0x45, 5, 6}},
+	}
+
+	var frame H264Frame
+	var ok bool
+	for _, p := range packets {
+		var err error
+		frame, ok, err = h.push(p)
+		if err != nil {
+			t.Fatalf("push failed: %v", err)
+		}
+	}
+
+	if !ok {
+		t.Fatalf("expected a complete frame")
+	}
+	if !frame.KeyFrame {
+		t.Fatalf("expected a key frame")
+	}
+
+	want := []byte{0, 0, 0, 1, 0x67, 1, 2, 0, 0, 0, 1, 0x65, 3, 4, 5, 6}
+	if !bytes.Equal(frame.Data, want) {
+		t.Fatalf("wrong frame data: %v, want %v", frame.Data, want)
+	}
+}
+
+func TestH264DepacketizerLost(t *testing.T) {
+	var h h264Depacketizer
+
+	h.push(rtpPacket{timestamp: 1, payload: []byte{0x7c, 0x85, 3, 4}})
+	h.lost()
+	_, ok, err := h.push(rtpPacket{timestamp: 1, marker: true, payload: []byte{0x7c, 0x45, 5, 6}})
+	if ok || err == nil {
+		t.Fatalf("expected the broken frame to be dropped")
+	}
+}
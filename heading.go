@@ -0,0 +1,96 @@
+package parrotbebop
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+const (
+	// headingInterval is how often RotateTo will calculate and send
+	// a new Yaw value.
+	headingInterval = time.Millisecond * 100
+	// headingTolerance in degrees, for when the heading is considered
+	// reached.
+	headingTolerance = 3.0
+	// headingGain is the Yaw percentage to use per degree of heading
+	// error.
+	headingGain = 1.0
+	// headingMaxYaw is the max Yaw percentage RotateTo will use.
+	headingMaxYaw = 50
+	// headingMinYaw is the min Yaw percentage RotateTo will use, so
+	// the drone don't get stuck close to the heading.
+	headingMinYaw = 5
+)
+
+// RotateTo will rotate the drone to the compass heading given in
+// degrees, where 0 is north and 90 is east. The rotation is done
+// by sending yaw PCMD commands based on the attitude reported by
+// the drone, and the method will block until the heading is reached
+// or the context is done.
+func (d *Drone) RotateTo(ctx context.Context, headingDeg float64) error {
+	if headingDeg < 0 || headingDeg >= 360 {
+		return fmt.Errorf("RotateTo: heading must be within [0, 360), got %v", headingDeg)
+	}
+
+	ticker := time.NewTicker(headingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.sendPcmdYaw(0)
+			return fmt.Errorf("RotateTo: %v", ctx.Err())
+		case <-ticker.C:
+			current := d.telemetry.snapshot().HeadingDegrees()
+			yaw, reached := headingYaw(headingDeg, current)
+			if err := d.sendPcmdYaw(yaw); err != nil {
+				return fmt.Errorf("RotateTo: %v", err)
+			}
+
+			if reached {
+				log.Printf("info: RotateTo: reached heading %v\n", headingDeg)
+				return nil
+			}
+		}
+	}
+}
+
+// sendPcmdYaw will set the yaw value given of the PCMD state sent by
+// the PCMD scheduler, leaving the other axes to the pilot.
+func (d *Drone) sendPcmdYaw(yaw int8) error {
+	arg := Ardrone3PilotingPCMDArguments{
+		Yaw: yaw,
+	}
+
+	d.updatePcmd(arg, pcmdYaw)
+
+	return nil
+}
+
+// headingYaw will calculate the Yaw value to use for turning from
+// the current heading to the target heading, and if the target is
+// considered reached. The drone will turn the shortest way.
+func headingYaw(target float64, current float64) (int8, bool) {
+	// Find the shortest difference in the range [-180, 180).
+	diff := math.Mod(target-current+540, 360) - 180
+	if math.Abs(diff) <= headingTolerance {
+		return 0, true
+	}
+
+	yaw := diff * headingGain
+	switch {
+	case yaw > headingMaxYaw:
+		yaw = headingMaxYaw
+	case yaw < -headingMaxYaw:
+		yaw = -headingMaxYaw
+	case yaw > 0 && yaw < headingMinYaw:
+		yaw = headingMinYaw
+	case yaw < 0 && yaw > -headingMinYaw:
+		yaw = -headingMinYaw
+	}
+
+	return int8(yaw), false
+}
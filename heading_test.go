@@ -0,0 +1,40 @@
+package parrotbebop
+
+import (
+	"testing"
+)
+
+func TestHeadingYaw(t *testing.T) {
+	tests := []struct {
+		target  float64
+		current float64
+		yaw     int8
+		reached bool
+	}{
+		{target: 90, current: 89, yaw: 0, reached: true},
+		{target: 90, current: 70, yaw: 20, reached: false},
+		// Should turn counter clockwise over north.
+		{target: 350, current: 10, yaw: -20, reached: false},
+		{target: 180, current: 0, yaw: -50, reached: false},
+		{target: 10, current: 6, yaw: 5, reached: false},
+	}
+
+	for _, tt := range tests {
+		yaw, reached := headingYaw(tt.target, tt.current)
+		if yaw != tt.yaw || reached != tt.reached {
+			t.Fatalf("headingYaw(%v, %v) = %v, %v, want %v, %v", tt.target, tt.current, yaw, reached, tt.yaw, tt.reached)
+		}
+	}
+}
+
+func TestSendPcmdYawOnlyYaw(t *testing.T) {
+	d := NewDrone()
+
+	d.updatePcmd(Ardrone3PilotingPCMDArguments{Pitch: 15, Gaz: 30}, pcmdAllAxes)
+	if err := d.sendPcmdYaw(40); err != nil {
+		t.Fatalf("sendPcmdYaw: %v", err)
+	}
+	if got, want := d.currentPcmd(), (Ardrone3PilotingPCMDArguments{Flag: 1, Pitch: 15, Yaw: 40, Gaz: 30}); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,136 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// HomeType is the preferred type of home position used by the drone
+// when doing a return home.
+type HomeType uint32
+
+const (
+	// HomeTypeTakeoff will use the position where the drone took off
+	// as the home position.
+	HomeTypeTakeoff HomeType = 0
+	// HomeTypePilot will use the position of the pilot, which is the
+	// position given with SetHome, or the controller gps position.
+	HomeTypePilot HomeType = 1
+	// HomeTypeFollowee will use the position of the target that is
+	// currently followed.
+	HomeTypeFollowee HomeType = 2
+)
+
+// String will return the name of the home type.
+func (h HomeType) String() string {
+	switch h {
+	case HomeTypeTakeoff:
+		return "takeoff"
+	case HomeTypePilot:
+		return "pilot"
+	case HomeTypeFollowee:
+		return "followee"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(h))
+}
+
+// HomePosition is the home position reported by the drone.
+type HomePosition struct {
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+	// Set is true when the drone have reported a valid home position.
+	Set bool
+}
+
+// home holds the current home values received from the drone.
+// All the values are read and written from different go routines,
+// so access should be done while holding the mutex.
+type home struct {
+	mu sync.Mutex
+	// position is the last home position reported by the drone.
+	position HomePosition
+	// homeType is the home type currently chosen by the drone.
+	homeType HomeType
+}
+
+// Home will return the last known home position reported by the drone.
+// HomePosition.Set will be false if no home position have been received.
+func (d *Drone) Home() HomePosition {
+	d.home.mu.Lock()
+	defer d.home.mu.Unlock()
+
+	return d.home.position
+}
+
+// HomeType will return the home type currently used by the drone.
+func (d *Drone) HomeType() HomeType {
+	d.home.mu.Lock()
+	defer d.home.mu.Unlock()
+
+	return d.home.homeType
+}
+
+// SetHome will set the home position to return to when a return
+// home are done. The position is only used by the drone when the
+// home type is HomeTypePilot.
+func (d *Drone) SetHome(lat float64, lon float64, alt float64) error {
+	if lat > 90 || lat < -90 || lon > 180 || lon < -180 {
+		return fmt.Errorf("SetHome: not allowed position, lat: %v, lon: %v", lat, lon)
+	}
+
+	arg := &Ardrone3GPSSettingsSetHomeArguments{
+		Latitude:  lat,
+		Longitude: lon,
+		Altitude:  alt,
+	}
+
+	return d.sendCmd(Command(GPSSettingsSetHome), arg)
+}
+
+// ResetHome will ask the drone to reset the home position to
+// it's default.
+func (d *Drone) ResetHome() error {
+	return d.sendCmd(Command(GPSSettingsResetHome), &Ardrone3GPSSettingsResetHomeArguments{})
+}
+
+// SetHomeType will set the preferred home type. The type actually
+// used by the drone is reported back with a HomeTypeChanged state,
+// and can be checked with Drone.HomeType().
+func (d *Drone) SetHomeType(t HomeType) error {
+	if t > HomeTypeFollowee {
+		return fmt.Errorf("SetHomeType: unknown home type: %v", t)
+	}
+
+	return d.sendCmd(Command(GPSSettingsHomeType), &Ardrone3GPSSettingsHomeTypeArguments{TypeX: uint32(t)})
+}
+
+// setPosition will update the home position with the values
+// received from the drone.
+func (h *home) setPosition(lat float64, lon float64, alt float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// The drone reports 500 for all values when no home is set.
+	h.position = HomePosition{
+		Latitude:  lat,
+		Longitude: lon,
+		Altitude:  alt,
+		Set:       lat != 500 && lon != 500,
+	}
+
+	log.Printf("info: home position changed: %#v\n", h.position)
+}
+
+// setType will update the home type with the value received from
+// the drone.
+func (h *home) setType(t HomeType) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.homeType = t
+
+	log.Printf("info: home type changed: %v\n", t)
+}
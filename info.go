@@ -0,0 +1,86 @@
+package parrotbebop
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DroneInfo holds the product information reported by the drone in
+// the settings sent after the connection is made.
+type DroneInfo struct {
+	// Name is the product name, which is also the name of the access
+	// point of the drone.
+	Name string
+	// SoftwareVersion is the firmware version, like "4.0.6".
+	SoftwareVersion string
+	// HardwareVersion is the version of the hardware.
+	HardwareVersion string
+	// SerialHigh and SerialLow are the two halves of the serial
+	// number as reported by the drone.
+	SerialHigh string
+	SerialLow  string
+}
+
+// Serial will return the full serial number of the drone.
+func (i DroneInfo) Serial() string {
+	return i.SerialHigh + i.SerialLow
+}
+
+// FirmwareAtLeast will return true if the firmware version of the drone
+// is the same or newer than the version given, like "4.0.0". It will
+// return false if the firmware version is not yet known, so it can be
+// used to only enable features the firmware is known to support.
+func (i DroneInfo) FirmwareAtLeast(version string) bool {
+	have, ok := parseVersion(i.SoftwareVersion)
+	if !ok {
+		return false
+	}
+	want, ok := parseVersion(version)
+	if !ok {
+		return false
+	}
+
+	for n := 0; n < len(have) || n < len(want); n++ {
+		var h, w int
+		if n < len(have) {
+			h = have[n]
+		}
+		if n < len(want) {
+			w = want[n]
+		}
+		if h != w {
+			return h > w
+		}
+	}
+
+	return true
+}
+
+// parseVersion will parse a dotted version string into it's numbers.
+// Anything following the numbers, like "-rc1", is ignored.
+func parseVersion(v string) ([]int, bool) {
+	if i := strings.IndexAny(v, "-+ "); i != -1 {
+		v = v[:i]
+	}
+	if v == "" {
+		return nil, false
+	}
+
+	var nums []int
+	for _, s := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, false
+		}
+		nums = append(nums, n)
+	}
+
+	return nums, true
+}
+
+// Info will return the product information reported by the drone. The
+// values are populated when the drone sends all it's settings after
+// the connection is made, so they are complete when Ready returns true.
+func (d *Drone) Info() DroneInfo {
+	return d.telemetry.snapshot().Info
+}
@@ -0,0 +1,28 @@
+package parrotbebop
+
+import "testing"
+
+func TestFirmwareAtLeast(t *testing.T) {
+	tests := []struct {
+		have string
+		want string
+		ok   bool
+	}{
+		{"4.0.6", "4.0.0", true},
+		{"4.0.6", "4.0.6", true},
+		{"4.0.6", "4.1", false},
+		{"3.3.0", "4.0.0", false},
+		{"4.0", "4.0.0", true},
+		{"4.10.0", "4.9.1", true},
+		{"4.0.6-rc1", "4.0.6", true},
+		{"", "1.0.0", false},
+		{"4.0.6", "bad", false},
+	}
+
+	for _, tt := range tests {
+		got := DroneInfo{SoftwareVersion: tt.have}.FirmwareAtLeast(tt.want)
+		if got != tt.ok {
+			t.Errorf("FirmwareAtLeast(%q) with firmware %q = %v, want %v", tt.want, tt.have, got, tt.ok)
+		}
+	}
+}
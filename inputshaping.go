@@ -0,0 +1,177 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// AxisShaping is how the input of a stick axis is turned into the PCMD
+// percentage sent to the drone.
+type AxisShaping struct {
+	// Deadzone is the part of the stick range around the center, in
+	// [0, 1), where the input is ignored. The rest of the range is
+	// scaled, so the output starts from 0 at the edge of the deadzone.
+	Deadzone float64
+	// Expo is the exponential curve in [0, 1], where 0 is linear, and 1
+	// is cubic, giving finer control around the center.
+	Expo float64
+	// MaxPercent is the percentage in (0, 100] given at full stick.
+	MaxPercent float64
+	// Trim is the percentage in [-100, 100] added to the output, to
+	// correct a drift of the drone. It is only used for roll and
+	// pitch, since a trimmed yaw or gaz would turn or climb the drone
+	// with the sticks centered.
+	Trim float64
+}
+
+// shape will return the PCMD percentage for the stick input in the
+// range [-1, 1].
+func (a AxisShaping) shape(v float64) int8 {
+	v = math.Max(-1, math.Min(1, v))
+
+	if math.Abs(v) <= a.Deadzone {
+		v = 0
+	} else {
+		v = math.Copysign((math.Abs(v)-a.Deadzone)/(1-a.Deadzone), v)
+	}
+
+	v = (1-a.Expo)*v + a.Expo*v*v*v
+	p := math.Round(v*a.MaxPercent + a.Trim)
+
+	return int8(math.Max(-100, math.Min(100, p)))
+}
+
+// moved will return true if the stick input in the range [-1, 1] is
+// outside the deadzone.
+func (a AxisShaping) moved(v float64) bool {
+	return math.Abs(v) > a.Deadzone
+}
+
+// check will return an error if the values are out of range.
+func (a AxisShaping) check() error {
+	switch {
+	case a.Deadzone < 0 || a.Deadzone >= 1:
+		return fmt.Errorf("deadzone must be within [0, 1), got %v", a.Deadzone)
+	case a.Expo < 0 || a.Expo > 1:
+		return fmt.Errorf("expo must be within [0, 1], got %v", a.Expo)
+	case a.MaxPercent <= 0 || a.MaxPercent > 100:
+		return fmt.Errorf("max percent must be within (0, 100], got %v", a.MaxPercent)
+	case a.Trim < -100 || a.Trim > 100:
+		return fmt.Errorf("trim must be within [-100, 100], got %v", a.Trim)
+	}
+
+	return nil
+}
+
+// InputShaping is the shaping of each of the PCMD axes.
+type InputShaping struct {
+	Roll  AxisShaping
+	Pitch AxisShaping
+	Yaw   AxisShaping
+	Gaz   AxisShaping
+}
+
+// DefaultInputShaping is the input shaping used if not set with
+// SetInputShaping, which is the linear mapping of the stick to the
+// percentage.
+var DefaultInputShaping = InputShaping{
+	Roll:  AxisShaping{MaxPercent: 100},
+	Pitch: AxisShaping{MaxPercent: 100},
+	Yaw:   AxisShaping{MaxPercent: 100},
+	Gaz:   AxisShaping{MaxPercent: 100},
+}
+
+// inputShapingConfig holds the input shaping, where the zero value uses
+// DefaultInputShaping.
+type inputShapingConfig struct {
+	mu      sync.Mutex
+	shaping *InputShaping
+}
+
+// get will return the current input shaping.
+func (c *inputShapingConfig) get() InputShaping {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.shaping == nil {
+		return DefaultInputShaping
+	}
+
+	return *c.shaping
+}
+
+// SetInputShaping will set the deadzone, expo curve, max percentage and
+// trim of each axis, used for the input of the keyboard, SendSticks and
+// the Gobot driver. SendPcmd is not shaped, since it is meant for
+// programs controlling the drone directly.
+func (d *Drone) SetInputShaping(s InputShaping) error {
+	axes := []struct {
+		name string
+		a    AxisShaping
+	}{{"roll", s.Roll}, {"pitch", s.Pitch}, {"yaw", s.Yaw}, {"gaz", s.Gaz}}
+	for _, ax := range axes {
+		if err := ax.a.check(); err != nil {
+			return fmt.Errorf("SetInputShaping: %v: %v", ax.name, err)
+		}
+	}
+	if s.Yaw.Trim != 0 || s.Gaz.Trim != 0 {
+		return fmt.Errorf("SetInputShaping: trim is only used for roll and pitch, got yaw %v and gaz %v", s.Yaw.Trim, s.Gaz.Trim)
+	}
+
+	d.inputShaping.mu.Lock()
+	defer d.inputShaping.mu.Unlock()
+
+	d.inputShaping.shaping = &s
+
+	return nil
+}
+
+// shapedPcmd will return the PCMD for the stick inputs in the range
+// [-1, 1], shaped by the input shaping.
+func (d *Drone) shapedPcmd(roll float64, pitch float64, yaw float64, gaz float64) Ardrone3PilotingPCMDArguments {
+	s := d.inputShaping.get()
+
+	arg := Ardrone3PilotingPCMDArguments{
+		Roll:  s.Roll.shape(roll),
+		Pitch: s.Pitch.shape(pitch),
+		Yaw:   s.Yaw.shape(yaw),
+		Gaz:   s.Gaz.shape(gaz),
+	}
+	// The flag must be set for roll and pitch to be used.
+	if arg.Roll != 0 || arg.Pitch != 0 {
+		arg.Flag = 1
+	}
+
+	return arg
+}
+
+// setShapedPcmd will set the axes given of the PCMD state from the
+// emulated sticks of the keyboard, where the percentages are shaped as
+// stick inputs.
+func (d *Drone) setShapedPcmd(arg Ardrone3PilotingPCMDArguments, axes pcmdAxes) {
+	roll, pitch, yaw, gaz := float64(arg.Roll)/100, float64(arg.Pitch)/100, float64(arg.Yaw)/100, float64(arg.Gaz)/100
+	shaped := d.shapedPcmd(roll, pitch, yaw, gaz)
+	d.updatePcmd(shaped, axes)
+	d.manualInput(roll, pitch, yaw, gaz)
+}
+
+// SendSticks will send a PCMD with the stick inputs given via the PCMD
+// scheduler, shaped as set with SetInputShaping, which is meant for
+// input backends like gamepads. All the values are in the range
+// [-1, 1], where positive is right roll, forward pitch, clockwise yaw
+// and up.
+func (d *Drone) SendSticks(roll float64, pitch float64, yaw float64, gaz float64) error {
+	for _, v := range []float64{roll, pitch, yaw, gaz} {
+		if math.IsNaN(v) || v < -1 || v > 1 {
+			return fmt.Errorf("SendSticks: values must be within [-1, 1], got %v, %v, %v, %v", roll, pitch, yaw, gaz)
+		}
+	}
+
+	arg := d.shapedPcmd(roll, pitch, yaw, gaz)
+	d.setPcmd(arg)
+	d.recordPcmd(arg)
+	d.manualInput(roll, pitch, yaw, gaz)
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package parrotbebop
+
+import "testing"
+
+func TestAxisShaping(t *testing.T) {
+	a := AxisShaping{Deadzone: 0.1, Expo: 0.5, MaxPercent: 50, Trim: 2}
+
+	tests := []struct {
+		in   float64
+		want int8
+	}{
+		// Inside the deadzone only the trim is left.
+		{0.05, 2},
+		{-0.1, 2},
+		// Half way outside the deadzone, 0.5*0.5 + 0.5*0.125 = 0.3125.
+		{0.55, 18},
+		{-0.55, -14},
+		// Full stick gives max percent, and beyond is limited.
+		{1, 52},
+		{2, 52},
+		{-1, -48},
+	}
+	for _, tt := range tests {
+		if got := a.shape(tt.in); got != tt.want {
+			t.Fatalf("shape(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	linear := DefaultInputShaping.Roll
+	for _, v := range []int8{-100, -37, 0, 1, 64, 100} {
+		if got := linear.shape(float64(v) / 100); got != v {
+			t.Fatalf("default shaping changed %v to %v", v, got)
+		}
+	}
+}
+
+func TestSetInputShaping(t *testing.T) {
+	d := NewDrone()
+
+	bad := []AxisShaping{
+		{Deadzone: 1, MaxPercent: 100},
+		{Expo: 1.5, MaxPercent: 100},
+		{MaxPercent: 0},
+		{MaxPercent: 100, Trim: 101},
+		{MaxPercent: 100, Trim: 5},
+	}
+	for _, a := range bad {
+		s := DefaultInputShaping
+		s.Yaw = a
+		if err := d.SetInputShaping(s); err == nil {
+			t.Fatalf("expected error for %+v", a)
+		}
+	}
+
+	s := DefaultInputShaping
+	s.Pitch = AxisShaping{MaxPercent: 30}
+	if err := d.SetInputShaping(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.SendSticks(0, 1.5, 0, 0); err == nil {
+		t.Fatalf("expected error for stick outside [-1, 1]")
+	}
+	if err := d.SendSticks(0, 1, -0.5, 0); err != nil {
+		t.Fatal(err)
+	}
+	want := Ardrone3PilotingPCMDArguments{Flag: 1, Pitch: 30, Yaw: -50}
+	if got := d.currentPcmd(); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
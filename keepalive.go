@@ -0,0 +1,103 @@
+package parrotbebop
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultKeepAliveInterval is the keep alive interval used if not
+	// set with SetKeepAliveInterval.
+	defaultKeepAliveInterval = time.Second
+	// minKeepAliveInterval and maxKeepAliveInterval are the limits
+	// allowed for the keep alive interval.
+	minKeepAliveInterval = time.Millisecond * 100
+	maxKeepAliveInterval = time.Second * 30
+)
+
+// keepAlive holds when a packet was last sent to the drone, and how
+// long the controller to drone path can be idle before sending a keep
+// alive.
+type keepAlive struct {
+	mu       sync.Mutex
+	interval time.Duration
+	disabled bool
+	lastSent time.Time
+}
+
+// sent will register that a packet was sent to the drone.
+func (k *keepAlive) sent(now time.Time) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.lastSent = now
+}
+
+// due will return true if a keep alive should be sent, and the interval
+// to use.
+func (k *keepAlive) due(now time.Time) (bool, time.Duration) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	interval := k.interval
+	if interval == 0 {
+		interval = defaultKeepAliveInterval
+	}
+	if k.disabled {
+		return false, interval
+	}
+
+	return now.Sub(k.lastSent) >= interval, interval
+}
+
+// SetKeepAliveInterval will set how long the path from the controller
+// to the drone can be idle, before a neutral PCMD is sent to keep NAT
+// and wifi power save from closing it. An interval of 0 will disable
+// the keep alive.
+func (d *Drone) SetKeepAliveInterval(interval time.Duration) error {
+	if interval != 0 && (interval < minKeepAliveInterval || interval > maxKeepAliveInterval) {
+		return fmt.Errorf("SetKeepAliveInterval: interval must be 0 or within [%v, %v], got %v", minKeepAliveInterval, maxKeepAliveInterval, interval)
+	}
+
+	d.keepAlive.mu.Lock()
+	defer d.keepAlive.mu.Unlock()
+
+	d.keepAlive.disabled = interval == 0
+	d.keepAlive.interval = interval
+
+	return nil
+}
+
+// startKeepAlive will send a neutral PCMD, with Flag=0 and no movement,
+// when nothing have been sent to the drone within the keep alive
+// interval. The PCMD scheduler normally keeps the path busy, so this
+// is for the periods where it is not running, like while the supervisor
+// is restarting it.
+func (d *Drone) startKeepAlive(ctx context.Context) {
+	for {
+		_, interval := d.keepAlive.due(time.Now())
+
+		select {
+		case <-ctx.Done():
+			log.Println("info: exiting startKeepAlive")
+			return
+		case <-time.After(interval / 2):
+		}
+
+		if ok, _ := d.keepAlive.due(time.Now()); !ok {
+			continue
+		}
+
+		d.debugf("sending keep alive to drone\r\n")
+
+		select {
+		case d.chSendingUDPPacket <- d.packetCreator.encodeCmd(Command(PilotingPCMD), &Ardrone3PilotingPCMDArguments{}):
+		case <-ctx.Done():
+			log.Println("info: exiting startKeepAlive")
+			return
+		}
+	}
+}
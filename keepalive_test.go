@@ -0,0 +1,61 @@
+package parrotbebop
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSetKeepAliveInterval(t *testing.T) {
+	d := NewDrone()
+
+	for _, i := range []time.Duration{-time.Second, time.Millisecond, time.Minute} {
+		if err := d.SetKeepAliveInterval(i); err == nil {
+			t.Fatalf("expected error for interval %v", i)
+		}
+	}
+
+	if err := d.SetKeepAliveInterval(0); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := d.keepAlive.due(time.Now()); ok {
+		t.Fatalf("keep alive due while disabled")
+	}
+}
+
+func TestKeepAliveSentWhenIdle(t *testing.T) {
+	d := NewDrone()
+	if err := d.SetKeepAliveInterval(minKeepAliveInterval); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.startKeepAlive(ctx)
+
+	select {
+	case p := <-d.chSendingUDPPacket:
+		_, args, err := DecodeCommand(p.data[frameHeaderSize:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if args != (Ardrone3PilotingPCMDArguments{}) {
+			t.Fatalf("keep alive is not a neutral PCMD: %+v", args)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no keep alive sent while idle")
+	}
+
+	// While sending, no keep alive should be sent.
+	stop := time.After(minKeepAliveInterval * 3)
+	for {
+		d.keepAlive.sent(time.Now())
+		select {
+		case <-d.chSendingUDPPacket:
+			t.Fatalf("keep alive sent while not idle")
+		case <-stop:
+			return
+		case <-time.After(minKeepAliveInterval / 10):
+		}
+	}
+}
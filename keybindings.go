@@ -0,0 +1,188 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/eiannone/keyboard"
+)
+
+// inputActionNames are the names of the actions that can be bound to a
+// key with SetKeyBindings, or in the config file.
+var inputActionNames = map[string]InputAction{
+	"takeoff":      ActionTakeoff,
+	"usertakeoff":  ActionUserTakeoff,
+	"land":         ActionLanding,
+	"home":         ActionNavigateHomeStart,
+	"stophome":     ActionNavigateHomeStop,
+	"climb":        ActionPcmdGazInc,
+	"descend":      ActionPcmdGazDec,
+	"yawleft":      ActionPcmdYawCounterClockwise,
+	"yawright":     ActionPcmdYawClockwise,
+	"forward":      ActionPcmdPitchForward,
+	"backward":     ActionPcmdPitchBackward,
+	"left":         ActionPcmdRollLeft,
+	"right":        ActionPcmdRollRight,
+	"repeat":       ActionPcmdRepeatLastCmd,
+	"hover":        ActionPcmdHover,
+	"north":        ActionMoveToSetLatInc,
+	"south":        ActionMoveToSetLatDec,
+	"east":         ActionMoveToSetLonInc,
+	"west":         ActionMoveToSetLonDec,
+	"here":         ActionMoveToSetBufferCurrentPosition,
+	"execute":      ActionMoveToExecute,
+	"cancel":       ActionMoveToCancel,
+	"cancelmoveby": ActionCancelMoveBy,
+	"nudgeforward": ActionNudgeForward,
+	"nudgeback":    ActionNudgeBackward,
+	"nudgeleft":    ActionNudgeLeft,
+	"nudgeright":   ActionNudgeRight,
+	"nudgeup":      ActionNudgeUp,
+	"nudgedown":    ActionNudgeDown,
+	"emergency":    ActionEmergency,
+	"flip":         ActionFlip,
+	"flattrim":     ActionFlatTrim,
+	"resume":       ActionResumeMission,
+	"absolute":     ActionAbsoluteControlToggle,
+}
+
+// keyNames are the names of the special keys that can be bound, where
+// the other keys are named by the character they give, like "t".
+var keyNames = map[keyboard.Key]string{
+	keyboard.KeyArrowUp:    "up",
+	keyboard.KeyArrowDown:  "down",
+	keyboard.KeyArrowLeft:  "left",
+	keyboard.KeyArrowRight: "right",
+	keyboard.KeySpace:      "space",
+	keyboard.KeyCtrlW:      "ctrl+w",
+	keyboard.KeyCtrlS:      "ctrl+s",
+	keyboard.KeyCtrlA:      "ctrl+a",
+	keyboard.KeyCtrlD:      "ctrl+d",
+	keyboard.KeyCtrlX:      "ctrl+x",
+	keyboard.KeyCtrlSpace:  "ctrl+space",
+	keyboard.KeyCtrlQ:      "ctrl+q",
+}
+
+// DefaultKeyBindings are the actions of the keys used if not set with
+// SetKeyBindings. Esc for quitting, and q for reconnecting, are not
+// actions, and can't be bound.
+var DefaultKeyBindings = map[string]string{
+	"t":          "takeoff",
+	"u":          "usertakeoff",
+	"l":          "land",
+	"r":          "home",
+	"R":          "stophome",
+	"w":          "climb",
+	"s":          "descend",
+	"a":          "yawleft",
+	"d":          "yawright",
+	"up":         "forward",
+	"down":       "backward",
+	"left":       "left",
+	"right":      "right",
+	"space":      "repeat",
+	"ctrl+w":     "north",
+	"ctrl+s":     "south",
+	"ctrl+a":     "west",
+	"ctrl+d":     "east",
+	"ctrl+x":     "here",
+	"ctrl+space": "execute",
+	"ctrl+q":     "cancel",
+	"h":          "hover",
+	"I":          "nudgeforward",
+	"K":          "nudgeback",
+	"J":          "nudgeleft",
+	"L":          "nudgeright",
+	"U":          "nudgeup",
+	"N":          "nudgedown",
+	"E":          "emergency",
+	"F":          "flip",
+	"T":          "flattrim",
+	"M":          "resume",
+	"A":          "absolute",
+}
+
+// keyBindings holds the actions of the keys, where nil uses
+// DefaultKeyBindings.
+type keyBindings struct {
+	mu       sync.Mutex
+	bindings map[string]InputAction
+}
+
+// parseKeyBindings will check the key and action names, and return the
+// actions of the keys.
+func parseKeyBindings(bindings map[string]string) (map[string]InputAction, error) {
+	known := map[string]bool{}
+	for _, name := range keyNames {
+		known[name] = true
+	}
+
+	actions := make(map[string]InputAction, len(bindings))
+	for key, name := range bindings {
+		if !known[key] && len([]rune(key)) != 1 {
+			return nil, fmt.Errorf("unknown key: %v", key)
+		}
+		if key == "q" {
+			return nil, fmt.Errorf("key q is used for reconnecting, and can't be bound")
+		}
+		action, ok := inputActionNames[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown action for key %v: %v", key, name)
+		}
+		actions[key] = action
+	}
+
+	return actions, nil
+}
+
+// SetKeyBindings will set the actions of the keys used for flying with
+// the keyboard, given as key names like "t", "up" or "ctrl+w", and
+// action names like "takeoff", as in DefaultKeyBindings. The keys not
+// given have no action. A nil map will use DefaultKeyBindings.
+func (d *Drone) SetKeyBindings(bindings map[string]string) error {
+	var actions map[string]InputAction
+	if bindings != nil {
+		var err error
+		actions, err = parseKeyBindings(bindings)
+		if err != nil {
+			return fmt.Errorf("SetKeyBindings: %v", err)
+		}
+	}
+
+	d.keyBindings.mu.Lock()
+	defer d.keyBindings.mu.Unlock()
+
+	d.keyBindings.bindings = actions
+
+	return nil
+}
+
+// defaultKeyActions are the actions of DefaultKeyBindings.
+var defaultKeyActions = func() map[string]InputAction {
+	actions, err := parseKeyBindings(DefaultKeyBindings)
+	if err != nil {
+		panic(err)
+	}
+	return actions
+}()
+
+// action will return the action bound to the key pressed.
+func (k *keyBindings) action(event keyboard.KeyEvent) (InputAction, bool) {
+	var key string
+	if event.Rune != 0 {
+		key = string(event.Rune)
+	} else {
+		key = keyNames[event.Key]
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	bindings := k.bindings
+	if bindings == nil {
+		bindings = defaultKeyActions
+	}
+	action, ok := bindings[key]
+
+	return action, ok
+}
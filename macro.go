@@ -0,0 +1,207 @@
+package parrotbebop
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrMacroUnsafe is returned by ReplayMacro when the safety limits are
+// not met, before or during the replay.
+var ErrMacroUnsafe = errors.New("macro safety limits not met")
+
+// MacroEventKind is what a MacroEvent holds.
+type MacroEventKind int
+
+const (
+	// MacroAction is an input action, like from the keyboard or
+	// SendAction.
+	MacroAction MacroEventKind = iota
+	// MacroPcmd is a PCMD state given with SendPcmd or SendSticks.
+	MacroPcmd
+)
+
+// MacroEvent is an input recorded in a macro.
+type MacroEvent struct {
+	// Offset is the time since the start of the recording.
+	Offset time.Duration
+	Kind   MacroEventKind
+	// Action is the input action if the kind is MacroAction.
+	Action InputAction
+	// Pcmd is the PCMD state if the kind is MacroPcmd.
+	Pcmd Ardrone3PilotingPCMDArguments
+}
+
+// Macro is a recording of the inputs given to the drone during a manual
+// flight, which can be replayed with ReplayMacro.
+type Macro struct {
+	Recorded time.Time
+	Events   []MacroEvent
+}
+
+// Duration will return the time from the start of the macro until the
+// last event.
+func (m *Macro) Duration() time.Duration {
+	if len(m.Events) == 0 {
+		return 0
+	}
+
+	return m.Events[len(m.Events)-1].Offset
+}
+
+// WriteMacro will write the macro to w as JSON.
+func WriteMacro(w io.Writer, m *Macro) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		return fmt.Errorf("WriteMacro: %v", err)
+	}
+
+	return nil
+}
+
+// LoadMacro will read a macro written with WriteMacro.
+func LoadMacro(r io.Reader) (*Macro, error) {
+	var m Macro
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("LoadMacro: %v", err)
+	}
+
+	for i, ev := range m.Events {
+		if ev.Kind != MacroAction && ev.Kind != MacroPcmd {
+			return nil, fmt.Errorf("LoadMacro: event %v have unknown kind %v", i, ev.Kind)
+		}
+		if i > 0 && ev.Offset < m.Events[i-1].Offset {
+			return nil, fmt.Errorf("LoadMacro: event %v is before the previous event", i)
+		}
+	}
+
+	return &m, nil
+}
+
+// macroRecorder holds the macro being recorded.
+type macroRecorder struct {
+	mu    sync.Mutex
+	start time.Time
+	macro *Macro
+}
+
+// add will add the event to the macro if recording.
+func (r *macroRecorder) add(ev MacroEvent, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.macro == nil {
+		return
+	}
+	ev.Offset = now.Sub(r.start)
+	r.macro.Events = append(r.macro.Events, ev)
+}
+
+// recordAction will add the input action to the macro if recording.
+func (d *Drone) recordAction(action InputAction) {
+	d.macroRecorder.add(MacroEvent{Kind: MacroAction, Action: action}, time.Now())
+}
+
+// recordPcmd will add the PCMD state to the macro if recording.
+func (d *Drone) recordPcmd(arg Ardrone3PilotingPCMDArguments) {
+	d.macroRecorder.add(MacroEvent{Kind: MacroPcmd, Pcmd: arg}, time.Now())
+}
+
+// StartMacroRecording will start recording the input actions, and the
+// PCMD states given with SendPcmd and SendSticks, into a macro. The
+// PCMD given by the driver itself, like by the altitude hold, is not
+// recorded.
+func (d *Drone) StartMacroRecording() {
+	d.macroRecorder.mu.Lock()
+	defer d.macroRecorder.mu.Unlock()
+
+	now := time.Now()
+	d.macroRecorder.start = now
+	d.macroRecorder.macro = &Macro{Recorded: now}
+}
+
+// StopMacroRecording will stop the recording, and return the macro
+// recorded, or nil if not recording.
+func (d *Drone) StopMacroRecording() *Macro {
+	d.macroRecorder.mu.Lock()
+	defer d.macroRecorder.mu.Unlock()
+
+	m := d.macroRecorder.macro
+	d.macroRecorder.macro = nil
+
+	return m
+}
+
+// MacroSafety is the limits checked before and during the replay of a
+// macro, where the replay is stopped if they are not met.
+type MacroSafety struct {
+	// MinBattery is the lowest battery level in percent allowed.
+	MinBattery uint8
+	// RequireGPSFix will require the drone to have a gps fix with at
+	// least MinSatellites satellites.
+	RequireGPSFix bool
+	MinSatellites uint8
+}
+
+// check will return an error if the telemetry does not meet the limits.
+func (s MacroSafety) check(t Telemetry) error {
+	switch {
+	case t.Battery < s.MinBattery:
+		return fmt.Errorf("%w: battery at %v%%, need at least %v%%", ErrMacroUnsafe, t.Battery, s.MinBattery)
+	case s.RequireGPSFix && !t.GPSFixed:
+		return fmt.Errorf("%w: drone have no gps fix", ErrMacroUnsafe)
+	case s.RequireGPSFix && t.NumberOfSatellites < s.MinSatellites:
+		return fmt.Errorf("%w: %v satellites, need at least %v", ErrMacroUnsafe, t.NumberOfSatellites, s.MinSatellites)
+	}
+
+	return nil
+}
+
+// ReplayMacro will give the inputs of the macro to the drone with the
+// same timing as when recorded. The safety limits are checked before
+// the replay and before each event, and the replay is stopped if they
+// are not met. When the replay ends, is stopped or the context is
+// cancelled, the drone is left hovering with a neutral PCMD.
+func (d *Drone) ReplayMacro(ctx context.Context, m *Macro, safety MacroSafety) error {
+	if err := safety.check(d.Telemetry()); err != nil {
+		return fmt.Errorf("ReplayMacro: %w", err)
+	}
+
+	defer d.setPcmd(Ardrone3PilotingPCMDArguments{})
+
+	log.Printf("info: replaying macro with %v events over %v\n", len(m.Events), m.Duration())
+
+	start := time.Now()
+	for i, ev := range m.Events {
+		select {
+		case <-ctx.Done():
+			log.Printf("info: macro replay cancelled at event %v\n", i)
+			return nil
+		case <-time.After(time.Until(start.Add(ev.Offset))):
+		}
+
+		if err := safety.check(d.Telemetry()); err != nil {
+			log.Printf("warning: macro replay stopped at event %v: %v\n", i, err)
+			return fmt.Errorf("ReplayMacro: %w", err)
+		}
+
+		switch ev.Kind {
+		case MacroAction:
+			if err := d.SendAction(ev.Action); err != nil {
+				return fmt.Errorf("ReplayMacro: event %v: %v", i, err)
+			}
+		case MacroPcmd:
+			d.setPcmd(ev.Pcmd)
+		}
+	}
+
+	log.Printf("info: macro replay done\n")
+
+	return nil
+}
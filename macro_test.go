@@ -0,0 +1,89 @@
+package parrotbebop
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMacroRecording(t *testing.T) {
+	d := NewDrone()
+
+	d.recordAction(ActionTakeoff)
+	if m := d.StopMacroRecording(); m != nil {
+		t.Fatalf("got macro while not recording: %+v", m)
+	}
+
+	d.StartMacroRecording()
+	d.recordAction(ActionTakeoff)
+	time.Sleep(time.Millisecond * 10)
+	if err := d.SendSticks(0, 0.5, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	m := d.StopMacroRecording()
+
+	if len(m.Events) != 2 || m.Events[0].Kind != MacroAction || m.Events[0].Action != ActionTakeoff {
+		t.Fatalf("wrong events: %+v", m.Events)
+	}
+	if ev := m.Events[1]; ev.Kind != MacroPcmd || ev.Pcmd.Pitch != 50 || ev.Offset < time.Millisecond*10 {
+		t.Fatalf("wrong pcmd event: %+v", ev)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMacro(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadMacro(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Events) != 2 || loaded.Events[1] != m.Events[1] || loaded.Duration() != m.Duration() {
+		t.Fatalf("macro changed by write and load: %+v", loaded)
+	}
+
+	if _, err := LoadMacro(bytes.NewBufferString(`{"Events":[{"Offset":2},{"Offset":1}]}`)); err == nil {
+		t.Fatalf("expected error for events out of order")
+	}
+}
+
+func TestReplayMacro(t *testing.T) {
+	d := NewDrone()
+	d.telemetry.update(func(t *Telemetry) { t.Battery = 30 })
+
+	actions := make(chan InputAction, 10)
+	go func() {
+		for a := range d.chInputActions {
+			actions <- a
+		}
+	}()
+
+	m := &Macro{Events: []MacroEvent{
+		{Kind: MacroAction, Action: ActionTakeoff},
+		{Offset: time.Millisecond * 20, Kind: MacroPcmd, Pcmd: Ardrone3PilotingPCMDArguments{Flag: 1, Roll: 10}},
+		{Offset: time.Millisecond * 40, Kind: MacroAction, Action: ActionLanding},
+	}}
+
+	err := d.ReplayMacro(context.Background(), m, MacroSafety{MinBattery: 50})
+	if !errors.Is(err, ErrMacroUnsafe) {
+		t.Fatalf("expected unsafe for low battery, got %v", err)
+	}
+
+	start := time.Now()
+	if err := d.ReplayMacro(context.Background(), m, MacroSafety{MinBattery: 20}); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < time.Millisecond*40 {
+		t.Fatalf("replay faster than recorded")
+	}
+	if a := <-actions; a != ActionTakeoff {
+		t.Fatalf("got action %v, want takeoff", a)
+	}
+	if a := <-actions; a != ActionLanding {
+		t.Fatalf("got action %v, want landing", a)
+	}
+	if p := d.currentPcmd(); p != (Ardrone3PilotingPCMDArguments{}) {
+		t.Fatalf("not hovering after replay: %+v", p)
+	}
+}
@@ -0,0 +1,206 @@
+package parrotbebop
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// manifestMaxGap is how far in time a picture can be from the nearest
+// sample of the flight log, and still be placed by the flight log.
+const manifestMaxGap = time.Second * 2
+
+// ManifestEntry is a picture on the drone, and the telemetry of the
+// drone when it was taken.
+type ManifestEntry struct {
+	File   MediaFile
+	Sample FlightSample
+}
+
+// PhotoManifest will find the telemetry for each picture in files from
+// the flight log, interpolated between the samples before and after the
+// time the picture was taken. Pictures taken when there is no sample
+// within 2 seconds are left out, and videos are ignored.
+func PhotoManifest(files []MediaFile, flightLog []FlightSample) []ManifestEntry {
+	var entries []ManifestEntry
+	for _, f := range files {
+		if !f.Photo {
+			continue
+		}
+
+		s, ok := sampleAt(flightLog, f.Time)
+		if !ok {
+			log.Printf("warning: PhotoManifest: no flight log sample for %v at %v\n", f.Name, f.Time.Format(time.RFC3339))
+			continue
+		}
+		entries = append(entries, ManifestEntry{File: f, Sample: s})
+	}
+
+	return entries
+}
+
+// sampleAt will return the sample at the time given, interpolated
+// between the samples of the log ordered by time, or false if no sample
+// is within manifestMaxGap.
+func sampleAt(samples []FlightSample, t time.Time) (FlightSample, bool) {
+	i := sort.Search(len(samples), func(i int) bool { return !samples[i].Time.Before(t) })
+
+	var before, after *FlightSample
+	if i > 0 && t.Sub(samples[i-1].Time) <= manifestMaxGap {
+		before = &samples[i-1]
+	}
+	if i < len(samples) && samples[i].Time.Sub(t) <= manifestMaxGap {
+		after = &samples[i]
+	}
+
+	switch {
+	case before == nil && after == nil:
+		return FlightSample{}, false
+	case before == nil:
+		return *after, true
+	case after == nil:
+		return *before, true
+	}
+
+	f := float64(t.Sub(before.Time)) / float64(after.Time.Sub(before.Time))
+	lerp := func(a float64, b float64) float64 { return a + (b-a)*f }
+
+	// Interpolate the heading the shortest way around.
+	dh := math.Mod(after.Heading-before.Heading+540, 360) - 180
+
+	return FlightSample{
+		Time:     t,
+		Position: Interpolate(before.Position, after.Position, f),
+		Altitude: lerp(before.Altitude, after.Altitude),
+		Heading:  math.Mod(before.Heading+dh*f+360, 360),
+		Roll:     float32(lerp(float64(before.Roll), float64(after.Roll))),
+		Pitch:    float32(lerp(float64(before.Pitch), float64(after.Pitch))),
+		Tilt:     float32(lerp(float64(before.Tilt), float64(after.Tilt))),
+		Pan:      float32(lerp(float64(before.Pan), float64(after.Pan))),
+	}, true
+}
+
+// MediaManifest will list the media on the drone, and return the
+// manifest of the pictures with the flight log recorded.
+func (d *Drone) MediaManifest() ([]ManifestEntry, error) {
+	files, err := d.ListMedia()
+	if err != nil {
+		return nil, fmt.Errorf("MediaManifest: %v", err)
+	}
+
+	return PhotoManifest(files, d.FlightLog()), nil
+}
+
+// WriteManifestCSV will write the manifest as CSV with a header line,
+// with one line for each picture. The angles are in degrees, the gps
+// altitude is above sea level, and the relative altitude is above the
+// take off point.
+func WriteManifestCSV(w io.Writer, entries []ManifestEntry) error {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	deg := func(rad float32) string { return f(float64(rad) * 180 / math.Pi) }
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"name", "time", "latitude", "longitude", "altitude", "relative_altitude", "heading", "roll", "pitch", "camera_tilt", "camera_pan"})
+	for _, e := range entries {
+		s := e.Sample
+		cw.Write([]string{
+			e.File.Name,
+			e.File.Time.Format(time.RFC3339),
+			f(s.Position.Latitude),
+			f(s.Position.Longitude),
+			f(s.Position.Altitude),
+			f(s.Altitude),
+			f(s.Heading),
+			deg(s.Roll),
+			deg(s.Pitch),
+			f(float64(s.Tilt)),
+			f(float64(s.Pan)),
+		})
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("WriteManifestCSV: %v", err)
+	}
+
+	return nil
+}
+
+// geoJSONFeature is a GeoJSON feature.
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   geoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// geoJSONGeometry is a GeoJSON geometry, where the coordinates of a
+// Point are a position, and of a LineString a list of positions.
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// geoJSONPosition will return the position as GeoJSON coordinates,
+// which are longitude, latitude and altitude.
+func geoJSONPosition(p Position) [3]float64 {
+	return [3]float64{p.Longitude, p.Latitude, p.Altitude}
+}
+
+// writeGeoJSON will write the features as a GeoJSON feature collection.
+func writeGeoJSON(w io.Writer, features []geoJSONFeature) error {
+	if features == nil {
+		features = []geoJSONFeature{}
+	}
+
+	fc := struct {
+		Type     string           `json:"type"`
+		Features []geoJSONFeature `json:"features"`
+	}{
+		Type:     "FeatureCollection",
+		Features: features,
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(fc)
+}
+
+// WriteManifestGeoJSON will write the manifest as a GeoJSON feature
+// collection, with a point feature for each picture, and the rest of
+// the telemetry as the properties of the feature.
+func WriteManifestGeoJSON(w io.Writer, entries []ManifestEntry) error {
+	var features []geoJSONFeature
+	for _, e := range entries {
+		s := e.Sample
+		features = append(features, geoJSONFeature{
+			Type: "Feature",
+			Geometry: geoJSONGeometry{
+				Type:        "Point",
+				Coordinates: geoJSONPosition(s.Position),
+			},
+			Properties: map[string]interface{}{
+				"name":              e.File.Name,
+				"time":              e.File.Time.Format(time.RFC3339),
+				"relative_altitude": s.Altitude,
+				"heading":           s.Heading,
+				"roll":              float64(s.Roll) * 180 / math.Pi,
+				"pitch":             float64(s.Pitch) * 180 / math.Pi,
+				"camera_tilt":       s.Tilt,
+				"camera_pan":        s.Pan,
+			},
+		})
+	}
+
+	if err := writeGeoJSON(w, features); err != nil {
+		return fmt.Errorf("WriteManifestGeoJSON: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package parrotbebop
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFlightLog(t *testing.T) {
+	d := NewDrone()
+	now := time.Now()
+	p := Position{Latitude: 60, Longitude: 10, Altitude: 100}
+
+	d.logFlightSample(p, now)
+	if n := len(d.FlightLog()); n != 0 {
+		t.Fatalf("got %v samples while not recording", n)
+	}
+
+	d.StartFlightLog()
+	d.telemetry.update(func(t *Telemetry) { t.Altitude = 20 })
+	d.logFlightSample(p, now)
+	d.StopFlightLog()
+	d.logFlightSample(p, now)
+
+	l := d.FlightLog()
+	if len(l) != 1 || l[0].Position != p || l[0].Altitude != 20 {
+		t.Fatalf("wrong flight log: %+v", l)
+	}
+}
+
+func TestPhotoManifest(t *testing.T) {
+	start := time.Date(2016, 6, 8, 13, 10, 0, 0, time.UTC)
+	a := Position{Latitude: 60, Longitude: 10, Altitude: 100}
+	samples := []FlightSample{
+		{Time: start, Position: a, Altitude: 10, Heading: 350},
+		{Time: start.Add(time.Second * 2), Position: a.Offset(20, 0), Altitude: 20, Heading: 10},
+	}
+	files := []MediaFile{
+		{Name: "a.jpg", Time: start.Add(time.Second), Photo: true},
+		{Name: "b.mp4", Time: start.Add(time.Second), Photo: false},
+		{Name: "c.jpg", Time: start.Add(time.Second * 10), Photo: true},
+	}
+
+	entries := PhotoManifest(files, samples)
+	if len(entries) != 1 || entries[0].File.Name != "a.jpg" {
+		t.Fatalf("wrong entries: %+v", entries)
+	}
+
+	s := entries[0].Sample
+	if d := a.DistanceTo(s.Position); math.Abs(d-10) > 0.01 {
+		t.Fatalf("wrong interpolated position, %v m from start", d)
+	}
+	if s.Altitude != 15 {
+		t.Fatalf("wrong interpolated altitude: %v", s.Altitude)
+	}
+	if math.Abs(s.Heading) > 0.01 && math.Abs(s.Heading-360) > 0.01 {
+		t.Fatalf("heading not interpolated the shortest way: %v", s.Heading)
+	}
+
+	var csvBuf bytes.Buffer
+	if err := WriteManifestCSV(&csvBuf, entries); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&csvBuf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1][0] != "a.jpg" || records[1][5] != "15" {
+		t.Fatalf("wrong csv: %v", records)
+	}
+
+	var jsonBuf bytes.Buffer
+	if err := WriteManifestGeoJSON(&jsonBuf, entries); err != nil {
+		t.Fatal(err)
+	}
+	var fc struct {
+		Type     string
+		Features []struct {
+			Geometry struct {
+				Coordinates []float64
+			}
+			Properties map[string]interface{}
+		}
+	}
+	if err := json.Unmarshal(jsonBuf.Bytes(), &fc); err != nil {
+		t.Fatal(err)
+	}
+	if fc.Type != "FeatureCollection" || len(fc.Features) != 1 {
+		t.Fatalf("wrong feature collection: %+v", fc)
+	}
+	c := fc.Features[0].Geometry.Coordinates
+	if len(c) != 3 || c[0] != s.Position.Longitude || c[1] != s.Position.Latitude {
+		t.Fatalf("wrong coordinates: %v", c)
+	}
+}
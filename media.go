@@ -0,0 +1,171 @@
+package parrotbebop
+
+import (
+	"fmt"
+)
+
+// VideoRecordState is the state of the onboard video recording.
+type VideoRecordState uint32
+
+const (
+	VideoRecordStopped      VideoRecordState = 0
+	VideoRecordStarted      VideoRecordState = 1
+	VideoRecordNotAvailable VideoRecordState = 2
+)
+
+// String will return the name of the video record state.
+func (v VideoRecordState) String() string {
+	switch v {
+	case VideoRecordStopped:
+		return "stopped"
+	case VideoRecordStarted:
+		return "started"
+	case VideoRecordNotAvailable:
+		return "notAvailable"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(v))
+}
+
+// PictureState is the state of the camera for taking pictures.
+type PictureState uint32
+
+const (
+	PictureReady        PictureState = 0
+	PictureBusy         PictureState = 1
+	PictureNotAvailable PictureState = 2
+)
+
+// String will return the name of the picture state.
+func (p PictureState) String() string {
+	switch p {
+	case PictureReady:
+		return "ready"
+	case PictureBusy:
+		return "busy"
+	case PictureNotAvailable:
+		return "notAvailable"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(p))
+}
+
+// MediaError is the error reported by the drone for the video
+// recording and the picture states.
+type MediaError uint32
+
+const (
+	MediaOK         MediaError = 0
+	MediaUnknown    MediaError = 1
+	MediaCameraKO   MediaError = 2
+	MediaMemoryFull MediaError = 3
+	MediaLowBattery MediaError = 4
+)
+
+// String will return a human readable description of the media error.
+func (m MediaError) String() string {
+	switch m {
+	case MediaOK:
+		return "ok"
+	case MediaUnknown:
+		return "unknown error"
+	case MediaCameraKO:
+		return "camera failure"
+	case MediaMemoryFull:
+		return "memory full"
+	case MediaLowBattery:
+		return "battery too low"
+	}
+
+	return fmt.Sprintf("unknown media error(%d)", uint32(m))
+}
+
+// MediaStatus holds the state of the onboard video recording and the
+// picture taking reported by the drone.
+type MediaStatus struct {
+	VideoState   VideoRecordState
+	VideoError   MediaError
+	PictureState PictureState
+	PictureError MediaError
+}
+
+// MassStorage holds the information reported about one of the mass
+// storage devices of the drone.
+type MassStorage struct {
+	ID   uint8
+	Name string
+	// Size and UsedSize are given in MBytes.
+	Size     uint32
+	UsedSize uint32
+	Plugged  bool
+	Full     bool
+	Internal bool
+	// Photos and Videos are the number of media files stored.
+	Photos uint16
+	Videos uint16
+}
+
+// StorageStatus holds the storage information reported by the drone.
+type StorageStatus struct {
+	// FreeSpace is the remaining space in MBytes.
+	FreeSpace uint32
+	// RecordingTime is the remaining video recording time in minutes.
+	RecordingTime uint16
+	// PhotosRemaining is the number of pictures that can still be taken.
+	PhotosRemaining uint32
+	// Devices holds the mass storage devices by their id.
+	Devices map[uint8]MassStorage
+}
+
+// MediaStatus will return the video recording and picture state
+// reported by the drone.
+func (d *Drone) MediaStatus() MediaStatus {
+	return d.telemetry.snapshot().Media
+}
+
+// Recording will return true if the drone reports that the onboard
+// video recording is running.
+func (d *Drone) Recording() bool {
+	return d.telemetry.snapshot().Media.VideoState == VideoRecordStarted
+}
+
+// Storage will return the storage information reported by the drone,
+// like the remaining space and recording time.
+func (d *Drone) Storage() StorageStatus {
+	return d.telemetry.snapshot().Storage
+}
+
+// updateMassStorage will call fn with the mass storage device with the
+// id given, creating it if not seen before, and store the result.
+func (d *Drone) updateMassStorage(id uint8, fn func(*MassStorage)) {
+	d.telemetry.update(func(t *Telemetry) {
+		if t.Storage.Devices == nil {
+			t.Storage.Devices = make(map[uint8]MassStorage)
+		}
+		m := t.Storage.Devices[id]
+		m.ID = id
+		fn(&m)
+		t.Storage.Devices[id] = m
+	})
+}
+
+// TakePicture will ask the drone to take a picture, which is stored on
+// the drone.
+func (d *Drone) TakePicture() error {
+	if s := d.MediaStatus(); s.PictureState == PictureNotAvailable {
+		return fmt.Errorf("TakePicture: camera not available: %v", s.PictureError)
+	}
+
+	return d.sendCmd(Command(MediaRecordPictureV2), &Ardrone3MediaRecordPictureV2Arguments{})
+}
+
+// SetVideoRecording will start or stop the video recording on the
+// drone, where the video is stored on the drone.
+func (d *Drone) SetVideoRecording(record bool) error {
+	var v uint32
+	if record {
+		v = 1
+	}
+
+	return d.sendCmd(Command(MediaRecordVideoV2), &Ardrone3MediaRecordVideoV2Arguments{Record: v})
+}
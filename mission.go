@@ -0,0 +1,173 @@
+package parrotbebop
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// MissionProgress is the progress of the waypoints of the moveTo buffer.
+type MissionProgress struct {
+	// Completed is the number of waypoints flown.
+	Completed int
+	// Current is the waypoint currently being flown, or nil if none.
+	Current *Position
+	// Remaining are the waypoints not yet flown.
+	Remaining []Position
+	// Updated is when the progress last changed.
+	Updated time.Time
+}
+
+// toPosition will convert the waypoint into a Position.
+func (g gpsLatLonAlt) toPosition() Position {
+	return Position{Latitude: g.latitude, Longitude: g.longitude, Altitude: g.altitude}
+}
+
+// fromPosition will convert the position into a waypoint.
+func fromPosition(p Position) gpsLatLonAlt {
+	return gpsLatLonAlt{latitude: p.Latitude, longitude: p.Longitude, altitude: p.Altitude}
+}
+
+// progressLocked will return the progress of the buffer. Must be called
+// while holding the lock.
+func (s *moveToBuffer) progressLocked() MissionProgress {
+	p := MissionProgress{
+		Completed: s.completed,
+		Updated:   s.updated,
+	}
+	if s.current != nil {
+		c := s.current.toPosition()
+		p.Current = &c
+	}
+	for _, wp := range s.waypoints {
+		p.Remaining = append(p.Remaining, wp.toPosition())
+	}
+
+	return p
+}
+
+// changedLocked will mark the buffer as changed, and write the progress
+// to the mission file if set. Must be called while holding the lock.
+func (s *moveToBuffer) changedLocked() {
+	s.updated = time.Now()
+
+	if s.missionFile == "" {
+		return
+	}
+
+	b, err := json.MarshalIndent(s.progressLocked(), "", "  ")
+	if err != nil {
+		log.Printf("error: failed to encode mission: %v\n", err)
+		return
+	}
+
+	// Write to a temporary file first, and rename it, so a crash while
+	// writing never leaves a half written mission file.
+	tmp := s.missionFile + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		log.Printf("error: failed to write mission file: %v\n", err)
+		return
+	}
+	if err := os.Rename(tmp, s.missionFile); err != nil {
+		log.Printf("error: failed to rename mission file: %v\n", err)
+	}
+}
+
+// confirmCurrent will mark the waypoint currently flown as completed.
+func (s *moveToBuffer) confirmCurrent() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current == nil {
+		return
+	}
+	s.current = nil
+	s.completed++
+	s.changedLocked()
+}
+
+// requeueCurrent will put the waypoint currently flown back at the
+// front of the buffer, so it is flown again when the mission resumes.
+func (s *moveToBuffer) requeueCurrent() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current == nil {
+		return
+	}
+	s.waypoints = append([]gpsLatLonAlt{*s.current}, s.waypoints...)
+	s.current = nil
+	s.changedLocked()
+}
+
+// SetMissionFile will keep the waypoints of the moveTo buffer, and the
+// progress of the mission, persisted in the file given, so the mission
+// can be resumed if the controller is restarted. If the file exists,
+// the remaining waypoints are loaded into the buffer, where a waypoint
+// that was being flown when the mission stopped is flown again, and
+// the number of waypoints loaded are returned. The mission is resumed
+// with ActionMoveToExecute. An empty path will stop the persisting.
+func (d *Drone) SetMissionFile(path string) (int, error) {
+	b := d.moveToBuffer
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.missionFile = path
+	if path == "" {
+		return 0, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return 0, fmt.Errorf("SetMissionFile: %v", err)
+		}
+		b.changedLocked()
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("SetMissionFile: %v", err)
+	}
+
+	var p MissionProgress
+	if err := json.Unmarshal(data, &p); err != nil {
+		return 0, fmt.Errorf("SetMissionFile: failed to decode mission file: %v", err)
+	}
+
+	var wps []gpsLatLonAlt
+	if p.Current != nil {
+		wps = append(wps, fromPosition(*p.Current))
+	}
+	for _, wp := range p.Remaining {
+		wps = append(wps, fromPosition(wp))
+	}
+	for _, wp := range wps {
+		if err := validWayPoint(wp); err != nil {
+			return 0, fmt.Errorf("SetMissionFile: bad waypoint in mission file: %v", err)
+		}
+	}
+
+	// The loaded waypoints are put before any waypoints already added.
+	b.waypoints = append(wps, b.waypoints...)
+	b.completed = p.Completed
+	b.current = nil
+	b.changedLocked()
+
+	log.Printf("info: loaded mission with %v waypoints left, %v completed\n", len(wps), p.Completed)
+
+	return len(wps), nil
+}
+
+// MissionProgress will return the progress of the waypoints of the
+// moveTo buffer.
+func (d *Drone) MissionProgress() MissionProgress {
+	d.moveToBuffer.mu.Lock()
+	defer d.moveToBuffer.mu.Unlock()
+
+	return d.moveToBuffer.progressLocked()
+}
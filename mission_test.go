@@ -0,0 +1,46 @@
+package parrotbebop
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMissionFileResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mission")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mission.json")
+
+	d := NewDrone()
+	if n, err := d.SetMissionFile(path); err != nil || n != 0 {
+		t.Fatalf("new mission file: n = %v, err = %v", n, err)
+	}
+	for i := 1; i <= 3; i++ {
+		if err := d.InsertWaypoint(-1, Position{Latitude: float64(i), Longitude: 10, Altitude: 5}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Fly the first waypoint, and stop while flying the second.
+	d.moveToBuffer.pullWayPointNext()
+	d.moveToBuffer.confirmCurrent()
+	d.moveToBuffer.pullWayPointNext()
+
+	d2 := NewDrone()
+	n, err := d2.SetMissionFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 waypoints resumed, got %v", n)
+	}
+
+	p := d2.MissionProgress()
+	if p.Completed != 1 || p.Current != nil || len(p.Remaining) != 2 || p.Remaining[0].Latitude != 2 {
+		t.Fatalf("wrong progress after resume: %+v", p)
+	}
+}
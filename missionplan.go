@@ -0,0 +1,670 @@
+package parrotbebop
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultOrbitSpeed is the angular speed in degrees/s used for the
+// orbit step if not given.
+const defaultOrbitSpeed = 10
+
+// MissionPlan is a mission loaded from the YAML mission format, which
+// is run step by step with RunMissionPlan, like:
+//
+//  name: corners
+//  steps:
+//    - takeoff
+//    - climb: 20
+//    - moveto:
+//        latitude: 59.1
+//        longitude: 10.2
+//        altitude: 20
+//    - photo
+//    - orbit: {latitude: 59.1, longitude: 10.2, altitude: 20, radius: 10, turns: 1}
+//    - wait: 5s
+//    - wait:
+//        condition: battery >= 30
+//        timeout: 1m
+//    - land
+//
+// The steps are takeoff, land, hover, home, photo, climb, rotate, flip,
+// moveto with latitude, longitude, altitude and an optional heading,
+// orbit with latitude, longitude, altitude, radius, and the optional
+// speed in degrees/s, turns and ccw, moveby with the optional forward,
+// right and up in meters and rotate in degrees to the right, and wait
+// with a duration, or a state or condition as in the scripts, and an
+// optional timeout.
+//
+// A mission with "mode: relative" is flown without GPS, like indoor,
+// where the route is given with moveby steps, and the steps needing
+// the GPS, moveto, orbit and home, are not allowed:
+//
+//  name: hallway
+//  mode: relative
+//  steps:
+//    - takeoff
+//    - moveby: {forward: 5}
+//    - moveby: {rotate: 90}
+//    - moveby: {forward: 3, up: 1}
+//    - land
+type MissionPlan struct {
+	Name string
+	// Relative is true for a mission flown without GPS.
+	Relative bool
+	Steps    []MissionStep
+}
+
+// MissionStep is a single step of a mission plan, where the params are
+// the values given for the step. A step given with a single value, like
+// "climb: 20", have the value in the param named value.
+type MissionStep struct {
+	Type   string
+	Params map[string]string
+}
+
+// missionStepParams holds the required and optional params of each
+// type of step.
+var missionStepParams = map[string]struct{ required, optional []string }{
+	"takeoff": {},
+	"land":    {},
+	"hover":   {},
+	"home":    {},
+	"photo":   {},
+	"climb":   {required: []string{"value"}},
+	"rotate":  {required: []string{"value"}},
+	"flip":    {required: []string{"value"}},
+	"moveto":  {required: []string{"latitude", "longitude", "altitude"}, optional: []string{"heading"}},
+	"orbit":   {required: []string{"latitude", "longitude", "altitude", "radius"}, optional: []string{"speed", "turns", "ccw"}},
+	"moveby":  {optional: []string{"forward", "right", "up", "rotate"}},
+	"wait":    {optional: []string{"value", "state", "condition", "timeout"}},
+}
+
+// missionStepNeedsGPS are the steps that can't be flown in a relative
+// mission.
+var missionStepNeedsGPS = map[string]bool{
+	"moveto": true,
+	"orbit":  true,
+	"home":   true,
+}
+
+// LoadMissionPlan will read a mission plan in the YAML mission format
+// from r, and check all the steps.
+func LoadMissionPlan(r io.Reader) (*MissionPlan, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("LoadMissionPlan: %v", err)
+	}
+
+	v, err := parseYAML(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("LoadMissionPlan: %v", err)
+	}
+	doc, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("LoadMissionPlan: the mission must be a mapping with name and steps")
+	}
+
+	plan := &MissionPlan{}
+	if name, ok := doc["name"].(string); ok {
+		plan.Name = name
+	}
+	switch mode, _ := doc["mode"].(string); mode {
+	case "", "gps":
+	case "relative":
+		plan.Relative = true
+	default:
+		return nil, fmt.Errorf("LoadMissionPlan: unknown mode %q, must be gps or relative", mode)
+	}
+	steps, ok := doc["steps"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("LoadMissionPlan: missing list of steps")
+	}
+
+	for i, s := range steps {
+		step, err := missionStepFrom(s)
+		if err == nil {
+			err = step.check()
+		}
+		if err == nil && plan.Relative && missionStepNeedsGPS[step.Type] {
+			err = fmt.Errorf("%v needs the GPS, and is not allowed in a relative mission", step.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("LoadMissionPlan: step %v: %v", i+1, err)
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+
+	return plan, nil
+}
+
+// missionStepFrom will create a step from the parsed YAML value, which
+// is either the type of the step, or a mapping from the type of the
+// step to it's value or params.
+func missionStepFrom(v interface{}) (MissionStep, error) {
+	switch v := v.(type) {
+	case string:
+		return MissionStep{Type: strings.ToLower(v), Params: map[string]string{}}, nil
+	case map[string]interface{}:
+		if len(v) != 1 {
+			return MissionStep{}, fmt.Errorf("a step must have a single type, got %v", len(v))
+		}
+		for typ, val := range v {
+			step := MissionStep{Type: strings.ToLower(typ), Params: map[string]string{}}
+			switch val := val.(type) {
+			case string:
+				if val != "" {
+					step.Params["value"] = val
+				}
+			case map[string]interface{}:
+				for k, pv := range val {
+					s, ok := pv.(string)
+					if !ok {
+						return MissionStep{}, fmt.Errorf("%v: param %v must be a single value", typ, k)
+					}
+					step.Params[strings.ToLower(k)] = s
+				}
+			default:
+				return MissionStep{}, fmt.Errorf("%v: bad params", typ)
+			}
+			return step, nil
+		}
+	}
+
+	return MissionStep{}, fmt.Errorf("bad step: %v", v)
+}
+
+// check will check that the step is known, and have the params needed
+// with valid values.
+func (s MissionStep) check() error {
+	p, ok := missionStepParams[s.Type]
+	if !ok {
+		return fmt.Errorf("unknown step: %v", s.Type)
+	}
+
+	allowed := map[string]bool{}
+	for _, name := range append(p.required, p.optional...) {
+		allowed[name] = true
+	}
+	for name := range s.Params {
+		if !allowed[name] {
+			return fmt.Errorf("%v: unknown param: %v", s.Type, name)
+		}
+	}
+	for _, name := range p.required {
+		if _, ok := s.Params[name]; !ok {
+			return fmt.Errorf("%v: missing param: %v", s.Type, name)
+		}
+	}
+
+	for name, v := range s.Params {
+		var err error
+		switch {
+		case s.Type == "flip":
+			if _, ok := scriptFlips[v]; !ok {
+				err = fmt.Errorf("unknown direction")
+			}
+		case name == "timeout" || (s.Type == "wait" && name == "value"):
+			_, err = time.ParseDuration(v)
+		case name == "state":
+			if _, ok := flyingStateNamed(v); !ok {
+				err = fmt.Errorf("unknown flying state")
+			}
+		case name == "condition":
+			if len(strings.Fields(v)) != 3 {
+				err = fmt.Errorf("must be <field> <op> <value>")
+				break
+			}
+			_, err = evalScriptCond(Telemetry{}, "", strings.Fields(v))
+		case name == "ccw":
+			_, err = strconv.ParseBool(v)
+		default:
+			var f float64
+			f, err = strconv.ParseFloat(v, 64)
+			if err == nil && (math.IsNaN(f) || math.IsInf(f, 0)) {
+				err = fmt.Errorf("must be a finite number")
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%v: bad %v %q: %v", s.Type, name, v, err)
+		}
+	}
+
+	if s.Type == "moveby" && len(s.Params) == 0 {
+		return fmt.Errorf("moveby: needs one of forward, right, up or rotate")
+	}
+
+	if s.Type == "wait" {
+		n := 0
+		for _, name := range []string{"value", "state", "condition"} {
+			if _, ok := s.Params[name]; ok {
+				n++
+			}
+		}
+		if n != 1 {
+			return fmt.Errorf("wait: needs one of a duration, state or condition")
+		}
+	}
+
+	return nil
+}
+
+// float will return the param as a number, or def if not given. The
+// params are checked when loaded.
+func (s MissionStep) float(name string, def float64) float64 {
+	v, ok := s.Params[name]
+	if !ok {
+		return def
+	}
+	f, _ := strconv.ParseFloat(v, 64)
+
+	return f
+}
+
+// duration will return the param as a duration, or def if not given.
+func (s MissionStep) duration(name string, def time.Duration) time.Duration {
+	v, ok := s.Params[name]
+	if !ok {
+		return def
+	}
+	d, _ := time.ParseDuration(v)
+
+	return d
+}
+
+// RunMissionPlan will run the steps of the mission plan in order, and
+// return when all the steps are done, a step fails, the missions are
+// paused, or the context is done. Each step waits for the drone to
+// report that it is done before the next step is started. A relative
+// mission can be taken over by the pilot like the moveTo missions.
+func (d *Drone) RunMissionPlan(ctx context.Context, plan *MissionPlan) error {
+	f, err := d.PlanFeasibility(plan)
+	if err := d.checkBatteryGuard(fmt.Sprintf("mission %q", plan.Name), f, err); err != nil {
+		return fmt.Errorf("RunMissionPlan: %w", err)
+	}
+
+	if plan.Relative {
+		log.Printf("info: starting relative mission %q without GPS with %v steps\n", plan.Name, len(plan.Steps))
+		d.setRelativeMission(true)
+		defer d.setRelativeMission(false)
+	} else {
+		log.Printf("info: starting mission %q with %v steps\n", plan.Name, len(plan.Steps))
+	}
+
+	for i, step := range plan.Steps {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("RunMissionPlan: %v", err)
+		}
+		if d.MissionPaused() {
+			return fmt.Errorf("RunMissionPlan: step %v: missions paused", i+1)
+		}
+
+		log.Printf("info: mission %q: step %v: %v %v\n", plan.Name, i+1, step.Type, step.Params)
+		if err := d.runMissionStep(ctx, step); err != nil {
+			return fmt.Errorf("RunMissionPlan: step %v: %v: %v", i+1, step.Type, err)
+		}
+	}
+
+	log.Printf("info: mission %q done\n", plan.Name)
+
+	return nil
+}
+
+// runMissionStep will run a single step of a mission plan using the
+// same executors as the scripts.
+func (d *Drone) runMissionStep(ctx context.Context, s MissionStep) error {
+	stmt := func(cmd string, args ...string) error {
+		return d.runScriptStmt(ctx, scriptStmt{cmd: cmd, args: args})
+	}
+	str := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	switch s.Type {
+	case "takeoff":
+		if err := d.SendAction(ActionTakeoff); err != nil {
+			return err
+		}
+		return d.waitFlyingState(ctx, scriptStateTimeout, FlyingStateHovering, FlyingStateFlying)
+	case "land":
+		// Land stops the altitude hold of a climb, which would cancel
+		// the landing.
+		return d.Land()
+	case "hover", "home", "photo":
+		return stmt(s.Type)
+	case "moveby":
+		m := MoveBy{
+			DX:   float32(s.float("forward", 0)),
+			DY:   float32(s.float("right", 0)),
+			DZ:   float32(-s.float("up", 0)),
+			DPsi: float32(s.float("rotate", 0) * math.Pi / 180),
+		}
+		return d.runMoveBy(ctx, m)
+	case "climb", "rotate", "flip":
+		return stmt(s.Type, s.Params["value"])
+	case "moveto":
+		p := Position{Latitude: s.float("latitude", 0), Longitude: s.float("longitude", 0), Altitude: s.float("altitude", 0)}
+		if _, ok := s.Params["heading"]; !ok {
+			return stmt("moveto", str(p.Latitude), str(p.Longitude), str(p.Altitude))
+		}
+		if err := d.moveToHeading(p, s.float("heading", 0)); err != nil {
+			return err
+		}
+		return d.scriptWaitFor(ctx, scriptMoveToTimeout, func() bool {
+			return d.positionReached(p)
+		})
+	case "orbit":
+		conf := OrbitConfig{
+			Center:       Position{Latitude: s.float("latitude", 0), Longitude: s.float("longitude", 0), Altitude: s.float("altitude", 0)},
+			Radius:       s.float("radius", 0),
+			AngularSpeed: s.float("speed", defaultOrbitSpeed),
+		}
+		conf.CounterClockwise, _ = strconv.ParseBool(s.Params["ccw"])
+		if conf.AngularSpeed <= 0 {
+			return fmt.Errorf("speed must be above 0")
+		}
+
+		// Orbit runs until the context is done, so it is stopped when
+		// the turns are done.
+		turns := s.float("turns", 1)
+		dur := time.Duration(turns * 360 / conf.AngularSpeed * float64(time.Second))
+		octx, cancel := context.WithTimeout(ctx, dur)
+		defer cancel()
+		if err := d.Orbit(octx, conf); err != nil {
+			return err
+		}
+		return ctx.Err()
+	case "wait":
+		timeout := s.duration("timeout", scriptWaitTimeout)
+		switch {
+		case s.Params["value"] != "":
+			return stmt("sleep", s.Params["value"])
+		case s.Params["state"] != "":
+			state, _ := flyingStateNamed(s.Params["state"])
+			return d.waitFlyingState(ctx, timeout, state)
+		default:
+			return stmt("wait", append(strings.Fields(s.Params["condition"]), timeout.String())...)
+		}
+	}
+
+	return fmt.Errorf("unknown step: %v", s.Type)
+}
+
+// waitFlyingState will wait until the drone reports one of the flying
+// states given, the timeout, or the context is done.
+func (d *Drone) waitFlyingState(ctx context.Context, timeout time.Duration, states ...FlyingState) error {
+	is := func(state FlyingState) bool {
+		for _, st := range states {
+			if state == st {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Subscribe before checking the current state, so a change in
+	// between is not lost.
+	events, unsubscribe := d.events.subscribe()
+	defer unsubscribe()
+
+	if state, ok := d.FlyingState(); ok && is(state) {
+		return nil
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return fmt.Errorf("timed out after %v waiting for flying state %v", timeout, states)
+		case ev, ok := <-events:
+			if !ok {
+				return fmt.Errorf("event subscription closed")
+			}
+			if ev.Type != EventFlyingStateChanged {
+				continue
+			}
+			if state, ok := ev.Value.(FlyingState); ok && is(state) {
+				return nil
+			}
+		}
+	}
+}
+
+// flyingStateNamed will return the flying state with the name given.
+func flyingStateNamed(name string) (FlyingState, bool) {
+	for f := FlyingStateLanded; f <= FlyingStateEmergencyLanding; f++ {
+		if f.String() == name {
+			return f, true
+		}
+	}
+
+	return 0, false
+}
+
+// parseYAML will parse the subset of YAML used by the mission format,
+// which is block mappings and lists, flow mappings like {a: 1}, and
+// scalars, where all the scalars are returned as strings. Anchors,
+// multi line strings and multiple documents are not supported.
+func parseYAML(s string) (interface{}, error) {
+	var lines []yamlLine
+	for i, l := range strings.Split(s, "\n") {
+		l = strings.TrimRight(stripYAMLComment(l), " \t\r")
+		if strings.TrimSpace(l) == "" || strings.TrimSpace(l) == "---" {
+			continue
+		}
+		if strings.HasPrefix(strings.TrimLeft(l, " "), "\t") {
+			return nil, fmt.Errorf("line %v: tabs are not allowed for indentation", i+1)
+		}
+		text := strings.TrimLeft(l, " ")
+		lines = append(lines, yamlLine{nr: i + 1, indent: len(l) - len(text), text: text})
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("empty document")
+	}
+
+	p := yamlParser{lines: lines}
+	v, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %v: bad indentation", p.lines[p.pos].nr)
+	}
+
+	return v, nil
+}
+
+// yamlLine is a line of YAML without the indentation.
+type yamlLine struct {
+	nr     int
+	indent int
+	text   string
+}
+
+// yamlParser holds the lines, and the position of the next line to
+// parse.
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// block will parse the list or mapping starting at the current line,
+// with the indentation given.
+func (p *yamlParser) block(indent int) (interface{}, error) {
+	if strings.HasPrefix(p.lines[p.pos].text, "-") {
+		return p.list(indent)
+	}
+
+	return p.mapping(indent)
+}
+
+// list will parse a block list.
+func (p *yamlParser) list(indent int) (interface{}, error) {
+	var list []interface{}
+
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		l := p.lines[p.pos]
+		if l.text != "-" && !strings.HasPrefix(l.text, "- ") {
+			return nil, fmt.Errorf("line %v: expected a list item", l.nr)
+		}
+		item := strings.TrimLeft(strings.TrimPrefix(l.text, "-"), " ")
+
+		switch {
+		case item == "":
+			// The value is the block on the following lines.
+			p.pos++
+			if p.pos >= len(p.lines) || p.lines[p.pos].indent <= indent {
+				list = append(list, "")
+				continue
+			}
+			v, err := p.block(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		case yamlKey(item) != "" && !strings.HasPrefix(item, "{"):
+			// A mapping starting on the same line as the dash, which
+			// continues at the indentation of the first key.
+			p.lines[p.pos] = yamlLine{nr: l.nr, indent: l.indent + len(l.text) - len(item), text: item}
+			v, err := p.mapping(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		default:
+			v, err := yamlScalar(item, l.nr)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			p.pos++
+		}
+	}
+
+	return list, nil
+}
+
+// mapping will parse a block mapping.
+func (p *yamlParser) mapping(indent int) (interface{}, error) {
+	m := map[string]interface{}{}
+
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		l := p.lines[p.pos]
+		key := yamlKey(l.text)
+		if key == "" {
+			return nil, fmt.Errorf("line %v: expected key: value", l.nr)
+		}
+		if _, ok := m[key]; ok {
+			return nil, fmt.Errorf("line %v: duplicate key: %v", l.nr, key)
+		}
+		value := strings.TrimSpace(l.text[len(key)+1:])
+		p.pos++
+
+		if value != "" {
+			v, err := yamlScalar(value, l.nr)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+			continue
+		}
+
+		// The value is the block on the following lines. A list can be
+		// at the same indentation as the key.
+		if p.pos < len(p.lines) && (p.lines[p.pos].indent > indent ||
+			(p.lines[p.pos].indent == indent && strings.HasPrefix(p.lines[p.pos].text, "-"))) {
+			v, err := p.block(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+			continue
+		}
+		m[key] = ""
+	}
+
+	return m, nil
+}
+
+// yamlKey will return the key if the text is a "key: value" or "key:"
+// pair, or an empty string if not.
+func yamlKey(text string) string {
+	i := strings.Index(text, ":")
+	if i <= 0 || (i+1 < len(text) && text[i+1] != ' ') {
+		return ""
+	}
+	key := text[:i]
+	if strings.ContainsAny(key, "{}[]\"'") {
+		return ""
+	}
+
+	return key
+}
+
+// yamlScalar will parse a scalar, or a flow mapping like {a: 1, b: 2}.
+func yamlScalar(s string, nr int) (interface{}, error) {
+	if strings.HasPrefix(s, "{") {
+		if !strings.HasSuffix(s, "}") {
+			return nil, fmt.Errorf("line %v: unterminated flow mapping", nr)
+		}
+		m := map[string]interface{}{}
+		for _, pair := range strings.Split(strings.Trim(s, "{}"), ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			kv := strings.SplitN(pair, ":", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("line %v: expected key: value in flow mapping, got %q", nr, pair)
+			}
+			m[strings.TrimSpace(kv[0])] = unquoteYAML(strings.TrimSpace(kv[1]))
+		}
+		return m, nil
+	}
+	if strings.HasPrefix(s, "[") || strings.HasPrefix(s, "|") || strings.HasPrefix(s, ">") || strings.HasPrefix(s, "&") || strings.HasPrefix(s, "*") {
+		return nil, fmt.Errorf("line %v: not supported in mission files: %q", nr, s)
+	}
+
+	return unquoteYAML(s), nil
+}
+
+// unquoteYAML will remove the quotes around a quoted scalar.
+func unquoteYAML(s string) string {
+	if len(s) >= 2 && (s[0] == '"' && s[len(s)-1] == '"' || s[0] == '\'' && s[len(s)-1] == '\'') {
+		return s[1 : len(s)-1]
+	}
+
+	return s
+}
+
+// stripYAMLComment will remove a comment from the line, which starts
+// with a # at the start of the line or after a space, and not within
+// quotes.
+func stripYAMLComment(l string) string {
+	var quote byte
+	for i := 0; i < len(l); i++ {
+		c := l[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || l[i-1] == ' ' || l[i-1] == '\t'):
+			return l[:i]
+		}
+	}
+
+	return l
+}
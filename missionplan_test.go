@@ -0,0 +1,150 @@
+package parrotbebop
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadMissionPlan(t *testing.T) {
+	plan, err := LoadMissionPlan(strings.NewReader(`
+# Photograph the field.
+name: "field survey"
+steps:
+- takeoff
+- climb: 20
+- moveto:
+    latitude: 59.1   # north corner
+    longitude: 10.2
+    altitude: 20
+    heading: 90
+- photo
+- orbit: {latitude: 59.1, longitude: 10.2, altitude: 20, radius: 10, ccw: true}
+- wait: 5s
+- wait:
+    condition: battery >= 30
+    timeout: 1m
+- wait: {state: hovering}
+- land
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Name != "field survey" || len(plan.Steps) != 9 {
+		t.Fatalf("wrong plan: %+v", plan)
+	}
+
+	moveTo := plan.Steps[2]
+	if moveTo.Type != "moveto" || moveTo.Params["latitude"] != "59.1" || moveTo.Params["heading"] != "90" {
+		t.Fatalf("wrong moveto step: %+v", moveTo)
+	}
+	if plan.Steps[1].Params["value"] != "20" {
+		t.Fatalf("wrong climb step: %+v", plan.Steps[1])
+	}
+	if plan.Steps[4].Params["ccw"] != "true" || plan.Steps[4].float("speed", defaultOrbitSpeed) != defaultOrbitSpeed {
+		t.Fatalf("wrong orbit step: %+v", plan.Steps[4])
+	}
+	if plan.Steps[6].duration("timeout", 0) != time.Minute {
+		t.Fatalf("wrong wait step: %+v", plan.Steps[6])
+	}
+
+	bad := []string{
+		"steps: takeoff",
+		"name: x",
+		"steps:\n- fly",
+		"steps:\n- climb",
+		"steps:\n- moveto: {latitude: 1, longitude: 2}",
+		"steps:\n- moveto: {latitude: 1, longitude: 2, altitude: x}",
+		"steps:\n- moveto: {latitude: NaN, longitude: 2, altitude: 3}",
+		"steps:\n- orbit: {latitude: 1, longitude: 2, altitude: 3, radius: +Inf}",
+		"steps:\n- orbit: {latitude: 1, longitude: 2, altitude: 3, radius: 4, spin: 1}",
+		"steps:\n- flip: sideways",
+		"steps:\n- wait: forever",
+		"steps:\n- wait: {state: sleeping}",
+		"steps:\n- wait: {condition: battery > }",
+		"steps:\n- wait",
+		"steps:\n- land\n  - takeoff",
+	}
+	for _, b := range bad {
+		if _, err := LoadMissionPlan(strings.NewReader(b)); err == nil {
+			t.Errorf("expected error for %q", b)
+		}
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	v, err := parseYAML(`
+a: 1
+b:
+  - x
+  - y: 2
+    z: '3 # not a comment'
+c: {d: e}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := v.(map[string]interface{})
+	if m["a"] != "1" {
+		t.Fatalf("wrong a: %v", m["a"])
+	}
+	b := m["b"].([]interface{})
+	if len(b) != 2 || b[0] != "x" {
+		t.Fatalf("wrong b: %v", b)
+	}
+	if y := b[1].(map[string]interface{}); y["y"] != "2" || y["z"] != "3 # not a comment" {
+		t.Fatalf("wrong b item: %v", y)
+	}
+	if m["c"].(map[string]interface{})["d"] != "e" {
+		t.Fatalf("wrong c: %v", m["c"])
+	}
+}
+
+func TestWaitFlyingState(t *testing.T) {
+	d := NewDrone()
+
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		d.events.publish(EventFlyingStateChanged, FlyingStateTakingOff)
+		d.events.publish(EventFlyingStateChanged, FlyingStateHovering)
+	}()
+	if err := d.waitFlyingState(context.Background(), time.Second, FlyingStateHovering); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.waitFlyingState(context.Background(), time.Millisecond*50, FlyingStateLanding); err == nil {
+		t.Fatal("expected timeout")
+	}
+}
+
+func TestLoadRelativeMissionPlan(t *testing.T) {
+	plan, err := LoadMissionPlan(strings.NewReader(`
+name: hallway
+mode: relative
+steps:
+- takeoff
+- moveby: {forward: 5, up: 1}
+- moveby: {rotate: 90}
+- land
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Relative || plan.Steps[1].float("up", 0) != 1 {
+		t.Fatalf("wrong plan: %+v", plan)
+	}
+
+	bad := []string{
+		"mode: relative\nsteps:\n- moveto: {latitude: 1, longitude: 2, altitude: 3}",
+		"mode: relative\nsteps:\n- home",
+		"mode: indoor\nsteps:\n- takeoff",
+		"steps:\n- moveby",
+		"steps:\n- moveby: {back: 2}",
+	}
+	for _, b := range bad {
+		if _, err := LoadMissionPlan(strings.NewReader(b)); err == nil {
+			t.Errorf("expected error for %q", b)
+		}
+	}
+}
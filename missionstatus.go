@@ -0,0 +1,109 @@
+package parrotbebop
+
+import (
+	"sync"
+	"time"
+)
+
+// missionStatusInterval is the shortest time between the mission
+// status events.
+const missionStatusInterval = time.Second
+
+// MissionStatus is the status of the mission flown by the moveTo
+// executor, found from the mission progress, the position and the
+// ground speed of the drone.
+type MissionStatus struct {
+	// Active is true while the moveTo executor is flying the mission.
+	Active bool
+	// NextWaypoint is the index in the mission of the waypoint being
+	// flown, or the next to be flown, and Waypoints the total number of
+	// waypoints in the mission.
+	NextWaypoint int
+	Waypoints    int
+	// Percent is the percentage of the waypoints completed.
+	Percent float64
+	// RemainingDistance is the horizontal distance in meters left to
+	// fly through all the remaining waypoints.
+	RemainingDistance float64
+	// ETA is the estimated time left of the mission with the current
+	// ground speed, and ETAKnown is false if the drone is not moving
+	// or the position is not known.
+	ETA      time.Duration
+	ETAKnown bool
+}
+
+// newMissionStatus will find the status of the mission from the
+// progress, when the drone is at the position moving with the ground
+// speed in m/s.
+func newMissionStatus(p MissionProgress, pos Position, groundSpeed float64) MissionStatus {
+	var wps []Position
+	if p.Current != nil {
+		wps = append(wps, *p.Current)
+	}
+	wps = append(wps, p.Remaining...)
+
+	s := MissionStatus{
+		NextWaypoint: p.Completed,
+		Waypoints:    p.Completed + len(wps),
+	}
+	if s.Waypoints > 0 {
+		s.Percent = float64(p.Completed) / float64(s.Waypoints) * 100
+	}
+	if len(wps) == 0 || !validGPSPosition(pos) {
+		return s
+	}
+
+	from := pos
+	for _, wp := range wps {
+		s.RemainingDistance += from.DistanceTo(wp)
+		from = wp
+	}
+
+	if groundSpeed > 0 {
+		s.ETA = time.Duration(s.RemainingDistance / groundSpeed * float64(time.Second))
+		s.ETAKnown = true
+	}
+
+	return s
+}
+
+// MissionStatus will return the status of the mission flown by the
+// moveTo executor.
+func (d *Drone) MissionStatus() MissionStatus {
+	t := d.Telemetry()
+	s := newMissionStatus(d.MissionProgress(), t.Position, t.GroundSpeed)
+	s.Active = d.MoveToActive()
+
+	return s
+}
+
+// missionStatusPublisher holds when the last mission status event was
+// published.
+type missionStatusPublisher struct {
+	mu   sync.Mutex
+	last time.Time
+	// active is if the mission was active in the last event, so the
+	// event when the mission stops is always published.
+	active bool
+}
+
+// publishMissionStatus will publish the mission status as an
+// EventMissionStatus while a mission is flown, at most once every
+// missionStatusInterval, and when the mission stops.
+func (d *Drone) publishMissionStatus(now time.Time) {
+	p := &d.missionStatusPublisher
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	active := d.MoveToActive()
+	switch {
+	case !active && !p.active:
+		return
+	case active && p.active && now.Sub(p.last) < missionStatusInterval:
+		return
+	}
+
+	p.last = now
+	p.active = active
+	d.events.publish(EventMissionStatus, d.MissionStatus())
+}
@@ -0,0 +1,69 @@
+package parrotbebop
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewMissionStatus(t *testing.T) {
+	pos := Position{Latitude: 60, Longitude: 10, Altitude: 10}
+	current := pos.Offset(100, 0)
+	next := current.Offset(200, 90)
+
+	p := MissionProgress{Completed: 2, Current: &current, Remaining: []Position{next}}
+	s := newMissionStatus(p, pos, 5)
+
+	if s.NextWaypoint != 2 || s.Waypoints != 4 || s.Percent != 50 {
+		t.Fatalf("wrong progress: %+v", s)
+	}
+	if s.RemainingDistance < 299.9 || s.RemainingDistance > 300.1 {
+		t.Fatalf("wrong remaining distance: %v", s.RemainingDistance)
+	}
+	if !s.ETAKnown || s.ETA.Round(time.Second) != time.Minute {
+		t.Fatalf("wrong eta: %v, %v", s.ETA, s.ETAKnown)
+	}
+
+	// Not moving, or no gps, gives no eta.
+	if s := newMissionStatus(p, pos, 0); s.ETAKnown {
+		t.Fatal("expected unknown eta when not moving")
+	}
+	if s := newMissionStatus(p, Position{Latitude: 500, Longitude: 500}, 5); s.ETAKnown || s.RemainingDistance != 0 {
+		t.Fatalf("expected unknown eta without gps: %+v", s)
+	}
+}
+
+func TestPublishMissionStatus(t *testing.T) {
+	d := NewDrone()
+	events, unsubscribe := d.Subscribe()
+	defer unsubscribe()
+
+	now := time.Now()
+	// Nothing is published without an active mission.
+	d.publishMissionStatus(now)
+
+	d.moveToState.set(moveToWaitingConfirm)
+	d.publishMissionStatus(now)
+	d.publishMissionStatus(now.Add(time.Millisecond * 100))
+	d.moveToState.set(moveToIdle)
+	d.publishMissionStatus(now.Add(time.Millisecond * 200))
+
+	var got []bool
+	for len(got) < 2 {
+		select {
+		case ev := <-events:
+			if ev.Type == EventMissionStatus {
+				got = append(got, ev.Value.(MissionStatus).Active)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected 2 events, got %v", got)
+		}
+	}
+	if !got[0] || got[1] {
+		t.Fatalf("expected an active and a stopped event, got %v", got)
+	}
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected event: %v", ev.Type)
+	default:
+	}
+}
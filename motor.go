@@ -0,0 +1,106 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"log"
+)
+
+// MotorError is a motor error reported by the drone.
+type MotorError uint32
+
+const (
+	MotorNoError                MotorError = 0
+	MotorErrorEEPRom            MotorError = 1
+	MotorErrorMotorStalled      MotorError = 2
+	MotorErrorPropellerSecurity MotorError = 3
+	MotorErrorCommLost          MotorError = 4
+	MotorErrorRCEmergencyStop   MotorError = 5
+	MotorErrorRealTime          MotorError = 6
+	MotorErrorMotorSetting      MotorError = 7
+	MotorErrorTemperature       MotorError = 8
+	MotorErrorBatteryVoltage    MotorError = 9
+	MotorErrorLipoCells         MotorError = 10
+	MotorErrorMOSFET            MotorError = 11
+	MotorErrorBootloader        MotorError = 12
+	MotorErrorAssert            MotorError = 13
+)
+
+// String will return a human readable description of the motor error.
+func (m MotorError) String() string {
+	switch m {
+	case MotorNoError:
+		return "no error"
+	case MotorErrorEEPRom:
+		return "EEPROM access failure"
+	case MotorErrorMotorStalled:
+		return "motor stalled"
+	case MotorErrorPropellerSecurity:
+		return "propeller cutout security triggered"
+	case MotorErrorCommLost:
+		return "communication with motor failed by timeout"
+	case MotorErrorRCEmergencyStop:
+		return "RC emergency stop"
+	case MotorErrorRealTime:
+		return "motor controller scheduler real-time out of bounds"
+	case MotorErrorMotorSetting:
+		return "one or several incorrect values in motor settings"
+	case MotorErrorTemperature:
+		return "too hot or too cold Cypress temperature"
+	case MotorErrorBatteryVoltage:
+		return "battery voltage out of bounds"
+	case MotorErrorLipoCells:
+		return "incorrect number of LIPO cells"
+	case MotorErrorMOSFET:
+		return "defective MOSFET or broken motor phases"
+	case MotorErrorBootloader:
+		return "not in use, bootloader"
+	case MotorErrorAssert:
+		return "error made by the motor controller firmware"
+	}
+
+	return fmt.Sprintf("unknown motor error(%d)", uint32(m))
+}
+
+// MotorStatus holds the motor related values reported by the drone.
+type MotorStatus struct {
+	// CurrentError is the motor error currently happening.
+	CurrentError MotorError
+	// MotorIDs is a bit field of the motors with the current error,
+	// where bit 0 is motor 1.
+	MotorIDs uint8
+	// LastError is the last motor error reported, which is kept even
+	// if the error is no longer happening.
+	LastError MotorError
+	// SoftwareVersion of the motor controllers.
+	SoftwareVersion string
+	// Flights is the number of flights done with the motors.
+	Flights uint16
+	// LastFlightDuration in seconds.
+	LastFlightDuration uint16
+	// TotalFlightDuration in seconds.
+	TotalFlightDuration uint32
+}
+
+// MotorStatus will return the motor status reported by the drone.
+func (d *Drone) MotorStatus() MotorStatus {
+	return d.telemetry.snapshot().Motor
+}
+
+// handleMotorError will update the motor telemetry with the current
+// motor error, and publish a high priority event if there is an error.
+func (d *Drone) handleMotorError(motorIDs uint8, e MotorError) {
+	d.telemetry.update(func(t *Telemetry) {
+		t.Motor.CurrentError = e
+		t.Motor.MotorIDs = motorIDs
+		if e != MotorNoError {
+			t.Motor.LastError = e
+		}
+	})
+
+	if e == MotorNoError {
+		return
+	}
+
+	log.Printf("warning: motor error on motors %04b: %v\n", motorIDs, e)
+	d.events.publishPriority(EventMotorError, PriorityHigh, e)
+}
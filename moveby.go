@@ -0,0 +1,198 @@
+package parrotbebop
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+const (
+	// moveByTimeout is how long a relative move in a mission can take
+	// before it is given up.
+	moveByTimeout = time.Minute
+	// moveByMaxCorrections is how many times the rest of a relative
+	// move in a mission is tried again when the move stopped short.
+	moveByMaxCorrections = 2
+	// moveByTolerance in meters, and moveByRotationTolerance in
+	// radians, are how far from the target a relative move in a
+	// mission can end without being corrected.
+	moveByTolerance         = 0.2
+	moveByRotationTolerance = 5 * math.Pi / 180
+)
+
+// MoveBy is a relative move, where DX is forward, DY is right and DZ is
+// down in meters, and DPsi is the rotation to the right in radians.
+type MoveBy struct {
+	DX   float32
+	DY   float32
+	DZ   float32
+	DPsi float32
+}
+
+// MoveByEnd is sent by the drone when a relative move ends, with how
+// far the drone actually moved, and why it stopped.
+type MoveByEnd struct {
+	// Moved is the move the drone managed to do.
+	Moved MoveBy
+	Error MoveByError
+}
+
+// Remaining will return what is left of the move m after the move
+// ended, which can be sent as a new move to correct for a move that
+// was interrupted or stopped short. The move is relative to the heading
+// of the drone when it started, so the rest of the move is rotated by
+// the part of the rotation done, to be relative to the heading of the
+// drone when it ended.
+func (e MoveByEnd) Remaining(m MoveBy) MoveBy {
+	dx := float64(m.DX - e.Moved.DX)
+	dy := float64(m.DY - e.Moved.DY)
+	sin, cos := math.Sincos(float64(e.Moved.DPsi))
+
+	return MoveBy{
+		DX:   float32(dx*cos + dy*sin),
+		DY:   float32(dy*cos - dx*sin),
+		DZ:   m.DZ - e.Moved.DZ,
+		DPsi: m.DPsi - e.Moved.DPsi,
+	}
+}
+
+// arguments will return the moveBy command arguments of the move.
+func (m MoveBy) arguments() *Ardrone3PilotingmoveByArguments {
+	return &Ardrone3PilotingmoveByArguments{DX: m.DX, DY: m.DY, DZ: m.DZ, DPsi: m.DPsi}
+}
+
+// sendMoveBy will send the relative move from the action handler.
+func (d *Drone) sendMoveBy(packetCreator *udpPacketCreator, m MoveBy) {
+	m = d.limitMoveBy(m)
+	d.chSendingUDPPacket <- packetCreator.encodeCmd(Command(PilotingmoveBy), m.arguments())
+	d.events.publish(EventMoveBySent, m)
+}
+
+// publishMoveBy will publish an event if the command sent was a moveBy
+// or a cancel of the moveBy.
+func (d *Drone) publishMoveBy(arg Encoder) {
+	switch arg := arg.(type) {
+	case *Ardrone3PilotingmoveByArguments:
+		d.events.publish(EventMoveBySent, MoveBy{DX: arg.DX, DY: arg.DY, DZ: arg.DZ, DPsi: arg.DPsi})
+	case *Ardrone3PilotingCancelMoveByArguments:
+		d.events.publish(EventMoveByCancelled, nil)
+	}
+}
+
+// moveByEndOf will return the end of the move from the event arguments.
+func moveByEndOf(arg Ardrone3PilotingEventmoveByEndArguments) MoveByEnd {
+	return MoveByEnd{
+		Moved: MoveBy{DX: arg.DX, DY: arg.DY, DZ: arg.DZ, DPsi: arg.DPsi},
+		Error: MoveByError(arg.Error),
+	}
+}
+
+// handleMoveByEnd will publish the end of a relative move reported by
+// the drone.
+func (d *Drone) handleMoveByEnd(arg Ardrone3PilotingEventmoveByEndArguments) {
+	d.events.publish(EventMoveByEnd, moveByEndOf(arg))
+}
+
+// MoveBy will move the drone relative to where it is, and wait until
+// the drone reports that the move ended. The end of the move is
+// returned together with an error if the move was not completed, like
+// when interrupted by another move, so the rest of the move can be
+// done with MoveByEnd.Remaining.
+func (d *Drone) MoveBy(m MoveBy, timeout time.Duration) (MoveByEnd, error) {
+	if err := d.checkFlyingStateFor(ActionMoveBy); err != nil {
+		return MoveByEnd{}, fmt.Errorf("MoveBy: %v", err)
+	}
+	m = d.limitMoveBy(m)
+
+	v, err := d.SendAndWait(Command(PilotingmoveBy), m.arguments(), Command(PilotingEventmoveByEnd), timeout)
+	if err != nil {
+		return MoveByEnd{}, fmt.Errorf("MoveBy: %v", err)
+	}
+	arg, ok := v.(Ardrone3PilotingEventmoveByEndArguments)
+	if !ok {
+		return MoveByEnd{}, fmt.Errorf("MoveBy: unexpected move end %T", v)
+	}
+
+	e := moveByEndOf(arg)
+	if e.Error != MoveByOK {
+		return e, fmt.Errorf("MoveBy: move ended with %v, moved %+v of %+v", e.Error, e.Moved, m)
+	}
+
+	return e, nil
+}
+
+// CancelMoveBy will stop the current relative move, and the drone
+// reports the end of the move with how far it got.
+func (d *Drone) CancelMoveBy() error {
+	if err := d.sendCmd(Command(PilotingCancelMoveBy), &Ardrone3PilotingCancelMoveByArguments{}); err != nil {
+		return fmt.Errorf("CancelMoveBy: %v", err)
+	}
+
+	return nil
+}
+
+// within will return true if the move is within the tolerance of the
+// relative moves in a mission.
+func (m MoveBy) within() bool {
+	dist := math.Sqrt(float64(m.DX*m.DX + m.DY*m.DY + m.DZ*m.DZ))
+	return dist <= moveByTolerance && math.Abs(float64(m.DPsi)) <= moveByRotationTolerance
+}
+
+// runMoveBy will do the relative move for a mission, and wait for the
+// drone to report the end of the move. A move that stopped short is
+// corrected by moving the rest of the way, which is how a mission
+// without GPS keeps on it's route. A move interrupted, like by the
+// pilot taking over, is not corrected, and an error is returned, as it
+// is if the missions are paused. The move is cancelled if the context
+// is done.
+func (d *Drone) runMoveBy(ctx context.Context, m MoveBy) error {
+	type result struct {
+		end MoveByEnd
+		err error
+	}
+
+	for i := 0; ; i++ {
+		if d.MissionPaused() {
+			return fmt.Errorf("missions paused")
+		}
+
+		// Limit before sending, so the rest of the move is from the
+		// move limited.
+		m = d.limitMoveBy(m)
+		done := make(chan result, 1)
+		go func(m MoveBy) {
+			e, err := d.MoveBy(m, moveByTimeout)
+			done <- result{end: e, err: err}
+		}(m)
+
+		var r result
+		select {
+		case <-ctx.Done():
+			if err := d.CancelMoveBy(); err != nil {
+				log.Printf("error: runMoveBy: %v\n", err)
+			}
+			return ctx.Err()
+		case r = <-done:
+		}
+
+		if r.err != nil && r.end == (MoveByEnd{}) {
+			// The move was refused, or no move end was received.
+			return r.err
+		}
+		if r.end.Error == MoveByNotAvailable || r.end.Error == MoveByInterrupted {
+			return r.err
+		}
+
+		rest := r.end.Remaining(m)
+		if rest.within() {
+			return nil
+		}
+		if i == moveByMaxCorrections {
+			return fmt.Errorf("move stopped short by %+v after %v corrections: %v", rest, i, r.err)
+		}
+		log.Printf("info: runMoveBy: move ended with %v, moving the rest %+v\n", r.end.Error, rest)
+		m = rest
+	}
+}
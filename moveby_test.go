@@ -0,0 +1,185 @@
+package parrotbebop
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMoveBy(t *testing.T) {
+	d := NewDrone()
+	d.setFlyingState(FlyingStateHovering)
+
+	events, unsubscribe := d.events.subscribe()
+	defer unsubscribe()
+
+	// Answer each move with a move end that got half way, and was
+	// interrupted.
+	go func() {
+		for range d.chSendingUDPPacket {
+			c := Command(PilotingEventmoveByEnd)
+			d.checkCmdFromDrone(protocolARCommands{project: int(c.Project), class: int(c.Class), command: int(c.Cmd)},
+				Ardrone3PilotingEventmoveByEndArguments{DX: 1, DZ: -0.5, Error: uint32(MoveByInterrupted)})
+		}
+	}()
+
+	m := MoveBy{DX: 2, DZ: -1}
+	e, err := d.MoveBy(m, time.Second)
+	if err == nil {
+		t.Fatalf("expected error for an interrupted move")
+	}
+	if e.Error != MoveByInterrupted {
+		t.Fatalf("got error %v, want interrupted", e.Error)
+	}
+	if got, want := e.Remaining(m), (MoveBy{DX: 1, DZ: -0.5}); got != want {
+		t.Fatalf("got remaining %+v, want %+v", got, want)
+	}
+
+	var sent, ended bool
+	for !sent || !ended {
+		select {
+		case ev := <-events:
+			switch ev.Type {
+			case EventMoveBySent:
+				sent = ev.Value.(MoveBy) == m
+			case EventMoveByEnd:
+				ended = ev.Value.(MoveByEnd) == e
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("missing events, sent %v, ended %v", sent, ended)
+		}
+	}
+
+	// Moving is refused while landed.
+	d.setFlyingState(FlyingStateLanded)
+	if _, err := d.MoveBy(m, time.Second); err == nil {
+		t.Fatalf("expected error when moving while landed")
+	}
+}
+
+// answerMoveBy will answer the moveBy commands sent with the move end
+// given by answer for the move sent, and put the moves sent on the
+// channel returned.
+func answerMoveBy(d *Drone, answer func(m MoveBy) Ardrone3PilotingEventmoveByEndArguments) <-chan MoveBy {
+	sent := make(chan MoveBy, 10)
+	go func() {
+		for p := range d.chSendingUDPPacket {
+			f, _, err := DecodeFrame(p.data)
+			if err != nil {
+				continue
+			}
+			_, v, _ := DecodeCommand(f.Data)
+			arg, ok := v.(Ardrone3PilotingmoveByArguments)
+			if !ok {
+				continue
+			}
+			m := MoveBy{DX: arg.DX, DY: arg.DY, DZ: arg.DZ, DPsi: arg.DPsi}
+			sent <- m
+			c := Command(PilotingEventmoveByEnd)
+			d.checkCmdFromDrone(protocolARCommands{project: int(c.Project), class: int(c.Class), command: int(c.Cmd)}, answer(m))
+		}
+	}()
+
+	return sent
+}
+
+func TestRunMoveBy(t *testing.T) {
+	d := NewDrone()
+	d.setFlyingState(FlyingStateHovering)
+
+	// The first move stops half way, and the correction for the rest of
+	// the move is done.
+	first := true
+	sent := answerMoveBy(d, func(m MoveBy) Ardrone3PilotingEventmoveByEndArguments {
+		if first {
+			first = false
+			return Ardrone3PilotingEventmoveByEndArguments{DX: 2, DPsi: 0.5, Error: uint32(MoveByOK)}
+		}
+		return Ardrone3PilotingEventmoveByEndArguments{DX: m.DX, DY: m.DY, DZ: m.DZ, DPsi: m.DPsi, Error: uint32(MoveByOK)}
+	})
+
+	if err := d.runMoveBy(context.Background(), MoveBy{DX: 4, DPsi: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	<-sent
+	// The rest of the move is relative to the heading after turning
+	// 0.5 radians to the right, so the rest of the forward move is
+	// partly to the left.
+	got := <-sent
+	wantX, wantY := 2*math.Cos(0.5), -2*math.Sin(0.5)
+	if math.Abs(float64(got.DX)-wantX) > 1e-3 || math.Abs(float64(got.DY)-wantY) > 1e-3 || math.Abs(float64(got.DPsi)-0.5) > 1e-3 {
+		t.Fatalf("got correction %+v, want DX %.3f, DY %.3f, DPsi 0.5", got, wantX, wantY)
+	}
+}
+
+func TestRunMoveByInterrupted(t *testing.T) {
+	d := NewDrone()
+	d.setFlyingState(FlyingStateHovering)
+
+	// A move interrupted, like by the pilot, must not be corrected.
+	sent := answerMoveBy(d, func(m MoveBy) Ardrone3PilotingEventmoveByEndArguments {
+		return Ardrone3PilotingEventmoveByEndArguments{DX: 1, Error: uint32(MoveByInterrupted)}
+	})
+
+	if err := d.runMoveBy(context.Background(), MoveBy{DX: 4}); err == nil {
+		t.Fatalf("expected error when interrupted")
+	}
+	<-sent
+	select {
+	case m := <-sent:
+		t.Fatalf("interrupted move corrected with %+v", m)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	// No move is sent while the missions are paused.
+	d.missionPause.paused = true
+	if err := d.runMoveBy(context.Background(), MoveBy{DX: 4}); err == nil {
+		t.Fatalf("expected error when paused")
+	}
+	select {
+	case m := <-sent:
+		t.Fatalf("move %+v sent while paused", m)
+	case <-time.After(time.Millisecond * 50):
+	}
+}
+
+func TestNudgeDistance(t *testing.T) {
+	d := NewDrone()
+	d.setFlyingState(FlyingStateHovering)
+	go func() {
+		for range d.chSendingUDPPacket {
+		}
+	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.handleInputAction(d.packetCreator, ctx)
+
+	if err := d.SetNudgeDistance(11); err == nil {
+		t.Fatalf("expected error for a nudge distance above 10m")
+	}
+
+	events, unsubscribe := d.events.subscribe()
+	defer unsubscribe()
+
+	// The distance can be changed while the actions are handled.
+	if err := d.SetNudgeDistance(3); err != nil {
+		t.Fatal(err)
+	}
+	d.chInputActions <- ActionNudgeRight
+	for {
+		select {
+		case ev := <-events:
+			if ev.Type != EventMoveBySent {
+				continue
+			}
+			if got := ev.Value.(MoveBy); got != (MoveBy{DY: 3}) {
+				t.Fatalf("got move %+v, want 3m right", got)
+			}
+			return
+		case <-time.After(time.Second):
+			t.Fatalf("no moveBy sent for the nudge")
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package parrotbebop
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The MQTT 3.1.1 packet types used.
+const (
+	mqttConnect    = 1
+	mqttConnack    = 2
+	mqttPublish    = 3
+	mqttSubscribe  = 8
+	mqttSuback     = 9
+	mqttPingreq    = 12
+	mqttDisconnect = 14
+)
+
+// mqttMaxPacketSize is the largest packet read from the broker, where
+// the actions are the only messages received.
+const mqttMaxPacketSize = 64 * 1024
+
+// MQTTConfig is how to connect with the MQTT broker for RunMQTT.
+type MQTTConfig struct {
+	// Broker is the host:port of the MQTT broker, where the port
+	// defaults to 1883.
+	Broker string
+	// ClientID defaults to "parrotbebop".
+	ClientID string
+	// Username and Password are given if not empty.
+	Username string
+	Password string
+	// Prefix is put before the topics, and defaults to "bebop".
+	Prefix string
+	// Interval is how often the telemetry is published, and defaults
+	// to 1 second.
+	Interval time.Duration
+	// KeepAlive is the MQTT keep alive, and defaults to 30 seconds.
+	KeepAlive time.Duration
+	// ActionToken must be given before the name of the action in the
+	// messages on the action topic, like "<token> takeoff", since any
+	// client of the broker can publish. The action topic is not
+	// subscribed to if empty.
+	ActionToken string
+}
+
+// withDefaults will return the config with the defaults filled in.
+func (c MQTTConfig) withDefaults() MQTTConfig {
+	if _, _, err := net.SplitHostPort(c.Broker); err != nil {
+		c.Broker = net.JoinHostPort(c.Broker, "1883")
+	}
+	if c.ClientID == "" {
+		c.ClientID = "parrotbebop"
+	}
+	if c.Prefix == "" {
+		c.Prefix = "bebop"
+	}
+	if c.Interval <= 0 {
+		c.Interval = time.Second
+	}
+	if c.KeepAlive <= 0 {
+		c.KeepAlive = time.Second * 30
+	}
+
+	return c
+}
+
+// mqttConn is a connection with an MQTT broker, only doing QoS 0.
+type mqttConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	// mu is held while writing a packet.
+	mu sync.Mutex
+}
+
+// mqttString will return the string prefixed with it's length.
+func mqttString(s string) []byte {
+	b := make([]byte, 2, 2+len(s))
+	binary.BigEndian.PutUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// write will write a packet of the type, with the flags and the body.
+func (m *mqttConn) write(typ byte, flags byte, body []byte) error {
+	p := []byte{typ<<4 | flags}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		p = append(p, b)
+		if n == 0 {
+			break
+		}
+	}
+	p = append(p, body...)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := m.conn.Write(p)
+	return err
+}
+
+// read will read a packet, and return the type, the flags and the body.
+func (m *mqttConn) read() (byte, byte, []byte, error) {
+	h, err := m.r.ReadByte()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	n, mult := 0, 1
+	for i := 0; ; i++ {
+		b, err := m.r.ReadByte()
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		if i == 4 {
+			return 0, 0, nil, fmt.Errorf("malformed remaining length")
+		}
+		n += int(b&0x7f) * mult
+		mult *= 128
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	if n > mqttMaxPacketSize {
+		return 0, 0, nil, fmt.Errorf("packet of %v bytes is larger than the max %v", n, mqttMaxPacketSize)
+	}
+
+	body := make([]byte, n)
+	if _, err := io.ReadFull(m.r, body); err != nil {
+		return 0, 0, nil, err
+	}
+
+	return h >> 4, h & 0x0f, body, nil
+}
+
+// publish will publish the payload on the topic with QoS 0.
+func (m *mqttConn) publish(topic string, payload []byte, retain bool) error {
+	var flags byte
+	if retain {
+		flags = 1
+	}
+
+	return m.write(mqttPublish, flags, append(mqttString(topic), payload...))
+}
+
+// dialMQTT will connect with the broker, and subscribe to the topics
+// given.
+func dialMQTT(ctx context.Context, c MQTTConfig, topics ...string) (*mqttConn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.Broker)
+	if err != nil {
+		return nil, err
+	}
+	m := &mqttConn{conn: conn, r: bufio.NewReader(conn)}
+
+	// Clean session, with the username and password if given.
+	flags := byte(0x02)
+	payload := mqttString(c.ClientID)
+	if c.Username != "" {
+		flags |= 0x80
+		payload = append(payload, mqttString(c.Username)...)
+	}
+	if c.Password != "" {
+		flags |= 0x40
+		payload = append(payload, mqttString(c.Password)...)
+	}
+	body := append(mqttString("MQTT"), 4, flags, 0, 0)
+	binary.BigEndian.PutUint16(body[len(body)-2:], uint16(c.KeepAlive/time.Second))
+	body = append(body, payload...)
+
+	conn.SetDeadline(time.Now().Add(time.Second * 10))
+	if err := m.write(mqttConnect, 0, body); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	typ, _, ack, err := m.read()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if typ != mqttConnack || len(ack) != 2 || ack[1] != 0 {
+		conn.Close()
+		return nil, fmt.Errorf("connection refused by broker: %v", ack)
+	}
+
+	if len(topics) > 0 {
+		body := []byte{0, 1}
+		for _, t := range topics {
+			body = append(body, mqttString(t)...)
+			body = append(body, 0)
+		}
+		if err := m.write(mqttSubscribe, 0x02, body); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if typ, _, _, err := m.read(); err != nil || typ != mqttSuback {
+			conn.Close()
+			return nil, fmt.Errorf("subscribe failed: %v", err)
+		}
+	}
+	conn.SetDeadline(time.Time{})
+
+	return m, nil
+}
+
+// mqttEvent is an event published on MQTT.
+type mqttEvent struct {
+	Type     string      `json:"type"`
+	Time     time.Time   `json:"time"`
+	Priority Priority    `json:"priority"`
+	Value    interface{} `json:"value,omitempty"`
+}
+
+// RunMQTT will make the drone available to a remote ground station
+// through the MQTT broker given, until the context is done. The broker
+// is connected with again if the connection is lost. The topics are
+// put after the prefix, which defaults to "bebop":
+//
+//  bebop/telemetry     : the Telemetry as JSON at the interval given.
+//  bebop/health        : the HealthStatus as JSON at the interval
+//                        given, retained.
+//  bebop/event/<type>  : each event published by the driver as JSON.
+//  bebop/action        : subscribed if an ActionToken is given, where
+//                        the payload is the token and the name of an
+//                        action to do, like "<token> land", as used for
+//                        the key bindings.
+//
+// Only QoS 0 is used, so the messages can be lost when the link is bad,
+// which is fine for the telemetry sent again at each interval.
+func (d *Drone) RunMQTT(ctx context.Context, c MQTTConfig) error {
+	if c.Broker == "" {
+		return fmt.Errorf("RunMQTT: no broker given")
+	}
+	c = c.withDefaults()
+
+	backoff := discoveryMinBackoff
+	for {
+		err := d.runMQTTOnce(ctx, c)
+		if ctx.Err() != nil {
+			return nil
+		}
+		log.Printf("warning: RunMQTT: %v, connecting again in %v\n", err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > discoveryMaxBackoff {
+			backoff = discoveryMaxBackoff
+		}
+	}
+}
+
+// runMQTTOnce will connect with the broker, and publish and receive
+// until the connection fails or the context is done.
+func (d *Drone) runMQTTOnce(ctx context.Context, c MQTTConfig) error {
+	actionTopic := c.Prefix + "/action"
+	var topics []string
+	if c.ActionToken != "" {
+		topics = append(topics, actionTopic)
+	}
+	m, err := dialMQTT(ctx, c, topics...)
+	if err != nil {
+		return err
+	}
+	defer m.conn.Close()
+	log.Printf("info: RunMQTT: connected with broker %v\n", c.Broker)
+
+	// Read the actions, and the pings answered, until the connection
+	// fails.
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			typ, flags, body, err := m.read()
+			if err != nil {
+				readErr <- err
+				return
+			}
+			if typ != mqttPublish || len(body) < 2 {
+				continue
+			}
+			n := int(binary.BigEndian.Uint16(body))
+			if len(body) < 2+n {
+				continue
+			}
+			topic, payload := string(body[2:2+n]), body[2+n:]
+			if flags&0x06 != 0 && len(payload) >= 2 {
+				// Skip the packet id given with QoS 1 and 2.
+				payload = payload[2:]
+			}
+			if c.ActionToken == "" || topic != actionTopic {
+				continue
+			}
+
+			fields := strings.Fields(string(payload))
+			if len(fields) != 2 || subtle.ConstantTimeCompare([]byte(fields[0]), []byte(c.ActionToken)) != 1 {
+				log.Printf("warning: RunMQTT: action refused, missing or wrong token\n")
+				continue
+			}
+			name := fields[1]
+			action, ok := inputActionNames[name]
+			if !ok {
+				log.Printf("warning: RunMQTT: unknown action: %q\n", name)
+				continue
+			}
+			if err := d.SendAction(action); err != nil {
+				log.Printf("error: RunMQTT: %v\n", err)
+			}
+		}
+	}()
+
+	events, unsubscribe := d.events.subscribe()
+	defer unsubscribe()
+
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+	ping := time.NewTicker(c.KeepAlive / 2)
+	defer ping.Stop()
+
+	// publishJSON will publish the value as JSON, where a value that
+	// can't be given as JSON is skipped.
+	publishJSON := func(topic string, v interface{}, retain bool) error {
+		b, err := json.Marshal(v)
+		if err != nil {
+			log.Printf("warning: RunMQTT: skipping %v: %v\n", topic, err)
+			return nil
+		}
+		return m.publish(c.Prefix+"/"+topic, b, retain)
+	}
+
+	for {
+		var err error
+
+		select {
+		case <-ctx.Done():
+			m.write(mqttDisconnect, 0, nil)
+			return nil
+		case err = <-readErr:
+			return err
+		case <-ping.C:
+			err = m.write(mqttPingreq, 0, nil)
+		case <-ticker.C:
+			err = publishJSON("telemetry", d.Telemetry(), false)
+			if err == nil {
+				err = publishJSON("health", d.HealthStatus(), true)
+			}
+		case ev := <-events:
+			err = publishJSON("event/"+ev.Type.String(), mqttEvent{Type: ev.Type.String(), Time: ev.Time, Priority: ev.Priority, Value: ev.Value}, false)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
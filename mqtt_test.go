@@ -0,0 +1,90 @@
+package parrotbebop
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunMQTT(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	d := NewDrone()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.RunMQTT(ctx, MQTTConfig{Broker: l.Addr().String(), Prefix: "test", Interval: time.Millisecond * 50, ActionToken: "secret"})
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Second * 5))
+	broker := &mqttConn{conn: conn, r: bufio.NewReader(conn)}
+
+	typ, _, body, err := broker.read()
+	if err != nil || typ != mqttConnect || !strings.Contains(string(body), "parrotbebop") {
+		t.Fatalf("expected connect, got %v %q %v", typ, body, err)
+	}
+	broker.write(mqttConnack, 0, []byte{0, 0})
+
+	typ, _, body, err = broker.read()
+	if err != nil || typ != mqttSubscribe || !strings.Contains(string(body), "test/action") {
+		t.Fatalf("expected subscribe, got %v %q %v", typ, body, err)
+	}
+	broker.write(mqttSuback, 0, []byte{0, 1, 0})
+
+	// Only an action published with the token on the action topic is
+	// given to the driver.
+	broker.publish("test/action", []byte("land"), false)
+	broker.publish("test/action", []byte("wrong emergency"), false)
+	broker.publish("test/action", []byte("secret takeoff"), false)
+	select {
+	case a := <-d.chInputActions:
+		if a != ActionTakeoff {
+			t.Fatalf("got action %v, want takeoff", a)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatalf("timed out waiting for the action")
+	}
+
+	// The telemetry is published at the interval.
+	for {
+		typ, _, body, err := broker.read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ != mqttPublish {
+			continue
+		}
+		n := binary.BigEndian.Uint16(body)
+		if topic := string(body[2 : 2+n]); topic == "test/telemetry" {
+			if !strings.HasPrefix(string(body[2+n:]), "{") {
+				t.Fatalf("expected JSON telemetry, got %q", body[2+n:])
+			}
+			break
+		}
+	}
+}
+
+func TestMQTTReadTooLarge(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	// A publish with a remaining length of 128 MB.
+	go server.Write([]byte{mqttPublish << 4, 0x80, 0x80, 0x80, 0x40})
+
+	m := &mqttConn{conn: client, r: bufio.NewReader(client)}
+	if _, _, _, err := m.read(); err == nil {
+		t.Fatalf("expected error for a packet larger than the max")
+	}
+}
@@ -0,0 +1,147 @@
+package parrotbebop
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// BufferStats holds the counters of one ARNetwork buffer.
+type BufferStats struct {
+	BufferID int
+	// FramesSent and FramesReceived are the number of data frames sent
+	// to and received from the drone on the buffer.
+	FramesSent     uint64
+	FramesReceived uint64
+	// AcksSent is the number of acks sent for frames received on the
+	// buffer, and AcksReceived is the number of acks received from the
+	// drone for frames sent on the buffer.
+	AcksSent     uint64
+	AcksReceived uint64
+	// Lost is the number of frames from the drone detected as lost,
+	// based on gaps in the sequence numbers.
+	Lost uint64
+	// Dropped is the number of frames that failed to be sent.
+	Dropped uint64
+	// LastSeqSent and LastSeqReceived are the last sequence numbers
+	// seen on the buffer.
+	LastSeqSent     uint8
+	LastSeqReceived uint8
+}
+
+// netStats holds the ARNetwork statistics for each buffer.
+type netStats struct {
+	mu      sync.Mutex
+	buffers map[int]*BufferStats
+	// seqSeen is true for a buffer when a sequence number have been
+	// received on the current connection, and is used to detect gaps.
+	seqSeen map[int]bool
+}
+
+// buffer will return the stats for the buffer ID, and create them if
+// not existing. Must be called while holding the lock.
+func (n *netStats) buffer(id int) *BufferStats {
+	if n.buffers == nil {
+		n.buffers = make(map[int]*BufferStats)
+	}
+
+	b, ok := n.buffers[id]
+	if !ok {
+		b = &BufferStats{BufferID: id}
+		n.buffers[id] = b
+	}
+
+	return b
+}
+
+// frameSent will count a frame sent to the drone, where ok tells if
+// the frame was sent successfully.
+func (n *netStats) frameSent(dataType int, bufferID int, seq uint8, ok bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if dataType == dataTypeAck {
+		b := n.buffer(bufferID - ackBufferOffset)
+		if !ok {
+			b.Dropped++
+			return
+		}
+		b.AcksSent++
+		return
+	}
+
+	b := n.buffer(bufferID)
+	if !ok {
+		b.Dropped++
+		return
+	}
+	b.FramesSent++
+	b.LastSeqSent = seq
+}
+
+// frameReceived will count a frame received from the drone, and check
+// the sequence number for lost frames.
+func (n *netStats) frameReceived(dataType int, bufferID int, seq uint8) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if dataType == dataTypeAck {
+		n.buffer(bufferID-ackBufferOffset).AcksReceived++
+		return
+	}
+
+	b := n.buffer(bufferID)
+	b.FramesReceived++
+
+	if n.seqSeen == nil {
+		n.seqSeen = make(map[int]bool)
+	}
+	if n.seqSeen[bufferID] {
+		// A gap of more than half the sequence range is most likely a
+		// frame that was re-sent or reordered, and not lost frames.
+		if gap := seq - b.LastSeqReceived - 1; gap > 0 && gap < 128 {
+			b.Lost += uint64(gap)
+		}
+	}
+	n.seqSeen[bufferID] = true
+	b.LastSeqReceived = seq
+}
+
+// resetSequences will forget the sequence numbers received, and is
+// called when a new connection is made since the drone then starts
+// on new sequence numbers.
+func (n *netStats) resetSequences() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.seqSeen = nil
+}
+
+// Stats will return a snapshot of the ARNetwork statistics for each
+// buffer used, sorted by buffer ID.
+func (d *Drone) Stats() []BufferStats {
+	d.netStats.mu.Lock()
+	defer d.netStats.mu.Unlock()
+
+	stats := make([]BufferStats, 0, len(d.netStats.buffers))
+	for _, b := range d.netStats.buffers {
+		stats = append(stats, *b)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].BufferID < stats[j].BufferID
+	})
+
+	return stats
+}
+
+// StatsHandler will return a http handler serving the ARNetwork
+// statistics given by Stats as JSON.
+func (d *Drone) StatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(d.Stats()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package parrotbebop
+
+import (
+	"testing"
+)
+
+func TestNetStatsLostFrames(t *testing.T) {
+	var n netStats
+
+	// Sequence numbers wrapping around, with 253 and 1 lost.
+	for _, seq := range []uint8{251, 252, 254, 255, 0, 2} {
+		n.frameReceived(2, 127, seq)
+	}
+	// A re-sent frame should not be counted as lost.
+	n.frameReceived(2, 127, 2)
+
+	b := n.buffers[127]
+	if b.FramesReceived != 7 {
+		t.Fatalf("wrong number of frames received: %v", b.FramesReceived)
+	}
+	if b.Lost != 2 {
+		t.Fatalf("wrong number of lost frames: %v, want 2", b.Lost)
+	}
+}
+
+func TestNetStatsAcks(t *testing.T) {
+	var n netStats
+
+	n.frameSent(4, 11, 5, true)
+	n.frameReceived(dataTypeAck, 11+ackBufferOffset, 5)
+	n.frameSent(dataTypeAck, 127+ackBufferOffset, 1, true)
+	n.frameSent(2, 10, 6, false)
+
+	if b := n.buffers[11]; b.FramesSent != 1 || b.AcksReceived != 1 || b.LastSeqSent != 5 {
+		t.Fatalf("wrong stats for buffer 11: %+v", *b)
+	}
+	if b := n.buffers[127]; b.AcksSent != 1 {
+		t.Fatalf("wrong stats for buffer 127: %+v", *b)
+	}
+	if b := n.buffers[10]; b.Dropped != 1 || b.FramesSent != 0 {
+		t.Fatalf("wrong stats for buffer 10: %+v", *b)
+	}
+}
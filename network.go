@@ -4,99 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/binary"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
-	"net"
 	"os"
 	"reflect"
-	"strconv"
+	"sync"
 	"time"
 	"unsafe"
 )
 
-// Discover will initalize the connection with the drone.
-func (d *Drone) Discover() error {
-	// A discover with JSON formated data like :
-	//
-	// { "status": 0, "c2d_port": 54321, "c2d_update_port": 51, "c2d_user_port": 21, "qos_mode": 0, "arstream2_server_stream_port": 5004, "arstream2_server_control_port": 5005 }
-
-	//const addr = "192.168.42.1:44444"
-
-	nd := net.Dialer{Timeout: time.Second * 3, Cancel: d.chQuit}
-	discoverConn, err := nd.Dial("tcp", d.addressDrone+":"+d.portDiscover)
-	if err != nil {
-		return err
-	}
-
-	defer func() {
-		err := discoverConn.Close()
-		if err != nil {
-			log.Printf("error: failed to close discoverConn: %v\r\n", err)
-		}
-		log.Printf("...closed discoverConn\r\n")
-	}()
-
-	// The drone expects the discovery data payload in the following format.
-	_, err = discoverConn.Write(
-		[]byte(
-			fmt.Sprintf(`{
-						"controller_type": "computer",
-						"controller_name": "go-bebop",
-						"d2c_port": "%s",
-						"arstream2_client_stream_port": "%s",
-						"arstream2_client_control_port": "%s",
-						}`,
-				d.portD2C,
-				d.portRTPStream,
-				d.portRTPControl),
-		),
-	)
-	if err != nil {
-		log.Println("error: Discover, discoveryClient.Write: ", err)
-	}
-
-	data := make([]byte, 1024) // not quite sure about the size here...
-
-	// Read the returned response of the discovery from the drone.
-	_, err = discoverConn.Read(data)
-	if err != nil {
-		return err
-	}
-	log.Printf("*** Discovery data \r\n %v \r\n\r\n, Size of data = %v\r\n", string(data), len(data))
-
-	// Using anonymous struct just for unmarshalling the discoveryData
-	discoverData := struct {
-		Status                     int `json:"status"`
-		C2dPort                    int `json:"c2d_port"`
-		C2dUpdate                  int `json:"c2d_update_port"`
-		C2dUserPort                int `json:"c2d_user_port"`
-		QosMode                    int `json:"qos_mode"`
-		Arstream2ServerStreamPort  int `json:"arstream2_server_stream_port"`
-		Arstream2ServerControlPort int `json:"arstream2_server_control_port"`
-	}{}
-
-	// Remove all the zero allocations in the byte slice, else unmarshal will fail.
-	data = bytes.Trim(data, "\x00")
-
-	if err := json.Unmarshal(data, &discoverData); err != nil {
-		log.Println("error:Umarshal discovery data: ", err)
-	}
-	fmt.Printf("Unmarshaled : %v\r\n", discoverData)
-
-	// if the status !=0 the disovery failed.
-	if discoverData.Status != 0 {
-		log.Fatal("DISCOVERY FAILED")
-	}
-
-	// Set the received Controller to Drone port to use based on discovery data.
-	d.portC2D = strconv.Itoa(discoverData.C2dPort)
-
-	return nil
-}
-
 // // getNetworkTestingPacketsD2C gets the raw UDP packets from the test data.
 // // Will read the raw testing UDP packets, and put them on a channel to be
 // // picked up by the frame decoder.
@@ -124,6 +42,10 @@ func (d *Drone) Discover() error {
 //
 // }
 
+// maxUDPPacketSize is the size of the buffer the UDP packets from the
+// drone are read into.
+const maxUDPPacketSize = 16384
+
 // getNetworkPacketsD2C gets the raw UDP packets from the drone sent to the controller.
 // Will read the raw UDP packets from the network, and put them on a channel to be
 // picked up by the frame decoder.
@@ -137,31 +59,40 @@ func (d *Drone) readNetworkUDPPacketsD2C(ctx context.Context) {
 		log.Printf("...closed connUDPRead\r\n")
 	}()
 
+	p := make([]byte, maxUDPPacketSize)
+
 	for {
 		select {
 		case <-ctx.Done():
 			log.Printf("info: exiting readNetworkUDPPacketD2C\n")
 			return
 		default:
-			p := make([]byte, 16384) // NB: buf might be to small ?
+
+			// The deadline is only for checking if the context is done
+			// while no data is received. There can be legitimate quiet
+			// periods, and the link loss is detected by the missing pings
+			// in startLinkWatchdog.
+			d.connUDPRead.SetReadDeadline(time.Now().Add(readPollInterval))
 
 			n, addr, err := d.connUDPRead.ReadFrom(p)
 			if err != nil {
 				if errors.Is(err, os.ErrDeadlineExceeded) {
-					d.chNetworkConnect <- struct{}{}
-					return
+					continue
 				}
 				log.Printf("error: failed ReadFrom: %v %v\n", addr, err)
+				continue
 			}
+			// The read buffer is used again for the next packet, so
+			// only a copy of the size of the packet is passed on.
+			data := append([]byte(nil), p[:n]...)
 
-			// setting the deadline after a succesful write will make the
-			// next read fail if it does not receive any data within the
-			// deadline
-			d.connUDPRead.SetReadDeadline(time.Now().Add(time.Second * 3))
+			d.linkWatchdog.data(time.Now())
+			d.capture.udp(addr, d.capture.local(d.portD2C), data)
+			d.frameDebug.packet("D2C", data)
 
 			packet := networkUDPPacket{
 				size: n,
-				data: p,
+				data: data,
 				// Set framePos to zero so we start with the first frame.
 				framePos: 0,
 			}
@@ -182,28 +113,104 @@ func (d *Drone) writeNetworkUDPPacketsC2D(ctx context.Context) {
 		if err != nil {
 			log.Printf("error:failed to close connUDPWrite: %v\r\n", err)
 		}
-		fmt.Printf("...connUDPWrite closed\r\n")
+		d.debugf("...connUDPWrite closed\r\n")
 	}()
 
+	// The frames are given to the traffic shaper, which holds them back
+	// until the rate limits allow them to be sent.
+	var shaper trafficShaper
+
+	// When the drone asks for QoS in the discovery each packet is marked
+	// with the class selector of it's frame.
+	qos := qosMarker{conn: d.connUDPWrite, tos: -1}
+
+	// held are the frames held back for packing until flush.
+	var held []networkUDPPacket
+	var flush <-chan time.Time
+
+	write := func(ps []networkUDPPacket) {
+		for _, v := range ps {
+			if d.qosMode == 1 {
+				qos.mark(classSelectorFor(v.data))
+			}
+			d.writeUDPPacket(v)
+		}
+	}
+
 	for {
+		var due <-chan time.Time
+		if wait, ok := shaper.next(time.Now()); ok {
+			due = time.After(wait)
+		}
+
+		flushNow := false
 		select {
 		case <-ctx.Done():
 			log.Printf("info: exiting writeNetworkUDPPacketsC2D\n")
 			return
 		case v := <-d.chSendingUDPPacket:
+			shaper.add(v)
+		case <-due:
+		case <-flush:
+			flushNow = true
+		}
 
-			fmt.Printf("sending to Drone, v = %v\r\n", v.data)
+		shaping := d.trafficShaping.get()
+		ps := shaper.ready(shaping, time.Now())
+		if !shaping.PackFrames && len(held) == 0 {
+			write(ps)
+			continue
+		}
 
-			n, err := d.connUDPWrite.Write(v.data)
-			if err != nil {
-				log.Printf("error: failed conn.Write while sending: %v", err)
+		held = append(held, ps...)
+		for _, v := range ps {
+			if isControlFrame(v.data) {
+				flushNow = true
 			}
+		}
+		if len(held) == 0 {
+			continue
+		}
+		if flushNow || !shaping.PackFrames || shaping.PackDelay == 0 {
+			write(packFrames(held, d.qosMode == 1))
+			held = nil
+			flush = nil
+			continue
+		}
+		if flush == nil {
+			flush = time.After(shaping.PackDelay)
+		}
+	}
+}
+
+// writeUDPPacket will write the UDP packet to the drone.
+func (d *Drone) writeUDPPacket(v networkUDPPacket) {
+	d.debugf("sending to Drone, v = %v\r\n", v.data)
 
-			fmt.Printf("*** while sending to Drone, n = %v\r\n", n)
-			fmt.Printf("--------------------\r\n")
-			//time.Sleep(time.Millisecond * 200)
+	n, err := d.connUDPWrite.Write(v.data)
+	if err != nil {
+		log.Printf("error: failed conn.Write while sending: %v", err)
+	} else {
+		d.keepAlive.sent(time.Now())
+	}
+	d.capture.udp(d.connUDPWrite.LocalAddr(), d.connUDPWrite.RemoteAddr(), v.data)
+	d.frameDebug.packet("C2D", v.data)
+	// The packet can hold several frames when packed, which are each
+	// counted.
+	for b := v.data; len(b) >= 3; {
+		d.netStats.frameSent(int(b[0]), int(b[1]), b[2], err == nil)
+		if len(b) < 7 {
+			break
+		}
+		n := int(binary.LittleEndian.Uint32(b[3:7]))
+		if n < 7 || n > len(b) {
+			break
 		}
+		b = b[n:]
 	}
+
+	d.debugf("*** while sending to Drone, n = %v\r\n", n)
+	d.debugf("--------------------\r\n")
 }
 
 // handleReadPackages holds the logic for what action to do when an UDP
@@ -217,9 +224,7 @@ func (d *Drone) handleReadPackages(packetCreator *udpPacketCreator, ctx context.
 		case <-ctx.Done():
 			log.Printf("info: exiting handleReadPAclages\n")
 			return fmt.Errorf("error: context.Done() for handleReadPackages")
-		default:
-			// Get a packet
-			udpPacket := <-d.chReceivedUDPPacket
+		case udpPacket := <-d.chReceivedUDPPacket:
 
 			var lastFrame bool
 			// An UDP Packet can consist of several frames, loop over each
@@ -238,6 +243,15 @@ func (d *Drone) handleReadPackages(packetCreator *udpPacketCreator, ctx context.
 				if err == io.EOF {
 					lastFrame = true
 				}
+				// A malformed frame means that the rest of the packet
+				// can't be trusted either, so skip it and continue with
+				// the next packet.
+				if err != nil && err != io.EOF {
+					log.Printf("warning: skipping UDP packet: %v\n", err)
+					break
+				}
+
+				d.netStats.frameReceived(frameARNetworkAL.dataType, frameARNetworkAL.targetBufferID, uint8(frameARNetworkAL.sequenceNR))
 
 				// • Ack(1): Acknowledgment of previously received data
 				// • Data(2): Normal data (no ack requested)
@@ -251,12 +265,25 @@ func (d *Drone) handleReadPackages(packetCreator *udpPacketCreator, ctx context.
 				// pong is not received within 5 seconds.
 				// Check if it is a ping packet from drone, and incase
 				// it is, reply with a pong.
-				if frameARNetworkAL.targetBufferID == 0 || frameARNetworkAL.targetBufferID == 1 {
-					{
-						p := packetCreator.encodePong(frameARNetworkAL)
-						d.chSendingUDPPacket <- p
-					}
+				//
+				// The acks from the drone for the frames we have sent are
+				// only counted in the stats, and frames on buffers not
+				// known are skipped, so neither are decoded as commands.
+				buf, err := lookupBuffer(frameARNetworkAL.targetBufferID)
+				skip := true
+				switch {
+				case err != nil:
+					log.Printf("warning: %v\n", err)
+				case buf.id == bufferPing:
+					d.linkWatchdog.ping(time.Now())
+					p := packetCreator.encodePong(frameARNetworkAL)
+					d.chSendingUDPPacket <- p
+				case buf.id == bufferPong || frameARNetworkAL.dataType == dataTypeAck:
+				default:
+					skip = false
+				}
 
+				if skip {
 					if lastFrame {
 						break
 					}
@@ -264,8 +291,8 @@ func (d *Drone) handleReadPackages(packetCreator *udpPacketCreator, ctx context.
 					continue
 				}
 
-				// Send an ACK packet if the dataType == 4
-				if frameARNetworkAL.dataType == 4 {
+				// Send an ACK packet if the frame is data with ack.
+				if frameARNetworkAL.dataType == dataTypeDataWithAck {
 					{
 						p := packetCreator.encodeAck(frameARNetworkAL.targetBufferID, uint8(frameARNetworkAL.sequenceNR))
 						d.chSendingUDPPacket <- p
@@ -276,9 +303,23 @@ func (d *Drone) handleReadPackages(packetCreator *udpPacketCreator, ctx context.
 				// Based on the type of cmdArgs we can execute som action.
 				cmd, cmdArgs, err := frameARNetworkAL.decode()
 				if err != nil {
-					log.Println("error: frame.decode: ", err)
-					break
+					log.Printf("warning: skipping frame: %v\n", err)
+
+					if lastFrame {
+						break
+					}
+
+					continue
+				}
+				// The same commands are received many times a second,
+				// so they are only logged when changed, and not too
+				// often.
+				if d.logThrottle.allow(cmdArgs, time.Now()) {
+					d.debugf("* Content of frame : protocolARNetworkAL%+v\r\n", frameARNetworkAL)
+					d.debugCmd(cmd, cmdArgs)
 				}
+				d.eventJSON.record(Command{Project: ProjectDef(cmd.project), Class: ClassDef(cmd.class), Cmd: CmdDef(cmd.command)}, cmdArgs, time.Now())
+
 				// Check the type of the command received from drone, and do
 				// some action.
 				d.checkCmdFromDrone(cmd, cmdArgs)
@@ -298,25 +339,34 @@ func (d *Drone) handleReadPackages(packetCreator *udpPacketCreator, ctx context.
 // a channel, and then do the logic for landing/takeoff/rotate etc.
 
 // PcmdPacketScheduler
-// The idea here is for every time.After we check if there
-// is a new received packet. If there is we passing it along
-// on the d.chSendingUDPPacket channel, if there is nothing
-// we just nothing and loop again. It will also drop packets
-// if the chSendingUDPPacket channel is congested.
+// The idea here is for every tick of the PCMD interval we send the
+// latest pcmd state set with setPcmd to the drone, encoded with a fresh
+// timestamp and sequence number. The drone expects a continuous stream
+// of PCMD's while flying like the official SDK does, so the state is
+// re-sent even if it have not changed. Only the latest state is kept,
+// so pcmd's set faster than the interval are coalesced into one, and
+// never queued up to be sent delayed.
 func (d *Drone) PcmdPacketScheduler(ctx context.Context) {
-	duration1 := time.Duration(50) * time.Millisecond
+	var seq uint8
+	// Start with a hover command, so we don't continue with any
+	// movement from a previous connection.
+	d.setPcmd(Ardrone3PilotingPCMDArguments{})
 
 	for {
 		select {
 		case <-ctx.Done():
 			log.Println("info: exiting PcmdPacketScheduler")
 			return
-		case <-time.After(duration1):
+		case <-time.After(d.pcmdInterval()):
+			arg := d.limitPcmd(d.currentPcmd())
+			arg.TimestampAndSeqNum = pcmdTimestampAndSeqNum(time.Now(), seq)
+			seq++
+
 			select {
-			case p := <-d.chPcmdPacketScheduler:
-				d.chSendingUDPPacket <- p
-			default:
-				// log.Printf("No packets to send, or buffer full\n")
+			case d.chSendingUDPPacket <- d.packetCreator.encodeCmd(Command(PilotingPCMD), arg):
+			case <-ctx.Done():
+				log.Println("info: exiting PcmdPacketScheduler")
+				return
 			}
 		}
 	}
@@ -352,6 +402,9 @@ type networkUDPPacket struct {
 	// the packet the value will be set to the start position of the next
 	// frame in the slice.
 	framePos int
+	// raw is true for the frames sent with SendRawCommand, which are
+	// sent as they are and never coalesced by the traffic shaper.
+	raw bool
 }
 
 // udpPacketCreator will keep the sequence counter needed
@@ -360,7 +413,11 @@ type networkUDPPacket struct {
 // Since the type is uint8 we don't need any logic to put
 // it back to 0 when >255, since it jump back to zero when
 // max value is reached.
+// A single udpPacketCreator is shared by all the go routines sending to
+// the drone, so it must always be used by pointer, and the sequence
+// numbers are only to be used through nextSequence.
 type udpPacketCreator struct {
+	mu sync.Mutex
 	// The sequence number used when sending packets
 	//
 	// Each individual ID has it's
@@ -377,21 +434,42 @@ func newUdpPacketCreator() *udpPacketCreator {
 	}
 }
 
+// nextSequence will return the sequence number to use for the next
+// frame sent on the buffer, and increment it, so two frames on the same
+// buffer never gets the same sequence number.
+func (u *udpPacketCreator) nextSequence(bufferID int) uint8 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	seq := u.sequenceNR[bufferID]
+	u.sequenceNR[bufferID]++
+
+	return seq
+}
+
+// reset will start all the buffers on sequence number 0 again, and is
+// used when a new connection with the drone is made.
+func (u *udpPacketCreator) reset() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.sequenceNR = make(map[int]uint8)
+}
+
 // encode will prepare a pong packet to be used as
 // a response for an incomming ping packet.
 // The ID of the incomming ping packet is put in the
 // payload of the pong response packet.
 func (u *udpPacketCreator) encodePong(data protocolARNetworkAL) networkUDPPacket {
+	buf := networkBuffers[bufferPong]
 
-	u.sequenceNR[int(data.targetBufferID)]++
-
-	pdataType := uint8(2)
-	ptargetBufferID := uint8(data.targetBufferID)
-	psequenceNR := uint8(u.sequenceNR[int(ptargetBufferID)])
-	psize := []byte{8, 0, 0, 0}
+	pdataType := uint8(buf.dataType)
+	ptargetBufferID := uint8(buf.id)
+	psequenceNR := u.nextSequence(buf.id)
 	pdata := data.dataARNetwork
-
-	u.sequenceNR[int(ptargetBufferID)]++
+	// The header size is 7 bytes, and the payload is the one of the ping.
+	psize := make([]byte, 4)
+	binary.LittleEndian.PutUint32(psize, uint32(7+len(pdata)))
 
 	d := []byte{pdataType, ptargetBufferID, psequenceNR}
 	d = append(d, psize...)
@@ -413,17 +491,16 @@ func (u *udpPacketCreator) encodeAck(targetBufferID int, sequenceNR uint8) netwo
 	// E.g. : To acknowledge the frame    "(hex) 04 0b 42 0b000000 12345678",
 	// you will need to send a frame like "(hex) 01 8b 01 08000000 42"
 
-	pdataType := uint8(1)
-	ptargetBufferID := uint8(targetBufferID + 128)
-	psequenceNR := sequenceNR
+	// The ack frame have it's own sequence number on the ack buffer.
+	pdataType := uint8(dataTypeAck)
+	ptargetBufferID := uint8(ackID(targetBufferID))
+	psequenceNR := u.nextSequence(ackID(targetBufferID))
 	// Ack is always 8 bytes. 7 bytes of header, and 1 byte for the received
 	// sequence number put into the data part.
 	psize := []byte{8, 0, 0, 0}
 	// Put the received sequence number into the data payload
 	pdata := uint8(sequenceNR)
 
-	u.sequenceNR[int(ptargetBufferID)]++
-
 	d := []byte{pdataType, ptargetBufferID, psequenceNR}
 	d = append(d, psize...)
 	d = append(d, pdata)
@@ -444,78 +521,25 @@ func (u *udpPacketCreator) encodeCmd(c Command, argument Encoder) networkUDPPack
 	//  • Data with ack(4): Data requesting an ack. The receiver must send an
 	//    ack for this data !
 
-	// Controller To Device buffers
-	// • Non ack data (periodic commands for piloting and camera orientation).
-	//   Non ack data (periodic commands for piloting and camera orientation).
-	//   This buffers transports ARCommands.
-	//   {
-	//   .ID = 10
-	//   .dataType = ARNETWORKAL FRAME TYPE DATA;
-	//   ...
-	//   }
-	//
-	// • Ack data (Events, settings ...).
-	//   Ack data (Events, settings ...).
-	//   This buffers transports ARCommands.
-	//   {
-	//   .ID = 11
-	//   .dataType = ARNETWORKAL FRAME TYPE DATA WITH ACK;
-	//   ...
-	//   }
-	//
-	// • Emergency data (Emergency command only).
-	//   This buffers transports ARCommands.
-	//   {
-	//   .ID = 12
-	//   .dataType = ARNETWORKAL FRAME TYPE DATA WITH ACK;
-	//   ...
-	//   }
-	//
-	// • ARStream video acks.
-	//   This buffers transports ARStream data.
-	//   {
-	//   .ID = 13
-	//   .dataType = ARNETWORKAL FRAME TYPE DATA LOW LATENCY;
-	//   ...
-	//   }
-
-	// Setting buffer to 10 which is no-ack for ARCommands
-	// 11 is for packages that should be ack'ed.
-	const buffer int = 10
-
-	// setting type to data no-ack
-	pdataType := uint8(2)
-	// ARCommands uses buffer 11 ?
-	ptargetBufferID := uint8(buffer)
-
-	u.sequenceNR[buffer]++
-	psequenceNR := u.sequenceNR[buffer]
-	// Convert the content of the Command from input argument from struct to []byte
-	pdata := convertCMDToBytes(Command(c))
-
-	adata := argument.Encode()
-	log.Printf("%#v\n", adata)
-
-	// The header size is 7 bytes, 1+1+1+4.
-	const headerSize uint32 = 7
-
-	// Get the size, and convert it to a []byte with length of 4.
-	size := uint32(len(pdata)) + uint32(len(adata)) + headerSize
-	var buf bytes.Buffer
-	err := binary.Write(&buf, binary.LittleEndian, size)
-	if err != nil {
-		fmt.Printf("error: binary write failed: %v\r\n", err)
-	}
-	psize := buf.Bytes()
+	// The buffer, and the data type of the frame, is given by the
+	// kind of command, see bufferForCmd.
+	nb := bufferForCmd(c)
 
-	// Create the data package by putting the values in the correct places.
-	d := []byte{pdataType, ptargetBufferID, psequenceNR}
-	d = append(d, psize...)
-	d = append(d, pdata...)
-	d = append(d, adata...)
+	return u.encodeFrame(nb, EncodeCommand(c, argument))
+}
+
+// encodeFrame will encode the data in a frame for the buffer given,
+// with the next sequence number of the buffer.
+func (u *udpPacketCreator) encodeFrame(nb networkBuffer, data []byte) networkUDPPacket {
+	f := Frame{
+		DataType: nb.dataType,
+		BufferID: nb.id,
+		Sequence: int(u.nextSequence(nb.id)),
+		Data:     data,
+	}
 
 	return networkUDPPacket{
-		data: d,
+		data: EncodeFrame(f),
 	}
 }
 
@@ -556,37 +580,42 @@ const (
 // If the there are more than one ARNetworkAL frame in the UDP packet the
 // method will return error == nil, and the method should be run over again
 // until io.EOF is received.
+// If the frame is malformed ErrShortFrame or ErrFrameSize is returned
+// wrapped with the position of the frame, and the rest of the packet
+// should be skipped.
 func (packet *networkUDPPacket) decode() (protocolARNetworkAL, error) {
-	// TODO: Make the program check that the length of the packet is the
-	// same as the size field, and if they are not equal do something
-	// about it.......check if this verification is needed at all, or
-	// if is already handled in the ARNetworkAL protocol itself ?
-	frame := protocolARNetworkAL{
-		dataType:       int(packet.data[packet.framePos+0]),
-		targetBufferID: int(packet.data[packet.framePos+1]),
-		sequenceNR:     int(packet.data[packet.framePos+2]),
-		dataARNetwork:  []byte{},
+	// The size given might be larger than the data if the packet is
+	// created by hand, so use the smallest of them.
+	end := packet.size
+	if end > len(packet.data) {
+		end = len(packet.data)
 	}
 
-	fmt.Printf("* Content of frame : protocolARNetworkAL%+v\r\n", frame)
+	pos := packet.framePos
+	if pos < 0 || pos > end {
+		return protocolARNetworkAL{}, fmt.Errorf("decode: %w at position %v, packet size %v", ErrShortFrame, pos, end)
+	}
 
-	// Get the size of the ARNetworkAL frame. Size includes the header of 7bytes.
-	var size uint32
-	ConvLittleEndianSliceToNumeric(packet.data[packet.framePos+3:packet.framePos+7], &size)
+	f, rest, err := DecodeFrame(packet.data[pos:end])
+	if err != nil {
+		return protocolARNetworkAL{}, fmt.Errorf("decode: at position %v: %w", pos, err)
+	}
 
-	frame.size = int(size)
-	frame.dataARNetwork = packet.data[packet.framePos+7 : packet.framePos+frame.size]
+	frame := protocolARNetworkAL{
+		dataType:       f.DataType,
+		targetBufferID: f.BufferID,
+		sequenceNR:     f.Sequence,
+		size:           f.Size(),
+		dataARNetwork:  f.Data,
+	}
 
 	// Figure out if there are another frame after this one.
 	// This can be checked if there are a complete header
 	// of 7bytes following directly afte the current frame.
-	const headerSize = 7
-
-	if packet.framePos+frame.size+headerSize <= packet.size {
-		packet.framePos = packet.framePos + frame.size
+	if len(rest) >= frameHeaderSize {
+		packet.framePos = pos + frame.size
 
 		return frame, nil
-
 	}
 
 	return frame, io.EOF
@@ -641,73 +670,18 @@ type protocolARNetworkAL struct {
 
 // decode will try to decode the command found in the ARNetworkAL frame,
 // if it fails it will return an empty protocolARCommands struct, and the
-// error
+// error, which is ErrShortCommand or ErrMalformedArguments.
 func (p *protocolARNetworkAL) decode() (cmd protocolARCommands, cmdArgs interface{}, err error) {
-	const headerSize = 7
-
-	// Start preparing a cmd struct that will be returned to the caller.
-	cmd = protocolARCommands{
-		project: int(p.dataARNetwork[0]),
-		class:   int(p.dataARNetwork[1]),
-		size:    p.size - headerSize,
-	}
-
-	//fmt.Println("1. inside command contains = ", cmd)
-
-	// Since we read and slice out 2 bytes, we need to use an uint16 to
-	// write into. We then convert the uint16 to int, and store the
-	// value in the command field of the struct.
-	var tmpCommand uint16
-	ConvLittleEndianSliceToNumeric(p.dataARNetwork[2:4], &tmpCommand)
-	cmd.command = int(tmpCommand)
-
-	//fmt.Printf("tmpCommand = %v, %T\n", tmpCommand, tmpCommand)
-	//fmt.Println("2. inside command contains = ", cmd)
-	//fmt.Println("******************Parsing of command*************************")
-	//fmt.Printf("* cmd.project = %v\n", cmd.project)
-	//fmt.Printf("* cmd.class = %v\n", cmd.class)
-	//fmt.Printf("* cmd.command = %v\n", cmd.command)
-	//fmt.Printf("* cmd.size = %v\n", cmd.size)
-	//fmt.Println()
-
-	// #### Done parsing the project/class/cmd
-	// ------------------- Figure out arguments and types from here.
-
-	//... testing from here
-	// Creating a temporary command value of type command (which is the same as used in the map)
-	// of project/class/def with the values parsed earlier, we need this type to compare against
-	// the map.
-	// The key's of map are a variable of type 'command', and we will check if we find that
-	// same variable later.
-	c := Command{
-		Project: ProjectDef(cmd.project),
-		Class:   ClassDef(cmd.class),
-		Cmd:     CmdDef(cmd.command),
+	c, cmdArgs, err := DecodeCommand(p.dataARNetwork)
+	if err != nil {
+		return protocolARCommands{}, nil, err
 	}
-	//fmt.Printf("c = %#v\n", c)
-
-	// prereq : Parse arg struct, and create arg map which maps arg struct to cmd.
-	arguments := p.dataARNetwork[4:cmd.size]
-	//fmt.Printf("--- arguments = %+v\n", arguments)
-	//fmt.Println("******************End Parsing of command*********************")
-
-	// To get the actual type we have to check the map holding all the commands, to get it's
-	// actual type.
-	// Check if the command c with the correct values are specified in the map, and if it is...
-	v, ok := CommandMap[c]
-	if ok {
-		//fmt.Printf("+++++ main : Content before calling decode of v = %+v, arguments = %v\n", v, arguments)
-
-		//-- !!!!!!!!! If you are running the _test file uncomment the line below
-		// and comment out the 2 lines below that one so the output doesn't get flooded.
-		//_ = v.decode(arguments)
-		cmdArgs = v.Decode(arguments)
-		// fmt.Printf("cmdargmain : type %T, arguments = %+v\n", cmdArgs, cmdArgs)
-
-		// Check the type...for testing
-		//_, ok := args.(ardrone3PilotingStateAttitudeChangedArguments)
-		//fmt.Println("The result of the type check for arguments = ", ok)
 
+	cmd = protocolARCommands{
+		project: int(c.Project),
+		class:   int(c.Class),
+		command: int(c.Cmd),
+		size:    len(p.dataARNetwork),
 	}
 
 	return cmd, cmdArgs, nil
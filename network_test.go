@@ -0,0 +1,138 @@
+package parrotbebop
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"testing"
+)
+
+// attitudeFrame will return a frame with the attitude changed command,
+// and the number of argument bytes given.
+func attitudeFrame(argBytes int) []byte {
+	c := convertCMDToBytes(Command(PilotingStateAttitudeChanged))
+	size := 7 + len(c) + argBytes
+
+	f := []byte{2, bufferD2CNavdata, 1, byte(size), 0, 0, 0}
+	f = append(f, c...)
+	f = append(f, make([]byte, argBytes)...)
+
+	return f
+}
+
+func TestPacketDecodeMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"empty", []byte{}, ErrShortFrame},
+		{"truncated header", []byte{2, 127, 1, 8}, ErrShortFrame},
+		{"size below header", []byte{2, 127, 1, 3, 0, 0, 0}, ErrFrameSize},
+		{"size beyond packet", []byte{2, 127, 1, 20, 0, 0, 0, 1}, ErrFrameSize},
+		{"huge size", []byte{2, 127, 1, 0xff, 0xff, 0xff, 0xff, 1}, ErrFrameSize},
+	}
+
+	for _, tt := range tests {
+		p := networkUDPPacket{size: len(tt.data), data: tt.data}
+		_, err := p.decode()
+		if !errors.Is(err, tt.want) {
+			t.Fatalf("%v: got error %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestPacketDecodeSeveralFrames(t *testing.T) {
+	data := append(attitudeFrame(12), attitudeFrame(12)...)
+	p := networkUDPPacket{size: len(data), data: data}
+
+	if _, err := p.decode(); err != nil {
+		t.Fatalf("first frame: %v", err)
+	}
+	if _, err := p.decode(); err != io.EOF {
+		t.Fatalf("second frame: got %v, want io.EOF", err)
+	}
+}
+
+func TestFrameDecodeMalformed(t *testing.T) {
+	// Too few bytes for the project, class and command.
+	f := protocolARNetworkAL{size: 9, dataARNetwork: []byte{1, 4}}
+	if _, _, err := f.decode(); !errors.Is(err, ErrShortCommand) {
+		t.Fatalf("got error %v, want %v", err, ErrShortCommand)
+	}
+
+	// The attitude needs 12 bytes of arguments, give it 5.
+	p := networkUDPPacket{data: attitudeFrame(5)}
+	p.size = len(p.data)
+	frame, err := p.decode()
+	if err != io.EOF {
+		t.Fatalf("packet decode: %v", err)
+	}
+	if _, _, err := frame.decode(); !errors.Is(err, ErrMalformedArguments) {
+		t.Fatalf("got error %v, want %v", err, ErrMalformedArguments)
+	}
+
+	// And with all the arguments it should decode.
+	p = networkUDPPacket{data: attitudeFrame(12)}
+	p.size = len(p.data)
+	frame, _ = p.decode()
+	_, args, err := frame.decode()
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if _, ok := args.(Ardrone3PilotingStateAttitudeChangedArguments); !ok {
+		t.Fatalf("wrong type of arguments: %T", args)
+	}
+}
+
+func TestPacketCreatorConcurrent(t *testing.T) {
+	u := newUdpPacketCreator()
+
+	const workers, perWorker = 8, 30
+	seqs := make(chan byte, workers*perWorker)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				p := u.encodeCmd(Command(PilotingTakeOff), &Ardrone3PilotingTakeOffArguments{})
+				seqs <- p.data[2]
+			}
+		}()
+	}
+	wg.Wait()
+	close(seqs)
+
+	// 240 frames fits within the 256 sequence numbers, so all should
+	// be unique.
+	seen := make(map[byte]bool)
+	for s := range seqs {
+		if seen[s] {
+			t.Fatalf("sequence number %v used twice", s)
+		}
+		seen[s] = true
+	}
+}
+
+func TestEncodeAckSequence(t *testing.T) {
+	u := newUdpPacketCreator()
+
+	a1 := u.encodeAck(bufferD2CEvents, 42)
+	a2 := u.encodeAck(bufferD2CEvents, 42)
+
+	// The acked sequence number is the payload, and the ack frames have
+	// their own sequence numbers on the ack buffer.
+	if a1.data[7] != 42 || a2.data[7] != 42 {
+		t.Fatalf("wrong acked sequence numbers: %v, %v", a1.data[7], a2.data[7])
+	}
+	if a1.data[2] == a2.data[2] {
+		t.Fatalf("ack frames have the same sequence number %v", a1.data[2])
+	}
+
+	u.reset()
+	if a3 := u.encodeAck(bufferD2CEvents, 42); a3.data[2] != a1.data[2] {
+		t.Fatalf("sequence number after reset %v, want %v", a3.data[2], a1.data[2])
+	}
+}
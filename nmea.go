@@ -0,0 +1,161 @@
+package parrotbebop
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultNMEAInterval is the interval between the NMEA sentences if
+	// not given, which is the usual rate of a gps receiver.
+	defaultNMEAInterval = time.Second
+	// metersPerSecondToKnots converts a speed in m/s to knots.
+	metersPerSecondToKnots = 1.943844
+)
+
+// nmeaChecksum will return the sentence with the $ prefix and the
+// checksum added, where the checksum is the XOR of all the characters
+// between the $ and the *.
+func nmeaChecksum(body string) string {
+	var cs byte
+	for i := 0; i < len(body); i++ {
+		cs ^= body[i]
+	}
+
+	return fmt.Sprintf("$%v*%02X", body, cs)
+}
+
+// nmeaCoordinate will return the coordinate in the NMEA degrees and
+// minutes format with the number of degree digits given, and the
+// hemisphere letter.
+func nmeaCoordinate(v float64, degDigits int, pos string, neg string) (string, string) {
+	hemisphere := pos
+	if v < 0 {
+		hemisphere = neg
+		v = -v
+	}
+
+	// Round to whole 1/100000 minutes first, so the minutes never round
+	// up to 60.
+	m := int64(math.Round(v * 60 * 1e5))
+	deg := m / (60 * 1e5)
+	rem := m - deg*60*1e5
+
+	return fmt.Sprintf("%0*d%02d.%05d", degDigits, deg, rem/1e5, rem%1e5), hemisphere
+}
+
+// nmeaSentences will return the GGA and RMC sentences for the position
+// in the telemetry at the time given. Without a gps fix the position
+// fields are left empty, and the fix is reported as not valid.
+func nmeaSentences(t Telemetry, now time.Time) []string {
+	now = now.UTC()
+	hms := now.Format("150405.00")
+
+	fixed := t.GPSFixed && validGPSPosition(t.Position)
+
+	var lat, ns, lon, ew, alt, speed, course string
+	quality, status, mode := "0", "V", "N"
+	if fixed {
+		lat, ns = nmeaCoordinate(t.Position.Latitude, 2, "N", "S")
+		lon, ew = nmeaCoordinate(t.Position.Longitude, 3, "E", "W")
+		alt = fmt.Sprintf("%.1f", t.Position.Altitude)
+		speed = fmt.Sprintf("%.1f", t.GroundSpeed*metersPerSecondToKnots)
+		course = fmt.Sprintf("%.1f", t.Velocity.Course())
+		quality, status, mode = "1", "A", "A"
+	}
+
+	gga := fmt.Sprintf("GPGGA,%v,%v,%v,%v,%v,%v,%02d,,%v,M,,M,,", hms, lat, ns, lon, ew, quality, t.NumberOfSatellites, alt)
+	rmc := fmt.Sprintf("GPRMC,%v,%v,%v,%v,%v,%v,%v,%v,%v,,,%v", hms, status, lat, ns, lon, ew, speed, course, now.Format("020106"), mode)
+
+	return []string{nmeaChecksum(gga), nmeaChecksum(rmc)}
+}
+
+// WriteNMEA will write the position of the drone as NMEA 0183 GGA and
+// RMC sentences to w on each interval, like a gps receiver, where w can
+// be an opened serial port. An interval of 0 uses 1 second. WriteNMEA
+// will block until the context is done, or writing to w fails.
+func (d *Drone) WriteNMEA(ctx context.Context, w io.Writer, interval time.Duration) error {
+	if interval < 0 {
+		return fmt.Errorf("WriteNMEA: interval can't be negative, got %v", interval)
+	}
+	if interval == 0 {
+		interval = defaultNMEAInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			s := strings.Join(nmeaSentences(d.Telemetry(), now), "\r\n") + "\r\n"
+			if _, err := io.WriteString(w, s); err != nil {
+				return fmt.Errorf("WriteNMEA: %v", err)
+			}
+		}
+	}
+}
+
+// StartNMEAServer will start a TCP server on the address given, sending
+// the position of the drone as NMEA 0183 sentences to every client
+// connected, as with WriteNMEA. It can be used as a gps source by gpsd
+// with tcp://host:port, and by moving map software taking NMEA over
+// the network. The server is stopped when the context is done.
+func (d *Drone) StartNMEAServer(ctx context.Context, addr string, interval time.Duration) error {
+	if interval < 0 {
+		return fmt.Errorf("StartNMEAServer: interval can't be negative, got %v", interval)
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("StartNMEAServer: %v", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		l.Close()
+		log.Printf("info: exiting NMEA server\n")
+	}()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("error: NMEA server failed: %v\n", err)
+				}
+				return
+			}
+
+			log.Printf("info: NMEA client connected from %v\n", conn.RemoteAddr())
+			go func() {
+				defer conn.Close()
+
+				// Close the connection when the context is done, since
+				// WriteNMEA is not interrupted while writing.
+				clientCtx, cancel := context.WithCancel(ctx)
+				defer cancel()
+				go func() {
+					<-clientCtx.Done()
+					conn.Close()
+				}()
+
+				if err := d.WriteNMEA(clientCtx, conn, interval); err != nil {
+					log.Printf("info: NMEA client %v disconnected: %v\n", conn.RemoteAddr(), err)
+				}
+			}()
+		}
+	}()
+
+	log.Printf("info: NMEA server started on %v\n", l.Addr())
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package parrotbebop
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNMEASentences(t *testing.T) {
+	now := time.Date(2016, 6, 8, 13, 10, 36, 0, time.UTC)
+	tel := Telemetry{
+		GPSFixed:           true,
+		NumberOfSatellites: 9,
+		Position:           Position{Latitude: 59.9127, Longitude: -10.75, Altitude: 123.4},
+		Velocity:           Velocity{North: 0, East: 5},
+		GroundSpeed:        5,
+	}
+
+	s := nmeaSentences(tel, now)
+	want := []string{
+		"$GPGGA,131036.00,5954.76200,N,01045.00000,W,1,09,,123.4,M,,M,,*4B",
+		"$GPRMC,131036.00,A,5954.76200,N,01045.00000,W,9.7,90.0,080616,,,A*7A",
+	}
+	for i := range want {
+		if s[i] != want[i] {
+			t.Fatalf("got %v, want %v", s[i], want[i])
+		}
+	}
+
+	// The checksum of a known sentence.
+	if got := nmeaChecksum("GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,"); got != "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47" {
+		t.Fatalf("wrong checksum: %v", got)
+	}
+
+	// Minutes very close to a whole degree should not be 60.
+	if c, _ := nmeaCoordinate(9.9999999999, 3, "E", "W"); c != "01000.00000" {
+		t.Fatalf("wrong rounding: %v", c)
+	}
+
+	tel.GPSFixed = false
+	s = nmeaSentences(tel, now)
+	if !strings.HasPrefix(s[0], "$GPGGA,131036.00,,,,,0,09,") || !strings.HasPrefix(s[1], "$GPRMC,131036.00,V,,,,,,,080616,") {
+		t.Fatalf("wrong sentences without fix: %v", s)
+	}
+}
+
+func TestNMEAServer(t *testing.T) {
+	d := NewDrone()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	if err := d.StartNMEAServer(ctx, addr, time.Millisecond*10); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Second * 2))
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(line, "$GPGGA,") || !strings.HasSuffix(line, "\r\n") {
+		t.Fatalf("wrong sentence: %q", line)
+	}
+}
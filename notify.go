@@ -0,0 +1,187 @@
+package parrotbebop
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// notifyRepeatInterval is how long a notification with the same title
+// is held back after being given, so repeated alerts like the RTH
+// fallback hovering don't flood the operator.
+const notifyRepeatInterval = time.Second * 10
+
+// Notification is a critical event made into a message for the
+// operator.
+type Notification struct {
+	Time    time.Time
+	Title   string
+	Message string
+}
+
+// Notifier is a way of getting the attention of the operator, like
+// with a sound or a desktop notification, so the critical events are
+// not missed while looking at the video.
+type Notifier interface {
+	Notify(n Notification) error
+}
+
+// BellNotifier is a Notifier ringing the terminal bell, and writing the
+// notification as a line to W, or os.Stderr if W is nil.
+type BellNotifier struct {
+	W io.Writer
+}
+
+// Notify will ring the bell and write the notification.
+func (b BellNotifier) Notify(n Notification) error {
+	w := b.W
+	if w == nil {
+		w = os.Stderr
+	}
+
+	_, err := fmt.Fprintf(w, "\a%v: %v: %v\n", n.Time.Format("15:04:05"), n.Title, n.Message)
+	return err
+}
+
+// DesktopNotifier is a Notifier showing a desktop notification, using
+// osascript on macOS, and notify-send on the other systems.
+type DesktopNotifier struct {
+	// Path is the notify-send executable, and defaults to notify-send
+	// found in the PATH.
+	Path string
+}
+
+// Notify will show the notification on the desktop.
+func (dn DesktopNotifier) Notify(n Notification) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		script := fmt.Sprintf("display notification %q with title %q sound name \"Basso\"", n.Message, n.Title)
+		cmd = exec.Command("osascript", "-e", script)
+	} else {
+		path := dn.Path
+		if path == "" {
+			path = "notify-send"
+		}
+		cmd = exec.Command(path, "--urgency=critical", n.Title, n.Message)
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("desktop notification failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// notificationFor will return the notification for the event if it is
+// critical, like low battery, the link being lost, or a motor error.
+// All the other high priority events are notified with the type and
+// value of the event.
+func notificationFor(ev Event) (Notification, bool) {
+	n := Notification{Time: ev.Time}
+
+	switch v := ev.Value.(type) {
+	case AlertState:
+		switch v {
+		case AlertNone:
+			return n, false
+		case AlertLowBattery, AlertCriticalBattery:
+			n.Title = "Battery"
+		default:
+			n.Title = "Alert"
+		}
+		n.Message = fmt.Sprintf("drone reports %v", v)
+	case ConnectionEvent:
+		if v.State != ConnectionLost && v.State != ConnectionGaveUp {
+			return n, false
+		}
+		n.Title = "Link"
+		n.Message = fmt.Sprintf("connection with drone %v", v.State)
+		if v.Err != nil {
+			n.Message += fmt.Sprintf(": %v", v.Err)
+		}
+	case MotorError:
+		if v == MotorNoError {
+			return n, false
+		}
+		n.Title = "Motor"
+		n.Message = fmt.Sprintf("motor error: %v", v)
+	case SensorState:
+		if v.OK {
+			return n, false
+		}
+		n.Title = "Sensor"
+		n.Message = fmt.Sprintf("sensor %v is not ok", v.Sensor)
+	case WarningLevel:
+		if v != LevelCritical {
+			return n, false
+		}
+		n.Title = ev.Type.String()
+		n.Message = fmt.Sprintf("level is %v", v)
+	case RTHFallbackEvent:
+		if v.Stage != RTHFallbackStarted && v.Stage != RTHFallbackHovering {
+			return n, false
+		}
+		n.Title = "Return home"
+		n.Message = fmt.Sprintf("fallback %v: %v", v.Stage, v.Reason)
+	default:
+		if ev.Priority != PriorityHigh {
+			return n, false
+		}
+		n.Title = ev.Type.String()
+		n.Message = fmt.Sprintf("%v", ev.Value)
+	}
+
+	return n, true
+}
+
+// notifyThrottle holds when each title was last notified.
+type notifyThrottle struct {
+	last map[string]time.Time
+}
+
+// allow will return true if the title have not been notified within
+// the repeat interval.
+func (t *notifyThrottle) allow(title string, now time.Time) bool {
+	if t.last == nil {
+		t.last = make(map[string]time.Time)
+	}
+	if last, ok := t.last[title]; ok && now.Sub(last) < notifyRepeatInterval {
+		return false
+	}
+	t.last[title] = now
+
+	return true
+}
+
+// RunNotifier will give the critical events, like low battery, the
+// link being lost, motor errors and the other high priority events, to
+// the notifier until the context is done. The same kind of
+// notification is only given once every 10 seconds. A failing notifier
+// is logged, and not stopped.
+func (d *Drone) RunNotifier(ctx context.Context, n Notifier) error {
+	events, unsubscribe := d.events.subscribe()
+	defer unsubscribe()
+
+	var throttle notifyThrottle
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev := <-events:
+			notification, ok := notificationFor(ev)
+			if !ok || !throttle.allow(notification.Title, ev.Time) {
+				continue
+			}
+			if err := n.Notify(notification); err != nil {
+				log.Printf("error: RunNotifier: %v\n", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package parrotbebop
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chanNotifier is a Notifier delivering the notifications on a channel.
+type chanNotifier chan Notification
+
+func (c chanNotifier) Notify(n Notification) error {
+	c <- n
+	return nil
+}
+
+func TestNotificationFor(t *testing.T) {
+	tests := []struct {
+		ev    Event
+		title string
+	}{
+		{Event{Type: EventAlertStateChanged, Value: AlertNone}, ""},
+		{Event{Type: EventAlertStateChanged, Value: AlertLowBattery}, "Battery"},
+		{Event{Type: EventAlertStateChanged, Value: AlertCutOut}, "Alert"},
+		{Event{Type: EventConnection, Value: ConnectionEvent{State: ConnectionAttempt}}, ""},
+		{Event{Type: EventConnection, Value: ConnectionEvent{State: ConnectionLost, Err: errors.New("no ping")}}, "Link"},
+		{Event{Type: EventMotorError, Value: MotorNoError}, ""},
+		{Event{Type: EventMotorError, Value: MotorErrorMotorStalled}, "Motor"},
+		{Event{Type: EventWindStateChanged, Value: LevelWarning}, ""},
+		{Event{Type: EventWindStateChanged, Value: LevelCritical}, "WindStateChanged"},
+		{Event{Type: EventControlAuthority, Priority: PriorityHigh, Value: AuthorityManual}, "ControlAuthority"},
+		{Event{Type: EventControlAuthority, Value: AuthorityMission}, ""},
+	}
+	for _, tt := range tests {
+		n, ok := notificationFor(tt.ev)
+		if ok != (tt.title != "") || n.Title != tt.title {
+			t.Fatalf("%v %v: got %q %v, want %q", tt.ev.Type, tt.ev.Value, n.Title, ok, tt.title)
+		}
+	}
+}
+
+func TestRunNotifier(t *testing.T) {
+	d := NewDrone()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := make(chanNotifier, 10)
+	go d.RunNotifier(ctx, ch)
+	time.Sleep(time.Millisecond * 50)
+
+	// The second low battery alert is within the repeat interval.
+	d.handleAlertState(AlertLowBattery)
+	d.handleAlertState(AlertCriticalBattery)
+	d.publishConnection(ConnectionEvent{State: ConnectionLost})
+
+	for _, want := range []string{"Battery", "Link"} {
+		select {
+		case n := <-ch:
+			if n.Title != want {
+				t.Fatalf("got %q, want %q", n.Title, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no %v notification", want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := (BellNotifier{W: &buf}).Notify(Notification{Title: "Motor", Message: "stalled"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); !strings.HasPrefix(got, "\a") || !strings.HasSuffix(got, "Motor: stalled\n") {
+		t.Fatalf("got %q", got)
+	}
+}
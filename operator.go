@@ -0,0 +1,235 @@
+package parrotbebop
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrOutOfScope is returned by the methods of an Operator when the
+// operator is not allowed to control what the method does.
+var ErrOutOfScope = errors.New("not within the scope of the operator")
+
+// ErrOperatorClosed is returned by the methods of an Operator after
+// Close.
+var ErrOperatorClosed = errors.New("operator closed")
+
+// OperatorScope is what an operator is allowed to control, where the
+// scopes can be combined.
+type OperatorScope int
+
+const (
+	// ScopePiloting is the flying of the drone, with the actions,
+	// sticks and PCMD.
+	ScopePiloting OperatorScope = 1 << iota
+	// ScopeCamera is the orientation of the camera, and taking pictures
+	// and recording video.
+	ScopeCamera
+	// ScopeAll is both piloting and the camera.
+	ScopeAll = ScopePiloting | ScopeCamera
+)
+
+// operatorScopes are the single scopes, used for finding the owner of
+// each.
+var operatorScopes = []OperatorScope{ScopePiloting, ScopeCamera}
+
+// String will return the names of the scopes.
+func (s OperatorScope) String() string {
+	var names []string
+	if s&ScopePiloting != 0 {
+		names = append(names, "piloting")
+	}
+	if s&ScopeCamera != 0 {
+		names = append(names, "camera")
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+
+	return strings.Join(names, "+")
+}
+
+// operatorRegistry holds the name of the operator owning each scope.
+type operatorRegistry struct {
+	mu     sync.Mutex
+	owners map[OperatorScope]string
+}
+
+// Operator is an input source, like a gamepad or a remote user, that is
+// only allowed to control what is within it's scope. Each scope can
+// only be held by one operator at a time, so one operator can pilot
+// the drone while another controls the camera without them
+// interfering. The methods of Drone are not limited by the operators.
+type Operator struct {
+	name  string
+	scope OperatorScope
+	drone *Drone
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewOperator will return an operator with the name and scope given,
+// which holds the scope until closed. An error is returned if any of
+// the scopes are already held by another operator.
+func (d *Drone) NewOperator(name string, scope OperatorScope) (*Operator, error) {
+	if scope&ScopeAll == 0 || scope&^ScopeAll != 0 {
+		return nil, fmt.Errorf("NewOperator: unknown scope %d", int(scope))
+	}
+
+	d.operators.mu.Lock()
+	defer d.operators.mu.Unlock()
+
+	if d.operators.owners == nil {
+		d.operators.owners = make(map[OperatorScope]string)
+	}
+	for _, s := range operatorScopes {
+		if owner, ok := d.operators.owners[s]; ok && scope&s != 0 {
+			return nil, fmt.Errorf("NewOperator: %v scope is held by operator %v", s, owner)
+		}
+	}
+	for _, s := range operatorScopes {
+		if scope&s != 0 {
+			d.operators.owners[s] = name
+		}
+	}
+
+	return &Operator{name: name, scope: scope, drone: d}, nil
+}
+
+// Operators will return the name of the operator holding each scope.
+func (d *Drone) Operators() map[OperatorScope]string {
+	d.operators.mu.Lock()
+	defer d.operators.mu.Unlock()
+
+	m := make(map[OperatorScope]string, len(d.operators.owners))
+	for s, name := range d.operators.owners {
+		m[s] = name
+	}
+
+	return m
+}
+
+// Name will return the name of the operator.
+func (o *Operator) Name() string { return o.name }
+
+// Scope will return the scope of the operator.
+func (o *Operator) Scope() OperatorScope { return o.scope }
+
+// Close will release the scope of the operator, so it can be given to
+// another operator. The operator can not be used after Close.
+func (o *Operator) Close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return
+	}
+	o.closed = true
+
+	o.drone.operators.mu.Lock()
+	defer o.drone.operators.mu.Unlock()
+
+	for _, s := range operatorScopes {
+		if o.scope&s != 0 {
+			delete(o.drone.operators.owners, s)
+		}
+	}
+}
+
+// allowed will return an error if the operator is closed, or the scope
+// is not held by the operator.
+func (o *Operator) allowed(scope OperatorScope) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return ErrOperatorClosed
+	}
+	if o.scope&scope != scope {
+		return fmt.Errorf("%w: operator %v have the %v scope, needs %v", ErrOutOfScope, o.name, o.scope, scope)
+	}
+
+	return nil
+}
+
+// SendAction will give the action to the drone as with Drone.SendAction,
+// which needs the piloting scope.
+func (o *Operator) SendAction(action InputAction) error {
+	if err := o.allowed(ScopePiloting); err != nil {
+		return fmt.Errorf("SendAction: %w", err)
+	}
+
+	return o.drone.SendAction(action)
+}
+
+// SendSticks will give the stick inputs to the drone as with
+// Drone.SendSticks, which needs the piloting scope.
+func (o *Operator) SendSticks(roll float64, pitch float64, yaw float64, gaz float64) error {
+	if err := o.allowed(ScopePiloting); err != nil {
+		return fmt.Errorf("SendSticks: %w", err)
+	}
+
+	return o.drone.SendSticks(roll, pitch, yaw, gaz)
+}
+
+// SendPcmd will give the PCMD to the drone as with Drone.SendPcmd,
+// which needs the piloting scope.
+func (o *Operator) SendPcmd(roll int8, pitch int8, yaw int8, gaz int8) error {
+	if err := o.allowed(ScopePiloting); err != nil {
+		return fmt.Errorf("SendPcmd: %w", err)
+	}
+
+	return o.drone.SendPcmd(roll, pitch, yaw, gaz)
+}
+
+// SetCameraOrientation will move the camera as with
+// Drone.SetCameraOrientation, which needs the camera scope.
+func (o *Operator) SetCameraOrientation(tilt float32, pan float32) error {
+	if err := o.allowed(ScopeCamera); err != nil {
+		return fmt.Errorf("SetCameraOrientation: %w", err)
+	}
+
+	return o.drone.SetCameraOrientation(tilt, pan)
+}
+
+// SetCameraVelocity will move the camera as with
+// Drone.SetCameraVelocity, which needs the camera scope.
+func (o *Operator) SetCameraVelocity(tilt float32, pan float32) error {
+	if err := o.allowed(ScopeCamera); err != nil {
+		return fmt.Errorf("SetCameraVelocity: %w", err)
+	}
+
+	return o.drone.SetCameraVelocity(tilt, pan)
+}
+
+// SetCameraStick will move the camera as with Drone.SetCameraStick,
+// which needs the camera scope.
+func (o *Operator) SetCameraStick(x float64, y float64) error {
+	if err := o.allowed(ScopeCamera); err != nil {
+		return fmt.Errorf("SetCameraStick: %w", err)
+	}
+
+	return o.drone.SetCameraStick(x, y)
+}
+
+// TakePicture will take a picture as with Drone.TakePicture, which
+// needs the camera scope.
+func (o *Operator) TakePicture() error {
+	if err := o.allowed(ScopeCamera); err != nil {
+		return fmt.Errorf("TakePicture: %w", err)
+	}
+
+	return o.drone.TakePicture()
+}
+
+// SetVideoRecording will start or stop the recording as with
+// Drone.SetVideoRecording, which needs the camera scope.
+func (o *Operator) SetVideoRecording(record bool) error {
+	if err := o.allowed(ScopeCamera); err != nil {
+		return fmt.Errorf("SetVideoRecording: %w", err)
+	}
+
+	return o.drone.SetVideoRecording(record)
+}
@@ -0,0 +1,58 @@
+package parrotbebop
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOperatorScopes(t *testing.T) {
+	d := NewDrone()
+	go func() {
+		for range d.chSendingUDPPacket {
+		}
+	}()
+
+	pilot, err := d.NewOperator("gamepad", ScopePiloting)
+	if err != nil {
+		t.Fatal(err)
+	}
+	camera, err := d.NewOperator("web", ScopeCamera)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both scopes are held, so no other operator can be made.
+	if _, err := d.NewOperator("other", ScopeAll); err == nil {
+		t.Fatalf("expected error for scope already held")
+	}
+	if _, err := d.NewOperator("other", OperatorScope(4)); err == nil {
+		t.Fatalf("expected error for unknown scope")
+	}
+
+	if err := pilot.SendSticks(0, 0.5, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := pilot.TakePicture(); !errors.Is(err, ErrOutOfScope) {
+		t.Fatalf("expected out of scope for the pilot taking a picture, got %v", err)
+	}
+	if err := camera.SendPcmd(0, 10, 0, 0); !errors.Is(err, ErrOutOfScope) {
+		t.Fatalf("expected out of scope for the camera operator piloting, got %v", err)
+	}
+	if err := camera.SetCameraOrientation(-10, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	owners := d.Operators()
+	if owners[ScopePiloting] != "gamepad" || owners[ScopeCamera] != "web" {
+		t.Fatalf("wrong owners: %v", owners)
+	}
+
+	// After closing, the scope can be taken by another operator.
+	pilot.Close()
+	if err := pilot.SendSticks(0, 0, 0, 0); !errors.Is(err, ErrOperatorClosed) {
+		t.Fatalf("expected closed error, got %v", err)
+	}
+	if _, err := d.NewOperator("keyboard", ScopePiloting); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,124 @@
+package parrotbebop
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+// OrbitConfig holds the values describing the circle to fly when
+// orbiting around a point.
+type OrbitConfig struct {
+	// Center is the position to circle around. The altitude of the
+	// center is used as the altitude to fly the orbit at.
+	Center Position
+	// Radius of the circle in meters.
+	Radius float64
+	// AngularSpeed is the speed in degrees per second to move around
+	// the circle.
+	AngularSpeed float64
+	// CounterClockwise will make the drone circle counter clockwise
+	// seen from above. The default is clockwise.
+	CounterClockwise bool
+	// Interval is how often a new moveTo are sent to the drone.
+	Interval time.Duration
+}
+
+// Orbit will make the drone circle around the center position given
+// in the config, with the front of the drone, and by that the camera,
+// pointed towards the center. For each interval the next position on
+// the circle is calculated and given to the drone with a moveTo.
+// If the current position of the drone is known the orbit will start
+// at the point of the circle closest to the drone.
+// Orbit will block until the context is cancelled, and a CancelMoveTo
+// will then be sent to the drone. An error is returned if a moveTo
+// mission or FollowMe is already flying the drone.
+func (d *Drone) Orbit(ctx context.Context, conf OrbitConfig) error {
+	if conf.Radius <= 0 {
+		return fmt.Errorf("Orbit: radius must be above 0, got %v", conf.Radius)
+	}
+	if conf.AngularSpeed <= 0 {
+		return fmt.Errorf("Orbit: angular speed must be above 0, got %v", conf.AngularSpeed)
+	}
+	if conf.Interval <= 0 {
+		conf.Interval = time.Second
+	}
+
+	if err := d.checkGPSGuard(); err != nil {
+		return fmt.Errorf("Orbit: %v", err)
+	}
+
+	release, err := d.claimDirectMoveTo("Orbit")
+	if err != nil {
+		return fmt.Errorf("Orbit: %v", err)
+	}
+	defer release()
+
+	// Find the angle on the circle to start at. The angle is the
+	// bearing from the center to the position on the circle.
+	var angle float64
+	pos := d.telemetry.snapshot().Position
+	if validPosition(pos) {
+		angle = bearingTo(conf.Center.Latitude, conf.Center.Longitude, pos.Latitude, pos.Longitude)
+	}
+
+	step := conf.AngularSpeed * conf.Interval.Seconds()
+	if conf.CounterClockwise {
+		step = -step
+	}
+
+	defer func() {
+		err := d.sendCmd(Command(PilotingCancelMoveTo), &Ardrone3PilotingCancelMoveToArguments{})
+		if err != nil {
+			log.Printf("error: Orbit: failed to cancel moveTo: %v\n", err)
+		}
+	}()
+
+	ticker := time.NewTicker(conf.Interval)
+	defer ticker.Stop()
+
+	for {
+		lat, lon := offsetPosition(conf.Center.Latitude, conf.Center.Longitude, conf.Radius, angle)
+		wp := Position{Latitude: lat, Longitude: lon, Altitude: conf.Center.Altitude}
+		// Point the drone towards the center.
+		heading := math.Mod(angle+180, 360)
+		if err := d.moveToHeading(wp, heading); err != nil {
+			log.Printf("error: Orbit: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("info: exiting Orbit\n")
+			return nil
+		case <-ticker.C:
+			angle = nextOrbitAngle(angle, step)
+		}
+	}
+}
+
+// nextOrbitAngle will return the angle after moving the step given
+// around the circle, in the range 0 to 360, where the step is negative
+// when counter clockwise and can be larger than a full circle.
+func nextOrbitAngle(angle, step float64) float64 {
+	return math.Mod(math.Mod(angle+step, 360)+360, 360)
+}
+
+// validPosition will check if the position is a valid gps position.
+// The drone will report 500 for all the values when it have no gps
+// connection.
+func validPosition(p Position) bool {
+	switch {
+	case p.Latitude == 500 || p.Longitude == 500:
+		return false
+	case p.Latitude == 0 && p.Longitude == 0:
+		return false
+	case p.Latitude > 90 || p.Latitude < -90:
+		return false
+	case p.Longitude > 180 || p.Longitude < -180:
+		return false
+	}
+
+	return true
+}
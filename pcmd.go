@@ -0,0 +1,121 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultPcmdInterval is the interval used by the PCMD scheduler
+	// if not set with SetPcmdInterval.
+	defaultPcmdInterval = time.Millisecond * 50
+	// minPcmdInterval and maxPcmdInterval are the limits allowed for
+	// the PCMD interval.
+	minPcmdInterval = time.Millisecond * 10
+	maxPcmdInterval = time.Millisecond * 500
+)
+
+// pcmdConfig holds the settings for the PCMD scheduler, and the
+// latest pcmd state to send.
+type pcmdConfig struct {
+	mu       sync.Mutex
+	interval time.Duration
+	current  Ardrone3PilotingPCMDArguments
+}
+
+// SetPcmdInterval will set the interval between each PCMD sent to the
+// drone by the PCMD scheduler. The official SDK uses 50ms.
+func (d *Drone) SetPcmdInterval(interval time.Duration) error {
+	if interval < minPcmdInterval || interval > maxPcmdInterval {
+		return fmt.Errorf("SetPcmdInterval: interval must be within [%v, %v], got %v", minPcmdInterval, maxPcmdInterval, interval)
+	}
+
+	d.pcmdConfig.mu.Lock()
+	defer d.pcmdConfig.mu.Unlock()
+
+	d.pcmdConfig.interval = interval
+
+	return nil
+}
+
+// pcmdInterval will return the interval to use for the PCMD scheduler.
+func (d *Drone) pcmdInterval() time.Duration {
+	d.pcmdConfig.mu.Lock()
+	defer d.pcmdConfig.mu.Unlock()
+
+	if d.pcmdConfig.interval == 0 {
+		return defaultPcmdInterval
+	}
+
+	return d.pcmdConfig.interval
+}
+
+// pcmdTimestampAndSeqNum will create the value of the TimestampAndSeqNum
+// field of a PCMD, where the low 24 bits are the timestamp in ms, and
+// the high 8 bits are the sequence number. The drone uses it to know
+// how fresh a PCMD is.
+func pcmdTimestampAndSeqNum(t time.Time, seq uint8) uint32 {
+	ms := uint32(t.UnixNano()/int64(time.Millisecond)) & 0xffffff
+
+	return uint32(seq)<<24 | ms
+}
+
+// setPcmd will replace the pcmd state sent by the PCMD scheduler on
+// each tick. It never blocks, so it is safe to call as often as wanted.
+func (d *Drone) setPcmd(arg Ardrone3PilotingPCMDArguments) {
+	d.pcmdConfig.mu.Lock()
+	defer d.pcmdConfig.mu.Unlock()
+
+	d.pcmdConfig.current = arg
+}
+
+// pcmdAxes is a set of the axes of a PCMD.
+type pcmdAxes uint8
+
+const (
+	pcmdRoll pcmdAxes = 1 << iota
+	pcmdPitch
+	pcmdYaw
+	pcmdGaz
+	pcmdAllAxes = pcmdRoll | pcmdPitch | pcmdYaw | pcmdGaz
+)
+
+// updatePcmd will set only the axes given of the pcmd state to the
+// values of arg, and keep the current values of the other axes, so the
+// pilots and controllers of different axes don't clobber each other.
+// The flag is set when roll or pitch are used, and the updated state
+// is returned.
+func (d *Drone) updatePcmd(arg Ardrone3PilotingPCMDArguments, axes pcmdAxes) Ardrone3PilotingPCMDArguments {
+	d.pcmdConfig.mu.Lock()
+	defer d.pcmdConfig.mu.Unlock()
+
+	c := d.pcmdConfig.current
+	if axes&pcmdRoll != 0 {
+		c.Roll = arg.Roll
+	}
+	if axes&pcmdPitch != 0 {
+		c.Pitch = arg.Pitch
+	}
+	if axes&pcmdYaw != 0 {
+		c.Yaw = arg.Yaw
+	}
+	if axes&pcmdGaz != 0 {
+		c.Gaz = arg.Gaz
+	}
+	c.Flag = 0
+	if c.Roll != 0 || c.Pitch != 0 {
+		c.Flag = 1
+	}
+	d.pcmdConfig.current = c
+
+	return c
+}
+
+// currentPcmd will return the pcmd state to send.
+func (d *Drone) currentPcmd() Ardrone3PilotingPCMDArguments {
+	d.pcmdConfig.mu.Lock()
+	defer d.pcmdConfig.mu.Unlock()
+
+	return d.pcmdConfig.current
+}
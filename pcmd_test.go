@@ -0,0 +1,37 @@
+package parrotbebop
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPcmdTimestampAndSeqNum(t *testing.T) {
+	// 0x1234567 ms, where only the low 24 bits should be kept.
+	now := time.Unix(0, 0x1234567*int64(time.Millisecond))
+
+	got := pcmdTimestampAndSeqNum(now, 0xab)
+	if want := uint32(0xab234567); got != want {
+		t.Fatalf("wrong value: %#x, want %#x", got, want)
+	}
+}
+
+func TestUpdatePcmd(t *testing.T) {
+	d := NewDrone()
+
+	d.updatePcmd(Ardrone3PilotingPCMDArguments{Gaz: 30}, pcmdGaz)
+	got := d.updatePcmd(Ardrone3PilotingPCMDArguments{Roll: 10, Gaz: 99}, pcmdRoll)
+	if want := (Ardrone3PilotingPCMDArguments{Flag: 1, Roll: 10, Gaz: 30}); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	// SendPcmd leaves the Gaz to the altitude hold.
+	if err := d.SetTargetAltitude(5); err != nil {
+		t.Fatalf("SetTargetAltitude: %v", err)
+	}
+	if err := d.SendPcmd(0, 0, 20, 0); err != nil {
+		t.Fatalf("SendPcmd: %v", err)
+	}
+	if got, want := d.currentPcmd(), (Ardrone3PilotingPCMDArguments{Yaw: 20, Gaz: 30}); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,142 @@
+package parrotbebop
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// photoPollInterval is how often the photo schedule checks if a picture
+// is due.
+const photoPollInterval = time.Millisecond * 200
+
+// PhotoSchedule is when to take pictures with PhotoSchedule, where the
+// pictures can be taken on an interval, like for a burst or a
+// timelapse, or for each distance travelled, like for mapping. If both
+// Interval and Distance are set, a picture is taken when either of
+// them is reached.
+type PhotoSchedule struct {
+	// Interval is the time between the pictures, where 0 is not using
+	// time.
+	Interval time.Duration
+	// Distance is the distance in meters travelled between the
+	// pictures, found from the gps position, where 0 is not using
+	// distance.
+	Distance float64
+	// MaxPhotos is the number of pictures to take before stopping,
+	// where 0 is no limit.
+	MaxPhotos int
+	// MissionOnly will only take pictures while the moveTo executor is
+	// flying a mission, and stop when the mission is done.
+	MissionOnly bool
+}
+
+// photoTrigger keeps track of when and where the last picture was
+// taken, and the distance travelled since.
+type photoTrigger struct {
+	schedule  PhotoSchedule
+	lastPhoto time.Time
+	lastPos   Position
+	havePos   bool
+	travelled float64
+}
+
+// move will add the distance from the previous position to the
+// distance travelled. Invalid positions are ignored, so the distance
+// continues from the last valid position when the gps is back.
+func (p *photoTrigger) move(pos Position) {
+	if !validGPSPosition(pos) {
+		return
+	}
+	if p.havePos {
+		p.travelled += p.lastPos.DistanceTo(pos)
+	}
+	p.lastPos = pos
+	p.havePos = true
+}
+
+// due will return true if a picture should be taken at the time given.
+// The first picture is due right away.
+func (p *photoTrigger) due(now time.Time) bool {
+	if p.lastPhoto.IsZero() {
+		return true
+	}
+	if p.schedule.Interval > 0 && now.Sub(p.lastPhoto) >= p.schedule.Interval {
+		return true
+	}
+	if p.schedule.Distance > 0 && p.travelled >= p.schedule.Distance {
+		return true
+	}
+
+	return false
+}
+
+// taken will register that a picture was taken at the time given.
+func (p *photoTrigger) taken(now time.Time) {
+	p.lastPhoto = now
+	p.travelled = 0
+}
+
+// PhotoSchedule will take pictures on the drone as given by the
+// schedule, using the gps position from the telemetry for the distance
+// travelled. A picture that is due while the camera is busy is taken
+// when the camera is ready again. PhotoSchedule will block until the
+// context is cancelled, MaxPhotos is reached, or the mission is done if
+// MissionOnly is set, and return the number of pictures taken.
+func (d *Drone) PhotoSchedule(ctx context.Context, s PhotoSchedule) (int, error) {
+	if s.Interval < 0 || s.Distance < 0 || s.MaxPhotos < 0 {
+		return 0, fmt.Errorf("PhotoSchedule: interval, distance and max photos can't be negative, got %v, %v, %v", s.Interval, s.Distance, s.MaxPhotos)
+	}
+	if s.Interval == 0 && s.Distance == 0 {
+		return 0, fmt.Errorf("PhotoSchedule: interval or distance must be set")
+	}
+	if s.Distance > 0 {
+		if err := d.checkGPSGuard(); err != nil {
+			return 0, fmt.Errorf("PhotoSchedule: %v", err)
+		}
+	}
+
+	ticker := time.NewTicker(photoPollInterval)
+	defer ticker.Stop()
+
+	trigger := photoTrigger{schedule: s}
+	var photos int
+	var missionStarted bool
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("info: exiting PhotoSchedule after %v pictures\n", photos)
+			return photos, nil
+		case now := <-ticker.C:
+			if s.MissionOnly {
+				active := d.MissionStatus().Active
+				if !active && missionStarted {
+					log.Printf("info: PhotoSchedule: mission done after %v pictures\n", photos)
+					return photos, nil
+				}
+				if !active {
+					continue
+				}
+				missionStarted = true
+			}
+
+			trigger.move(d.Telemetry().Position)
+			if !trigger.due(now) || d.MediaStatus().PictureState == PictureBusy {
+				continue
+			}
+
+			if err := d.TakePicture(); err != nil {
+				log.Printf("error: PhotoSchedule: %v\n", err)
+				continue
+			}
+			trigger.taken(now)
+			photos++
+
+			if s.MaxPhotos > 0 && photos >= s.MaxPhotos {
+				return photos, nil
+			}
+		}
+	}
+}
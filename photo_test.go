@@ -0,0 +1,75 @@
+package parrotbebop
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPhotoTriggerDistance(t *testing.T) {
+	p := photoTrigger{schedule: PhotoSchedule{Distance: 10}}
+	now := time.Now()
+
+	start := Position{Latitude: 60, Longitude: 10}
+	p.move(start)
+	if !p.due(now) {
+		t.Fatalf("first picture not due")
+	}
+	p.taken(now)
+
+	// 4 m out and back is 8 m travelled, even if the straight line
+	// distance is 0.
+	p.move(start.Offset(4, 90))
+	p.move(start)
+	if p.due(now) {
+		t.Fatalf("picture due after %v m", p.travelled)
+	}
+
+	// Invalid positions should not add any distance.
+	p.move(Position{Latitude: 500, Longitude: 500})
+	p.move(start.Offset(3, 0))
+	if !p.due(now) {
+		t.Fatalf("picture not due after %v m", p.travelled)
+	}
+	p.taken(now)
+	if p.due(now) {
+		t.Fatalf("picture due right after taken")
+	}
+}
+
+func TestPhotoTriggerInterval(t *testing.T) {
+	p := photoTrigger{schedule: PhotoSchedule{Interval: time.Second}}
+	now := time.Now()
+
+	p.taken(now)
+	if p.due(now.Add(time.Millisecond * 999)) {
+		t.Fatalf("picture due before the interval")
+	}
+	if !p.due(now.Add(time.Second)) {
+		t.Fatalf("picture not due after the interval")
+	}
+}
+
+func TestPhotoSchedule(t *testing.T) {
+	d := NewDrone()
+
+	if _, err := d.PhotoSchedule(context.Background(), PhotoSchedule{}); err == nil {
+		t.Fatalf("expected error for no interval or distance")
+	}
+
+	go func() {
+		for range d.chSendingUDPPacket {
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	n, err := d.PhotoSchedule(ctx, PhotoSchedule{Interval: time.Millisecond, MaxPhotos: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("got %v pictures, want 2", n)
+	}
+}
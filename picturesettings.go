@@ -0,0 +1,396 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"log"
+)
+
+// PictureFormat is the format of the pictures taken by the drone.
+type PictureFormat uint32
+
+const (
+	PictureFormatRaw         PictureFormat = 0
+	PictureFormatJPEG        PictureFormat = 1
+	PictureFormatSnapshot    PictureFormat = 2
+	PictureFormatJPEGFisheye PictureFormat = 3
+)
+
+// String will return the name of the picture format.
+func (p PictureFormat) String() string {
+	switch p {
+	case PictureFormatRaw:
+		return "raw"
+	case PictureFormatJPEG:
+		return "jpeg"
+	case PictureFormatSnapshot:
+		return "snapshot"
+	case PictureFormatJPEGFisheye:
+		return "jpeg_fisheye"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(p))
+}
+
+// WhiteBalance is the white balance mode of the camera.
+type WhiteBalance uint32
+
+const (
+	WhiteBalanceAuto      WhiteBalance = 0
+	WhiteBalanceTungsten  WhiteBalance = 1
+	WhiteBalanceDaylight  WhiteBalance = 2
+	WhiteBalanceCloudy    WhiteBalance = 3
+	WhiteBalanceCoolWhite WhiteBalance = 4
+)
+
+// String will return the name of the white balance mode.
+func (w WhiteBalance) String() string {
+	switch w {
+	case WhiteBalanceAuto:
+		return "auto"
+	case WhiteBalanceTungsten:
+		return "tungsten"
+	case WhiteBalanceDaylight:
+		return "daylight"
+	case WhiteBalanceCloudy:
+		return "cloudy"
+	case WhiteBalanceCoolWhite:
+		return "cool_white"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(w))
+}
+
+// VideoStabilization is the stabilization mode of the video.
+type VideoStabilization uint32
+
+const (
+	VideoStabilizationRollPitch VideoStabilization = 0
+	VideoStabilizationPitch     VideoStabilization = 1
+	VideoStabilizationRoll      VideoStabilization = 2
+	VideoStabilizationNone      VideoStabilization = 3
+)
+
+// String will return the name of the video stabilization mode.
+func (v VideoStabilization) String() string {
+	switch v {
+	case VideoStabilizationRollPitch:
+		return "roll_pitch"
+	case VideoStabilizationPitch:
+		return "pitch"
+	case VideoStabilizationRoll:
+		return "roll"
+	case VideoStabilizationNone:
+		return "none"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(v))
+}
+
+// VideoRecordingMode tells if the video recording should favour the
+// quality, or the recording time left on the storage.
+type VideoRecordingMode uint32
+
+const (
+	VideoRecordingQuality VideoRecordingMode = 0
+	VideoRecordingTime    VideoRecordingMode = 1
+)
+
+// String will return the name of the video recording mode.
+func (v VideoRecordingMode) String() string {
+	switch v {
+	case VideoRecordingQuality:
+		return "quality"
+	case VideoRecordingTime:
+		return "time"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(v))
+}
+
+// VideoFramerate is the framerate of the recorded video.
+type VideoFramerate uint32
+
+const (
+	VideoFramerate24 VideoFramerate = 0
+	VideoFramerate25 VideoFramerate = 1
+	VideoFramerate30 VideoFramerate = 2
+)
+
+// String will return the name of the video framerate.
+func (v VideoFramerate) String() string {
+	switch v {
+	case VideoFramerate24:
+		return "24_FPS"
+	case VideoFramerate25:
+		return "25_FPS"
+	case VideoFramerate30:
+		return "30_FPS"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(v))
+}
+
+// VideoResolution is the resolution of the recorded and streamed video.
+type VideoResolution uint32
+
+const (
+	// VideoResolutionRec1080Stream480 will record in 1080p, and stream
+	// in 480p.
+	VideoResolutionRec1080Stream480 VideoResolution = 0
+	// VideoResolutionRec720Stream720 will both record and stream in 720p.
+	VideoResolutionRec720Stream720 VideoResolution = 1
+)
+
+// String will return the name of the video resolution.
+func (v VideoResolution) String() string {
+	switch v {
+	case VideoResolutionRec1080Stream480:
+		return "rec1080_stream480"
+	case VideoResolutionRec720Stream720:
+		return "rec720_stream720"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(v))
+}
+
+// ElectricFrequency is the frequency of the electric grid powering the
+// lights around the drone, used by the antiflickering in auto mode.
+type ElectricFrequency uint32
+
+const (
+	ElectricFrequency50Hz ElectricFrequency = 0
+	ElectricFrequency60Hz ElectricFrequency = 1
+)
+
+// String will return the name of the electric frequency.
+func (e ElectricFrequency) String() string {
+	switch e {
+	case ElectricFrequency50Hz:
+		return "fiftyHertz"
+	case ElectricFrequency60Hz:
+		return "sixtyHertz"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(e))
+}
+
+// AntiflickeringMode is how the camera avoids the flickering of
+// artificial lights in the video.
+type AntiflickeringMode uint32
+
+const (
+	// AntiflickeringAuto will use the ElectricFrequency set.
+	AntiflickeringAuto AntiflickeringMode = 0
+	// AntiflickeringFixed50Hz and AntiflickeringFixed60Hz are forcing
+	// the frequency used, whatever the electric frequency set.
+	AntiflickeringFixed50Hz AntiflickeringMode = 1
+	AntiflickeringFixed60Hz AntiflickeringMode = 2
+)
+
+// String will return the name of the antiflickering mode.
+func (a AntiflickeringMode) String() string {
+	switch a {
+	case AntiflickeringAuto:
+		return "auto"
+	case AntiflickeringFixed50Hz:
+		return "FixedFiftyHertz"
+	case AntiflickeringFixed60Hz:
+		return "FixedSixtyHertz"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(a))
+}
+
+// CameraSettings holds the picture and video settings reported by
+// the drone.
+type CameraSettings struct {
+	PictureFormat PictureFormat
+	WhiteBalance  WhiteBalance
+	// Exposure is the exposure compensation, within the range given by
+	// ExposureMin and ExposureMax.
+	Exposure    float32
+	ExposureMin float32
+	ExposureMax float32
+	// Saturation is the color saturation, within the range given by
+	// SaturationMin and SaturationMax.
+	Saturation    float32
+	SaturationMin float32
+	SaturationMax float32
+	// Timelapse is true if timelapse pictures are enabled, with
+	// TimelapseInterval seconds between each picture.
+	Timelapse         bool
+	TimelapseInterval float32
+	// VideoAutorecord is true if the drone will start recording video
+	// by itself when taking off, to the mass storage with the id
+	// VideoAutorecordStorage.
+	VideoAutorecord        bool
+	VideoAutorecordStorage uint8
+	VideoStabilization     VideoStabilization
+	VideoRecordingMode     VideoRecordingMode
+	VideoFramerate         VideoFramerate
+	VideoResolution        VideoResolution
+	// ElectricFrequency and AntiflickeringMode are used to avoid the
+	// flickering of artificial lights when flying indoor.
+	ElectricFrequency  ElectricFrequency
+	AntiflickeringMode AntiflickeringMode
+}
+
+// CameraSettings will return the picture and video settings reported
+// by the drone.
+func (d *Drone) CameraSettings() CameraSettings {
+	return d.telemetry.snapshot().Camera
+}
+
+// SetPictureFormat will set the format of the pictures taken.
+func (d *Drone) SetPictureFormat(f PictureFormat) error {
+	if f > PictureFormatJPEGFisheye {
+		return fmt.Errorf("SetPictureFormat: unknown format: %v", f)
+	}
+
+	return d.sendCmd(Command(PictureSettingsPictureFormatSelection), &Ardrone3PictureSettingsPictureFormatSelectionArguments{TypeX: uint32(f)})
+}
+
+// SetWhiteBalance will set the white balance mode of the camera.
+func (d *Drone) SetWhiteBalance(w WhiteBalance) error {
+	if w > WhiteBalanceCoolWhite {
+		return fmt.Errorf("SetWhiteBalance: unknown mode: %v", w)
+	}
+
+	return d.sendCmd(Command(PictureSettingsAutoWhiteBalanceSelection), &Ardrone3PictureSettingsAutoWhiteBalanceSelectionArguments{TypeX: uint32(w)})
+}
+
+// SetExposure will set the exposure compensation of the camera. If the
+// drone have reported the allowed range, the value is checked against it.
+func (d *Drone) SetExposure(value float32) error {
+	c := d.CameraSettings()
+	if c.ExposureMin != c.ExposureMax && (value < c.ExposureMin || value > c.ExposureMax) {
+		return fmt.Errorf("SetExposure: value must be within [%v, %v], got %v", c.ExposureMin, c.ExposureMax, value)
+	}
+
+	return d.sendCmd(Command(PictureSettingsExpositionSelection), &Ardrone3PictureSettingsExpositionSelectionArguments{Value: value})
+}
+
+// SetSaturation will set the color saturation of the camera. If the
+// drone have reported the allowed range, the value is checked against it.
+func (d *Drone) SetSaturation(value float32) error {
+	c := d.CameraSettings()
+	if c.SaturationMin != c.SaturationMax && (value < c.SaturationMin || value > c.SaturationMax) {
+		return fmt.Errorf("SetSaturation: value must be within [%v, %v], got %v", c.SaturationMin, c.SaturationMax, value)
+	}
+
+	return d.sendCmd(Command(PictureSettingsSaturationSelection), &Ardrone3PictureSettingsSaturationSelectionArguments{Value: value})
+}
+
+// SetTimelapse will enable or disable timelapse pictures, taken with
+// the interval given in seconds.
+func (d *Drone) SetTimelapse(enabled bool, interval float32) error {
+	return d.sendCmd(Command(PictureSettingsTimelapseSelection), &Ardrone3PictureSettingsTimelapseSelectionArguments{
+		Enabled:  boolToUint8(enabled),
+		Interval: interval,
+	})
+}
+
+// SetVideoAutorecord will enable or disable that the drone starts
+// recording video to the internal storage by itself when taking off,
+// so each flight is recorded.
+func (d *Drone) SetVideoAutorecord(enabled bool) error {
+	var id uint8
+	for _, m := range d.Storage().Devices {
+		if m.Internal {
+			id = m.ID
+			break
+		}
+	}
+
+	return d.SetVideoAutorecordStorage(enabled, id)
+}
+
+// SetVideoAutorecordStorage will enable or disable that the drone
+// starts recording video by itself when taking off, to the mass storage
+// with the id given, as reported in Storage. If the drone have reported
+// it's mass storages, the id is checked against them.
+func (d *Drone) SetVideoAutorecordStorage(enabled bool, massStorageID uint8) error {
+	if devices := d.Storage().Devices; len(devices) > 0 {
+		m, ok := devices[massStorageID]
+		if !ok {
+			return fmt.Errorf("SetVideoAutorecordStorage: unknown mass storage: %v", massStorageID)
+		}
+		if enabled && m.Full {
+			log.Printf("warning: SetVideoAutorecordStorage: mass storage %v is full\n", massStorageID)
+		}
+	}
+
+	return d.sendCmd(Command(PictureSettingsVideoAutorecordSelection), &Ardrone3PictureSettingsVideoAutorecordSelectionArguments{
+		Enabled:       boolToUint8(enabled),
+		Massstorageid: massStorageID,
+	})
+}
+
+// SetVideoStabilization will set the stabilization mode of the video.
+func (d *Drone) SetVideoStabilization(v VideoStabilization) error {
+	if v > VideoStabilizationNone {
+		return fmt.Errorf("SetVideoStabilization: unknown mode: %v", v)
+	}
+
+	return d.sendCmd(Command(PictureSettingsVideoStabilizationMode), &Ardrone3PictureSettingsVideoStabilizationModeArguments{Mode: uint32(v)})
+}
+
+// SetVideoRecordingMode will set if the recording should favour the
+// quality or the recording time.
+func (d *Drone) SetVideoRecordingMode(v VideoRecordingMode) error {
+	if v > VideoRecordingTime {
+		return fmt.Errorf("SetVideoRecordingMode: unknown mode: %v", v)
+	}
+
+	return d.sendCmd(Command(PictureSettingsVideoRecordingMode), &Ardrone3PictureSettingsVideoRecordingModeArguments{Mode: uint32(v)})
+}
+
+// SetVideoFramerate will set the framerate of the recorded video.
+func (d *Drone) SetVideoFramerate(v VideoFramerate) error {
+	if v > VideoFramerate30 {
+		return fmt.Errorf("SetVideoFramerate: unknown framerate: %v", v)
+	}
+
+	return d.sendCmd(Command(PictureSettingsVideoFramerate), &Ardrone3PictureSettingsVideoFramerateArguments{Framerate: uint32(v)})
+}
+
+// SetVideoResolution will set the resolution of the recorded and the
+// streamed video.
+func (d *Drone) SetVideoResolution(v VideoResolution) error {
+	if v > VideoResolutionRec720Stream720 {
+		return fmt.Errorf("SetVideoResolution: unknown resolution: %v", v)
+	}
+
+	return d.sendCmd(Command(PictureSettingsVideoResolutions), &Ardrone3PictureSettingsVideoResolutionsArguments{TypeX: uint32(v)})
+}
+
+// SetElectricFrequency will set the frequency of the electric grid
+// powering the lights around the drone, which is used to avoid the
+// flickering in the video when the antiflickering mode is auto.
+func (d *Drone) SetElectricFrequency(e ElectricFrequency) error {
+	if e > ElectricFrequency60Hz {
+		return fmt.Errorf("SetElectricFrequency: unknown frequency: %v", e)
+	}
+
+	return d.sendCmd(Command(AntiflickeringelectricFrequency), &Ardrone3AntiflickeringelectricFrequencyArguments{Frequency: uint32(e)})
+}
+
+// SetAntiflickeringMode will set how the camera avoids the flickering
+// of artificial lights, which makes the video better when flying
+// indoor.
+func (d *Drone) SetAntiflickeringMode(a AntiflickeringMode) error {
+	if a > AntiflickeringFixed60Hz {
+		return fmt.Errorf("SetAntiflickeringMode: unknown mode: %v", a)
+	}
+
+	return d.sendCmd(Command(AntiflickeringsetMode), &Ardrone3AntiflickeringsetModeArguments{Mode: uint32(a)})
+}
+
+// boolToUint8 will convert a bool into the uint8 used for bools in
+// the command arguments.
+func boolToUint8(b bool) uint8 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package parrotbebop
+
+import "testing"
+
+func TestVideoAutorecordStorage(t *testing.T) {
+	d := NewDrone()
+	go func() {
+		for range d.chSendingUDPPacket {
+		}
+	}()
+
+	// Any storage is allowed before the drone have reported them.
+	if err := d.SetVideoAutorecordStorage(true, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	d.updateMassStorage(0, func(m *MassStorage) { m.Internal = true })
+	if err := d.SetVideoAutorecordStorage(true, 3); err == nil {
+		t.Fatalf("expected error for unknown mass storage")
+	}
+	if err := d.SetVideoAutorecord(true); err != nil {
+		t.Fatal(err)
+	}
+
+	c := Command(PictureSettingsStateVideoAutorecordChanged)
+	d.checkCmdFromDrone(protocolARCommands{project: int(c.Project), class: int(c.Class), command: int(c.Cmd)},
+		Ardrone3PictureSettingsStateVideoAutorecordChangedArguments{Enabled: 1, Massstorageid: 2})
+	if s := d.CameraSettings(); !s.VideoAutorecord || s.VideoAutorecordStorage != 2 {
+		t.Fatalf("got autorecord %v on storage %v, want true on 2", s.VideoAutorecord, s.VideoAutorecordStorage)
+	}
+}
+
+func TestAntiflickering(t *testing.T) {
+	d := NewDrone()
+	go func() {
+		for range d.chSendingUDPPacket {
+		}
+	}()
+
+	if err := d.SetAntiflickeringMode(AntiflickeringFixed60Hz + 1); err == nil {
+		t.Fatalf("expected error for unknown mode")
+	}
+	if err := d.SetElectricFrequency(ElectricFrequency60Hz); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetAntiflickeringMode(AntiflickeringAuto); err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(c Command, args interface{}) {
+		d.checkCmdFromDrone(protocolARCommands{project: int(c.Project), class: int(c.Class), command: int(c.Cmd)}, args)
+	}
+	send(Command(AntiflickeringStateelectricFrequencyChanged), Ardrone3AntiflickeringStateelectricFrequencyChangedArguments{Frequency: 1})
+	send(Command(AntiflickeringStatemodeChanged), Ardrone3AntiflickeringStatemodeChangedArguments{Mode: 2})
+
+	s := d.CameraSettings()
+	if s.ElectricFrequency != ElectricFrequency60Hz || s.AntiflickeringMode != AntiflickeringFixed60Hz {
+		t.Fatalf("got %v and %v, want sixtyHertz and FixedSixtyHertz", s.ElectricFrequency, s.AntiflickeringMode)
+	}
+}
@@ -0,0 +1,167 @@
+package parrotbebop
+
+import (
+	"fmt"
+)
+
+// The autonomous flight settings of the PilotingSettings class are
+// deprecated in the newer versions of the protocol, and are not part of
+// the generated commands, so they are defined here. They are still
+// used by the Bebop firmware when flying with moveTo and moveBy.
+const (
+	autonomousFlightCmdMaxHorizontalSpeed        CmdDef = 5
+	autonomousFlightCmdMaxVerticalSpeed          CmdDef = 6
+	autonomousFlightCmdMaxHorizontalAcceleration CmdDef = 7
+	autonomousFlightCmdMaxVerticalAcceleration   CmdDef = 8
+	autonomousFlightCmdMaxRotationSpeed          CmdDef = 9
+)
+
+// autonomousFlightArguments are the arguments of the autonomous flight
+// settings, and their state events, which all holds a single value.
+type autonomousFlightArguments struct {
+	Value float32
+}
+
+// Encode will encode the arguments into the command payload.
+func (a autonomousFlightArguments) Encode() []byte {
+	return ConvLittleEndianNumericToSlice(a.Value)
+}
+
+// autonomousFlightStateChangedArguments is the decoded state event of
+// one of the autonomous flight settings, where cmd tells which one.
+type autonomousFlightStateChangedArguments struct {
+	cmd   CmdDef
+	Value float32
+}
+
+// autonomousFlightStateChanged is the decoder for the state events of
+// the autonomous flight settings.
+type autonomousFlightStateChanged Command
+
+// Decode will decode the state event.
+func (a autonomousFlightStateChanged) Decode(b []byte) interface{} {
+	arg := autonomousFlightStateChangedArguments{cmd: a.Cmd}
+	if len(b) >= 4 {
+		ConvLittleEndianSliceToNumeric(b[0:4], &arg.Value)
+	}
+
+	return arg
+}
+
+func init() {
+	// Register the state events so they are decoded when received.
+	for cmd := autonomousFlightCmdMaxHorizontalSpeed; cmd <= autonomousFlightCmdMaxRotationSpeed; cmd++ {
+		c := Command{
+			Project: ProjectArdrone3,
+			Class:   Ardrone3PilotingSettingsStateClassPilotingSettingsState,
+			Cmd:     cmd,
+		}
+		CommandMap[c] = autonomousFlightStateChanged(c)
+	}
+}
+
+// PilotingSettings holds the piloting settings reported by the drone
+// which changes how the drone flies.
+type PilotingSettings struct {
+	// BankedTurn is true if the drone will use the yaw values to also
+	// roll the drone when it is moving, like a plane.
+	BankedTurn bool
+	// AbsoluteControl is true if the drone takes the pitch and roll
+	// of the PCMD relative to the heading it had at the takeoff,
+	// instead of relative to the heading it have now, so forward is
+	// always away from the pilot whichever way the drone is turned.
+	AbsoluteControl bool
+	// MaxHorizontalSpeed and MaxVerticalSpeed are the max speeds in m/s
+	// used when flying autonomously, like with moveTo.
+	MaxHorizontalSpeed float32
+	MaxVerticalSpeed   float32
+	// MaxHorizontalAcceleration and MaxVerticalAcceleration are the
+	// max accelerations in m/s² used when flying autonomously.
+	MaxHorizontalAcceleration float32
+	MaxVerticalAcceleration   float32
+	// MaxRotationSpeed is the max rotation speed in degrees/s used when
+	// flying autonomously.
+	MaxRotationSpeed float32
+}
+
+// PilotingSettings will return the piloting settings reported by the drone.
+func (d *Drone) PilotingSettings() PilotingSettings {
+	return d.telemetry.snapshot().Piloting
+}
+
+// SetBankedTurn will enable or disable banked turn mode.
+func (d *Drone) SetBankedTurn(enabled bool) error {
+	return d.sendCmd(Command(PilotingSettingsBankedTurn), &Ardrone3PilotingSettingsBankedTurnArguments{Value: boolToUint8(enabled)})
+}
+
+// SetAbsoluteControl will enable or disable absolute control, also
+// known as headless mode, where the drone is piloted relative to the
+// heading it had at the takeoff.
+func (d *Drone) SetAbsoluteControl(enabled bool) error {
+	return d.sendCmd(Command(PilotingSettingsAbsolutControl), &Ardrone3PilotingSettingsAbsolutControlArguments{On: boolToUint8(enabled)})
+}
+
+// SetAutonomousFlightMaxHorizontalSpeed will set the max horizontal
+// speed in m/s used when flying autonomously.
+func (d *Drone) SetAutonomousFlightMaxHorizontalSpeed(mps float32) error {
+	return d.sendAutonomousFlightSetting("SetAutonomousFlightMaxHorizontalSpeed", autonomousFlightCmdMaxHorizontalSpeed, mps)
+}
+
+// SetAutonomousFlightMaxVerticalSpeed will set the max vertical speed
+// in m/s used when flying autonomously.
+func (d *Drone) SetAutonomousFlightMaxVerticalSpeed(mps float32) error {
+	return d.sendAutonomousFlightSetting("SetAutonomousFlightMaxVerticalSpeed", autonomousFlightCmdMaxVerticalSpeed, mps)
+}
+
+// SetAutonomousFlightMaxHorizontalAcceleration will set the max
+// horizontal acceleration in m/s² used when flying autonomously.
+func (d *Drone) SetAutonomousFlightMaxHorizontalAcceleration(mps2 float32) error {
+	return d.sendAutonomousFlightSetting("SetAutonomousFlightMaxHorizontalAcceleration", autonomousFlightCmdMaxHorizontalAcceleration, mps2)
+}
+
+// SetAutonomousFlightMaxVerticalAcceleration will set the max vertical
+// acceleration in m/s² used when flying autonomously.
+func (d *Drone) SetAutonomousFlightMaxVerticalAcceleration(mps2 float32) error {
+	return d.sendAutonomousFlightSetting("SetAutonomousFlightMaxVerticalAcceleration", autonomousFlightCmdMaxVerticalAcceleration, mps2)
+}
+
+// SetAutonomousFlightMaxRotationSpeed will set the max rotation speed
+// in degrees/s used when flying autonomously.
+func (d *Drone) SetAutonomousFlightMaxRotationSpeed(degps float32) error {
+	return d.sendAutonomousFlightSetting("SetAutonomousFlightMaxRotationSpeed", autonomousFlightCmdMaxRotationSpeed, degps)
+}
+
+// sendAutonomousFlightSetting will send one of the autonomous flight
+// settings to the drone.
+func (d *Drone) sendAutonomousFlightSetting(name string, cmd CmdDef, value float32) error {
+	if value <= 0 {
+		return fmt.Errorf("%v: value must be positive, got %v", name, value)
+	}
+
+	c := Command{
+		Project: ProjectArdrone3,
+		Class:   Ardrone3PilotingSettingsClassPilotingSettings,
+		Cmd:     cmd,
+	}
+
+	return d.sendCmd(c, autonomousFlightArguments{Value: value})
+}
+
+// handleAutonomousFlightState will store the autonomous flight setting
+// reported by the drone.
+func (d *Drone) handleAutonomousFlightState(a autonomousFlightStateChangedArguments) {
+	d.telemetry.update(func(t *Telemetry) {
+		switch a.cmd {
+		case autonomousFlightCmdMaxHorizontalSpeed:
+			t.Piloting.MaxHorizontalSpeed = a.Value
+		case autonomousFlightCmdMaxVerticalSpeed:
+			t.Piloting.MaxVerticalSpeed = a.Value
+		case autonomousFlightCmdMaxHorizontalAcceleration:
+			t.Piloting.MaxHorizontalAcceleration = a.Value
+		case autonomousFlightCmdMaxVerticalAcceleration:
+			t.Piloting.MaxVerticalAcceleration = a.Value
+		case autonomousFlightCmdMaxRotationSpeed:
+			t.Piloting.MaxRotationSpeed = a.Value
+		}
+	})
+}
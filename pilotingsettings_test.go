@@ -0,0 +1,24 @@
+package parrotbebop
+
+import "testing"
+
+func TestAbsoluteControl(t *testing.T) {
+	d := NewDrone()
+
+	go func() { <-d.chSendingUDPPacket }()
+	if err := d.SetAbsoluteControl(true); err != nil {
+		t.Fatal(err)
+	}
+
+	c := Command(PilotingSettingsStateAbsolutControlChanged)
+	p := protocolARCommands{project: int(c.Project), class: int(c.Class), command: int(c.Cmd)}
+
+	d.checkCmdFromDrone(p, Ardrone3PilotingSettingsStateAbsolutControlChangedArguments{On: 1})
+	if !d.PilotingSettings().AbsoluteControl {
+		t.Fatalf("expected absolute control on")
+	}
+	d.checkCmdFromDrone(p, Ardrone3PilotingSettingsStateAbsolutControlChangedArguments{On: 0})
+	if d.PilotingSettings().AbsoluteControl {
+		t.Fatalf("expected absolute control off")
+	}
+}
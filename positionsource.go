@@ -0,0 +1,226 @@
+package parrotbebop
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// PositionSource is a source of positions, like the position of the
+// operator when using follow-me.
+type PositionSource interface {
+	// Positions will start the source, and return a channel where all
+	// the positions are delivered. The channel is closed when the
+	// context is cancelled, or the source have no more positions.
+	Positions(ctx context.Context) (<-chan Position, error)
+}
+
+// ChanPositionSource is a PositionSource where positions are given by
+// sending them on the channel In, which for example can be used with
+// positions received from a phone over an API.
+type ChanPositionSource struct {
+	In chan Position
+}
+
+// NewChanPositionSource will return a new ChanPositionSource.
+func NewChanPositionSource() *ChanPositionSource {
+	return &ChanPositionSource{
+		In: make(chan Position),
+	}
+}
+
+// Positions will return a channel delivering the positions put on
+// the In channel.
+func (c *ChanPositionSource) Positions(ctx context.Context) (<-chan Position, error) {
+	ch := make(chan Position)
+
+	go func() {
+		defer close(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case p := <-c.In:
+				select {
+				case ch <- p:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// NMEAPositionSource will read NMEA 0183 sentences from the reader,
+// which typically will be a serial port with a gps receiver, and
+// deliver the positions found in the $GPGGA/$GNGGA sentences.
+type NMEAPositionSource struct {
+	Reader io.Reader
+}
+
+// Positions will start reading the NMEA sentences, and return a
+// channel delivering the positions.
+func (n *NMEAPositionSource) Positions(ctx context.Context) (<-chan Position, error) {
+	if n.Reader == nil {
+		return nil, fmt.Errorf("NMEAPositionSource: no reader given")
+	}
+
+	ch := make(chan Position)
+
+	go func() {
+		defer close(ch)
+		scanner := bufio.NewScanner(n.Reader)
+		for scanner.Scan() {
+			p, err := parseNMEAGGA(scanner.Text())
+			if err != nil {
+				continue
+			}
+
+			select {
+			case ch <- p:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			log.Printf("error: NMEAPositionSource: %v\n", err)
+		}
+	}()
+
+	return ch, nil
+}
+
+// parseNMEAGGA will parse a GGA sentence, and return the position.
+// An error is returned if the sentence is not a GGA sentence, or
+// there is no gps fix.
+func parseNMEAGGA(s string) (Position, error) {
+	s = strings.TrimSpace(s)
+	// Strip off the checksum.
+	if i := strings.Index(s, "*"); i != -1 {
+		s = s[:i]
+	}
+
+	f := strings.Split(s, ",")
+	if len(f) < 10 || len(f[0]) != 6 || f[0][3:] != "GGA" {
+		return Position{}, fmt.Errorf("not a GGA sentence: %v", s)
+	}
+
+	// Field 6 is the fix quality, where 0 means no fix.
+	if f[6] == "" || f[6] == "0" {
+		return Position{}, fmt.Errorf("no gps fix: %v", s)
+	}
+
+	lat, err := parseNMEACoordinate(f[2], f[3], 2)
+	if err != nil {
+		return Position{}, err
+	}
+	lon, err := parseNMEACoordinate(f[4], f[5], 3)
+	if err != nil {
+		return Position{}, err
+	}
+	alt, err := strconv.ParseFloat(f[9], 64)
+	if err != nil {
+		return Position{}, fmt.Errorf("failed to parse altitude: %v", err)
+	}
+
+	return Position{Latitude: lat, Longitude: lon, Altitude: alt}, nil
+}
+
+// parseNMEACoordinate will convert the NMEA (d)ddmm.mmmm format into
+// decimal degrees. degDigits is the number of digits used for degrees.
+func parseNMEACoordinate(v string, hemisphere string, degDigits int) (float64, error) {
+	if len(v) < degDigits {
+		return 0, fmt.Errorf("malformed coordinate: %v", v)
+	}
+
+	deg, err := strconv.ParseFloat(v[:degDigits], 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed coordinate: %v", v)
+	}
+	min, err := strconv.ParseFloat(v[degDigits:], 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed coordinate: %v", v)
+	}
+
+	c := deg + min/60
+	if hemisphere == "S" || hemisphere == "W" {
+		c = -c
+	}
+
+	return c, nil
+}
+
+// GPSDPositionSource will connect to a gpsd daemon, and deliver the
+// positions found in the TPV reports.
+type GPSDPositionSource struct {
+	// Address of gpsd, defaults to localhost:2947 if not set.
+	Address string
+}
+
+// Positions will connect to gpsd, and return a channel delivering
+// the positions.
+func (g *GPSDPositionSource) Positions(ctx context.Context) (<-chan Position, error) {
+	addr := g.Address
+	if addr == "" {
+		addr = "localhost:2947"
+	}
+
+	var nd net.Dialer
+	conn, err := nd.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("GPSDPositionSource: failed to dial gpsd: %v", err)
+	}
+
+	_, err = conn.Write([]byte(`?WATCH={"enable":true,"json":true}` + "\n"))
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("GPSDPositionSource: failed to write watch command: %v", err)
+	}
+
+	ch := make(chan Position)
+
+	// Close the connection when the context is done, which will also
+	// make the reading below to stop.
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	go func() {
+		defer close(ch)
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			tpv := struct {
+				Class string  `json:"class"`
+				Mode  int     `json:"mode"`
+				Lat   float64 `json:"lat"`
+				Lon   float64 `json:"lon"`
+				Alt   float64 `json:"alt"`
+			}{}
+
+			if err := json.Unmarshal(scanner.Bytes(), &tpv); err != nil {
+				continue
+			}
+			// Mode 2 is 2D fix, and 3 is 3D fix.
+			if tpv.Class != "TPV" || tpv.Mode < 2 {
+				continue
+			}
+
+			select {
+			case ch <- Position{Latitude: tpv.Lat, Longitude: tpv.Lon, Altitude: tpv.Alt}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
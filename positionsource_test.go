@@ -0,0 +1,40 @@
+package parrotbebop
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseNMEAGGA(t *testing.T) {
+	s := "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47"
+
+	p, err := parseNMEAGGA(s)
+	if err != nil {
+		t.Fatalf("parseNMEAGGA failed: %v", err)
+	}
+
+	if math.Abs(p.Latitude-48.1173) > 0.0001 || math.Abs(p.Longitude-11.516666) > 0.0001 || p.Altitude != 545.4 {
+		t.Fatalf("wrong position: %#v", p)
+	}
+
+	// No fix should give an error.
+	s = "$GPGGA,123519,4807.038,N,01131.000,E,0,08,0.9,545.4,M,46.9,M,,*47"
+	if _, err := parseNMEAGGA(s); err == nil {
+		t.Fatalf("expected error for sentence with no fix")
+	}
+
+	// Other sentences should give an error.
+	s = "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A"
+	if _, err := parseNMEAGGA(s); err == nil {
+		t.Fatalf("expected error for RMC sentence")
+	}
+}
+
+func TestOffsetPosition(t *testing.T) {
+	// Moving 1000 meters north should increase the latitude with
+	// about 0.009 degrees, and leave the longitude as is.
+	lat, lon := offsetPosition(60, 10, 1000, 0)
+	if math.Abs(lat-60.008993) > 0.00001 || math.Abs(lon-10) > 0.00001 {
+		t.Fatalf("wrong offset position: %v, %v", lat, lon)
+	}
+}
@@ -0,0 +1,244 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The flat trim command and it's state event are not part of the
+// generated commands, so they are defined here.
+const (
+	flatTrimCmd        CmdDef = 0
+	flatTrimChangedCmd CmdDef = 0
+)
+
+// flatTrimArguments are the arguments of the flat trim command, and
+// the flat trim changed event, which have no values.
+type flatTrimArguments struct{}
+
+// Encode will encode the arguments into the command payload.
+func (a flatTrimArguments) Encode() []byte {
+	return nil
+}
+
+// flatTrimChanged is the decoder for the flat trim changed event.
+type flatTrimChanged Command
+
+// Decode will decode the flat trim changed event.
+func (a flatTrimChanged) Decode(b []byte) interface{} {
+	return flatTrimArguments{}
+}
+
+func init() {
+	CommandMap[flatTrimChangedCommand] = flatTrimChanged(flatTrimChangedCommand)
+}
+
+// flatTrimCommand and flatTrimChangedCommand are the flat trim command,
+// and the event sent by the drone when the flat trim is done.
+var (
+	flatTrimCommand = Command{
+		Project: ProjectArdrone3,
+		Class:   Ardrone3PilotingClassPiloting,
+		Cmd:     flatTrimCmd,
+	}
+	flatTrimChangedCommand = Command{
+		Project: ProjectArdrone3,
+		Class:   Ardrone3PilotingStateClassPilotingState,
+		Cmd:     flatTrimChangedCmd,
+	}
+)
+
+// FlatTrim will ask the drone to do a flat trim, which calibrates the
+// drone to the ground it is standing on. It should be done on flat
+// ground before the takeoff.
+func (d *Drone) FlatTrim() error {
+	return d.sendCmd(flatTrimCommand, flatTrimArguments{})
+}
+
+// FlatTrimAndWait will ask the drone to do a flat trim like FlatTrim,
+// and wait until the drone reports that it is done.
+func (d *Drone) FlatTrimAndWait(timeout time.Duration) error {
+	if _, err := d.SendAndWait(flatTrimCommand, flatTrimArguments{}, flatTrimChangedCommand, timeout); err != nil {
+		return fmt.Errorf("FlatTrimAndWait: %v", err)
+	}
+
+	return nil
+}
+
+// PreflightConfig holds which preflight checks to run.
+type PreflightConfig struct {
+	// Calibration checks that the magnetometer needs no calibration.
+	Calibration bool
+	// GPSFix checks that the drone have a gps fix.
+	GPSFix bool
+	// MinBattery is the minimum battery level in percent, where 0
+	// disables the check.
+	MinBattery uint8
+	// HomeSet checks that the drone have a home position.
+	HomeSet bool
+	// Sensors checks that the drone reports no failing sensors.
+	Sensors bool
+	// FlatTrim checks that a flat trim have been done.
+	FlatTrim bool
+	// BlockTakeOff will refuse the takeoff actions until all the
+	// checks passes.
+	BlockTakeOff bool
+}
+
+// DefaultPreflightConfig is the preflight config used if not set with
+// SetPreflightConfig. All the checks are enabled, but the takeoff is
+// not blocked.
+var DefaultPreflightConfig = PreflightConfig{
+	Calibration: true,
+	GPSFix:      true,
+	MinBattery:  20,
+	HomeSet:     true,
+	Sensors:     true,
+	FlatTrim:    true,
+}
+
+// PreflightResult is the result of a single preflight check.
+type PreflightResult struct {
+	Check string
+	OK    bool
+	// Reason tells why the check failed.
+	Reason string
+}
+
+// PreflightReport is the result of all the preflight checks run.
+type PreflightReport struct {
+	Time    time.Time
+	Results []PreflightResult
+	// Passed is true when all the checks passed.
+	Passed bool
+}
+
+// Failed will return the checks that failed.
+func (r PreflightReport) Failed() []PreflightResult {
+	var failed []PreflightResult
+	for _, res := range r.Results {
+		if !res.OK {
+			failed = append(failed, res)
+		}
+	}
+
+	return failed
+}
+
+// String will return a short summary of the report.
+func (r PreflightReport) String() string {
+	if r.Passed {
+		return "preflight passed"
+	}
+
+	var reasons []string
+	for _, res := range r.Failed() {
+		reasons = append(reasons, res.Check+": "+res.Reason)
+	}
+
+	return "preflight failed: " + strings.Join(reasons, ", ")
+}
+
+// preflight holds the preflight config, and if a flat trim have been
+// done.
+type preflight struct {
+	mu           sync.Mutex
+	config       PreflightConfig
+	flatTrimDone bool
+}
+
+// SetPreflightConfig will set which checks Preflight should run.
+func (d *Drone) SetPreflightConfig(c PreflightConfig) error {
+	if c.MinBattery > 100 {
+		return fmt.Errorf("SetPreflightConfig: min battery must be within [0, 100], got %v", c.MinBattery)
+	}
+
+	d.preflight.mu.Lock()
+	defer d.preflight.mu.Unlock()
+
+	d.preflight.config = c
+
+	return nil
+}
+
+// Preflight will run the configured preflight checks against the
+// latest values reported by the drone, and return the report.
+func (d *Drone) Preflight() PreflightReport {
+	d.preflight.mu.Lock()
+	c := d.preflight.config
+	flatTrimDone := d.preflight.flatTrimDone
+	d.preflight.mu.Unlock()
+
+	t := d.telemetry.snapshot()
+	report := PreflightReport{Time: time.Now(), Passed: true}
+
+	add := func(check string, ok bool, reason string) {
+		if ok {
+			reason = ""
+		}
+		report.Results = append(report.Results, PreflightResult{Check: check, OK: ok, Reason: reason})
+		report.Passed = report.Passed && ok
+	}
+
+	if c.Calibration {
+		add("calibration", !t.MagnetoCalibrationRequired, "magnetometer calibration required")
+	}
+	if c.GPSFix {
+		add("gps", t.GPSFixed, "no gps fix")
+	}
+	if c.MinBattery > 0 {
+		add("battery", t.Battery >= c.MinBattery, fmt.Sprintf("battery at %v%%, need at least %v%%", t.Battery, c.MinBattery))
+	}
+	if c.HomeSet {
+		add("home", d.Home().Set, "no home position")
+	}
+	if c.Sensors {
+		var failing []string
+		for s, ok := range t.Sensors {
+			if !ok {
+				failing = append(failing, s.String())
+			}
+		}
+		sort.Strings(failing)
+		add("sensors", len(failing) == 0, "failing sensors: "+strings.Join(failing, ", "))
+	}
+	if c.FlatTrim {
+		add("flattrim", flatTrimDone, "no flat trim done")
+	}
+
+	return report
+}
+
+// checkPreflightFor will run the preflight checks if the action is a
+// takeoff and the takeoff should be blocked, and return an error if
+// the checks fails.
+func (d *Drone) checkPreflightFor(action InputAction) error {
+	if action != ActionTakeoff && action != ActionUserTakeoff {
+		return nil
+	}
+
+	d.preflight.mu.Lock()
+	block := d.preflight.config.BlockTakeOff
+	d.preflight.mu.Unlock()
+
+	if !block {
+		return nil
+	}
+
+	if r := d.Preflight(); !r.Passed {
+		return fmt.Errorf("%v", r)
+	}
+
+	return nil
+}
+
+// handleFlatTrimChanged will mark the flat trim as done.
+func (d *Drone) handleFlatTrimChanged() {
+	d.preflight.mu.Lock()
+	defer d.preflight.mu.Unlock()
+
+	d.preflight.flatTrimDone = true
+}
@@ -0,0 +1,35 @@
+package parrotbebop
+
+import "testing"
+
+func TestPreflight(t *testing.T) {
+	d := NewDrone()
+	if err := d.SetPreflightConfig(PreflightConfig{MinBattery: 30, Sensors: true, FlatTrim: true, BlockTakeOff: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	d.checkCmdFromDrone(protocolARCommands{}, CommonCommonStateBatteryStateChangedArguments{Percent: 25})
+	d.checkCmdFromDrone(protocolARCommands{}, CommonCommonStateSensorsStatesListChangedArguments{SensorName: uint32(SensorGPS), SensorState: 0})
+
+	r := d.Preflight()
+	if r.Passed || len(r.Failed()) != 3 {
+		t.Fatalf("expected 3 failed checks, got %v", r)
+	}
+	if err := d.checkPreflightFor(ActionTakeoff); err == nil {
+		t.Fatalf("expected takeoff to be blocked")
+	}
+	if err := d.checkPreflightFor(ActionLanding); err != nil {
+		t.Fatalf("landing should not be blocked: %v", err)
+	}
+
+	d.checkCmdFromDrone(protocolARCommands{}, CommonCommonStateBatteryStateChangedArguments{Percent: 80})
+	d.checkCmdFromDrone(protocolARCommands{}, CommonCommonStateSensorsStatesListChangedArguments{SensorName: uint32(SensorGPS), SensorState: 1})
+	d.checkCmdFromDrone(protocolARCommands{}, flatTrimArguments{})
+
+	if r := d.Preflight(); !r.Passed {
+		t.Fatalf("expected preflight to pass, got %v", r)
+	}
+	if err := d.checkPreflightFor(ActionTakeoff); err != nil {
+		t.Fatalf("takeoff should be allowed: %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package parrotbebop
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os/exec"
+)
+
+// VideoTranscoder will transcode the raw H264 video stream into some
+// other format that can be viewed directly, like MJPEG for a browser.
+type VideoTranscoder interface {
+	// ContentType is the http content type of the transcoded stream.
+	ContentType() string
+	// Transcode will read the H264 Annex-B stream from r, and write the
+	// transcoded stream to w until the context is done or r is closed.
+	Transcode(ctx context.Context, r io.Reader, w io.Writer) error
+}
+
+// FFmpegMJPEG is a VideoTranscoder using an ffmpeg binary to transcode
+// the video into a multipart MJPEG stream which can be shown directly
+// by a browser.
+type FFmpegMJPEG struct {
+	// Path to the ffmpeg binary. Defaults to "ffmpeg" found in PATH.
+	Path string
+	// Quality is the jpeg quality given to ffmpeg with -q:v, where 2
+	// is the best and 31 the worst. Defaults to 5.
+	Quality int
+}
+
+// ContentType will return the content type of the multipart MJPEG
+// stream, where ffmpeg uses "ffmpeg" as the boundary.
+func (f FFmpegMJPEG) ContentType() string {
+	return "multipart/x-mixed-replace;boundary=ffmpeg"
+}
+
+// Transcode will run ffmpeg with the H264 stream as input, and the
+// MJPEG stream as output.
+func (f FFmpegMJPEG) Transcode(ctx context.Context, r io.Reader, w io.Writer) error {
+	path := f.Path
+	if path == "" {
+		path = "ffmpeg"
+	}
+	quality := f.Quality
+	if quality == 0 {
+		quality = 5
+	}
+
+	cmd := exec.CommandContext(ctx, path,
+		"-loglevel", "error",
+		"-f", "h264", "-i", "pipe:0",
+		"-f", "mpjpeg", "-q:v", fmt.Sprint(quality),
+		"pipe:1",
+	)
+	cmd.Stdin = r
+	cmd.Stdout = w
+
+	if err := cmd.Run(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("ffmpeg transcoding failed: %v", err)
+	}
+
+	return nil
+}
+
+// StartVideoPreview will start a http server on the address given,
+// serving the live video from the drone, so it can be viewed with for
+// example VLC or a browser pointed at the controller machine.
+//
+//  /video.h264  : the raw H264 stream, playable with VLC or ffplay.
+//  /video.mjpeg : the stream transcoded to MJPEG, viewable in a
+//                 browser, only available if a transcoder is given.
+//
+// An error is returned if the address can't be listened on. The server
+// is stopped when the context is done.
+func (d *Drone) StartVideoPreview(ctx context.Context, addr string, transcoder VideoTranscoder) error {
+	mux := http.NewServeMux()
+	d.registerVideoHandlers(mux, transcoder)
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("StartVideoPreview: %v", err)
+	}
+
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+
+	go func() {
+		if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+			log.Printf("error: video preview server failed: %v\n", err)
+		}
+	}()
+
+	log.Printf("info: video preview started on http://%v/video.h264\n", addr)
+
+	return nil
+}
+
+// registerVideoHandlers will register the video handlers on the mux,
+// where the MJPEG handler is only registered if a transcoder is given.
+func (d *Drone) registerVideoHandlers(mux *http.ServeMux, transcoder VideoTranscoder) {
+	mux.HandleFunc("/video.h264", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "video/h264")
+		if err := d.writeVideoStream(r.Context(), w); err != nil {
+			log.Printf("info: video preview: %v\n", err)
+		}
+	})
+
+	if transcoder != nil {
+		mux.HandleFunc("/video.mjpeg", func(w http.ResponseWriter, r *http.Request) {
+			pr, pw := io.Pipe()
+			go func() {
+				err := d.writeVideoStream(r.Context(), pw)
+				pw.CloseWithError(err)
+			}()
+
+			w.Header().Set("Content-Type", transcoder.ContentType())
+			if err := transcoder.Transcode(r.Context(), pr, w); err != nil {
+				log.Printf("error: video preview: %v\n", err)
+			}
+			pr.Close()
+		})
+	}
+}
+
+// writeVideoStream will write the H264 frames from the drone to w as
+// a continuous Annex-B stream, starting at the next key frame so the
+// receiver are able to decode it. It will return when the context is
+// done, or writing fails.
+func (d *Drone) writeVideoStream(ctx context.Context, w io.Writer) error {
+	chFrames, unsubscribe := d.frames.subscribe()
+	defer unsubscribe()
+
+	flusher, _ := w.(http.Flusher)
+	var started bool
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case f := <-chFrames:
+			if !started && !f.KeyFrame {
+				continue
+			}
+			started = true
+
+			if _, err := w.Write(f.Data); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package parrotbebop
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestStartVideoPreviewListenError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	d := NewDrone()
+	if err := d.StartVideoPreview(context.Background(), l.Addr().String(), nil); err == nil {
+		t.Fatalf("expected error when the address is in use")
+	}
+}
@@ -0,0 +1,119 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ConnectionProfile holds how to connect to a drone, or something
+// speaking the same protocol like a simulator.
+type ConnectionProfile struct {
+	// Name of the profile.
+	Name string
+	// Address is the IP address of the drone.
+	Address string
+	// DiscoveryPort is the TCP port used for the discovery.
+	DiscoveryPort string
+	// D2CPort is the UDP port the controller listens on for the frames
+	// from the drone, given to the drone in the discovery. Empty keeps
+	// the port used before, which defaults to 43210.
+	D2CPort string
+	// DiscoveryTimeout is how long each discovery attempt can take.
+	DiscoveryTimeout time.Duration
+	// AllowMissingStatus will accept a discovery response without a
+	// status as long as the c2d_port is given.
+	AllowMissingStatus bool
+	// IgnoreQoS will not mark the packets for QoS, even if the drone
+	// asks for it in the discovery.
+	IgnoreQoS bool
+}
+
+var (
+	// ProfileBebop is the profile for a real drone, connected to with
+	// the wifi access point of the drone.
+	ProfileBebop = ConnectionProfile{
+		Name:             "bebop",
+		Address:          "192.168.42.1",
+		DiscoveryPort:    "44444",
+		DiscoveryTimeout: discoveryTimeout,
+	}
+
+	// ProfileSphinx is the profile for the Parrot Sphinx simulator,
+	// where the firmware of the drone runs in a simulated physics
+	// environment, and is reached on the virtual interface created by
+	// Sphinx. The simulated firmware can be slow to answer the
+	// discovery while booting, may leave out the status in the
+	// discovery response, and the virtual network have no use for QoS
+	// marking.
+	ProfileSphinx = ConnectionProfile{
+		Name:               "sphinx",
+		Address:            "10.202.0.1",
+		DiscoveryPort:      "44444",
+		DiscoveryTimeout:   time.Second * 10,
+		AllowMissingStatus: true,
+		IgnoreQoS:          true,
+	}
+)
+
+// profiles are the known connection profiles by name.
+var profiles = map[string]ConnectionProfile{
+	ProfileBebop.Name:  ProfileBebop,
+	ProfileSphinx.Name: ProfileSphinx,
+}
+
+// ProfileByName will return the known connection profile with the name
+// given, like "bebop" or "sphinx".
+func ProfileByName(name string) (ConnectionProfile, error) {
+	p, ok := profiles[name]
+	if !ok {
+		var names []string
+		for n := range profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return ConnectionProfile{}, fmt.Errorf("ProfileByName: unknown profile %q, known profiles are %v", name, names)
+	}
+
+	return p, nil
+}
+
+// discoveryQuirks holds how the discovery should deal with drones not
+// behaving like a real drone, given by the connection profile.
+type discoveryQuirks struct {
+	timeout            time.Duration
+	allowMissingStatus bool
+	ignoreQoS          bool
+}
+
+// SetProfile will set the address and the discovery settings of the
+// connection profile. Must be called before Start.
+func (d *Drone) SetProfile(p ConnectionProfile) error {
+	if net.ParseIP(p.Address) == nil {
+		return fmt.Errorf("SetProfile: invalid ip address: %v", p.Address)
+	}
+	if port, err := strconv.Atoi(p.DiscoveryPort); err != nil || port <= 0 || port > 65535 {
+		return fmt.Errorf("SetProfile: invalid discovery port: %v", p.DiscoveryPort)
+	}
+	if port, err := strconv.Atoi(p.D2CPort); p.D2CPort != "" && (err != nil || port <= 0 || port > 65535) {
+		return fmt.Errorf("SetProfile: invalid d2c port: %v", p.D2CPort)
+	}
+	if p.DiscoveryTimeout < 0 {
+		return fmt.Errorf("SetProfile: negative discovery timeout: %v", p.DiscoveryTimeout)
+	}
+
+	d.addressDrone = p.Address
+	d.portDiscover = p.DiscoveryPort
+	if p.D2CPort != "" {
+		d.portD2C = p.D2CPort
+	}
+	d.discoveryQuirks = discoveryQuirks{
+		timeout:            p.DiscoveryTimeout,
+		allowMissingStatus: p.AllowMissingStatus,
+		ignoreQoS:          p.IgnoreQoS,
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package parrotbebop
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSetProfile(t *testing.T) {
+	d := NewDrone()
+	p, err := ProfileByName("sphinx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetProfile(p); err != nil {
+		t.Fatal(err)
+	}
+	if d.addressDrone != "10.202.0.1" || !d.discoveryQuirks.allowMissingStatus {
+		t.Fatalf("profile not set: %v, %+v", d.addressDrone, d.discoveryQuirks)
+	}
+
+	if _, err := ProfileByName("mambo"); err == nil {
+		t.Fatal("expected error for unknown profile")
+	}
+	p.D2CPort = "43211"
+	if err := d.SetProfile(p); err != nil || d.portD2C != "43211" {
+		t.Fatalf("d2c port not set: %v, %v", d.portD2C, err)
+	}
+	p.DiscoveryPort = "0"
+	if err := d.SetProfile(p); err == nil {
+		t.Fatal("expected error for invalid port")
+	}
+}
+
+func TestDiscoverSphinxQuirks(t *testing.T) {
+	resp := `{ "c2d_port": 54321, "qos_mode": 1 }`
+
+	// A real drone must give the status.
+	d := NewDrone()
+	d.addressDrone = "127.0.0.1"
+	d.portDiscover = fakeDiscoveryServer(t, resp)
+	if err := d.discoverOnce(context.Background()); err == nil {
+		t.Fatal("expected error for missing status")
+	}
+
+	p := ProfileSphinx
+	p.Address = "127.0.0.1"
+	p.DiscoveryPort = fakeDiscoveryServer(t, resp)
+	if err := d.SetProfile(p); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+	if err := d.Discover(ctx); err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	if d.portC2D != "54321" || d.qosMode != 0 {
+		t.Fatalf("wrong values from discovery: %v, %v", d.portC2D, d.qosMode)
+	}
+}
@@ -0,0 +1,62 @@
+package parrotbebop
+
+import (
+	"log"
+	"net"
+)
+
+// The class selectors used for marking the packets sent to the drone
+// when the drone asks for QoS in the discovery, which is the same as
+// used by the ARSDK. The values are the full TOS byte, where the class
+// selector is the 3 highest bits of the DSCP field.
+const (
+	// classSelectorBestEffort is CS0, used for the commands where the
+	// latency is not critical, like the actions, settings and acks.
+	classSelectorBestEffort = 0x00
+	// classSelectorCommands is CS6, used for the low latency commands,
+	// which are the PCMD and the emergency.
+	classSelectorCommands = 0xc0
+	// classSelectorVideo is CS5, used for the video stream control.
+	classSelectorVideo = 0xa0
+)
+
+// classSelectorFor will return the class selector to mark the frame
+// with, where the PCMD and the emergency are sent with low latency, and
+// the rest as best effort.
+func classSelectorFor(data []byte) int {
+	if len(data) < 2 {
+		return classSelectorBestEffort
+	}
+
+	switch int(data[1]) {
+	case bufferC2DEmergency:
+		return classSelectorCommands
+	case bufferC2DNonAck:
+		if isPcmdFrame(data) {
+			return classSelectorCommands
+		}
+	}
+
+	return classSelectorBestEffort
+}
+
+// qosMarker will mark the packets sent on the connection with the class
+// selector for each frame, where the socket option is only changed when
+// the class selector differs from the previous packet.
+type qosMarker struct {
+	conn *net.UDPConn
+	// tos is the class selector currently set, where -1 is not set.
+	tos int
+}
+
+// mark will set the class selector of the next packets sent.
+func (q *qosMarker) mark(tos int) {
+	if tos == q.tos {
+		return
+	}
+	if err := setClassSelector(q.conn, tos); err != nil {
+		log.Printf("error: %v\n", err)
+		return
+	}
+	q.tos = tos
+}
@@ -0,0 +1,24 @@
+package parrotbebop
+
+import "testing"
+
+func TestClassSelectorFor(t *testing.T) {
+	u := newUdpPacketCreator()
+
+	tests := []struct {
+		name string
+		p    networkUDPPacket
+		want int
+	}{
+		{"pcmd", u.encodeCmd(Command(PilotingPCMD), &Ardrone3PilotingPCMDArguments{}), classSelectorCommands},
+		{"emergency", u.encodeCmd(Command(PilotingEmergency), &Ardrone3PilotingEmergencyArguments{}), classSelectorCommands},
+		{"camera", u.encodeCmd(Command(CameraOrientationV2), &Ardrone3CameraOrientationV2Arguments{}), classSelectorBestEffort},
+		{"takeoff", u.encodeCmd(Command(PilotingTakeOff), &Ardrone3PilotingTakeOffArguments{}), classSelectorBestEffort},
+		{"ack", u.encodeAck(bufferD2CEvents, 1), classSelectorBestEffort},
+	}
+	for _, tt := range tests {
+		if got := classSelectorFor(tt.p.data); got != tt.want {
+			t.Errorf("%v: got %#x, want %#x", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+// +build !windows
+
+package parrotbebop
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// setClassSelector will set the IP TOS field of the packets sent on
+// the connection.
+func setClassSelector(conn *net.UDPConn, tos int) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("setClassSelector: %v", err)
+	}
+
+	var serr error
+	err = raw.Control(func(fd uintptr) {
+		serr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TOS, tos)
+	})
+	if err != nil {
+		return fmt.Errorf("setClassSelector: %v", err)
+	}
+	if serr != nil {
+		return fmt.Errorf("setClassSelector: failed to set IP_TOS: %v", serr)
+	}
+
+	return nil
+}
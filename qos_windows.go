@@ -0,0 +1,11 @@
+package parrotbebop
+
+import (
+	"net"
+)
+
+// setClassSelector is not supported on windows, where setting the IP
+// TOS field is ignored by the OS unless configured with group policy.
+func setClassSelector(conn *net.UDPConn, tos int) error {
+	return nil
+}
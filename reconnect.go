@@ -0,0 +1,199 @@
+package parrotbebop
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrMaxAttempts is returned by Discover when the connection with the
+// drone could not be made within the number of attempts given by the
+// ReconnectPolicy.
+var ErrMaxAttempts = errors.New("max connection attempts reached")
+
+// ReconnectAction is what to do when all the attempts to connect with
+// the drone have failed.
+type ReconnectAction int
+
+const (
+	// ReconnectForever will start over with a new round of attempts.
+	ReconnectForever ReconnectAction = iota
+	// ReconnectGiveUp will make Start return with ErrMaxAttempts.
+	ReconnectGiveUp
+	// ReconnectReturnHome will try to make the drone return home, and
+	// then continue trying to connect like ReconnectForever. Since
+	// there is no connection, the return home command is sent directly
+	// to the last known address and port of the drone, and might never
+	// arrive.
+	ReconnectReturnHome
+)
+
+// String will return the name of the action.
+func (r ReconnectAction) String() string {
+	switch r {
+	case ReconnectForever:
+		return "forever"
+	case ReconnectGiveUp:
+		return "give up"
+	case ReconnectReturnHome:
+		return "return home"
+	}
+
+	return fmt.Sprintf("ReconnectAction(%d)", int(r))
+}
+
+// ReconnectPolicy is how to retry connecting with the drone.
+type ReconnectPolicy struct {
+	// MaxAttempts is the number of discovery attempts before OnFailure
+	// is done, where 0 is no limit.
+	MaxAttempts int
+	// MinBackoff and MaxBackoff are the limits of the exponential
+	// backoff between the attempts.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// OnFailure is what to do when all the attempts have failed.
+	OnFailure ReconnectAction
+}
+
+// DefaultReconnectPolicy is the reconnect policy used if not set with
+// SetReconnectPolicy.
+var DefaultReconnectPolicy = ReconnectPolicy{
+	MaxAttempts: 20,
+	MinBackoff:  discoveryMinBackoff,
+	MaxBackoff:  discoveryMaxBackoff,
+	OnFailure:   ReconnectForever,
+}
+
+// reconnectConfig holds the reconnect policy, where the zero value uses
+// DefaultReconnectPolicy.
+type reconnectConfig struct {
+	mu     sync.Mutex
+	policy *ReconnectPolicy
+}
+
+// get will return the current policy.
+func (r *reconnectConfig) get() ReconnectPolicy {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.policy == nil {
+		return DefaultReconnectPolicy
+	}
+
+	return *r.policy
+}
+
+// SetReconnectPolicy will set how many times, and how often, to retry
+// connecting with the drone, and what to do if all the attempts fail.
+func (d *Drone) SetReconnectPolicy(p ReconnectPolicy) error {
+	if p.MaxAttempts < 0 {
+		return fmt.Errorf("SetReconnectPolicy: max attempts can't be negative, got %v", p.MaxAttempts)
+	}
+	if p.MinBackoff <= 0 || p.MaxBackoff < p.MinBackoff {
+		return fmt.Errorf("SetReconnectPolicy: backoff must be 0 < min <= max, got min %v and max %v", p.MinBackoff, p.MaxBackoff)
+	}
+	if p.OnFailure < ReconnectForever || p.OnFailure > ReconnectReturnHome {
+		return fmt.Errorf("SetReconnectPolicy: unknown action on failure: %v", p.OnFailure)
+	}
+
+	d.reconnectConfig.mu.Lock()
+	defer d.reconnectConfig.mu.Unlock()
+
+	d.reconnectConfig.policy = &p
+
+	return nil
+}
+
+// ConnectionState is a step in the life cycle of the connection.
+type ConnectionState int
+
+const (
+	// ConnectionAttempt is an attempt to connect being started.
+	ConnectionAttempt ConnectionState = iota
+	// ConnectionAttemptFailed is an attempt that failed, where the
+	// next attempt is made after the backoff.
+	ConnectionAttemptFailed
+	// ConnectionEstablished is the drone accepting the connection.
+	ConnectionEstablished
+	// ConnectionLost is an established connection being lost.
+	ConnectionLost
+	// ConnectionGaveUp is all the attempts of the ReconnectPolicy
+	// having failed.
+	ConnectionGaveUp
+)
+
+// String will return the name of the state.
+func (c ConnectionState) String() string {
+	switch c {
+	case ConnectionAttempt:
+		return "attempt"
+	case ConnectionAttemptFailed:
+		return "attempt failed"
+	case ConnectionEstablished:
+		return "established"
+	case ConnectionLost:
+		return "lost"
+	case ConnectionGaveUp:
+		return "gave up"
+	}
+
+	return fmt.Sprintf("ConnectionState(%d)", int(c))
+}
+
+// ConnectionEvent is the value of the EventConnection events.
+type ConnectionEvent struct {
+	State ConnectionState
+	// Attempt is the number of the attempt, starting at 1.
+	Attempt int
+	// Err is why the attempt failed, or the connection was given up.
+	Err error
+	// Backoff is how long until the next attempt, if failed.
+	Backoff time.Duration
+}
+
+// publishConnection will publish the connection event.
+func (d *Drone) publishConnection(ev ConnectionEvent) {
+	d.connectionState.mu.Lock()
+	d.connectionState.link = ev
+	d.connectionState.linkKnown = true
+	d.connectionState.mu.Unlock()
+
+	priority := PriorityNormal
+	if ev.State == ConnectionLost || ev.State == ConnectionGaveUp {
+		priority = PriorityHigh
+	}
+
+	d.events.publishPriority(EventConnection, priority, ev)
+}
+
+// linkUp will return true if the connection with the drone is
+// established, and not lost since.
+func (d *Drone) linkUp() bool {
+	d.connectionState.mu.Lock()
+	defer d.connectionState.mu.Unlock()
+
+	return d.connectionState.linkKnown && d.connectionState.link.State == ConnectionEstablished
+}
+
+// returnHomeDirect will send the navigate home command straight to the
+// drone on a new UDP connection, without the writer of the connection,
+// which is stopped when the connection is lost.
+func (d *Drone) returnHomeDirect() error {
+	conn, err := net.Dial("udp", d.addressDrone+":"+d.portC2D)
+	if err != nil {
+		return fmt.Errorf("returnHomeDirect: %v", err)
+	}
+	defer conn.Close()
+
+	p := d.packetCreator.encodeCmd(Command(PilotingNavigateHome), &Ardrone3PilotingNavigateHomeArguments{Start: 1})
+	if _, err := conn.Write(p.data); err != nil {
+		return fmt.Errorf("returnHomeDirect: %v", err)
+	}
+
+	log.Printf("info: sent return home to %v after failing to connect\n", conn.RemoteAddr())
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package parrotbebop
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSetReconnectPolicy(t *testing.T) {
+	d := NewDrone()
+
+	bad := []ReconnectPolicy{
+		{MaxAttempts: -1, MinBackoff: time.Second, MaxBackoff: time.Second},
+		{MinBackoff: 0, MaxBackoff: time.Second},
+		{MinBackoff: time.Second * 2, MaxBackoff: time.Second},
+		{MinBackoff: time.Second, MaxBackoff: time.Second, OnFailure: ReconnectAction(9)},
+	}
+	for _, p := range bad {
+		if err := d.SetReconnectPolicy(p); err == nil {
+			t.Fatalf("expected error for policy %+v", p)
+		}
+	}
+
+	if got := d.reconnectConfig.get(); got != DefaultReconnectPolicy {
+		t.Fatalf("policy changed by invalid policies: %+v", got)
+	}
+}
+
+func TestDiscoverMaxAttempts(t *testing.T) {
+	// A listener that closes every connection right away, so all the
+	// discovery attempts fails.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	d := NewDrone()
+	d.addressDrone = "127.0.0.1"
+	_, d.portDiscover, _ = net.SplitHostPort(l.Addr().String())
+
+	err = d.SetReconnectPolicy(ReconnectPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  time.Millisecond * 2,
+		OnFailure:   ReconnectGiveUp,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, unsubscribe := d.Subscribe()
+	defer unsubscribe()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	if err := d.Discover(ctx); !errors.Is(err, ErrMaxAttempts) {
+		t.Fatalf("expected ErrMaxAttempts, got %v", err)
+	}
+
+	var attempts, failed int
+	for done := false; !done; {
+		select {
+		case ev := <-events:
+			if ev.Type != EventConnection {
+				continue
+			}
+			switch ev.Value.(ConnectionEvent).State {
+			case ConnectionAttempt:
+				attempts++
+			case ConnectionAttemptFailed:
+				failed++
+			}
+		default:
+			done = true
+		}
+	}
+	if attempts != 3 || failed != 3 {
+		t.Fatalf("got %v attempts and %v failed events, want 3 of each", attempts, failed)
+	}
+}
@@ -0,0 +1,304 @@
+package parrotbebop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrReturnHomeUnavailable is the reason given to the RTHFallback when
+// the drone can't do a return home.
+var ErrReturnHomeUnavailable = errors.New("return home unavailable")
+
+// RTHFallback is what to do when a return home is requested, but the
+// drone can't do it, like when no home position is set or there is no
+// gps fix.
+type RTHFallback interface {
+	// Fallback will fly the drone until it have landed, or the context
+	// is cancelled. The reason is why the return home can't be done.
+	Fallback(ctx context.Context, d *Drone, reason error) error
+}
+
+// RTHFallbackFunc is a function used as an RTHFallback.
+type RTHFallbackFunc func(ctx context.Context, d *Drone, reason error) error
+
+// Fallback will call the function.
+func (f RTHFallbackFunc) Fallback(ctx context.Context, d *Drone, reason error) error {
+	return f(ctx, d, reason)
+}
+
+// RTHFallbackStage is the stage of the RTH fallback, published with
+// EventRTHFallback.
+type RTHFallbackStage int
+
+const (
+	// RTHFallbackStarted is published when the fallback is started.
+	RTHFallbackStarted RTHFallbackStage = iota
+	// RTHFallbackClimbing is the climb to the fallback altitude.
+	RTHFallbackClimbing
+	// RTHFallbackHovering is published repeatedly while hovering, as an
+	// alert for the pilot to take over.
+	RTHFallbackHovering
+	// RTHFallbackDescending is the controlled descent.
+	RTHFallbackDescending
+	// RTHFallbackLanding is published when the landing command is sent.
+	RTHFallbackLanding
+	// RTHFallbackStopped is published when the fallback ends, either by
+	// being done, failing or being cancelled.
+	RTHFallbackStopped
+)
+
+// String will return the name of the stage.
+func (s RTHFallbackStage) String() string {
+	switch s {
+	case RTHFallbackStarted:
+		return "started"
+	case RTHFallbackClimbing:
+		return "climbing"
+	case RTHFallbackHovering:
+		return "hovering"
+	case RTHFallbackDescending:
+		return "descending"
+	case RTHFallbackLanding:
+		return "landing"
+	case RTHFallbackStopped:
+		return "stopped"
+	}
+
+	return fmt.Sprintf("RTHFallbackStage(%d)", int(s))
+}
+
+// RTHFallbackEvent is the value of EventRTHFallback.
+type RTHFallbackEvent struct {
+	Stage RTHFallbackStage
+	// Reason is why the return home could not be done.
+	Reason error
+	// Err is the error of the fallback when stopped, which is nil if
+	// it ended by landing the drone.
+	Err error
+}
+
+const (
+	// rthFallbackAlertInterval is how often the hovering alert is
+	// published.
+	rthFallbackAlertInterval = time.Second
+	// rthFallbackLandAltitude in meters, is the altitude where the
+	// controlled descent ends, and the landing command is sent.
+	rthFallbackLandAltitude = 1.0
+)
+
+// ClimbHoverDescend is an RTHFallback that will climb to a safe
+// altitude to clear any obstacles, then hover while alerting the pilot
+// to take over, and at last do a controlled descent and land where it
+// is.
+type ClimbHoverDescend struct {
+	// Altitude in meters to climb to. If the drone is higher it will
+	// stay at the current altitude.
+	Altitude float64
+	// Hover is how long to hover before descending.
+	Hover time.Duration
+	// DescentRate is the speed of the descent in meters per second.
+	DescentRate float64
+}
+
+// DefaultRTHFallback is the fallback used if not set with
+// SetRTHFallback.
+var DefaultRTHFallback = ClimbHoverDescend{
+	Altitude:    20,
+	Hover:       time.Second * 10,
+	DescentRate: 0.5,
+}
+
+// Fallback will climb, hover and descend using the altitude controller,
+// which must be running.
+func (c ClimbHoverDescend) Fallback(ctx context.Context, d *Drone, reason error) error {
+	switch {
+	case c.Altitude < 0:
+		return fmt.Errorf("ClimbHoverDescend: altitude can not be negative, got %v", c.Altitude)
+	case c.Hover < 0:
+		return fmt.Errorf("ClimbHoverDescend: hover can not be negative, got %v", c.Hover)
+	case c.DescentRate <= 0:
+		return fmt.Errorf("ClimbHoverDescend: descent rate must be positive, got %v", c.DescentRate)
+	}
+
+	defer d.ClearTargetAltitude()
+
+	ticker := time.NewTicker(altitudeHoldInterval)
+	defer ticker.Stop()
+
+	// Climb, and wait for the altitude controller to reach the target.
+	target := math.Max(c.Altitude, d.telemetry.snapshot().Altitude)
+	d.publishRTHFallback(RTHFallbackEvent{Stage: RTHFallbackClimbing, Reason: reason})
+	if err := d.SetTargetAltitude(target); err != nil {
+		return err
+	}
+	for !d.TargetAltitudeReached() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	// Hover, and repeat the alert so it is not missed by the pilot.
+	alert := time.NewTicker(rthFallbackAlertInterval)
+	defer alert.Stop()
+	hoverDone := time.After(c.Hover)
+	d.publishRTHFallback(RTHFallbackEvent{Stage: RTHFallbackHovering, Reason: reason})
+hover:
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-hoverDone:
+			break hover
+		case <-alert.C:
+			d.publishRTHFallback(RTHFallbackEvent{Stage: RTHFallbackHovering, Reason: reason})
+		}
+	}
+
+	// Lower the target altitude with the descent rate, until low
+	// enough to let the drone do the landing.
+	d.publishRTHFallback(RTHFallbackEvent{Stage: RTHFallbackDescending, Reason: reason})
+	step := c.DescentRate * altitudeHoldInterval.Seconds()
+	for target > rthFallbackLandAltitude {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		target = math.Max(target-step, rthFallbackLandAltitude)
+		if err := d.SetTargetAltitude(target); err != nil {
+			return err
+		}
+	}
+
+	d.publishRTHFallback(RTHFallbackEvent{Stage: RTHFallbackLanding, Reason: reason})
+	d.ClearTargetAltitude()
+	if err := d.sendCmd(Command(PilotingLanding), &Ardrone3PilotingLandingArguments{}); err != nil {
+		return fmt.Errorf("ClimbHoverDescend: %v", err)
+	}
+
+	return nil
+}
+
+// rthFallbackConfig holds the RTH fallback, where the zero value uses
+// DefaultRTHFallback, and the cancel of the fallback running.
+type rthFallbackConfig struct {
+	mu       sync.Mutex
+	set      bool
+	fallback RTHFallback
+	cancel   context.CancelFunc
+}
+
+// SetRTHFallback will set what to do when a return home is requested
+// with ReturnHome or ActionNavigateHomeStart, but the drone can't do it.
+// Setting it to nil will disable the fallback, so the return home is
+// refused.
+func (d *Drone) SetRTHFallback(f RTHFallback) {
+	d.rthFallback.mu.Lock()
+	defer d.rthFallback.mu.Unlock()
+
+	d.rthFallback.set = true
+	d.rthFallback.fallback = f
+}
+
+// RTHFallbackActive will return true while the RTH fallback is flying
+// the drone.
+func (d *Drone) RTHFallbackActive() bool {
+	d.rthFallback.mu.Lock()
+	defer d.rthFallback.mu.Unlock()
+
+	return d.rthFallback.cancel != nil
+}
+
+// StopRTHFallback will stop the RTH fallback if running, leaving the
+// drone hovering where it is.
+func (d *Drone) StopRTHFallback() {
+	d.rthFallback.mu.Lock()
+	defer d.rthFallback.mu.Unlock()
+
+	if d.rthFallback.cancel != nil {
+		d.rthFallback.cancel()
+	}
+}
+
+// returnHomeAvailable will return an error wrapping
+// ErrReturnHomeUnavailable if the drone can't do a return home.
+func (d *Drone) returnHomeAvailable() error {
+	if !d.Home().Set {
+		return fmt.Errorf("%w: no home position set", ErrReturnHomeUnavailable)
+	}
+	if !d.telemetry.snapshot().GPSFixed {
+		return fmt.Errorf("%w: drone have no gps fix", ErrReturnHomeUnavailable)
+	}
+
+	return nil
+}
+
+// ReturnHome will make the drone return home. If the drone can't do a
+// return home the RTH fallback is started instead, and an
+// EventRTHFallback is published. An error is returned if the return
+// home can't be done, and the fallback is disabled.
+func (d *Drone) ReturnHome() error {
+	if err := d.returnHomeAvailable(); err != nil {
+		if !d.startRTHFallback(err) {
+			return fmt.Errorf("ReturnHome: %w", err)
+		}
+		return nil
+	}
+
+	return d.sendCmd(Command(PilotingNavigateHome), &Ardrone3PilotingNavigateHomeArguments{Start: 1})
+}
+
+// startRTHFallback will start the RTH fallback in it's own go routine.
+// false is returned if the fallback is disabled. If the fallback is
+// already running it is left running.
+func (d *Drone) startRTHFallback(reason error) bool {
+	d.rthFallback.mu.Lock()
+	defer d.rthFallback.mu.Unlock()
+
+	var f RTHFallback = DefaultRTHFallback
+	if d.rthFallback.set {
+		f = d.rthFallback.fallback
+	}
+	if f == nil {
+		log.Printf("error: return home not possible, and no fallback set: %v\n", reason)
+		return false
+	}
+	if d.rthFallback.cancel != nil {
+		return true
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d.rthFallback.cancel = cancel
+
+	log.Printf("warning: return home not possible, starting the fallback: %v\n", reason)
+	d.publishRTHFallback(RTHFallbackEvent{Stage: RTHFallbackStarted, Reason: reason})
+
+	go func() {
+		err := f.Fallback(ctx, d, reason)
+		if err != nil {
+			log.Printf("error: RTH fallback stopped: %v\n", err)
+		}
+
+		d.rthFallback.mu.Lock()
+		d.rthFallback.cancel = nil
+		d.rthFallback.mu.Unlock()
+		cancel()
+
+		d.publishRTHFallback(RTHFallbackEvent{Stage: RTHFallbackStopped, Reason: reason, Err: err})
+	}()
+
+	return true
+}
+
+// publishRTHFallback will publish the stage of the RTH fallback as a
+// high priority event.
+func (d *Drone) publishRTHFallback(ev RTHFallbackEvent) {
+	d.events.publishPriority(EventRTHFallback, PriorityHigh, ev)
+}
@@ -0,0 +1,118 @@
+package parrotbebop
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReturnHomeFallback(t *testing.T) {
+	d := NewDrone()
+
+	reasons := make(chan error, 1)
+	d.SetRTHFallback(RTHFallbackFunc(func(ctx context.Context, d *Drone, reason error) error {
+		reasons <- reason
+		return nil
+	}))
+
+	if err := d.ReturnHome(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case reason := <-reasons:
+		if !errors.Is(reason, ErrReturnHomeUnavailable) {
+			t.Fatalf("wrong reason: %v", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("fallback not started")
+	}
+
+	// With home and gps fix the navigate home is sent to the drone.
+	d.home.setPosition(60, 10, 0)
+	d.telemetry.update(func(t *Telemetry) { t.GPSFixed = true })
+	go func() {
+		if err := d.ReturnHome(); err != nil {
+			t.Error(err)
+		}
+	}()
+	select {
+	case <-d.chSendingUDPPacket:
+	case <-reasons:
+		t.Fatalf("fallback started with home available")
+	case <-time.After(time.Second * 3):
+		t.Fatalf("no navigate home sent")
+	}
+
+	d.home.setPosition(0, 0, 0)
+	d.home.mu.Lock()
+	d.home.position.Set = false
+	d.home.mu.Unlock()
+	d.SetRTHFallback(nil)
+	if err := d.ReturnHome(); !errors.Is(err, ErrReturnHomeUnavailable) {
+		t.Fatalf("expected refused return home, got %v", err)
+	}
+}
+
+func TestClimbHoverDescend(t *testing.T) {
+	d := NewDrone()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go d.startAltitudeController(ctx)
+
+	// Let the drone follow the target altitude of the controller, and
+	// record the highest altitude reached.
+	highest := make(chan float64, 1)
+	go func() {
+		var max float64
+		ticker := time.NewTicker(time.Millisecond * 10)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				highest <- max
+				return
+			case <-ticker.C:
+			}
+			d.altitudeHold.mu.Lock()
+			target, enabled := d.altitudeHold.target, d.altitudeHold.enabled
+			d.altitudeHold.mu.Unlock()
+			if !enabled {
+				continue
+			}
+			if target > max {
+				max = target
+			}
+			d.telemetry.update(func(t *Telemetry) { t.Altitude = target })
+		}
+	}()
+
+	landing := make(chan struct{})
+	go func() {
+		<-d.chSendingUDPPacket
+		close(landing)
+	}()
+
+	f := ClimbHoverDescend{Altitude: 3, Hover: time.Millisecond * 50, DescentRate: 50}
+	if err := f.Fallback(ctx, d, ErrReturnHomeUnavailable); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-landing:
+	case <-time.After(time.Second):
+		t.Fatalf("no landing sent")
+	}
+	if d.TargetAltitudeReached() {
+		t.Fatalf("altitude controller still enabled after landing")
+	}
+
+	cancel()
+	if got := <-highest; got != 3 {
+		t.Fatalf("highest altitude %v, want 3", got)
+	}
+
+	if err := (ClimbHoverDescend{Altitude: 3}).Fallback(context.Background(), d, nil); err == nil {
+		t.Fatalf("expected error for zero descent rate")
+	}
+}
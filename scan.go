@@ -0,0 +1,225 @@
+package parrotbebop
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultScanSubnet is the subnet of the drone's own access point.
+	DefaultScanSubnet = "192.168.42.0/24"
+	// bebopMDNSService is the mDNS service announced by the Bebop.
+	bebopMDNSService = "_arsdk-0901._udp.local."
+	// scanDialTimeout is how long to wait for each host when sweeping.
+	scanDialTimeout = time.Millisecond * 500
+	// scanMaxHosts is the max number of hosts swept, which is a /22.
+	scanMaxHosts = 1022
+	// scanConcurrency is the number of hosts dialed at the same time.
+	scanConcurrency = 64
+)
+
+var mdnsAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// Scan will look for Bebop drones on the local network, and return the
+// IP addresses found. The drones are found both by asking for the Bebop
+// mDNS service, and by sweeping the subnet given for hosts accepting
+// connections on the discovery port. If subnet is empty the default
+// subnet of the drone's own access point is used. Scan returns when the
+// sweep is done, or the context is done.
+//
+// The address found can be given to the drone with SetAddress, which
+// is useful when the drone is bridged into another network.
+func Scan(ctx context.Context, subnet string) ([]string, error) {
+	if subnet == "" {
+		subnet = DefaultScanSubnet
+	}
+
+	hosts, err := subnetHosts(subnet)
+	if err != nil {
+		return nil, fmt.Errorf("Scan: %v", err)
+	}
+
+	var mu sync.Mutex
+	found := make(map[string]bool)
+	add := func(ip string) {
+		mu.Lock()
+		found[ip] = true
+		mu.Unlock()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The mDNS lookup runs until the sweep is done.
+	mdnsDone := make(chan struct{})
+	go func() {
+		defer close(mdnsDone)
+		scanMDNS(ctx, add)
+	}()
+
+	sweepSubnet(ctx, hosts, add)
+	cancel()
+	<-mdnsDone
+
+	ips := make([]string, 0, len(found))
+	for ip := range found {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+
+	return ips, nil
+}
+
+// SetAddress will set the IP address of the drone to connect to, which
+// defaults to 192.168.42.1. Must be called before Start.
+func (d *Drone) SetAddress(ip string) error {
+	if net.ParseIP(ip) == nil {
+		return fmt.Errorf("SetAddress: invalid ip address: %v", ip)
+	}
+
+	d.addressDrone = ip
+
+	return nil
+}
+
+// subnetHosts will return the IPv4 addresses of the hosts in the subnet,
+// without the network and broadcast addresses.
+func subnetHosts(subnet string) ([]net.IP, error) {
+	_, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return nil, err
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	if bits != 32 {
+		return nil, fmt.Errorf("only IPv4 subnets are supported: %v", subnet)
+	}
+	size := 1 << uint(bits-ones)
+	if size-2 > scanMaxHosts {
+		return nil, fmt.Errorf("subnet too large, max %v hosts: %v", scanMaxHosts, subnet)
+	}
+
+	base := binary.BigEndian.Uint32(ipNet.IP.To4())
+	var hosts []net.IP
+	for i := 1; i < size-1; i++ {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, base+uint32(i))
+		hosts = append(hosts, ip)
+	}
+
+	return hosts, nil
+}
+
+// sweepSubnet will try to connect to the discovery port of each host,
+// and call found with the address of the hosts accepting.
+func sweepSubnet(ctx context.Context, hosts []net.IP, found func(ip string)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, scanConcurrency)
+
+	for _, ip := range hosts {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(ip string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			nd := net.Dialer{Timeout: scanDialTimeout}
+			conn, err := nd.DialContext(ctx, "tcp", net.JoinHostPort(ip, "44444"))
+			if err != nil {
+				return
+			}
+			conn.Close()
+			found(ip)
+		}(ip.String())
+	}
+
+	wg.Wait()
+}
+
+// scanMDNS will ask for the Bebop mDNS service, and call found with the
+// address of each host answering, until the context is done.
+func scanMDNS(ctx context.Context, found func(ip string)) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	if _, err := conn.WriteTo(mdnsQuery(bebopMDNSService), mdnsAddr); err != nil {
+		return
+	}
+
+	b := make([]byte, 9000)
+	for {
+		n, addr, err := conn.ReadFromUDP(b)
+		if err != nil {
+			return
+		}
+		if isMDNSAnswerFor(b[:n], bebopMDNSService) {
+			found(addr.IP.String())
+		}
+	}
+}
+
+// mdnsQuery will create a mDNS query for the PTR records of the service,
+// asking for the answer to be sent as unicast.
+func mdnsQuery(service string) []byte {
+	// Header with ID 0, no flags, and 1 question.
+	q := []byte{0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0}
+	q = append(q, dnsName(service)...)
+	// Type PTR, and class IN with the unicast response bit set.
+	q = append(q, 0, 12, 0x80, 1)
+
+	return q
+}
+
+// dnsName will encode the domain name as DNS labels.
+func dnsName(name string) []byte {
+	var b []byte
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+
+	return append(b, 0)
+}
+
+// isMDNSAnswerFor will check if the packet is a DNS response with
+// answers, which mentions the first label of the service.
+func isMDNSAnswerFor(b []byte, service string) bool {
+	if len(b) < 12 {
+		return false
+	}
+	response := b[2]&0x80 != 0
+	answers := binary.BigEndian.Uint16(b[6:8])
+	if !response || answers == 0 {
+		return false
+	}
+
+	// The names in the answer can be compressed, but the first label
+	// of the service will always be given in full at least once.
+	label := dnsName(service)
+	label = label[:label[0]+1]
+
+	return bytes.Contains(b[12:], label)
+}
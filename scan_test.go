@@ -0,0 +1,40 @@
+package parrotbebop
+
+import (
+	"testing"
+)
+
+func TestSubnetHosts(t *testing.T) {
+	hosts, err := subnetHosts("192.168.42.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 254 {
+		t.Fatalf("wrong number of hosts: %v", len(hosts))
+	}
+	if hosts[0].String() != "192.168.42.1" || hosts[253].String() != "192.168.42.254" {
+		t.Fatalf("wrong first or last host: %v, %v", hosts[0], hosts[253])
+	}
+
+	if _, err := subnetHosts("10.0.0.0/8"); err == nil {
+		t.Fatalf("expected error for too large subnet")
+	}
+}
+
+func TestMDNSAnswer(t *testing.T) {
+	q := mdnsQuery(bebopMDNSService)
+	if isMDNSAnswerFor(q, bebopMDNSService) {
+		t.Fatalf("query should not be taken as an answer")
+	}
+
+	// Make the query into a response with one answer.
+	resp := append([]byte{}, q...)
+	resp[2] = 0x84
+	resp[7] = 1
+	if !isMDNSAnswerFor(resp, bebopMDNSService) {
+		t.Fatalf("response not detected as answer")
+	}
+	if isMDNSAnswerFor(resp, "_arsdk-090c._udp.local.") {
+		t.Fatalf("response detected as answer for other service")
+	}
+}
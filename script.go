@@ -0,0 +1,412 @@
+package parrotbebop
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// scriptPollInterval is how often the conditions of a script are
+	// checked while waiting.
+	scriptPollInterval = time.Millisecond * 100
+	// scriptWaitTimeout is the default timeout for the wait statement.
+	scriptWaitTimeout = time.Minute
+	// scriptStateTimeout is how long takeoff and land waits for the
+	// drone to reach the new flying state.
+	scriptStateTimeout = time.Second * 30
+	// scriptMoveToTimeout is how long moveto waits for the drone to
+	// reach the position.
+	scriptMoveToTimeout = time.Minute * 5
+	// scriptMoveToTolerance and scriptMoveToAltTolerance are how close
+	// in meters the drone must be for a position to be reached.
+	scriptMoveToTolerance    = 2.0
+	scriptMoveToAltTolerance = 1.0
+)
+
+// Script is a parsed mission script, which can be run with RunScript.
+//
+// A script have one statement per line, and everything after # is a
+// comment. The statements are:
+//
+//  takeoff                     take off, and wait until hovering.
+//  land                        land, and wait until landed.
+//  hover                       stop moving.
+//  home                        start the return home.
+//  emergency                   cut the motors.
+//  moveto <lat> <lon> <alt>    fly to the position, and wait until reached.
+//  climb <alt>                 hold the altitude given, and wait until reached.
+//  rotate <heading>            turn to the heading given in degrees.
+//  photo                       take a picture.
+//  flip front|back|right|left  do a flip.
+//  sleep <duration>            wait for the duration, like 2s.
+//  wait <cond> [timeout]       wait until the condition is true.
+//  log <text>                  write the text to the log.
+//  repeat <n> ... end          run the statements n times.
+//  if <cond> ... end           run the statements if the condition is true.
+//
+// A condition is "<field> <op> <value>", where the op is one of ==, !=,
+// <, <=, > or >=, and the field is one of altitude, battery,
+// satellites, latitude, longitude, heading or state, where the value
+// of state is a flying state like hovering.
+type Script struct {
+	stmts []scriptStmt
+}
+
+// scriptStmt is a single statement of a script, where body holds the
+// statements of a block.
+type scriptStmt struct {
+	line int
+	cmd  string
+	args []string
+	body []scriptStmt
+}
+
+// scriptArgs holds the number of arguments allowed for each statement,
+// as min and max.
+var scriptArgs = map[string][2]int{
+	"takeoff":   {0, 0},
+	"land":      {0, 0},
+	"hover":     {0, 0},
+	"home":      {0, 0},
+	"emergency": {0, 0},
+	"moveto":    {3, 3},
+	"climb":     {1, 1},
+	"rotate":    {1, 1},
+	"photo":     {0, 0},
+	"flip":      {1, 1},
+	"sleep":     {1, 1},
+	"wait":      {3, 4},
+	"log":       {0, math.MaxInt32},
+	"repeat":    {1, 1},
+	"if":        {3, 3},
+}
+
+// scriptFlips are the directions of the flip statement.
+var scriptFlips = map[string]FlipDirection{
+	"front": FlipFront,
+	"back":  FlipBack,
+	"right": FlipRight,
+	"left":  FlipLeft,
+}
+
+// ParseScript will parse the script read from r. All the statements
+// and their arguments are checked, so errors are found before the
+// script is run.
+func ParseScript(r io.Reader) (*Script, error) {
+	scanner := bufio.NewScanner(r)
+
+	// stack holds the blocks being parsed, where the first is the
+	// top level of the script.
+	stack := []*scriptStmt{{}}
+	lineNr := 0
+
+	for scanner.Scan() {
+		lineNr++
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i != -1 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		stmt := scriptStmt{line: lineNr, cmd: strings.ToLower(fields[0]), args: fields[1:]}
+
+		if stmt.cmd == "end" {
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("ParseScript: line %v: end without repeat or if", lineNr)
+			}
+			block := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			parent := stack[len(stack)-1]
+			parent.body = append(parent.body, *block)
+			continue
+		}
+
+		if err := checkScriptStmt(stmt); err != nil {
+			return nil, fmt.Errorf("ParseScript: line %v: %v", lineNr, err)
+		}
+
+		if stmt.cmd == "repeat" || stmt.cmd == "if" {
+			stack = append(stack, &stmt)
+			continue
+		}
+
+		top := stack[len(stack)-1]
+		top.body = append(top.body, stmt)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ParseScript: %v", err)
+	}
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("ParseScript: line %v: %v without end", stack[len(stack)-1].line, stack[len(stack)-1].cmd)
+	}
+
+	return &Script{stmts: stack[0].body}, nil
+}
+
+// checkScriptStmt will check the statement and it's arguments.
+func checkScriptStmt(s scriptStmt) error {
+	n, ok := scriptArgs[s.cmd]
+	if !ok {
+		return fmt.Errorf("unknown statement: %v", s.cmd)
+	}
+	if len(s.args) < n[0] || len(s.args) > n[1] {
+		return fmt.Errorf("%v: wrong number of arguments: %v", s.cmd, len(s.args))
+	}
+
+	switch s.cmd {
+	case "moveto":
+		for _, a := range s.args {
+			if _, err := strconv.ParseFloat(a, 64); err != nil {
+				return fmt.Errorf("moveto: bad number: %v", a)
+			}
+		}
+	case "climb", "rotate":
+		if _, err := strconv.ParseFloat(s.args[0], 64); err != nil {
+			return fmt.Errorf("%v: bad number: %v", s.cmd, s.args[0])
+		}
+	case "flip":
+		if _, ok := scriptFlips[s.args[0]]; !ok {
+			return fmt.Errorf("flip: unknown direction: %v", s.args[0])
+		}
+	case "sleep":
+		if _, err := time.ParseDuration(s.args[0]); err != nil {
+			return fmt.Errorf("sleep: bad duration: %v", s.args[0])
+		}
+	case "repeat":
+		if n, err := strconv.Atoi(s.args[0]); err != nil || n < 0 {
+			return fmt.Errorf("repeat: bad count: %v", s.args[0])
+		}
+	case "wait", "if":
+		if _, err := evalScriptCond(Telemetry{}, "", s.args[:3]); err != nil {
+			return err
+		}
+		if len(s.args) == 4 {
+			if _, err := time.ParseDuration(s.args[3]); err != nil {
+				return fmt.Errorf("wait: bad timeout: %v", s.args[3])
+			}
+		}
+	}
+
+	return nil
+}
+
+// RunScript will run the script, and return when all the statements
+// are done, a statement fails, or the context is done.
+func (d *Drone) RunScript(ctx context.Context, s *Script) error {
+	if err := d.runScriptStmts(ctx, s.stmts); err != nil {
+		return fmt.Errorf("RunScript: %v", err)
+	}
+
+	return nil
+}
+
+// runScriptStmts will run the statements in order.
+func (d *Drone) runScriptStmts(ctx context.Context, stmts []scriptStmt) error {
+	for _, s := range stmts {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := d.runScriptStmt(ctx, s); err != nil {
+			return fmt.Errorf("line %v: %v: %v", s.line, s.cmd, err)
+		}
+	}
+
+	return nil
+}
+
+// runScriptStmt will run a single statement. The arguments are already
+// checked by the parser.
+func (d *Drone) runScriptStmt(ctx context.Context, s scriptStmt) error {
+	num := func(i int) float64 {
+		v, _ := strconv.ParseFloat(s.args[i], 64)
+		return v
+	}
+	stateIs := func(states ...FlyingState) func() bool {
+		return func() bool {
+			state, _ := d.FlyingState()
+			for _, st := range states {
+				if state == st {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	switch s.cmd {
+	case "takeoff":
+		if err := d.SendAction(ActionTakeoff); err != nil {
+			return err
+		}
+		return d.scriptWaitFor(ctx, scriptStateTimeout, stateIs(FlyingStateHovering, FlyingStateFlying))
+	case "land":
+		// The altitude hold of a climb would cancel the landing.
+		d.ClearTargetAltitude()
+		if err := d.SendAction(ActionLanding); err != nil {
+			return err
+		}
+		return d.scriptWaitFor(ctx, scriptStateTimeout, stateIs(FlyingStateLanded))
+	case "hover":
+		return d.SendAction(ActionPcmdHover)
+	case "home":
+		return d.SendAction(ActionNavigateHomeStart)
+	case "emergency":
+		return d.SendAction(ActionEmergency)
+	case "moveto":
+		p := Position{Latitude: num(0), Longitude: num(1), Altitude: num(2)}
+		if err := d.moveToHeading(p, d.Telemetry().HeadingDegrees()); err != nil {
+			return err
+		}
+		return d.scriptWaitFor(ctx, scriptMoveToTimeout, func() bool {
+			return d.positionReached(p)
+		})
+	case "climb":
+		if err := d.SetTargetAltitude(num(0)); err != nil {
+			return err
+		}
+		return d.scriptWaitFor(ctx, scriptWaitTimeout, d.TargetAltitudeReached)
+	case "rotate":
+		return d.RotateTo(ctx, num(0))
+	case "photo":
+		return d.TakePicture()
+	case "flip":
+		return d.Flip(scriptFlips[s.args[0]])
+	case "sleep":
+		dur, _ := time.ParseDuration(s.args[0])
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(dur):
+		}
+	case "wait":
+		timeout := scriptWaitTimeout
+		if len(s.args) == 4 {
+			timeout, _ = time.ParseDuration(s.args[3])
+		}
+		return d.scriptWaitFor(ctx, timeout, func() bool {
+			ok, _ := d.scriptCond(s.args)
+			return ok
+		})
+	case "log":
+		log.Printf("info: script: %v\n", strings.Join(s.args, " "))
+	case "repeat":
+		n, _ := strconv.Atoi(s.args[0])
+		for i := 0; i < n; i++ {
+			if err := d.runScriptStmts(ctx, s.body); err != nil {
+				return err
+			}
+		}
+	case "if":
+		ok, err := d.scriptCond(s.args)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return d.runScriptStmts(ctx, s.body)
+		}
+	}
+
+	return nil
+}
+
+// positionReached will return true if the drone is within the moveto
+// tolerance of the position.
+func (d *Drone) positionReached(p Position) bool {
+	return withinDistance(d.Telemetry(), p, scriptMoveToTolerance, scriptMoveToAltTolerance)
+}
+
+// scriptWaitFor will wait until fn returns true, the timeout, or the
+// context is done.
+func (d *Drone) scriptWaitFor(ctx context.Context, timeout time.Duration, fn func() bool) error {
+	ticker := time.NewTicker(scriptPollInterval)
+	defer ticker.Stop()
+	deadline := time.After(timeout)
+
+	for !fn() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			return fmt.Errorf("timed out after %v", timeout)
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
+// scriptCond will evaluate the condition with the current telemetry.
+func (d *Drone) scriptCond(args []string) (bool, error) {
+	state, _ := d.FlyingState()
+
+	return evalScriptCond(d.Telemetry(), state.String(), args)
+}
+
+// evalScriptCond will evaluate the condition "<field> <op> <value>"
+// with the telemetry and flying state given.
+func evalScriptCond(t Telemetry, state string, args []string) (bool, error) {
+	field, op, value := args[0], args[1], args[2]
+
+	if field == "state" {
+		if _, ok := flyingStateNamed(value); !ok {
+			return false, fmt.Errorf("unknown flying state: %v", value)
+		}
+		switch op {
+		case "==":
+			return state == value, nil
+		case "!=":
+			return state != value, nil
+		}
+		return false, fmt.Errorf("state can only be compared with == or !=, got %v", op)
+	}
+
+	var v float64
+	switch field {
+	case "altitude":
+		v = t.Altitude
+	case "battery":
+		v = float64(t.Battery)
+	case "satellites":
+		v = float64(t.NumberOfSatellites)
+	case "latitude":
+		v = t.Position.Latitude
+	case "longitude":
+		v = t.Position.Longitude
+	case "heading":
+		v = t.HeadingDegrees()
+	default:
+		return false, fmt.Errorf("unknown field: %v", field)
+	}
+
+	want, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false, fmt.Errorf("bad number: %v", value)
+	}
+
+	switch op {
+	case "==":
+		return v == want, nil
+	case "!=":
+		return v != want, nil
+	case "<":
+		return v < want, nil
+	case "<=":
+		return v <= want, nil
+	case ">":
+		return v > want, nil
+	case ">=":
+		return v >= want, nil
+	}
+
+	return false, fmt.Errorf("unknown operator: %v", op)
+}
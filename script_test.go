@@ -0,0 +1,135 @@
+package parrotbebop
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseScript(t *testing.T) {
+	s, err := ParseScript(strings.NewReader(`
+# Photograph the corners.
+takeoff
+climb 20
+repeat 4
+	moveto 59.1 10.2 20   # corner
+	photo
+	if battery < 30
+		home
+	end
+end
+wait state == landed 10m
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.stmts) != 4 || len(s.stmts[2].body) != 3 || len(s.stmts[2].body[2].body) != 1 {
+		t.Fatalf("wrong script structure: %+v", s.stmts)
+	}
+
+	bad := []string{
+		"fly away",
+		"repeat 2\nland",
+		"end",
+		"moveto 1 2",
+		"flip sideways",
+		"wait altitude ~ 3",
+		"wait state > hovering",
+		"wait state == hoovering",
+		"sleep forever",
+	}
+	for _, b := range bad {
+		if _, err := ParseScript(strings.NewReader(b)); err == nil {
+			t.Errorf("expected error for script %q", b)
+		}
+	}
+}
+
+func TestRunScript(t *testing.T) {
+	d := NewDrone()
+	d.checkCmdFromDrone(protocolARCommands{}, CommonCommonStateBatteryStateChangedArguments{Percent: 50})
+
+	s, err := ParseScript(strings.NewReader(`
+repeat 2
+	sleep 1ms
+end
+if battery > 80
+	land
+end
+wait battery >= 50 1s
+log done
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.RunScript(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+
+	s, _ = ParseScript(strings.NewReader("wait battery > 90 50ms"))
+	if err := d.RunScript(context.Background(), s); err == nil {
+		t.Fatalf("expected wait to time out")
+	}
+}
+
+func TestRunScriptRefusedWhileLanded(t *testing.T) {
+	d := NewDrone()
+	d.setFlyingState(FlyingStateLanded)
+
+	for _, src := range []string{"moveto 59.1 10.2 20", "flip front"} {
+		s, err := ParseScript(strings.NewReader(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := d.RunScript(context.Background(), s); err == nil {
+			t.Errorf("expected %q to be refused while landed", src)
+		}
+	}
+}
+
+func TestPositionReached(t *testing.T) {
+	d := NewDrone()
+	p := Position{Latitude: 60, Longitude: 10, Altitude: 20}
+
+	// The gps altitude is above sea level, and the moveto altitude is
+	// above the take off point.
+	d.telemetry.update(func(t *Telemetry) {
+		t.Position = Position{Latitude: 60, Longitude: 10, Altitude: 300}
+		t.Altitude = 20
+	})
+	if !d.positionReached(p) {
+		t.Fatalf("position not reached at the altitude above take off")
+	}
+
+	d.telemetry.update(func(t *Telemetry) {
+		t.Altitude = 25
+	})
+	if d.positionReached(p) {
+		t.Fatalf("position reached when too high")
+	}
+}
+
+func TestRunScriptLandStopsAltitudeHold(t *testing.T) {
+	d := NewDrone()
+	d.setFlyingState(FlyingStateHovering)
+	if err := d.SetTargetAltitude(5); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		<-d.chInputActions
+		d.setFlyingState(FlyingStateLanding)
+		d.setFlyingState(FlyingStateLanded)
+	}()
+
+	s, _ := ParseScript(strings.NewReader("land"))
+	if err := d.RunScript(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+	d.altitudeHold.mu.Lock()
+	enabled := d.altitudeHold.enabled
+	d.altitudeHold.mu.Unlock()
+	if enabled {
+		t.Fatalf("expected the altitude hold to be stopped before landing")
+	}
+}
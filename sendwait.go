@@ -0,0 +1,91 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// cmdWaiters holds the callers waiting for a command from the drone.
+type cmdWaiters struct {
+	mu      sync.Mutex
+	waiters map[int]cmdWaiter
+	nextID  int
+}
+
+// cmdWaiter is a caller waiting for the command cmd, where the decoded
+// arguments are delivered on ch.
+type cmdWaiter struct {
+	cmd Command
+	ch  chan interface{}
+}
+
+// add will register a waiter for the command, and return the channel
+// the arguments are delivered on, and a function to remove the waiter.
+func (w *cmdWaiters) add(cmd Command) (<-chan interface{}, func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.waiters == nil {
+		w.waiters = make(map[int]cmdWaiter)
+	}
+	id := w.nextID
+	w.nextID++
+	ch := make(chan interface{}, 1)
+	w.waiters[id] = cmdWaiter{cmd: cmd, ch: ch}
+
+	return ch, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		delete(w.waiters, id)
+	}
+}
+
+// notify will deliver the arguments to the waiters for the command.
+func (w *cmdWaiters) notify(cmd Command, cmdArgs interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, wt := range w.waiters {
+		if wt.cmd != cmd {
+			continue
+		}
+		select {
+		case wt.ch <- cmdArgs:
+		default:
+		}
+	}
+}
+
+// commandOf will return the Command for the project, class and command
+// of the decoded frame.
+func commandOf(p protocolARCommands) Command {
+	return Command{
+		Project: ProjectDef(p.project),
+		Class:   ClassDef(p.class),
+		Cmd:     CmdDef(p.command),
+	}
+}
+
+// SendAndWait will send the command to the drone, and wait until the
+// drone sends the command expect, like sending the flat trim and
+// waiting for the flat trim changed event. The decoded arguments of the
+// expected command are returned, or an error if not received within
+// the timeout.
+func (d *Drone) SendAndWait(cmd Command, args Encoder, expect Command, timeout time.Duration) (interface{}, error) {
+	// Start waiting before sending, so a fast answer is not lost.
+	ch, remove := d.cmdWaiters.add(expect)
+	defer remove()
+
+	if err := d.sendCmd(cmd, args); err != nil {
+		return nil, fmt.Errorf("SendAndWait: %v", err)
+	}
+
+	select {
+	case v := <-ch:
+		return v, nil
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("SendAndWait: timed out after %v waiting for %+v", timeout, expect)
+	}
+}
@@ -0,0 +1,29 @@
+package parrotbebop
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSendAndWait(t *testing.T) {
+	d := NewDrone()
+
+	// Answer each command sent with the flat trim changed event, like
+	// the drone does when the flat trim is done.
+	go func() {
+		for range d.chSendingUDPPacket {
+			c := flatTrimChangedCommand
+			d.checkCmdFromDrone(protocolARCommands{project: int(c.Project), class: int(c.Class), command: int(c.Cmd)}, flatTrimArguments{})
+		}
+	}()
+
+	if err := d.FlatTrimAndWait(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// Waiting for a command never sent should time out.
+	other := Command{Project: ProjectArdrone3, Class: Ardrone3PilotingSettingsStateClassPilotingSettingsState, Cmd: Ardrone3PilotingSettingsStateCmdMaxAltitudeChanged}
+	if _, err := d.SendAndWait(flatTrimCommand, flatTrimArguments{}, other, time.Millisecond*50); err == nil {
+		t.Fatal("expected timeout")
+	}
+}
@@ -0,0 +1,186 @@
+package parrotbebop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// shutdownHomeRadius is how close in meters the drone must be to home
+// before Shutdown lands it after a return home, since the drone hovers
+// above home when the return home is done.
+const shutdownHomeRadius = 10
+
+// HealthStatus is the health of the driver, as served by the health
+// endpoints.
+type HealthStatus struct {
+	// Healthy is false when the driver have given up connecting with
+	// the drone, and should be restarted.
+	Healthy bool
+	// Ready is true when the connection with the drone is ready for
+	// taking commands.
+	Ready bool
+	// Link is the last state of the connection, or "unknown" before the
+	// first attempt to connect.
+	Link    string
+	Attempt int
+	// LinkError is why the last attempt failed, or the connection was
+	// given up.
+	LinkError   string `json:",omitempty"`
+	FlyingState string
+}
+
+// HealthStatus will return the health of the driver.
+func (d *Drone) HealthStatus() HealthStatus {
+	d.connectionState.mu.Lock()
+	link, known := d.connectionState.link, d.connectionState.linkKnown
+	d.connectionState.mu.Unlock()
+
+	h := HealthStatus{
+		Healthy:     !known || link.State != ConnectionGaveUp,
+		Ready:       d.Ready(),
+		Link:        "unknown",
+		FlyingState: "unknown",
+	}
+	if known {
+		h.Link = link.State.String()
+		h.Attempt = link.Attempt
+		if link.Err != nil {
+			h.LinkError = link.Err.Error()
+		}
+		// The connection can be ready from before the connection was
+		// lost, until the next is made.
+		h.Ready = h.Ready && link.State == ConnectionEstablished
+	}
+	if state, ok := d.FlyingState(); ok {
+		h.FlyingState = state.String()
+	}
+
+	return h
+}
+
+// HealthHandler will return a http handler with the health endpoints
+// for running the driver as a service, where the status is given as a
+// JSON HealthStatus.
+//
+//  /healthz : 200 while the driver is connecting or connected with the
+//             drone, and 503 when it have given up connecting.
+//  /readyz  : 200 when the connection is ready for taking commands,
+//             and 503 if not.
+func (d *Drone) HealthHandler() http.Handler {
+	serve := func(w http.ResponseWriter, ok bool) {
+		w.Header().Set("Content-Type", "application/json")
+		if !ok {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(w).Encode(d.HealthStatus()); err != nil {
+			log.Printf("error: HealthHandler: %v\n", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, d.HealthStatus().Healthy)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, d.HealthStatus().Ready)
+	})
+
+	return mux
+}
+
+// ShutdownAction is what Shutdown does with a drone still flying.
+type ShutdownAction int
+
+const (
+	// ShutdownLand will land the drone where it is.
+	ShutdownLand ShutdownAction = iota
+	// ShutdownReturnHome will return home, and land when home is
+	// reached. If the return home can't be done the drone is landed
+	// where it is, unless the RTH fallback is started, which lands the
+	// drone itself.
+	ShutdownReturnHome
+)
+
+// String will return the name of the action.
+func (a ShutdownAction) String() string {
+	switch a {
+	case ShutdownLand:
+		return "land"
+	case ShutdownReturnHome:
+		return "home"
+	}
+
+	return fmt.Sprintf("ShutdownAction(%d)", int(a))
+}
+
+// Shutdown will bring the drone safely to the ground before the driver
+// is stopped, like when the service is asked to stop. It returns when
+// the drone have landed, or at once if it is not flying. An error is
+// returned if the context is done before the drone have landed.
+func (d *Drone) Shutdown(ctx context.Context, action ShutdownAction) error {
+	if state, ok := d.FlyingState(); !ok || !state.Airborne() {
+		return nil
+	}
+
+	// Subscribe before sending the commands, so the landing is not
+	// missed.
+	events, unsubscribe := d.events.subscribe()
+	defer unsubscribe()
+
+	// A positive Gaz from the altitude hold cancels the landing, and
+	// fights the return home.
+	d.ClearTargetAltitude()
+
+	landingSent := false
+	land := func() {
+		if landingSent {
+			return
+		}
+		log.Printf("info: shutdown: landing the drone\n")
+		if err := d.SendAction(ActionLanding); err != nil {
+			log.Printf("error: shutdown: %v\n", err)
+			return
+		}
+		landingSent = true
+	}
+
+	switch action {
+	case ShutdownReturnHome:
+		log.Printf("info: shutdown: returning home before landing\n")
+		if err := d.ReturnHome(); err != nil {
+			log.Printf("warning: shutdown: %v\n", err)
+			land()
+		}
+	default:
+		land()
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		if state, ok := d.FlyingState(); ok && !state.Airborne() {
+			log.Printf("info: shutdown: drone have landed\n")
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("Shutdown: drone have not landed: %v", ctx.Err())
+		case <-events:
+		case <-ticker.C:
+			if !landingSent && !d.RTHFallbackActive() {
+				// Land when the return home is done.
+				h := d.Home()
+				home := Position{Latitude: h.Latitude, Longitude: h.Longitude}
+				if !h.Set || home.DistanceTo(d.Telemetry().Position) <= shutdownHomeRadius {
+					land()
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package parrotbebop
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthHandler(t *testing.T) {
+	d := NewDrone()
+	h := d.HealthHandler()
+
+	get := func(path string) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec.Code
+	}
+
+	if get("/healthz") != http.StatusOK || get("/readyz") != http.StatusServiceUnavailable {
+		t.Fatalf("expected healthy and not ready before connecting")
+	}
+
+	d.publishConnection(ConnectionEvent{State: ConnectionEstablished, Attempt: 1})
+	d.setReady(true)
+	if get("/readyz") != http.StatusOK {
+		t.Fatalf("expected ready when connected: %+v", d.HealthStatus())
+	}
+
+	// Ready from before should not count while the connection is lost.
+	d.publishConnection(ConnectionEvent{State: ConnectionLost})
+	if get("/readyz") != http.StatusServiceUnavailable {
+		t.Fatalf("expected not ready when the connection is lost")
+	}
+
+	d.publishConnection(ConnectionEvent{State: ConnectionGaveUp, Err: errors.New("no route")})
+	if get("/healthz") != http.StatusServiceUnavailable {
+		t.Fatalf("expected unhealthy when given up")
+	}
+	if s := d.HealthStatus(); s.Link != "gave up" || s.LinkError != "no route" {
+		t.Fatalf("got %+v", s)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	d := NewDrone()
+
+	// Nothing to do when not flying.
+	if err := d.Shutdown(context.Background(), ShutdownLand); err != nil {
+		t.Fatal(err)
+	}
+
+	d.setFlyingState(FlyingStateHovering)
+	if err := d.SetTargetAltitude(5); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		if a := <-d.chInputActions; a != ActionLanding {
+			t.Errorf("got action %v, want landing", a)
+		}
+		d.setFlyingState(FlyingStateLanded)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if err := d.Shutdown(ctx, ShutdownLand); err != nil {
+		t.Fatal(err)
+	}
+	d.altitudeHold.mu.Lock()
+	enabled := d.altitudeHold.enabled
+	d.altitudeHold.mu.Unlock()
+	if enabled {
+		t.Fatalf("expected the altitude hold to be stopped when landing")
+	}
+
+	// The drone never landing should time out.
+	d.setFlyingState(FlyingStateHovering)
+	go func() { <-d.chInputActions }()
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	if err := d.Shutdown(ctx, ShutdownLand); err == nil {
+		t.Fatalf("expected error when not landing")
+	}
+}
@@ -0,0 +1,134 @@
+package parrotbebop
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// SettingChange is the value of a setting reported by the drone, where
+// Name is the name of the command reporting it, like
+// "Ardrone3PilotingSettingsStateMaxAltitudeChanged", and Value the
+// decoded arguments, like Ardrone3PilotingSettingsStateMaxAltitudeChangedArguments.
+type SettingChange struct {
+	Name  string
+	Value interface{}
+}
+
+// settingsStore holds the last value of each setting reported by the
+// drone.
+type settingsStore struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+}
+
+// settingName will return the name of the setting if the decoded
+// arguments are from one of the *SettingsState*Changed commands.
+func settingName(cmdArgs interface{}) (string, bool) {
+	if cmdArgs == nil {
+		return "", false
+	}
+
+	name := reflect.TypeOf(cmdArgs).Name()
+	if !strings.Contains(name, "SettingsState") || !strings.HasSuffix(name, "ChangedArguments") {
+		return "", false
+	}
+
+	return strings.TrimSuffix(name, "Arguments"), true
+}
+
+// handleSetting will store the setting if the decoded arguments are
+// a setting, and publish an EventSettingChanged.
+func (d *Drone) handleSetting(cmdArgs interface{}) {
+	name, ok := settingName(cmdArgs)
+	if !ok {
+		return
+	}
+
+	d.settings.mu.Lock()
+	if d.settings.values == nil {
+		d.settings.values = make(map[string]interface{})
+	}
+	d.settings.values[name] = cmdArgs
+	d.settings.mu.Unlock()
+
+	d.events.publish(EventSettingChanged, SettingChange{Name: name, Value: cmdArgs})
+}
+
+// Setting will return the last value reported by the drone for the
+// setting with the name given, like
+// "Ardrone3PilotingSettingsStateMaxAltitudeChanged", where the value is
+// the decoded arguments of the command.
+func (d *Drone) Setting(name string) (interface{}, bool) {
+	d.settings.mu.Lock()
+	defer d.settings.mu.Unlock()
+
+	v, ok := d.settings.values[name]
+
+	return v, ok
+}
+
+// Settings will return a copy of the last values of all the settings
+// reported by the drone, by name.
+func (d *Drone) Settings() map[string]interface{} {
+	d.settings.mu.Lock()
+	defer d.settings.mu.Unlock()
+
+	c := make(map[string]interface{}, len(d.settings.values))
+	for k, v := range d.settings.values {
+		c[k] = v
+	}
+
+	return c
+}
+
+// WatchSetting will wait until the drone reports a value for the
+// setting where match returns true, or the context is done, and
+// return the value. The current value is checked first, so it returns
+// at once if the setting already matches. A nil match will wait for
+// the next value reported, which can be used to verify that a setting
+// sent to the drone took effect, like:
+//
+//	d.SetMaxAltitude(50)
+//	v, err := d.WatchSetting(ctx, "Ardrone3PilotingSettingsStateMaxAltitudeChanged", func(v interface{}) bool {
+//		return v.(Ardrone3PilotingSettingsStateMaxAltitudeChangedArguments).Current == 50
+//	})
+func (d *Drone) WatchSetting(ctx context.Context, name string, match func(v interface{}) bool) (interface{}, error) {
+	// Subscribe before checking the current value, so a change in
+	// between is not lost.
+	events, unsubscribe := d.events.subscribe()
+	defer unsubscribe()
+
+	if match != nil {
+		if v, ok := d.Setting(name); ok && match(v) {
+			return v, nil
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("WatchSetting: %v: %v", name, ctx.Err())
+		case ev, ok := <-events:
+			if !ok {
+				return nil, fmt.Errorf("WatchSetting: %v: event subscription closed", name)
+			}
+			if ev.Type != EventSettingChanged {
+				continue
+			}
+			s := ev.Value.(SettingChange)
+			if s.Name == name && (match == nil || match(s.Value)) {
+				return s.Value, nil
+			}
+		}
+	}
+}
+
+// SetMaxAltitude will set the max altitude in meters the drone is
+// allowed to fly, which is reported back in the setting
+// "Ardrone3PilotingSettingsStateMaxAltitudeChanged".
+func (d *Drone) SetMaxAltitude(meters float32) error {
+	return d.sendCmd(Command(PilotingSettingsMaxAltitude), &Ardrone3PilotingSettingsMaxAltitudeArguments{Current: meters})
+}
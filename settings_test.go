@@ -0,0 +1,70 @@
+package parrotbebop
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSettingName(t *testing.T) {
+	tests := []struct {
+		args interface{}
+		name string
+		ok   bool
+	}{
+		{Ardrone3PilotingSettingsStateMaxAltitudeChangedArguments{}, "Ardrone3PilotingSettingsStateMaxAltitudeChanged", true},
+		{Ardrone3PilotingStateAttitudeChangedArguments{}, "", false},
+		{Ardrone3PilotingSettingsMaxAltitudeArguments{}, "", false},
+		{nil, "", false},
+	}
+
+	for _, tt := range tests {
+		name, ok := settingName(tt.args)
+		if name != tt.name || ok != tt.ok {
+			t.Errorf("%T: got %q %v, want %q %v", tt.args, name, ok, tt.name, tt.ok)
+		}
+	}
+}
+
+func TestWatchSetting(t *testing.T) {
+	d := NewDrone()
+	const name = "Ardrone3PilotingSettingsStateMaxAltitudeChanged"
+
+	d.checkCmdFromDrone(protocolARCommands{}, Ardrone3PilotingSettingsStateMaxAltitudeChangedArguments{Current: 30, Min: 1, Max: 150})
+	v, ok := d.Setting(name)
+	if !ok || v.(Ardrone3PilotingSettingsStateMaxAltitudeChangedArguments).Current != 30 {
+		t.Fatalf("setting not stored: %v %v", v, ok)
+	}
+
+	is := func(alt float32) func(v interface{}) bool {
+		return func(v interface{}) bool {
+			return v.(Ardrone3PilotingSettingsStateMaxAltitudeChangedArguments).Current == alt
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// The current value matches at once.
+	if _, err := d.WatchSetting(ctx, name, is(30)); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		d.checkCmdFromDrone(protocolARCommands{}, Ardrone3PilotingSettingsStateMaxAltitudeChangedArguments{Current: 40, Min: 1, Max: 150})
+		d.checkCmdFromDrone(protocolARCommands{}, Ardrone3PilotingSettingsStateMaxAltitudeChangedArguments{Current: 50, Min: 1, Max: 150})
+	}()
+	if _, err := d.WatchSetting(ctx, name, is(50)); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Settings()) != 1 {
+		t.Fatalf("expected 1 setting, got %v", d.Settings())
+	}
+
+	short, cancelShort := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancelShort()
+	if _, err := d.WatchSetting(short, name, is(60)); err == nil {
+		t.Fatal("expected timeout")
+	}
+}
@@ -0,0 +1,343 @@
+package parrotbebop
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// TrafficShaping is the rate limits of the frames sent to the drone, so
+// bursts of commands can't starve the pongs and acks on the WiFi link.
+// The pongs, acks, emergency and video acks are never limited, and are
+// always sent before the other frames, as are the frames of the non ack
+// buffer that are not PCMD or camera commands.
+type TrafficShaping struct {
+	// PCMDRate is the max rate in Hz of the PCMD frames, where 0 is not
+	// limited. PCMDs given faster are coalesced, so only the latest is
+	// sent.
+	PCMDRate float64
+	// CameraRate is the max rate in Hz of the camera orientation and
+	// velocity frames together, where 0 is not limited. Each of the
+	// camera commands are coalesced on it's own, so only the latest of
+	// each is sent.
+	CameraRate float64
+	// EventBurst is the number of frames on the ack buffer, like the
+	// actions and settings, that can be sent back to back, where 0 is
+	// not limited. The frames are never dropped, but queued in order.
+	EventBurst int
+	// EventBurstGap is the time it takes to allow a full burst again.
+	EventBurstGap time.Duration
+	// PackFrames will send the frames ready at the same time in a
+	// single UDP packet, as the ARNetworkAL allows, which lowers the
+	// packet rate on the WiFi link.
+	PackFrames bool
+	// PackDelay is how long the frames can be held back waiting for
+	// more frames to pack with, where 0 only packs the frames ready at
+	// the same time. The pongs, acks and emergency are never held back.
+	PackDelay time.Duration
+}
+
+// DefaultTrafficShaping is the traffic shaping used if not set with
+// SetTrafficShaping.
+var DefaultTrafficShaping = TrafficShaping{
+	PCMDRate:      40,
+	CameraRate:    10,
+	EventBurst:    10,
+	EventBurstGap: time.Millisecond * 100,
+}
+
+// trafficShapingConfig holds the traffic shaping, where the zero value
+// uses DefaultTrafficShaping.
+type trafficShapingConfig struct {
+	mu      sync.Mutex
+	shaping *TrafficShaping
+}
+
+// get will return the current traffic shaping.
+func (c *trafficShapingConfig) get() TrafficShaping {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.shaping == nil {
+		return DefaultTrafficShaping
+	}
+
+	return *c.shaping
+}
+
+// SetTrafficShaping will set the rate limits of the frames sent to the
+// drone. Setting the zero value will turn off the shaping.
+func (d *Drone) SetTrafficShaping(s TrafficShaping) error {
+	switch {
+	case s.PCMDRate < 0 || s.CameraRate < 0:
+		return fmt.Errorf("SetTrafficShaping: rates can't be negative, got %v and %v", s.PCMDRate, s.CameraRate)
+	case s.EventBurst < 0:
+		return fmt.Errorf("SetTrafficShaping: event burst can't be negative, got %v", s.EventBurst)
+	case s.EventBurst > 0 && s.EventBurstGap <= 0:
+		return fmt.Errorf("SetTrafficShaping: event burst gap must be above 0 when limiting the events, got %v", s.EventBurstGap)
+	case s.PackDelay < 0 || s.PackDelay > maxPackDelay:
+		return fmt.Errorf("SetTrafficShaping: pack delay must be within [0, %v], got %v", maxPackDelay, s.PackDelay)
+	}
+
+	d.trafficShaping.mu.Lock()
+	defer d.trafficShaping.mu.Unlock()
+
+	d.trafficShaping.shaping = &s
+
+	return nil
+}
+
+const (
+	// maxPackedSize is the max size of an UDP packet with packed
+	// frames, which is the UDP payload fitting in an ethernet MTU of
+	// 1500 bytes.
+	maxPackedSize = 1472
+	// maxPackDelay is the longest the frames can be held back for
+	// packing, since the PCMDs must reach the drone in time.
+	maxPackDelay = time.Millisecond * 20
+)
+
+// isControlFrame will return true if the frame is one of the frames
+// never limited, like the pongs, acks and emergency.
+func isControlFrame(data []byte) bool {
+	return len(data) < 2 || (int(data[1]) != bufferC2DNonAck && int(data[1]) != bufferC2DAck)
+}
+
+// packFrames will pack the frames in order into as few UDP packets as
+// possible, where each packet is at most maxPackedSize bytes. With
+// byClass the packets only hold frames of the same QoS class, so they
+// can be marked.
+func packFrames(ps []networkUDPPacket, byClass bool) []networkUDPPacket {
+	var packed []networkUDPPacket
+	for _, p := range ps {
+		if n := len(packed); n > 0 {
+			last := &packed[n-1]
+			sameClass := !byClass || classSelectorFor(last.data) == classSelectorFor(p.data)
+			if len(last.data)+len(p.data) <= maxPackedSize && sameClass {
+				last.data = append(last.data, p.data...)
+				last.size = len(last.data)
+				continue
+			}
+		}
+		data := append([]byte(nil), p.data...)
+		packed = append(packed, networkUDPPacket{size: len(data), data: data})
+	}
+
+	return packed
+}
+
+// tokenBucket is a rate limiter allowing a burst of frames, and then
+// frames at the rate, where a rate of 0 is not limited.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// configure will set the rate and burst, keeping the tokens already
+// collected within the new burst.
+func (b *tokenBucket) configure(rate float64, burst float64) {
+	b.rate = rate
+	b.burst = burst
+	b.tokens = math.Min(b.tokens, burst)
+}
+
+// refill will add the tokens collected since the last refill.
+func (b *tokenBucket) refill(now time.Time) {
+	if !b.last.IsZero() {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	} else {
+		b.tokens = b.burst
+	}
+	b.last = now
+}
+
+// take will return true, and use a token, if a frame can be sent now.
+func (b *tokenBucket) take(now time.Time) bool {
+	if b.rate == 0 {
+		return true
+	}
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+
+	return true
+}
+
+// wait will return the time until a frame can be sent.
+func (b *tokenBucket) wait(now time.Time) time.Duration {
+	if b.rate == 0 {
+		return 0
+	}
+	b.refill(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// pcmdCmdBytes is the command part of a PCMD frame, used for telling
+// the PCMD frames from the camera frames on the non ack buffer.
+var pcmdCmdBytes = convertCMDToBytes(Command(PilotingPCMD))
+
+// isPcmdFrame will return true if the frame is a PCMD.
+func isPcmdFrame(data []byte) bool {
+	return len(data) >= 7+len(pcmdCmdBytes) && bytes.Equal(data[7:7+len(pcmdCmdBytes)], pcmdCmdBytes)
+}
+
+// cameraCmdBytes are the command part of the camera frames limited by
+// the camera rate.
+var cameraCmdBytes = [][]byte{
+	convertCMDToBytes(Command(CameraOrientation)),
+	convertCMDToBytes(Command(CameraOrientationV2)),
+	convertCMDToBytes(Command(CameraVelocity)),
+}
+
+// frameCmdBytes will return the command part of the frame, or nil if
+// the frame is too short to hold a command.
+func frameCmdBytes(data []byte) []byte {
+	if len(data) < frameHeaderSize+cmdHeaderSize {
+		return nil
+	}
+
+	return data[frameHeaderSize : frameHeaderSize+cmdHeaderSize]
+}
+
+// isCameraFrame will return true if the frame is one of the camera
+// commands.
+func isCameraFrame(data []byte) bool {
+	c := frameCmdBytes(data)
+	for _, b := range cameraCmdBytes {
+		if c != nil && bytes.Equal(c, b) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// trafficShaper holds the frames waiting to be sent to the drone by the
+// UDP writer.
+type trafficShaper struct {
+	// control are the frames that are never limited.
+	control []networkUDPPacket
+	// pcmd is the latest PCMD, which replaces any PCMD not yet sent.
+	pcmd *networkUDPPacket
+	// camera are the latest frame of each camera command, which
+	// replaces the frame of the same command not yet sent, in the order
+	// the commands were first added.
+	camera []networkUDPPacket
+	// events are the frames of the ack buffer in order.
+	events []networkUDPPacket
+
+	pcmdBucket   tokenBucket
+	cameraBucket tokenBucket
+	eventBucket  tokenBucket
+}
+
+// add will queue the frame for sending.
+func (s *trafficShaper) add(p networkUDPPacket) {
+	if len(p.data) < 2 || p.raw {
+		s.control = append(s.control, p)
+		return
+	}
+
+	switch int(p.data[1]) {
+	case bufferC2DNonAck:
+		switch {
+		case isPcmdFrame(p.data):
+			s.pcmd = &p
+		case isCameraFrame(p.data):
+			s.addCamera(p)
+		default:
+			// Other commands are not known to be periodic, so they
+			// can't be coalesced, and are sent as they are.
+			s.control = append(s.control, p)
+		}
+	case bufferC2DAck:
+		s.events = append(s.events, p)
+	default:
+		s.control = append(s.control, p)
+	}
+}
+
+// addCamera will replace the waiting frame of the same camera command,
+// or queue the frame if none is waiting.
+func (s *trafficShaper) addCamera(p networkUDPPacket) {
+	for i, c := range s.camera {
+		if bytes.Equal(frameCmdBytes(c.data), frameCmdBytes(p.data)) {
+			s.camera[i] = p
+			return
+		}
+	}
+
+	s.camera = append(s.camera, p)
+}
+
+// ready will return the frames that can be sent now with the shaping
+// given, where the control frames are first.
+func (s *trafficShaper) ready(shaping TrafficShaping, now time.Time) []networkUDPPacket {
+	s.configure(shaping)
+
+	ps := s.control
+	s.control = nil
+
+	if s.pcmd != nil && s.pcmdBucket.take(now) {
+		ps = append(ps, *s.pcmd)
+		s.pcmd = nil
+	}
+	for len(s.camera) > 0 && s.cameraBucket.take(now) {
+		ps = append(ps, s.camera[0])
+		s.camera = s.camera[1:]
+	}
+	for len(s.events) > 0 && s.eventBucket.take(now) {
+		ps = append(ps, s.events[0])
+		s.events = s.events[1:]
+	}
+
+	return ps
+}
+
+// next will return the time until the next frame waiting can be sent,
+// and false if no frames are waiting.
+func (s *trafficShaper) next(now time.Time) (time.Duration, bool) {
+	wait := time.Duration(math.MaxInt64)
+	found := false
+
+	check := func(waiting bool, b *tokenBucket) {
+		if !waiting {
+			return
+		}
+		found = true
+		if w := b.wait(now); w < wait {
+			wait = w
+		}
+	}
+	check(s.pcmd != nil, &s.pcmdBucket)
+	check(len(s.camera) > 0, &s.cameraBucket)
+	check(len(s.events) > 0, &s.eventBucket)
+
+	if len(s.control) > 0 {
+		return 0, true
+	}
+
+	return wait, found
+}
+
+// configure will set the rate limits of the buckets from the shaping.
+func (s *trafficShaper) configure(shaping TrafficShaping) {
+	s.pcmdBucket.configure(shaping.PCMDRate, 1)
+	s.cameraBucket.configure(shaping.CameraRate, 1)
+
+	var rate float64
+	if shaping.EventBurst > 0 {
+		rate = float64(shaping.EventBurst) / shaping.EventBurstGap.Seconds()
+	}
+	s.eventBucket.configure(rate, float64(shaping.EventBurst))
+}
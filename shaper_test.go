@@ -0,0 +1,182 @@
+package parrotbebop
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrafficShaper(t *testing.T) {
+	u := newUdpPacketCreator()
+	shaping := TrafficShaping{PCMDRate: 10, CameraRate: 10, EventBurst: 2, EventBurstGap: time.Millisecond * 100}
+	var s trafficShaper
+	now := time.Now()
+
+	pcmd := func(gaz int8) networkUDPPacket {
+		return u.encodeCmd(Command(PilotingPCMD), &Ardrone3PilotingPCMDArguments{Gaz: gaz})
+	}
+	event := func() networkUDPPacket {
+		return u.encodeCmd(Command(PilotingTakeOff), &Ardrone3PilotingTakeOffArguments{})
+	}
+
+	s.add(pcmd(1))
+	s.add(pcmd(2))
+	for i := 0; i < 3; i++ {
+		s.add(event())
+	}
+	s.add(u.encodeAck(bufferD2CEvents, 1))
+	s.add(u.encodeCmd(Command(CameraOrientationV2), &Ardrone3CameraOrientationV2Arguments{}))
+
+	// The ack is first, the PCMDs are coalesced to the latest, and only
+	// a burst of 2 events are sent.
+	got := s.ready(shaping, now)
+	if len(got) != 5 {
+		t.Fatalf("got %v frames, want 5", len(got))
+	}
+	if got[0].data[0] != dataTypeAck {
+		t.Fatalf("ack not sent first")
+	}
+	if got[1].data[len(got[1].data)-5] != 2 {
+		t.Fatalf("PCMD not coalesced to the latest: %v", got[1].data)
+	}
+
+	// The last event is sent when the bucket have refilled.
+	s.add(pcmd(3))
+	if got := s.ready(shaping, now.Add(time.Millisecond*10)); len(got) != 0 {
+		t.Fatalf("got %v frames before the rate allows, want 0", len(got))
+	}
+	wait, ok := s.next(now.Add(time.Millisecond * 10))
+	if !ok || wait <= 0 || wait > time.Millisecond*90 {
+		t.Fatalf("got wait %v %v", wait, ok)
+	}
+	if got := s.ready(shaping, now.Add(time.Millisecond*101)); len(got) != 2 {
+		t.Fatalf("got %v frames after the wait, want 2", len(got))
+	}
+	if _, ok := s.next(now.Add(time.Millisecond * 101)); ok {
+		t.Fatalf("frames still waiting")
+	}
+
+	// No shaping sends everything right away.
+	for i := 0; i < 5; i++ {
+		s.add(event())
+	}
+	if got := s.ready(TrafficShaping{}, now.Add(time.Millisecond*102)); len(got) != 5 {
+		t.Fatalf("got %v frames without shaping, want 5", len(got))
+	}
+}
+
+func TestSetTrafficShaping(t *testing.T) {
+	d := NewDrone()
+	bad := []TrafficShaping{
+		{PCMDRate: -1},
+		{EventBurst: -1},
+		{EventBurst: 5},
+		{PackDelay: -1},
+		{PackDelay: time.Second},
+	}
+	for _, s := range bad {
+		if err := d.SetTrafficShaping(s); err == nil {
+			t.Fatalf("expected error for %+v", s)
+		}
+	}
+	if err := d.SetTrafficShaping(TrafficShaping{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPackFrames(t *testing.T) {
+	u := newUdpPacketCreator()
+	pcmd := u.encodeCmd(Command(PilotingPCMD), &Ardrone3PilotingPCMDArguments{Gaz: 1})
+	camera := u.encodeCmd(Command(CameraOrientationV2), &Ardrone3CameraOrientationV2Arguments{})
+	ack := u.encodeAck(bufferD2CEvents, 1)
+	before := string(pcmd.data)
+
+	got := packFrames([]networkUDPPacket{pcmd, camera, ack}, false)
+	if len(got) != 1 {
+		t.Fatalf("got %v packets, want 1", len(got))
+	}
+	if want := len(pcmd.data) + len(camera.data) + len(ack.data); len(got[0].data) != want || got[0].size != want {
+		t.Fatalf("got packet of %v bytes, want %v", len(got[0].data), want)
+	}
+
+	// With QoS the PCMD is in another class than the camera and ack.
+	got = packFrames([]networkUDPPacket{pcmd, camera, ack}, true)
+	if len(got) != 2 {
+		t.Fatalf("got %v packets with QoS, want 2", len(got))
+	}
+	if want := string(camera.data) + string(ack.data); string(got[1].data) != want {
+		t.Fatalf("frames changed when packed: %v", got[1].data)
+	}
+
+	// The frames packed must not be changed.
+	if string(pcmd.data) != before {
+		t.Fatalf("frame changed when packed: %v", pcmd.data)
+	}
+
+	var many []networkUDPPacket
+	for i := 0; i < maxPackedSize/len(pcmd.data)+1; i++ {
+		many = append(many, pcmd)
+	}
+	got = packFrames(many, false)
+	if len(got) != 2 {
+		t.Fatalf("got %v packets, want 2 when above the max size", len(got))
+	}
+	for _, p := range got {
+		if len(p.data) > maxPackedSize {
+			t.Fatalf("packet of %v bytes above the max size", len(p.data))
+		}
+	}
+}
+
+func TestTrafficShaperCameraCommands(t *testing.T) {
+	u := newUdpPacketCreator()
+	shaping := TrafficShaping{CameraRate: 10}
+	var s trafficShaper
+	now := time.Now()
+
+	velocity := func(tilt float32) networkUDPPacket {
+		return u.encodeCmd(Command(CameraVelocity), &Ardrone3CameraVelocityArguments{Tilt: tilt})
+	}
+	unknown := u.encodeFrame(networkBuffers[bufferC2DNonAck], EncodeCommand(Command{Project: 1, Class: 99, Cmd: 7}, nil))
+
+	s.add(velocity(5))
+	s.add(u.encodeCmd(Command(CameraOrientationV2), &Ardrone3CameraOrientationV2Arguments{Tilt: 10}))
+	s.add(velocity(0))
+	s.add(unknown)
+
+	// The unknown command is not shaped, and the velocity is coalesced
+	// to the stop without replacing the orientation.
+	got := s.ready(shaping, now)
+	if len(got) != 2 {
+		t.Fatalf("got %v frames, want 2", len(got))
+	}
+	if got[0].data[frameHeaderSize+1] != 99 {
+		t.Fatalf("unknown command not passed through first: %v", got[0].data)
+	}
+	if !isCameraFrame(got[1].data) || got[1].data[len(got[1].data)-5] != 0 {
+		t.Fatalf("velocity not coalesced to the latest: %v", got[1].data)
+	}
+
+	got = s.ready(shaping, now.Add(time.Millisecond*101))
+	if len(got) != 1 || got[0].data[frameHeaderSize+1] != byte(Command(CameraOrientationV2).Class) {
+		t.Fatalf("orientation not sent after the velocity: %v", got)
+	}
+	if _, ok := s.next(now.Add(time.Millisecond * 101)); ok {
+		t.Fatalf("frames still waiting")
+	}
+}
+
+func TestTrafficShaperRaw(t *testing.T) {
+	u := newUdpPacketCreator()
+	var s trafficShaper
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		p := u.encodeCmd(Command(PilotingPCMD), &Ardrone3PilotingPCMDArguments{Gaz: int8(i)})
+		p.raw = true
+		s.add(p)
+	}
+
+	if got := s.ready(TrafficShaping{PCMDRate: 10}, now); len(got) != 3 {
+		t.Fatalf("got %v raw frames, want all 3 sent as they are", len(got))
+	}
+}
@@ -0,0 +1,201 @@
+package parrotbebop
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Default values for the SimulatedPositionSource.
+const (
+	defaultSimSpeed      = 5.0
+	defaultSimClimbSpeed = 1.0
+	defaultSimInterval   = time.Millisecond * 200
+)
+
+// SimulatedPositionSource is a PositionSource simulating the position
+// of the drone, which moves towards the position given with MoveTo at
+// the configured speed. Used with Drone.Simulate the moveTo commands
+// sent to the drone are flown by the simulator, and the positions are
+// delivered to the driver as if they were reported by the drone, so
+// the waypoint executor, follow-me and the missions can be tested
+// without a drone or a gps.
+type SimulatedPositionSource struct {
+	// Speed is the horizontal speed in m/s.
+	Speed float64
+	// ClimbSpeed is the vertical speed in m/s.
+	ClimbSpeed float64
+	// Interval is how often a new position is delivered, and the time
+	// step used when moving.
+	Interval time.Duration
+
+	mu     sync.Mutex
+	pos    Position
+	target *Position
+}
+
+// NewSimulatedPositionSource will return a new simulator, starting at
+// the position given.
+func NewSimulatedPositionSource(start Position) *SimulatedPositionSource {
+	return &SimulatedPositionSource{
+		Speed:      defaultSimSpeed,
+		ClimbSpeed: defaultSimClimbSpeed,
+		Interval:   defaultSimInterval,
+		pos:        start,
+	}
+}
+
+// MoveTo will make the simulator start moving towards the position.
+func (s *SimulatedPositionSource) MoveTo(p Position) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.target = &p
+}
+
+// Cancel will stop the current move, leaving the simulator at the
+// position it have reached.
+func (s *SimulatedPositionSource) Cancel() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.target = nil
+}
+
+// Position will return the current simulated position.
+func (s *SimulatedPositionSource) Position() Position {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.pos
+}
+
+// Moving will return true if the simulator have not yet reached the
+// position given with MoveTo.
+func (s *SimulatedPositionSource) Moving() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.target != nil
+}
+
+// step will move the simulator towards the target for the duration
+// given, and return the new position.
+func (s *SimulatedPositionSource) step(dt time.Duration) Position {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.target == nil {
+		return s.pos
+	}
+	t := *s.target
+
+	// Move horizontally along the great circle, and stop at the target
+	// if it is closer than the distance moved in this step.
+	dist := distanceTo(s.pos.Latitude, s.pos.Longitude, t.Latitude, t.Longitude)
+	move := s.Speed * dt.Seconds()
+	if dist <= move {
+		s.pos.Latitude, s.pos.Longitude = t.Latitude, t.Longitude
+	} else {
+		brng := bearingTo(s.pos.Latitude, s.pos.Longitude, t.Latitude, t.Longitude)
+		s.pos.Latitude, s.pos.Longitude = offsetPosition(s.pos.Latitude, s.pos.Longitude, move, brng)
+	}
+
+	climb := s.ClimbSpeed * dt.Seconds()
+	diff := t.Altitude - s.pos.Altitude
+	if math.Abs(diff) <= climb {
+		s.pos.Altitude = t.Altitude
+	} else {
+		s.pos.Altitude += math.Copysign(climb, diff)
+	}
+
+	if s.pos.Latitude == t.Latitude && s.pos.Longitude == t.Longitude && s.pos.Altitude == t.Altitude {
+		s.target = nil
+	}
+
+	return s.pos
+}
+
+// Positions will start the simulator, and return a channel delivering
+// the simulated position every interval.
+func (s *SimulatedPositionSource) Positions(ctx context.Context) (<-chan Position, error) {
+	if s.Interval <= 0 || s.Speed <= 0 || s.ClimbSpeed <= 0 {
+		return nil, fmt.Errorf("SimulatedPositionSource: interval and speeds must be above 0")
+	}
+
+	ch := make(chan Position)
+
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(s.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			select {
+			case ch <- s.step(s.Interval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// Simulate will make the simulator fly the moveTo commands sent to
+// the drone, and feed the simulated positions into the driver as
+// Ardrone3PilotingStatePositionChanged and AltitudeChanged events,
+// until the context is done. The commands are still sent to the drone
+// if connected.
+func (d *Drone) Simulate(ctx context.Context, sim *SimulatedPositionSource) error {
+	events, unsubscribe := d.events.subscribe()
+	positions, err := sim.Positions(ctx)
+	if err != nil {
+		unsubscribe()
+		return fmt.Errorf("Simulate: %v", err)
+	}
+
+	go func() {
+		defer unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-events:
+				if !ok {
+					return
+				}
+				switch ev.Type {
+				case EventMoveToSent:
+					sim.MoveTo(ev.Value.(Position))
+				case EventMoveToCancelled:
+					sim.Cancel()
+				}
+			case p, ok := <-positions:
+				if !ok {
+					return
+				}
+				d.checkCmdFromDrone(protocolARCommands{}, Ardrone3PilotingStatePositionChangedArguments{
+					Latitude:  p.Latitude,
+					Longitude: p.Longitude,
+					Altitude:  p.Altitude,
+				})
+				// The simulated drone took off at sea level, so the
+				// altitude above the take off point is the same.
+				d.checkCmdFromDrone(protocolARCommands{}, Ardrone3PilotingStateAltitudeChangedArguments{
+					Altitude: p.Altitude,
+				})
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package parrotbebop
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSimulatedPositionSourceStep(t *testing.T) {
+	start := Position{Latitude: 60, Longitude: 10, Altitude: 0}
+	s := NewSimulatedPositionSource(start)
+
+	lat, lon := offsetPosition(60, 10, 20, 90)
+	s.MoveTo(Position{Latitude: lat, Longitude: lon, Altitude: 2})
+
+	// 5 m/s horizontal and 1 m/s vertical.
+	p := s.step(time.Second)
+	if d := distanceTo(start.Latitude, start.Longitude, p.Latitude, p.Longitude); math.Abs(d-5) > 0.01 {
+		t.Fatalf("expected to move 5m, moved %v", d)
+	}
+	if p.Altitude != 1 {
+		t.Fatalf("expected altitude 1, got %v", p.Altitude)
+	}
+
+	p = s.step(time.Second * 10)
+	if p.Latitude != lat || p.Longitude != lon || p.Altitude != 2 {
+		t.Fatalf("expected to reach the target, got %#v", p)
+	}
+	if s.Moving() {
+		t.Fatal("expected to stop at the target")
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	d := NewDrone()
+	go func() {
+		for range d.chSendingUDPPacket {
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sim := NewSimulatedPositionSource(Position{Latitude: 60, Longitude: 10, Altitude: 0})
+	sim.Speed = 1000
+	sim.ClimbSpeed = 1000
+	sim.Interval = time.Millisecond * 10
+	if err := d.Simulate(ctx, sim); err != nil {
+		t.Fatal(err)
+	}
+
+	target := Position{Latitude: 60.001, Longitude: 10.001, Altitude: 10}
+	if err := d.moveToHeading(target, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	err := d.scriptWaitFor(ctx, time.Second*2, func() bool {
+		return d.Telemetry().Position == target
+	})
+	if err != nil {
+		t.Fatalf("simulated position not delivered: %v, at %#v", err, d.Telemetry().Position)
+	}
+}
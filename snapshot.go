@@ -0,0 +1,129 @@
+package parrotbebop
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// snapshotTimeout is how long Snapshot will wait for a key frame.
+const snapshotTimeout = time.Second * 5
+
+// FrameDecoder will decode a H264 key frame into an image. The
+// decoding of H264 are not done by the driver itself, so a decoder
+// must be given with SetFrameDecoder before Snapshot can be used.
+type FrameDecoder interface {
+	Decode(ctx context.Context, frame H264Frame) (image.Image, error)
+}
+
+// FFmpegFrameDecoder is a FrameDecoder using an ffmpeg binary to
+// decode the frame.
+type FFmpegFrameDecoder struct {
+	// Path to the ffmpeg binary. Defaults to "ffmpeg" found in PATH.
+	Path string
+}
+
+// Decode will run ffmpeg with the frame as input, and decode the png
+// image given as output.
+func (f FFmpegFrameDecoder) Decode(ctx context.Context, frame H264Frame) (image.Image, error) {
+	path := f.Path
+	if path == "" {
+		path = "ffmpeg"
+	}
+
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, path,
+		"-loglevel", "error",
+		"-f", "h264", "-i", "pipe:0",
+		"-frames:v", "1", "-f", "image2pipe", "-vcodec", "png",
+		"pipe:1",
+	)
+	cmd.Stdin = bytes.NewReader(frame.Data)
+	cmd.Stdout = &out
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg decoding failed: %v", err)
+	}
+
+	return png.Decode(&out)
+}
+
+// snapshotConfig holds the decoder and image format used by Snapshot.
+type snapshotConfig struct {
+	mu      sync.Mutex
+	decoder FrameDecoder
+	png     bool
+}
+
+// SetFrameDecoder will set the decoder used to decode the video frames
+// into images for Snapshot. If asPNG is true the snapshots will be png
+// encoded, else jpeg.
+func (d *Drone) SetFrameDecoder(dec FrameDecoder, asPNG bool) {
+	d.snapshotConfig.mu.Lock()
+	defer d.snapshotConfig.mu.Unlock()
+
+	d.snapshotConfig.decoder = dec
+	d.snapshotConfig.png = asPNG
+}
+
+// Snapshot will grab the next key frame from the live video stream,
+// and return it as an encoded jpeg or png image. This is done without
+// touching the media stored on the drone.
+func (d *Drone) Snapshot() ([]byte, error) {
+	d.snapshotConfig.mu.Lock()
+	dec := d.snapshotConfig.decoder
+	asPNG := d.snapshotConfig.png
+	d.snapshotConfig.mu.Unlock()
+
+	if dec == nil {
+		return nil, fmt.Errorf("Snapshot: no frame decoder set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), snapshotTimeout)
+	defer cancel()
+
+	frame, err := d.nextKeyFrame(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Snapshot: %v", err)
+	}
+
+	img, err := dec.Decode(ctx, frame)
+	if err != nil {
+		return nil, fmt.Errorf("Snapshot: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if asPNG {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Snapshot: failed to encode image: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// nextKeyFrame will wait for the next key frame in the video stream.
+func (d *Drone) nextKeyFrame(ctx context.Context) (H264Frame, error) {
+	chFrames, unsubscribe := d.frames.subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return H264Frame{}, fmt.Errorf("no key frame received: %v", ctx.Err())
+		case f := <-chFrames:
+			if f.KeyFrame {
+				return f, nil
+			}
+		}
+	}
+}
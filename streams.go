@@ -0,0 +1,120 @@
+package parrotbebop
+
+import "sync"
+
+// telemetryStreamBuffer is the number of values each typed telemetry
+// stream holds for a consumer not reading.
+const telemetryStreamBuffer = 16
+
+// telemetryStreams holds the channels of the typed telemetry streams
+// given out with Attitude, Position and BatteryEvents. Each channel is
+// used as a ring buffer, so when the consumer is not keeping up the
+// oldest value is dropped for the newest, and the decoding of the
+// commands received is never stalled.
+type telemetryStreams struct {
+	mu       sync.Mutex
+	attitude []chan Attitude
+	position []chan Position
+	battery  []chan int
+}
+
+// Attitude will return a channel where every attitude reported by the
+// drone is delivered. The channel holds the latest 16 attitudes, and
+// the oldest is dropped when the consumer is not reading fast enough.
+// The channel is kept for as long as the drone, so it should be called
+// once for each consumer. For the events with a way of unsubscribing
+// use Subscribe.
+func (d *Drone) Attitude() <-chan Attitude {
+	ch := make(chan Attitude, telemetryStreamBuffer)
+
+	d.telemetryStreams.mu.Lock()
+	defer d.telemetryStreams.mu.Unlock()
+	d.telemetryStreams.attitude = append(d.telemetryStreams.attitude, ch)
+
+	return ch
+}
+
+// Position will return a channel where every valid gps position
+// reported by the drone is delivered, buffered like with Attitude.
+func (d *Drone) Position() <-chan Position {
+	ch := make(chan Position, telemetryStreamBuffer)
+
+	d.telemetryStreams.mu.Lock()
+	defer d.telemetryStreams.mu.Unlock()
+	d.telemetryStreams.position = append(d.telemetryStreams.position, ch)
+
+	return ch
+}
+
+// BatteryEvents will return a channel where every battery level in
+// percent reported by the drone is delivered, buffered like with
+// Attitude.
+func (d *Drone) BatteryEvents() <-chan int {
+	ch := make(chan int, telemetryStreamBuffer)
+
+	d.telemetryStreams.mu.Lock()
+	defer d.telemetryStreams.mu.Unlock()
+	d.telemetryStreams.battery = append(d.telemetryStreams.battery, ch)
+
+	return ch
+}
+
+// publishAttitude will deliver the attitude to all the attitude
+// streams.
+func (s *telemetryStreams) publishAttitude(a Attitude) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, ch := range s.attitude {
+		select {
+		case ch <- a:
+		default:
+			// Full, so drop the oldest to make room. The streams are
+			// only written while holding the lock, so the send after
+			// will not fail.
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- a
+		}
+	}
+}
+
+// publishPosition will deliver the position to all the position
+// streams.
+func (s *telemetryStreams) publishPosition(p Position) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, ch := range s.position {
+		select {
+		case ch <- p:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- p
+		}
+	}
+}
+
+// publishBattery will deliver the battery level to all the battery
+// streams.
+func (s *telemetryStreams) publishBattery(percent int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, ch := range s.battery {
+		select {
+		case ch <- percent:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- percent
+		}
+	}
+}
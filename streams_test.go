@@ -0,0 +1,30 @@
+package parrotbebop
+
+import "testing"
+
+func TestTelemetryStreams(t *testing.T) {
+	d := NewDrone()
+	attitude := d.Attitude()
+	battery := d.BatteryEvents()
+	position := d.Position()
+
+	// A consumer not reading should only get the latest values, and
+	// never block the publishing.
+	for i := 0; i < telemetryStreamBuffer*2; i++ {
+		d.telemetryStreams.publishBattery(i)
+		d.telemetryStreams.publishAttitude(Attitude{Yaw: float32(i)})
+	}
+	if got := <-battery; got != telemetryStreamBuffer {
+		t.Fatalf("got oldest battery level %v, want %v", got, telemetryStreamBuffer)
+	}
+	if len(attitude) != telemetryStreamBuffer {
+		t.Fatalf("got %v attitudes buffered, want %v", len(attitude), telemetryStreamBuffer)
+	}
+
+	// Only the valid gps positions are delivered.
+	d.checkCmdFromDrone(protocolARCommands{}, Ardrone3PilotingStatePositionChangedArguments{Latitude: 500, Longitude: 500})
+	d.checkCmdFromDrone(protocolARCommands{}, Ardrone3PilotingStatePositionChangedArguments{Latitude: 60, Longitude: 10})
+	if p := <-position; p.Latitude != 60 || len(position) != 0 {
+		t.Fatalf("got position %+v with %v more", p, len(position))
+	}
+}
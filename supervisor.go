@@ -0,0 +1,205 @@
+package parrotbebop
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RestartPolicy tells the supervisor what to do when a go routine
+// of the connection returns.
+type RestartPolicy int
+
+const (
+	// RestartNever will leave the go routine stopped.
+	RestartNever RestartPolicy = iota
+	// RestartOnFailure will restart the go routine if it returned an
+	// error or panicked, but not if it returned without error.
+	RestartOnFailure
+	// RestartAlways will restart the go routine whenever it returns,
+	// until the connection is closed.
+	RestartAlways
+	// RestartConnection will re-initialize the whole connection with
+	// the drone if the go routine failed, and is used for the go
+	// routines owning the network connections, which can't be restarted
+	// by themselves.
+	RestartConnection
+)
+
+// String will return the name of the policy.
+func (r RestartPolicy) String() string {
+	switch r {
+	case RestartNever:
+		return "never"
+	case RestartOnFailure:
+		return "on failure"
+	case RestartAlways:
+		return "always"
+	case RestartConnection:
+		return "connection"
+	}
+
+	return fmt.Sprintf("RestartPolicy(%d)", int(r))
+}
+
+// supervisorRestartDelay is how long to wait before restarting a go
+// routine, so a go routine failing right away does not spin.
+const supervisorRestartDelay = time.Second
+
+// GoroutineHealth is the health of a go routine of the connection
+// with the drone, as reported by Drone.Health.
+type GoroutineHealth struct {
+	Name    string
+	Policy  RestartPolicy
+	Running bool
+	// Restarts is the number of times the go routine was restarted.
+	Restarts int
+	// Failures is the number of times the go routine returned an error
+	// or panicked.
+	Failures  int
+	LastError string
+	Started   time.Time
+	Stopped   time.Time
+}
+
+// supervisor owns the go routines of the connection with the drone,
+// restarts them according to their RestartPolicy, and keeps track of
+// their health.
+type supervisor struct {
+	mu    sync.Mutex
+	tasks map[string]*GoroutineHealth
+	// restartDelay is set to supervisorRestartDelay when zero.
+	restartDelay time.Duration
+	// reconnect is called when a go routine with the RestartConnection
+	// policy fails.
+	reconnect func(ctx context.Context)
+}
+
+// reset will forget the go routines of the previous connection, and is
+// called before starting the go routines of a new connection.
+func (s *supervisor) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tasks = make(map[string]*GoroutineHealth)
+}
+
+// start will run fn in a new go routine, and restart it according to
+// the policy until ctx is done.
+func (s *supervisor) start(ctx context.Context, name string, policy RestartPolicy, fn func(ctx context.Context) error) {
+	s.mu.Lock()
+	if s.tasks == nil {
+		s.tasks = make(map[string]*GoroutineHealth)
+	}
+	h := &GoroutineHealth{Name: name, Policy: policy}
+	s.tasks[name] = h
+	s.mu.Unlock()
+
+	go s.run(ctx, h, fn)
+}
+
+// run will run fn, and restart it if needed when it returns.
+func (s *supervisor) run(ctx context.Context, h *GoroutineHealth, fn func(ctx context.Context) error) {
+	for {
+		s.mu.Lock()
+		h.Running = true
+		h.Started = time.Now()
+		s.mu.Unlock()
+
+		err := runRecover(ctx, fn)
+
+		s.mu.Lock()
+		h.Running = false
+		h.Stopped = time.Now()
+		if err != nil {
+			h.Failures++
+			h.LastError = err.Error()
+		}
+		s.mu.Unlock()
+
+		// Everything is expected to stop when the connection is closed.
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			log.Printf("error: supervisor: %v failed: %v\n", h.Name, err)
+		}
+
+		switch {
+		case h.Policy == RestartAlways:
+		case h.Policy == RestartOnFailure && err != nil:
+		case h.Policy == RestartConnection && err != nil:
+			if s.reconnect != nil {
+				log.Printf("info: supervisor: re-initializing the connection after %v failed\n", h.Name)
+				s.reconnect(ctx)
+			}
+			return
+		default:
+			return
+		}
+
+		delay := s.restartDelay
+		if delay == 0 {
+			delay = supervisorRestartDelay
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+
+		s.mu.Lock()
+		h.Restarts++
+		s.mu.Unlock()
+		log.Printf("info: supervisor: restarting %v\n", h.Name)
+	}
+}
+
+// runRecover will run fn, and return a panic as an error.
+func runRecover(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	return fn(ctx)
+}
+
+// health will return a copy of the health of all the go routines,
+// sorted by name.
+func (s *supervisor) health() []GoroutineHealth {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hs := make([]GoroutineHealth, 0, len(s.tasks))
+	for _, h := range s.tasks {
+		hs = append(hs, *h)
+	}
+	sort.Slice(hs, func(i, j int) bool {
+		return hs[i].Name < hs[j].Name
+	})
+
+	return hs
+}
+
+// Health will return the health of the go routines handling the current
+// connection with the drone, like the network reader and writer, the
+// Pcmd scheduler, and the handlers.
+func (d *Drone) Health() []GoroutineHealth {
+	return d.supervisor.health()
+}
+
+// requestReconnect will make Start re-initialize the connection with
+// the drone, unless the connection is already closed.
+func (d *Drone) requestReconnect(ctx context.Context) {
+	select {
+	case d.chNetworkConnect <- struct{}{}:
+	case <-ctx.Done():
+	}
+}
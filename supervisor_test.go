@@ -0,0 +1,82 @@
+package parrotbebop
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitHealth will wait until the health of the go routine matches.
+func waitHealth(t *testing.T, s *supervisor, name string, match func(h GoroutineHealth) bool) GoroutineHealth {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second * 2)
+	for time.Now().Before(deadline) {
+		for _, h := range s.health() {
+			if h.Name == name && match(h) {
+				return h
+			}
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+
+	t.Fatalf("timeout waiting for the health of %v: %+v", name, s.health())
+	return GoroutineHealth{}
+}
+
+func TestSupervisorRestartOnFailure(t *testing.T) {
+	s := supervisor{restartDelay: time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runs := 0
+	s.start(ctx, "panics", RestartOnFailure, func(ctx context.Context) error {
+		runs++
+		if runs < 3 {
+			panic("boom")
+		}
+		<-ctx.Done()
+		return nil
+	})
+
+	h := waitHealth(t, &s, "panics", func(h GoroutineHealth) bool {
+		return h.Running && h.Restarts == 2
+	})
+	if h.Failures != 2 || h.LastError == "" {
+		t.Fatalf("wrong health after panics: %+v", h)
+	}
+
+	cancel()
+	waitHealth(t, &s, "panics", func(h GoroutineHealth) bool { return !h.Running })
+}
+
+func TestSupervisorPolicies(t *testing.T) {
+	s := supervisor{restartDelay: time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reconnected := make(chan struct{}, 1)
+	s.reconnect = func(ctx context.Context) { reconnected <- struct{}{} }
+
+	s.start(ctx, "done", RestartOnFailure, func(ctx context.Context) error { return nil })
+	s.start(ctx, "never", RestartNever, func(ctx context.Context) error { return errors.New("failed") })
+	s.start(ctx, "conn", RestartConnection, func(ctx context.Context) error { return errors.New("failed") })
+
+	waitHealth(t, &s, "done", func(h GoroutineHealth) bool { return !h.Stopped.IsZero() && h.Failures == 0 })
+	waitHealth(t, &s, "never", func(h GoroutineHealth) bool { return h.Failures == 1 })
+
+	select {
+	case <-reconnected:
+	case <-time.After(time.Second * 2):
+		t.Fatalf("no reconnect after the connection go routine failed")
+	}
+
+	// Give a restart the time to happen, and check that none did.
+	time.Sleep(time.Millisecond * 20)
+	for _, h := range s.health() {
+		if h.Restarts != 0 || h.Running {
+			t.Fatalf("go routine restarted: %+v", h)
+		}
+	}
+}
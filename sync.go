@@ -0,0 +1,94 @@
+package parrotbebop
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// allStatesTimeout is how long to wait for the drone to report that
+// all the states and settings have been sent.
+const allStatesTimeout = time.Second * 10
+
+// connectionState holds if the connection with the drone is ready,
+// which is when the drone have sent all it's states and settings
+// after the connection was made.
+type connectionState struct {
+	mu    sync.Mutex
+	ready bool
+	// link is the last connection event, used for the health of the
+	// driver, where linkKnown is false before the first attempt.
+	link      ConnectionEvent
+	linkKnown bool
+}
+
+// Ready will return true when the connection with the drone is
+// initialized, and the drone have sent a full snapshot of all it's
+// states and settings.
+func (d *Drone) Ready() bool {
+	d.connectionState.mu.Lock()
+	defer d.connectionState.mu.Unlock()
+
+	return d.connectionState.ready
+}
+
+// setReady will set the ready state of the connection, and publish
+// an event if it changed.
+func (d *Drone) setReady(ready bool) {
+	d.connectionState.mu.Lock()
+	changed := d.connectionState.ready != ready
+	d.connectionState.ready = ready
+	d.connectionState.mu.Unlock()
+
+	if changed {
+		d.events.publish(EventConnectionReady, ready)
+	}
+}
+
+// syncAllStates will ask the drone to send all it's states and all
+// it's settings, like FreeFlight does when it connects, and wait for
+// the AllStatesChanged and AllSettingsChanged events telling that
+// all the values are sent. All the values received will populate the
+// telemetry through the normal decoding of the packets, and when
+// both events are received the connection are set to ready.
+func (d *Drone) syncAllStates(ctx context.Context) error {
+	d.setReady(false)
+
+	// Subscribe before asking, so we don't miss the events.
+	chEvents, unsubscribe := d.events.subscribe()
+	defer unsubscribe()
+
+	if err := d.sendCmd(Command(CommonAllStates), &CommonCommonAllStatesArguments{}); err != nil {
+		return fmt.Errorf("syncAllStates: failed to ask for all states: %v", err)
+	}
+	if err := d.sendCmd(Command(SettingsAllSettings), &CommonSettingsAllSettingsArguments{}); err != nil {
+		return fmt.Errorf("syncAllStates: failed to ask for all settings: %v", err)
+	}
+
+	var statesDone, settingsDone bool
+	timeout := time.NewTimer(allStatesTimeout)
+	defer timeout.Stop()
+
+	for !statesDone || !settingsDone {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("syncAllStates: %v", ctx.Err())
+		case <-timeout.C:
+			return fmt.Errorf("syncAllStates: timed out waiting for drone, states done: %v, settings done: %v", statesDone, settingsDone)
+		case ev := <-chEvents:
+			switch ev.Type {
+			case EventAllStatesReceived:
+				statesDone = true
+			case EventAllSettingsReceived:
+				settingsDone = true
+			}
+		}
+	}
+
+	log.Printf("info: received all states and settings from drone, connection ready\n")
+	d.setReady(true)
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package parrotbebop
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// takeOffTimeout is how long TakeOffToAltitude waits for the drone
+	// to report hovering after the takeoff.
+	takeOffTimeout = time.Second * 30
+	// takeOffClimbTimeout is how long TakeOffToAltitude waits for the
+	// altitude to be reached after the takeoff.
+	takeOffClimbTimeout = time.Minute
+	// userTakeOffArmTimeout is how long UserTakeOff waits for the
+	// drone to report that it is ready to be thrown.
+	userTakeOffArmTimeout = time.Second * 10
+	// landTimeout is how long Land waits for the drone to report
+	// landed, which is long enough to come down from the max altitude.
+	landTimeout = time.Minute * 2
+)
+
+// TakeOffToAltitude will take off, wait until the drone reports that
+// it is hovering, and then climb or descend to the altitude given in
+// meters with the altitude controller. It returns when the altitude is
+// reached, where the altitude controller keeps holding it until
+// ClearTargetAltitude or Land is called. An error is returned if the
+// drone don't report hovering, or don't reach the altitude, in time,
+// where the drone is left hovering where it is.
+func (d *Drone) TakeOffToAltitude(meters float64) error {
+	if meters <= 0 {
+		return fmt.Errorf("TakeOffToAltitude: altitude must be above 0, got %v", meters)
+	}
+
+	// Check before sending, since a takeoff refused would only be
+	// noticed when timing out.
+	if err := d.checkFlyingStateFor(ActionTakeoff); err != nil {
+		return fmt.Errorf("TakeOffToAltitude: %v", err)
+	}
+
+	// Subscribe before the takeoff is sent, so the hovering state is
+	// not missed.
+	events, unsubscribe := d.events.subscribe()
+	defer unsubscribe()
+
+	if err := d.SendAction(ActionTakeoff); err != nil {
+		return fmt.Errorf("TakeOffToAltitude: %v", err)
+	}
+	if err := d.waitFlyingStateEvent(events, takeOffTimeout, FlyingStateHovering, FlyingStateFlying); err != nil {
+		return fmt.Errorf("TakeOffToAltitude: %v", err)
+	}
+
+	if err := d.SetTargetAltitude(meters); err != nil {
+		return fmt.Errorf("TakeOffToAltitude: %v", err)
+	}
+	if err := d.scriptWaitFor(context.Background(), takeOffClimbTimeout, d.TargetAltitudeReached); err != nil {
+		d.ClearTargetAltitude()
+		return fmt.Errorf("TakeOffToAltitude: altitude %vm not reached, at %vm: %v", meters, d.Telemetry().Altitude, err)
+	}
+
+	return nil
+}
+
+// UserTakeOff will do a hand launch, where the motors are started
+// while the drone is held, and it takes off when thrown. It waits until
+// the drone reports that it is ready to be thrown, and then until it is
+// hovering after the throw or the context is done. If the context is
+// done before the throw, or the drone don't get ready in time, the
+// hand launch is cancelled so the motors are stopped. An error is also
+// returned if the drone cancels the hand launch itself.
+func (d *Drone) UserTakeOff(ctx context.Context) error {
+	if err := d.checkFlyingStateFor(ActionUserTakeoff); err != nil {
+		return fmt.Errorf("UserTakeOff: %v", err)
+	}
+
+	events, unsubscribe := d.events.subscribe()
+	defer unsubscribe()
+
+	if err := d.SendAction(ActionUserTakeoff); err != nil {
+		return fmt.Errorf("UserTakeOff: %v", err)
+	}
+
+	// cancel will stop the motors, which is done with the landing
+	// action while the drone is waiting to be thrown.
+	cancel := func(reason error) error {
+		if state, _ := d.FlyingState(); state.userTakeoffPending() {
+			if err := d.SendAction(ActionLanding); err != nil {
+				log.Printf("error: UserTakeOff: cancelling: %v\n", err)
+			}
+		}
+		return fmt.Errorf("UserTakeOff: %v", reason)
+	}
+
+	armCtx, armCancel := context.WithTimeout(ctx, userTakeOffArmTimeout)
+	defer armCancel()
+
+	ready := false
+	for {
+		var deadline <-chan struct{}
+		if !ready {
+			deadline = armCtx.Done()
+		}
+
+		select {
+		case <-ctx.Done():
+			return cancel(fmt.Errorf("not thrown: %v", ctx.Err()))
+		case <-deadline:
+			return cancel(fmt.Errorf("timed out after %v waiting for the drone to be ready to be thrown", userTakeOffArmTimeout))
+		case ev, ok := <-events:
+			if !ok {
+				return fmt.Errorf("UserTakeOff: event subscription closed")
+			}
+			if ev.Type != EventFlyingStateChanged {
+				continue
+			}
+			state, ok := ev.Value.(FlyingState)
+			if !ok {
+				continue
+			}
+
+			switch state {
+			case FlyingStateUserTakeoff:
+				log.Printf("info: UserTakeOff: ready to be thrown\n")
+				ready = true
+			case FlyingStateTakingOff:
+				ready = true
+			case FlyingStateHovering, FlyingStateFlying:
+				return nil
+			case FlyingStateLanded:
+				return fmt.Errorf("UserTakeOff: cancelled by the drone")
+			case FlyingStateEmergency:
+				return fmt.Errorf("UserTakeOff: drone in emergency")
+			}
+		}
+	}
+}
+
+// Land will land the drone, and wait until it reports that it have
+// landed. The altitude controller is stopped first, since a positive
+// Gaz cancels the landing. It returns at once if the drone have already
+// landed, and an error if the landing is refused in the current flying
+// state or not reported in time.
+func (d *Drone) Land() error {
+	d.ClearTargetAltitude()
+
+	events, unsubscribe := d.events.subscribe()
+	defer unsubscribe()
+
+	// A drone already landing is only waited for, since the landing
+	// command is refused while landing.
+	state, ok := d.FlyingState()
+	switch {
+	case ok && state == FlyingStateLanded:
+		return nil
+	case ok && state == FlyingStateLanding:
+	default:
+		if err := d.checkFlyingStateFor(ActionLanding); err != nil {
+			return fmt.Errorf("Land: %v", err)
+		}
+		if err := d.SendAction(ActionLanding); err != nil {
+			return fmt.Errorf("Land: %v", err)
+		}
+	}
+	if err := d.waitFlyingStateEvent(events, landTimeout, FlyingStateLanded); err != nil {
+		return fmt.Errorf("Land: %v", err)
+	}
+
+	return nil
+}
+
+// waitFlyingStateEvent will wait until one of the flying states given
+// is received on the events subscribed to before the command was sent,
+// or the timeout. Unlike waitFlyingState the state from before is not
+// used, since it is the change caused by the command that is waited
+// for.
+func (d *Drone) waitFlyingStateEvent(events <-chan Event, timeout time.Duration, states ...FlyingState) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			state, _ := d.FlyingState()
+			return fmt.Errorf("timed out after %v waiting for flying state %v, the drone is %v", timeout, states, state)
+		case ev, ok := <-events:
+			if !ok {
+				return fmt.Errorf("event subscription closed")
+			}
+			if ev.Type != EventFlyingStateChanged {
+				continue
+			}
+			state, ok := ev.Value.(FlyingState)
+			if !ok {
+				continue
+			}
+			for _, s := range states {
+				if state == s {
+					return nil
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package parrotbebop
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTakeOffToAltitude(t *testing.T) {
+	d := NewDrone()
+	d.setFlyingState(FlyingStateLanded)
+
+	if err := d.TakeOffToAltitude(0); err == nil {
+		t.Fatalf("expected error for altitude 0")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.startAltitudeController(ctx)
+
+	go func() {
+		if a := <-d.chInputActions; a != ActionTakeoff {
+			t.Errorf("got action %v, want takeoff", a)
+		}
+		d.telemetry.update(func(t *Telemetry) { t.Altitude = 1 })
+		d.setFlyingState(FlyingStateTakingOff)
+		d.setFlyingState(FlyingStateHovering)
+		d.telemetry.update(func(t *Telemetry) { t.Altitude = 5 })
+	}()
+
+	if err := d.TakeOffToAltitude(5); err != nil {
+		t.Fatal(err)
+	}
+	if !d.TargetAltitudeReached() {
+		t.Fatalf("expected the altitude to be held after the takeoff")
+	}
+
+	// Taking off again while flying should be refused at once.
+	if err := d.TakeOffToAltitude(5); err == nil {
+		t.Fatalf("expected error when taking off while hovering")
+	}
+}
+
+func TestLand(t *testing.T) {
+	d := NewDrone()
+	d.setFlyingState(FlyingStateHovering)
+	if err := d.SetTargetAltitude(5); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		if a := <-d.chInputActions; a != ActionLanding {
+			t.Errorf("got action %v, want landing", a)
+		}
+		d.setFlyingState(FlyingStateLanding)
+		d.setFlyingState(FlyingStateLanded)
+	}()
+
+	if err := d.Land(); err != nil {
+		t.Fatal(err)
+	}
+	d.altitudeHold.mu.Lock()
+	enabled := d.altitudeHold.enabled
+	d.altitudeHold.mu.Unlock()
+	if enabled {
+		t.Fatalf("expected the altitude controller to be stopped")
+	}
+
+	// Nothing to do when landed.
+	if err := d.Land(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLandingActionStopsAltitudeHold(t *testing.T) {
+	d := NewDrone()
+	go func() {
+		for range d.chSendingUDPPacket {
+		}
+	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.handleInputAction(d.packetCreator, ctx)
+
+	holding := func() bool {
+		d.altitudeHold.mu.Lock()
+		defer d.altitudeHold.mu.Unlock()
+		return d.altitudeHold.enabled
+	}
+
+	for _, action := range []InputAction{ActionLanding, ActionEmergency} {
+		d.setFlyingState(FlyingStateHovering)
+		if err := d.SetTargetAltitude(5); err != nil {
+			t.Fatal(err)
+		}
+		d.chInputActions <- action
+		err := d.scriptWaitFor(ctx, time.Second, func() bool { return !holding() })
+		if err != nil {
+			t.Fatalf("altitude hold not stopped by %v: %v", action, err)
+		}
+	}
+}
+
+func TestUserTakeOff(t *testing.T) {
+	d := NewDrone()
+	d.setFlyingState(FlyingStateLanded)
+
+	go func() {
+		if a := <-d.chInputActions; a != ActionUserTakeoff {
+			t.Errorf("got action %v, want user takeoff", a)
+		}
+		d.setFlyingState(FlyingStateMotorRamping)
+		d.setFlyingState(FlyingStateUserTakeoff)
+		d.setFlyingState(FlyingStateTakingOff)
+		d.setFlyingState(FlyingStateHovering)
+	}()
+
+	if err := d.UserTakeOff(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Not thrown before the context is done should cancel the hand
+	// launch with the landing action.
+	d.setFlyingState(FlyingStateLanded)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-d.chInputActions
+		d.setFlyingState(FlyingStateUserTakeoff)
+		cancel()
+		if a := <-d.chInputActions; a != ActionLanding {
+			t.Errorf("got action %v, want landing to cancel", a)
+		}
+	}()
+
+	if err := d.UserTakeOff(ctx); err == nil {
+		t.Fatalf("expected error when not thrown")
+	}
+	<-done
+}
@@ -0,0 +1,170 @@
+package parrotbebop
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Position is a gps position given in decimal degrees, and the
+// altitude in meters.
+type Position struct {
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+}
+
+// Telemetry is a snapshot of the latest state values received
+// from the drone.
+type Telemetry struct {
+	// GPSFixed is true when the drone reports that it have a gps fix.
+	GPSFixed bool
+	// NumberOfSatellites is the number of satellites the drone
+	// currently sees.
+	NumberOfSatellites uint8
+	// Position is the last gps position reported by the drone.
+	Position Position
+	// EstimatedPosition is the gps position while the gps is ok, or
+	// the position estimated from the speed of the drone when the gps
+	// is lost, as told by PositionConfidence. LastGPSFix is when the
+	// last valid gps position was received.
+	EstimatedPosition  Position
+	PositionConfidence PositionConfidence
+	LastGPSFix         time.Time
+	// WaypointDistance is the horizontal distance in meters to the
+	// waypoint being flown by the moveTo executor, or 0 if none.
+	WaypointDistance float64
+	// Altitude is the altitude in meters above the take off point.
+	Altitude float64
+	// Velocity is the speed of the drone reported by the drone.
+	Velocity Velocity
+	// GroundSpeed is the horizontal speed in m/s, and ClimbRate the
+	// vertical speed in m/s where climbing is positive, found from the
+	// velocity.
+	GroundSpeed float64
+	ClimbRate   float64
+	// Roll, Pitch and Yaw is the attitude of the drone in radians.
+	// Yaw is the heading of the drone where 0 is north.
+	Roll  float32
+	Pitch float32
+	Yaw   float32
+	// FlyingState is the last flying state reported by the drone.
+	FlyingState FlyingState
+	// flyingStateKnown is true when a flying state have been received.
+	flyingStateKnown bool
+	// AlertState is the last alert state reported by the drone.
+	AlertState AlertState
+	// Sensors holds the last reported state of each sensor, where
+	// true means the sensor is ok.
+	Sensors map[Sensor]bool
+	// Motor holds the motor errors and versions reported by the drone.
+	Motor MotorStatus
+	// VideoStream is the state of the video stream.
+	VideoStream VideoEnableState
+	// VideoStreamMode is the mode of the video stream.
+	VideoStreamMode VideoStreamMode
+	// Media is the state of the onboard video recording and pictures.
+	Media MediaStatus
+	// Storage is the onboard storage information.
+	Storage StorageStatus
+	// Camera holds the picture and video settings.
+	Camera CameraSettings
+	// Gimbal is the orientation of the camera, and the limits of it.
+	Gimbal CameraGimbal
+	// Piloting holds the piloting settings.
+	Piloting PilotingSettings
+	// Wind and Vibration are the levels reported by the drone.
+	Wind      WarningLevel
+	Vibration WarningLevel
+	// Info holds the product name, versions and serial number.
+	Info DroneInfo
+	// Wifi holds the wifi settings.
+	Wifi WifiSettings
+	// Battery is the battery level in percent.
+	Battery uint8
+	// MagnetoCalibrationRequired is true if the drone reports that the
+	// magnetometer needs to be calibrated.
+	MagnetoCalibrationRequired bool
+}
+
+// Velocity is the speed of the drone in m/s in the north, east and
+// down directions.
+type Velocity struct {
+	North float64
+	East  float64
+	Down  float64
+}
+
+// GroundSpeed will return the horizontal speed in m/s.
+func (v Velocity) GroundSpeed() float64 {
+	return math.Hypot(v.North, v.East)
+}
+
+// ClimbRate will return the vertical speed in m/s, where climbing is
+// positive.
+func (v Velocity) ClimbRate() float64 {
+	return -v.Down
+}
+
+// Course will return the direction the drone is moving in as a compass
+// heading in degrees [0, 360), which is 0 when not moving.
+func (v Velocity) Course() float64 {
+	if v.North == 0 && v.East == 0 {
+		return 0
+	}
+
+	return math.Mod(math.Atan2(v.East, v.North)*180/math.Pi+360, 360)
+}
+
+// HeadingDegrees will return the yaw of the drone converted to a
+// compass heading in degrees [0, 360).
+func (t Telemetry) HeadingDegrees() float64 {
+	return math.Mod(float64(t.Yaw)*180/math.Pi+360, 360)
+}
+
+// telemetry holds the latest state values received from the drone.
+// The values are written by the go routine decoding the packets from
+// the drone, and read by the API methods, so all access should be
+// done while holding the mutex.
+type telemetry struct {
+	mu   sync.Mutex
+	data Telemetry
+}
+
+// update will call the function given as input with a pointer to
+// the telemetry data while holding the lock.
+func (t *telemetry) update(fn func(*Telemetry)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fn(&t.data)
+}
+
+// snapshot will return a copy of the current telemetry data.
+func (t *telemetry) snapshot() Telemetry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.data
+	// Maps are references, so they need to be copied.
+	if t.data.Sensors != nil {
+		c.Sensors = make(map[Sensor]bool, len(t.data.Sensors))
+		for k, v := range t.data.Sensors {
+			c.Sensors[k] = v
+		}
+	}
+	if t.data.Storage.Devices != nil {
+		c.Storage.Devices = make(map[uint8]MassStorage, len(t.data.Storage.Devices))
+		for k, v := range t.data.Storage.Devices {
+			c.Storage.Devices[k] = v
+		}
+	}
+
+	return c
+}
+
+// Telemetry will return a snapshot of the latest state values
+// received from the drone.
+func (d *Drone) Telemetry() Telemetry {
+	return d.telemetry.snapshot()
+}
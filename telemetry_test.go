@@ -0,0 +1,25 @@
+package parrotbebop
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSpeedTelemetry(t *testing.T) {
+	d := NewDrone()
+
+	arg := Ardrone3PilotingStateSpeedChangedArguments{SpeedX: 3, SpeedY: -4, SpeedZ: -1.5}
+	decoded := Ardrone3PilotingStateSpeedChanged{}.Decode(arg.Encode())
+	d.checkCmdFromDrone(protocolARCommands{}, decoded)
+
+	tm := d.Telemetry()
+	if tm.Velocity != (Velocity{North: 3, East: -4, Down: -1.5}) {
+		t.Fatalf("wrong velocity: %+v", tm.Velocity)
+	}
+	if tm.GroundSpeed != 5 || tm.ClimbRate != 1.5 {
+		t.Fatalf("wrong ground speed or climb rate: %v, %v", tm.GroundSpeed, tm.ClimbRate)
+	}
+	if c := tm.Velocity.Course(); math.Abs(c-306.87) > 0.01 {
+		t.Fatalf("wrong course: %v", c)
+	}
+}
@@ -0,0 +1,179 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultLogThrottle is the default shortest time between logging
+	// the same command received from the drone.
+	defaultLogThrottle = time.Second
+	// attitudeEventInterval is the shortest time between the attitude
+	// events, since the drone reports the attitude at around 30 Hz.
+	attitudeEventInterval = time.Millisecond * 100
+)
+
+// loggedCmd is the last time and value a command was logged.
+type loggedCmd struct {
+	time  time.Time
+	value string
+}
+
+// logThrottle will limit how often each type of command received from
+// the drone is logged, and skip logging values not changed since last
+// logged.
+type logThrottle struct {
+	mu       sync.Mutex
+	interval time.Duration
+	last     map[reflect.Type]loggedCmd
+}
+
+// allow will return true if the command should be logged.
+func (l *logThrottle) allow(cmdArgs interface{}, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.interval == 0 {
+		return true
+	}
+	if l.last == nil {
+		l.last = make(map[reflect.Type]loggedCmd)
+	}
+
+	typ := reflect.TypeOf(cmdArgs)
+	value := fmt.Sprintf("%+v", cmdArgs)
+	last, ok := l.last[typ]
+	if ok && (last.value == value || now.Sub(last.time) < l.interval) {
+		return false
+	}
+
+	l.last[typ] = loggedCmd{time: now, value: value}
+
+	return true
+}
+
+// SetLogThrottle will make the debug output log each type of command
+// received from the drone at most once every interval, and only when
+// the value have changed since it was last logged. 0 logs every
+// command. Defaults to 1 second.
+func (d *Drone) SetLogThrottle(interval time.Duration) error {
+	if interval < 0 {
+		return fmt.Errorf("SetLogThrottle: interval can not be negative, got %v", interval)
+	}
+
+	d.logThrottle.mu.Lock()
+	defer d.logThrottle.mu.Unlock()
+
+	d.logThrottle.interval = interval
+	d.logThrottle.last = nil
+
+	return nil
+}
+
+// Attitude is the attitude of the drone in radians.
+type Attitude struct {
+	Roll  float32
+	Pitch float32
+	Yaw   float32
+}
+
+// SubscribeThrottled will return a channel where the events published
+// by the driver are delivered like with Subscribe, but where each type
+// of event is delivered at most once every interval. When several
+// events of a type are published within the interval, only the latest
+// is delivered when the interval is over. Events with high priority,
+// like alerts, are always delivered at once. As with Subscribe the
+// oldest events are dropped for a subscriber not reading fast enough.
+// An interval of 0 or less delivers all the events like Subscribe.
+func (d *Drone) SubscribeThrottled(interval time.Duration) (<-chan Event, func()) {
+	if interval <= 0 {
+		return d.events.subscribe()
+	}
+
+	id, in, unsubscribe := d.events.subscribeID()
+	out := make(chan Event, eventSubscriberBuffer)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(out)
+		t := eventThrottle{interval: interval}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		// This go routine is the only sender on out.
+		deliver := func(evs []Event) {
+			for _, ev := range evs {
+				if sendDropOldest(out, ev) {
+					d.events.countDropped(id)
+				}
+			}
+		}
+
+		for {
+			select {
+			case <-done:
+				return
+			case ev, ok := <-in:
+				if !ok {
+					return
+				}
+				deliver(t.add(ev, time.Now()))
+			case now := <-ticker.C:
+				deliver(t.due(now))
+			}
+		}
+	}()
+
+	var once sync.Once
+	return out, func() {
+		once.Do(func() {
+			unsubscribe()
+			close(done)
+		})
+	}
+}
+
+// eventThrottle holds when each type of event was last delivered, and
+// the latest event of each type waiting to be delivered.
+type eventThrottle struct {
+	interval time.Duration
+	last     map[EventType]time.Time
+	pending  map[EventType]Event
+}
+
+// add will return the events to deliver now when the event is
+// published, where the event is kept as pending if the type was
+// delivered within the interval.
+func (t *eventThrottle) add(ev Event, now time.Time) []Event {
+	if t.last == nil {
+		t.last = make(map[EventType]time.Time)
+		t.pending = make(map[EventType]Event)
+	}
+
+	if ev.Priority == PriorityHigh || now.Sub(t.last[ev.Type]) >= t.interval {
+		t.last[ev.Type] = now
+		delete(t.pending, ev.Type)
+		return []Event{ev}
+	}
+
+	t.pending[ev.Type] = ev
+
+	return nil
+}
+
+// due will return the pending events where the interval is over.
+func (t *eventThrottle) due(now time.Time) []Event {
+	var evs []Event
+	for typ, ev := range t.pending {
+		if now.Sub(t.last[typ]) >= t.interval {
+			evs = append(evs, ev)
+			t.last[typ] = now
+			delete(t.pending, typ)
+		}
+	}
+
+	return evs
+}
@@ -0,0 +1,131 @@
+package parrotbebop
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLogThrottle(t *testing.T) {
+	l := logThrottle{interval: time.Second}
+	now := time.Now()
+	a := Ardrone3PilotingStateAttitudeChangedArguments{Roll: 1}
+	b := Ardrone3PilotingStateAttitudeChangedArguments{Roll: 2}
+
+	if !l.allow(a, now) {
+		t.Fatal("expected the first command to be logged")
+	}
+	if l.allow(b, now.Add(time.Millisecond*500)) {
+		t.Fatal("expected changed command within the interval to be skipped")
+	}
+	if !l.allow(CommonCommonStateBatteryStateChangedArguments{Percent: 50}, now.Add(time.Millisecond*500)) {
+		t.Fatal("expected other commands to be logged")
+	}
+	if l.allow(a, now.Add(time.Second*2)) {
+		t.Fatal("expected unchanged command to be skipped")
+	}
+	if !l.allow(b, now.Add(time.Second*2)) {
+		t.Fatal("expected changed command after the interval to be logged")
+	}
+
+	l.interval = 0
+	if !l.allow(b, now.Add(time.Second*2)) {
+		t.Fatal("expected all commands to be logged without throttle")
+	}
+}
+
+func TestEventThrottle(t *testing.T) {
+	th := eventThrottle{interval: time.Second}
+	now := time.Now()
+	ev := func(typ EventType, v int, p Priority) Event {
+		return Event{Type: typ, Value: v, Priority: p}
+	}
+
+	if evs := th.add(ev(EventAttitudeChanged, 1, PriorityNormal), now); len(evs) != 1 {
+		t.Fatalf("expected the first event delivered, got %v", evs)
+	}
+	th.add(ev(EventAttitudeChanged, 2, PriorityNormal), now.Add(time.Millisecond*100))
+	th.add(ev(EventAttitudeChanged, 3, PriorityNormal), now.Add(time.Millisecond*200))
+	if evs := th.add(ev(EventAlertStateChanged, 4, PriorityHigh), now.Add(time.Millisecond*300)); len(evs) != 1 {
+		t.Fatalf("expected high priority event delivered at once, got %v", evs)
+	}
+
+	if evs := th.due(now.Add(time.Millisecond * 500)); len(evs) != 0 {
+		t.Fatalf("expected nothing due within the interval, got %v", evs)
+	}
+	evs := th.due(now.Add(time.Second))
+	if len(evs) != 1 || evs[0].Value != 3 {
+		t.Fatalf("expected the latest event when due, got %v", evs)
+	}
+}
+
+func TestSubscribeThrottled(t *testing.T) {
+	d := NewDrone()
+	events, unsubscribe := d.SubscribeThrottled(time.Millisecond * 50)
+	defer unsubscribe()
+
+	for i := 0; i < 10; i++ {
+		d.events.publish(EventAttitudeChanged, Attitude{Roll: float32(i)})
+	}
+
+	var got []Attitude
+	timeout := time.After(time.Second)
+	for len(got) < 2 {
+		select {
+		case ev := <-events:
+			got = append(got, ev.Value.(Attitude))
+		case <-timeout:
+			t.Fatalf("expected 2 events, got %v", got)
+		}
+	}
+	if got[0].Roll != 0 || got[1].Roll != 9 {
+		t.Fatalf("expected the first and the latest event, got %v", got)
+	}
+}
+
+func TestSubscribeThrottledUnthrottled(t *testing.T) {
+	d := NewDrone()
+	events, unsubscribe := d.SubscribeThrottled(0)
+	defer unsubscribe()
+
+	d.events.publish(EventAttitudeChanged, Attitude{Roll: 1})
+	d.events.publish(EventAttitudeChanged, Attitude{Roll: 2})
+	for _, want := range []float32{1, 2} {
+		if ev := <-events; ev.Value.(Attitude).Roll != want {
+			t.Fatalf("got %v, want roll %v", ev.Value, want)
+		}
+	}
+}
+
+func TestSubscribeThrottledDropsOldest(t *testing.T) {
+	d := NewDrone()
+	events, unsubscribe := d.SubscribeThrottled(time.Millisecond * 50)
+	defer unsubscribe()
+
+	// Fill the queue without reading, where the high priority event
+	// published last must be kept.
+	for i := 0; i < eventSubscriberBuffer+10; i++ {
+		d.events.publishPriority(EventType(1000+i), PriorityNormal, i)
+	}
+	d.events.publishPriority(EventRTHFallback, PriorityHigh, nil)
+
+	err := d.scriptWaitFor(context.Background(), time.Second, func() bool {
+		for _, s := range d.EventSubscriberStats() {
+			if s.Dropped > 0 {
+				return len(events) == eventSubscriberBuffer
+			}
+		}
+		return false
+	})
+	if err != nil {
+		t.Fatalf("expected the dropped events to be counted: %v", err)
+	}
+
+	var last Event
+	for len(events) > 0 {
+		last = <-events
+	}
+	if last.Type != EventRTHFallback {
+		t.Fatalf("expected the newest event to be kept, got %v", last.Type)
+	}
+}
@@ -0,0 +1,150 @@
+package parrotbebop
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trackPositions will return the valid gps positions of the flight log,
+// leaving out the samples where the drone had no gps fix.
+func trackPositions(track []FlightSample) []Position {
+	var ps []Position
+	for _, s := range track {
+		if validGPSPosition(s.Position) {
+			ps = append(ps, s.Position)
+		}
+	}
+
+	return ps
+}
+
+// WriteTrackGeoJSON will write the track flown from the flight log, and
+// the planned route of waypoints, as a GeoJSON feature collection. The
+// track and the route are LineString features with the kind property
+// "track" and "route", and each waypoint is a Point feature with the
+// kind "waypoint" and it's index. The samples without a gps fix are
+// left out of the track, and a track or route with less than two
+// positions is left out, while the waypoints are always written.
+func WriteTrackGeoJSON(w io.Writer, track []FlightSample, route []Position) error {
+	var features []geoJSONFeature
+
+	if ps := trackPositions(track); len(ps) >= 2 {
+		features = append(features, geoJSONLineString(ps, map[string]interface{}{
+			"kind":  "track",
+			"start": track[0].Time.Format(time.RFC3339),
+			"end":   track[len(track)-1].Time.Format(time.RFC3339),
+		}))
+	}
+
+	if len(route) >= 2 {
+		features = append(features, geoJSONLineString(route, map[string]interface{}{
+			"kind": "route",
+		}))
+	}
+	for i, p := range route {
+		features = append(features, geoJSONFeature{
+			Type: "Feature",
+			Geometry: geoJSONGeometry{
+				Type:        "Point",
+				Coordinates: geoJSONPosition(p),
+			},
+			Properties: map[string]interface{}{
+				"kind":  "waypoint",
+				"index": i,
+			},
+		})
+	}
+
+	if err := writeGeoJSON(w, features); err != nil {
+		return fmt.Errorf("WriteTrackGeoJSON: %v", err)
+	}
+
+	return nil
+}
+
+// geoJSONLineString will return a LineString feature of the positions.
+func geoJSONLineString(ps []Position, properties map[string]interface{}) geoJSONFeature {
+	coords := make([][3]float64, len(ps))
+	for i, p := range ps {
+		coords[i] = geoJSONPosition(p)
+	}
+
+	return geoJSONFeature{
+		Type: "Feature",
+		Geometry: geoJSONGeometry{
+			Type:        "LineString",
+			Coordinates: coords,
+		},
+		Properties: properties,
+	}
+}
+
+// kmlPlacemark is a KML placemark with either a line or a point.
+type kmlPlacemark struct {
+	Name       string          `xml:"name"`
+	LineString *kmlCoordinates `xml:"LineString,omitempty"`
+	Point      *kmlCoordinates `xml:"Point,omitempty"`
+}
+
+// kmlCoordinates is the geometry of a KML line or point, where the
+// altitude is above sea level.
+type kmlCoordinates struct {
+	AltitudeMode string `xml:"altitudeMode"`
+	Coordinates  string `xml:"coordinates"`
+}
+
+// newKMLCoordinates will return the positions as KML coordinates, which
+// are longitude, latitude and altitude separated by commas, and the
+// positions separated by spaces.
+func newKMLCoordinates(ps []Position) *kmlCoordinates {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+
+	var s []string
+	for _, p := range ps {
+		s = append(s, f(p.Longitude)+","+f(p.Latitude)+","+f(p.Altitude))
+	}
+
+	return &kmlCoordinates{
+		AltitudeMode: "absolute",
+		Coordinates:  strings.Join(s, " "),
+	}
+}
+
+// WriteTrackKML will write the track flown from the flight log, and the
+// planned route of waypoints, as a KML document, with the same content
+// as WriteTrackGeoJSON, for tools like Google Earth.
+func WriteTrackKML(w io.Writer, track []FlightSample, route []Position) error {
+	var placemarks []kmlPlacemark
+
+	if ps := trackPositions(track); len(ps) >= 2 {
+		placemarks = append(placemarks, kmlPlacemark{Name: "track", LineString: newKMLCoordinates(ps)})
+	}
+	if len(route) >= 2 {
+		placemarks = append(placemarks, kmlPlacemark{Name: "route", LineString: newKMLCoordinates(route)})
+	}
+	for i, p := range route {
+		placemarks = append(placemarks, kmlPlacemark{Name: "waypoint " + strconv.Itoa(i), Point: newKMLCoordinates([]Position{p})})
+	}
+
+	doc := struct {
+		XMLName    xml.Name       `xml:"http://www.opengis.net/kml/2.2 kml"`
+		Placemarks []kmlPlacemark `xml:"Document>Placemark"`
+	}{
+		Placemarks: placemarks,
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("WriteTrackKML: %v", err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("WriteTrackKML: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package parrotbebop
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+// trackTestData will return a flight log with a sample without gps fix,
+// and a route of three waypoints.
+func trackTestData() ([]FlightSample, []Position) {
+	start := time.Date(2016, 6, 8, 13, 10, 0, 0, time.UTC)
+	a := Position{Latitude: 60, Longitude: 10, Altitude: 100}
+	track := []FlightSample{
+		{Time: start, Position: a},
+		{Time: start.Add(time.Second), Position: Position{Latitude: 500, Longitude: 500, Altitude: 500}},
+		{Time: start.Add(time.Second * 2), Position: a.Offset(10, 90)},
+	}
+	route := []Position{a.Offset(20, 0), a.Offset(20, 90), a.Offset(20, 180)}
+
+	return track, route
+}
+
+func TestWriteTrackGeoJSON(t *testing.T) {
+	track, route := trackTestData()
+
+	var buf bytes.Buffer
+	if err := WriteTrackGeoJSON(&buf, track, route); err != nil {
+		t.Fatal(err)
+	}
+
+	var fc struct {
+		Features []struct {
+			Geometry struct {
+				Type        string
+				Coordinates json.RawMessage
+			}
+			Properties map[string]interface{}
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &fc); err != nil {
+		t.Fatal(err)
+	}
+	// The track, the route, and the three waypoints.
+	if len(fc.Features) != 5 {
+		t.Fatalf("got %v features, want 5", len(fc.Features))
+	}
+
+	f := fc.Features[0]
+	if f.Properties["kind"] != "track" || f.Geometry.Type != "LineString" {
+		t.Fatalf("first feature is not the track: %+v", f)
+	}
+	var coords [][]float64
+	if err := json.Unmarshal(f.Geometry.Coordinates, &coords); err != nil {
+		t.Fatal(err)
+	}
+	if len(coords) != 2 || coords[0][0] != 10 || coords[0][1] != 60 {
+		t.Fatalf("wrong track coordinates, the sample without gps fix should be left out: %v", coords)
+	}
+
+	if w := fc.Features[4]; w.Properties["kind"] != "waypoint" || w.Properties["index"] != float64(2) {
+		t.Fatalf("wrong last waypoint: %+v", w)
+	}
+
+	buf.Reset()
+	if err := WriteTrackGeoJSON(&buf, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"features": []`) {
+		t.Fatalf("empty track should give an empty feature collection: %v", buf.String())
+	}
+}
+
+func TestWriteTrackKML(t *testing.T) {
+	track, route := trackTestData()
+
+	var buf bytes.Buffer
+	if err := WriteTrackKML(&buf, track, route); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Placemarks []kmlPlacemark `xml:"Document>Placemark"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Placemarks) != 5 {
+		t.Fatalf("got %v placemarks, want 5", len(doc.Placemarks))
+	}
+
+	p := doc.Placemarks[0]
+	if p.Name != "track" || p.LineString == nil || len(strings.Fields(p.LineString.Coordinates)) != 2 {
+		t.Fatalf("wrong track placemark: %+v", p)
+	}
+	if !strings.HasPrefix(p.LineString.Coordinates, "10,60,100 ") {
+		t.Fatalf("wrong track coordinates: %v", p.LineString.Coordinates)
+	}
+	if w := doc.Placemarks[2]; w.Name != "waypoint 0" || w.Point == nil {
+		t.Fatalf("wrong first waypoint: %+v", w)
+	}
+}
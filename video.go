@@ -0,0 +1,133 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"sync"
+)
+
+// VideoStreamMode is the mode of the video stream from the drone.
+type VideoStreamMode uint32
+
+const (
+	// VideoStreamLowLatency will minimize the latency of the stream,
+	// with the cost of more artifacts on lossy links.
+	VideoStreamLowLatency VideoStreamMode = 0
+	// VideoStreamHighReliability will maximize the quality of the
+	// stream, with the cost of a higher latency.
+	VideoStreamHighReliability VideoStreamMode = 1
+	// VideoStreamHighReliabilityLowFramerate will maximize the quality
+	// of the stream, and also lower the framerate.
+	VideoStreamHighReliabilityLowFramerate VideoStreamMode = 2
+)
+
+// String will return the name of the video stream mode.
+func (v VideoStreamMode) String() string {
+	switch v {
+	case VideoStreamLowLatency:
+		return "low_latency"
+	case VideoStreamHighReliability:
+		return "high_reliability"
+	case VideoStreamHighReliabilityLowFramerate:
+		return "high_reliability_low_framerate"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(v))
+}
+
+// VideoEnableState is the state of the video stream reported by the drone.
+type VideoEnableState uint32
+
+const (
+	VideoEnabled  VideoEnableState = 0
+	VideoDisabled VideoEnableState = 1
+	VideoError    VideoEnableState = 2
+)
+
+// String will return the name of the video enable state.
+func (v VideoEnableState) String() string {
+	switch v {
+	case VideoEnabled:
+		return "enabled"
+	case VideoDisabled:
+		return "disabled"
+	case VideoError:
+		return "error"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(v))
+}
+
+// videoConfig holds the settings for the video stream that are
+// given to the drone each time a connection is made.
+type videoConfig struct {
+	mu sync.Mutex
+	// enable is true if the video stream should be enabled.
+	enable bool
+	// mode of the video stream.
+	mode VideoStreamMode
+}
+
+// SetVideoStreamMode will set the mode to use for the video stream.
+// The mode is given to the drone right away if connected, and also
+// each time a new connection is made.
+func (d *Drone) SetVideoStreamMode(mode VideoStreamMode) error {
+	if mode > VideoStreamHighReliabilityLowFramerate {
+		return fmt.Errorf("SetVideoStreamMode: unknown mode: %v", mode)
+	}
+
+	d.videoConfig.mu.Lock()
+	d.videoConfig.mode = mode
+	d.videoConfig.mu.Unlock()
+
+	if !d.linkUp() {
+		return nil
+	}
+
+	return d.sendCmd(Command(MediaStreamingVideoStreamMode), &Ardrone3MediaStreamingVideoStreamModeArguments{Mode: uint32(mode)})
+}
+
+// EnableVideoStream will enable or disable the video stream from the
+// drone. The drone will not send any video before it is enabled.
+// The setting is given to the drone right away if connected, and also
+// each time a new connection is made.
+func (d *Drone) EnableVideoStream(enable bool) error {
+	d.videoConfig.mu.Lock()
+	d.videoConfig.enable = enable
+	d.videoConfig.mu.Unlock()
+
+	if !d.linkUp() {
+		return nil
+	}
+
+	return d.sendVideoEnable(enable)
+}
+
+// sendVideoEnable will send the video enable command to the drone.
+func (d *Drone) sendVideoEnable(enable bool) error {
+	var v uint8
+	if enable {
+		v = 1
+	}
+
+	return d.sendCmd(Command(MediaStreamingVideoEnable), &Ardrone3MediaStreamingVideoEnableArguments{Enable: v})
+}
+
+// startVideoStream will give the drone the video stream settings, and
+// is called each time a new connection with the drone is made.
+func (d *Drone) startVideoStream() error {
+	d.videoConfig.mu.Lock()
+	enable := d.videoConfig.enable
+	mode := d.videoConfig.mode
+	d.videoConfig.mu.Unlock()
+
+	err := d.sendCmd(Command(MediaStreamingVideoStreamMode), &Ardrone3MediaStreamingVideoStreamModeArguments{Mode: uint32(mode)})
+	if err != nil {
+		return fmt.Errorf("startVideoStream: failed to set stream mode: %v", err)
+	}
+
+	if err := d.sendVideoEnable(enable); err != nil {
+		return fmt.Errorf("startVideoStream: failed to enable stream: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package parrotbebop
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// pingTimeout is how long without a ping from the drone before the
+	// link is considered lost. The drone sends a ping every second,
+	// and the protocol considers the link lost after 5 seconds.
+	pingTimeout = time.Second * 5
+	// watchdogInterval is how often the watchdog checks the pings.
+	watchdogInterval = time.Millisecond * 500
+	// readPollInterval is the read deadline of the UDP reader, so it
+	// can check if the connection is closed while no data is received.
+	readPollInterval = time.Second
+)
+
+// LinkStatus is the time of the last ping, and of the last data of any
+// kind, received from the drone on the current connection.
+type LinkStatus struct {
+	Connected time.Time
+	LastPing  time.Time
+	LastData  time.Time
+}
+
+// linkWatchdog keeps track of the pings and data received from the
+// drone.
+type linkWatchdog struct {
+	mu     sync.Mutex
+	status LinkStatus
+}
+
+// reset will start tracking a new connection made at the time given.
+func (w *linkWatchdog) reset(now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.status = LinkStatus{Connected: now}
+}
+
+// ping will register a ping received from the drone.
+func (w *linkWatchdog) ping(now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.status.LastPing = now
+}
+
+// data will register data received from the drone.
+func (w *linkWatchdog) data(now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.status.LastData = now
+}
+
+// get will return the current status.
+func (w *linkWatchdog) get() LinkStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.status
+}
+
+// lost will return true if no ping have been received within the
+// timeout, where the time of the connection counts as the first ping.
+func (w *linkWatchdog) lost(now time.Time, timeout time.Duration) bool {
+	s := w.get()
+
+	last := s.LastPing
+	if last.Before(s.Connected) {
+		last = s.Connected
+	}
+
+	return now.Sub(last) > timeout
+}
+
+// LinkStatus will return when the last ping, and the last data of any
+// kind, was received from the drone. The link is considered lost when
+// no ping is received for 5 seconds, while there can be legitimate
+// quiet periods without other data.
+func (d *Drone) LinkStatus() LinkStatus {
+	return d.linkWatchdog.get()
+}
+
+// startLinkWatchdog will re-initialize the connection if the drone
+// stops sending pings, and runs until the context is done.
+func (d *Drone) startLinkWatchdog(ctx context.Context) {
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("info: exiting startLinkWatchdog")
+			return
+		case now := <-ticker.C:
+			if !d.linkWatchdog.lost(now, pingTimeout) {
+				continue
+			}
+
+			s := d.linkWatchdog.get()
+			log.Printf("error: no ping from drone within %v, last ping %v, last data %v, link lost\n", pingTimeout, s.LastPing.Format(time.RFC3339), s.LastData.Format(time.RFC3339))
+			d.requestReconnect(ctx)
+			return
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package parrotbebop
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLinkWatchdogLost(t *testing.T) {
+	var w linkWatchdog
+	start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	w.reset(start)
+
+	// The connection counts as the first ping.
+	if w.lost(start.Add(time.Second*4), pingTimeout) {
+		t.Fatalf("link lost before the timeout after connecting")
+	}
+
+	// Data without pings should not keep the link alive.
+	w.data(start.Add(time.Second * 5))
+	if !w.lost(start.Add(time.Second*6), pingTimeout) {
+		t.Fatalf("link not lost with data but no pings")
+	}
+
+	// And a quiet period with pings should not loose it.
+	w.ping(start.Add(time.Second * 5))
+	if w.lost(start.Add(time.Second*9), pingTimeout) {
+		t.Fatalf("link lost with pings, but no data")
+	}
+
+	s := w.get()
+	if !s.LastPing.Equal(start.Add(time.Second*5)) || !s.LastData.Equal(start.Add(time.Second*5)) {
+		t.Fatalf("wrong link status: %+v", s)
+	}
+}
+
+func TestLinkWatchdogReconnect(t *testing.T) {
+	d := NewDrone()
+	// Connected long ago, and never pinged.
+	d.linkWatchdog.reset(time.Now().Add(-pingTimeout * 2))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		d.startLinkWatchdog(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-d.chNetworkConnect:
+	case <-time.After(time.Second * 3):
+		t.Fatalf("no reconnect requested by the watchdog")
+	}
+	<-done
+}
@@ -0,0 +1,181 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"sync"
+)
+
+// validWayPoint will check that the waypoint is within the allowed
+// limits. Too big values means that no GPS connection was available
+// when the waypoint was made.
+func validWayPoint(wp gpsLatLonAlt) error {
+	switch {
+	case wp.latitude > 91 || wp.latitude < -91:
+		return fmt.Errorf("latitude not allowed: %v", wp.latitude)
+	case wp.longitude > 181 || wp.longitude < -181:
+		return fmt.Errorf("longitude not allowed: %v", wp.longitude)
+	}
+
+	return nil
+}
+
+// insert will insert the waypoint at index i, where -1 or the length
+// of the buffer appends it.
+func (s *moveToBuffer) insert(i int, wp gpsLatLonAlt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i == -1 {
+		i = len(s.waypoints)
+	}
+	if i < 0 || i > len(s.waypoints) {
+		return fmt.Errorf("index out of range: %v", i)
+	}
+
+	s.waypoints = append(s.waypoints, gpsLatLonAlt{})
+	copy(s.waypoints[i+1:], s.waypoints[i:])
+	s.waypoints[i] = wp
+	s.changedLocked()
+
+	return nil
+}
+
+// remove will remove the waypoint at index i.
+func (s *moveToBuffer) remove(i int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i < 0 || i >= len(s.waypoints) {
+		return fmt.Errorf("index out of range: %v", i)
+	}
+
+	s.waypoints = append(s.waypoints[:i], s.waypoints[i+1:]...)
+	s.changedLocked()
+
+	return nil
+}
+
+// move will move the waypoint at index from to index to.
+func (s *moveToBuffer) move(from int, to int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if from < 0 || from >= len(s.waypoints) || to < 0 || to >= len(s.waypoints) {
+		return fmt.Errorf("index out of range: from %v, to %v", from, to)
+	}
+
+	wp := s.waypoints[from]
+	s.waypoints = append(s.waypoints[:from], s.waypoints[from+1:]...)
+	s.waypoints = append(s.waypoints, gpsLatLonAlt{})
+	copy(s.waypoints[to+1:], s.waypoints[to:])
+	s.waypoints[to] = wp
+	s.changedLocked()
+
+	return nil
+}
+
+// clear will remove all the waypoints.
+func (s *moveToBuffer) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.waypoints = nil
+	s.changedLocked()
+}
+
+// InsertWaypoint will insert the waypoint into the moveTo buffer at the
+// index given, where -1 will add it to the end of the buffer.
+func (d *Drone) InsertWaypoint(index int, p Position) error {
+	wp := fromPosition(p)
+	if err := validWayPoint(wp); err != nil {
+		return fmt.Errorf("InsertWaypoint: %v", err)
+	}
+
+	if err := d.moveToBuffer.insert(index, wp); err != nil {
+		return fmt.Errorf("InsertWaypoint: %v", err)
+	}
+
+	return nil
+}
+
+// RemoveWaypoint will remove the waypoint at the index given from the
+// moveTo buffer.
+func (d *Drone) RemoveWaypoint(index int) error {
+	if err := d.moveToBuffer.remove(index); err != nil {
+		return fmt.Errorf("RemoveWaypoint: %v", err)
+	}
+
+	return nil
+}
+
+// MoveWaypoint will move the waypoint at index from to index to, to
+// change the order the waypoints are flown.
+func (d *Drone) MoveWaypoint(from int, to int) error {
+	if err := d.moveToBuffer.move(from, to); err != nil {
+		return fmt.Errorf("MoveWaypoint: %v", err)
+	}
+
+	return nil
+}
+
+// ClearWaypoints will remove all the waypoints from the moveTo buffer.
+func (d *Drone) ClearWaypoints() {
+	d.moveToBuffer.clear()
+}
+
+// legConfig holds the max length of the legs flown by the moveTo
+// executor, which can be changed while the executor is running. 0
+// means no limit.
+type legConfig struct {
+	mu        sync.Mutex
+	maxLength float64
+}
+
+// get will return the max length in meters.
+func (l *legConfig) get() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.maxLength
+}
+
+// SetMaxLegLength will make the moveTo executor split the legs longer
+// than meters into shorter legs, so the drone is given intermediate
+// positions along the great circle. 0 means no limit.
+func (d *Drone) SetMaxLegLength(meters float64) error {
+	if meters < 0 {
+		return fmt.Errorf("SetMaxLegLength: length can not be negative, got %v", meters)
+	}
+
+	d.legConfig.mu.Lock()
+	defer d.legConfig.mu.Unlock()
+
+	d.legConfig.maxLength = meters
+
+	return nil
+}
+
+// currentWaypoint will return the waypoint being flown, if any.
+func (s *moveToBuffer) currentWaypoint() (Position, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current == nil {
+		return Position{}, false
+	}
+
+	return s.current.toPosition(), true
+}
+
+// updateWaypointDistance will put the distance from the position to the
+// waypoint being flown in the telemetry.
+func (d *Drone) updateWaypointDistance(p Position) {
+	wp, ok := d.moveToBuffer.currentWaypoint()
+
+	d.telemetry.update(func(t *Telemetry) {
+		t.WaypointDistance = 0
+		if ok && validGPSPosition(p) {
+			t.WaypointDistance = p.DistanceTo(wp)
+		}
+	})
+}
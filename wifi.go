@@ -0,0 +1,252 @@
+package parrotbebop
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// wifiScanTimeout is how long to wait for the drone to report the end
+// of a wifi scan.
+const wifiScanTimeout = time.Second * 10
+
+// WifiBand is a wifi frequency band.
+type WifiBand uint32
+
+const (
+	WifiBand2_4GHz WifiBand = 0
+	WifiBand5GHz   WifiBand = 1
+	WifiBandAll    WifiBand = 2
+)
+
+// String will return the name of the wifi band.
+func (b WifiBand) String() string {
+	switch b {
+	case WifiBand2_4GHz:
+		return "2.4GHz"
+	case WifiBand5GHz:
+		return "5GHz"
+	case WifiBandAll:
+		return "all"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(b))
+}
+
+// WifiSelection is how the drone selects the wifi channel.
+type WifiSelection uint32
+
+const (
+	// WifiAutoAll will select the best channel in all the bands.
+	WifiAutoAll WifiSelection = 0
+	// WifiAuto2_4GHz will select the best channel in the 2.4GHz band.
+	WifiAuto2_4GHz WifiSelection = 1
+	// WifiAuto5GHz will select the best channel in the 5GHz band.
+	WifiAuto5GHz WifiSelection = 2
+	// WifiManual will use the band and channel given.
+	WifiManual WifiSelection = 3
+)
+
+// String will return the name of the wifi selection.
+func (w WifiSelection) String() string {
+	switch w {
+	case WifiAutoAll:
+		return "autoAll"
+	case WifiAuto2_4GHz:
+		return "auto2.4GHz"
+	case WifiAuto5GHz:
+		return "auto5GHz"
+	case WifiManual:
+		return "manual"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(w))
+}
+
+// WifiSettings holds the wifi settings reported by the drone.
+type WifiSettings struct {
+	Selection WifiSelection
+	Band      WifiBand
+	Channel   uint8
+	// Country is the ISO 3166 country code used for the allowed
+	// channels, and AutoCountry is true if the drone selects it.
+	Country     string
+	AutoCountry bool
+}
+
+// WifiChannel is a channel the drone is allowed to use.
+type WifiChannel struct {
+	Band    WifiBand
+	Channel uint8
+	// Outdoor is true if the channel is allowed to be used outdoor.
+	Outdoor bool
+}
+
+// WifiNetwork is a wifi network seen by the drone in a scan.
+type WifiNetwork struct {
+	SSID string
+	// RSSI is the signal strength in dBm.
+	RSSI    int16
+	Band    WifiBand
+	Channel uint8
+}
+
+// wifiScan holds the results of the wifi scans while they are being
+// reported by the drone, which is one command for each result followed
+// by a command telling that all the results are sent.
+type wifiScan struct {
+	mu       sync.Mutex
+	channels []WifiChannel
+	networks []WifiNetwork
+}
+
+// WifiSettings will return the wifi settings reported by the drone.
+func (d *Drone) WifiSettings() WifiSettings {
+	return d.telemetry.snapshot().Wifi
+}
+
+// SetWifiSelection will set how the drone selects the wifi channel. The
+// band and channel are only used with WifiManual, where the band can
+// not be WifiBandAll. The drone will restart it's access point, so the
+// connection will be lost for a little while.
+func (d *Drone) SetWifiSelection(s WifiSelection, band WifiBand, channel uint8) error {
+	if s > WifiManual {
+		return fmt.Errorf("SetWifiSelection: unknown selection: %v", s)
+	}
+	if s == WifiManual && (band > WifiBand5GHz || channel == 0) {
+		return fmt.Errorf("SetWifiSelection: manual selection needs a band and a channel, got %v channel %v", band, channel)
+	}
+
+	return d.sendCmd(Command(NetworkSettingsWifiSelection), &Ardrone3NetworkSettingsWifiSelectionArguments{
+		TypeX:   uint32(s),
+		Band:    uint32(band),
+		Channel: channel,
+	})
+}
+
+// SetCountry will set the country used by the drone to know which wifi
+// channels are allowed, given as an ISO 3166 code like "NO".
+func (d *Drone) SetCountry(code string) error {
+	if len(code) != 2 || code[0] < 'A' || code[0] > 'Z' || code[1] < 'A' || code[1] > 'Z' {
+		return fmt.Errorf("SetCountry: country code must be 2 upper case letters, got %q", code)
+	}
+
+	return d.sendCmd(Command(SettingsCountry), &CommonSettingsCountryArguments{Code: code})
+}
+
+// SetAutoCountry will set if the drone should select the country by
+// itself.
+func (d *Drone) SetAutoCountry(auto bool) error {
+	return d.sendCmd(Command(SettingsAutoCountry), &CommonSettingsAutoCountryArguments{Automatic: boolToUint8(auto)})
+}
+
+// SetSSID will set the product name of the drone, which is also the
+// SSID of it's access point. The new SSID is used after the drone is
+// restarted.
+func (d *Drone) SetSSID(ssid string) error {
+	if len(ssid) == 0 || len(ssid) > 32 {
+		return fmt.Errorf("SetSSID: ssid must be 1 to 32 characters, got %q", ssid)
+	}
+
+	return d.sendCmd(Command(SettingsProductName), &CommonSettingsProductNameArguments{Name: ssid})
+}
+
+// WifiAuthChannels will ask the drone for the wifi channels it is
+// allowed to use in the current country, and wait for the list.
+func (d *Drone) WifiAuthChannels(ctx context.Context) ([]WifiChannel, error) {
+	d.wifiScan.mu.Lock()
+	d.wifiScan.channels = nil
+	d.wifiScan.mu.Unlock()
+
+	v, err := d.waitWifiScan(ctx, EventWifiAuthChannelsReceived, Command(NetworkWifiAuthChannel), &Ardrone3NetworkWifiAuthChannelArguments{})
+	if err != nil {
+		return nil, fmt.Errorf("WifiAuthChannels: %v", err)
+	}
+
+	return v.([]WifiChannel), nil
+}
+
+// ScanWifi will ask the drone to scan for wifi networks in the band
+// given, and wait for the result. It can be used to find a channel with
+// little traffic before changing channel with SetWifiSelection.
+func (d *Drone) ScanWifi(ctx context.Context, band WifiBand) ([]WifiNetwork, error) {
+	if band > WifiBandAll {
+		return nil, fmt.Errorf("ScanWifi: unknown band: %v", band)
+	}
+
+	d.wifiScan.mu.Lock()
+	d.wifiScan.networks = nil
+	d.wifiScan.mu.Unlock()
+
+	v, err := d.waitWifiScan(ctx, EventWifiScanReceived, Command(NetworkWifiScan), &Ardrone3NetworkWifiScanArguments{Band: uint32(band)})
+	if err != nil {
+		return nil, fmt.Errorf("ScanWifi: %v", err)
+	}
+
+	return v.([]WifiNetwork), nil
+}
+
+// waitWifiScan will send the command, and wait for the event published
+// when the drone have sent all the results.
+func (d *Drone) waitWifiScan(ctx context.Context, typ EventType, cmd Command, args Encoder) (interface{}, error) {
+	// Subscribe before asking, so we don't miss the event.
+	chEvents, unsubscribe := d.events.subscribe()
+	defer unsubscribe()
+
+	if err := d.sendCmd(cmd, args); err != nil {
+		return nil, err
+	}
+
+	timeout := time.NewTimer(wifiScanTimeout)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout.C:
+			return nil, fmt.Errorf("timed out waiting for drone")
+		case ev := <-chEvents:
+			if ev.Type == typ {
+				return ev.Value, nil
+			}
+		}
+	}
+}
+
+// handleWifiAuthChannel will add a channel to the list being received.
+func (d *Drone) handleWifiAuthChannel(c WifiChannel) {
+	d.wifiScan.mu.Lock()
+	defer d.wifiScan.mu.Unlock()
+
+	d.wifiScan.channels = append(d.wifiScan.channels, c)
+}
+
+// handleWifiNetwork will add a network to the scan result being received.
+func (d *Drone) handleWifiNetwork(n WifiNetwork) {
+	d.wifiScan.mu.Lock()
+	defer d.wifiScan.mu.Unlock()
+
+	d.wifiScan.networks = append(d.wifiScan.networks, n)
+}
+
+// handleWifiAuthChannelsDone will publish the list of channels received.
+func (d *Drone) handleWifiAuthChannelsDone() {
+	d.wifiScan.mu.Lock()
+	channels := append([]WifiChannel(nil), d.wifiScan.channels...)
+	d.wifiScan.channels = nil
+	d.wifiScan.mu.Unlock()
+
+	d.events.publish(EventWifiAuthChannelsReceived, channels)
+}
+
+// handleWifiScanDone will publish the networks found in the scan.
+func (d *Drone) handleWifiScanDone() {
+	d.wifiScan.mu.Lock()
+	networks := append([]WifiNetwork(nil), d.wifiScan.networks...)
+	d.wifiScan.networks = nil
+	d.wifiScan.mu.Unlock()
+
+	d.events.publish(EventWifiScanReceived, networks)
+}
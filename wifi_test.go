@@ -0,0 +1,37 @@
+package parrotbebop
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWifiAuthChannelsCollected(t *testing.T) {
+	d := NewDrone()
+	chEvents, unsubscribe := d.events.subscribe()
+	defer unsubscribe()
+
+	want := []WifiChannel{
+		{Band: WifiBand2_4GHz, Channel: 6, Outdoor: true},
+		{Band: WifiBand5GHz, Channel: 36},
+	}
+	d.checkCmdFromDrone(protocolARCommands{}, Ardrone3NetworkStateWifiAuthChannelListChangedArguments{Band: 0, Channel: 6, Inorout: 1})
+	d.checkCmdFromDrone(protocolARCommands{}, Ardrone3NetworkStateWifiAuthChannelListChangedArguments{Band: 1, Channel: 36, Inorout: 0})
+	d.checkCmdFromDrone(protocolARCommands{}, Ardrone3NetworkStateAllWifiAuthChannelChangedArguments{})
+
+	ev := <-chEvents
+	if ev.Type != EventWifiAuthChannelsReceived {
+		t.Fatalf("wrong event: %v", ev.Type)
+	}
+	if got := ev.Value.([]WifiChannel); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestSetCountryValidates(t *testing.T) {
+	d := NewDrone()
+	for _, code := range []string{"", "no", "NOR", "N1"} {
+		if err := d.SetCountry(code); err == nil {
+			t.Errorf("expected error for country code %q", code)
+		}
+	}
+}
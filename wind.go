@@ -0,0 +1,149 @@
+package parrotbebop
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// WarningLevel is the level reported by the drone for the wind and
+// vibration states.
+type WarningLevel uint32
+
+const (
+	LevelOK       WarningLevel = 0
+	LevelWarning  WarningLevel = 1
+	LevelCritical WarningLevel = 2
+)
+
+// String will return the name of the warning level.
+func (w WarningLevel) String() string {
+	switch w {
+	case LevelOK:
+		return "ok"
+	case LevelWarning:
+		return "warning"
+	case LevelCritical:
+		return "critical"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(w))
+}
+
+// missionPause holds if the autonomous missions like FollowMe and
+// Orbit are paused, and if they should be paused automatically when
+// the wind or vibration level becomes critical.
+type missionPause struct {
+	mu         sync.Mutex
+	paused     bool
+	onCritical bool
+	// resumeExecutor is true if the moveTo executor was stopped by the
+	// pause, and should be started again when resumed.
+	resumeExecutor bool
+}
+
+// SetPauseOnCritical will make the driver pause any active mission when
+// the drone reports a critical wind or vibration level. When paused the
+// moveTo in progress is cancelled so the drone hovers, and no new moveTo
+// commands are sent before ResumeMission is called, where the waypoint
+// being flown is flown again.
+func (d *Drone) SetPauseOnCritical(enabled bool) {
+	d.missionPause.mu.Lock()
+	defer d.missionPause.mu.Unlock()
+
+	d.missionPause.onCritical = enabled
+}
+
+// MissionPaused will return true if the missions are paused.
+func (d *Drone) MissionPaused() bool {
+	d.missionPause.mu.Lock()
+	defer d.missionPause.mu.Unlock()
+
+	return d.missionPause.paused
+}
+
+// ResumeMission will allow the missions to send moveTo commands again
+// after being paused, and give the control back to the mission if the
+// pilot took over. The moveTo executor is started again if it was
+// stopped by the pause.
+func (d *Drone) ResumeMission() {
+	d.missionPause.mu.Lock()
+	d.missionPause.paused = false
+	executor := d.missionPause.resumeExecutor
+	d.missionPause.resumeExecutor = false
+	d.missionPause.mu.Unlock()
+
+	d.resumeAuthority()
+
+	if executor {
+		if err := d.executeMoveTo(); err != nil {
+			log.Printf("warning: ResumeMission: moveTo executor not started: %v\n", err)
+		}
+	}
+}
+
+// handleWindState will update the wind state telemetry, and warn and
+// pause the mission if the level is critical.
+func (d *Drone) handleWindState(w WarningLevel) {
+	d.telemetry.update(func(t *Telemetry) {
+		t.Wind = w
+	})
+
+	d.handleWarningLevel(EventWindStateChanged, "wind", w)
+}
+
+// handleVibrationLevel will update the vibration level telemetry, and
+// warn and pause the mission if the level is critical.
+func (d *Drone) handleVibrationLevel(w WarningLevel) {
+	d.telemetry.update(func(t *Telemetry) {
+		t.Vibration = w
+	})
+
+	d.handleWarningLevel(EventVibrationLevelChanged, "vibration", w)
+}
+
+// handleWarningLevel will publish the event for the level, and pause
+// the mission if the level is critical and the policy says so.
+func (d *Drone) handleWarningLevel(e EventType, name string, w WarningLevel) {
+	if w == LevelOK {
+		d.events.publish(e, w)
+		return
+	}
+
+	log.Printf("warning: %v level from drone: %v\n", name, w)
+	d.events.publishPriority(e, PriorityHigh, w)
+
+	if w != LevelCritical {
+		return
+	}
+
+	executor := d.MoveToActive()
+
+	d.missionPause.mu.Lock()
+	pause := d.missionPause.onCritical && !d.missionPause.paused
+	if pause {
+		d.missionPause.paused = true
+		d.missionPause.resumeExecutor = executor
+	}
+	d.missionPause.mu.Unlock()
+
+	if !pause {
+		return
+	}
+
+	log.Printf("warning: pausing mission because of critical %v level\n", name)
+
+	// The executor will cancel the moveTo, and put the waypoint being
+	// flown back in the buffer. Otherwise the moveTo is cancelled here,
+	// in it's own go routine so we don't block the decoding of packets
+	// from the drone.
+	if executor {
+		signalMoveTo(d.gps.chMoveToCancel)
+		return
+	}
+	go func() {
+		if err := d.sendCmd(Command(PilotingCancelMoveTo), &Ardrone3PilotingCancelMoveToArguments{}); err != nil {
+			log.Printf("error: failed to cancel moveTo when pausing mission: %v\n", err)
+		}
+	}()
+}